@@ -0,0 +1,7 @@
+//go:build !dev || !regtest
+// +build !dev !regtest
+
+package build
+
+// REGTEST set to false keeps the regular parameters of the release.
+const REGTEST = false
@@ -0,0 +1,8 @@
+//go:build dev && regtest
+// +build dev,regtest
+
+package build
+
+// REGTEST set to true switches a dev build to the parameters of the regression
+// test network, where blocks can be mined instantly.
+const REGTEST = true
@@ -16,6 +16,13 @@ var (
 		Long:  "Print the current state of consensus such as current block, block height, and target.",
 		Run:   wrap(consensuscmd),
 	}
+
+	consensusCompactCmd = &cobra.Command{
+		Use:   "compact",
+		Short: "Compact the consensus database",
+		Long:  "Rewrite the consensus database into a fresh file, dropping orphaned blocks and stale buckets. The consensus set is unavailable while the database is compacted.",
+		Run:   wrap(consensuscompactcmd),
+	}
 )
 
 // consensuscmd is the handler for the command `siac consensus`.
@@ -32,7 +39,21 @@ Height:     %v
 Target:     %v
 Difficulty: %v
 `, yesNo(cg.Synced), cg.CurrentBlock, cg.Height, cg.Target, cg.Difficulty)
+	} else if sp, err := httpClient.ConsensusSyncGet(); err == nil {
+		eta := "unknown"
+		if sp.ETA > 0 {
+			eta = sp.ETA.Round(time.Second).String()
+		}
+		fmt.Printf(`Synced: %v
+Height: %v
+Estimated Network Height: %v
+Progress (estimated): %.1f%%
+Blocks Per Second: %.1f
+ETA: %v
+`, yesNo(sp.Synced), sp.Height, sp.EstimatedHeight, sp.Progress*100, sp.BlocksPerSecond, eta)
 	} else {
+		// Older daemons do not report their sync progress, so the network
+		// height is estimated from the time instead.
 		estimatedHeight := estimatedHeightAt(time.Now())
 		estimatedProgress := float64(cg.Height) / float64(estimatedHeight) * 100
 		if estimatedProgress > 100 {
@@ -45,6 +66,22 @@ Progress (estimated): %.1f%%
 	}
 }
 
+// consensuscompactcmd is the handler for the command `siac consensus compact`.
+// Compacts the consensus database.
+func consensuscompactcmd() {
+	fmt.Println("Compacting the consensus database, this may take a while...")
+	ccp, err := httpClient.ConsensusCompactPost()
+	if err != nil {
+		die("Could not compact the consensus database:", err)
+	}
+	fmt.Printf(`Compacted the consensus database.
+Old size:        %v
+New size:        %v
+Blocks dropped:  %v
+Buckets dropped: %v
+`, filesizeUnits(int64(ccp.OldSize)), filesizeUnits(int64(ccp.NewSize)), ccp.BlocksDropped, ccp.BucketsDropped)
+}
+
 // estimatedHeightAt returns the estimated block height for the given time.
 // Block height is estimated by calculating the minutes since a known block in
 // the past and dividing by 10 minutes (the block time).
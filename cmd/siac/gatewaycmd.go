@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/spf13/cobra"
@@ -44,6 +45,13 @@ var (
 		Long:  "View the current peer list.",
 		Run:   wrap(gatewaylistcmd),
 	}
+
+	gatewayStatsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "View the bandwidth usage of each peer",
+		Long:  "View the bytes sent and received, RPC counts, latency, and connection age of each peer.",
+		Run:   wrap(gatewaystatscmd),
+	}
 )
 
 // gatewayconnectcmd is the handler for the command `siac gateway add [address]`.
@@ -106,3 +114,25 @@ func gatewaylistcmd() {
 	}
 	w.Flush()
 }
+
+// gatewaystatscmd is the handler for the command `siac gateway stats`.
+// Prints the traffic statistics of all peers.
+func gatewaystatscmd() {
+	gsg, err := httpClient.GatewayStatsGet()
+	if err != nil {
+		die("Could not get peer stats:", err)
+	}
+	if len(gsg.Peers) == 0 {
+		fmt.Println("No peers to show.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Address\tOutbound\tAge\tLatency\tSent\tReceived\tRPCs Called\tRPCs Handled")
+	for _, ps := range gsg.Peers {
+		age := time.Since(ps.ConnectedAt).Round(time.Second)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", ps.NetAddress, yesNo(!ps.Inbound), age,
+			ps.Latency.Round(time.Millisecond), filesizeUnits(int64(ps.BytesSent)), filesizeUnits(int64(ps.BytesReceived)),
+			ps.RPCsCalled, ps.RPCsHandled)
+	}
+	w.Flush()
+}
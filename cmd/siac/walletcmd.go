@@ -6,12 +6,16 @@ import (
 	"math"
 	"math/big"
 	"os"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/NebulousLabs/entropy-mnemonics"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh/terminal"
 
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/wallet"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -63,6 +67,16 @@ The smallest unit of siacoins is the hasting. One siacoin is 10^24 hastings. Oth
 		Run: wrap(walletbalancecmd),
 	}
 
+	walletColdCmd = &cobra.Command{
+		Use:   "cold [index]",
+		Short: "Generate a cold storage address from a seed",
+		Long: `Derive the address and secret key at the given index of a seed. The seed is
+read from the prompt, and siad is not contacted, so this can be run on an
+offline machine. The coins sent to the address can be recovered by loading the
+seed into a wallet, or by sweeping the printed secret key.`,
+		Run: wrap(walletcoldcmd),
+	}
+
 	walletInitCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize and encrypt a new wallet",
@@ -243,6 +257,27 @@ func walletchangepasswordcmd() {
 	fmt.Println("Password changed successfully.")
 }
 
+// walletcoldcmd derives an address and its secret key from a seed without
+// contacting siad.
+func walletcoldcmd(indexStr string) {
+	index, err := strconv.ParseUint(indexStr, 10, 64)
+	if err != nil {
+		die("Could not parse index:", err)
+	}
+	seedStr, err := passwordPrompt("Seed: ")
+	if err != nil {
+		die("Reading seed failed:", err)
+	}
+	seed, err := modules.StringToSeed(seedStr, mnemonics.English)
+	if err != nil {
+		die("Invalid seed:", err)
+	}
+	uc, sk := wallet.GenerateKey(seed, index)
+	fmt.Printf("Address:    %v\n", uc.UnlockHash())
+	fmt.Printf("Secret key: %x\n", sk[:])
+	fmt.Println("The secret key can be swept with /wallet/sweep/key. Keep it and the seed offline.")
+}
+
 // walletinitcmd encrypts the wallet with the given password
 func walletinitcmd() {
 	var password string
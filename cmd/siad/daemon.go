@@ -3,8 +3,10 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -13,6 +15,8 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/consensus"
+	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/profile"
 	mnemonics "github.com/NebulousLabs/entropy-mnemonics"
 
@@ -241,3 +245,46 @@ func startDaemonCmd(cmd *cobra.Command, _ []string) {
 	// Daemon seems to have closed cleanly. Print a 'closed' mesasge.
 	fmt.Println("Shutdown complete.")
 }
+
+// replayAuditLog replays the consensus audit log at filename into a new
+// consensus set in a temporary directory, and returns the number of replayed
+// entries.
+func replayAuditLog(filename string) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	dir, err := ioutil.TempDir("", "siad-replay")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	// The gateway does not bootstrap, so that the consensus set only
+	// receives the blocks in the log.
+	g, err := gateway.New("localhost:0", false, filepath.Join(dir, modules.GatewayDir))
+	if err != nil {
+		return 0, err
+	}
+	defer g.Close()
+	cs, err := consensus.New(g, false, filepath.Join(dir, modules.ConsensusDir))
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Close()
+	return cs.ReplayAuditLog(f)
+}
+
+// replayAuditLogCmd is a cobra command that replays a consensus audit log.
+func replayAuditLogCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	entries, err := replayAuditLog(args[0])
+	if err != nil {
+		die(fmt.Sprintf("Replay failed after %v entries: %v", entries, err))
+	}
+	fmt.Printf("Replayed %v entries, the consensus set matches the audit log.\n", entries)
+}
@@ -37,10 +37,18 @@ type Config struct {
 		HostAddr     string
 		AllowAPIBind bool
 
-		Modules           string
-		NoBootstrap       bool
-		RequiredUserAgent string
-		AuthenticateAPI   bool
+		Proxy         string
+		HiddenService string
+		LocalNetwork  bool
+
+		Modules               string
+		NoBootstrap           bool
+		ConsensusSnapshot     string
+		ConsensusSnapshotHash string
+		ConsensusAuditLog     string
+		WalletBackup          string
+		RequiredUserAgent     string
+		AuthenticateAPI       bool
 
 		Profile    string
 		ProfileDir string
@@ -149,6 +157,13 @@ func main() {
 		Run:   versionCmd,
 	})
 
+	root.AddCommand(&cobra.Command{
+		Use:   "replay-audit-log [file]",
+		Short: "Replay a consensus audit log",
+		Long:  "Replay a consensus audit log written with --consensus-audit-log into a new consensus set in a temporary directory, stopping at the first change that does not match the log.",
+		Run:   replayAuditLogCmd,
+	})
+
 	root.AddCommand(&cobra.Command{
 		Use:   "modules",
 		Short: "List available modules for use with -M, --modules flag",
@@ -163,8 +178,15 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.Siad.APIaddr, "api-addr", "", "localhost:9980", "which host:port the API server listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaDir, "sia-directory", "d", "", "location of the sia directory")
 	root.Flags().BoolVarP(&globalConfig.Siad.NoBootstrap, "no-bootstrap", "", false, "disable bootstrapping on this run")
+	root.Flags().StringVarP(&globalConfig.Siad.ConsensusSnapshot, "consensus-snapshot", "", "", "file or http(s) URL of a consensus snapshot to bootstrap a new consensus database from")
+	root.Flags().StringVarP(&globalConfig.Siad.ConsensusSnapshotHash, "consensus-snapshot-hash", "", "", "hex blake2b-256 hash of the consensus snapshot, required with --consensus-snapshot")
+	root.Flags().StringVarP(&globalConfig.Siad.ConsensusAuditLog, "consensus-audit-log", "", "", "file that every change to the consensus set is appended to, for replaying with 'siad replay-audit-log'")
+	root.Flags().StringVarP(&globalConfig.Siad.WalletBackup, "wallet-backup", "", "", "wallet backup created by /wallet/backup to restore into a new wallet")
 	root.Flags().StringVarP(&globalConfig.Siad.Profile, "profile", "", "", "enable profiling with flags 'cmt' for CPU, memory, trace")
-	root.Flags().StringVarP(&globalConfig.Siad.RPCaddr, "rpc-addr", "", ":9981", "which port the gateway listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.RPCaddr, "rpc-addr", "", ":9981", "which port the gateway listens on, or a comma-separated list of addresses")
+	root.Flags().StringVarP(&globalConfig.Siad.Proxy, "proxy", "", "", "host:port of a SOCKS5 proxy (e.g. Tor) that the gateway dials peers through")
+	root.Flags().StringVarP(&globalConfig.Siad.HiddenService, "hidden-service", "", "", "onion address of a Tor hidden service that forwards to the gateway, requires --proxy")
+	root.Flags().BoolVarP(&globalConfig.Siad.LocalNetwork, "local-network", "", false, "gossip private network addresses, for offline test networks and LAN clusters")
 	root.Flags().StringVarP(&globalConfig.Siad.Modules, "modules", "M", "cghrtw", "enabled modules, see 'siad modules' for more info")
 	root.Flags().BoolVarP(&globalConfig.Siad.AuthenticateAPI, "authenticate-api", "", false, "enable API password protection")
 	root.Flags().BoolVarP(&globalConfig.Siad.AllowAPIBind, "disable-api-security", "", false, "allow siad to listen on a non-localhost address (DANGEROUS)")
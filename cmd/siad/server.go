@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/modules/explorer"
@@ -491,7 +492,11 @@ func (srv *Server) loadModules() error {
 	if strings.Contains(srv.config.Siad.Modules, "g") {
 		i++
 		fmt.Printf("(%d/%d) Loading gateway...\n", i, len(srv.config.Siad.Modules))
-		g, err = gateway.New(srv.config.Siad.RPCaddr, !srv.config.Siad.NoBootstrap, filepath.Join(srv.config.Siad.SiaDir, modules.GatewayDir))
+		proxy := gateway.ProxyConfig{
+			Address:       srv.config.Siad.Proxy,
+			HiddenService: modules.NetAddress(srv.config.Siad.HiddenService),
+		}
+		g, err = gateway.NewCustomGateway(srv.config.Siad.RPCaddr, !srv.config.Siad.NoBootstrap, filepath.Join(srv.config.Siad.SiaDir, modules.GatewayDir), proxy, srv.config.Siad.LocalNetwork)
 		if err != nil {
 			return err
 		}
@@ -501,11 +506,33 @@ func (srv *Server) loadModules() error {
 	if strings.Contains(srv.config.Siad.Modules, "c") {
 		i++
 		fmt.Printf("(%d/%d) Loading consensus...\n", i, len(srv.config.Siad.Modules))
-		cs, err = consensus.New(g, !srv.config.Siad.NoBootstrap, filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir))
+		if srv.config.Siad.ConsensusSnapshot != "" {
+			// No checkpoints are built in yet, so the hash must be pinned.
+			if srv.config.Siad.ConsensusSnapshotHash == "" {
+				return errors.New("--consensus-snapshot requires --consensus-snapshot-hash")
+			}
+			var snapshotHash crypto.Hash
+			err = snapshotHash.LoadString(srv.config.Siad.ConsensusSnapshotHash)
+			if err != nil {
+				return errors.New("unable to parse consensus snapshot hash: " + err.Error())
+			}
+			err = consensus.FetchSnapshot(srv.config.Siad.ConsensusSnapshot, filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir), snapshotHash)
+			if err != nil {
+				return err
+			}
+		}
+		consensusSet, err := consensus.New(g, !srv.config.Siad.NoBootstrap, filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir))
 		if err != nil {
 			return err
 		}
+		cs = consensusSet
 		srv.moduleClosers = append(srv.moduleClosers, moduleCloser{name: "consensus", Closer: cs})
+		if srv.config.Siad.ConsensusAuditLog != "" {
+			err = consensusSet.StartAuditLog(srv.config.Siad.ConsensusAuditLog)
+			if err != nil {
+				return errors.New("unable to start the consensus audit log: " + err.Error())
+			}
+		}
 	}
 	var e modules.Explorer
 	if strings.Contains(srv.config.Siad.Modules, "e") {
@@ -531,7 +558,11 @@ func (srv *Server) loadModules() error {
 	if strings.Contains(srv.config.Siad.Modules, "w") {
 		i++
 		fmt.Printf("(%d/%d) Loading wallet...\n", i, len(srv.config.Siad.Modules))
-		w, err = wallet.New(cs, tpool, filepath.Join(srv.config.Siad.SiaDir, modules.WalletDir))
+		if srv.config.Siad.WalletBackup != "" {
+			w, err = wallet.NewFromBackup(cs, tpool, filepath.Join(srv.config.Siad.SiaDir, modules.WalletDir), srv.config.Siad.WalletBackup)
+		} else {
+			w, err = wallet.New(cs, tpool, filepath.Join(srv.config.Siad.SiaDir, modules.WalletDir))
+		}
 		if err != nil {
 			return err
 		}
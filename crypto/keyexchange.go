@@ -0,0 +1,50 @@
+package crypto
+
+// keyexchange.go contains the X25519 Diffie-Hellman key exchange, which two
+// parties use to agree on a shared secret over an insecure channel.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/fastrand"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// X25519KeySize is the size of X25519 public and secret keys in bytes.
+	X25519KeySize = curve25519.ScalarSize
+)
+
+var (
+	// ErrLowOrderKey is returned if the public key of the other party would
+	// produce an all-zero shared secret.
+	ErrLowOrderKey = errors.New("public key has low order")
+)
+
+type (
+	// X25519SecretKey is the secret half of an X25519 key pair.
+	X25519SecretKey [X25519KeySize]byte
+
+	// X25519PublicKey is the public half of an X25519 key pair.
+	X25519PublicKey [X25519KeySize]byte
+)
+
+// GenerateX25519KeyPair creates a key pair that can be used for a single key
+// exchange.
+func GenerateX25519KeyPair() (sk X25519SecretKey, pk X25519PublicKey) {
+	fastrand.Read(sk[:])
+	curve25519.ScalarBaseMult((*[32]byte)(&pk), (*[32]byte)(&sk))
+	return
+}
+
+// SharedSecret returns the secret that is shared between the owner of sk and
+// the owner of the secret key that corresponds to pk.
+func (sk X25519SecretKey) SharedSecret(pk X25519PublicKey) (secret [32]byte, err error) {
+	s, err := curve25519.X25519(sk[:], pk[:])
+	if err != nil {
+		return secret, ErrLowOrderKey
+	}
+	copy(secret[:], s)
+	return secret, nil
+}
@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"testing"
+)
+
+// TestUnitSharedSecret checks that both parties of a key exchange derive the
+// same secret, and that low order public keys are rejected.
+func TestUnitSharedSecret(t *testing.T) {
+	sk1, pk1 := GenerateX25519KeyPair()
+	sk2, pk2 := GenerateX25519KeyPair()
+	if pk1 == pk2 {
+		t.Fatal("generated the same key pair twice")
+	}
+
+	s1, err := sk1.SharedSecret(pk2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := sk2.SharedSecret(pk1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1 != s2 {
+		t.Fatal("parties derived different secrets")
+	}
+
+	// A third party should derive a different secret.
+	sk3, _ := GenerateX25519KeyPair()
+	s3, err := sk3.SharedSecret(pk2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3 == s1 {
+		t.Fatal("third party derived the shared secret")
+	}
+
+	// The all-zero public key has low order.
+	if _, err := sk1.SharedSecret(X25519PublicKey{}); err != ErrLowOrderKey {
+		t.Fatal("expected ErrLowOrderKey, got", err)
+	}
+}
@@ -30,6 +30,11 @@ var (
 	// ErrInvalidSignature is returned if a signature is provided that does not
 	// match the data and public key.
 	ErrInvalidSignature = errors.New("invalid signature")
+
+	// messagePrefix is hashed along with off-chain messages, so that the
+	// signature of a message can never be valid for a transaction or for any
+	// other signed object.
+	messagePrefix = "Sia Signed Message"
 )
 
 type (
@@ -92,6 +97,13 @@ func ReadSignedObject(r io.Reader, obj interface{}, maxLen uint64, pk PublicKey)
 	return encoding.Unmarshal(encObj, obj)
 }
 
+// HashMessage returns the hash that is signed to prove control of a key or an
+// address off-chain, such as by the operator of a host or by the parties of a
+// trade.
+func HashMessage(msg []byte) Hash {
+	return HashAll(messagePrefix, msg)
+}
+
 // SignHash signs a message using a secret key.
 func SignHash(data Hash, sk SecretKey) (sig Signature) {
 	copy(sig[:], ed25519.Sign(sk[:], data[:]))
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/types"
@@ -20,6 +21,45 @@ const (
 	DiffRevert DiffDirection = false
 )
 
+const (
+	// ConsensusMetricBlockApplied is recorded for every block that is applied
+	// to the current path. The height is the height of the block, the count
+	// is the number of transactions in the block, and the duration is the
+	// time taken to apply the block.
+	ConsensusMetricBlockApplied ConsensusMetricType = "blockapplied"
+
+	// ConsensusMetricTransactionsValidated is recorded for every batch of
+	// blocks whose transactions are verified before being applied. The height
+	// is the height of the first block of the batch, and the count is the
+	// number of transactions verified.
+	ConsensusMetricTransactionsValidated ConsensusMetricType = "transactionsvalidated"
+
+	// ConsensusMetricReorg is recorded for every reorg. The height is the
+	// height of the common ancestor, the count is the number of blocks that
+	// were reverted, and the duration is not set.
+	ConsensusMetricReorg ConsensusMetricType = "reorg"
+
+	// ConsensusMetricDatabaseUpdate is recorded for every database
+	// transaction that adds blocks to the consensus set. The height is the
+	// height of the consensus set before the transaction, and the count is
+	// the number of blocks in the transaction.
+	ConsensusMetricDatabaseUpdate ConsensusMetricType = "databaseupdate"
+
+	// ConsensusMetricBlocksDownloaded is recorded for every range of blocks
+	// that is downloaded during the initial blockchain download. The height
+	// is the height of the first block of the range, the count is the number
+	// of blocks in the range, the duration is the time taken to download the
+	// range, and the peer is the peer that sent the range.
+	ConsensusMetricBlocksDownloaded ConsensusMetricType = "blocksdownloaded"
+
+	// ConsensusMetricCompactBlock is recorded for every block that is
+	// reconstructed from a compact block. The height is the height of the
+	// consensus set when the block was received, the count is the number of
+	// transactions that had to be fetched from the peer, the duration is the time taken to reconstruct
+	// the block, and the peer is the peer that sent the compact block.
+	ConsensusMetricCompactBlock ConsensusMetricType = "compactblock"
+)
+
 var (
 	// ConsensusChangeBeginning is a special consensus change id that tells the
 	// consensus set to provide all consensus changes starting from the very
@@ -46,10 +86,22 @@ var (
 	// should be handled by the module, and not reported to the user.
 	ErrInvalidConsensusChangeID = errors.New("consensus subscription has invalid id - files are inconsistent")
 
+	// ErrConsensusChangePruned indicates that ConsensusSetSubscribe was called
+	// with a consensus change id that is not recognized, and that the id may
+	// have been pruned from the consensus changelog. Like
+	// ErrInvalidConsensusChangeID, the module should handle this error by
+	// rescanning from ConsensusChangeBeginning.
+	ErrConsensusChangePruned = errors.New("consensus subscription id is not in the changelog - the changelog has been pruned")
+
 	// ErrNonExtendingBlock indicates that a block is valid but does not result
 	// in a fork that is the heaviest known fork - the consensus set has not
 	// changed as a result of seeing the block.
 	ErrNonExtendingBlock = errors.New("block does not extend the longest fork")
+
+	// ErrMissingSiacoinOutput indicates that a transaction spends a siacoin
+	// output that does not exist in the consensus set, either because it was
+	// spent already or because its parent transaction is not confirmed yet.
+	ErrMissingSiacoinOutput = errors.New("transaction spends a nonexisting siacoin output")
 )
 
 type (
@@ -70,6 +122,33 @@ type (
 		ProcessConsensusChange(ConsensusChange)
 	}
 
+	// A ConsensusSetHeaderSubscriber is an object that receives the block
+	// headers of every change to the consensus set, without any of the diffs.
+	// It is intended for lightweight consumers that do not track outputs.
+	ConsensusSetHeaderSubscriber interface {
+		// ProcessHeaderConsensusChange sends a header-only consensus update
+		// to a module through a function call. Updates will always be sent in
+		// the correct order.
+		ProcessHeaderConsensusChange(HeaderConsensusChange)
+	}
+
+	// A ConsensusChangeFilter selects the diffs of a ConsensusChange that a
+	// filtered subscriber is interested in. Diffs of a type that is not
+	// selected are left out of every change sent to the subscriber, and are
+	// never collected by the consensus set in the first place.
+	ConsensusChangeFilter struct {
+		SiacoinOutputDiffs        bool
+		FileContractDiffs         bool
+		SiafundOutputDiffs        bool
+		DelayedSiacoinOutputDiffs bool
+		SiafundPoolDiffs          bool
+
+		// UnlockHashes, if not empty, further restricts the siacoin output,
+		// siafund output and delayed siacoin output diffs to those whose
+		// output is sent to one of the unlock hashes.
+		UnlockHashes map[types.UnlockHash]struct{}
+	}
+
 	// A ConsensusChange enumerates a set of changes that occurred to the consensus set.
 	ConsensusChange struct {
 		// ID is a unique id for the consensus change derived from the reverted
@@ -131,6 +210,208 @@ type (
 		TryTransactionSet func([]types.Transaction) (ConsensusChange, error)
 	}
 
+	// ConsensusCompactionStats describes the result of compacting the
+	// consensus database.
+	ConsensusCompactionStats struct {
+		// OldSize and NewSize are the sizes of the database file, in bytes,
+		// before and after compaction.
+		OldSize uint64 `json:"oldsize"`
+		NewSize uint64 `json:"newsize"`
+
+		// BlocksDropped is the number of orphaned blocks that were removed.
+		BlocksDropped uint64 `json:"blocksdropped"`
+
+		// BucketsDropped is the number of stale buckets that were removed.
+		BucketsDropped uint64 `json:"bucketsdropped"`
+	}
+
+	// ConsensusConsistencyIssue describes a single invariant of the consensus
+	// database that was found to be violated.
+	ConsensusConsistencyIssue struct {
+		// Check is the name of the check that found the issue.
+		Check string `json:"check"`
+
+		// Description explains how the invariant was violated.
+		Description string `json:"description"`
+	}
+
+	// ConsensusConsistencyReport is the result of scanning the consensus
+	// database for violated invariants.
+	ConsensusConsistencyReport struct {
+		// Height is the height of the current block at the time of the scan.
+		Height types.BlockHeight `json:"height"`
+
+		// MarkedInconsistent indicates that an inconsistency was detected and
+		// recorded in the database before the scan.
+		MarkedInconsistent bool `json:"markedinconsistent"`
+
+		// ChecksRun lists the names of the checks that were run.
+		ChecksRun []string `json:"checksrun"`
+
+		// Issues lists every violated invariant. The database is consistent
+		// if there are no issues.
+		Issues []ConsensusConsistencyIssue `json:"issues"`
+	}
+
+	// BlockDiffs contains a block in the current path along with the diffs
+	// that the block applied to the consensus set.
+	BlockDiffs struct {
+		Block  types.Block       `json:"block"`
+		Height types.BlockHeight `json:"height"`
+
+		SiacoinOutputDiffs        []SiacoinOutputDiff        `json:"siacoinoutputdiffs"`
+		FileContractDiffs         []FileContractDiff         `json:"filecontractdiffs"`
+		SiafundOutputDiffs        []SiafundOutputDiff        `json:"siafundoutputdiffs"`
+		DelayedSiacoinOutputDiffs []DelayedSiacoinOutputDiff `json:"delayedsiacoinoutputdiffs"`
+		SiafundPoolDiffs          []SiafundPoolDiff          `json:"siafundpooldiffs"`
+	}
+
+	// A ConsensusSnapshot is a read-only view of the state of the consensus
+	// set, captured at a single point in time. All of its fields are
+	// consistent with each other.
+	ConsensusSnapshot struct {
+		// Height is the height of the current block.
+		Height types.BlockHeight `json:"height"`
+
+		// CurrentBlock is the id of the current block. The ids of earlier
+		// blocks in the current path can be fetched with BlocksInRange.
+		CurrentBlock types.BlockID `json:"currentblock"`
+
+		// ChildTarget is the target of the children of the current block.
+		ChildTarget types.Target `json:"childtarget"`
+
+		// SiafundPool is the value of the siafund pool.
+		SiafundPool types.Currency `json:"siafundpool"`
+	}
+
+	// ConsensusSyncProgress describes how far the consensus set has synced
+	// with the network.
+	ConsensusSyncProgress struct {
+		// Synced indicates whether initial blockchain download has finished.
+		Synced bool `json:"synced"`
+
+		// Height is the height of the current block.
+		Height types.BlockHeight `json:"height"`
+
+		// EstimatedHeight is the estimated height of the network, based on
+		// the blocks announced by peers and on the time that has passed since
+		// the current block was mined. It is never lower than Height.
+		EstimatedHeight types.BlockHeight `json:"estimatedheight"`
+
+		// Progress is Height divided by EstimatedHeight.
+		Progress float64 `json:"progress"`
+
+		// BlocksPerSecond is the rate at which blocks were added to the
+		// current path recently.
+		BlocksPerSecond float64 `json:"blockspersecond"`
+
+		// ETA is the estimated time until the consensus set reaches the
+		// estimated height. It is zero if no blocks were added recently.
+		ETA time.Duration `json:"eta"`
+	}
+
+	// A ConsensusMetricType identifies the measurement contained in a
+	// ConsensusMetric.
+	ConsensusMetricType string
+
+	// A ConsensusMetric is a single measurement taken by the consensus set.
+	ConsensusMetric struct {
+		Type ConsensusMetricType `json:"type"`
+
+		// Height, Count and Duration are the measured values. Their meaning
+		// depends on the type of the metric.
+		Height   types.BlockHeight `json:"height"`
+		Count    int               `json:"count"`
+		Duration time.Duration     `json:"duration"`
+
+		// Peer is the peer that the measurement relates to, if any.
+		Peer NetAddress `json:"peer,omitempty"`
+	}
+
+	// A ConsensusMetricsSink receives the measurements taken by the consensus
+	// set, for example to export them to a monitoring system.
+	ConsensusMetricsSink interface {
+		// RecordConsensusMetric is called for every measurement. It may be
+		// called while the consensus set is locked, and therefore must not
+		// block or call back into the consensus set. It may also be called
+		// from several goroutines at once.
+		RecordConsensusMetric(ConsensusMetric)
+	}
+
+	// A BlockAcceptanceHook observes blocks before they are added to the
+	// consensus set and can veto them. Hooks are intended for experiments on
+	// dev and testing networks, and cannot be registered on the standard
+	// network.
+	BlockAcceptanceHook interface {
+		// CheckBlock is called for every block that passed validation of its
+		// header, before the block is added to the block tree. The block is
+		// rejected if an error is returned. CheckBlock is called while the
+		// consensus set is locked, and therefore must not call back into the
+		// consensus set.
+		CheckBlock(b types.Block, height types.BlockHeight) error
+	}
+
+	// A TransactionSource provides the unconfirmed transactions that the
+	// consensus set uses to reconstruct blocks that are relayed in compact
+	// form. It is usually the transaction pool.
+	TransactionSource interface {
+		// TransactionList returns the unconfirmed transactions that are
+		// known to the source. It is never called while the consensus set
+		// is locked.
+		TransactionList() []types.Transaction
+	}
+
+	// A ConsensusSetReorgSubscriber is an object that is notified every time
+	// the consensus set reorganizes onto a different fork.
+	ConsensusSetReorgSubscriber interface {
+		// ProcessReorgEvent sends a reorg event to a module through a
+		// function call. The event is sent after the corresponding
+		// consensus change has been sent to the regular subscribers.
+		ProcessReorgEvent(ReorgEvent)
+	}
+
+	// A ReorgEvent describes a reorganization of the blockchain, where one or
+	// more blocks of the current path were reverted in favor of a heavier
+	// fork.
+	ReorgEvent struct {
+		// Depth is the number of blocks that were reverted.
+		Depth uint64 `json:"depth"`
+
+		// CommonAncestor is the most recent block shared by the old and new
+		// forks, and CommonAncestorHeight is its height.
+		CommonAncestor       types.BlockID     `json:"commonancestor"`
+		CommonAncestorHeight types.BlockHeight `json:"commonancestorheight"`
+
+		// OldTip is the block that was the tip of the current path before
+		// the reorg, and NewTip is the tip afterwards.
+		OldTip types.BlockID `json:"oldtip"`
+		NewTip types.BlockID `json:"newtip"`
+	}
+
+	// A HeaderConsensusChange contains the block headers of a change to the
+	// consensus set. It is the header-only counterpart of ConsensusChange.
+	HeaderConsensusChange struct {
+		// ID is the id of the corresponding ConsensusChange, and can be used
+		// to resume a header subscription.
+		ID ConsensusChangeID
+
+		// RevertedBlockHeaders are the headers of the blocks that were
+		// reverted by the change, in the order that they were reverted.
+		RevertedBlockHeaders []types.BlockHeader
+
+		// AppliedBlockHeaders are the headers of the blocks that were applied
+		// by the change, in the order that they were applied.
+		AppliedBlockHeaders []types.BlockHeader
+
+		// ChildTarget defines the target of any block that would be the child
+		// of the block most recently appended to the consensus set.
+		ChildTarget types.Target
+
+		// Synced indicates whether or not the ConsensusSet is synced with its
+		// peers.
+		Synced bool
+	}
+
 	// A SiacoinOutputDiff indicates the addition or removal of a SiacoinOutput in
 	// the consensus set.
 	SiacoinOutputDiff struct {
@@ -194,15 +475,44 @@ type (
 		// a bool to indicate whether that block exists.
 		BlockByID(types.BlockID) (types.Block, types.BlockHeight, bool)
 
+		// BlocksInRange returns the blocks in the current path between the
+		// start and end heights, inclusive, along with their diffs.
+		BlocksInRange(start, end types.BlockHeight) ([]BlockDiffs, error)
+
 		// ChildTarget returns the target required to extend the current heaviest
 		// fork. This function is typically used by miners looking to extend the
 		// heaviest fork.
 		ChildTarget(types.BlockID) (types.Target, bool)
 
+		// CurrentTarget returns the target required to extend the current
+		// block.
+		CurrentTarget() types.Target
+
+		// Difficulty returns the difficulty of the next block on top of the
+		// current block.
+		Difficulty() types.Currency
+
+		// EstimatedHashrate estimates the hashrate of the network in hashes
+		// per second from the most recent 'window' blocks.
+		EstimatedHashrate(window types.BlockHeight) (types.Currency, error)
+
+		// EstimatedHashrateAt estimates the hashrate of the network at the
+		// block at 'height' in the current path from the 'window' blocks
+		// ending at that block.
+		EstimatedHashrateAt(height, window types.BlockHeight) (types.Currency, error)
+
 		// Close will shut down the consensus set, giving the module enough time to
 		// run any required closing routines.
 		Close() error
 
+		// Compact rewrites the consensus database into a fresh file, dropping
+		// orphaned blocks and stale buckets.
+		Compact() (ConsensusCompactionStats, error)
+
+		// CheckConsistency scans the consensus database and reports any
+		// violated invariants.
+		CheckConsistency() (ConsensusConsistencyReport, error)
+
 		// ConsensusSetSubscribe adds a subscriber to the list of subscribers
 		// and gives them every consensus change that has occurred since the
 		// change with the provided id. There are a few special cases,
@@ -210,10 +520,48 @@ type (
 		// A channel can be provided to abort the subscription process.
 		ConsensusSetSubscribe(ConsensusSetSubscriber, ConsensusChangeID, <-chan struct{}) error
 
+		// ConsensusSetSubscribeAsync behaves like ConsensusSetSubscribe, except
+		// that consensus changes are queued and delivered to the subscriber
+		// from a separate goroutine. A slow asynchronous subscriber will not
+		// stall the consensus set.
+		ConsensusSetSubscribeAsync(ConsensusSetSubscriber, ConsensusChangeID, <-chan struct{}) error
+
+		// ConsensusSetSubscribeFiltered behaves like ConsensusSetSubscribe,
+		// except that the subscriber is only sent the diffs selected by the
+		// filter.
+		ConsensusSetSubscribeFiltered(ConsensusSetSubscriber, ConsensusChangeID, ConsensusChangeFilter, <-chan struct{}) error
+
+		// ConsensusSetHeaderSubscribe adds a header subscriber to the list of
+		// subscribers and gives them the block headers of every consensus
+		// change that has occurred since the change with the provided id.
+		// The same special cases as ConsensusSetSubscribe apply.
+		ConsensusSetHeaderSubscribe(ConsensusSetHeaderSubscriber, ConsensusChangeID, <-chan struct{}) error
+
 		// CurrentBlock returns the latest block in the heaviest known
 		// blockchain.
 		CurrentBlock() types.Block
 
+		// DelayedOutputs returns the delayed siacoin outputs, such as miner
+		// payouts and file contract payouts, that mature at the provided
+		// height.
+		DelayedOutputs(types.BlockHeight) (map[types.SiacoinOutputID]types.SiacoinOutput, error)
+
+		// FileContract returns the open file contract with the given id, with
+		// a bool to indicate whether the file contract exists.
+		FileContract(types.FileContractID) (types.FileContract, bool)
+
+		// ForEachFileContract calls the provided function on every open file
+		// contract, stopping if the function returns an error.
+		ForEachFileContract(func(types.FileContractID, types.FileContract) error) error
+
+		// ForEachSiacoinOutput calls the provided function on every unspent
+		// siacoin output, stopping if the function returns an error.
+		ForEachSiacoinOutput(func(types.SiacoinOutputID, types.SiacoinOutput) error) error
+
+		// ForEachSiafundOutput calls the provided function on every unspent
+		// siafund output, stopping if the function returns an error.
+		ForEachSiafundOutput(func(types.SiafundOutputID, types.SiafundOutput) error) error
+
 		// Flush will cause the consensus set to finish all in-progress
 		// routines.
 		Flush() error
@@ -224,16 +572,63 @@ type (
 		// Synced returns true if the consensus set is synced with the network.
 		Synced() bool
 
+		// SyncProgress reports how far the consensus set has synced with the
+		// network.
+		SyncProgress() ConsensusSyncProgress
+
 		// InCurrentPath returns true if the block id presented is found in the
 		// current path, false otherwise.
 		InCurrentPath(types.BlockID) bool
 
+		// MedianTimePast returns the median timestamp of the most recent
+		// blocks in the current path. Depending on the active consensus
+		// rules, a new block must have a timestamp at least equal to or
+		// strictly greater than it.
+		MedianTimePast() types.Timestamp
+
 		// MinimumValidChildTimestamp returns the earliest timestamp that is
 		// valid on the current longest fork according to the consensus set. This is
 		// a required piece of information for the miner, who could otherwise be at
 		// risk of mining invalid blocks.
 		MinimumValidChildTimestamp(types.BlockID) (types.Timestamp, bool)
 
+		// RegisterBlockAcceptanceHook adds a hook that can veto blocks before
+		// they are added to the consensus set. An error is returned on the
+		// standard network.
+		RegisterBlockAcceptanceHook(BlockAcceptanceHook) error
+
+		// RegisterMetricsSink adds a sink that receives every measurement
+		// taken by the consensus set after registering.
+		RegisterMetricsSink(ConsensusMetricsSink)
+
+		// RegisterTransactionSource adds a source of unconfirmed
+		// transactions that are used to reconstruct compact blocks.
+		RegisterTransactionSource(TransactionSource)
+
+		// ReorgSubscribe adds a subscriber that is notified of every reorg
+		// that occurs after subscribing.
+		ReorgSubscribe(ConsensusSetReorgSubscriber)
+
+		// ReorgUnsubscribe removes a reorg subscriber. If the subscriber is
+		// not found, no action is taken.
+		ReorgUnsubscribe(ConsensusSetReorgSubscriber)
+
+		// SiacoinOutput returns the unspent siacoin output with the given id,
+		// with a bool to indicate whether the output exists.
+		SiacoinOutput(types.SiacoinOutputID) (types.SiacoinOutput, bool)
+
+		// SiafundOutput returns the unspent siafund output with the given id,
+		// with a bool to indicate whether the output exists.
+		SiafundOutput(types.SiafundOutputID) (types.SiafundOutput, bool)
+
+		// SiafundPoolAt returns the value of the siafund pool after the block
+		// at the given height in the current path was applied.
+		SiafundPoolAt(types.BlockHeight) (types.Currency, error)
+
+		// Snapshot returns a read-only view of the state of the consensus
+		// set, captured at a single point in time.
+		Snapshot() (ConsensusSnapshot, error)
+
 		// StorageProofSegment returns the segment to be used in the storage proof for
 		// a given file contract.
 		StorageProofSegment(types.FileContractID) (uint64, error)
@@ -244,10 +639,26 @@ type (
 		// transaction.
 		TryTransactionSet([]types.Transaction) (ConsensusChange, error)
 
+		// UnregisterBlockAcceptanceHook removes a block acceptance hook. If
+		// the hook is not found, no action is taken.
+		UnregisterBlockAcceptanceHook(BlockAcceptanceHook)
+
+		// UnregisterMetricsSink removes a metrics sink. If the sink is not
+		// found, no action is taken.
+		UnregisterMetricsSink(ConsensusMetricsSink)
+
+		// UnregisterTransactionSource removes a transaction source. If the
+		// source is not found, no action is taken.
+		UnregisterTransactionSource(TransactionSource)
+
 		// Unsubscribe removes a subscriber from the list of subscribers,
 		// allowing for garbage collection and rescanning. If the subscriber is
 		// not found in the subscriber database, no action is taken.
 		Unsubscribe(ConsensusSetSubscriber)
+
+		// UnsubscribeHeaders removes a header subscriber from the list of
+		// subscribers. If the subscriber is not found, no action is taken.
+		UnsubscribeHeaders(ConsensusSetHeaderSubscriber)
 	}
 )
 
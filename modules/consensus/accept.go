@@ -146,7 +146,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 // on the block. Such errors are handled outside of the transaction by the
 // caller. Switching to a managed tx through bolt will make this complexity
 // unneeded.
-func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *processedBlock) (ce changeEntry, err error) {
+func (cs *ConsensusSet) addBlockToTree(tx Tx, b types.Block, parent *processedBlock) (ce changeEntry, err error) {
 	// Prepare the child processed block associated with the parent block.
 	newNode := cs.newChild(tx, parent, b)
 
@@ -175,36 +175,11 @@ func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *proce
 	if err != nil {
 		return changeEntry{}, err
 	}
-	return ce, nil
-}
-
-// threadedSleepOnFutureBlock will sleep until the timestamp of a future block
-// has arrived.
-//
-// TODO: An attacker can broadcast a future block multiple times, resulting in a
-// goroutine spinup for each future block.  Need to prevent that.
-//
-// TODO: An attacker could produce a very large number of future blocks,
-// consuming memory. Need to prevent that.
-func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
-	// Add this thread to the threadgroup.
-	err := cs.tg.Add()
+	err = cs.applyChangeLogRetention(tx)
 	if err != nil {
-		return
-	}
-	defer cs.tg.Done()
-
-	// Perform a soft-sleep while we wait for the block to become valid.
-	select {
-	case <-cs.tg.StopChan():
-		return
-	case <-time.After(time.Duration(b.Timestamp-(types.CurrentTimestamp()+types.FutureThreshold)) * time.Second):
-		_, err := cs.managedAcceptBlocks([]types.Block{b})
-		if err != nil {
-			cs.log.Debugln("WARN: failed to accept a future block:", err)
-		}
-		cs.managedBroadcastBlock(b)
+		return changeEntry{}, err
 	}
+	return ce, nil
 }
 
 // managedAcceptBlocks will try to add blocks to the consensus set. If the
@@ -219,9 +194,9 @@ func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
 // consecutive calls to AcceptBlock with each successive call accepting the
 // child block of the previous call.
 func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExtended bool, err error) {
-	// Grab a lock on the consensus set.
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
+	// Once the lock has been released, wait for any asynchronous subscribers
+	// that have fallen too far behind.
+	defer cs.managedAsyncBackpressure()
 
 	// Make sure that blocks are consecutive. Though this isn't a strict
 	// requirement, if blocks are not consecutive then it becomes a lot harder
@@ -236,26 +211,64 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		}
 	}
 
+	// Verify the transaction signatures of the blocks in parallel before
+	// grabbing the lock, so that the expensive checks do not need to be
+	// repeated while the blocks are applied.
+	verified := cs.managedVerifyBlockTransactions(blocks, blockIDs)
+
+	// Orphans whose parents are added to the block tree are accepted after the
+	// lock has been released.
+	var orphans []types.Block
+	defer func() {
+		cs.managedAcceptOrphans(orphans)
+	}()
+
+	// Grab a lock on the consensus set.
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.verifiedBlocks = verified
+	defer func() {
+		cs.verifiedBlocks = nil
+	}()
+
 	// Verify the headers for every block, throw out known blocks, and the
 	// invalid blocks (which includes the children of invalid blocks).
 	chainExtended := false
 	changes := make([]changeEntry, 0, len(blocks))
-	setErr := cs.db.Update(func(tx *bolt.Tx) error {
+	var auditEntries []auditEntry
+	var startHeight, endHeight types.BlockHeight
+	start := time.Now()
+	setErr := cs.db.Update(func(tx Tx) error {
+		startHeight = blockHeight(tx)
+		defer func() {
+			endHeight = blockHeight(tx)
+		}()
 		for i := 0; i < len(blocks); i++ {
 			// Start by checking the header of the block.
-			parent, err := cs.validateHeaderAndBlock(boltTxWrapper{tx}, blocks[i], blockIDs[i])
+			parent, err := cs.validateHeaderAndBlock(txWrapper{tx}, blocks[i], blockIDs[i])
 			if err == modules.ErrBlockKnown {
 				// Skip over known blocks.
 				continue
 			}
 			if err == errFutureTimestamp {
 				// Queue the block to be tried again if it is a future block.
-				go cs.threadedSleepOnFutureBlock(blocks[i])
+				cs.queueFutureBlock(blocks[i], blockIDs[i])
+			}
+			if err == errOrphan {
+				// Hold on to the block and its children in case the parent
+				// arrives later, if they are plausibly valid.
+				cs.addOrphans(blocks[i:], blockIDs[i:], minOrphanTarget(tx))
 			}
 			if err != nil {
 				return err
 			}
 
+			// Give the block acceptance hooks a chance to veto the block.
+			err = cs.checkBlockHooks(blocks[i], parent.Height+1)
+			if err != nil {
+				return err
+			}
+
 			// Try adding the block to consensus.
 			changeEntry, err := cs.addBlockToTree(tx, blocks[i], parent)
 			if err == nil {
@@ -269,6 +282,12 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 					reverted = append(reverted, b.String()[:6])
 				}
 			}
+			if err == nil && cs.auditLog != nil {
+				// Record the resulting state for the audit log.
+				var ae auditEntry
+				ae, err = cs.computeAuditEntry(tx, changeEntry)
+				auditEntries = append(auditEntries, ae)
+			}
 			if err == modules.ErrNonExtendingBlock {
 				err = nil
 			}
@@ -284,6 +303,7 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		}
 		return nil
 	})
+	cs.recordMetric(modules.ConsensusMetricDatabaseUpdate, startHeight, len(blocks), time.Since(start))
 	if _, ok := setErr.(bolt.MmapError); ok {
 		cs.log.Println("ERROR: Bolt mmap failed:", setErr)
 		fmt.Println("Blockchain database has run out of disk space!")
@@ -299,13 +319,23 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		}
 		return false, setErr
 	}
+	// Every block in the set is now part of the block tree, meaning that any
+	// of their children in the orphan pool can be accepted.
+	orphans = cs.removeOrphanChildren(blockIDs)
+
+	// The changes are written to the audit log before they are sent to
+	// subscribers.
+	cs.appendAuditLog(auditEntries)
+
 	// Stop here if the blocks did not extend the longest blockchain.
 	if !chainExtended {
 		return false, modules.ErrNonExtendingBlock
 	}
+	cs.recordSyncSample(endHeight)
 	// Send any changes to subscribers.
 	for i := 0; i < len(changes); i++ {
 		cs.updateSubscribers(changes[i])
+		cs.updateReorgSubscribers(changes[i])
 	}
 	return chainExtended, nil
 }
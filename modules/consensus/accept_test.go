@@ -9,8 +9,6 @@ import (
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 var (
@@ -1168,7 +1166,7 @@ func TestChainedAcceptBlock(t *testing.T) {
 	// Check that every change recorded in 'bcs' is also available in the
 	// consensus set.
 	for _, change := range bcs.changes {
-		err := cst2.cs.db.Update(func(tx *bolt.Tx) error {
+		err := cst2.cs.db.Update(func(tx Tx) error {
 			_, exists := getEntry(tx, change)
 			if !exists {
 				t.Error("an entry was provided that doesn't exist")
@@ -7,13 +7,11 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 // applySiacoinInputs takes all of the siacoin inputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applySiacoinInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applySiacoinInputs(tx Tx, pb *processedBlock, t types.Transaction) {
 	// Remove all siacoin inputs from the unspent siacoin outputs list.
 	for _, sci := range t.SiacoinInputs {
 		sco, err := getSiacoinOutput(tx, sci.ParentID)
@@ -32,7 +30,7 @@ func applySiacoinInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 
 // applySiacoinOutputs takes all of the siacoin outputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applySiacoinOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applySiacoinOutputs(tx Tx, pb *processedBlock, t types.Transaction) {
 	// Add all siacoin outputs to the unspent siacoin outputs list.
 	for i, sco := range t.SiacoinOutputs {
 		scoid := t.SiacoinOutputID(uint64(i))
@@ -49,7 +47,7 @@ func applySiacoinOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 // applyFileContracts iterates through all of the file contracts in a
 // transaction and applies them to the state, updating the diffs in the proccesed
 // block.
-func applyFileContracts(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyFileContracts(tx Tx, pb *processedBlock, t types.Transaction) {
 	for i, fc := range t.FileContracts {
 		fcid := t.FileContractID(uint64(i))
 		fcd := modules.FileContractDiff{
@@ -76,7 +74,7 @@ func applyFileContracts(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 // applyTxFileContractRevisions iterates through all of the file contract
 // revisions in a transaction and applies them to the state, updating the diffs
 // in the processed block.
-func applyFileContractRevisions(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyFileContractRevisions(tx Tx, pb *processedBlock, t types.Transaction) {
 	for _, fcr := range t.FileContractRevisions {
 		fc, err := getFileContract(tx, fcr.ParentID)
 		if build.DEBUG && err != nil {
@@ -117,7 +115,7 @@ func applyFileContractRevisions(tx *bolt.Tx, pb *processedBlock, t types.Transac
 // applyTxStorageProofs iterates through all of the storage proofs in a
 // transaction and applies them to the state, updating the diffs in the processed
 // block.
-func applyStorageProofs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyStorageProofs(tx Tx, pb *processedBlock, t types.Transaction) {
 	for _, sp := range t.StorageProofs {
 		fc, err := getFileContract(tx, sp.ParentID)
 		if build.DEBUG && err != nil {
@@ -149,7 +147,7 @@ func applyStorageProofs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 
 // applyTxSiafundInputs takes all of the siafund inputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applySiafundInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applySiafundInputs(tx Tx, pb *processedBlock, t types.Transaction) {
 	for _, sfi := range t.SiafundInputs {
 		// Calculate the volume of siacoins to put in the claim output.
 		sfo, err := getSiafundOutput(tx, sfi.ParentID)
@@ -186,7 +184,7 @@ func applySiafundInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 }
 
 // applySiafundOutput applies a siafund output to the consensus set.
-func applySiafundOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applySiafundOutputs(tx Tx, pb *processedBlock, t types.Transaction) {
 	for i, sfo := range t.SiafundOutputs {
 		sfoid := t.SiafundOutputID(uint64(i))
 		sfo.ClaimStart = getSiafundPool(tx)
@@ -203,7 +201,7 @@ func applySiafundOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 // applyTransaction applies the contents of a transaction to the ConsensusSet.
 // This produces a set of diffs, which are stored in the blockNode containing
 // the transaction. No verification is done by this function.
-func applyTransaction(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyTransaction(tx Tx, pb *processedBlock, t types.Transaction) {
 	applySiacoinInputs(tx, pb, t)
 	applySiacoinOutputs(tx, pb, t)
 	applyFileContracts(tx, pb, t)
@@ -0,0 +1,288 @@
+package consensus
+
+// auditlog.go implements the audit log of the consensus set. While the audit
+// log is active, every change to the consensus set is appended to the log
+// along with the hash of the diffs of the applied blocks. The log can be
+// replayed into an empty consensus set, which checks that the replayed blocks
+// produce the recorded diffs. This is useful when debugging nodes whose
+// consensus states have diverged, as the replay stops at the first block
+// whose diffs differ. Hashing the diffs rather than the entire consensus
+// state keeps the cost of the log proportional to the size of the change.
+//
+// A new log starts with the current path of the consensus set, one entry per
+// block, so that it can be replayed from the genesis block. The last of these
+// entries also carries the consensus checksum of the state when the log was
+// started. If the audit log is stopped and later restarted on the same file,
+// any changes made in between are missing from the log, and a replay will
+// fail at the first change that depends on them.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// auditLogMaxEntrySize is the maximum size of an encoded audit log entry.
+	// An entry contains every block applied by a change, which can be a large
+	// number of blocks for a deep reorg.
+	auditLogMaxEntrySize = 1 << 30
+)
+
+var (
+	// auditLogMetadata is the header of the audit log file.
+	auditLogMetadata = persist.Metadata{
+		Header:  "Consensus Audit Log",
+		Version: "1.0",
+	}
+
+	errAuditLogActive   = errors.New("the audit log is already active")
+	errAuditLogInactive = errors.New("the audit log is not active")
+	errAuditLogMismatch = errors.New("replayed consensus state does not match the audit log")
+)
+
+// auditEntry is a single change to the consensus set recorded in the audit
+// log.
+type auditEntry struct {
+	RevertedBlocks []types.BlockID
+	AppliedBlocks  []types.Block
+
+	// Height is the height of the consensus set after the change, and
+	// DiffsHash is the hash of the diffs of the applied blocks.
+	// ConsensusChecksum is the checksum of the consensus set after the
+	// change. An empty checksum is not checked during a replay.
+	Height            types.BlockHeight
+	DiffsHash         crypto.Hash
+	ConsensusChecksum crypto.Hash
+}
+
+// diffsHash returns the hash of the diffs of the provided blocks.
+func diffsHash(tx Tx, ids []types.BlockID) (crypto.Hash, error) {
+	h := crypto.NewHash()
+	enc := encoding.NewEncoder(h)
+	for _, id := range ids {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return crypto.Hash{}, err
+		}
+		err = enc.EncodeAll(pb.SiacoinOutputDiffs, pb.FileContractDiffs, pb.SiafundOutputDiffs, pb.DelayedSiacoinOutputDiffs, pb.SiafundPoolDiffs)
+		if err != nil {
+			return crypto.Hash{}, err
+		}
+	}
+	var dh crypto.Hash
+	copy(dh[:], h.Sum(nil))
+	return dh, nil
+}
+
+// computeAuditEntry computes the audit log entry for a change entry that has
+// just been applied.
+func (cs *ConsensusSet) computeAuditEntry(tx Tx, ce changeEntry) (auditEntry, error) {
+	ae := auditEntry{
+		RevertedBlocks: ce.RevertedBlocks,
+		Height:         blockHeight(tx),
+	}
+	for _, id := range ce.AppliedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return auditEntry{}, err
+		}
+		ae.AppliedBlocks = append(ae.AppliedBlocks, pb.Block)
+	}
+	var err error
+	ae.DiffsHash, err = diffsHash(tx, ce.AppliedBlocks)
+	if err != nil {
+		return auditEntry{}, err
+	}
+	return ae, nil
+}
+
+// appendAuditLog writes entries to the audit log and syncs the log to disk.
+func (cs *ConsensusSet) appendAuditLog(entries []auditEntry) {
+	if cs.auditLog == nil || len(entries) == 0 {
+		return
+	}
+	for _, ae := range entries {
+		err := encoding.WriteObject(cs.auditLog, ae)
+		if err != nil {
+			cs.log.Println("ERROR: unable to write to the audit log:", err)
+			return
+		}
+	}
+	err := cs.auditLog.Sync()
+	if err != nil {
+		cs.log.Println("ERROR: unable to sync the audit log:", err)
+	}
+}
+
+// closeAuditLog closes the audit log if it is active.
+func (cs *ConsensusSet) closeAuditLog() error {
+	if cs.auditLog == nil {
+		return errAuditLogInactive
+	}
+	err := cs.auditLog.Close()
+	cs.auditLog = nil
+	return err
+}
+
+// StartAuditLog starts appending every change to the consensus set to the
+// audit log at filename. If the file does not exist, it is created and the
+// current path is written to it first.
+func (cs *ConsensusSet) StartAuditLog(filename string) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.auditLog != nil {
+		return errAuditLogActive
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if stat.Size() != 0 {
+		// Continue an existing log.
+		var md persist.Metadata
+		err = encoding.ReadObject(f, &md, 1<<10)
+		if err == nil && md != auditLogMetadata {
+			err = persist.ErrBadHeader
+		}
+	} else {
+		// Start a new log with the current path.
+		err = encoding.WriteObject(f, auditLogMetadata)
+		if err == nil {
+			err = cs.db.View(func(tx Tx) error {
+				height := blockHeight(tx)
+				for h := types.BlockHeight(1); h <= height; h++ {
+					id, err := getPath(tx, h)
+					if err != nil {
+						return err
+					}
+					pb, err := getBlockMap(tx, id)
+					if err != nil {
+						return err
+					}
+					dh, err := diffsHash(tx, []types.BlockID{id})
+					if err != nil {
+						return err
+					}
+					ae := auditEntry{
+						AppliedBlocks: []types.Block{pb.Block},
+						Height:        h,
+						DiffsHash:     dh,
+					}
+					if h == height {
+						ae.ConsensusChecksum = consensusChecksum(tx)
+					}
+					if err := encoding.WriteObject(f, ae); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	cs.auditLog = f
+	return nil
+}
+
+// StopAuditLog stops appending changes to the audit log and closes it.
+func (cs *ConsensusSet) StopAuditLog() error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.closeAuditLog()
+}
+
+// ReplayAuditLog applies every change recorded in the audit log r to the
+// consensus set, checking after each change that the replayed blocks produced
+// the diffs recorded in the log. The consensus set should be
+// empty, and must not receive blocks from elsewhere during the replay. The
+// number of replayed entries is returned.
+func (cs *ConsensusSet) ReplayAuditLog(r io.Reader) (entries int, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+
+	var md persist.Metadata
+	err = encoding.ReadObject(r, &md, 1<<10)
+	if err != nil {
+		return 0, err
+	}
+	if md != auditLogMetadata {
+		return 0, persist.ErrBadHeader
+	}
+
+	for {
+		var ae auditEntry
+		err = encoding.ReadObject(r, &ae, auditLogMaxEntrySize)
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		if len(ae.AppliedBlocks) == 0 {
+			return entries, errors.New("audit log entry does not apply any blocks")
+		}
+
+		_, err = cs.managedAcceptBlocks(ae.AppliedBlocks)
+		if err != nil {
+			return entries, fmt.Errorf("unable to replay audit log entry %v: %v", entries, err)
+		}
+
+		// Check the resulting state.
+		cs.mu.RLock()
+		err = cs.db.View(func(tx Tx) error {
+			if blockHeight(tx) != ae.Height || currentBlockID(tx) != ae.AppliedBlocks[len(ae.AppliedBlocks)-1].ID() {
+				return errAuditLogMismatch
+			}
+			ids := make([]types.BlockID, len(ae.AppliedBlocks))
+			for i, b := range ae.AppliedBlocks {
+				ids[i] = b.ID()
+			}
+			dh, err := diffsHash(tx, ids)
+			if err != nil {
+				return err
+			} else if dh != ae.DiffsHash {
+				return errAuditLogMismatch
+			}
+			if ae.ConsensusChecksum != (crypto.Hash{}) && consensusChecksum(tx) != ae.ConsensusChecksum {
+				return errAuditLogMismatch
+			}
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return entries, fmt.Errorf("audit log entry %v at height %v: %v", entries, ae.Height, err)
+		}
+		entries++
+	}
+}
@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+)
+
+// TestAuditLog checks that replaying an audit log into an empty consensus set
+// reconstructs the state of the consensus set that wrote the log.
+func TestAuditLog(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "sub")
+	if err := os.MkdirAll(testdir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	logFilename := filepath.Join(testdir, "audit.log")
+	if err := cst.cs.StartAuditLog(logFilename); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.StartAuditLog(logFilename); err != errAuditLogActive {
+		t.Fatal("expected errAuditLogActive, got", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cst.cs.StopAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarting the log should continue the existing file.
+	if err := cst.cs.StartAuditLog(logFilename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.StopAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay the log into an empty consensus set.
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewMemory(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	f, err := os.Open(logFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := cs.ReplayAuditLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != int(cst.cs.Height()) {
+		t.Fatalf("expected %v entries, got %v", cst.cs.Height(), entries)
+	}
+	if cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("replayed consensus set does not match")
+	}
+}
@@ -18,11 +18,22 @@ type stdBlockRuleHelper struct{}
 
 // minimumValidChildTimestamp returns the earliest timestamp that a child node
 // can have while still being valid. See section 'Block Timestamps' in
-// Consensus.md.
+// Consensus.md. Once RuleMedianTimePast is active, the timestamp of the child
+// must be strictly greater than the median-time-past.
 //
 // To boost performance, minimumValidChildTimestamp is passed a bucket that it
 // can use from inside of a boltdb transaction.
 func (rh stdBlockRuleHelper) minimumValidChildTimestamp(blockMap dbBucket, pb *processedBlock) types.Timestamp {
+	mtp := medianTimePast(blockMap, pb)
+	if types.RuleMedianTimePast.Active(pb.Height + 1) {
+		return mtp + 1
+	}
+	return mtp
+}
+
+// medianTimePast returns the median timestamp of the previous
+// MedianTimestampWindow blocks, ending with the provided block.
+func medianTimePast(blockMap dbBucket, pb *processedBlock) types.Timestamp {
 	// Get the previous MedianTimestampWindow timestamps.
 	windowTimes := make(types.TimestampSlice, types.MedianTimestampWindow)
 	windowTimes[0] = pb.Block.Timestamp
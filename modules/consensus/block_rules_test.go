@@ -0,0 +1,48 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMedianTimePastRule checks that the minimum valid child timestamp moves
+// past the median-time-past once RuleMedianTimePast is active.
+//
+// The test modifies the activation height of a consensus rule, and therefore
+// must not be run in parallel.
+func TestMedianTimePastRule(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	mtp := cst.cs.MedianTimePast()
+	earliest, ok := cst.cs.MinimumValidChildTimestamp(cst.cs.CurrentBlock().ID())
+	if !ok || earliest != mtp {
+		t.Fatalf("expected minimum timestamp %v, got %v", mtp, earliest)
+	}
+
+	restore := types.SimulateActivation(types.RuleMedianTimePast, cst.cs.Height()+1)
+	defer restore()
+	earliest, ok = cst.cs.MinimumValidChildTimestamp(cst.cs.CurrentBlock().ID())
+	if !ok || earliest != mtp+1 {
+		t.Fatalf("expected minimum timestamp %v, got %v", mtp+1, earliest)
+	}
+
+	// A block with a timestamp equal to the median-time-past should be
+	// rejected.
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Timestamp = mtp
+	solvedBlock, _ := cst.miner.SolveBlock(block, target)
+	if err := cst.cs.AcceptBlock(solvedBlock); err != errEarlyTimestamp {
+		t.Fatalf("expected %v, got %v", errEarlyTimestamp, err)
+	}
+}
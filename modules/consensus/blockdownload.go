@@ -0,0 +1,237 @@
+package consensus
+
+// blockdownload.go implements the scheduler that downloads a known chain of
+// blocks from several peers at once. The chain is split into disjoint ranges
+// of up to MaxCatchUpBlocks blocks. Every peer gets a worker that repeatedly
+// claims the first range that nobody is downloading yet, so fast peers end up
+// downloading more ranges than slow ones. The ranges are accepted strictly in
+// order. A worker that fails to download a range releases it for the other
+// peers and stops, and the download ends once no worker is left that can
+// download the next range.
+
+import (
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// downloadLookahead is the number of ranges per worker that may be
+	// downloaded ahead of the first range that has not been accepted yet. It
+	// bounds the memory used by blocks that are waiting for their parents.
+	downloadLookahead = 4
+)
+
+// blockRange is a set of consecutive blocks that are downloaded together.
+type blockRange struct {
+	start  types.BlockHeight
+	ids    []types.BlockID
+	blocks []types.Block
+	peer   modules.NetAddress
+	busy   bool
+}
+
+// downloadSource is a peer that blocks are downloaded from. A peer only knows
+// the blocks up to the end of its own chain, so it can only download the
+// first numRanges ranges.
+type downloadSource struct {
+	addr      modules.NetAddress
+	numRanges int
+}
+
+// peerThroughput tracks how many blocks were downloaded from a peer, and how
+// long it took.
+type peerThroughput struct {
+	blocks   int
+	ranges   int
+	duration time.Duration
+}
+
+// blockDownload schedules the download of a chain of blocks.
+type blockDownload struct {
+	cs         *ConsensusSet
+	ranges     []*blockRange
+	next       int // index of the first range that has not been accepted
+	workers    int
+	stopped    bool
+	throughput map[modules.NetAddress]*peerThroughput
+
+	cond *sync.Cond
+	mu   sync.Mutex
+}
+
+// newBlockDownload splits the blocks with the given IDs into ranges. The first
+// block is at startHeight, and the IDs must form a chain.
+func newBlockDownload(cs *ConsensusSet, startHeight types.BlockHeight, ids []types.BlockID) *blockDownload {
+	bd := &blockDownload{
+		cs:         cs,
+		throughput: make(map[modules.NetAddress]*peerThroughput),
+	}
+	bd.cond = sync.NewCond(&bd.mu)
+	for i := 0; i < len(ids); i += int(MaxCatchUpBlocks) {
+		end := i + int(MaxCatchUpBlocks)
+		if end > len(ids) {
+			end = len(ids)
+		}
+		bd.ranges = append(bd.ranges, &blockRange{
+			start: startHeight + types.BlockHeight(i),
+			ids:   ids[i:end],
+		})
+	}
+	return bd
+}
+
+// claimRange returns the index of the first range that a worker can download,
+// blocking until one is available. ok is false if the download is over or if
+// there are no more ranges that the worker can download.
+func (bd *blockDownload) claimRange(numRanges int) (index int, ok bool) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	for {
+		if bd.stopped || bd.next >= numRanges {
+			return 0, false
+		}
+		limit := bd.next + downloadLookahead*bd.workers
+		for i := bd.next; i < numRanges && i < limit; i++ {
+			r := bd.ranges[i]
+			if !r.busy && r.blocks == nil {
+				r.busy = true
+				return i, true
+			}
+		}
+		bd.cond.Wait()
+	}
+}
+
+// finishRange stores the result of downloading a range. If the download
+// failed, the range is released so that another worker can retry it.
+func (bd *blockDownload) finishRange(r *blockRange, addr modules.NetAddress, blocks []types.Block, elapsed time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	defer bd.cond.Broadcast()
+	r.busy = false
+	if blocks == nil {
+		return
+	}
+	r.blocks = blocks
+	r.peer = addr
+	pt, exists := bd.throughput[addr]
+	if !exists {
+		pt = new(peerThroughput)
+		bd.throughput[addr] = pt
+	}
+	pt.blocks += len(blocks)
+	pt.ranges++
+	pt.duration += elapsed
+}
+
+// threadedDownloadRanges is the worker that downloads ranges from a single
+// peer until the peer fails or there are no more ranges it can download.
+func (bd *blockDownload) threadedDownloadRanges(src downloadSource) {
+	defer func() {
+		bd.mu.Lock()
+		bd.workers--
+		bd.mu.Unlock()
+		bd.cond.Broadcast()
+	}()
+	for {
+		i, ok := bd.claimRange(src.numRanges)
+		if !ok {
+			return
+		}
+		r := bd.ranges[i]
+		var blocks []types.Block
+		start := time.Now()
+		err := bd.cs.gateway.RPC(src.addr, "BlocksByID", managedReceiveBlocksByID(r.ids, &blocks))
+		if err == nil && len(blocks) != len(r.ids) {
+			err = errNoBlocksSent
+		}
+		if err != nil {
+			bd.finishRange(r, src.addr, nil, 0)
+			bd.cs.log.Debugf("WARN: dropping %v from the block download after it failed to send blocks %v-%v: %v", src.addr, r.start, r.start+types.BlockHeight(len(r.ids))-1, err)
+			if err == errBlockNotRequested {
+				bd.cs.gateway.PenalizePeer(src.addr, modules.PenaltyInvalidBlock)
+			} else if isTimeoutErr(err) {
+				bd.cs.gateway.PenalizePeer(src.addr, modules.PenaltyStall)
+			}
+			return
+		}
+		elapsed := time.Since(start)
+		bd.finishRange(r, src.addr, blocks, elapsed)
+
+		bd.cs.mu.RLock()
+		bd.cs.recordPeerMetric(modules.ConsensusMetricBlocksDownloaded, src.addr, r.start, len(blocks), elapsed)
+		bd.cs.mu.RUnlock()
+	}
+}
+
+// logThroughput logs the number of blocks downloaded from each peer and the
+// rate at which they were downloaded.
+func (bd *blockDownload) logThroughput() {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	for addr, pt := range bd.throughput {
+		rate := float64(pt.blocks) / pt.duration.Seconds()
+		bd.cs.log.Printf("INFO: downloaded %v blocks in %v ranges from %v (%.2f blocks/s)", pt.blocks, pt.ranges, addr, rate)
+	}
+}
+
+// managedDownload downloads the blocks from the sources and accepts them in
+// order. Peers whose blocks are rejected are penalized, and the download stops
+// at the first range that is rejected or that no peer was able to send.
+func (bd *blockDownload) managedDownload(sources []downloadSource) error {
+	bd.mu.Lock()
+	bd.workers = len(sources)
+	bd.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src downloadSource) {
+			defer wg.Done()
+			bd.threadedDownloadRanges(src)
+		}(src)
+	}
+	defer func() {
+		bd.mu.Lock()
+		bd.stopped = true
+		bd.mu.Unlock()
+		bd.cond.Broadcast()
+		wg.Wait()
+		bd.logThroughput()
+	}()
+
+	for {
+		bd.mu.Lock()
+		for bd.next < len(bd.ranges) && bd.ranges[bd.next].blocks == nil && bd.workers > 0 {
+			bd.cond.Wait()
+		}
+		if bd.next == len(bd.ranges) || bd.ranges[bd.next].blocks == nil {
+			bd.mu.Unlock()
+			return nil
+		}
+		r := bd.ranges[bd.next]
+		bd.mu.Unlock()
+
+		_, err := bd.cs.managedAcceptBlocks(r.blocks)
+		bd.cs.managedPenalizeInvalidBlocks(r.peer, r.blocks, err)
+		if err != nil && err != modules.ErrNonExtendingBlock && err != modules.ErrBlockKnown {
+			bd.cs.log.Printf("WARN: block download stopped after blocks %v-%v from %v were rejected: %v", r.start, r.start+types.BlockHeight(len(r.ids))-1, r.peer, err)
+			return nil
+		}
+
+		// Free the blocks of the accepted range.
+		bd.mu.Lock()
+		r.blocks = nil
+		bd.next++
+		bd.mu.Unlock()
+		bd.cond.Broadcast()
+
+		select {
+		case <-bd.cs.tg.StopChan():
+			return errEarlyStop
+		default:
+		}
+	}
+}
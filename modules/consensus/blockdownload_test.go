@@ -0,0 +1,116 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlockDownload checks that the download scheduler downloads ranges from
+// several peers, retries the ranges of peers that fail, and reports the
+// throughput of every peer.
+func TestBlockDownload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	remote1, err := createConsensusSetTester(t.Name() + "-remote1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote1.Close()
+	for i := types.BlockHeight(0); i < 4*MaxCatchUpBlocks; i++ {
+		if _, err := remote1.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remote2, err := blankConsensusSetTester(t.Name()+"-remote2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote2.Close()
+	if err := remote2.gateway.Connect(remote1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if remote2.cs.CurrentBlock().ID() != remote1.cs.CurrentBlock().ID() {
+			return errors.New("remote2 did not sync with remote1")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-local")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(mockGatewayNoRPCs{g}, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	mms := &mockMetricsSink{metrics: make(map[modules.ConsensusMetricType][]modules.ConsensusMetric)}
+	cs.RegisterMetricsSink(mms)
+	peers := []modules.NetAddress{remote1.gateway.Address(), remote2.gateway.Address()}
+	for _, addr := range peers {
+		if err := g.Connect(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []types.BlockID
+	for h := types.BlockHeight(1); h <= remote1.cs.Height(); h++ {
+		b, _ := remote1.cs.BlockAtHeight(h)
+		ids = append(ids, b.ID())
+	}
+	bd := newBlockDownload(cs, 1, ids)
+	if len(bd.ranges) < 2 {
+		t.Fatal("expected several ranges, got", len(bd.ranges))
+	}
+
+	// The first source is not connected, so every range that it claims has to
+	// be downloaded from the other peers.
+	sources := []downloadSource{
+		{"127.0.0.1:1", len(bd.ranges)},
+		{peers[0], len(bd.ranges)},
+		{peers[1], len(bd.ranges)},
+	}
+	if err := bd.managedDownload(sources); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != remote1.cs.CurrentBlock().ID() {
+		t.Fatalf("local did not sync: height %v, remote height %v", cs.Height(), remote1.cs.Height())
+	}
+
+	// Every block should have been reported exactly once, by one of the
+	// connected peers.
+	downloaded := make(map[types.BlockHeight]bool)
+	var total int
+	for _, m := range mms.metrics[modules.ConsensusMetricBlocksDownloaded] {
+		if m.Peer != peers[0] && m.Peer != peers[1] {
+			t.Fatal("blocks were reported for the wrong peer:", m.Peer)
+		} else if downloaded[m.Height] {
+			t.Fatal("range was reported twice:", m.Height)
+		}
+		downloaded[m.Height] = true
+		total += m.Count
+	}
+	if total != len(ids) {
+		t.Fatalf("expected %v blocks to be reported, got %v", len(ids), total)
+	}
+	for addr, pt := range bd.throughput {
+		if addr == sources[0].addr || pt.blocks == 0 {
+			t.Fatal("wrong throughput for", addr, pt)
+		}
+	}
+}
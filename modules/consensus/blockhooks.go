@@ -0,0 +1,77 @@
+package consensus
+
+// blockhooks.go implements the block acceptance hooks of the consensus set. A
+// hook is shown every block that passed header validation and can veto it
+// before it is added to the block tree, which allows custom acceptance rules
+// to be tried out on dev and testing networks without changing the consensus
+// code. Hooks cannot be registered on the standard network, as a vetoed block
+// would fork the node off of the network.
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBlockHooksDisabled = errors.New("block acceptance hooks are not available on the standard network")
+	errBlockVetoed        = errors.New("block was vetoed by a block acceptance hook")
+)
+
+// checkBlockHooks shows a block to every block acceptance hook, returning an
+// error if any of the hooks vetoes the block. The consensus set must be locked
+// when checkBlockHooks is called.
+func (cs *ConsensusSet) checkBlockHooks(b types.Block, height types.BlockHeight) error {
+	for _, hook := range cs.blockHooks {
+		if err := hook.CheckBlock(b, height); err != nil {
+			return fmt.Errorf("%v: %v", errBlockVetoed, err)
+		}
+	}
+	return nil
+}
+
+// RegisterBlockAcceptanceHook adds a hook that is shown every block before it
+// is added to the block tree, and that can veto the block by returning an
+// error. Hooks are only available on dev and testing builds.
+func (cs *ConsensusSet) RegisterBlockAcceptanceHook(hook modules.BlockAcceptanceHook) error {
+	if build.Release == "standard" {
+		return errBlockHooksDisabled
+	}
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, h := range cs.blockHooks {
+		if h == hook {
+			return errors.New("block acceptance hook is already registered")
+		}
+	}
+	cs.blockHooks = append(cs.blockHooks, hook)
+	return nil
+}
+
+// UnregisterBlockAcceptanceHook removes a block acceptance hook. If the hook
+// is not found, no action is taken.
+func (cs *ConsensusSet) UnregisterBlockAcceptanceHook(hook modules.BlockAcceptanceHook) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for i := range cs.blockHooks {
+		if cs.blockHooks[i] == hook {
+			cs.blockHooks[i] = nil
+			cs.blockHooks = append(cs.blockHooks[:i], cs.blockHooks[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// vetoHook is a block acceptance hook that vetoes every block at or above a
+// height.
+type vetoHook struct {
+	height  types.BlockHeight
+	checked int
+}
+
+// CheckBlock implements modules.BlockAcceptanceHook.
+func (vh *vetoHook) CheckBlock(b types.Block, height types.BlockHeight) error {
+	vh.checked++
+	if height >= vh.height {
+		return errors.New("height is vetoed")
+	}
+	return nil
+}
+
+// TestBlockAcceptanceHooks checks that a registered hook can veto blocks, and
+// that vetoed blocks can be accepted after the hook is unregistered.
+func TestBlockAcceptanceHooks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Allow the next block, but veto the one after it.
+	height := cst.cs.Height()
+	vh := &vetoHook{height: height + 2}
+	if err := cst.cs.RegisterBlockAcceptanceHook(vh); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.RegisterBlockAcceptanceHook(vh); err == nil {
+		t.Fatal("registering a hook twice should fail")
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vetoed, _ := cst.miner.SolveBlock(block, target)
+	if err := cst.cs.AcceptBlock(vetoed); err == nil {
+		t.Fatal("expected the hook to veto the block")
+	}
+	if cst.cs.Height() != height+1 {
+		t.Fatal("vetoed block was added to the consensus set")
+	}
+	if vh.checked != 2 {
+		t.Fatal("expected the hook to check 2 blocks, got", vh.checked)
+	}
+
+	// After unregistering, the same block should be accepted.
+	cst.cs.UnregisterBlockAcceptanceHook(vh)
+	if err := cst.cs.AcceptBlock(vetoed); err != nil {
+		t.Fatal(err)
+	}
+	if cst.cs.Height() != height+2 {
+		t.Fatal("block was not accepted after the hook was unregistered")
+	}
+	if vh.checked != 2 {
+		t.Fatal("unregistered hook was still called")
+	}
+}
@@ -15,15 +15,25 @@ package consensus
 //
 // Initialization only needs to worry about creating the blank change entry,
 // the genesis block will call 'append' later on during initialization.
+//
+// The changelog can be pruned, removing the oldest entries. The
+// 'ChangeLogHeadID' key points to the oldest entry that is still present, and
+// the 'ChangeLogSize' key tracks the number of entries in the list. Databases
+// created before pruning was supported have neither key, which is equivalent
+// to a changelog that starts at the genesis entry and has never been pruned.
+//
+// If the 'ChangeLogRetention' key is set, the changelog is pruned to the
+// retention window every time a new entry is appended, which keeps the size of
+// the changelog bounded on long-running nodes.
 
 import (
+	"errors"
+
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 var (
@@ -35,6 +45,24 @@ var (
 	// ChangeLogTailID is a key that points to the id of the current changelog
 	// tail.
 	ChangeLogTailID = []byte("ChangeLogTailID")
+
+	// ChangeLogHeadID is a key that points to the id of the oldest change
+	// entry still present in the changelog. The key is only set once the
+	// changelog has been pruned.
+	ChangeLogHeadID = []byte("ChangeLogHeadID")
+
+	// ChangeLogSize is a key that points to the number of change entries in
+	// the changelog.
+	ChangeLogSize = []byte("ChangeLogSize")
+
+	// ChangeLogRetention is a key that points to the number of change entries
+	// that are kept when the changelog is automatically pruned. A retention of
+	// 0, or a missing key, disables automatic pruning.
+	ChangeLogRetention = []byte("ChangeLogRetention")
+)
+
+var (
+	errPruneChangeLogTail = errors.New("cannot prune the tail of the changelog")
 )
 
 type (
@@ -53,7 +81,7 @@ type (
 )
 
 // appendChangeLog adds a new change entry to the change log.
-func appendChangeLog(tx *bolt.Tx, ce changeEntry) error {
+func appendChangeLog(tx Tx, ce changeEntry) error {
 	// Insert the change entry.
 	cl := tx.Bucket(ChangeLog)
 	ceid := ce.ID()
@@ -84,17 +112,119 @@ func appendChangeLog(tx *bolt.Tx, ce changeEntry) error {
 		}
 	}
 
-	// Update the tail id.
+	// Update the tail id and the size.
 	err = cl.Put(ChangeLogTailID, ceid[:])
 	if err != nil {
 		return err
 	}
-	return nil
+	return cl.Put(ChangeLogSize, encoding.Marshal(changeLogSize(tx)+1))
+}
+
+// changeLogHeadID returns the id of the oldest entry in the changelog.
+func (cs *ConsensusSet) changeLogHeadID(tx Tx) (id modules.ConsensusChangeID) {
+	headIDBytes := tx.Bucket(ChangeLog).Get(ChangeLogHeadID)
+	if headIDBytes == nil {
+		ge := cs.genesisEntry()
+		return ge.ID()
+	}
+	copy(id[:], headIDBytes)
+	return id
+}
+
+// changeLogPruned returns true if the genesis entry has been pruned from the
+// changelog.
+func changeLogPruned(tx Tx) bool {
+	return tx.Bucket(ChangeLog).Get(ChangeLogHeadID) != nil
+}
+
+// changeLogRetention returns the number of entries that are kept when the
+// changelog is automatically pruned, or 0 if automatic pruning is disabled.
+func changeLogRetention(tx Tx) (keep uint64) {
+	retentionBytes := tx.Bucket(ChangeLog).Get(ChangeLogRetention)
+	if retentionBytes == nil {
+		return 0
+	}
+	err := encoding.Unmarshal(retentionBytes, &keep)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return keep
+}
+
+// applyChangeLogRetention prunes the changelog down to the retention window,
+// if one has been set.
+func (cs *ConsensusSet) applyChangeLogRetention(tx Tx) error {
+	keep := changeLogRetention(tx)
+	if keep == 0 {
+		return nil
+	}
+	_, err := cs.pruneChangeLog(tx, keep)
+	return err
+}
+
+// changeLogSize returns the number of entries in the changelog.
+func changeLogSize(tx Tx) (size uint64) {
+	err := encoding.Unmarshal(tx.Bucket(ChangeLog).Get(ChangeLogSize), &size)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return size
+}
+
+// initChangeLogSize sets the size of changelogs that were created before the
+// size was tracked by walking the full changelog.
+func (cs *ConsensusSet) initChangeLogSize(tx Tx) error {
+	cl := tx.Bucket(ChangeLog)
+	if cl.Get(ChangeLogSize) != nil {
+		return nil
+	}
+	var size uint64
+	entry, exists := getEntry(tx, cs.changeLogHeadID(tx))
+	for ; exists; entry, exists = entry.NextEntry(tx) {
+		size++
+	}
+	return cl.Put(ChangeLogSize, encoding.Marshal(size))
+}
+
+// pruneChangeLog removes the oldest entries from the changelog until at most
+// 'keep' entries remain. The tail of the changelog is never pruned.
+func (cs *ConsensusSet) pruneChangeLog(tx Tx, keep uint64) (pruned uint64, err error) {
+	if keep == 0 {
+		return 0, errPruneChangeLogTail
+	}
+	cl := tx.Bucket(ChangeLog)
+	size := changeLogSize(tx)
+	if size <= keep {
+		return 0, nil
+	}
+
+	// Walk forward from the head, deleting entries.
+	headID := cs.changeLogHeadID(tx)
+	for ; size > keep; size-- {
+		var cn changeNode
+		err = encoding.Unmarshal(cl.Get(headID[:]), &cn)
+		if err != nil {
+			return pruned, err
+		}
+		err = cl.Delete(headID[:])
+		if err != nil {
+			return pruned, err
+		}
+		headID = cn.Next
+		pruned++
+	}
+
+	// Update the head id and the size.
+	err = cl.Put(ChangeLogHeadID, headID[:])
+	if err != nil {
+		return pruned, err
+	}
+	return pruned, cl.Put(ChangeLogSize, encoding.Marshal(size))
 }
 
 // getEntry returns the change entry with a given id, using a bool to indicate
 // existence.
-func getEntry(tx *bolt.Tx, id modules.ConsensusChangeID) (ce changeEntry, exists bool) {
+func getEntry(tx Tx, id modules.ConsensusChangeID) (ce changeEntry, exists bool) {
 	var cn changeNode
 	cl := tx.Bucket(ChangeLog)
 	changeNodeBytes := cl.Get(id[:])
@@ -114,7 +244,7 @@ func (ce *changeEntry) ID() modules.ConsensusChangeID {
 }
 
 // NextEntry returns the entry after the current entry.
-func (ce *changeEntry) NextEntry(tx *bolt.Tx) (nextEntry changeEntry, exists bool) {
+func (ce *changeEntry) NextEntry(tx Tx) (nextEntry changeEntry, exists bool) {
 	// Get the change node associated with the provided change entry.
 	ceid := ce.ID()
 	var cn changeNode
@@ -129,7 +259,7 @@ func (ce *changeEntry) NextEntry(tx *bolt.Tx) (nextEntry changeEntry, exists boo
 }
 
 // createChangeLog assumes that no change log exists and creates a new one.
-func (cs *ConsensusSet) createChangeLog(tx *bolt.Tx) error {
+func (cs *ConsensusSet) createChangeLog(tx Tx) error {
 	// Create the changelog bucket.
 	cl, err := tx.CreateBucket(ChangeLog)
 	if err != nil {
@@ -151,7 +281,70 @@ func (cs *ConsensusSet) createChangeLog(tx *bolt.Tx) error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return cl.Put(ChangeLogSize, encoding.Marshal(uint64(1)))
+}
+
+// PruneChangeLog removes the oldest entries from the changelog until at most
+// 'keep' entries remain, returning the number of entries that were removed.
+// Subscribers that have not yet seen the removed entries will need to
+// resubscribe from modules.ConsensusChangeBeginning.
+func (cs *ConsensusSet) PruneChangeLog(keep uint64) (pruned uint64, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	err = cs.db.Update(func(tx Tx) error {
+		pruned, err = cs.pruneChangeLog(tx, keep)
+		return err
+	})
+	return pruned, err
+}
+
+// ChangeLogRetention returns the number of entries that are kept when the
+// changelog is automatically pruned. A return value of 0 indicates that the
+// changelog is never pruned automatically.
+func (cs *ConsensusSet) ChangeLogRetention() (keep uint64, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	err = cs.db.View(func(tx Tx) error {
+		keep = changeLogRetention(tx)
+		return nil
+	})
+	return keep, err
+}
+
+// SetChangeLogRetention sets the number of entries that are kept in the
+// changelog. Once set, the changelog is pruned immediately and then again each
+// time a new entry is added, so that at most 'keep' entries are ever stored.
+// Setting the retention to 0 disables automatic pruning, but does not restore
+// entries that have already been pruned. The retention is persisted across
+// restarts.
+func (cs *ConsensusSet) SetChangeLogRetention(keep uint64) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.db.Update(func(tx Tx) error {
+		err := tx.Bucket(ChangeLog).Put(ChangeLogRetention, encoding.Marshal(keep))
+		if err != nil {
+			return err
+		}
+		return cs.applyChangeLogRetention(tx)
+	})
 }
 
 // genesisEntry returns the id of the genesis block log entry.
@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/modules"
@@ -57,3 +58,249 @@ func TestIntegrationChangeLog(t *testing.T) {
 		t.Error("subscribers have inconsistent update chains")
 	}
 }
+
+// TestChangeLogResumeAfterRestart checks that a subscriber can resume its
+// subscription using the id of the last change it received, even after the
+// consensus set has been restarted.
+func TestChangeLogResumeAfterRestart(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Subscribe a mock subscriber, then unsubscribe it and mine a few more
+	// blocks that the subscriber will miss.
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Unsubscribe(&ms)
+	lastID := ms.updates[len(ms.updates)-1].ID
+	for i := 0; i < 3; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	height := cst.cs.Height()
+
+	// Restart the consensus set.
+	err = cst.cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := New(cst.gateway, false, filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs = cs
+
+	// Resume the subscription. Only the missed blocks should be sent.
+	resumed := newMockSubscriber()
+	err = cs.ConsensusSetSubscribe(&resumed, lastID, cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed.updates) != 3 {
+		t.Fatal("resumed subscription received the wrong number of changes:", len(resumed.updates))
+	}
+	if resumed.updates[0].AppliedBlocks[0].ParentID != ms.updates[len(ms.updates)-1].AppliedBlocks[0].ID() {
+		t.Fatal("resumed subscription did not start after the last received change")
+	}
+	if cs.Height() != height {
+		t.Fatal("consensus set height changed during restart")
+	}
+}
+
+// TestPruneChangeLog checks that the changelog can be pruned, and that
+// subscribers are still able to subscribe after pruning.
+func TestPruneChangeLog(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Grab the full set of changes before pruning.
+	full := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&full, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Unsubscribe(&full)
+	var size uint64
+	err = cst.cs.db.View(func(tx Tx) error {
+		size = changeLogSize(tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != uint64(len(full.updates)) {
+		t.Fatalf("changelog size is %v, expected %v", size, len(full.updates))
+	}
+
+	// Pruning everything is not allowed, and pruning to a larger size should
+	// have no effect.
+	if _, err := cst.cs.PruneChangeLog(0); err != errPruneChangeLogTail {
+		t.Fatal("expected errPruneChangeLogTail, got", err)
+	}
+	if pruned, err := cst.cs.PruneChangeLog(size + 1); err != nil || pruned != 0 {
+		t.Fatal("unexpected prune:", pruned, err)
+	}
+
+	// Prune all but the last 3 entries.
+	pruned, err := cst.cs.PruneChangeLog(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != size-3 {
+		t.Fatalf("pruned %v entries, expected %v", pruned, size-3)
+	}
+
+	// Subscribing from a pruned entry should fail, but subscribing from a
+	// retained entry should work.
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, full.updates[0].ID, cst.cs.tg.StopChan())
+	if err != modules.ErrConsensusChangePruned {
+		t.Fatal("expected ErrConsensusChangePruned, got", err)
+	}
+	err = cst.cs.ConsensusSetSubscribe(&ms, full.updates[len(full.updates)-3].ID, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.updates) != 2 {
+		t.Fatal("wrong number of updates after subscribing to a retained entry:", len(ms.updates))
+	}
+	cst.cs.Unsubscribe(&ms)
+
+	// Subscribing from the beginning should produce the same blocks and the
+	// same final id.
+	fromPath := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&fromPath, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromPath.updates) != len(full.updates) {
+		t.Fatal("wrong number of updates after subscribing from the beginning:", len(fromPath.updates))
+	}
+	for i := range full.updates {
+		if fromPath.updates[i].AppliedBlocks[0].ID() != full.updates[i].AppliedBlocks[0].ID() {
+			t.Fatal("subscriber received the wrong block at index", i)
+		}
+		if len(fromPath.updates[i].SiacoinOutputDiffs) != len(full.updates[i].SiacoinOutputDiffs) {
+			t.Fatal("subscriber received the wrong diffs at index", i)
+		}
+	}
+	// The changes that are still in the changelog should carry their real
+	// ids, and the earlier changes should carry ConsensusChangeBeginning.
+	for i := range full.updates {
+		expected := full.updates[i].ID
+		if i < len(full.updates)-3 {
+			expected = modules.ConsensusChangeBeginning
+		}
+		if fromPath.updates[i].ID != expected {
+			t.Fatalf("update %v has id %v, expected %v", i, fromPath.updates[i].ID, expected)
+		}
+	}
+
+	// The subscriber should continue to receive new changes.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromPath.updates) != len(full.updates)+1 {
+		t.Fatal("subscriber did not receive the new change")
+	}
+}
+
+// TestChangeLogRetention checks that the changelog is bounded by the retention
+// window, and that the retention is persisted across restarts.
+func TestChangeLogRetention(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Subscribe a subscriber so that it has an id that will be pruned.
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Unsubscribe(&ms)
+	oldID := ms.updates[len(ms.updates)-1].ID
+
+	// Set a retention window and mine past it.
+	const keep = 5
+	if err := cst.cs.SetChangeLogRetention(keep); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < keep+2; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var size uint64
+	cst.cs.db.View(func(tx Tx) error {
+		size = changeLogSize(tx)
+		return nil
+	})
+	if size != keep {
+		t.Fatalf("changelog size is %v, expected %v", size, keep)
+	}
+
+	// The old id should now be reported as pruned.
+	ms2 := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms2, oldID, cst.cs.tg.StopChan())
+	if err != modules.ErrConsensusChangePruned {
+		t.Fatal("expected ErrConsensusChangePruned, got", err)
+	}
+
+	// Restart the consensus set and check that the retention was persisted.
+	err = cst.cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := New(cst.gateway, false, filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs = cs
+	retention, err := cst.cs.ChangeLogRetention()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retention != keep {
+		t.Fatalf("retention is %v, expected %v", retention, keep)
+	}
+
+	// Disabling the retention should allow the changelog to grow again.
+	if err := cst.cs.SetChangeLogRetention(0); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := cst.miner.FindBlock()
+	if err := cst.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.db.View(func(tx Tx) error {
+		size = changeLogSize(tx)
+		return nil
+	})
+	if size != keep+1 {
+		t.Fatalf("changelog size is %v, expected %v", size, keep+1)
+	}
+}
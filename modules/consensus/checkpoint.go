@@ -0,0 +1,240 @@
+package consensus
+
+// checkpoint.go implements consensus snapshots, which allow a new node to
+// bootstrap from the database of a fully-synced node instead of validating
+// every block from genesis.
+//
+// A snapshot is a consistent copy of the consensus database prefixed by a
+// header naming the height, block id and consensus checksum of the snapshot.
+// The full database is shipped rather than only the unspent outputs because
+// subscribers rely on the blocks and the changelog when they perform their
+// initial scan. The consensus checksum only covers the current path and the
+// unspent outputs, so snapshots are identified by the hash of the entire
+// snapshot instead, and are only trusted if that hash matches a checkpoint.
+// The hash is the blake2b-256 hash of the snapshot file, so it can also be
+// computed with standard tools.
+//
+// Snapshots are not signed. A snapshot is trusted only because its hash
+// matches a checkpoint or a hash supplied by the operator. No checkpoints are
+// shipped yet, so until the table below is populated, a snapshot can only be
+// loaded by pinning its hash.
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// checkpoints are the snapshots that are allowed to bootstrap a consensus
+	// set. New checkpoints can be taken from the header and the hash of a
+	// snapshot exported by a fully-synced node. The table is intentionally
+	// empty until a snapshot has been published and reviewed.
+	checkpoints = build.Select(build.Var{
+		Standard: []checkpoint{},
+		Dev:      []checkpoint{},
+		Testing:  []checkpoint{},
+	}).([]checkpoint)
+
+	// snapshotHeaderSize is the maximum size of an encoded snapshot header.
+	snapshotHeaderSize = uint64(1 << 10)
+
+	errSnapshotConflict     = errors.New("snapshot conflicts with a trusted checkpoint")
+	errSnapshotDBExists     = errors.New("cannot load a snapshot over an existing consensus database")
+	errSnapshotHashMismatch = errors.New("snapshot does not match the pinned snapshot hash")
+	errSnapshotMismatch     = errors.New("snapshot does not match its header")
+	errSnapshotNotSynced    = errors.New("cannot export a snapshot until the consensus set is synced")
+	errSnapshotNoCheckpoint = errors.New("snapshot does not match any trusted checkpoint")
+)
+
+type (
+	// checkpoint identifies a trusted snapshot. SnapshotHash is the hash of
+	// the entire snapshot, which commits to every block and changelog entry
+	// that is imported along with the consensus state.
+	checkpoint struct {
+		Height            types.BlockHeight
+		BlockID           types.BlockID
+		ConsensusChecksum crypto.Hash
+		SnapshotHash      crypto.Hash
+	}
+
+	// snapshotHeader is written at the start of a snapshot.
+	snapshotHeader struct {
+		Height            types.BlockHeight
+		BlockID           types.BlockID
+		ConsensusChecksum crypto.Hash
+	}
+)
+
+// header returns the snapshot header that the checkpoint expects.
+func (cp checkpoint) header() snapshotHeader {
+	return snapshotHeader{
+		Height:            cp.Height,
+		BlockID:           cp.BlockID,
+		ConsensusChecksum: cp.ConsensusChecksum,
+	}
+}
+
+// matchCheckpoint returns true if the header and the hash of the snapshot
+// match one of the provided checkpoints.
+func (sh snapshotHeader) matchCheckpoint(cps []checkpoint, snapshotHash crypto.Hash) bool {
+	for _, cp := range cps {
+		if cp.header() == sh && cp.SnapshotHash == snapshotHash {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictsWithCheckpoint returns true if one of the provided checkpoints is
+// at the same height as the header but does not match it.
+func (sh snapshotHeader) conflictsWithCheckpoint(cps []checkpoint) bool {
+	for _, cp := range cps {
+		if cp.Height == sh.Height && cp.header() != sh {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportSnapshot writes a snapshot of the consensus set to w and returns its
+// hash. The header and the hash of the snapshot can be used as a checkpoint
+// for nodes that load the snapshot.
+func (cs *ConsensusSet) ExportSnapshot(w io.Writer) (crypto.Hash, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	bdb, ok := cs.db.(boltDatabase)
+	if !ok {
+		return crypto.Hash{}, errUnsupportedDatabase
+	}
+	if !cs.synced {
+		return crypto.Hash{}, errSnapshotNotSynced
+	}
+
+	h := crypto.NewHash()
+	w = io.MultiWriter(w, h)
+	err = bdb.DB.View(func(btx *bolt.Tx) error {
+		tx := boltTx{btx}
+		sh := snapshotHeader{
+			Height:            blockHeight(tx),
+			BlockID:           currentBlockID(tx),
+			ConsensusChecksum: consensusChecksum(tx),
+		}
+		err := encoding.WriteObject(w, sh)
+		if err != nil {
+			return err
+		}
+		_, err = btx.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	var snapshotHash crypto.Hash
+	copy(snapshotHash[:], h.Sum(nil))
+	return snapshotHash, nil
+}
+
+// LoadSnapshot reads a snapshot from r and installs it as the consensus
+// database in persistDir. The snapshot must match one of the hardcoded
+// checkpoints, so LoadSnapshot rejects every snapshot while the checkpoint
+// table is empty. LoadSnapshot must be called before the consensus set is
+// created, and will not overwrite an existing database.
+func LoadSnapshot(r io.Reader, persistDir string) error {
+	return loadSnapshot(r, persistDir, checkpoints, crypto.Hash{})
+}
+
+// loadSnapshot reads a snapshot from r, verifies it, and installs it as the
+// consensus database in persistDir. If pinnedHash is not empty, the snapshot
+// must have that hash; otherwise it must match one of the provided
+// checkpoints. In both cases, it must not conflict with a checkpoint.
+func loadSnapshot(r io.Reader, persistDir string, cps []checkpoint, pinnedHash crypto.Hash) error {
+	filename := filepath.Join(persistDir, DatabaseFilename)
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		return errSnapshotDBExists
+	}
+	h := crypto.NewHash()
+	r = io.TeeReader(r, h)
+	var sh snapshotHeader
+	err := encoding.ReadObject(r, &sh, snapshotHeaderSize)
+	if err != nil {
+		return err
+	}
+	if sh.conflictsWithCheckpoint(cps) {
+		return errSnapshotConflict
+	}
+
+	// Write the database to a temporary file so that a partial or invalid
+	// snapshot is never loaded.
+	err = os.MkdirAll(persistDir, 0700)
+	if err != nil {
+		return err
+	}
+	tmpFilename := filename + "_snapshot"
+	f, err := os.OpenFile(tmpFilename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFilename)
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Check the hash of the snapshot before the database is opened, so that
+	// only the exact snapshot named by a checkpoint or by the operator is
+	// ever imported.
+	var snapshotHash crypto.Hash
+	copy(snapshotHash[:], h.Sum(nil))
+	if pinnedHash != (crypto.Hash{}) {
+		if snapshotHash != pinnedHash {
+			return errSnapshotHashMismatch
+		}
+	} else if !sh.matchCheckpoint(cps, snapshotHash) {
+		return errSnapshotNoCheckpoint
+	}
+
+	// Check that the database matches the header.
+	db, err := openBoltDatabase(tmpFilename)
+	if err != nil {
+		return err
+	}
+	err = db.View(func(tx Tx) error {
+		if tx.Bucket(SiafundPool) == nil || tx.Bucket(ChangeLog) == nil {
+			return errSnapshotMismatch
+		}
+		if blockHeight(tx) != sh.Height || currentBlockID(tx) != sh.BlockID {
+			return errSnapshotMismatch
+		}
+		if consensusChecksum(tx) != sh.ConsensusChecksum {
+			return errSnapshotMismatch
+		}
+		return nil
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
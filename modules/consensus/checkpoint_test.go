@@ -0,0 +1,126 @@
+package consensus
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+)
+
+// TestSnapshot checks that a snapshot exported by one consensus set can be
+// used to bootstrap another, and that snapshots which do not match a trusted
+// checkpoint are rejected.
+func TestSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Export a snapshot and read the header.
+	var buf bytes.Buffer
+	var snapshotHash crypto.Hash
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		buf.Reset()
+		var err error
+		snapshotHash, err = cst.cs.ExportSnapshot(&buf)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := buf.Bytes()
+	if snapshotHash != crypto.HashBytes(snapshot) {
+		t.Fatal("ExportSnapshot returned the wrong snapshot hash")
+	}
+	var sh snapshotHeader
+	if err := encoding.ReadObject(bytes.NewReader(snapshot), &sh, snapshotHeaderSize); err != nil {
+		t.Fatal(err)
+	}
+	if sh.Height != cst.cs.Height() || sh.BlockID != cst.cs.CurrentBlock().ID() {
+		t.Fatal("snapshot header does not match the consensus set")
+	}
+	cp := checkpoint{
+		Height:            sh.Height,
+		BlockID:           sh.BlockID,
+		ConsensusChecksum: sh.ConsensusChecksum,
+		SnapshotHash:      snapshotHash,
+	}
+
+	// A snapshot without a matching checkpoint should be rejected.
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "bootstrap")
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	err = loadSnapshot(bytes.NewReader(snapshot), csDir, nil, crypto.Hash{})
+	if err != errSnapshotNoCheckpoint {
+		t.Fatal("expected errSnapshotNoCheckpoint, got", err)
+	}
+
+	// A snapshot whose database was modified should be rejected, even if its
+	// header matches a checkpoint.
+	headerLen := len(encoding.Marshal(sh)) + 8
+	modified := append([]byte(nil), snapshot...)
+	modified[len(modified)-1]++
+	err = loadSnapshot(bytes.NewReader(modified), csDir, []checkpoint{cp}, crypto.Hash{})
+	if err != errSnapshotNoCheckpoint {
+		t.Fatal("expected errSnapshotNoCheckpoint, got", err)
+	}
+
+	// A snapshot whose database does not match its header should be
+	// rejected, even if the header matches a checkpoint.
+	var tampered bytes.Buffer
+	badHeader := sh
+	badHeader.ConsensusChecksum[0]++
+	encoding.WriteObject(&tampered, badHeader)
+	tampered.Write(snapshot[headerLen:])
+	badCP := checkpoint{
+		Height:            badHeader.Height,
+		BlockID:           badHeader.BlockID,
+		ConsensusChecksum: badHeader.ConsensusChecksum,
+		SnapshotHash:      crypto.HashBytes(tampered.Bytes()),
+	}
+	err = loadSnapshot(&tampered, csDir, []checkpoint{badCP}, crypto.Hash{})
+	if err != errSnapshotMismatch {
+		t.Fatal("expected errSnapshotMismatch, got", err)
+	}
+
+	// Load the snapshot and bootstrap a new consensus set from it.
+	err = loadSnapshot(bytes.NewReader(snapshot), csDir, []checkpoint{cp}, crypto.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(g, false, csDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if cs.Height() != cst.cs.Height() || cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("bootstrapped consensus set does not match the original")
+	}
+
+	// The snapshot should not overwrite an existing database.
+	err = loadSnapshot(bytes.NewReader(snapshot), csDir, []checkpoint{cp}, crypto.Hash{})
+	if err != errSnapshotDBExists {
+		t.Fatal("expected errSnapshotDBExists, got", err)
+	}
+
+	// The bootstrapped consensus set should accept new blocks.
+	b, _ := cst.miner.FindBlock()
+	if err := cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,264 @@
+package consensus
+
+// compact.go implements compaction of the consensus database. Bolt never
+// returns freed pages to the filesystem, so the database file only grows.
+// Compaction copies everything that is still needed into a fresh file and
+// swaps it in place of the old database.
+//
+// Blocks that are neither in the current path nor referenced by the changelog
+// are dropped, as are unrecognized buckets and empty file contract expiration
+// buckets. Buckets added to the consensus database must be added to
+// compactKnownBuckets, otherwise they will be dropped.
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// compactBatchSize is the number of keys that are copied into the new
+	// database within a single transaction.
+	compactBatchSize = build.Select(build.Var{
+		Standard: 10000,
+		Dev:      1000,
+		Testing:  100,
+	}).(int)
+
+	// compactKnownBuckets are the top-level buckets of the consensus database
+	// that are not created dynamically.
+	compactKnownBuckets = [][]byte{
+		BlockHeight,
+		BlockMap,
+		BlockPath,
+		BucketOak,
+		ChangeLog,
+		Consistency,
+		FileContracts,
+		SiacoinOutputs,
+		SiafundOutputs,
+		SiafundPool,
+		SiafundPoolHistory,
+	}
+
+	// compactMetadataBucket is the bucket used by the persist package to store
+	// the database metadata. It is created when the new database is opened.
+	compactMetadataBucket = []byte("Metadata")
+
+	errCompactNestedBucket = errors.New("cannot compact a consensus database that contains nested buckets")
+)
+
+// compactKeyValue is a key-value pair waiting to be written to the compacted
+// database.
+type compactKeyValue struct {
+	key, value []byte
+}
+
+// isStaleBucket returns true if the bucket does not need to be copied into the
+// compacted database.
+func isStaleBucket(name []byte, b Bucket) bool {
+	for _, known := range compactKnownBuckets {
+		if bytes.Equal(name, known) {
+			return false
+		}
+	}
+	if bytes.HasPrefix(name, prefixDSCO) {
+		// Empty delayed siacoin output buckets are expected to exist for
+		// upcoming heights.
+		return false
+	}
+	if bytes.HasPrefix(name, prefixFCEX) {
+		// File contract expiration buckets are created on demand, and are not
+		// needed once they are empty.
+		k, _ := b.Cursor().First()
+		return k == nil
+	}
+	return true
+}
+
+// referencedBlocks returns the set of blocks that are either in the current
+// path or referenced by an entry in the changelog.
+func (cs *ConsensusSet) referencedBlocks(tx Tx) (map[types.BlockID]struct{}, error) {
+	referenced := make(map[types.BlockID]struct{})
+	err := tx.Bucket(BlockPath).ForEach(func(_, v []byte) error {
+		var id types.BlockID
+		copy(id[:], v)
+		referenced[id] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := getEntry(tx, cs.changeLogHeadID(tx))
+	for ; exists; entry, exists = entry.NextEntry(tx) {
+		for _, id := range entry.RevertedBlocks {
+			referenced[id] = struct{}{}
+		}
+		for _, id := range entry.AppliedBlocks {
+			referenced[id] = struct{}{}
+		}
+	}
+	return referenced, nil
+}
+
+// compactInto copies the contents of the consensus database into dst,
+// skipping unreferenced blocks and stale buckets.
+func (cs *ConsensusSet) compactInto(tx Tx, dst Database, stats *modules.ConsensusCompactionStats) error {
+	referenced, err := cs.referencedBlocks(tx)
+	if err != nil {
+		return err
+	}
+
+	// Count the keys so that progress can be reported.
+	var total, copied, nextReport uint64
+	err = tx.ForEach(func(_ []byte, b Bucket) error {
+		total += uint64(b.Len())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cs.log.Printf("Compacting consensus database, %v keys to process", total)
+	reportProgress := func() {
+		if total > 0 && copied*10 >= nextReport*total {
+			cs.log.Printf("Consensus database compaction %v%% complete", copied*100/total)
+			nextReport = copied*10/total + 1
+		}
+	}
+
+	return tx.ForEach(func(name []byte, b Bucket) error {
+		if bytes.Equal(name, compactMetadataBucket) {
+			return nil
+		}
+		if isStaleBucket(name, b) {
+			copied += uint64(b.Len())
+			stats.BucketsDropped++
+			return nil
+		}
+		// The name is only valid for the life of the transaction, and the
+		// destination transactions are separate.
+		name = append([]byte(nil), name...)
+		err := dst.Update(func(dtx Tx) error {
+			_, err := dtx.CreateBucket(name)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// Copy the bucket in batches.
+		isBlockMap := bytes.Equal(name, BlockMap)
+		isOak := bytes.Equal(name, BucketOak)
+		var batch []compactKeyValue
+		flush := func() error {
+			err := dst.Update(func(dtx Tx) error {
+				db := dtx.Bucket(name)
+				for _, kv := range batch {
+					if err := db.Put(kv.key, kv.value); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			batch = batch[:0]
+			reportProgress()
+			return err
+		}
+		err = b.ForEach(func(k, v []byte) error {
+			copied++
+			if v == nil {
+				return errCompactNestedBucket
+			}
+			if isBlockMap || (isOak && !bytes.Equal(k, FieldOakInit)) {
+				var id types.BlockID
+				copy(id[:], k)
+				if _, exists := referenced[id]; !exists {
+					if isBlockMap {
+						stats.BlocksDropped++
+					}
+					return nil
+				}
+			}
+			batch = append(batch, compactKeyValue{
+				key:   append([]byte(nil), k...),
+				value: append([]byte(nil), v...),
+			})
+			if len(batch) >= compactBatchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+}
+
+// Compact rewrites the consensus database into a fresh file, dropping blocks
+// that are not in the current path or the changelog, along with any stale
+// buckets. Progress is reported in the consensus log. The consensus set is
+// locked for the duration of the compaction.
+func (cs *ConsensusSet) Compact() (stats modules.ConsensusCompactionStats, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return stats, err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.db.(boltDatabase); !ok {
+		return stats, errUnsupportedDatabase
+	}
+
+	filename := filepath.Join(cs.persistDir, DatabaseFilename)
+	tmpFilename := filename + "_compact"
+	if fi, err := os.Stat(filename); err == nil {
+		stats.OldSize = uint64(fi.Size())
+	}
+
+	// Copy the database into the temporary file.
+	os.Remove(tmpFilename)
+	dst, err := openBoltDatabase(tmpFilename)
+	if err != nil {
+		return stats, err
+	}
+	err = cs.db.View(func(tx Tx) error {
+		return cs.compactInto(tx, dst, &stats)
+	})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFilename)
+		return stats, err
+	}
+
+	// Swap the compacted database in place of the old one.
+	err = cs.db.Close()
+	if err != nil {
+		os.Remove(tmpFilename)
+		return stats, err
+	}
+	renameErr := os.Rename(tmpFilename, filename)
+	cs.db, err = openBoltDatabase(filename)
+	if err != nil {
+		// The consensus set cannot continue without a database.
+		cs.log.Critical("Unable to reopen the consensus database after compaction:", err)
+		return stats, err
+	}
+	if renameErr != nil {
+		os.Remove(tmpFilename)
+		return stats, renameErr
+	}
+	if fi, err := os.Stat(filename); err == nil {
+		stats.NewSize = uint64(fi.Size())
+	}
+	cs.log.Printf("Consensus database compaction complete: %v bytes -> %v bytes, %v blocks dropped", stats.OldSize, stats.NewSize, stats.BlocksDropped)
+	return stats, nil
+}
@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestCompact checks that compacting the consensus database drops orphaned
+// blocks and stale buckets while preserving the consensus state.
+func TestCompact(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create a block that is not in the current path.
+	child0, _ := cst.miner.FindBlock()
+	child1, _ := cst.miner.FindBlock()
+	if err := cst.cs.AcceptBlock(child0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.AcceptBlock(child1); err != modules.ErrNonExtendingBlock {
+		t.Fatal("expected ErrNonExtendingBlock, got", err)
+	}
+
+	// Add a stale bucket.
+	err = cst.cs.db.Update(func(tx Tx) error {
+		_, err := tx.CreateBucket([]byte("StaleBucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var checksum []byte
+	cst.cs.db.View(func(tx Tx) error {
+		cs := consensusChecksum(tx)
+		checksum = cs[:]
+		return nil
+	})
+	stats, err := cst.cs.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.BlocksDropped != 1 {
+		t.Error("expected 1 block to be dropped, got", stats.BlocksDropped)
+	}
+	if stats.BucketsDropped != 1 {
+		t.Error("expected 1 bucket to be dropped, got", stats.BucketsDropped)
+	}
+	if stats.NewSize == 0 || stats.OldSize == 0 {
+		t.Error("database sizes were not reported:", stats.OldSize, stats.NewSize)
+	}
+
+	// The orphaned block and the stale bucket should be gone, and the
+	// consensus state should be unchanged.
+	err = cst.cs.db.View(func(tx Tx) error {
+		if _, err := getBlockMap(tx, child1.ID()); err != errNilItem {
+			t.Error("orphaned block was not dropped:", err)
+		}
+		if _, err := getBlockMap(tx, child0.ID()); err != nil {
+			t.Error("block in the current path was dropped:", err)
+		}
+		if tx.Bucket([]byte("StaleBucket")) != nil {
+			t.Error("stale bucket was not dropped")
+		}
+		if cs := consensusChecksum(tx); string(cs[:]) != string(checksum) {
+			t.Error("consensus checksum changed during compaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribers should still be able to scan the full changelog, and the
+	// consensus set should still accept blocks.
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Unsubscribe(&ms)
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+}
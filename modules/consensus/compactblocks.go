@@ -0,0 +1,283 @@
+package consensus
+
+// compactblocks.go implements compact block relay. Once a node is synced, the
+// transactions of a new block are usually already in its transaction pool, so
+// sending the full block wastes bandwidth. When a peer relays a header and
+// advertises the CompactBlocks capability, the block is requested with the
+// CompactBlk RPC instead of SendBlk. The peer sends the block without its
+// transactions, followed by a short ID for each transaction. The block is
+// reconstructed from the transactions known to the registered transaction
+// sources, and only the transactions that could not be found are fetched from
+// the peer.
+//
+// Short IDs are salted with the ID of the block, so that an attacker cannot
+// precompute transactions whose short IDs collide with those of another
+// transaction. Collisions can still happen by chance, in which case the
+// reconstructed block does not match the header and the full block is
+// requested with SendBlk.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// compactBlocksCapability is the gateway capability that is advertised by
+	// consensus sets that serve the CompactBlk RPC.
+	compactBlocksCapability = "CompactBlocks"
+)
+
+var (
+	errCompactBlockMismatch   = errors.New("reconstructed compact block does not match the requested block")
+	errInvalidCompactIndex    = errors.New("requested transaction index is out of range")
+	errWrongTransactionsCount = errors.New("peer sent the wrong number of transactions")
+
+	// compactBlkTimeout is the timeout for the CompactBlk RPC.
+	compactBlkTimeout = build.Select(build.Var{
+		Standard: 90 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  4 * time.Second,
+	}).(time.Duration)
+)
+
+type (
+	// shortTransactionID identifies a transaction within a compact block.
+	shortTransactionID [8]byte
+
+	// compactBlock is a block whose transactions have been replaced by their
+	// short IDs.
+	compactBlock struct {
+		ParentID     types.BlockID
+		Nonce        types.BlockNonce
+		Timestamp    types.Timestamp
+		MinerPayouts []types.SiacoinOutput
+		ShortIDs     []shortTransactionID
+	}
+)
+
+// shortID returns the short ID of a transaction in the block with the given ID.
+func shortID(bid types.BlockID, tid types.TransactionID) (sid shortTransactionID) {
+	h := crypto.HashAll(bid, tid)
+	copy(sid[:], h[:])
+	return sid
+}
+
+// newCompactBlock returns the compact form of a block.
+func newCompactBlock(b types.Block) compactBlock {
+	bid := b.ID()
+	cb := compactBlock{
+		ParentID:     b.ParentID,
+		Nonce:        b.Nonce,
+		Timestamp:    b.Timestamp,
+		MinerPayouts: b.MinerPayouts,
+		ShortIDs:     make([]shortTransactionID, len(b.Transactions)),
+	}
+	for i, txn := range b.Transactions {
+		cb.ShortIDs[i] = shortID(bid, txn.ID())
+	}
+	return cb
+}
+
+// managedUnconfirmedTransactions returns the transactions of every transaction
+// source, indexed by their short ID in the block with the given ID. Short IDs
+// that belong to more than one transaction are left out.
+func (cs *ConsensusSet) managedUnconfirmedTransactions(bid types.BlockID) map[shortTransactionID]types.Transaction {
+	cs.mu.RLock()
+	sources := append([]modules.TransactionSource(nil), cs.txnSources...)
+	cs.mu.RUnlock()
+
+	txns := make(map[shortTransactionID]types.Transaction)
+	collisions := make(map[shortTransactionID]struct{})
+	for _, source := range sources {
+		for _, txn := range source.TransactionList() {
+			tid := txn.ID()
+			sid := shortID(bid, tid)
+			if existing, exists := txns[sid]; exists && existing.ID() != tid {
+				collisions[sid] = struct{}{}
+			}
+			txns[sid] = txn
+		}
+	}
+	for sid := range collisions {
+		delete(txns, sid)
+	}
+	return txns
+}
+
+// rpcSendCompactBlk is an RPC that sends the requested block to the requesting
+// peer in compact form, followed by any transactions that the peer is missing.
+func (cs *ConsensusSet) rpcSendCompactBlk(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(compactBlkTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Decode the block id from the connection.
+	var id types.BlockID
+	err = encoding.ReadObject(conn, &id, crypto.HashSize)
+	if err != nil {
+		return err
+	}
+	// Lookup the corresponding block.
+	var b types.Block
+	cs.mu.RLock()
+	err = cs.db.View(func(tx Tx) error {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		b = pb.Block
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	// Send the compact block, and then the transactions that the caller
+	// could not find.
+	err = encoding.WriteObject(conn, newCompactBlock(b))
+	if err != nil {
+		return err
+	}
+	var missing []uint64
+	err = encoding.ReadObject(conn, &missing, uint64(8+8*len(b.Transactions)))
+	if err != nil {
+		return err
+	}
+	txns := make([]types.Transaction, len(missing))
+	for i, index := range missing {
+		if index >= uint64(len(b.Transactions)) {
+			return errInvalidCompactIndex
+		}
+		txns[i] = b.Transactions[index]
+	}
+	return encoding.WriteObject(conn, txns)
+}
+
+// managedReceiveCompactBlock takes a block id and returns an RPCFunc that
+// requests the block in compact form, reconstructs it and then calls
+// AcceptBlock on it. The returned function should be used as the calling end
+// of the CompactBlk RPC. errCompactBlockMismatch is returned if the block could
+// not be reconstructed, in which case it should be requested with SendBlk.
+func (cs *ConsensusSet) managedReceiveCompactBlock(id types.BlockID) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		if err := encoding.WriteObject(conn, id); err != nil {
+			return err
+		}
+		var cb compactBlock
+		if err := encoding.ReadObject(conn, &cb, types.BlockSizeLimit); err != nil {
+			return err
+		}
+		start := time.Now()
+
+		// Fill in the transactions that are known, and request the rest.
+		known := cs.managedUnconfirmedTransactions(id)
+		block := types.Block{
+			ParentID:     cb.ParentID,
+			Nonce:        cb.Nonce,
+			Timestamp:    cb.Timestamp,
+			MinerPayouts: cb.MinerPayouts,
+			Transactions: make([]types.Transaction, len(cb.ShortIDs)),
+		}
+		var missing []uint64
+		for i, sid := range cb.ShortIDs {
+			txn, exists := known[sid]
+			if !exists {
+				missing = append(missing, uint64(i))
+				continue
+			}
+			block.Transactions[i] = txn
+		}
+		if err := encoding.WriteObject(conn, missing); err != nil {
+			return err
+		}
+		var txns []types.Transaction
+		if err := encoding.ReadObject(conn, &txns, types.BlockSizeLimit); err != nil {
+			return err
+		} else if len(txns) != len(missing) {
+			return errWrongTransactionsCount
+		}
+		for i, index := range missing {
+			block.Transactions[index] = txns[i]
+		}
+		if block.ID() != id {
+			return errCompactBlockMismatch
+		}
+
+		elapsed := time.Since(start)
+		cs.mu.RLock()
+		_ = cs.db.View(func(tx Tx) error {
+			cs.recordPeerMetric(modules.ConsensusMetricCompactBlock, conn.RPCAddr(), blockHeight(tx), len(missing), elapsed)
+			return nil
+		})
+		cs.mu.RUnlock()
+
+		chainExtended, err := cs.managedAcceptBlocks([]types.Block{block})
+		if chainExtended {
+			cs.managedBroadcastBlock(block)
+		}
+		cs.managedPenalizeInvalidBlocks(conn.RPCAddr(), []types.Block{block}, err)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// RegisterTransactionSource adds a source of unconfirmed transactions that is
+// used to reconstruct compact blocks.
+func (cs *ConsensusSet) RegisterTransactionSource(source modules.TransactionSource) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, s := range cs.txnSources {
+		if s == source {
+			build.Critical("refusing to register transaction source twice")
+			return
+		}
+	}
+	cs.txnSources = append(cs.txnSources, source)
+}
+
+// UnregisterTransactionSource removes a transaction source. If the source is
+// not found, no action is taken.
+func (cs *ConsensusSet) UnregisterTransactionSource(source modules.TransactionSource) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for i := range cs.txnSources {
+		if cs.txnSources[i] == source {
+			cs.txnSources[i] = nil
+			cs.txnSources = append(cs.txnSources[:i], cs.txnSources[i+1:]...)
+			break
+		}
+	}
+}
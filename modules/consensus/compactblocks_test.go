@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mockTransactionSource is a transaction source with a fixed set of
+// transactions.
+type mockTransactionSource struct {
+	txns []types.Transaction
+}
+
+// TransactionList returns the transactions of the mock transaction source.
+func (mts *mockTransactionSource) TransactionList() []types.Transaction {
+	return mts.txns
+}
+
+// TestCompactBlockRelay checks that a block can be reconstructed from a
+// compact block, fetching only the transactions that are missing.
+func TestCompactBlockRelay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	remote, err := createConsensusSetTester(t.Name() + "-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	// Create a local consensus set that only downloads blocks when told to,
+	// and bring it up to date with the remote.
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-local")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(mockGatewayNoRPCs{g}, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	mms := &mockMetricsSink{metrics: make(map[modules.ConsensusMetricType][]modules.ConsensusMetric)}
+	cs.RegisterMetricsSink(mms)
+	addr := remote.gateway.Address()
+	if err := g.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RPC(addr, "SendBlocks", cs.managedReceiveBlocks); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != remote.cs.CurrentBlock().ID() {
+		t.Fatal("local did not sync with remote")
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if !g.PeerCapable(addr, compactBlocksCapability) {
+			return errors.New("remote does not advertise compact blocks")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine a block with several transactions on the remote, and give only
+	// the first one to the local consensus set.
+	for i := 0; i < 3; i++ {
+		_, err := remote.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := remote.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Transactions) < 2 {
+		t.Fatal("expected several transactions in the block, got", len(b.Transactions))
+	}
+	cs.RegisterTransactionSource(&mockTransactionSource{txns: b.Transactions[:1]})
+
+	if err := g.RPC(addr, "CompactBlk", cs.managedReceiveCompactBlock(b.ID())); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != b.ID() {
+		t.Fatal("compact block was not accepted")
+	}
+	compact := mms.metrics[modules.ConsensusMetricCompactBlock]
+	if len(compact) != 1 || compact[0].Count != len(b.Transactions)-1 || compact[0].Peer != addr {
+		t.Fatal("wrong compact block metrics:", compact)
+	}
+}
@@ -8,7 +8,9 @@ package consensus
 
 import (
 	"errors"
+	"os"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
@@ -16,11 +18,22 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/NebulousLabs/demotemutex"
-	"github.com/coreos/bbolt"
 )
 
 var (
-	errNilGateway = errors.New("cannot have a nil gateway as input")
+	// maxBlocksInRange is the maximum number of blocks that can be requested
+	// in a single call to BlocksInRange.
+	maxBlocksInRange = build.Select(build.Var{
+		Standard: types.BlockHeight(1000),
+		Dev:      types.BlockHeight(100),
+		Testing:  types.BlockHeight(10),
+	}).(types.BlockHeight)
+
+	errInvalidRange  = errors.New("start of the range is after the end")
+	errNilDatabase   = errors.New("cannot have a nil database as input")
+	errNilGateway    = errors.New("cannot have a nil gateway as input")
+	errRangeTooLarge = errors.New("requested range contains too many blocks")
+	errRangeTooHigh  = errors.New("requested range extends beyond the current height")
 )
 
 // marshaler marshals objects into byte slices and unmarshals byte
@@ -56,6 +69,40 @@ type ConsensusSet struct {
 	// the function of adding a subscriber should not be exposed.
 	subscribers []modules.ConsensusSetSubscriber
 
+	// asyncSubscribers maps the subscribers that were added through
+	// ConsensusSetSubscribeAsync to the asyncSubscriber that queues their
+	// changes. The asyncSubscriber is what appears in 'subscribers'.
+	asyncSubscribers map[modules.ConsensusSetSubscriber]*asyncSubscriber
+
+	// headerSubscribers maps the subscribers that were added through
+	// ConsensusSetHeaderSubscribe to the headerSubscriber that wraps them. The
+	// headerSubscriber is what appears in 'subscribers'.
+	headerSubscribers map[modules.ConsensusSetHeaderSubscriber]*headerSubscriber
+
+	// filteredSubscribers maps the subscribers that were added through
+	// ConsensusSetSubscribeFiltered to the filteredSubscriber that wraps them.
+	// The filteredSubscriber is what appears in 'subscribers'.
+	filteredSubscribers map[modules.ConsensusSetSubscriber]*filteredSubscriber
+
+	// reorgSubscribers are notified every time the current path is
+	// reorganized onto a different fork.
+	reorgSubscribers []modules.ConsensusSetReorgSubscriber
+
+	// metricsSinks receive the measurements taken by the consensus set.
+	metricsSinks []modules.ConsensusMetricsSink
+
+	// txnSources provide the unconfirmed transactions that compact blocks
+	// are reconstructed from.
+	txnSources []modules.TransactionSource
+
+	// blockHooks can veto blocks before they are added to the block tree.
+	// They can only be registered on dev and testing builds.
+	blockHooks []modules.BlockAcceptanceHook
+
+	// auditLog is the file that changes are appended to while the audit log
+	// is active. See auditlog.go.
+	auditLog *os.File
+
 	// dosBlocks are blocks that are invalid, but the invalidity is only
 	// discoverable during an expensive step of validation. These blocks are
 	// recorded to eliminate a DoS vector where an expensive-to-validate block
@@ -69,6 +116,22 @@ type ConsensusSet struct {
 	// the genesis block, meaning the PoW is not very expensive.
 	dosBlocks map[types.BlockID]struct{}
 
+	// orphans are blocks whose parent is not yet known, held until the parent
+	// arrives or the orphan expires. See orphans.go.
+	orphans     map[types.BlockID]orphanBlock
+	orphanBytes uint64
+
+	// futureBlocks are blocks whose timestamp is too far in the future to be
+	// accepted yet, held until the timestamp becomes valid. See
+	// futureblocks.go.
+	futureBlocks map[types.BlockID]types.Block
+
+	// verifiedBlocks are the blocks of the batch currently being accepted
+	// whose transactions have already passed the standalone checks, including
+	// signature verification. It is only set while managedAcceptBlocks holds
+	// the lock.
+	verifiedBlocks map[types.BlockID]struct{}
+
 	// checkingConsistency is a bool indicating whether or not a consistency
 	// check is in progress. The consistency check logic call itself, resulting
 	// in infinite loops. This bool prevents that while still allowing for full
@@ -81,13 +144,19 @@ type ConsensusSet struct {
 	// whether the consensus set is synced with the network.
 	synced bool
 
+	// peerHeight is the greatest height of the blocks announced by peers,
+	// and syncSamples are the recent heights of the current path. They are
+	// used to report the sync progress. See syncprogress.go.
+	peerHeight  types.BlockHeight
+	syncSamples []syncSample
+
 	// Interfaces to abstract the dependencies of the ConsensusSet.
 	marshaler       marshaler
 	blockRuleHelper blockRuleHelper
 	blockValidator  blockValidator
 
 	// Utilities
-	db         *persist.BoltDatabase
+	db         Database
 	staticDeps modules.Dependencies
 	log        *persist.Logger
 	mu         demotemutex.DemoteMutex
@@ -106,6 +175,23 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 // there is an existing block database present in the persist directory, it
 // will be loaded.
 func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, deps modules.Dependencies) (*ConsensusSet, error) {
+	return newConsensusSet(gateway, bootstrap, persistDir, deps, nil)
+}
+
+// NewWithDatabase returns a new ConsensusSet that is stored in the provided
+// database instead of the bolt database in the persist directory. The
+// database is initialized if it is empty, and is closed when the consensus
+// set is closed. The persist directory is still used for the log.
+func NewWithDatabase(gateway modules.Gateway, bootstrap bool, persistDir string, db Database) (*ConsensusSet, error) {
+	if db == nil {
+		return nil, errNilDatabase
+	}
+	return newConsensusSet(gateway, bootstrap, persistDir, modules.ProdDependencies, db)
+}
+
+// newConsensusSet returns a new ConsensusSet stored in db. If db is nil, the
+// bolt database in the persist directory is used.
+func newConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, deps modules.Dependencies, db Database) (*ConsensusSet, error) {
 	// Check for nil dependencies.
 	if gateway == nil {
 		return nil, errNilGateway
@@ -123,12 +209,18 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 			DiffsGenerated: true,
 		},
 
-		dosBlocks: make(map[types.BlockID]struct{}),
+		asyncSubscribers:    make(map[modules.ConsensusSetSubscriber]*asyncSubscriber),
+		headerSubscribers:   make(map[modules.ConsensusSetHeaderSubscriber]*headerSubscriber),
+		filteredSubscribers: make(map[modules.ConsensusSetSubscriber]*filteredSubscriber),
+		dosBlocks:           make(map[types.BlockID]struct{}),
+		orphans:             make(map[types.BlockID]orphanBlock),
+		futureBlocks:        make(map[types.BlockID]types.Block),
 
 		marshaler:       stdMarshaler{},
 		blockRuleHelper: stdBlockRuleHelper{},
 		blockValidator:  NewBlockValidator(),
 
+		db:         db,
 		staticDeps: deps,
 		persistDir: persistDir,
 	}
@@ -176,12 +268,22 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 		gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 		gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
 		gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
+		gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+		gateway.RegisterRPC("BlocksByID", cs.rpcBlocksByID)
+		gateway.RegisterRPC("CompactBlk", cs.rpcSendCompactBlk)
 		gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
+		gateway.RegisterCapability(headerSyncCapability)
+		gateway.RegisterCapability(compactBlocksCapability)
 		cs.tg.OnStop(func() {
 			cs.gateway.UnregisterRPC("SendBlocks")
 			cs.gateway.UnregisterRPC("RelayHeader")
 			cs.gateway.UnregisterRPC("SendBlk")
+			cs.gateway.UnregisterRPC("SendHeaders")
+			cs.gateway.UnregisterRPC("BlocksByID")
+			cs.gateway.UnregisterRPC("CompactBlk")
 			cs.gateway.UnregisterConnectCall("SendBlocks")
+			cs.gateway.UnregisterCapability(headerSyncCapability)
+			cs.gateway.UnregisterCapability(compactBlocksCapability)
 		})
 
 		// Mark that we are synced with the network.
@@ -195,7 +297,7 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 
 // BlockAtHeight returns the block at a given height.
 func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Block, exists bool) {
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		id, err := getPath(tx, height)
 		if err != nil {
 			return err
@@ -213,7 +315,7 @@ func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Blo
 
 // BlockByID returns the block for a given BlockID.
 func (cs *ConsensusSet) BlockByID(id types.BlockID) (block types.Block, height types.BlockHeight, exists bool) {
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -226,6 +328,54 @@ func (cs *ConsensusSet) BlockByID(id types.BlockID) (block types.Block, height t
 	return block, height, exists
 }
 
+// BlocksInRange returns the blocks in the current path between the start and
+// end heights, inclusive, along with the diffs that each block applied to the
+// consensus set. At most maxBlocksInRange blocks can be requested at once.
+func (cs *ConsensusSet) BlocksInRange(start, end types.BlockHeight) (blocks []modules.BlockDiffs, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+	if start > end {
+		return nil, errInvalidRange
+	}
+	if end-start >= maxBlocksInRange {
+		return nil, errRangeTooLarge
+	}
+
+	err = cs.db.View(func(tx Tx) error {
+		if end > blockHeight(tx) {
+			return errRangeTooHigh
+		}
+		for height := start; height <= end; height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, modules.BlockDiffs{
+				Block:  pb.Block,
+				Height: pb.Height,
+
+				SiacoinOutputDiffs:        pb.SiacoinOutputDiffs,
+				FileContractDiffs:         pb.FileContractDiffs,
+				SiafundOutputDiffs:        pb.SiafundOutputDiffs,
+				DelayedSiacoinOutputDiffs: pb.DelayedSiacoinOutputDiffs,
+				SiafundPoolDiffs:          pb.SiafundPoolDiffs,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
 // ChildTarget returns the target for the child of a block.
 func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exists bool) {
 	// A call to a closed database can cause undefined behavior.
@@ -235,7 +385,7 @@ func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exis
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -257,7 +407,7 @@ func (cs *ConsensusSet) managedCurrentBlock() (block types.Block) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb := currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
@@ -280,7 +430,7 @@ func (cs *ConsensusSet) CurrentBlock() (block types.Block) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb := currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
@@ -309,13 +459,43 @@ func (cs *ConsensusSet) Height() (height types.BlockHeight) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		height = blockHeight(tx)
 		return nil
 	})
 	return height
 }
 
+// Snapshot returns a read-only view of the state of the consensus set. The
+// view is captured in a single database transaction, so callers that need
+// several related values should use Snapshot instead of calling the
+// individual getters, which may observe different states.
+func (cs *ConsensusSet) Snapshot() (snap modules.ConsensusSnapshot, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	err = cs.db.View(func(tx Tx) error {
+		snap.Height = blockHeight(tx)
+		snap.CurrentBlock = currentBlockID(tx)
+		pb, err := getBlockMap(tx, snap.CurrentBlock)
+		if err != nil {
+			return err
+		}
+		snap.ChildTarget = pb.ChildTarget
+		snap.SiafundPool = getSiafundPool(tx)
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	return snap, nil
+}
+
 // InCurrentPath returns true if the block presented is in the current path,
 // false otherwise.
 func (cs *ConsensusSet) InCurrentPath(id types.BlockID) (inPath bool) {
@@ -326,7 +506,7 @@ func (cs *ConsensusSet) InCurrentPath(id types.BlockID) (inPath bool) {
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			inPath = false
@@ -354,7 +534,7 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 	defer cs.tg.Done()
 
 	// Error is not checked because it does not matter.
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -366,6 +546,25 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 	return timestamp, exists
 }
 
+// MedianTimePast returns the median timestamp of the most recent
+// MedianTimestampWindow blocks in the current path.
+func (cs *ConsensusSet) MedianTimePast() (mtp types.Timestamp) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return 0
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	_ = cs.db.View(func(tx Tx) error {
+		mtp = medianTimePast(tx.Bucket(BlockMap), currentProcessedBlock(tx))
+		return nil
+	})
+	return mtp
+}
+
 // StorageProofSegment returns the segment to be used in the storage proof for
 // a given file contract.
 func (cs *ConsensusSet) StorageProofSegment(fcid types.FileContractID) (index uint64, err error) {
@@ -376,7 +575,7 @@ func (cs *ConsensusSet) StorageProofSegment(fcid types.FileContractID) (index ui
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx Tx) error {
 		index, err = storageProofSegment(tx, fcid)
 		return nil
 	})
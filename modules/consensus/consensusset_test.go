@@ -202,3 +202,95 @@ func TestDatabaseClosing(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestBlocksInRange probes the BlocksInRange method of the consensus set.
+func TestBlocksInRange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Fetch every block in the current path and compare against BlockAtHeight
+	// and the diffs in the block map.
+	height := cst.cs.Height()
+	if height >= maxBlocksInRange {
+		t.Fatal("test requires the height to be below maxBlocksInRange")
+	}
+	blocks, err := cst.cs.BlocksInRange(0, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.BlockHeight(len(blocks)) != height+1 {
+		t.Fatal("wrong number of blocks returned:", len(blocks))
+	}
+	for i, bd := range blocks {
+		b, exists := cst.cs.BlockAtHeight(types.BlockHeight(i))
+		if !exists || b.ID() != bd.Block.ID() || bd.Height != types.BlockHeight(i) {
+			t.Fatal("wrong block returned at height", i)
+		}
+		pb, err := cst.cs.dbGetBlockMap(b.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pb.SiacoinOutputDiffs) != len(bd.SiacoinOutputDiffs) || len(pb.DelayedSiacoinOutputDiffs) != len(bd.DelayedSiacoinOutputDiffs) {
+			t.Fatal("wrong diffs returned at height", i)
+		}
+	}
+
+	// Check the invalid ranges.
+	if _, err := cst.cs.BlocksInRange(2, 1); err != errInvalidRange {
+		t.Error("expected errInvalidRange, got", err)
+	}
+	if _, err := cst.cs.BlocksInRange(height, height+1); err != errRangeTooHigh {
+		t.Error("expected errRangeTooHigh, got", err)
+	}
+	if _, err := cst.cs.BlocksInRange(0, maxBlocksInRange); err != errRangeTooLarge {
+		t.Error("expected errRangeTooLarge, got", err)
+	}
+}
+
+// TestConsensusSnapshot checks that Snapshot returns a view that matches the state of
+// the consensus set.
+func TestConsensusSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	snap, err := cst.cs.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Height != cst.cs.Height() {
+		t.Fatal("snapshot has the wrong height:", snap.Height)
+	}
+	currentID := cst.cs.CurrentBlock().ID()
+	if snap.CurrentBlock != currentID {
+		t.Fatal("snapshot has the wrong current block")
+	}
+	target, _ := cst.cs.ChildTarget(currentID)
+	if snap.ChildTarget != target {
+		t.Fatal("snapshot has the wrong child target")
+	}
+	if !snap.SiafundPool.Equals(cst.cs.dbGetSiafundPool()) {
+		t.Fatal("snapshot has the wrong siafund pool")
+	}
+
+	// The snapshot should not change when a block is mined.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if snap.CurrentBlock != currentID || snap.Height+1 != cst.cs.Height() {
+		t.Fatal("snapshot changed after a block was mined")
+	}
+}
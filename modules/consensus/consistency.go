@@ -10,12 +10,10 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
-
-	"github.com/coreos/bbolt"
 )
 
 // manageErr handles an error detected by the consistency checks.
-func manageErr(tx *bolt.Tx, err error) {
+func manageErr(tx Tx, err error) {
 	markInconsistency(tx)
 	if build.DEBUG {
 		panic(err)
@@ -28,13 +26,13 @@ func manageErr(tx *bolt.Tx, err error) {
 // the elements in sorted order into a merkle tree and taking the root. All
 // consensus sets with the same current block should have identical consensus
 // checksums.
-func consensusChecksum(tx *bolt.Tx) crypto.Hash {
+func consensusChecksum(tx Tx) crypto.Hash {
 	// Create a checksum tree.
 	tree := crypto.NewTree()
 
 	// For all of the constant buckets, push every key and every value. Buckets
 	// are sorted in byte-order, therefore this operation is deterministic.
-	consensusSetBuckets := []*bolt.Bucket{
+	consensusSetBuckets := []Bucket{
 		tx.Bucket(BlockPath),
 		tx.Bucket(SiacoinOutputs),
 		tx.Bucket(FileContracts),
@@ -55,7 +53,7 @@ func consensusChecksum(tx *bolt.Tx) crypto.Hash {
 	// Iterate through all the buckets looking for buckets prefixed with
 	// prefixDSCO or prefixFCEX. Buckets are presented in byte-sorted order by
 	// name.
-	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+	err := tx.ForEach(func(name []byte, b Bucket) error {
 		// If the bucket is not a delayed siacoin output bucket or a file
 		// contract expiration bucket, skip.
 		if !bytes.HasPrefix(name, prefixDSCO) && !bytes.HasPrefix(name, prefixFCEX) {
@@ -78,11 +76,11 @@ func consensusChecksum(tx *bolt.Tx) crypto.Hash {
 
 // checkSiacoinCount checks that the number of siacoins countable within the
 // consensus set equal the expected number of siacoins for the block height.
-func checkSiacoinCount(tx *bolt.Tx) {
+func checkSiacoinCount(tx Tx) error {
 	// Iterate through all the buckets looking for the delayed siacoin output
 	// buckets, and check that they are for the correct heights.
 	var dscoSiacoins types.Currency
-	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+	err := tx.ForEach(func(name []byte, b Bucket) error {
 		// Check if the bucket is a delayed siacoin output bucket.
 		if !bytes.HasPrefix(name, prefixDSCO) {
 			return nil
@@ -93,7 +91,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 			var sco types.SiacoinOutput
 			err := encoding.Unmarshal(delayedOutput, &sco)
 			if err != nil {
-				manageErr(tx, err)
+				return err
 			}
 			dscoSiacoins = dscoSiacoins.Add(sco.Value)
 			return nil
@@ -104,7 +102,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 
 	// Add all of the siacoin outputs.
@@ -113,13 +111,13 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		var sco types.SiacoinOutput
 		err := encoding.Unmarshal(scoBytes, &sco)
 		if err != nil {
-			manageErr(tx, err)
+			return err
 		}
 		scoSiacoins = scoSiacoins.Add(sco.Value)
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 
 	// Add all of the payouts from file contracts.
@@ -128,7 +126,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		var fc types.FileContract
 		err := encoding.Unmarshal(fcBytes, &fc)
 		if err != nil {
-			manageErr(tx, err)
+			return err
 		}
 		var fcCoins types.Currency
 		for _, output := range fc.ValidProofOutputs {
@@ -138,7 +136,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 
 	// Add all of the siafund claims.
@@ -147,7 +145,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		var sfo types.SiafundOutput
 		err := encoding.Unmarshal(sfoBytes, &sfo)
 		if err != nil {
-			manageErr(tx, err)
+			return err
 		}
 
 		coinsPerFund := getSiafundPool(tx).Sub(sfo.ClaimStart)
@@ -156,7 +154,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 
 	expectedSiacoins := types.CalculateNumSiacoins(blockHeight(tx))
@@ -168,34 +166,36 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		} else {
 			diagnostics += fmt.Sprintf("total: %v\nexpected: %v\n expected is bigger: %v", totalSiacoins, expectedSiacoins, totalSiacoins.Sub(expectedSiacoins))
 		}
-		manageErr(tx, errors.New(diagnostics))
+		return errors.New(diagnostics)
 	}
+	return nil
 }
 
 // checkSiafundCount checks that the number of siafunds countable within the
 // consensus set equal the expected number of siafunds for the block height.
-func checkSiafundCount(tx *bolt.Tx) {
+func checkSiafundCount(tx Tx) error {
 	var total types.Currency
 	err := tx.Bucket(SiafundOutputs).ForEach(func(_, siafundOutputBytes []byte) error {
 		var sfo types.SiafundOutput
 		err := encoding.Unmarshal(siafundOutputBytes, &sfo)
 		if err != nil {
-			manageErr(tx, err)
+			return err
 		}
 		total = total.Add(sfo.Value)
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 	if !total.Equals(types.SiafundCount) {
-		manageErr(tx, errors.New("wrong number of siafunds in the consensus set"))
+		return errors.New("wrong number of siafunds in the consensus set")
 	}
+	return nil
 }
 
 // checkDSCOs scans the sets of delayed siacoin outputs and checks for
 // consistency.
-func checkDSCOs(tx *bolt.Tx) {
+func checkDSCOs(tx Tx) error {
 	// Create a map to track which delayed siacoin output maps exist, and
 	// another map to track which ids have appeared in the dsco set.
 	dscoTracker := make(map[types.BlockHeight]struct{})
@@ -203,7 +203,7 @@ func checkDSCOs(tx *bolt.Tx) {
 
 	// Iterate through all the buckets looking for the delayed siacoin output
 	// buckets, and check that they are for the correct heights.
-	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+	err := tx.ForEach(func(name []byte, b Bucket) error {
 		// If the bucket is not a delayed siacoin output bucket or a file
 		// contract expiration bucket, skip.
 		if !bytes.HasPrefix(name, prefixDSCO) {
@@ -214,7 +214,7 @@ func checkDSCOs(tx *bolt.Tx) {
 		var height types.BlockHeight
 		err := encoding.Unmarshal(name[len(prefixDSCO):], &height)
 		if err != nil {
-			manageErr(tx, err)
+			return err
 		}
 		_, exists := dscoTracker[height]
 		if exists {
@@ -237,7 +237,7 @@ func checkDSCOs(tx *bolt.Tx) {
 			var sco types.SiacoinOutput
 			err := encoding.Unmarshal(delayedOutput, &sco)
 			if err != nil {
-				manageErr(tx, err)
+				return err
 			}
 			total = total.Add(sco.Value)
 			return nil
@@ -255,7 +255,7 @@ func checkDSCOs(tx *bolt.Tx) {
 		return nil
 	})
 	if err != nil {
-		manageErr(tx, err)
+		return err
 	}
 
 	// Check that all of the correct heights are represented.
@@ -267,20 +267,21 @@ func checkDSCOs(tx *bolt.Tx) {
 		}
 		_, exists := dscoTracker[i]
 		if !exists {
-			manageErr(tx, errors.New("missing a dsco bucket"))
+			return errors.New("missing a dsco bucket")
 		}
 		expectedBuckets++
 	}
 	if len(dscoTracker) != expectedBuckets {
-		manageErr(tx, errors.New("too many dsco buckets"))
+		return errors.New("too many dsco buckets")
 	}
+	return nil
 }
 
 // checkRevertApply reverts the most recent block, checking to see that the
 // consensus set hash matches the hash obtained for the previous block. Then it
 // applies the block again and checks that the consensus set hash matches the
 // original consensus set hash.
-func (cs *ConsensusSet) checkRevertApply(tx *bolt.Tx) {
+func (cs *ConsensusSet) checkRevertApply(tx Tx) {
 	current := currentProcessedBlock(tx)
 	// Don't perform the check if this block is the genesis block.
 	if current.Block.ID() == cs.blockRoot.Block.ID() {
@@ -312,15 +313,17 @@ func (cs *ConsensusSet) checkRevertApply(tx *bolt.Tx) {
 
 // checkConsistency runs a series of checks to make sure that the consensus set
 // is consistent with some rules that should always be true.
-func (cs *ConsensusSet) checkConsistency(tx *bolt.Tx) {
+func (cs *ConsensusSet) checkConsistency(tx Tx) {
 	if cs.checkingConsistency {
 		return
 	}
 
 	cs.checkingConsistency = true
-	checkDSCOs(tx)
-	checkSiacoinCount(tx)
-	checkSiafundCount(tx)
+	for _, check := range []func(Tx) error{checkDSCOs, checkSiacoinCount, checkSiafundCount} {
+		if err := check(tx); err != nil {
+			manageErr(tx, err)
+		}
+	}
 	if build.DEBUG {
 		cs.checkRevertApply(tx)
 	}
@@ -331,7 +334,7 @@ func (cs *ConsensusSet) checkConsistency(tx *bolt.Tx) {
 // Useful for detecting database corruption in production without needing to go
 // through the extremely slow process of running a consistency check every
 // block.
-func (cs *ConsensusSet) maybeCheckConsistency(tx *bolt.Tx) {
+func (cs *ConsensusSet) maybeCheckConsistency(tx Tx) {
 	if fastrand.Intn(1000) == 0 {
 		cs.checkConsistency(tx)
 	}
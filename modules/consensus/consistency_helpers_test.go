@@ -2,14 +2,12 @@ package consensus
 
 import (
 	"github.com/NebulousLabs/Sia/crypto"
-
-	"github.com/coreos/bbolt"
 )
 
 // dbConsensusChecksum is a convenience function to call consensusChecksum
 // without a bolt.Tx.
 func (cs *ConsensusSet) dbConsensusChecksum() (checksum crypto.Hash) {
-	err := cs.db.Update(func(tx *bolt.Tx) error {
+	err := cs.db.Update(func(tx Tx) error {
 		checksum = consensusChecksum(tx)
 		return nil
 	})
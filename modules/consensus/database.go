@@ -26,9 +26,81 @@ var (
 	errNilItem        = errors.New("requested item does not exist")
 	errNonEmptyBucket = errors.New("cannot remove a map with objects still in it")
 	errRepeatInsert   = errors.New("attempting to add an already existing item to the consensus set")
+
+	errUnsupportedDatabase = errors.New("operation requires the consensus set to be stored in a bolt database")
 )
 
 type (
+	// Database is a transactional key-value store that holds the consensus
+	// set. Keys within a bucket, and the buckets themselves, must be iterated
+	// in byte order, and an Update must be rolled back entirely if the
+	// provided function returns an error. The consensus set takes ownership
+	// of the database and closes it when the consensus set is closed.
+	Database interface {
+		// View runs fn in a read-only transaction.
+		View(fn func(Tx) error) error
+
+		// Update runs fn in a read-write transaction. The transaction is
+		// committed if fn returns nil, and rolled back otherwise.
+		Update(fn func(Tx) error) error
+
+		// Close closes the database.
+		Close() error
+	}
+
+	// Tx is a transaction on a Database. Top-level buckets can only be
+	// created or deleted within an Update.
+	Tx interface {
+		// Bucket returns the bucket with the given name, or nil if it does not
+		// exist.
+		Bucket(name []byte) Bucket
+
+		// CreateBucket creates a new bucket, returning an error if it already
+		// exists.
+		CreateBucket(name []byte) (Bucket, error)
+
+		// CreateBucketIfNotExists creates a new bucket if it does not already
+		// exist, and returns it.
+		CreateBucketIfNotExists(name []byte) (Bucket, error)
+
+		// DeleteBucket deletes the bucket with the given name.
+		DeleteBucket(name []byte) error
+
+		// ForEach calls fn for every bucket in byte order of their names.
+		ForEach(fn func(name []byte, b Bucket) error) error
+	}
+
+	// Bucket is a collection of key/value pairs within a Tx. Slices returned
+	// by a Bucket are only valid for the life of the transaction.
+	Bucket interface {
+		// Get returns the value for the key, or nil if it does not exist.
+		Get(key []byte) []byte
+
+		// Put sets the value for the key.
+		Put(key, value []byte) error
+
+		// Delete removes the key. Deleting a key that does not exist is not an
+		// error.
+		Delete(key []byte) error
+
+		// ForEach calls fn for every key/value pair in byte order of the keys.
+		ForEach(fn func(k, v []byte) error) error
+
+		// Cursor returns a cursor for iterating over the bucket in byte order
+		// of the keys.
+		Cursor() Cursor
+
+		// Len returns the number of keys in the bucket.
+		Len() int
+	}
+
+	// Cursor iterates over the key/value pairs of a Bucket. A nil key
+	// indicates that there are no more pairs.
+	Cursor interface {
+		First() (key, value []byte)
+		Next() (key, value []byte)
+	}
+
 	// dbBucket represents a collection of key/value pairs inside the database.
 	dbBucket interface {
 		Get(key []byte) []byte
@@ -40,17 +112,109 @@ type (
 		Bucket(name []byte) dbBucket
 	}
 
-	// boltTxWrapper wraps a bolt.Tx so that it matches the dbTx interface. The
-	// wrap is necessary because bolt.Tx.Bucket() returns a fixed type
-	// (bolt.Bucket), but we want it to return an interface (dbBucket).
-	boltTxWrapper struct {
+	// txWrapper wraps a Tx so that it matches the dbTx interface. The wrap is
+	// necessary because Tx.Bucket() returns a Bucket, but we want it to
+	// return a dbBucket.
+	txWrapper struct {
+		tx Tx
+	}
+
+	// boltDatabase is the Database that stores the consensus set in a bolt
+	// file. It is the default Database.
+	boltDatabase struct {
+		*persist.BoltDatabase
+	}
+
+	// boltTx adapts a bolt.Tx to the Tx interface.
+	boltTx struct {
 		tx *bolt.Tx
 	}
+
+	// boltBucket adapts a bolt.Bucket to the Bucket interface.
+	boltBucket struct {
+		*bolt.Bucket
+	}
 )
 
 // Bucket returns the dbBucket associated with the given bucket name.
-func (b boltTxWrapper) Bucket(name []byte) dbBucket {
-	return b.tx.Bucket(name)
+func (w txWrapper) Bucket(name []byte) dbBucket {
+	// A nil Bucket must be returned as a nil dbBucket.
+	b := w.tx.Bucket(name)
+	if b == nil {
+		return nil
+	}
+	return b
+}
+
+// View implements Database.
+func (db boltDatabase) View(fn func(Tx) error) error {
+	return db.DB.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+// Update implements Database.
+func (db boltDatabase) Update(fn func(Tx) error) error {
+	return db.DB.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+// wrapBoltBucket returns b as a Bucket, preserving nil.
+func wrapBoltBucket(b *bolt.Bucket) Bucket {
+	if b == nil {
+		return nil
+	}
+	return boltBucket{b}
+}
+
+// Bucket implements Tx.
+func (tx boltTx) Bucket(name []byte) Bucket {
+	return wrapBoltBucket(tx.tx.Bucket(name))
+}
+
+// CreateBucket implements Tx.
+func (tx boltTx) CreateBucket(name []byte) (Bucket, error) {
+	b, err := tx.tx.CreateBucket(name)
+	return wrapBoltBucket(b), err
+}
+
+// CreateBucketIfNotExists implements Tx.
+func (tx boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	b, err := tx.tx.CreateBucketIfNotExists(name)
+	return wrapBoltBucket(b), err
+}
+
+// DeleteBucket implements Tx.
+func (tx boltTx) DeleteBucket(name []byte) error {
+	return tx.tx.DeleteBucket(name)
+}
+
+// ForEach implements Tx.
+func (tx boltTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	return tx.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		return fn(name, wrapBoltBucket(b))
+	})
+}
+
+// Cursor implements Bucket.
+func (b boltBucket) Cursor() Cursor {
+	return b.Bucket.Cursor()
+}
+
+// Len implements Bucket.
+func (b boltBucket) Len() int {
+	return b.Stats().KeyN
+}
+
+// openBoltDatabase opens the bolt database at filename, creating it if it does
+// not exist.
+func openBoltDatabase(filename string) (Database, error) {
+	db, err := persist.OpenDatabase(dbMetadata, filename)
+	if err != nil {
+		return nil, err
+	}
+	return boltDatabase{db}, nil
 }
 
 // replaceDatabase backs up the existing database and creates a new one.
@@ -64,7 +228,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 	// Try again to create a new database, this time without checking for an
 	// outdated database error.
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = openBoltDatabase(filename)
 	if err != nil {
 		return errors.New("error opening consensus database: " + err.Error())
 	}
@@ -73,7 +237,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 // openDB loads the set database and populates it with the necessary buckets
 func (cs *ConsensusSet) openDB(filename string) (err error) {
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = openBoltDatabase(filename)
 	if err == persist.ErrBadVersion {
 		return cs.replaceDatabase(filename)
 	}
@@ -85,7 +249,7 @@ func (cs *ConsensusSet) openDB(filename string) (err error) {
 
 // initDB is run if there is no existing consensus database, creating a
 // database with all the required buckets and sane initial values.
-func (cs *ConsensusSet) initDB(tx *bolt.Tx) error {
+func (cs *ConsensusSet) initDB(tx Tx) error {
 	// If the database has already been initialized, there is nothing to do.
 	// Initialization can be detected by looking for the presence of the siafund
 	// pool bucket. (legacy design chioce - ultimately probably not the best way
@@ -115,7 +279,7 @@ func (cs *ConsensusSet) initDB(tx *bolt.Tx) error {
 
 // markInconsistency flags the database to indicate that inconsistency has been
 // detected.
-func markInconsistency(tx *bolt.Tx) {
+func markInconsistency(tx Tx) {
 	// Place a 'true' in the consistency bucket to indicate that
 	// inconsistencies have been found.
 	err := tx.Bucket(Consistency).Put(Consistency, encoding.Marshal(true))
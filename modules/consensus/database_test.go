@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+)
+
+// countingDatabase wraps a Database, counting the number of transactions.
+type countingDatabase struct {
+	Database
+	views, updates int
+}
+
+// View implements Database.
+func (db *countingDatabase) View(fn func(Tx) error) error {
+	db.views++
+	return db.Database.View(fn)
+}
+
+// Update implements Database.
+func (db *countingDatabase) Update(fn func(Tx) error) error {
+	db.updates++
+	return db.Database.Update(fn)
+}
+
+// TestNewWithDatabase checks that a consensus set can be stored in a
+// database provided by the caller.
+func TestNewWithDatabase(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name())
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	if _, err := NewWithDatabase(g, false, testdir, nil); err != errNilDatabase {
+		t.Fatal("expected errNilDatabase, got", err)
+	}
+
+	bdb, err := openBoltDatabase(filepath.Join(testdir, "custom.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &countingDatabase{Database: bdb}
+	cs, err := NewWithDatabase(g, false, testdir, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Height() != 0 || cs.CurrentBlock().ID() != cs.blockRoot.Block.ID() {
+		t.Fatal("consensus set was not initialized")
+	}
+	if db.views == 0 || db.updates == 0 {
+		t.Fatal("provided database was not used")
+	}
+	if _, err := os.Stat(filepath.Join(testdir, DatabaseFilename)); !os.IsNotExist(err) {
+		t.Fatal("default database was created")
+	}
+
+	// Operations that depend on the bolt file are not supported.
+	if _, err := cs.ExportSnapshot(new(bytes.Buffer)); err != errUnsupportedDatabase {
+		t.Fatal("expected errUnsupportedDatabase, got", err)
+	}
+	if _, err := cs.Compact(); err != errUnsupportedDatabase {
+		t.Fatal("expected errUnsupportedDatabase, got", err)
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/NebulousLabs/errors"
-	"github.com/coreos/bbolt"
 )
 
 // Errors returned by this file.
@@ -17,6 +16,10 @@ var (
 	// cannot begin because the consensus database was not upgraded before the
 	// hardfork height.
 	errOakHardforkIncompatibility = errors.New("difficulty adjustment hardfork incompatibility detected")
+
+	// errInvalidHashrateWindow is returned if the hashrate is estimated over
+	// zero blocks, or over more blocks than are in the current path.
+	errInvalidHashrateWindow = errors.New("hashrate estimation window must be between 1 and the height of a block in the current path")
 )
 
 // difficulty.go defines the Oak difficulty adjustment algorithm. Past the
@@ -73,7 +76,7 @@ func (cs *ConsensusSet) childTargetOak(parentTotalTime int64, parentTotalTarget,
 	// The desired total time is the difference between the genesis block
 	// timestamp and the current block timestamp.
 	var delta int64
-	if parentHeight < types.OakHardforkFixBlock {
+	if !types.RuleOakDifficultyFix.Active(parentHeight) {
 		// This is the original code. It is incorrect, because it is comparing
 		// 'expectedTime', an absolute value, to 'parentTotalTime', a value
 		// which gets compressed every block. The result is that 'expectedTime'
@@ -144,17 +147,16 @@ func (cs *ConsensusSet) childTargetOak(parentTotalTime int64, parentTotalTarget,
 
 // getBlockTotals returns the block totals values that get stored in
 // storeBlockTotals.
-func (cs *ConsensusSet) getBlockTotals(tx *bolt.Tx, id types.BlockID) (totalTime int64, totalTarget types.Target) {
+func (cs *ConsensusSet) getBlockTotals(tx Tx, id types.BlockID) (totalTime int64, totalTarget types.Target) {
 	totalsBytes := tx.Bucket(BucketOak).Get(id[:])
 	totalTime = int64(binary.LittleEndian.Uint64(totalsBytes[:8]))
 	copy(totalTarget[:], totalsBytes[8:])
 	return
 }
 
-// storeBlockTotals computes the new total time and total target for the current
-// block and stores that new time in the database. It also returns the new
-// totals.
-func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+// blockTotals computes the total time and total target of a block from the
+// totals of its parent.
+func blockTotals(currentHeight types.BlockHeight, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target) {
 	// Reset the prevTotalTime to a delta of zero just before the hardfork.
 	//
 	// NOTICE: This code is broken, an incorrectly executed hardfork. The
@@ -171,7 +173,7 @@ func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockH
 	//
 	// The disruption will be complete well before we can deploy a fix, so
 	// there's no point in fixing it.
-	if currentHeight == types.OakHardforkBlock-1 {
+	if currentHeight == types.RuleOakDifficulty.ActivationHeight()-1 {
 		prevTotalTime = int64(types.BlockFrequency * currentHeight)
 	}
 
@@ -179,6 +181,14 @@ func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockH
 	// delta.
 	newTotalTime = (prevTotalTime * types.OakDecayNum / types.OakDecayDenom) + (int64(currentTimestamp) - int64(parentTimestamp))
 	newTotalTarget = prevTotalTarget.MulDifficulty(big.NewRat(types.OakDecayNum, types.OakDecayDenom)).AddDifficulties(targetOfCurrentBlock)
+	return newTotalTime, newTotalTarget
+}
+
+// storeBlockTotals computes the new total time and total target for the current
+// block and stores that new time in the database. It also returns the new
+// totals.
+func (cs *ConsensusSet) storeBlockTotals(tx Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+	newTotalTime, newTotalTarget = blockTotals(currentHeight, prevTotalTime, parentTimestamp, currentTimestamp, prevTotalTarget, targetOfCurrentBlock)
 
 	// Store the new total time and total target in the database at the
 	// appropriate id.
@@ -198,7 +208,7 @@ func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockH
 //
 // After oak initialization is complete, a specific field in the oak bucket is
 // marked so that oak initialization can be skipped in the future.
-func (cs *ConsensusSet) initOak(tx *bolt.Tx) error {
+func (cs *ConsensusSet) initOak(tx Tx) error {
 	// Prep the oak bucket.
 	bucketOak, err := tx.CreateBucketIfNotExists(BucketOak)
 	if err != nil {
@@ -213,7 +223,7 @@ func (cs *ConsensusSet) initOak(tx *bolt.Tx) error {
 	// If the current height is greater than the hardfork trigger date, return
 	// an error and refuse to initialize.
 	height := blockHeight(tx)
-	if height > types.OakHardforkBlock {
+	if height > types.RuleOakDifficulty.ActivationHeight() {
 		return errOakHardforkIncompatibility
 	}
 
@@ -256,3 +266,96 @@ func (cs *ConsensusSet) initOak(tx *bolt.Tx) error {
 	}
 	return nil
 }
+
+// CurrentTarget returns the target that the next block on top of the current
+// block must meet.
+func (cs *ConsensusSet) CurrentTarget() (target types.Target) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.Target{}
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx Tx) error {
+		target = currentProcessedBlock(tx).ChildTarget
+		return nil
+	})
+	return target
+}
+
+// Difficulty returns the difficulty of the next block on top of the current
+// block, which is the expected number of hashes needed to find it.
+func (cs *ConsensusSet) Difficulty() types.Currency {
+	return cs.CurrentTarget().Difficulty()
+}
+
+// estimatedHashrate estimates the hashrate of the network in hashes per second
+// at the block at 'height' in the current path, using the difficulty of the
+// 'window' blocks ending at that block and the time it took to find them.
+func estimatedHashrate(tx Tx, height, window types.BlockHeight) (types.Currency, error) {
+	if window == 0 || window > height || height > blockHeight(tx) {
+		return types.Currency{}, errInvalidHashrateWindow
+	}
+	// Each block was found at the target set by its parent, so the parent of
+	// the oldest block in the window is needed too.
+	var totalDifficulty types.Currency
+	var newest, oldest types.Timestamp
+	for h := height - window; h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return types.Currency{}, err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return types.Currency{}, err
+		}
+		if h < height {
+			totalDifficulty = totalDifficulty.Add(pb.ChildTarget.Difficulty())
+		}
+		if h == height-window {
+			oldest = pb.Block.Timestamp
+		}
+		newest = pb.Block.Timestamp
+	}
+	elapsed := int64(newest) - int64(oldest)
+	if elapsed <= 0 {
+		// Timestamps are not required to increase, avoid dividing by zero.
+		elapsed = 1
+	}
+	return totalDifficulty.Div64(uint64(elapsed)), nil
+}
+
+// EstimatedHashrate estimates the hashrate of the network in hashes per
+// second, using the difficulty of the most recent 'window' blocks and the time
+// it took to find them.
+func (cs *ConsensusSet) EstimatedHashrate(window types.BlockHeight) (hashrate types.Currency, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Currency{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx Tx) error {
+		hashrate, err = estimatedHashrate(tx, blockHeight(tx), window)
+		return err
+	})
+	return hashrate, err
+}
+
+// EstimatedHashrateAt estimates the hashrate of the network at the block at
+// 'height' in the current path, using the difficulty of the 'window' blocks
+// ending at that block and the time it took to find them.
+func (cs *ConsensusSet) EstimatedHashrateAt(height, window types.BlockHeight) (hashrate types.Currency, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Currency{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx Tx) error {
+		hashrate, err = estimatedHashrate(tx, height, window)
+		return err
+	})
+	return hashrate, err
+}
@@ -6,8 +6,6 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 // TestChildTargetOak checks the childTargetOak function, especially for edge
@@ -282,7 +280,7 @@ func TestStoreBlockTotals(t *testing.T) {
 	// Check that as totals get stored over and over, the values getting
 	// returned follow a decay. While storing repeatedly, check that the
 	// getBlockTotals values match the values that were stored.
-	err = cs.db.Update(func(tx *bolt.Tx) error {
+	err = cs.db.Update(func(tx Tx) error {
 		var totalTime int64
 		var id types.BlockID
 		var parentTimestamp, currentTimestamp types.Timestamp
@@ -368,3 +366,71 @@ func TestHardforkMechanic(t *testing.T) {
 
 	}
 }
+
+// TestEstimatedHashrate probes the CurrentTarget, Difficulty,
+// EstimatedHashrate and EstimatedHashrateAt methods of the consensus set.
+func TestEstimatedHashrate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	target, _ := cst.cs.ChildTarget(cst.cs.CurrentBlock().ID())
+	if cst.cs.CurrentTarget() != target {
+		t.Fatal("CurrentTarget does not match the child target of the current block")
+	}
+	if !cst.cs.Difficulty().Equals(target.Difficulty()) {
+		t.Fatal("Difficulty does not match the difficulty of the current target")
+	}
+
+	height := cst.cs.Height()
+	if _, err := cst.cs.EstimatedHashrate(0); err != errInvalidHashrateWindow {
+		t.Fatal("expected errInvalidHashrateWindow, got", err)
+	}
+	if _, err := cst.cs.EstimatedHashrate(height + 1); err != errInvalidHashrateWindow {
+		t.Fatal("expected errInvalidHashrateWindow, got", err)
+	}
+
+	// Compute the hashrate of the last 5 blocks by hand.
+	window := types.BlockHeight(5)
+	var totalDifficulty types.Currency
+	for h := height - window + 1; h <= height; h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		target, _ := cst.cs.ChildTarget(b.ParentID)
+		totalDifficulty = totalDifficulty.Add(target.Difficulty())
+	}
+	newest, _ := cst.cs.BlockAtHeight(height)
+	oldest, _ := cst.cs.BlockAtHeight(height - window)
+	elapsed := int64(newest.Timestamp) - int64(oldest.Timestamp)
+	if elapsed <= 0 {
+		elapsed = 1
+	}
+	hashrate, err := cst.cs.EstimatedHashrate(window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hashrate.Equals(totalDifficulty.Div64(uint64(elapsed))) {
+		t.Fatal("hashrate was not estimated correctly:", hashrate)
+	}
+
+	// Estimating the hashrate at the current height should give the same
+	// result, and heights beyond the current height should be rejected.
+	hashrateAt, err := cst.cs.EstimatedHashrateAt(height, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hashrateAt.Equals(hashrate) {
+		t.Fatal("EstimatedHashrateAt does not match EstimatedHashrate:", hashrateAt, hashrate)
+	}
+	if _, err := cst.cs.EstimatedHashrateAt(height+1, 1); err != errInvalidHashrateWindow {
+		t.Fatal("expected errInvalidHashrateWindow, got", err)
+	}
+	if _, err := cst.cs.EstimatedHashrateAt(height-1, height); err != errInvalidHashrateWindow {
+		t.Fatal("expected errInvalidHashrateWindow, got", err)
+	}
+}
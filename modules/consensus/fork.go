@@ -2,11 +2,10 @@ package consensus
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
-
-	"github.com/coreos/bbolt"
 )
 
 var (
@@ -17,7 +16,7 @@ var (
 // in the ConsensusSet's current path (the "common parent"). It returns the
 // (inclusive) set of blocks between the common parent and 'pb', starting from
 // the former.
-func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
+func backtrackToCurrentPath(tx Tx, pb *processedBlock) []*processedBlock {
 	path := []*processedBlock{pb}
 	for {
 		// Error is not checked in production code - an error can only indicate
@@ -46,7 +45,7 @@ func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
 // revertToBlock will revert blocks from the ConsensusSet's current path until
 // 'pb' is the current block. Blocks are returned in the order that they were
 // reverted.  'pb' is not reverted.
-func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (revertedBlocks []*processedBlock) {
+func (cs *ConsensusSet) revertToBlock(tx Tx, pb *processedBlock) (revertedBlocks []*processedBlock) {
 	// Sanity check - make sure that pb is in the current path.
 	currentPathID, err := getPath(tx, pb.Height)
 	if err != nil || currentPathID != pb.Block.ID() {
@@ -76,16 +75,19 @@ func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (reverted
 
 // applyUntilBlock will successively apply the blocks between the consensus
 // set's current path and 'pb'.
-func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (appliedBlocks []*processedBlock, err error) {
+func (cs *ConsensusSet) applyUntilBlock(tx Tx, pb *processedBlock) (appliedBlocks []*processedBlock, err error) {
 	// Backtrack to the common parent of 'bn' and current path and then apply the new blocks.
 	newPath := backtrackToCurrentPath(tx, pb)
 	for _, block := range newPath[1:] {
+		start := time.Now()
+
 		// If the diffs for this block have already been generated, apply diffs
 		// directly instead of generating them. This is much faster.
 		if block.DiffsGenerated {
 			commitDiffSet(tx, block, modules.DiffApply)
 		} else {
-			err := generateAndApplyDiff(tx, block)
+			_, verified := cs.verifiedBlocks[block.Block.ID()]
+			err := generateAndApplyDiff(tx, block, verified)
 			if err != nil {
 				// Mark the block as invalid.
 				cs.dosBlocks[block.Block.ID()] = struct{}{}
@@ -93,6 +95,11 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 			}
 		}
 		appliedBlocks = append(appliedBlocks, block)
+		if !cs.checkingConsistency {
+			// Blocks that are re-applied by the consistency checks are not
+			// measured.
+			cs.recordMetric(modules.ConsensusMetricBlockApplied, block.Height, len(block.Block.Transactions), time.Since(start))
+		}
 
 		// Sanity check - after applying a block, check that the consensus set
 		// has maintained consistency.
@@ -109,7 +116,7 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 // error will be returned if any of the blocks applied in the transition are
 // found to be invalid. forkBlockchain is atomic; the ConsensusSet is only
 // updated if the function returns nil.
-func (cs *ConsensusSet) forkBlockchain(tx *bolt.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
+func (cs *ConsensusSet) forkBlockchain(tx Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
 	commonParent := backtrackToCurrentPath(tx, newBlock)[0]
 	revertedBlocks = cs.revertToBlock(tx, commonParent)
 	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock)
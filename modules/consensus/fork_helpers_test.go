@@ -1,13 +1,9 @@
 package consensus
 
-import (
-	"github.com/coreos/bbolt"
-)
-
 // dbBacktrackToCurrentPath is a convenience function to call
 // backtrackToCurrentPath without a bolt.Tx.
 func (cs *ConsensusSet) dbBacktrackToCurrentPath(pb *processedBlock) (pbs []*processedBlock) {
-	_ = cs.db.Update(func(tx *bolt.Tx) error {
+	_ = cs.db.Update(func(tx Tx) error {
 		pbs = backtrackToCurrentPath(tx, pb)
 		return nil
 	})
@@ -17,7 +13,7 @@ func (cs *ConsensusSet) dbBacktrackToCurrentPath(pb *processedBlock) (pbs []*pro
 // dbRevertToNode is a convenience function to call revertToBlock without a
 // bolt.Tx.
 func (cs *ConsensusSet) dbRevertToNode(pb *processedBlock) (pbs []*processedBlock) {
-	_ = cs.db.Update(func(tx *bolt.Tx) error {
+	_ = cs.db.Update(func(tx Tx) error {
 		pbs = cs.revertToBlock(tx, pb)
 		return nil
 	})
@@ -27,7 +23,7 @@ func (cs *ConsensusSet) dbRevertToNode(pb *processedBlock) (pbs []*processedBloc
 // dbForkBlockchain is a convenience function to call forkBlockchain without a
 // bolt.Tx.
 func (cs *ConsensusSet) dbForkBlockchain(pb *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
-	updateErr := cs.db.Update(func(tx *bolt.Tx) error {
+	updateErr := cs.db.Update(func(tx Tx) error {
 		revertedBlocks, appliedBlocks, err = cs.forkBlockchain(tx, pb)
 		return nil
 	})
@@ -0,0 +1,71 @@
+package consensus
+
+// futureblocks.go implements the future block queue. Blocks with a timestamp
+// that is slightly in the future are not valid yet, but will become valid
+// shortly. Rather than rejecting them outright, which would leave a node with
+// a little clock skew behind the rest of the network, they are held in the
+// queue and resubmitted once their timestamp has arrived.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// maxFutureBlocks is the maximum number of blocks held in the future
+	// block queue. Blocks that arrive while the queue is full are dropped.
+	maxFutureBlocks = build.Select(build.Var{
+		Standard: 50,
+		Dev:      20,
+		Testing:  10,
+	}).(int)
+)
+
+// queueFutureBlock adds a block to the future block queue and starts a thread
+// that will resubmit the block once its timestamp is valid. Blocks that are
+// already queued are ignored, so that a peer relaying the same future block
+// many times does not spawn a thread for each copy.
+func (cs *ConsensusSet) queueFutureBlock(b types.Block, id types.BlockID) {
+	if _, exists := cs.futureBlocks[id]; exists {
+		return
+	}
+	if len(cs.futureBlocks) >= maxFutureBlocks {
+		cs.log.Debugln("WARN: future block queue is full, dropping block", id)
+		return
+	}
+	cs.futureBlocks[id] = b
+	go cs.threadedSleepOnFutureBlock(b, id)
+}
+
+// threadedSleepOnFutureBlock will sleep until the timestamp of a future block
+// has arrived, and then resubmit the block.
+func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block, id types.BlockID) {
+	// Add this thread to the threadgroup.
+	err := cs.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	// Perform a soft-sleep while we wait for the block to become valid.
+	select {
+	case <-cs.tg.StopChan():
+		return
+	case <-time.After(time.Duration(b.Timestamp-(types.CurrentTimestamp()+types.FutureThreshold)) * time.Second):
+	}
+
+	// Remove the block from the queue before resubmitting it, so that it is
+	// queued again if it is still in the future.
+	cs.mu.Lock()
+	delete(cs.futureBlocks, id)
+	cs.mu.Unlock()
+
+	_, err = cs.managedAcceptBlocks([]types.Block{b})
+	if err != nil {
+		cs.log.Debugln("WARN: failed to accept a future block:", err)
+		return
+	}
+	cs.managedBroadcastBlock(b)
+}
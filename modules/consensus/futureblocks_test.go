@@ -0,0 +1,66 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFutureBlockQueue checks that a future block is only queued once, and
+// that it is accepted and removed from the queue once its timestamp arrives.
+func TestFutureBlockQueue(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Timestamp = types.CurrentTimestamp() + 2 + types.FutureThreshold
+	solvedBlock, _ := cst.miner.SolveBlock(block, target)
+	for i := 0; i < 3; i++ {
+		if err := cst.cs.AcceptBlock(solvedBlock); err != errFutureTimestamp {
+			t.Fatalf("expected %v, got %v", errFutureTimestamp, err)
+		}
+	}
+	cst.cs.mu.RLock()
+	queued := len(cst.cs.futureBlocks)
+	cst.cs.mu.RUnlock()
+	if queued != 1 {
+		t.Fatal("expected 1 queued block, got", queued)
+	}
+
+	// Wait for the block to be accepted.
+	for i := 0; i < 50 && cst.cs.CurrentBlock().ID() != solvedBlock.ID(); i++ {
+		time.Sleep(200 * time.Millisecond)
+	}
+	if cst.cs.CurrentBlock().ID() != solvedBlock.ID() {
+		t.Fatal("future block was not accepted")
+	}
+	cst.cs.mu.RLock()
+	queued = len(cst.cs.futureBlocks)
+	cst.cs.mu.RUnlock()
+	if queued != 0 {
+		t.Fatal("accepted block is still queued")
+	}
+
+	// The queue should not grow beyond maxFutureBlocks.
+	cst.cs.mu.Lock()
+	for i := 0; i < maxFutureBlocks*2; i++ {
+		b := types.Block{Timestamp: types.CurrentTimestamp() + types.ExtremeFutureThreshold + types.Timestamp(i)}
+		cst.cs.queueFutureBlock(b, b.ID())
+	}
+	queued = len(cst.cs.futureBlocks)
+	cst.cs.mu.Unlock()
+	if queued != maxFutureBlocks {
+		t.Fatalf("expected %v queued blocks, got %v", maxFutureBlocks, queued)
+	}
+}
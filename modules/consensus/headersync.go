@@ -0,0 +1,500 @@
+package consensus
+
+// headersync.go implements header-first synchronization. During the initial
+// blockchain download, the consensus set first downloads the headers of the
+// missing blocks from every outbound peer that supports it, requesting more
+// headers until the peer has none left. Each chain of headers must link
+// together, and every header must meet the exact target that the difficulty
+// adjustment sets for it. The bodies of the chain with the most work are then
+// fetched from all of the peers that know about them by the download
+// scheduler in blockdownload.go.
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// headerSyncCapability is the gateway capability that is advertised by
+	// consensus sets that serve the SendHeaders and BlocksByID RPCs.
+	headerSyncCapability = "HeaderSync"
+)
+
+var (
+	errBlockNotRequested = errors.New("peer sent a block that was not requested")
+	errNoBlocksSent      = errors.New("peer did not send any of the requested blocks")
+	errTooManyBlockIDs   = errors.New("too many block ids were requested")
+
+	// maxSyncHeaders is the maximum number of headers that are sent in
+	// response to a single SendHeaders RPC.
+	maxSyncHeaders = build.Select(build.Var{
+		Standard: types.BlockHeight(5000),
+		Dev:      types.BlockHeight(500),
+		Testing:  types.BlockHeight(50),
+	}).(types.BlockHeight)
+
+	// sendHeadersTimeout is the timeout for the SendHeaders RPC.
+	sendHeadersTimeout = build.Select(build.Var{
+		Standard: 60 * time.Second,
+		Dev:      20 * time.Second,
+		Testing:  3 * time.Second,
+	}).(time.Duration)
+
+	// blocksByIDTimeout is the timeout for the BlocksByID RPC.
+	blocksByIDTimeout = build.Select(build.Var{
+		Standard: 120 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  4 * time.Second,
+	}).(time.Duration)
+)
+
+// headerChain is the chain of headers that a peer sent in response to the
+// SendHeaders RPC, after the headers that are already known were removed.
+// tip is the state of the last header, or of the known parent of the chain if
+// no headers have been received yet, and more is set if the peer has more
+// headers to send.
+type headerChain struct {
+	peer         modules.NetAddress
+	parentHeight types.BlockHeight
+	headers      []types.BlockHeader
+	tip          headerTip
+	more         bool
+}
+
+// headerTip is the state of a block that is needed to validate the headers of
+// its children. Before the Oak hardfork, timestamps holds the timestamps of
+// the block and of up to TargetWindow of its ancestors, oldest first.
+type headerTip struct {
+	id          types.BlockID
+	height      types.BlockHeight
+	timestamp   types.Timestamp
+	timestamps  []types.Timestamp
+	depth       types.Target
+	childTarget types.Target
+	totalTime   int64
+	totalTarget types.Target
+}
+
+// maxChildTarget returns the easiest target that the child of a block at the
+// given height can have, if the block had to meet the given target. The exact
+// target depends on the timestamps of the blocks, which are only checked once
+// the blocks are accepted, so the easiest target that the difficulty
+// adjustment allows is used instead.
+func maxChildTarget(target types.Target, height types.BlockHeight) types.Target {
+	if height > 0 && types.RuleOakDifficulty.Active(height-1) {
+		return target.MulDifficulty(types.OakMaxDrop)
+	} else if height%(types.TargetWindow/2) == 0 {
+		return types.RatToTarget(new(big.Rat).Mul(target.Rat(), types.MaxTargetAdjustmentUp))
+	}
+	return target
+}
+
+// knownHeaderTip returns the header tip of a block in the database.
+func (cs *ConsensusSet) knownHeaderTip(tx Tx, pb *processedBlock) (headerTip, error) {
+	totalTime, totalTarget := cs.getBlockTotals(tx, pb.Block.ID())
+	tip := headerTip{
+		id:          pb.Block.ID(),
+		height:      pb.Height,
+		timestamp:   pb.Block.Timestamp,
+		depth:       pb.Depth,
+		childTarget: pb.ChildTarget,
+		totalTime:   totalTime,
+		totalTarget: totalTarget,
+	}
+	if types.RuleOakDifficulty.Active(pb.Height) {
+		return tip, nil
+	}
+	for b := pb; ; {
+		tip.timestamps = append([]types.Timestamp{b.Block.Timestamp}, tip.timestamps...)
+		if b.Height == 0 || len(tip.timestamps) > int(types.TargetWindow) {
+			break
+		}
+		var err error
+		b, err = getBlockMap(tx, b.Block.ParentID)
+		if err != nil {
+			return headerTip{}, err
+		}
+	}
+	return tip, nil
+}
+
+// extendHeaderTip checks that h is a child of the block at tip and meets its
+// target, and returns the tip of h. The target of the child of h is computed
+// like newChild does, which only depends on the timestamps and targets of h
+// and its ancestors.
+func (cs *ConsensusSet) extendHeaderTip(tip headerTip, h types.BlockHeader) (headerTip, error) {
+	if h.ParentID != tip.id {
+		return headerTip{}, errNonLinearChain
+	} else if _, exists := cs.dosBlocks[h.ID()]; exists {
+		return headerTip{}, errDoSBlock
+	} else if !checkHeaderTarget(h, tip.childTarget) {
+		return headerTip{}, modules.ErrBlockUnsolved
+	}
+	child := headerTip{
+		id:        h.ID(),
+		height:    tip.height + 1,
+		timestamp: h.Timestamp,
+		depth:     tip.depth.AddDifficulties(tip.childTarget),
+	}
+	child.totalTime, child.totalTarget = blockTotals(child.height, tip.totalTime, tip.timestamp, h.Timestamp, tip.totalTarget, tip.childTarget)
+	if types.RuleOakDifficulty.Active(tip.height) {
+		child.childTarget = cs.childTargetOak(tip.totalTime, tip.totalTarget, tip.childTarget, tip.height, tip.timestamp)
+		return child, nil
+	}
+
+	// Before the Oak hardfork, the target is adjusted every TargetWindow/2
+	// blocks, see setChildTarget.
+	start := 0
+	if len(tip.timestamps) > int(types.TargetWindow) {
+		start = len(tip.timestamps) - int(types.TargetWindow)
+	}
+	child.timestamps = append(append([]types.Timestamp(nil), tip.timestamps[start:]...), h.Timestamp)
+	child.childTarget = tip.childTarget
+	if child.height%(types.TargetWindow/2) == 0 {
+		windowSize := types.BlockHeight(len(child.timestamps) - 1)
+		timePassed := h.Timestamp - child.timestamps[0]
+		base := big.NewRat(int64(timePassed), int64(types.BlockFrequency*windowSize))
+		child.childTarget = types.RatToTarget(new(big.Rat).Mul(tip.childTarget.Rat(), clampTargetAdjustment(base)))
+	}
+	return child, nil
+}
+
+// validateHeaderChain validates a batch of headers and appends them to chain.
+// If chain is still empty, the headers that are already known are removed
+// from the start of the batch, and the remaining headers must extend a known
+// block. Otherwise the batch must extend the last header of chain.
+func (cs *ConsensusSet) validateHeaderChain(tx Tx, chain *headerChain, headers []types.BlockHeader) error {
+	if len(chain.headers) == 0 {
+		for len(headers) > 0 {
+			if _, err := getBlockMap(tx, headers[0].ID()); err != nil {
+				break
+			}
+			headers = headers[1:]
+		}
+		if len(headers) == 0 {
+			return nil
+		}
+		parent, err := getBlockMap(tx, headers[0].ParentID)
+		if err != nil {
+			return errOrphan
+		}
+		chain.parentHeight = parent.Height
+		chain.tip, err = cs.knownHeaderTip(tx, parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	tip := chain.tip
+	for _, h := range headers {
+		var err error
+		tip, err = cs.extendHeaderTip(tip, h)
+		if err != nil {
+			return err
+		}
+	}
+	chain.headers = append(chain.headers, headers...)
+	chain.tip = tip
+	return nil
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. Like
+// SendBlocks, it reads 32 block IDs known to the caller, and sends the headers
+// of up to maxSyncHeaders blocks that follow the most recent one in the
+// current path, followed by a boolean indicating whether more are available.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var knownBlocks [32]types.BlockID
+	err = encoding.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+
+	headers := []types.BlockHeader{}
+	var moreAvailable bool
+	cs.mu.RLock()
+	err = cs.db.View(func(tx Tx) error {
+		start, found := historyStart(tx, knownBlocks)
+		if !found {
+			return nil
+		}
+		height := blockHeight(tx)
+		for i := start; i <= height && i < start+maxSyncHeaders; i++ {
+			id, err := getPath(tx, i)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			headers = append(headers, pb.Block.Header())
+		}
+		moreAvailable = start+maxSyncHeaders <= height
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := encoding.WriteObject(conn, headers); err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, moreAvailable)
+}
+
+// managedReceiveHeaders returns an RPCFunc that is the calling end of the
+// SendHeaders RPC. It requests the headers that follow the last header of
+// chain, or the current block if chain is empty, and appends them to chain
+// once they are validated.
+func (cs *ConsensusSet) managedReceiveHeaders(chain *headerChain) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+		if err != nil {
+			return err
+		}
+
+		var history [32]types.BlockID
+		cs.mu.RLock()
+		err = cs.db.View(func(tx Tx) error {
+			history = blockHistory(tx)
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if n := len(chain.headers); n > 0 {
+			// Put the last header first, keeping the genesis block as the
+			// last entry of the history.
+			copy(history[1:31], history[:30])
+			history[0] = chain.headers[n-1].ID()
+		}
+		if err := encoding.WriteObject(conn, history); err != nil {
+			return err
+		}
+
+		var headers []types.BlockHeader
+		if err := encoding.ReadObject(conn, &headers, 8+uint64(maxSyncHeaders)*types.BlockHeaderSize); err != nil {
+			return err
+		}
+		if err := encoding.ReadObject(conn, &chain.more, 1); err != nil {
+			return err
+		}
+
+		cs.mu.RLock()
+		err = cs.db.View(func(tx Tx) error {
+			return cs.validateHeaderChain(tx, chain, headers)
+		})
+		cs.mu.RUnlock()
+		return err
+	}
+}
+
+// rpcBlocksByID is the receiving end of the BlocksByID RPC. It reads
+// up to MaxCatchUpBlocks block IDs and sends the corresponding blocks, stopping
+// at the first block that is unknown.
+func (cs *ConsensusSet) rpcBlocksByID(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(blocksByIDTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var ids []types.BlockID
+	err = encoding.ReadObject(conn, &ids, 8+uint64(MaxCatchUpBlocks)*crypto.HashSize)
+	if err != nil {
+		return err
+	} else if types.BlockHeight(len(ids)) > MaxCatchUpBlocks {
+		return errTooManyBlockIDs
+	}
+
+	blocks := []types.Block{}
+	cs.mu.RLock()
+	err = cs.db.View(func(tx Tx) error {
+		for _, id := range ids {
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				break
+			}
+			blocks = append(blocks, pb.Block)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, blocks)
+}
+
+// managedReceiveBlocksByID returns an RPCFunc that is the calling end of the
+// BlocksByID RPC. It checks that the peer sent the requested blocks in
+// order, and stores them in blocks.
+func managedReceiveBlocksByID(ids []types.BlockID, blocks *[]types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(blocksByIDTimeout))
+		if err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		var received []types.Block
+		if err := encoding.ReadObject(conn, &received, uint64(len(ids))*types.BlockSizeLimit); err != nil {
+			return err
+		}
+		if len(received) == 0 {
+			return errNoBlocksSent
+		} else if len(received) > len(ids) {
+			return errBlockNotRequested
+		}
+		for i, b := range received {
+			if b.ID() != ids[i] {
+				return errBlockNotRequested
+			}
+		}
+		*blocks = received
+		return nil
+	}
+}
+
+// managedSyncHeadersFirst downloads the blocks that the peers have and the
+// consensus set is missing, using header-first synchronization. The headers
+// are requested from every peer until it has sent all of them, and the blocks
+// of the chain with the most work are then downloaded. Peers that send invalid
+// headers or blocks are penalized, and peers that stall are dropped from the
+// download. Errors are only returned if the download could not be started,
+// and any blocks that could not be downloaded are left for the SendBlocks RPC.
+func (cs *ConsensusSet) managedSyncHeadersFirst(peers []modules.NetAddress) error {
+	// Download and validate the headers from every peer in parallel.
+	chains := make([]headerChain, len(peers))
+	var wg sync.WaitGroup
+	for i, addr := range peers {
+		wg.Add(1)
+		go func(chain *headerChain, addr modules.NetAddress) {
+			defer wg.Done()
+			chain.peer = addr
+			for {
+				n := len(chain.headers)
+				err := cs.gateway.RPC(addr, "SendHeaders", cs.managedReceiveHeaders(chain))
+				if err != nil {
+					// The headers that were validated before the error are
+					// kept, unless the peer sent an invalid header.
+					cs.log.Debugf("WARN: unable to get headers from %v: %v", addr, err)
+					if isInvalidBlockErr(err) {
+						chain.headers = nil
+						cs.gateway.PenalizePeer(addr, modules.PenaltyInvalidBlock)
+					}
+					return
+				}
+				// Stop if the peer has sent all of its headers, or if it
+				// claims to have more but did not send any.
+				if !chain.more || len(chain.headers) == n {
+					return
+				}
+				select {
+				case <-cs.tg.StopChan():
+					return
+				default:
+				}
+			}
+		}(&chains[i], addr)
+	}
+	wg.Wait()
+
+	// Pick the chain with the most work. Only blocks that are in the chain
+	// are downloaded, from the peers whose chains end somewhere in it.
+	var best headerChain
+	for _, chain := range chains {
+		if len(chain.headers) > 0 && (len(best.headers) == 0 || chain.tip.depth.Cmp(best.tip.depth) < 0) {
+			best = chain
+		}
+	}
+	if len(best.headers) == 0 {
+		return nil
+	}
+	cs.mu.Lock()
+	cs.notePeerHeight(best.tip.height)
+	cs.mu.Unlock()
+	var currentDepth types.Target
+	cs.mu.RLock()
+	err := cs.db.View(func(tx Tx) error {
+		currentDepth = currentProcessedBlock(tx).Depth
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	} else if best.tip.depth.Cmp(currentDepth) >= 0 {
+		// The chain does not have more work than the current path. The
+		// depth is inversed, a smaller target means more work.
+		return nil
+	}
+	ids := make([]types.BlockID, len(best.headers))
+	index := make(map[types.BlockID]int, len(best.headers))
+	for i, h := range best.headers {
+		ids[i] = h.ID()
+		index[ids[i]] = i
+	}
+	bd := newBlockDownload(cs, best.parentHeight+1, ids)
+	var sources []downloadSource
+	for _, chain := range chains {
+		if len(chain.headers) == 0 {
+			continue
+		}
+		last, exists := index[chain.headers[len(chain.headers)-1].ID()]
+		if !exists {
+			continue
+		}
+		// A peer can only serve the ranges that end within its chain.
+		n := (last + 1) / int(MaxCatchUpBlocks)
+		if last+1 == len(best.headers) {
+			n = len(bd.ranges)
+		}
+		if n > 0 {
+			sources = append(sources, downloadSource{chain.peer, n})
+		}
+	}
+	return bd.managedDownload(sources)
+}
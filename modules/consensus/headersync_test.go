@@ -0,0 +1,199 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mockGatewayNoRPCs is a mock implementation of modules.Gateway that does not
+// register RPCs, so that the consensus set only synchronizes when told to.
+type mockGatewayNoRPCs struct {
+	modules.Gateway
+}
+
+func (mockGatewayNoRPCs) RegisterRPC(string, modules.RPCFunc)         {}
+func (mockGatewayNoRPCs) UnregisterRPC(string)                        {}
+func (mockGatewayNoRPCs) RegisterConnectCall(string, modules.RPCFunc) {}
+func (mockGatewayNoRPCs) UnregisterConnectCall(string)                {}
+
+// TestValidateHeaderChain checks that chains of headers are only accepted if
+// they link together and carry enough work.
+func TestValidateHeaderChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	remote, err := createConsensusSetTester(t.Name() + "-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	local, err := blankConsensusSetTester(t.Name()+"-local", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+
+	// Mine past the Oak hardfork, so that both difficulty adjustments are
+	// checked.
+	for remote.cs.Height() < types.OakHardforkFixBlock+types.TargetWindow {
+		if _, err := remote.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var headers []types.BlockHeader
+	for h := types.BlockHeight(0); h <= remote.cs.Height(); h++ {
+		b, _ := remote.cs.BlockAtHeight(h)
+		headers = append(headers, b.Header())
+	}
+	validate := func(headers []types.BlockHeader) (chain headerChain, err error) {
+		local.cs.mu.RLock()
+		defer local.cs.mu.RUnlock()
+		err = local.cs.db.View(func(tx Tx) error {
+			return local.cs.validateHeaderChain(tx, &chain, headers)
+		})
+		return
+	}
+
+	// The known genesis header should be removed.
+	chain, err := validate(headers)
+	if err != nil {
+		t.Fatal(err)
+	} else if chain.parentHeight != 0 || len(chain.headers) != len(headers)-1 {
+		t.Fatal("wrong headers were validated:", chain.parentHeight, len(chain.headers))
+	}
+
+	// The targets and depth of the chain should be computed exactly like the
+	// remote consensus set computed them.
+	remotePB := remote.cs.CurrentBlock()
+	if chain.tip.id != remotePB.ID() || chain.tip.childTarget != remote.cs.CurrentTarget() {
+		t.Fatal("header chain does not have the same child target as the remote chain")
+	}
+	err = remote.cs.db.View(func(tx Tx) error {
+		if depth := currentProcessedBlock(tx).Depth; chain.tip.depth != depth {
+			return errors.New("header chain does not have the same depth as the remote chain")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A chain can be extended by a batch that follows its last header, but
+	// not by one that does not.
+	half := len(headers) / 2
+	chain, err = validate(headers[:half])
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.cs.mu.RLock()
+	err = local.cs.db.View(func(tx Tx) error {
+		if err := local.cs.validateHeaderChain(tx, &chain, headers[half+1:]); err != errNonLinearChain {
+			return errors.New("expected errNonLinearChain, got " + fmt.Sprint(err))
+		}
+		return local.cs.validateHeaderChain(tx, &chain, headers[half:])
+	})
+	local.cs.mu.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(chain.headers) != len(headers)-1 || chain.tip.id != remotePB.ID() {
+		t.Fatal("chain was not extended:", len(chain.headers))
+	}
+
+	// Headers that do not link together should be rejected.
+	broken := append([]types.BlockHeader(nil), headers...)
+	broken[3], broken[4] = broken[4], broken[3]
+	if _, err := validate(broken); err != errNonLinearChain {
+		t.Fatal("expected errNonLinearChain, got", err)
+	}
+
+	// Headers that do not meet the target should be rejected. The nonce of
+	// the last header is changed until its ID is far above the testing
+	// target.
+	unsolved := append([]types.BlockHeader(nil), headers...)
+	for unsolved[len(unsolved)-1].ID()[0] < 200 {
+		unsolved[len(unsolved)-1].Nonce[0]++
+	}
+	if _, err := validate(unsolved); err != modules.ErrBlockUnsolved {
+		t.Fatal("expected ErrBlockUnsolved, got", err)
+	}
+
+	// Headers without a known parent are orphans.
+	if _, err := validate(headers[2:]); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+}
+
+// TestHeaderFirstSync checks that a consensus set can download a blockchain
+// from several peers with header-first synchronization.
+func TestHeaderFirstSync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	remote1, err := createConsensusSetTester(t.Name() + "-remote1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote1.Close()
+	// The chain is longer than a single batch of headers.
+	for remote1.cs.Height() <= maxSyncHeaders+MaxCatchUpBlocks {
+		if _, err := remote1.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remote2, err := blankConsensusSetTester(t.Name()+"-remote2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote2.Close()
+	if err := remote2.gateway.Connect(remote1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if remote2.cs.CurrentBlock().ID() != remote1.cs.CurrentBlock().ID() {
+			return errors.New("remote2 did not sync with remote1")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a local consensus set that does not synchronize on its own.
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-local")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(mockGatewayNoRPCs{g}, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	peers := []modules.NetAddress{remote1.gateway.Address(), remote2.gateway.Address()}
+	for _, addr := range peers {
+		if err := g.Connect(addr); err != nil {
+			t.Fatal(err)
+		}
+		if !g.PeerCapable(addr, headerSyncCapability) {
+			t.Fatal("peer did not advertise header-first synchronization")
+		}
+	}
+
+	if err := cs.managedSyncHeadersFirst(peers); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != remote1.cs.CurrentBlock().ID() {
+		t.Fatalf("local did not sync: height %v, remote height %v", cs.Height(), remote1.cs.Height())
+	}
+}
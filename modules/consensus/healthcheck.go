@@ -0,0 +1,175 @@
+package consensus
+
+// healthcheck.go implements a scan of the consensus database that reports
+// violated invariants instead of panicking or flagging the database, so that
+// operators can check the health of a node without restarting it in debug
+// mode.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// consistencyCheck is a named check that is run by CheckConsistency.
+type consistencyCheck struct {
+	name  string
+	check func(Tx) error
+}
+
+// consistencyChecks are the checks run by CheckConsistency, in order.
+var consistencyChecks = []consistencyCheck{
+	{"blockmap", checkBlockMap},
+	{"path", checkCurrentPath},
+	{"siacoincount", checkSiacoinCount},
+	{"siafundcount", checkSiafundCount},
+	{"siafundpool", checkSiafundPool},
+	{"delayedoutputs", checkDSCOs},
+	{"filecontracts", checkFileContractExpirations},
+}
+
+// checkBlockMap checks that every block in the block map decodes, is stored
+// under its own id, and has a parent in the block map.
+func checkBlockMap(tx Tx) error {
+	blockMap := tx.Bucket(BlockMap)
+	return blockMap.ForEach(func(k, v []byte) error {
+		var pb processedBlock
+		err := encoding.Unmarshal(v, &pb)
+		if err != nil {
+			return fmt.Errorf("block %x could not be decoded: %v", k, err)
+		}
+		id := pb.Block.ID()
+		if !bytes.Equal(id[:], k) {
+			return fmt.Errorf("block %v is stored under the wrong id", id)
+		}
+		if pb.Height > 0 && blockMap.Get(pb.Block.ParentID[:]) == nil {
+			return fmt.Errorf("block %v has no parent in the block map", id)
+		}
+		return nil
+	})
+}
+
+// checkCurrentPath checks that the current path is a contiguous chain of
+// blocks from the genesis block to the current block.
+func checkCurrentPath(tx Tx) error {
+	height := blockHeight(tx)
+	if n := tx.Bucket(BlockPath).Len(); n != int(height)+1 {
+		return fmt.Errorf("current path has %v entries, expected %v", n, height+1)
+	}
+	var parentID types.BlockID
+	for i := types.BlockHeight(0); i <= height; i++ {
+		id, err := getPath(tx, i)
+		if err != nil {
+			return fmt.Errorf("current path is missing height %v", i)
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return fmt.Errorf("block %v at height %v is not in the block map", id, i)
+		}
+		if pb.Height != i {
+			return fmt.Errorf("block %v at height %v has height %v", id, i, pb.Height)
+		}
+		if i > 0 && pb.Block.ParentID != parentID {
+			return fmt.Errorf("block %v at height %v is not a child of the previous block", id, i)
+		}
+		parentID = id
+	}
+	if parentID != currentBlockID(tx) {
+		return errors.New("current path does not end at the current block")
+	}
+	return nil
+}
+
+// checkSiafundPool checks that the siafund pool can be decoded.
+func checkSiafundPool(tx Tx) error {
+	var pool types.Currency
+	err := encoding.Unmarshal(tx.Bucket(SiafundPool).Get(SiafundPool), &pool)
+	if err != nil {
+		return errors.New("siafund pool could not be decoded: " + err.Error())
+	}
+	return nil
+}
+
+// checkFileContractExpirations checks that every file contract has not yet
+// expired and has exactly one expiration entry, and that every expiration
+// entry belongs to a file contract.
+func checkFileContractExpirations(tx Tx) error {
+	height := blockHeight(tx)
+	fcBucket := tx.Bucket(FileContracts)
+	err := fcBucket.ForEach(func(k, v []byte) error {
+		var fc types.FileContract
+		err := encoding.Unmarshal(v, &fc)
+		if err != nil {
+			return fmt.Errorf("file contract %x could not be decoded: %v", k, err)
+		}
+		if fc.WindowEnd <= height {
+			return fmt.Errorf("file contract %x expired at height %v", k, fc.WindowEnd)
+		}
+		expirationBucket := tx.Bucket(append(prefixFCEX, encoding.Marshal(fc.WindowEnd)...))
+		if expirationBucket == nil || expirationBucket.Get(k) == nil {
+			return fmt.Errorf("file contract %x has no expiration entry", k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var expirations int
+	err = tx.ForEach(func(name []byte, b Bucket) error {
+		if !bytes.HasPrefix(name, prefixFCEX) {
+			return nil
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			expirations++
+			if fcBucket.Get(k) == nil {
+				return fmt.Errorf("expiration entry %x has no file contract", k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if n := fcBucket.Len(); n != expirations {
+		return fmt.Errorf("there are %v file contracts but %v expiration entries", n, expirations)
+	}
+	return nil
+}
+
+// CheckConsistency scans the consensus database and returns a report of every
+// invariant that is violated. Unlike the consistency checks that run while
+// blocks are processed, CheckConsistency never panics and does not modify the
+// database.
+func (cs *ConsensusSet) CheckConsistency() (report modules.ConsensusConsistencyReport, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return report, err
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	err = cs.db.View(func(tx Tx) error {
+		report.Height = blockHeight(tx)
+		var inconsistent bool
+		err := encoding.Unmarshal(tx.Bucket(Consistency).Get(Consistency), &inconsistent)
+		report.MarkedInconsistent = err != nil || inconsistent
+
+		for _, cc := range consistencyChecks {
+			report.ChecksRun = append(report.ChecksRun, cc.name)
+			if err := cc.check(tx); err != nil {
+				report.Issues = append(report.Issues, modules.ConsensusConsistencyIssue{
+					Check:       cc.name,
+					Description: err.Error(),
+				})
+			}
+		}
+		return nil
+	})
+	return report, err
+}
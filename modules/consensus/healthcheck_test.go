@@ -0,0 +1,82 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestCheckConsistency checks that CheckConsistency reports no issues for a
+// healthy database, and reports the checks that fail once the database has
+// been corrupted.
+func TestCheckConsistency(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	report, err := cst.cs.CheckConsistency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatal("healthy database has issues:", report.Issues)
+	}
+	if report.Height != cst.cs.Height() || report.MarkedInconsistent {
+		t.Fatal("report is incorrect:", report)
+	}
+	if len(report.ChecksRun) != len(consistencyChecks) {
+		t.Fatal("not every check was run:", report.ChecksRun)
+	}
+
+	// Remove a siacoin output and add an expiration entry for a file contract
+	// that does not exist. The output must have a value, or removing it would
+	// not change the siacoin count.
+	err = cst.cs.db.Update(func(tx Tx) error {
+		scoBucket := tx.Bucket(SiacoinOutputs)
+		var id []byte
+		c := scoBucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var sco types.SiacoinOutput
+			if err := encoding.Unmarshal(v, &sco); err != nil {
+				return err
+			}
+			if !sco.Value.IsZero() {
+				id = k
+				break
+			}
+		}
+		if id == nil {
+			return errors.New("no siacoin output with a nonzero value")
+		}
+		if err := scoBucket.Delete(id); err != nil {
+			return err
+		}
+		expirationBucketID := append(prefixFCEX, encoding.Marshal(blockHeight(tx)+10)...)
+		b, err := tx.CreateBucketIfNotExists(expirationBucketID)
+		if err != nil {
+			return err
+		}
+		return b.Put(make([]byte, len(types.FileContractID{})), []byte{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = cst.cs.CheckConsistency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 2 {
+		t.Fatal("expected 2 issues, got", report.Issues)
+	}
+	if report.Issues[0].Check != "siacoincount" || report.Issues[1].Check != "filecontracts" {
+		t.Fatal("wrong checks failed:", report.Issues)
+	}
+}
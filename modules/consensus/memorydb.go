@@ -0,0 +1,326 @@
+package consensus
+
+// memorydb.go implements a Database that keeps the entire consensus set in
+// memory. It is intended for tests and simulations, where the cost of writing
+// every block to disk is not worth paying and the consensus set does not need
+// to survive a restart.
+//
+// Updates are serialized, and every modification made within an Update is
+// recorded in an undo log so that it can be rolled back if the update fails.
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errMemoryBucketExists   = errors.New("bucket already exists")
+	errMemoryBucketNotFound = errors.New("bucket not found")
+	errMemoryDatabaseClosed = errors.New("database is closed")
+	errMemoryTxNotWritable  = errors.New("cannot modify the database within a read-only transaction")
+)
+
+type (
+	// memoryDatabase is a Database that is stored in memory.
+	memoryDatabase struct {
+		buckets map[string]*memoryBucket
+		closed  bool
+		mu      sync.RWMutex
+	}
+
+	// memoryBucket holds the key/value pairs of a bucket. The sorted keys are
+	// computed lazily, as most buckets are modified far more often than they
+	// are iterated over. They are only cached within an Update, since
+	// concurrent Views must not modify the bucket.
+	memoryBucket struct {
+		values map[string][]byte
+		keys   []string
+		sorted bool
+	}
+
+	// memoryTx is a transaction on a memoryDatabase.
+	memoryTx struct {
+		db       *memoryDatabase
+		undo     []memoryUndo
+		writable bool
+	}
+
+	// memoryUndo records how to revert a single modification.
+	memoryUndo struct {
+		// For modifications to a key.
+		bucket  *memoryBucket
+		key     string
+		value   []byte
+		existed bool
+
+		// For buckets that were created or deleted.
+		name          string
+		createdBucket bool
+		deletedBucket *memoryBucket
+	}
+
+	// memoryBucketHandle is a memoryBucket within a transaction.
+	memoryBucketHandle struct {
+		tx *memoryTx
+		b  *memoryBucket
+	}
+
+	// memoryCursor iterates over the keys that were in a bucket when the
+	// cursor was created.
+	memoryCursor struct {
+		b    *memoryBucket
+		keys []string
+		i    int
+	}
+)
+
+// newMemoryDatabase returns an empty memoryDatabase.
+func newMemoryDatabase() *memoryDatabase {
+	return &memoryDatabase{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+// NewMemory returns a new ConsensusSet that is stored entirely in memory. The
+// consensus set is lost when it is closed. The persist directory is only used
+// for the log.
+func NewMemory(gateway modules.Gateway, bootstrap bool, persistDir string) (*ConsensusSet, error) {
+	return NewWithDatabase(gateway, bootstrap, persistDir, newMemoryDatabase())
+}
+
+// View implements Database.
+func (db *memoryDatabase) View(fn func(Tx) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return errMemoryDatabaseClosed
+	}
+	return fn(&memoryTx{db: db})
+}
+
+// Update implements Database.
+func (db *memoryDatabase) Update(fn func(Tx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return errMemoryDatabaseClosed
+	}
+	tx := &memoryTx{db: db, writable: true}
+	err := fn(tx)
+	if err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+// Close implements Database.
+func (db *memoryDatabase) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.closed = true
+	db.buckets = nil
+	return nil
+}
+
+// rollback reverts every modification made within the transaction.
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		u := tx.undo[i]
+		switch {
+		case u.createdBucket:
+			delete(tx.db.buckets, u.name)
+		case u.deletedBucket != nil:
+			tx.db.buckets[u.name] = u.deletedBucket
+		case u.existed:
+			u.bucket.set(u.key, u.value)
+		default:
+			u.bucket.remove(u.key)
+		}
+	}
+	tx.undo = nil
+}
+
+// Bucket implements Tx.
+func (tx *memoryTx) Bucket(name []byte) Bucket {
+	b, exists := tx.db.buckets[string(name)]
+	if !exists {
+		return nil
+	}
+	return memoryBucketHandle{tx: tx, b: b}
+}
+
+// CreateBucket implements Tx.
+func (tx *memoryTx) CreateBucket(name []byte) (Bucket, error) {
+	if !tx.writable {
+		return nil, errMemoryTxNotWritable
+	}
+	if _, exists := tx.db.buckets[string(name)]; exists {
+		return nil, errMemoryBucketExists
+	}
+	b := &memoryBucket{values: make(map[string][]byte)}
+	tx.db.buckets[string(name)] = b
+	tx.undo = append(tx.undo, memoryUndo{name: string(name), createdBucket: true})
+	return memoryBucketHandle{tx: tx, b: b}, nil
+}
+
+// CreateBucketIfNotExists implements Tx.
+func (tx *memoryTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	if b := tx.Bucket(name); b != nil {
+		if !tx.writable {
+			return nil, errMemoryTxNotWritable
+		}
+		return b, nil
+	}
+	return tx.CreateBucket(name)
+}
+
+// DeleteBucket implements Tx.
+func (tx *memoryTx) DeleteBucket(name []byte) error {
+	if !tx.writable {
+		return errMemoryTxNotWritable
+	}
+	b, exists := tx.db.buckets[string(name)]
+	if !exists {
+		return errMemoryBucketNotFound
+	}
+	delete(tx.db.buckets, string(name))
+	tx.undo = append(tx.undo, memoryUndo{name: string(name), deletedBucket: b})
+	return nil
+}
+
+// ForEach implements Tx.
+func (tx *memoryTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	names := make([]string, 0, len(tx.db.buckets))
+	for name := range tx.db.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, exists := tx.db.buckets[name]
+		if !exists {
+			// The bucket was deleted by fn.
+			continue
+		}
+		if err := fn([]byte(name), memoryBucketHandle{tx: tx, b: b}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set sets the value of a key without recording an undo.
+func (b *memoryBucket) set(key string, value []byte) {
+	if _, exists := b.values[key]; !exists {
+		b.sorted = false
+	}
+	b.values[key] = value
+}
+
+// remove deletes a key without recording an undo.
+func (b *memoryBucket) remove(key string) {
+	if _, exists := b.values[key]; exists {
+		b.sorted = false
+		delete(b.values, key)
+	}
+}
+
+// sortedKeys returns a copy of the keys of the bucket in byte order. If cache
+// is true, the sorted keys are cached in the bucket, which is only safe while
+// the database is locked for writing.
+func (b *memoryBucket) sortedKeys(cache bool) []string {
+	if b.sorted {
+		return append([]string(nil), b.keys...)
+	}
+	keys := make([]string, 0, len(b.values))
+	for k := range b.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if cache {
+		b.keys = append(b.keys[:0], keys...)
+		b.sorted = true
+	}
+	return keys
+}
+
+// Get implements Bucket.
+func (h memoryBucketHandle) Get(key []byte) []byte {
+	return h.b.values[string(key)]
+}
+
+// Put implements Bucket.
+func (h memoryBucketHandle) Put(key, value []byte) error {
+	if !h.tx.writable {
+		return errMemoryTxNotWritable
+	}
+	old, existed := h.b.values[string(key)]
+	h.tx.undo = append(h.tx.undo, memoryUndo{bucket: h.b, key: string(key), value: old, existed: existed})
+	// Unlike the key, the value slice is retained by the bucket, so it must
+	// be copied.
+	h.b.set(string(key), append(make([]byte, 0, len(value)), value...))
+	return nil
+}
+
+// Delete implements Bucket.
+func (h memoryBucketHandle) Delete(key []byte) error {
+	if !h.tx.writable {
+		return errMemoryTxNotWritable
+	}
+	old, existed := h.b.values[string(key)]
+	if !existed {
+		return nil
+	}
+	h.tx.undo = append(h.tx.undo, memoryUndo{bucket: h.b, key: string(key), value: old, existed: true})
+	h.b.remove(string(key))
+	return nil
+}
+
+// ForEach implements Bucket. Keys that are added by fn are not visited.
+func (h memoryBucketHandle) ForEach(fn func(k, v []byte) error) error {
+	c := h.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cursor implements Bucket.
+func (h memoryBucketHandle) Cursor() Cursor {
+	return &memoryCursor{
+		b:    h.b,
+		keys: h.b.sortedKeys(h.tx.writable),
+	}
+}
+
+// Len implements Bucket.
+func (h memoryBucketHandle) Len() int {
+	return len(h.b.values)
+}
+
+// First implements Cursor.
+func (c *memoryCursor) First() (key, value []byte) {
+	c.i = 0
+	return c.current()
+}
+
+// Next implements Cursor.
+func (c *memoryCursor) Next() (key, value []byte) {
+	c.i++
+	return c.current()
+}
+
+// current returns the key/value pair at the cursor's position, skipping keys
+// that have been deleted since the cursor was created.
+func (c *memoryCursor) current() (key, value []byte) {
+	for ; c.i < len(c.keys); c.i++ {
+		if v, exists := c.b.values[c.keys[c.i]]; exists {
+			return []byte(c.keys[c.i]), v
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,196 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMemoryDatabase probes the ordering and rollback behavior of the
+// memoryDatabase.
+func TestMemoryDatabase(t *testing.T) {
+	db := newMemoryDatabase()
+	err := db.Update(func(tx Tx) error {
+		b, err := tx.CreateBucket([]byte("b"))
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"c", "a", "b"} {
+			if err := b.Put([]byte(k), []byte(k)); err != nil {
+				return err
+			}
+		}
+		_, err = tx.CreateBucket([]byte("a"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Buckets and keys should be iterated in byte order.
+	var names, keys []byte
+	err = db.View(func(tx Tx) error {
+		err := tx.ForEach(func(name []byte, _ Bucket) error {
+			names = append(names, name...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("b")).ForEach(func(k, _ []byte) error {
+			keys = append(keys, k...)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(names) != "ab" || string(keys) != "abc" {
+		t.Fatal("iteration is not ordered:", string(names), string(keys))
+	}
+
+	// A failed update should be rolled back entirely.
+	errFail := errors.New("fail")
+	err = db.Update(func(tx Tx) error {
+		b := tx.Bucket([]byte("b"))
+		b.Put([]byte("a"), []byte("changed"))
+		b.Put([]byte("d"), []byte("d"))
+		b.Delete([]byte("c"))
+		tx.DeleteBucket([]byte("a"))
+		tx.CreateBucket([]byte("e"))
+		return errFail
+	})
+	if err != errFail {
+		t.Fatal("expected errFail, got", err)
+	}
+	err = db.View(func(tx Tx) error {
+		b := tx.Bucket([]byte("b"))
+		if !bytes.Equal(b.Get([]byte("a")), []byte("a")) || b.Get([]byte("d")) != nil || b.Get([]byte("c")) == nil || b.Len() != 3 {
+			return errors.New("keys were not rolled back")
+		}
+		if tx.Bucket([]byte("a")) == nil || tx.Bucket([]byte("e")) != nil {
+			return errors.New("buckets were not rolled back")
+		}
+		// Read-only transactions cannot modify the database.
+		if b.Put([]byte("a"), nil) != errMemoryTxNotWritable {
+			return errors.New("read-only transaction was modified")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMemoryDatabaseConcurrentViews checks that concurrent Views can iterate
+// over a bucket whose keys changed since it was last iterated over. It is
+// only meaningful with the race detector enabled.
+func TestMemoryDatabaseConcurrentViews(t *testing.T) {
+	db := newMemoryDatabase()
+	err := db.Update(func(tx Tx) error {
+		b, err := tx.CreateBucket([]byte("b"))
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"c", "a", "b"} {
+			if err := b.Put([]byte(k), []byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.View(func(tx Tx) error {
+				var keys []byte
+				err := tx.Bucket([]byte("b")).ForEach(func(k, _ []byte) error {
+					keys = append(keys, k...)
+					return nil
+				})
+				if err == nil && string(keys) != "abc" {
+					err = errors.New("keys were visited out of order: " + string(keys))
+				}
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestNewMemory checks that an in-memory consensus set accepts the same
+// blocks and arrives at the same state as one stored on disk.
+func TestNewMemory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "memory")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewMemory(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	var blocks []types.Block
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		blocks = append(blocks, b)
+	}
+	if _, err := cs.managedAcceptBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("in-memory consensus set did not accept the blocks")
+	}
+	var diskChecksum, memoryChecksum [32]byte
+	cst.cs.db.View(func(tx Tx) error {
+		diskChecksum = consensusChecksum(tx)
+		return nil
+	})
+	cs.db.View(func(tx Tx) error {
+		memoryChecksum = consensusChecksum(tx)
+		return nil
+	})
+	if diskChecksum != memoryChecksum {
+		t.Fatal("in-memory consensus set has a different consensus checksum")
+	}
+	report, err := cs.CheckConsistency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatal("in-memory consensus set is inconsistent:", report.Issues)
+	}
+}
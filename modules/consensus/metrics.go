@@ -0,0 +1,77 @@
+package consensus
+
+// metrics.go implements the metrics sinks of the consensus set. A sink is
+// handed every measurement taken by the consensus set, which allows exporters
+// for monitoring systems to be built outside of the consensus package.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// recordMetric sends a measurement to every metrics sink. The consensus set
+// must be locked (read or write) when recordMetric is called.
+func (cs *ConsensusSet) recordMetric(metricType modules.ConsensusMetricType, height types.BlockHeight, count int, duration time.Duration) {
+	cs.recordPeerMetric(metricType, "", height, count, duration)
+}
+
+// recordPeerMetric sends a measurement that relates to a peer to every metrics
+// sink. The consensus set must be locked (read or write) when recordPeerMetric
+// is called.
+func (cs *ConsensusSet) recordPeerMetric(metricType modules.ConsensusMetricType, peer modules.NetAddress, height types.BlockHeight, count int, duration time.Duration) {
+	if len(cs.metricsSinks) == 0 {
+		return
+	}
+	m := modules.ConsensusMetric{
+		Type:     metricType,
+		Height:   height,
+		Count:    count,
+		Duration: duration,
+		Peer:     peer,
+	}
+	for _, sink := range cs.metricsSinks {
+		sink.RecordConsensusMetric(m)
+	}
+}
+
+// RegisterMetricsSink adds a sink that receives every measurement taken by the
+// consensus set after registering. Sinks are called while the consensus set
+// is locked.
+func (cs *ConsensusSet) RegisterMetricsSink(sink modules.ConsensusMetricsSink) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, s := range cs.metricsSinks {
+		if s == sink {
+			build.Critical("refusing to register metrics sink twice")
+			return
+		}
+	}
+	cs.metricsSinks = append(cs.metricsSinks, sink)
+}
+
+// UnregisterMetricsSink removes a metrics sink. If the sink is not found, no
+// action is taken.
+func (cs *ConsensusSet) UnregisterMetricsSink(sink modules.ConsensusMetricsSink) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for i := range cs.metricsSinks {
+		if cs.metricsSinks[i] == sink {
+			cs.metricsSinks[i] = nil
+			cs.metricsSinks = append(cs.metricsSinks[:i], cs.metricsSinks[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package consensus
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mockMetricsSink holds the measurements it receives, grouped by type.
+type mockMetricsSink struct {
+	metrics map[modules.ConsensusMetricType][]modules.ConsensusMetric
+	mu      sync.Mutex
+}
+
+// RecordConsensusMetric adds a measurement to the mock metrics sink.
+func (mms *mockMetricsSink) RecordConsensusMetric(m modules.ConsensusMetric) {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+	mms.metrics[m.Type] = append(mms.metrics[m.Type], m)
+}
+
+// TestMetricsSink checks that a metrics sink receives measurements for new
+// blocks and reorgs, and stops receiving them after it is unregistered.
+func TestMetricsSink(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	mms := &mockMetricsSink{metrics: make(map[modules.ConsensusMetricType][]modules.ConsensusMetric)}
+	cstMain.cs.RegisterMetricsSink(mms)
+
+	b, err := cstMain.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := mms.metrics[modules.ConsensusMetricBlockApplied]
+	if len(applied) != 1 || applied[0].Height != cstMain.cs.Height() || applied[0].Count != len(b.Transactions) {
+		t.Fatal("wrong block applied metrics:", applied)
+	}
+	validated := mms.metrics[modules.ConsensusMetricTransactionsValidated]
+	if len(validated) != 1 || validated[0].Height != cstMain.cs.Height() || validated[0].Count != len(b.Transactions) {
+		t.Fatal("wrong transactions validated metrics:", validated)
+	}
+	updates := mms.metrics[modules.ConsensusMetricDatabaseUpdate]
+	if len(updates) != 1 || updates[0].Height != cstMain.cs.Height()-1 || updates[0].Count != 1 {
+		t.Fatal("wrong database update metrics:", updates)
+	}
+	if len(mms.metrics[modules.ConsensusMetricReorg]) != 0 {
+		t.Fatal("reorg metric was recorded for a block that extends the current path")
+	}
+
+	// Reorg the main chain onto the longer alternate chain.
+	for cstAlt.cs.Height() <= cstMain.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldHeight := cstMain.cs.Height()
+	var blocks []types.Block
+	for h := types.BlockHeight(1); h <= cstAlt.cs.Height(); h++ {
+		b, _ := cstAlt.cs.BlockAtHeight(h)
+		blocks = append(blocks, b)
+	}
+	if _, err := cstMain.cs.managedAcceptBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	reorgs := mms.metrics[modules.ConsensusMetricReorg]
+	if len(reorgs) != 1 || reorgs[0].Count != int(oldHeight) || reorgs[0].Height != 0 {
+		t.Fatal("wrong reorg metrics:", reorgs)
+	}
+
+	// After unregistering, no more measurements should arrive.
+	cstMain.cs.UnregisterMetricsSink(mms)
+	numApplied := len(mms.metrics[modules.ConsensusMetricBlockApplied])
+	if _, err := cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mms.metrics[modules.ConsensusMetricBlockApplied]) != numApplied {
+		t.Fatal("metrics sink received a measurement after unregistering")
+	}
+}
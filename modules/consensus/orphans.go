@@ -0,0 +1,143 @@
+package consensus
+
+// orphans.go implements the orphan pool. Blocks that arrive before their
+// parent are held in the pool for a limited time instead of being thrown away.
+// When the parent of an orphan is added to the block tree, the orphan is taken
+// out of the pool and accepted as well, so that a block which is relayed out
+// of order does not need to be downloaded a second time.
+//
+// Orphans cannot be fully validated until their parent is known, so only
+// blocks that are within the size limit and whose ID meets a plausible target
+// are pooled, and the pool is bounded both by the number of blocks and by
+// their total size. Otherwise a peer could fill the pool with unsolved blocks
+// for free, evicting the legitimate orphans.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// maxOrphanBlocks is the maximum number of blocks held in the orphan pool.
+	// When the pool is full, the oldest orphan is evicted to make room.
+	maxOrphanBlocks = build.Select(build.Var{
+		Standard: 100,
+		Dev:      50,
+		Testing:  10,
+	}).(int)
+
+	// maxOrphanBytes is the maximum total size of the blocks held in the
+	// orphan pool. When the pool is full, the oldest orphans are evicted to
+	// make room.
+	maxOrphanBytes = build.Select(build.Var{
+		Standard: uint64(20e6),
+		Dev:      uint64(10e6),
+		Testing:  uint64(4e6),
+	}).(uint64)
+
+	// orphanBlockTimeout is the amount of time that a block is held in the
+	// orphan pool before it is discarded.
+	orphanBlockTimeout = build.Select(build.Var{
+		Standard: 20 * time.Minute,
+		Dev:      5 * time.Minute,
+		Testing:  30 * time.Second,
+	}).(time.Duration)
+)
+
+// orphanBlock is a block in the orphan pool.
+type orphanBlock struct {
+	block types.Block
+	added time.Time
+	size  uint64
+}
+
+// minOrphanTarget returns the easiest target that an orphan can plausibly
+// meet: the child target of the current block, relaxed by the largest drop in
+// difficulty that a single block can cause.
+func minOrphanTarget(tx Tx) types.Target {
+	return currentProcessedBlock(tx).ChildTarget.MulDifficulty(types.OakMaxDrop)
+}
+
+// removeOrphan removes a block from the orphan pool.
+func (cs *ConsensusSet) removeOrphan(id types.BlockID) {
+	cs.orphanBytes -= cs.orphans[id].size
+	delete(cs.orphans, id)
+}
+
+// pruneOrphans removes the expired blocks from the orphan pool.
+func (cs *ConsensusSet) pruneOrphans() {
+	for id, ob := range cs.orphans {
+		if time.Since(ob.added) > orphanBlockTimeout {
+			cs.removeOrphan(id)
+		}
+	}
+}
+
+// addOrphans adds blocks to the orphan pool, evicting the oldest orphans if
+// the pool is full. Blocks that exceed the size limit or whose ID does not
+// meet minTarget are not added.
+func (cs *ConsensusSet) addOrphans(blocks []types.Block, ids []types.BlockID, minTarget types.Target) {
+	cs.pruneOrphans()
+	for i := range blocks {
+		if _, exists := cs.orphans[ids[i]]; exists {
+			continue
+		}
+		if !checkTarget(blocks[i], ids[i], minTarget) {
+			continue
+		}
+		size := uint64(len(cs.marshaler.Marshal(blocks[i])))
+		if size > types.BlockSizeLimit || size > maxOrphanBytes {
+			continue
+		}
+		for len(cs.orphans) >= maxOrphanBlocks || cs.orphanBytes+size > maxOrphanBytes {
+			var oldestID types.BlockID
+			var oldest time.Time
+			for id, ob := range cs.orphans {
+				if oldest.IsZero() || ob.added.Before(oldest) {
+					oldestID, oldest = id, ob.added
+				}
+			}
+			cs.removeOrphan(oldestID)
+		}
+		cs.orphans[ids[i]] = orphanBlock{
+			block: blocks[i],
+			added: time.Now(),
+			size:  size,
+		}
+		cs.orphanBytes += size
+	}
+}
+
+// removeOrphanChildren removes the children of the provided blocks from the
+// orphan pool and returns them.
+func (cs *ConsensusSet) removeOrphanChildren(parents []types.BlockID) (children []types.Block) {
+	cs.pruneOrphans()
+	if len(cs.orphans) == 0 {
+		return nil
+	}
+	parentSet := make(map[types.BlockID]struct{}, len(parents))
+	for _, id := range parents {
+		parentSet[id] = struct{}{}
+	}
+	for id, ob := range cs.orphans {
+		if _, exists := parentSet[ob.block.ParentID]; exists {
+			children = append(children, ob.block)
+			cs.removeOrphan(id)
+		}
+	}
+	return children
+}
+
+// managedAcceptOrphans accepts blocks that were taken out of the orphan pool
+// after their parent was added to the block tree. Accepting an orphan will in
+// turn accept any of its own children that are in the pool.
+func (cs *ConsensusSet) managedAcceptOrphans(orphans []types.Block) {
+	for _, b := range orphans {
+		_, err := cs.managedAcceptBlocks([]types.Block{b})
+		if err != nil {
+			cs.log.Debugln("WARN: failed to accept an orphan block:", err)
+		}
+	}
+}
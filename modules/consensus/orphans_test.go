@@ -0,0 +1,140 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOrphanPool checks that blocks which arrive before their parent are held
+// in the orphan pool and accepted once the parent arrives.
+func TestOrphanPool(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "sub")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewMemory(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// Give the new consensus set all but the last three blocks.
+	var blocks []types.Block
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		blocks = append(blocks, b)
+	}
+	n := len(blocks)
+	if _, err := cs.managedAcceptBlocks(blocks[:n-3]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Submit the last two blocks out of order. Both should be held as
+	// orphans.
+	if err := cs.AcceptBlock(blocks[n-1]); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	if err := cs.AcceptBlock(blocks[n-2]); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	cs.mu.RLock()
+	numOrphans := len(cs.orphans)
+	cs.mu.RUnlock()
+	if numOrphans != 2 {
+		t.Fatal("expected 2 orphans, got", numOrphans)
+	}
+
+	// Submitting the missing parent should connect both orphans.
+	if err := cs.AcceptBlock(blocks[n-3]); err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("orphans were not accepted after their parent arrived")
+	}
+	cs.mu.RLock()
+	numOrphans = len(cs.orphans)
+	cs.mu.RUnlock()
+	if numOrphans != 0 {
+		t.Fatal("accepted orphans are still in the pool:", numOrphans)
+	}
+
+	// The pool should not grow beyond maxOrphanBlocks.
+	cs.mu.Lock()
+	for i := 0; i < maxOrphanBlocks*2; i++ {
+		b := types.Block{Timestamp: types.Timestamp(i)}
+		cs.addOrphans([]types.Block{b}, []types.BlockID{b.ID()}, types.RootDepth)
+	}
+	numOrphans = len(cs.orphans)
+	cs.mu.Unlock()
+	if numOrphans != maxOrphanBlocks {
+		t.Fatalf("expected %v orphans, got %v", maxOrphanBlocks, numOrphans)
+	}
+}
+
+// TestOrphanPoolLimits checks that implausible blocks are not added to the
+// orphan pool, and that the pool is bounded by the total size of its blocks.
+func TestOrphanPoolLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cs := cst.cs
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	// Blocks whose ID does not meet the minimum target are not pooled.
+	unsolved := types.Block{Timestamp: 1}
+	cs.addOrphans([]types.Block{unsolved}, []types.BlockID{unsolved.ID()}, types.Target{})
+	if len(cs.orphans) != 0 {
+		t.Fatal("an unsolved block was added to the orphan pool")
+	}
+
+	// Blocks beyond the size limit are not pooled.
+	large := types.Block{Transactions: []types.Transaction{{
+		ArbitraryData: [][]byte{make([]byte, types.BlockSizeLimit)},
+	}}}
+	cs.addOrphans([]types.Block{large}, []types.BlockID{large.ID()}, types.RootDepth)
+	if len(cs.orphans) != 0 {
+		t.Fatal("a block beyond the size limit was added to the orphan pool")
+	}
+
+	// The total size of the pool should not exceed maxOrphanBytes.
+	for i := 0; i < maxOrphanBlocks; i++ {
+		b := types.Block{
+			Timestamp: types.Timestamp(i),
+			Transactions: []types.Transaction{{
+				ArbitraryData: [][]byte{make([]byte, types.BlockSizeLimit/2)},
+			}},
+		}
+		cs.addOrphans([]types.Block{b}, []types.BlockID{b.ID()}, types.RootDepth)
+	}
+	var size uint64
+	for _, ob := range cs.orphans {
+		size += ob.size
+	}
+	if size != cs.orphanBytes || size > maxOrphanBytes || len(cs.orphans) == 0 || len(cs.orphans) == maxOrphanBlocks {
+		t.Fatalf("orphan pool holds %v blocks of %v bytes, expected at most %v bytes", len(cs.orphans), size, maxOrphanBytes)
+	}
+}
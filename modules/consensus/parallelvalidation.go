@@ -0,0 +1,136 @@
+package consensus
+
+// parallelvalidation.go verifies the transactions of a batch of blocks using
+// multiple goroutines before the batch is applied. Applying blocks has to
+// happen sequentially because transactions may depend on each other, but the
+// standalone checks - signatures in particular - only depend on the
+// transaction and the height of the block, so they can be done up front and
+// in parallel. Blocks that pass are recorded in cs.verifiedBlocks so that the
+// standalone checks are skipped when the blocks are applied. Blocks that fail
+// are not recorded, and are rejected by the usual sequential validation.
+//
+// Verifying signatures is expensive, so only blocks that pass the cheap checks
+// of validateHeaderAndBlock are verified up front: they must not be known DoS
+// blocks or already known, and they must meet the target of their parent.
+// Otherwise a peer could make the consensus set verify the signatures of
+// unsolved blocks for free.
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// verifyTxnJob is a transaction that needs to be checked by a verification
+// worker.
+type verifyTxnJob struct {
+	block  int
+	txn    *types.Transaction
+	height types.BlockHeight
+}
+
+// managedVerifyBlockTransactions runs the standalone checks on every
+// transaction of a contiguous chain of blocks in parallel, and returns the set
+// of blocks whose transactions all passed. If the parent of the first block is
+// unknown, no blocks are verified. Blocks are only verified up to the first
+// block that is a known DoS block or that does not meet its target.
+func (cs *ConsensusSet) managedVerifyBlockTransactions(blocks []types.Block, ids []types.BlockID) map[types.BlockID]struct{} {
+	verified := make(map[types.BlockID]struct{})
+	if len(blocks) == 0 {
+		return verified
+	}
+
+	// Transactions are checked against the height of the parent of the block
+	// that contains them, mirroring validTransaction. The target of a parent
+	// is only known if the parent is in the database; the other blocks of the
+	// batch must meet the easiest target that the difficulty adjustment
+	// allows.
+	var parentHeight types.BlockHeight
+	var numChecked int
+	known := make([]bool, len(blocks))
+	cs.mu.RLock()
+	err := cs.db.View(func(tx Tx) error {
+		parent, err := getBlockMap(tx, blocks[0].ParentID)
+		if err != nil {
+			return err
+		}
+		parentHeight = parent.Height
+		target := parent.ChildTarget
+		for i := range blocks {
+			if _, exists := cs.dosBlocks[ids[i]]; exists {
+				break
+			}
+			if pb, err := getBlockMap(tx, ids[i]); err == nil {
+				// Known blocks are skipped when the batch is applied, so
+				// they do not need to be verified.
+				known[i] = true
+				target = pb.ChildTarget
+			} else if checkTarget(blocks[i], ids[i], target) {
+				target = maxChildTarget(target, parentHeight+types.BlockHeight(i)+1)
+			} else {
+				break
+			}
+			numChecked = i + 1
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return verified
+	}
+	blocks = blocks[:numChecked]
+
+	// Spin up the workers.
+	start := time.Now()
+	var numTxns int
+	failed := make([]bool, len(blocks))
+	var failedMu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan verifyTxnJob)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := job.txn.StandaloneValid(job.height); err != nil {
+					failedMu.Lock()
+					failed[job.block] = true
+					failedMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range blocks {
+		if known[i] {
+			continue
+		}
+		numTxns += len(blocks[i].Transactions)
+		for j := range blocks[i].Transactions {
+			jobs <- verifyTxnJob{
+				block:  i,
+				txn:    &blocks[i].Transactions[j],
+				height: parentHeight + types.BlockHeight(i),
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	cs.mu.RLock()
+	cs.recordMetric(modules.ConsensusMetricTransactionsValidated, parentHeight+1, numTxns, time.Since(start))
+	cs.mu.RUnlock()
+
+	// A block is only verified if it and all of its ancestors in the batch
+	// passed; blocks after an invalid block will be rejected anyway.
+	for i := range blocks {
+		if known[i] {
+			continue
+		} else if failed[i] {
+			break
+		}
+		verified[ids[i]] = struct{}{}
+	}
+	return verified
+}
@@ -0,0 +1,119 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestVerifyBlockTransactions checks that the transactions of a batch of
+// blocks are verified in parallel, and that a block with a bad signature is
+// not marked as verified.
+func TestVerifyBlockTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Mine a signed transaction into cst, followed by an empty block.
+	_, err = cst.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Grab the blocks of cst, excluding the genesis block.
+	var blocks []types.Block
+	var ids []types.BlockID
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		blocks = append(blocks, b)
+		ids = append(ids, b.ID())
+	}
+	verified := cst2.cs.managedVerifyBlockTransactions(blocks, ids)
+	if len(verified) != len(blocks) {
+		t.Fatalf("expected %v verified blocks, got %v", len(blocks), len(verified))
+	}
+
+	// Corrupt a signature in the block containing the signed transaction. The
+	// block, and every block after it, should no longer be verified.
+	bad, signed := len(blocks)-2, -1
+	for i, txn := range blocks[bad].Transactions {
+		if len(txn.TransactionSignatures) > 0 {
+			signed = i
+		}
+	}
+	if signed == -1 {
+		t.Fatal("block does not contain a signed transaction")
+	}
+	badBlocks := append([]types.Block(nil), blocks...)
+	badBlocks[bad].Transactions = append([]types.Transaction(nil), blocks[bad].Transactions...)
+	txn := &badBlocks[bad].Transactions[signed]
+	txn.TransactionSignatures = append([]types.TransactionSignature(nil), txn.TransactionSignatures...)
+	txn.TransactionSignatures[0].Signature = append([]byte(nil), txn.TransactionSignatures[0].Signature...)
+	txn.TransactionSignatures[0].Signature[0]++
+	badIDs := append([]types.BlockID(nil), ids...)
+	badIDs[bad] = badBlocks[bad].ID()
+	verified = cst2.cs.managedVerifyBlockTransactions(badBlocks, badIDs)
+	if len(verified) != bad {
+		t.Fatalf("expected %v verified blocks, got %v", bad, len(verified))
+	}
+	if _, exists := verified[badIDs[bad]]; exists {
+		t.Fatal("block with a bad signature was verified")
+	}
+
+	// Blocks that do not meet their target, and known DoS blocks, should not
+	// be verified, and neither should the blocks after them.
+	unsolved := append([]types.Block(nil), blocks...)
+	unsolvedIDs := append([]types.BlockID(nil), ids...)
+	for checkTarget(unsolved[0], unsolvedIDs[0], types.RootTarget) {
+		unsolved[0].Nonce[0]++
+		unsolvedIDs[0] = unsolved[0].ID()
+	}
+	verified = cst2.cs.managedVerifyBlockTransactions(unsolved, unsolvedIDs)
+	if len(verified) != 0 {
+		t.Fatalf("expected 0 verified blocks after an unsolved block, got %v", len(verified))
+	}
+	cst2.cs.mu.Lock()
+	cst2.cs.dosBlocks[ids[0]] = struct{}{}
+	cst2.cs.mu.Unlock()
+	verified = cst2.cs.managedVerifyBlockTransactions(blocks, ids)
+	if len(verified) != 0 {
+		t.Fatalf("expected 0 verified blocks after a DoS block, got %v", len(verified))
+	}
+	cst2.cs.mu.Lock()
+	delete(cst2.cs.dosBlocks, ids[0])
+	cst2.cs.mu.Unlock()
+
+	// The valid blocks should be accepted by cst2 as a batch.
+	if _, err := cst2.cs.managedAcceptBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("batch of blocks was not accepted")
+	}
+	if cst2.cs.verifiedBlocks != nil {
+		t.Fatal("verified blocks were not cleared after the batch was accepted")
+	}
+
+	// Known blocks do not need to be verified again.
+	verified = cst2.cs.managedVerifyBlockTransactions(blocks, ids)
+	if len(verified) != 0 {
+		t.Fatalf("expected 0 verified blocks for known blocks, got %v", len(verified))
+	}
+}
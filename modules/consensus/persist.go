@@ -9,8 +9,6 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
-
-	"github.com/coreos/bbolt"
 )
 
 const (
@@ -24,13 +22,18 @@ const (
 // them to fill out the ConsensusSet.
 func (cs *ConsensusSet) loadDB() error {
 	// Open the database - a new bolt database will be created if none exists.
-	err := cs.openDB(filepath.Join(cs.persistDir, DatabaseFilename))
-	if err != nil {
-		return err
+	// The database may have already been provided when the consensus set was
+	// created.
+	var err error
+	if cs.db == nil {
+		err = cs.openDB(filepath.Join(cs.persistDir, DatabaseFilename))
+		if err != nil {
+			return err
+		}
 	}
 
 	// Walk through initialization for Sia.
-	return cs.db.Update(func(tx *bolt.Tx) error {
+	return cs.db.Update(func(tx Tx) error {
 		// Check if the database has been initialized.
 		err = cs.initDB(tx)
 		if err != nil {
@@ -47,6 +50,18 @@ func (cs *ConsensusSet) loadDB() error {
 			return err
 		}
 
+		// Older databases do not track the history of the siafund pool.
+		err = cs.initSiafundPoolHistory(tx)
+		if err != nil {
+			return err
+		}
+
+		// Older changelogs do not track their size.
+		err = cs.initChangeLogSize(tx)
+		if err != nil {
+			return err
+		}
+
 		// Check that the genesis block is correct - typically only incorrect
 		// in the event of developer binaries vs. release binaires.
 		genesisID, err := getPath(tx, 0)
@@ -83,13 +98,32 @@ func (cs *ConsensusSet) initPersist() error {
 		}
 	})
 
+	// If there is no existing database, try to bootstrap one from a snapshot.
+	// Snapshots can only be loaded into the default database.
+	if cs.db == nil {
+		err = cs.loadSnapshotFile()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Try to load an existing database from disk - a new one will be created
 	// if one does not exist.
 	err = cs.loadDB()
 	if err != nil {
 		return err
 	}
-	// Set up the closing of the database.
+	// Set up the closing of the audit log and the database.
+	cs.tg.AfterStop(func() {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		if cs.auditLog != nil {
+			err := cs.closeAuditLog()
+			if err != nil {
+				cs.log.Println("ERROR: Unable to close the audit log at shutdown:", err)
+			}
+		}
+	})
 	cs.tg.AfterStop(func() {
 		err := cs.db.Close()
 		if err != nil {
@@ -8,8 +8,6 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 // SurpassThreshold is a percentage that dictates how much heavier a competing
@@ -58,7 +56,7 @@ func (pb *processedBlock) childDepth() types.Target {
 
 // targetAdjustmentBase returns the magnitude that the target should be
 // adjusted by before a clamp is applied.
-func (cs *ConsensusSet) targetAdjustmentBase(blockMap *bolt.Bucket, pb *processedBlock) *big.Rat {
+func (cs *ConsensusSet) targetAdjustmentBase(blockMap Bucket, pb *processedBlock) *big.Rat {
 	// Grab the block that was generated 'TargetWindow' blocks prior to the
 	// parent. If there are not 'TargetWindow' blocks yet, stop at the genesis
 	// block.
@@ -101,7 +99,7 @@ func clampTargetAdjustment(base *big.Rat) *big.Rat {
 
 // setChildTarget computes the target of a blockNode's child. All children of a node
 // have the same target.
-func (cs *ConsensusSet) setChildTarget(blockMap *bolt.Bucket, pb *processedBlock) {
+func (cs *ConsensusSet) setChildTarget(blockMap Bucket, pb *processedBlock) {
 	// Fetch the parent block.
 	var parent processedBlock
 	parentBytes := blockMap.Get(pb.Block.ParentID[:])
@@ -121,7 +119,7 @@ func (cs *ConsensusSet) setChildTarget(blockMap *bolt.Bucket, pb *processedBlock
 
 // newChild creates a blockNode from a block and adds it to the parent's set of
 // children. The new node is also returned. It necessarily modifies the database
-func (cs *ConsensusSet) newChild(tx *bolt.Tx, pb *processedBlock, b types.Block) *processedBlock {
+func (cs *ConsensusSet) newChild(tx Tx, pb *processedBlock, b types.Block) *processedBlock {
 	// Create the child node.
 	childID := b.ID()
 	child := &processedBlock{
@@ -141,7 +139,7 @@ func (cs *ConsensusSet) newChild(tx *bolt.Tx, pb *processedBlock, b types.Block)
 	// Use the difficulty adjustment algorithm to set the target of the child
 	// block and put the new processed block into the database.
 	blockMap := tx.Bucket(BlockMap)
-	if pb.Height < types.OakHardforkBlock {
+	if !types.RuleOakDifficulty.Active(pb.Height) {
 		cs.setChildTarget(blockMap, child)
 	} else {
 		child.ChildTarget = cs.childTargetOak(prevTotalTime, prevTotalTarget, pb.ChildTarget, pb.Height, pb.Block.Timestamp)
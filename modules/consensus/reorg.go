@@ -0,0 +1,86 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// computeReorgEvent computes the reorg event for a change entry that reverted
+// at least one block.
+func (cs *ConsensusSet) computeReorgEvent(tx Tx, ce changeEntry) (modules.ReorgEvent, error) {
+	// The reverted blocks are listed starting from the old tip, and the first
+	// applied block is a child of the common ancestor.
+	firstApplied, err := getBlockMap(tx, ce.AppliedBlocks[0])
+	if err != nil {
+		return modules.ReorgEvent{}, err
+	}
+	return modules.ReorgEvent{
+		Depth:                uint64(len(ce.RevertedBlocks)),
+		CommonAncestor:       firstApplied.Block.ParentID,
+		CommonAncestorHeight: firstApplied.Height - 1,
+		OldTip:               ce.RevertedBlocks[0],
+		NewTip:               ce.AppliedBlocks[len(ce.AppliedBlocks)-1],
+	}, nil
+}
+
+// updateReorgSubscribers will inform all reorg subscribers of a reorg if the
+// change entry reverted any blocks.
+func (cs *ConsensusSet) updateReorgSubscribers(ce changeEntry) {
+	if len(ce.RevertedBlocks) == 0 {
+		return
+	}
+	var re modules.ReorgEvent
+	err := cs.db.View(func(tx Tx) error {
+		var err error
+		re, err = cs.computeReorgEvent(tx, ce)
+		return err
+	})
+	if err != nil {
+		cs.log.Critical("computeReorgEvent failed:", err)
+		return
+	}
+	cs.recordMetric(modules.ConsensusMetricReorg, re.CommonAncestorHeight, int(re.Depth), 0)
+	cs.log.Printf("Reorg of depth %v: %v -> %v, common ancestor %v at height %v", re.Depth, re.OldTip, re.NewTip, re.CommonAncestor, re.CommonAncestorHeight)
+	for _, subscriber := range cs.reorgSubscribers {
+		subscriber.ProcessReorgEvent(re)
+	}
+}
+
+// ReorgSubscribe adds a subscriber that is notified of every reorg that occurs
+// after subscribing. Like regular subscribers, reorg subscribers are called
+// while the consensus set is locked.
+func (cs *ConsensusSet) ReorgSubscribe(subscriber modules.ConsensusSetReorgSubscriber) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, s := range cs.reorgSubscribers {
+		if s == subscriber {
+			build.Critical("refusing to double-subscribe subscriber")
+			return
+		}
+	}
+	cs.reorgSubscribers = append(cs.reorgSubscribers, subscriber)
+}
+
+// ReorgUnsubscribe removes a reorg subscriber. If the subscriber is not found,
+// no action is taken.
+func (cs *ConsensusSet) ReorgUnsubscribe(subscriber modules.ConsensusSetReorgSubscriber) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for i := range cs.reorgSubscribers {
+		if cs.reorgSubscribers[i] == subscriber {
+			cs.reorgSubscribers[i] = nil
+			cs.reorgSubscribers = append(cs.reorgSubscribers[:i], cs.reorgSubscribers[i+1:]...)
+			break
+		}
+	}
+}
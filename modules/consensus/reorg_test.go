@@ -0,0 +1,90 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mockReorgSubscriber receives and holds reorg events.
+type mockReorgSubscriber struct {
+	events []modules.ReorgEvent
+}
+
+// ProcessReorgEvent adds a reorg event to the mock reorg subscriber.
+func (mrs *mockReorgSubscriber) ProcessReorgEvent(re modules.ReorgEvent) {
+	mrs.events = append(mrs.events, re)
+}
+
+// TestReorgEvents checks that reorg subscribers are notified of reorgs with
+// the correct depth, common ancestor and tips.
+func TestReorgEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	mrs := new(mockReorgSubscriber)
+	cstMain.cs.ReorgSubscribe(mrs)
+
+	// Extending the chain should not produce a reorg event.
+	if _, err := cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mrs.events) != 0 {
+		t.Fatal("reorg event was sent for a block that extends the current path")
+	}
+
+	// Make the alternate chain longer, and then give its blocks to the main
+	// chain. The chains only share the genesis block.
+	for cstAlt.cs.Height() <= cstMain.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldTip := cstMain.cs.CurrentBlock().ID()
+	oldHeight := cstMain.cs.Height()
+	for h := types.BlockHeight(1); h <= cstAlt.cs.Height(); h++ {
+		b, _ := cstAlt.cs.BlockAtHeight(h)
+		err := cstMain.cs.AcceptBlock(b)
+		if err != nil && err != modules.ErrNonExtendingBlock {
+			t.Fatal(err)
+		}
+	}
+	if cstMain.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("main chain did not reorg to the alternate chain")
+	}
+	if len(mrs.events) != 1 {
+		t.Fatal("expected 1 reorg event, got", len(mrs.events))
+	}
+	re := mrs.events[0]
+	if re.Depth != uint64(oldHeight) {
+		t.Errorf("reorg depth is %v, expected %v", re.Depth, oldHeight)
+	}
+	if re.CommonAncestor != types.GenesisID || re.CommonAncestorHeight != 0 {
+		t.Error("wrong common ancestor:", re.CommonAncestor, re.CommonAncestorHeight)
+	}
+	if re.OldTip != oldTip {
+		t.Error("wrong old tip")
+	}
+	if re.NewTip != cstMain.cs.CurrentBlock().ID() {
+		t.Error("wrong new tip")
+	}
+
+	// After unsubscribing, no more events should be received.
+	cstMain.cs.ReorgUnsubscribe(mrs)
+	if len(cstMain.cs.reorgSubscribers) != 0 {
+		t.Fatal("reorg subscriber was not removed")
+	}
+}
@@ -0,0 +1,104 @@
+package consensus
+
+// siafundpool.go tracks the value of the siafund pool at every height of the
+// current path. The history is needed to compute the claims earned by a
+// siafund over an arbitrary interval, as the siafund pool bucket only holds
+// the current value.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errFutureSiafundPoolHeight = errors.New("requested siafund pool height is above the current height")
+)
+
+// getSiafundPoolHistory returns the value of the siafund pool after the block
+// at the provided height was applied.
+func getSiafundPoolHistory(tx Tx, height types.BlockHeight) (pool types.Currency, err error) {
+	poolBytes := tx.Bucket(SiafundPoolHistory).Get(encoding.Marshal(height))
+	if poolBytes == nil {
+		return types.Currency{}, errNilItem
+	}
+	err = encoding.Unmarshal(poolBytes, &pool)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return pool, err
+}
+
+// setSiafundPoolHistory records the value of the siafund pool after the block
+// at the provided height was applied.
+func setSiafundPoolHistory(tx Tx, height types.BlockHeight, pool types.Currency) {
+	err := tx.Bucket(SiafundPoolHistory).Put(encoding.Marshal(height), encoding.Marshal(pool))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// deleteSiafundPoolHistory removes the value of the siafund pool at the
+// provided height, which happens when the block at that height is reverted.
+func deleteSiafundPoolHistory(tx Tx, height types.BlockHeight) {
+	err := tx.Bucket(SiafundPoolHistory).Delete(encoding.Marshal(height))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// initSiafundPoolHistory creates the siafund pool history for databases that
+// were created before the history was tracked. The history is rebuilt from the
+// siafund pool diffs of the blocks in the current path.
+func (cs *ConsensusSet) initSiafundPoolHistory(tx Tx) error {
+	if tx.Bucket(SiafundPoolHistory) != nil {
+		return nil
+	}
+	_, err := tx.CreateBucket(SiafundPoolHistory)
+	if err != nil {
+		return err
+	}
+
+	pool := types.NewCurrency64(0)
+	height := blockHeight(tx)
+	for h := types.BlockHeight(0); h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		for _, sfpd := range pb.SiafundPoolDiffs {
+			if sfpd.Direction == modules.DiffApply {
+				pool = sfpd.Adjusted
+			}
+		}
+		setSiafundPoolHistory(tx, h, pool)
+	}
+	return nil
+}
+
+// SiafundPoolAt returns the value of the siafund pool after the block at the
+// provided height in the current path was applied.
+func (cs *ConsensusSet) SiafundPoolAt(height types.BlockHeight) (pool types.Currency, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Currency{}, err
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	err = cs.db.View(func(tx Tx) error {
+		if height > blockHeight(tx) {
+			return errFutureSiafundPoolHeight
+		}
+		pool, err = getSiafundPoolHistory(tx, height)
+		return err
+	})
+	return pool, err
+}
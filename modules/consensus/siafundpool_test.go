@@ -0,0 +1,117 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSiafundPoolAt checks that the siafund pool history tracks the value of
+// the pool at every height, and that it can be rebuilt for older databases.
+func TestSiafundPoolAt(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Mine a block containing a file contract, which adds to the siafund
+	// pool.
+	oldPool := cst.cs.dbGetSiafundPool()
+	payout := types.NewCurrency64(400e6)
+	fc := types.FileContract{
+		WindowStart:        cst.cs.dbBlockHeight() + 2,
+		WindowEnd:          cst.cs.dbBlockHeight() + 4,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: types.PostTax(cst.cs.dbBlockHeight(), payout)}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: types.PostTax(cst.cs.dbBlockHeight(), payout)}},
+	}
+	txnBuilder, err := cst.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txnBuilder.FundSiacoins(payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txnBuilder.AddFileContract(fc)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := cst.cs.Height()
+	newPool := cst.cs.dbGetSiafundPool()
+	if newPool.Cmp(oldPool) <= 0 {
+		t.Fatal("siafund pool did not grow")
+	}
+
+	// The history should hold the old value before the block and the new
+	// value after it.
+	pool, err := cst.cs.SiafundPoolAt(height - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.Equals(oldPool) {
+		t.Fatalf("expected %v before the contract, got %v", oldPool, pool)
+	}
+	pool, err = cst.cs.SiafundPoolAt(height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.Equals(newPool) {
+		t.Fatalf("expected %v after the contract, got %v", newPool, pool)
+	}
+	pool, err = cst.cs.SiafundPoolAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.IsZero() {
+		t.Fatal("siafund pool of the genesis block should be zero, got", pool)
+	}
+	if _, err := cst.cs.SiafundPoolAt(height + 1); err != errFutureSiafundPoolHeight {
+		t.Fatal("expected errFutureSiafundPoolHeight, got", err)
+	}
+
+	// Drop the history and rebuild it, as would happen for a database created
+	// before the history was tracked. The rebuilt history should match.
+	var expected []types.Currency
+	for h := types.BlockHeight(0); h <= height; h++ {
+		pool, err := cst.cs.SiafundPoolAt(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, pool)
+	}
+	cst.cs.mu.Lock()
+	err = cst.cs.db.Update(func(tx Tx) error {
+		if err := tx.DeleteBucket(SiafundPoolHistory); err != nil {
+			return err
+		}
+		return cst.cs.initSiafundPoolHistory(tx)
+	})
+	cst.cs.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for h := range expected {
+		pool, err := cst.cs.SiafundPoolAt(types.BlockHeight(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pool.Equals(expected[h]) {
+			t.Fatalf("rebuilt history at height %v is %v, expected %v", h, pool, expected[h])
+		}
+	}
+}
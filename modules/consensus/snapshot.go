@@ -0,0 +1,126 @@
+package consensus
+
+// snapshot.go lets operators seed a new node from a snapshot file or URL.
+// A snapshot is only loaded if it matches a hardcoded checkpoint, or if the
+// operator pinned its hash, so that neither the server of the snapshot nor
+// anyone on the network path can feed the node a consensus database of their
+// choosing. If a snapshot file is present in the persist directory when the
+// consensus set is created without an existing database, the snapshot is used
+// as the database if it matches a checkpoint.
+//
+// No checkpoints are shipped yet, so pinning the hash of the snapshot (siad's
+// --consensus-snapshot-hash) is currently the only supported way to bootstrap
+// from a snapshot.
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// SnapshotFilename is the name of the snapshot file that is loaded from
+	// the persist directory when there is no consensus database.
+	SnapshotFilename = modules.ConsensusDir + ".snapshot"
+
+	// maxSnapshotRedirects is the number of redirects that are followed when
+	// downloading a snapshot.
+	maxSnapshotRedirects = 10
+)
+
+var (
+	// snapshotDownloadTimeout is the timeout for downloading a snapshot,
+	// which includes reading the entire consensus database.
+	snapshotDownloadTimeout = build.Select(build.Var{
+		Standard: 4 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  30 * time.Second,
+	}).(time.Duration)
+
+	errInsecureSnapshotURL = errors.New("snapshots can only be downloaded over plain http if their hash is pinned")
+	errSnapshotRedirects   = errors.New("too many redirects while downloading snapshot")
+)
+
+// FetchSnapshot bootstraps the consensus database in persistDir from the
+// snapshot at src, which can be a local path or an http(s) URL. If
+// snapshotHash is not empty, the snapshot must have that hash; otherwise it
+// must match a hardcoded checkpoint. Snapshots are only downloaded over plain
+// http if their hash is pinned. Nothing is fetched if a consensus database
+// already exists.
+func FetchSnapshot(src string, persistDir string, snapshotHash crypto.Hash) error {
+	if _, err := os.Stat(filepath.Join(persistDir, DatabaseFilename)); !os.IsNotExist(err) {
+		return nil
+	}
+	pinned := snapshotHash != (crypto.Hash{})
+
+	var r io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		if !pinned && !strings.HasPrefix(src, "https://") {
+			return errInsecureSnapshotURL
+		}
+		client := &http.Client{
+			Timeout: snapshotDownloadTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxSnapshotRedirects {
+					return errSnapshotRedirects
+				}
+				if !pinned && req.URL.Scheme != "https" {
+					return errInsecureSnapshotURL
+				}
+				return nil
+			},
+		}
+		resp, err := client.Get(src)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return errors.New("unable to download snapshot: " + resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	err := loadSnapshot(r, persistDir, checkpoints, snapshotHash)
+	if err != nil {
+		return errors.New("unable to load consensus snapshot: " + err.Error())
+	}
+	return nil
+}
+
+// loadSnapshotFile bootstraps the consensus database from the snapshot file in
+// the persist directory, if there is one and there is no existing database.
+func (cs *ConsensusSet) loadSnapshotFile() error {
+	if _, err := os.Stat(filepath.Join(cs.persistDir, DatabaseFilename)); !os.IsNotExist(err) {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(cs.persistDir, SnapshotFilename))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cs.log.Println("Bootstrapping the consensus database from", f.Name())
+	err = loadSnapshot(f, cs.persistDir, checkpoints, crypto.Hash{})
+	if err != nil {
+		return errors.New("unable to load consensus snapshot: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package consensus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+)
+
+// exportTestSnapshot exports a snapshot of the consensus set of cst and
+// returns it along with its hash.
+func exportTestSnapshot(cst *consensusSetTester) ([]byte, crypto.Hash, error) {
+	var buf bytes.Buffer
+	var snapshotHash crypto.Hash
+	err := build.Retry(100, 50*time.Millisecond, func() error {
+		buf.Reset()
+		var err error
+		snapshotHash, err = cst.cs.ExportSnapshot(&buf)
+		return err
+	})
+	return buf.Bytes(), snapshotHash, err
+}
+
+// TestFetchSnapshot checks that a consensus set can be bootstrapped from a
+// snapshot that was fetched over http, but only if its hash is pinned.
+func TestFetchSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	snapshot, snapshotHash, err := exportTestSnapshot(cst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(snapshot)
+	}))
+	defer server.Close()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "bootstrap")
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+
+	// Without a pinned hash, the snapshot cannot be downloaded over plain
+	// http.
+	if err := FetchSnapshot(server.URL, csDir, crypto.Hash{}); err != errInsecureSnapshotURL {
+		t.Fatal("expected errInsecureSnapshotURL, got", err)
+	}
+
+	// A snapshot that does not match the pinned hash should be rejected.
+	err = FetchSnapshot(server.URL, csDir, crypto.Hash{1})
+	if err == nil || !strings.Contains(err.Error(), errSnapshotHashMismatch.Error()) {
+		t.Fatal("expected errSnapshotHashMismatch, got", err)
+	}
+	if _, err := os.Stat(filepath.Join(csDir, DatabaseFilename)); !os.IsNotExist(err) {
+		t.Fatal("snapshot was loaded even though it does not match the pinned hash")
+	}
+
+	// Fetch the snapshot and create a consensus set from it.
+	if err := FetchSnapshot(server.URL, csDir, snapshotHash); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(g, false, csDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if cs.Height() != cst.cs.Height() || cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("bootstrapped consensus set does not match the original")
+	}
+
+	// Fetching again should do nothing now that a database exists.
+	if err := FetchSnapshot(server.URL, csDir, crypto.Hash{1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSnapshotFileNoCheckpoint checks that a snapshot file in the persist
+// directory is not loaded if it does not match a checkpoint.
+func TestSnapshotFileNoCheckpoint(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	snapshot, _, err := exportTestSnapshot(cst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testdir := build.TempDir(modules.ConsensusDir, t.Name(), "bootstrap")
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	if err := os.MkdirAll(csDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(csDir, SnapshotFilename), snapshot, 0600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(g, false, csDir)
+	if err == nil {
+		cs.Close()
+		t.Fatal("consensus set was bootstrapped from a snapshot without a checkpoint")
+	} else if !strings.Contains(err.Error(), errSnapshotNoCheckpoint.Error()) {
+		t.Fatal("expected errSnapshotNoCheckpoint, got", err)
+	}
+}
+
+// TestPinnedSnapshotCheckpointConflict checks that a snapshot with a pinned
+// hash is rejected if it contradicts a checkpoint.
+func TestPinnedSnapshotCheckpointConflict(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	snapshot, snapshotHash, err := exportTestSnapshot(cst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := checkpoint{Height: cst.cs.Height()}
+	csDir := build.TempDir(modules.ConsensusDir, t.Name(), "bootstrap")
+	err = loadSnapshot(bytes.NewReader(snapshot), csDir, []checkpoint{cp}, snapshotHash)
+	if err != errSnapshotConflict {
+		t.Fatal("expected errSnapshotConflict, got", err)
+	}
+}
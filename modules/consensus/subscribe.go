@@ -6,14 +6,28 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 
 	siasync "github.com/NebulousLabs/Sia/sync"
-	"github.com/coreos/bbolt"
 )
 
-// computeConsensusChange computes the consensus change from the change entry
-// at index 'i' in the change log. If i is out of bounds, an error is returned.
-func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (modules.ConsensusChange, error) {
+var (
+	// subscribeBatchSize is the number of consensus changes that are sent to
+	// a subscriber within a single database transaction while it catches up.
+	// The consensus set lock is released between batches so that a new
+	// module's initial scan does not starve other consensus operations.
+	subscribeBatchSize = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  10,
+	}).(int)
+)
+
+// computeConsensusChange computes the consensus change from the provided
+// change entry. The ID of the consensus change is the ID of the change entry,
+// which subscribers can use to resume their subscription at a later time. If a
+// filter is provided, only the diffs selected by the filter are collected.
+func (cs *ConsensusSet) computeConsensusChange(tx Tx, ce changeEntry, filter *modules.ConsensusChangeFilter) (modules.ConsensusChange, error) {
 	cc := modules.ConsensusChange{
 		ID: ce.ID(),
 	}
@@ -27,30 +41,49 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		// Because the direction is 'revert', the order of the diffs needs to
 		// be flipped and the direction of the diffs also needs to be flipped.
 		cc.RevertedBlocks = append(cc.RevertedBlocks, revertedBlock.Block)
-		for i := len(revertedBlock.SiacoinOutputDiffs) - 1; i >= 0; i-- {
-			scod := revertedBlock.SiacoinOutputDiffs[i]
-			scod.Direction = !scod.Direction
-			cc.SiacoinOutputDiffs = append(cc.SiacoinOutputDiffs, scod)
+		if filterSiacoinOutputDiffs(filter) {
+			for i := len(revertedBlock.SiacoinOutputDiffs) - 1; i >= 0; i-- {
+				scod := revertedBlock.SiacoinOutputDiffs[i]
+				if !filterUnlockHash(filter, scod.SiacoinOutput.UnlockHash) {
+					continue
+				}
+				scod.Direction = !scod.Direction
+				cc.SiacoinOutputDiffs = append(cc.SiacoinOutputDiffs, scod)
+			}
 		}
-		for i := len(revertedBlock.FileContractDiffs) - 1; i >= 0; i-- {
-			fcd := revertedBlock.FileContractDiffs[i]
-			fcd.Direction = !fcd.Direction
-			cc.FileContractDiffs = append(cc.FileContractDiffs, fcd)
+		if filterFileContractDiffs(filter) {
+			for i := len(revertedBlock.FileContractDiffs) - 1; i >= 0; i-- {
+				fcd := revertedBlock.FileContractDiffs[i]
+				fcd.Direction = !fcd.Direction
+				cc.FileContractDiffs = append(cc.FileContractDiffs, fcd)
+			}
 		}
-		for i := len(revertedBlock.SiafundOutputDiffs) - 1; i >= 0; i-- {
-			sfod := revertedBlock.SiafundOutputDiffs[i]
-			sfod.Direction = !sfod.Direction
-			cc.SiafundOutputDiffs = append(cc.SiafundOutputDiffs, sfod)
+		if filterSiafundOutputDiffs(filter) {
+			for i := len(revertedBlock.SiafundOutputDiffs) - 1; i >= 0; i-- {
+				sfod := revertedBlock.SiafundOutputDiffs[i]
+				if !filterUnlockHash(filter, sfod.SiafundOutput.UnlockHash) {
+					continue
+				}
+				sfod.Direction = !sfod.Direction
+				cc.SiafundOutputDiffs = append(cc.SiafundOutputDiffs, sfod)
+			}
 		}
-		for i := len(revertedBlock.DelayedSiacoinOutputDiffs) - 1; i >= 0; i-- {
-			dscod := revertedBlock.DelayedSiacoinOutputDiffs[i]
-			dscod.Direction = !dscod.Direction
-			cc.DelayedSiacoinOutputDiffs = append(cc.DelayedSiacoinOutputDiffs, dscod)
+		if filterDelayedSiacoinOutputDiffs(filter) {
+			for i := len(revertedBlock.DelayedSiacoinOutputDiffs) - 1; i >= 0; i-- {
+				dscod := revertedBlock.DelayedSiacoinOutputDiffs[i]
+				if !filterUnlockHash(filter, dscod.SiacoinOutput.UnlockHash) {
+					continue
+				}
+				dscod.Direction = !dscod.Direction
+				cc.DelayedSiacoinOutputDiffs = append(cc.DelayedSiacoinOutputDiffs, dscod)
+			}
 		}
-		for i := len(revertedBlock.SiafundPoolDiffs) - 1; i >= 0; i-- {
-			sfpd := revertedBlock.SiafundPoolDiffs[i]
-			sfpd.Direction = modules.DiffRevert
-			cc.SiafundPoolDiffs = append(cc.SiafundPoolDiffs, sfpd)
+		if filterSiafundPoolDiffs(filter) {
+			for i := len(revertedBlock.SiafundPoolDiffs) - 1; i >= 0; i-- {
+				sfpd := revertedBlock.SiafundPoolDiffs[i]
+				sfpd.Direction = modules.DiffRevert
+				cc.SiafundPoolDiffs = append(cc.SiafundPoolDiffs, sfpd)
+			}
 		}
 	}
 	for _, appliedBlockID := range ce.AppliedBlocks {
@@ -61,20 +94,32 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		}
 
 		cc.AppliedBlocks = append(cc.AppliedBlocks, appliedBlock.Block)
-		for _, scod := range appliedBlock.SiacoinOutputDiffs {
-			cc.SiacoinOutputDiffs = append(cc.SiacoinOutputDiffs, scod)
+		if filterSiacoinOutputDiffs(filter) {
+			for _, scod := range appliedBlock.SiacoinOutputDiffs {
+				if filterUnlockHash(filter, scod.SiacoinOutput.UnlockHash) {
+					cc.SiacoinOutputDiffs = append(cc.SiacoinOutputDiffs, scod)
+				}
+			}
 		}
-		for _, fcd := range appliedBlock.FileContractDiffs {
-			cc.FileContractDiffs = append(cc.FileContractDiffs, fcd)
+		if filterFileContractDiffs(filter) {
+			cc.FileContractDiffs = append(cc.FileContractDiffs, appliedBlock.FileContractDiffs...)
 		}
-		for _, sfod := range appliedBlock.SiafundOutputDiffs {
-			cc.SiafundOutputDiffs = append(cc.SiafundOutputDiffs, sfod)
+		if filterSiafundOutputDiffs(filter) {
+			for _, sfod := range appliedBlock.SiafundOutputDiffs {
+				if filterUnlockHash(filter, sfod.SiafundOutput.UnlockHash) {
+					cc.SiafundOutputDiffs = append(cc.SiafundOutputDiffs, sfod)
+				}
+			}
 		}
-		for _, dscod := range appliedBlock.DelayedSiacoinOutputDiffs {
-			cc.DelayedSiacoinOutputDiffs = append(cc.DelayedSiacoinOutputDiffs, dscod)
+		if filterDelayedSiacoinOutputDiffs(filter) {
+			for _, dscod := range appliedBlock.DelayedSiacoinOutputDiffs {
+				if filterUnlockHash(filter, dscod.SiacoinOutput.UnlockHash) {
+					cc.DelayedSiacoinOutputDiffs = append(cc.DelayedSiacoinOutputDiffs, dscod)
+				}
+			}
 		}
-		for _, sfpd := range appliedBlock.SiafundPoolDiffs {
-			cc.SiafundPoolDiffs = append(cc.SiafundPoolDiffs, sfpd)
+		if filterSiafundPoolDiffs(filter) {
+			cc.SiafundPoolDiffs = append(cc.SiafundPoolDiffs, appliedBlock.SiafundPoolDiffs...)
 		}
 	}
 
@@ -107,10 +152,10 @@ func (cs *ConsensusSet) updateSubscribers(ce changeEntry) {
 	}
 	// Get the consensus change and send it to all subscribers.
 	var cc modules.ConsensusChange
-	err := cs.db.View(func(tx *bolt.Tx) error {
+	err := cs.db.View(func(tx Tx) error {
 		// Compute the consensus change so it can be sent to subscribers.
 		var err error
-		cc, err = cs.computeConsensusChange(tx, ce)
+		cc, err = cs.computeConsensusChange(tx, ce, nil)
 		return err
 	})
 	if err != nil {
@@ -138,11 +183,15 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 
 	// 'exists' and 'entry' are going to be pointed to the first entry that
 	// has not yet been seen by subscriber.
-	var exists bool
+	var exists, pruned bool
 	var entry changeEntry
 	cs.mu.RLock()
-	err := cs.db.View(func(tx *bolt.Tx) error {
-		if start == modules.ConsensusChangeBeginning {
+	err := cs.db.View(func(tx Tx) error {
+		if start == modules.ConsensusChangeBeginning && changeLogPruned(tx) {
+			// The genesis entry is no longer in the changelog, the subscriber
+			// will need to be initialized from the current path instead.
+			pruned = true
+		} else if start == modules.ConsensusChangeBeginning {
 			// Special case: for modules.ConsensusChangeBeginning, create an
 			// initial node pointing to the genesis block. The subscriber will
 			// receive the diffs for all blocks in the consensus set, including
@@ -155,7 +204,12 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 			// 'entry' and 'exists' need to be pointed at the next consensus
 			// change.
 			entry, exists = getEntry(tx, start)
-			if !exists {
+			if !exists && changeLogPruned(tx) {
+				// The id may refer to an entry that has been pruned from
+				// the changelog, which the subscriber can recover from by
+				// rescanning.
+				return modules.ErrConsensusChangePruned
+			} else if !exists {
 				// modules.ErrInvalidConsensusChangeID is a named error that
 				// signals a break in synchronization between the consensus set
 				// persistence and the subscriber persistence. Typically,
@@ -171,6 +225,9 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 	if err != nil {
 		return modules.ConsensusChangeID{}, err
 	}
+	if pruned {
+		return cs.managedInitializeSubscribeFromPath(subscriber, cancel)
+	}
 
 	// Nothing to do if the changeEntry doesn't exist.
 	if !exists {
@@ -180,22 +237,26 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 	// Send all remaining consensus changes to the subscriber.
 	latestChangeID := entry.ID()
 	for exists {
-		// Send changes in batches of 100 so that we don't hold the
-		// lock for too long.
+		// Send changes in batches so that we don't hold the lock for too
+		// long.
 		cs.mu.RLock()
-		err = cs.db.View(func(tx *bolt.Tx) error {
-			for i := 0; i < 100 && exists; i++ {
+		err = cs.db.View(func(tx Tx) error {
+			// The changelog may have been pruned while the lock was released
+			// between batches.
+			if _, stillExists := getEntry(tx, entry.ID()); !stillExists {
+				return modules.ErrConsensusChangePruned
+			}
+			for i := 0; i < subscribeBatchSize && exists; i++ {
 				latestChangeID = entry.ID()
 				select {
 				case <-cancel:
 					return siasync.ErrStopped
 				default:
 				}
-				cc, err := cs.computeConsensusChange(tx, entry)
+				err := cs.processChangeEntry(tx, subscriber, entry, entry.ID())
 				if err != nil {
 					return err
 				}
-				subscriber.ProcessConsensusChange(cc)
 				entry, exists = entry.NextEntry(tx)
 			}
 			return nil
@@ -204,14 +265,132 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 		if err != nil {
 			return modules.ConsensusChangeID{}, err
 		}
+
+		// Asynchronous subscribers queue every change they are sent. Wait for
+		// the queue to drain between batches so that the entire history is
+		// not held in memory.
+		if as, ok := subscriber.(*asyncSubscriber); ok {
+			as.managedWaitForSpace(cs.tg.StopChan())
+		}
 	}
 	return latestChangeID, nil
 }
 
+// managedInitializeSubscribeFromPath sends the subscriber one consensus change
+// for every block from the genesis block up to the last block applied by the
+// head of the changelog, and returns the id of the head so that the
+// subscriber can continue from the changelog afterwards. It is used in place
+// of the changelog when subscribing from modules.ConsensusChangeBeginning
+// after the genesis entry has been pruned.
+//
+// The changes that precede the head are no longer in the changelog, so they
+// carry modules.ConsensusChangeBeginning as their id, and a subscriber that is
+// interrupted before it receives the head starts over from the beginning.
+// The ancestors of a block never change, so the lock is released between
+// batches like in managedInitializeSubscribe.
+func (cs *ConsensusSet) managedInitializeSubscribeFromPath(subscriber modules.ConsensusSetSubscriber, cancel <-chan struct{}) (modules.ConsensusChangeID, error) {
+	var headID modules.ConsensusChangeID
+	var tip types.BlockID
+	var tipHeight types.BlockHeight
+	cs.mu.RLock()
+	err := cs.db.View(func(tx Tx) error {
+		headID = cs.changeLogHeadID(tx)
+		head, exists := getEntry(tx, headID)
+		if !exists {
+			return modules.ErrConsensusChangePruned
+		}
+		tip = head.AppliedBlocks[len(head.AppliedBlocks)-1]
+		pb, err := getBlockMap(tx, tip)
+		if err != nil {
+			return err
+		}
+		tipHeight = pb.Height
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return modules.ConsensusChangeID{}, err
+	}
+
+	for height := types.BlockHeight(0); height <= tipHeight; {
+		cs.mu.RLock()
+		err = cs.db.View(func(tx Tx) error {
+			end := height + types.BlockHeight(subscribeBatchSize)
+			if end > tipHeight+1 {
+				end = tipHeight + 1
+			}
+			ids, err := chainSegment(tx, tip, height, end)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				select {
+				case <-cancel:
+					return siasync.ErrStopped
+				default:
+				}
+				ccID := modules.ConsensusChangeBeginning
+				if id == tip {
+					ccID = headID
+				}
+				err := cs.processChangeEntry(tx, subscriber, changeEntry{AppliedBlocks: []types.BlockID{id}}, ccID)
+				if err != nil {
+					return err
+				}
+				height++
+			}
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return modules.ConsensusChangeID{}, err
+		}
+		if as, ok := subscriber.(*asyncSubscriber); ok {
+			as.managedWaitForSpace(cs.tg.StopChan())
+		}
+	}
+	return headID, nil
+}
+
+// chainSegment returns the ids of the blocks at heights [start, end) on the
+// chain that ends in tip. Only the blocks of that chain that are not on the
+// current path are read from the block map.
+func chainSegment(tx Tx, tip types.BlockID, start, end types.BlockHeight) ([]types.BlockID, error) {
+	pb, err := getBlockMap(tx, tip)
+	if err != nil {
+		return nil, err
+	}
+	offPath := make(map[types.BlockHeight]types.BlockID)
+	for {
+		if id, err := getPath(tx, pb.Height); err == nil && id == pb.Block.ID() {
+			break
+		}
+		offPath[pb.Height] = pb.Block.ID()
+		pb, err = getBlockMap(tx, pb.Block.ParentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]types.BlockID, 0, end-start)
+	for h := start; h < end; h++ {
+		if id, ok := offPath[h]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		id, err := getPath(tx, h)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 // recentConsensusChangeID gets the ConsensusChangeID of the most recent
 // change.
 func (cs *ConsensusSet) recentConsensusChangeID() (cid modules.ConsensusChangeID, err error) {
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx Tx) error {
 		cl := tx.Bucket(ChangeLog)
 		d := cl.Get(ChangeLogTailID)
 		if d == nil {
@@ -295,6 +474,19 @@ func (cs *ConsensusSet) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
+	// If the subscriber was added through ConsensusSetSubscribeAsync, the
+	// subscriber in the list of subscribers is the wrapping asyncSubscriber.
+	if as, exists := cs.asyncSubscribers[subscriber]; exists {
+		delete(cs.asyncSubscribers, subscriber)
+		as.close()
+		subscriber = as
+	} else if fs, exists := cs.filteredSubscribers[subscriber]; exists {
+		// Likewise for subscribers added through
+		// ConsensusSetSubscribeFiltered.
+		delete(cs.filteredSubscribers, subscriber)
+		subscriber = fs
+	}
+
 	// Search for the subscriber in the list of subscribers and remove it if
 	// found.
 	for i := range cs.subscribers {
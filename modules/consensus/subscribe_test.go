@@ -3,9 +3,10 @@ package consensus
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
-	bolt "github.com/coreos/bbolt"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // mockSubscriber receives and holds changes to the consensus set, remembering
@@ -199,11 +200,11 @@ func TestModuleDesync(t *testing.T) {
 	// Get all the updates from the consensusSet.
 	updates := make([]modules.ConsensusChange, 0)
 	cst.cs.mu.Lock()
-	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+	err = cst.cs.db.View(func(tx Tx) error {
 		entry := cst.cs.genesisEntry()
 		exists := true
 		for ; exists; entry, exists = entry.NextEntry(tx) {
-			cc, err := cst.cs.computeConsensusChange(tx, entry)
+			cc, err := cst.cs.computeConsensusChange(tx, entry, nil)
 			if err != nil {
 				return err
 			}
@@ -237,3 +238,76 @@ func TestModuleDesync(t *testing.T) {
 		t.Fatal("last update doesn't equal recentChangeID")
 	}
 }
+
+// batchCheckSubscriber is a subscriber that, at the end of the first batch of
+// its initial scan, starts a thread that acquires the consensus set lock and
+// records how many updates had been received at that point.
+type batchCheckSubscriber struct {
+	cs         *ConsensusSet
+	updates    int
+	lockedAt   int
+	lockedChan chan struct{}
+	mu         sync.Mutex
+}
+
+// ProcessConsensusChange counts the update, and starts the locking thread at
+// the end of the first batch.
+func (bcs *batchCheckSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	bcs.mu.Lock()
+	bcs.updates++
+	updates := bcs.updates
+	bcs.mu.Unlock()
+	if updates != subscribeBatchSize {
+		return
+	}
+	go func() {
+		bcs.cs.mu.Lock()
+		bcs.mu.Lock()
+		bcs.lockedAt = bcs.updates
+		bcs.mu.Unlock()
+		bcs.cs.mu.Unlock()
+		close(bcs.lockedChan)
+	}()
+	// Give the thread time to block on the lock, so that it is waiting before
+	// the next batch attempts to acquire the lock.
+	time.Sleep(100 * time.Millisecond)
+}
+
+// TestInitializeSubscribeBatches checks that the consensus set lock is released
+// between batches while a subscriber is catching up.
+func TestInitializeSubscribeBatches(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Make sure that the scan will need more than two batches.
+	for cst.cs.Height() < types.BlockHeight(2*subscribeBatchSize) {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bcs := &batchCheckSubscriber{
+		cs:         cst.cs,
+		lockedChan: make(chan struct{}),
+	}
+	err = cst.cs.ConsensusSetSubscribe(bcs, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-bcs.lockedChan
+	bcs.mu.Lock()
+	defer bcs.mu.Unlock()
+	if bcs.lockedAt != subscribeBatchSize {
+		t.Fatalf("lock was acquired after %v updates, expected %v", bcs.lockedAt, subscribeBatchSize)
+	}
+	if bcs.updates != int(cst.cs.Height())+1 {
+		t.Fatal("subscriber did not receive every update:", bcs.updates)
+	}
+}
@@ -0,0 +1,208 @@
+package consensus
+
+// subscribeasync.go implements asynchronous delivery of consensus changes.
+// Regular subscribers are called synchronously while the consensus set holds
+// its lock, which means that one slow subscriber stalls block processing for
+// the whole node. Asynchronous subscribers instead have their consensus changes
+// placed into a queue that is drained by a dedicated goroutine, allowing each
+// subscriber to consume changes at its own pace.
+//
+// The queue is never allowed to block while the consensus set is holding its
+// lock, because an asynchronous subscriber is free to call exported methods on
+// the consensus set from within ProcessConsensusChange. Instead, backpressure
+// is applied after the lock has been released: if any queue has grown beyond
+// asyncSubscriberQueueSize, the thread that accepted the blocks waits for the
+// queue to drain before returning.
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// asyncSubscriberQueueSize is the number of consensus changes that can be
+	// queued for an asynchronous subscriber before the consensus set starts
+	// applying backpressure.
+	asyncSubscriberQueueSize = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  10,
+	}).(int)
+)
+
+// asyncSubscriber wraps a subscriber, queueing consensus changes so that they
+// can be delivered from a separate goroutine.
+type asyncSubscriber struct {
+	subscriber        modules.ConsensusSetSubscriber
+	tryTransactionSet func([]types.Transaction) (modules.ConsensusChange, error)
+
+	// queue contains the consensus changes that have not yet been delivered
+	// to the subscriber. wakeChan is signaled whenever a change is added to
+	// the queue, and spaceChan is signaled whenever a change is removed.
+	queue     []modules.ConsensusChange
+	wakeChan  chan struct{}
+	spaceChan chan struct{}
+
+	closeChan chan struct{}
+	closed    bool
+	mu        sync.Mutex
+}
+
+// newAsyncSubscriber returns an asyncSubscriber that delivers changes to the
+// provided subscriber.
+func (cs *ConsensusSet) newAsyncSubscriber(subscriber modules.ConsensusSetSubscriber) *asyncSubscriber {
+	return &asyncSubscriber{
+		subscriber:        subscriber,
+		tryTransactionSet: cs.TryTransactionSet,
+
+		wakeChan:  make(chan struct{}, 1),
+		spaceChan: make(chan struct{}, 1),
+		closeChan: make(chan struct{}),
+	}
+}
+
+// ProcessConsensusChange adds a consensus change to the queue of the
+// asyncSubscriber. ProcessConsensusChange never blocks.
+func (as *asyncSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	// The unlocked TryTransactionSet is only safe to call while the consensus
+	// set is holding its lock, which will not be the case by the time the
+	// change is delivered. Replace it with the locked version.
+	cc.TryTransactionSet = as.tryTransactionSet
+
+	as.mu.Lock()
+	if as.closed {
+		as.mu.Unlock()
+		return
+	}
+	as.queue = append(as.queue, cc)
+	as.mu.Unlock()
+
+	select {
+	case as.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the delivery of consensus changes, dropping any changes that
+// are still in the queue.
+func (as *asyncSubscriber) close() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.closed {
+		return
+	}
+	as.closed = true
+	as.queue = nil
+	close(as.closeChan)
+}
+
+// queueLen returns the number of consensus changes that are waiting to be
+// delivered to the subscriber.
+func (as *asyncSubscriber) queueLen() int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return len(as.queue)
+}
+
+// threadedDispatch delivers queued consensus changes to the subscriber in
+// order until the asyncSubscriber is closed or the consensus set is stopped.
+func (as *asyncSubscriber) threadedDispatch(stop <-chan struct{}) {
+	for {
+		as.mu.Lock()
+		if len(as.queue) == 0 {
+			as.mu.Unlock()
+			select {
+			case <-as.wakeChan:
+				continue
+			case <-as.closeChan:
+				return
+			case <-stop:
+				return
+			}
+		}
+		cc := as.queue[0]
+		as.queue[0] = modules.ConsensusChange{}
+		as.queue = as.queue[1:]
+		as.mu.Unlock()
+
+		select {
+		case as.spaceChan <- struct{}{}:
+		default:
+		}
+		as.subscriber.ProcessConsensusChange(cc)
+	}
+}
+
+// managedWaitForSpace blocks until the queue of the asyncSubscriber has fewer
+// than asyncSubscriberQueueSize elements, the asyncSubscriber is closed, or
+// the stop channel is closed.
+func (as *asyncSubscriber) managedWaitForSpace(stop <-chan struct{}) {
+	for as.queueLen() >= asyncSubscriberQueueSize {
+		select {
+		case <-as.spaceChan:
+		case <-as.closeChan:
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// managedAsyncBackpressure blocks until every asynchronous subscriber has
+// room in its queue. It must not be called while holding the consensus set
+// lock.
+func (cs *ConsensusSet) managedAsyncBackpressure() {
+	cs.mu.RLock()
+	subscribers := make([]*asyncSubscriber, 0, len(cs.asyncSubscribers))
+	for _, as := range cs.asyncSubscribers {
+		subscribers = append(subscribers, as)
+	}
+	cs.mu.RUnlock()
+
+	for _, as := range subscribers {
+		as.managedWaitForSpace(cs.tg.StopChan())
+	}
+}
+
+// ConsensusSetSubscribeAsync adds a subscriber to the list of subscribers and
+// gives them every consensus change that has occurred since the change with
+// the provided id. Unlike ConsensusSetSubscribe, changes are delivered from a
+// separate goroutine, meaning that a slow subscriber will not stall the
+// consensus set. The subscriber will not have received all of the changes by
+// the time ConsensusSetSubscribeAsync returns.
+func (cs *ConsensusSet) ConsensusSetSubscribeAsync(subscriber modules.ConsensusSetSubscriber, start modules.ConsensusChangeID,
+	cancel <-chan struct{}) error {
+
+	// Register the async subscriber before subscribing so that an Unsubscribe
+	// issued during the subscription can find it.
+	as := cs.newAsyncSubscriber(subscriber)
+	cs.mu.Lock()
+	if _, exists := cs.asyncSubscribers[subscriber]; exists {
+		cs.mu.Unlock()
+		build.Critical("refusing to double-subscribe subscriber")
+		return nil
+	}
+	cs.asyncSubscribers[subscriber] = as
+	cs.mu.Unlock()
+
+	// Start delivering changes. The dispatch thread does not hold a spot in
+	// the threadgroup, otherwise a subscriber that is stuck inside of
+	// ProcessConsensusChange would prevent the consensus set from shutting
+	// down.
+	go as.threadedDispatch(cs.tg.StopChan())
+
+	err := cs.ConsensusSetSubscribe(as, start, cancel)
+	if err != nil {
+		cs.mu.Lock()
+		if cs.asyncSubscribers[subscriber] == as {
+			delete(cs.asyncSubscribers, subscriber)
+		}
+		cs.mu.Unlock()
+		as.close()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package consensus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// asyncMockSubscriber is a thread-safe subscriber that can be blocked from
+// processing consensus changes by holding blockMu.
+type asyncMockSubscriber struct {
+	updates []modules.ConsensusChange
+	blockMu sync.Mutex
+	mu      sync.Mutex
+}
+
+// ProcessConsensusChange adds a consensus change to the mock subscriber.
+func (ams *asyncMockSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	ams.blockMu.Lock()
+	ams.blockMu.Unlock()
+	ams.mu.Lock()
+	ams.updates = append(ams.updates, cc)
+	ams.mu.Unlock()
+}
+
+// numUpdates returns the number of updates that the subscriber has received.
+func (ams *asyncMockSubscriber) numUpdates() int {
+	ams.mu.Lock()
+	defer ams.mu.Unlock()
+	return len(ams.updates)
+}
+
+// lastUpdate returns the id of the most recent update that the subscriber has
+// received.
+func (ams *asyncMockSubscriber) lastUpdate() modules.ConsensusChangeID {
+	ams.mu.Lock()
+	defer ams.mu.Unlock()
+	if len(ams.updates) == 0 {
+		return modules.ConsensusChangeID{}
+	}
+	return ams.updates[len(ams.updates)-1].ID
+}
+
+// waitForRecent blocks until the subscriber has received the most recent
+// consensus change.
+func (cst *consensusSetTester) waitForRecent(ams *asyncMockSubscriber) error {
+	return build.Retry(100, 50*time.Millisecond, func() error {
+		cst.cs.mu.RLock()
+		recentID, err := cst.cs.recentConsensusChangeID()
+		cst.cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if ams.lastUpdate() != recentID {
+			return errors.New("subscriber has not received the most recent change")
+		}
+		return nil
+	})
+}
+
+// TestConsensusSetSubscribeAsync checks that a slow asynchronous subscriber
+// does not stall the consensus set, and that it eventually receives every
+// change in order.
+func TestConsensusSetSubscribeAsync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Subscribe the subscriber and wait for it to catch up.
+	ams := new(asyncMockSubscriber)
+	err = cst.cs.ConsensusSetSubscribeAsync(ams, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.waitForRecent(ams); err != nil {
+		t.Fatal(err)
+	}
+	caughtUp := ams.numUpdates()
+
+	// Block the subscriber and mine a few blocks. Mining should not stall.
+	ams.blockMu.Lock()
+	mined := make(chan error)
+	go func() {
+		for i := 0; i < asyncSubscriberQueueSize/2; i++ {
+			if _, err := cst.miner.AddBlock(); err != nil {
+				mined <- err
+				return
+			}
+		}
+		mined <- nil
+	}()
+	select {
+	case err := <-mined:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("mining was stalled by a blocked asynchronous subscriber")
+	}
+
+	// Unblock the subscriber and check that all of the changes arrive.
+	ams.blockMu.Unlock()
+	if err := cst.waitForRecent(ams); err != nil {
+		t.Fatal(err)
+	}
+	if ams.numUpdates() != caughtUp+asyncSubscriberQueueSize/2 {
+		t.Fatal("subscriber received the wrong number of updates:", ams.numUpdates())
+	}
+
+	// The changes should be received in order, with working TryTransactionSet
+	// functions.
+	ams.mu.Lock()
+	for i := 1; i < len(ams.updates); i++ {
+		prev := ams.updates[i-1].AppliedBlocks
+		if ams.updates[i].AppliedBlocks[0].ParentID != prev[len(prev)-1].ID() {
+			t.Error("updates were received out of order")
+		}
+	}
+	_, err = ams.updates[len(ams.updates)-1].TryTransactionSet(nil)
+	ams.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unsubscribe the subscriber and check that no more updates arrive.
+	cst.cs.Unsubscribe(ams)
+	numUpdates := ams.numUpdates()
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ams.numUpdates() != numUpdates {
+		t.Fatal("subscriber received an update after unsubscribing")
+	}
+}
@@ -0,0 +1,144 @@
+package consensus
+
+// subscribefilter.go implements filtered subscriptions. Filtered subscribers
+// are wrapped in a filteredSubscriber, which is placed into the regular list of
+// subscribers. When catching up, the consensus set recognizes the wrapper and
+// only collects the diffs selected by the filter, which is considerably cheaper
+// for subscribers such as the wallet that only care about a few addresses.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// filteredSubscriber wraps a subscriber so that it is only sent the diffs
+// selected by its filter.
+type filteredSubscriber struct {
+	subscriber modules.ConsensusSetSubscriber
+	filter     modules.ConsensusChangeFilter
+}
+
+// filterSiacoinOutputDiffs returns whether the filter selects siacoin output
+// diffs. A nil filter selects every diff.
+func filterSiacoinOutputDiffs(filter *modules.ConsensusChangeFilter) bool {
+	return filter == nil || filter.SiacoinOutputDiffs
+}
+
+// filterFileContractDiffs returns whether the filter selects file contract
+// diffs.
+func filterFileContractDiffs(filter *modules.ConsensusChangeFilter) bool {
+	return filter == nil || filter.FileContractDiffs
+}
+
+// filterSiafundOutputDiffs returns whether the filter selects siafund output
+// diffs.
+func filterSiafundOutputDiffs(filter *modules.ConsensusChangeFilter) bool {
+	return filter == nil || filter.SiafundOutputDiffs
+}
+
+// filterDelayedSiacoinOutputDiffs returns whether the filter selects delayed
+// siacoin output diffs.
+func filterDelayedSiacoinOutputDiffs(filter *modules.ConsensusChangeFilter) bool {
+	return filter == nil || filter.DelayedSiacoinOutputDiffs
+}
+
+// filterSiafundPoolDiffs returns whether the filter selects siafund pool
+// diffs.
+func filterSiafundPoolDiffs(filter *modules.ConsensusChangeFilter) bool {
+	return filter == nil || filter.SiafundPoolDiffs
+}
+
+// filterUnlockHash returns whether the filter selects outputs sent to the
+// provided unlock hash.
+func filterUnlockHash(filter *modules.ConsensusChangeFilter, uh types.UnlockHash) bool {
+	if filter == nil || len(filter.UnlockHashes) == 0 {
+		return true
+	}
+	_, exists := filter.UnlockHashes[uh]
+	return exists
+}
+
+// filterConsensusChange returns a copy of the consensus change that only
+// contains the diffs selected by the filter.
+func filterConsensusChange(cc modules.ConsensusChange, filter *modules.ConsensusChangeFilter) modules.ConsensusChange {
+	fcc := cc
+	fcc.SiacoinOutputDiffs = nil
+	fcc.FileContractDiffs = nil
+	fcc.SiafundOutputDiffs = nil
+	fcc.DelayedSiacoinOutputDiffs = nil
+	fcc.SiafundPoolDiffs = nil
+	if filterSiacoinOutputDiffs(filter) {
+		for _, scod := range cc.SiacoinOutputDiffs {
+			if filterUnlockHash(filter, scod.SiacoinOutput.UnlockHash) {
+				fcc.SiacoinOutputDiffs = append(fcc.SiacoinOutputDiffs, scod)
+			}
+		}
+	}
+	if filterFileContractDiffs(filter) {
+		fcc.FileContractDiffs = cc.FileContractDiffs
+	}
+	if filterSiafundOutputDiffs(filter) {
+		for _, sfod := range cc.SiafundOutputDiffs {
+			if filterUnlockHash(filter, sfod.SiafundOutput.UnlockHash) {
+				fcc.SiafundOutputDiffs = append(fcc.SiafundOutputDiffs, sfod)
+			}
+		}
+	}
+	if filterDelayedSiacoinOutputDiffs(filter) {
+		for _, dscod := range cc.DelayedSiacoinOutputDiffs {
+			if filterUnlockHash(filter, dscod.SiacoinOutput.UnlockHash) {
+				fcc.DelayedSiacoinOutputDiffs = append(fcc.DelayedSiacoinOutputDiffs, dscod)
+			}
+		}
+	}
+	if filterSiafundPoolDiffs(filter) {
+		fcc.SiafundPoolDiffs = cc.SiafundPoolDiffs
+	}
+	return fcc
+}
+
+// ProcessConsensusChange removes the diffs that were not selected by the
+// filter and sends the change to the subscriber.
+func (fs *filteredSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	fs.subscriber.ProcessConsensusChange(filterConsensusChange(cc, &fs.filter))
+}
+
+// ConsensusSetSubscribeFiltered adds a subscriber to the list of subscribers,
+// and gives them every consensus change that has occurred since the change
+// with the provided id. Only the diffs selected by the filter are sent to the
+// subscriber.
+func (cs *ConsensusSet) ConsensusSetSubscribeFiltered(subscriber modules.ConsensusSetSubscriber, start modules.ConsensusChangeID,
+	filter modules.ConsensusChangeFilter, cancel <-chan struct{}) error {
+
+	// Copy the unlock hashes so that the caller cannot modify the filter
+	// while changes are being computed.
+	if len(filter.UnlockHashes) != 0 {
+		uhs := make(map[types.UnlockHash]struct{}, len(filter.UnlockHashes))
+		for uh := range filter.UnlockHashes {
+			uhs[uh] = struct{}{}
+		}
+		filter.UnlockHashes = uhs
+	}
+
+	fs := &filteredSubscriber{subscriber: subscriber, filter: filter}
+	cs.mu.Lock()
+	if _, exists := cs.filteredSubscribers[subscriber]; exists {
+		cs.mu.Unlock()
+		build.Critical("refusing to double-subscribe subscriber")
+		return nil
+	}
+	cs.filteredSubscribers[subscriber] = fs
+	cs.mu.Unlock()
+
+	err := cs.ConsensusSetSubscribe(fs, start, cancel)
+	if err != nil {
+		cs.mu.Lock()
+		if cs.filteredSubscribers[subscriber] == fs {
+			delete(cs.filteredSubscribers, subscriber)
+		}
+		cs.mu.Unlock()
+		return err
+	}
+	return nil
+}
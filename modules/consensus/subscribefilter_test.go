@@ -0,0 +1,99 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// checkFiltered checks that the filtered changes contain exactly the diffs of
+// the full changes that are selected by the filter.
+func checkFiltered(t *testing.T, full, filtered []modules.ConsensusChange, filter modules.ConsensusChangeFilter) {
+	if len(full) != len(filtered) {
+		t.Fatalf("filtered subscriber has %v updates, expected %v", len(filtered), len(full))
+	}
+	for i := range full {
+		expected := filterConsensusChange(full[i], &filter)
+		if expected.ID != filtered[i].ID || len(expected.AppliedBlocks) != len(filtered[i].AppliedBlocks) {
+			t.Fatal("mismatched change at index", i)
+		}
+		if len(expected.SiacoinOutputDiffs) != len(filtered[i].SiacoinOutputDiffs) {
+			t.Fatal("mismatched siacoin output diffs at index", i)
+		}
+		for j, scod := range filtered[i].SiacoinOutputDiffs {
+			if scod.ID != expected.SiacoinOutputDiffs[j].ID || scod.Direction != expected.SiacoinOutputDiffs[j].Direction {
+				t.Fatal("mismatched siacoin output diff at index", i)
+			}
+		}
+		if len(filtered[i].FileContractDiffs) != 0 || len(filtered[i].SiafundOutputDiffs) != 0 ||
+			len(filtered[i].DelayedSiacoinOutputDiffs) != 0 || len(filtered[i].SiafundPoolDiffs) != 0 {
+			t.Fatal("filtered subscriber received diffs that were not selected at index", i)
+		}
+	}
+}
+
+// TestConsensusSetSubscribeFiltered checks that filtered subscribers only
+// receive the diffs selected by their filter, both during catch-up and for
+// new blocks.
+func TestConsensusSetSubscribeFiltered(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.cs.Unsubscribe(&ms)
+
+	// Only select the siacoin outputs sent to the address of the first
+	// output that was created.
+	var uh types.UnlockHash
+	for _, cc := range ms.updates {
+		if len(cc.SiacoinOutputDiffs) != 0 {
+			uh = cc.SiacoinOutputDiffs[0].SiacoinOutput.UnlockHash
+			break
+		}
+	}
+	filter := modules.ConsensusChangeFilter{
+		SiacoinOutputDiffs: true,
+		UnlockHashes:       map[types.UnlockHash]struct{}{uh: {}},
+	}
+	mfs := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribeFiltered(&mfs, modules.ConsensusChangeBeginning, filter, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFiltered(t, ms.updates, mfs.updates, filter)
+	var matched int
+	for _, cc := range mfs.updates {
+		matched += len(cc.SiacoinOutputDiffs)
+	}
+	if matched == 0 {
+		t.Fatal("filtered subscriber did not receive any siacoin output diffs")
+	}
+
+	// Mine a block and check that the filtered subscriber receives it.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiltered(t, ms.updates, mfs.updates, filter)
+
+	// After unsubscribing, no more updates should arrive.
+	cst.cs.Unsubscribe(&mfs)
+	numUpdates := len(mfs.updates)
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mfs.updates) != numUpdates {
+		t.Fatal("filtered subscriber received an update after unsubscribing")
+	}
+}
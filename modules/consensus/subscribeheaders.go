@@ -0,0 +1,138 @@
+package consensus
+
+// subscribeheaders.go implements header-only subscriptions. Header subscribers
+// are wrapped in a headerSubscriber, which is placed into the regular list of
+// subscribers. When catching up, the consensus set recognizes the wrapper and
+// only computes the headers of each change, skipping the cost of collecting
+// all of the diffs.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// headerSubscriber wraps a header subscriber so that it can be used as a
+// regular subscriber.
+type headerSubscriber struct {
+	subscriber modules.ConsensusSetHeaderSubscriber
+}
+
+// ProcessConsensusChange strips the diffs from a consensus change and sends
+// the headers to the header subscriber.
+func (hs *headerSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	hcc := modules.HeaderConsensusChange{
+		ID:          cc.ID,
+		ChildTarget: cc.ChildTarget,
+		Synced:      cc.Synced,
+	}
+	for _, b := range cc.RevertedBlocks {
+		hcc.RevertedBlockHeaders = append(hcc.RevertedBlockHeaders, b.Header())
+	}
+	for _, b := range cc.AppliedBlocks {
+		hcc.AppliedBlockHeaders = append(hcc.AppliedBlockHeaders, b.Header())
+	}
+	hs.subscriber.ProcessHeaderConsensusChange(hcc)
+}
+
+// computeHeaderConsensusChange computes the header-only consensus change from
+// the provided change entry.
+func (cs *ConsensusSet) computeHeaderConsensusChange(tx Tx, ce changeEntry) (modules.HeaderConsensusChange, error) {
+	hcc := modules.HeaderConsensusChange{
+		ID: ce.ID(),
+	}
+	for _, revertedBlockID := range ce.RevertedBlocks {
+		revertedBlock, err := getBlockMap(tx, revertedBlockID)
+		if err != nil {
+			cs.log.Critical("getBlockMap failed in computeHeaderConsensusChange:", err)
+			return modules.HeaderConsensusChange{}, err
+		}
+		hcc.RevertedBlockHeaders = append(hcc.RevertedBlockHeaders, revertedBlock.Block.Header())
+	}
+	var pb *processedBlock
+	for _, appliedBlockID := range ce.AppliedBlocks {
+		appliedBlock, err := getBlockMap(tx, appliedBlockID)
+		if err != nil {
+			cs.log.Critical("getBlockMap failed in computeHeaderConsensusChange:", err)
+			return modules.HeaderConsensusChange{}, err
+		}
+		hcc.AppliedBlockHeaders = append(hcc.AppliedBlockHeaders, appliedBlock.Block.Header())
+		pb = appliedBlock
+	}
+	if pb != nil {
+		hcc.ChildTarget = pb.ChildTarget
+		hcc.Synced = cs.synced && pb.Block.ID() == currentBlockID(tx)
+	}
+	return hcc, nil
+}
+
+// processChangeEntry sends the consensus change for the provided change entry
+// to the subscriber, giving the change the provided id. Header subscribers are
+// only sent the headers of the change, and filtered subscribers are only sent
+// the diffs selected by their filter.
+func (cs *ConsensusSet) processChangeEntry(tx Tx, subscriber modules.ConsensusSetSubscriber, ce changeEntry, id modules.ConsensusChangeID) error {
+	if hs, ok := subscriber.(*headerSubscriber); ok {
+		hcc, err := cs.computeHeaderConsensusChange(tx, ce)
+		if err != nil {
+			return err
+		}
+		hcc.ID = id
+		hs.subscriber.ProcessHeaderConsensusChange(hcc)
+		return nil
+	}
+	if fs, ok := subscriber.(*filteredSubscriber); ok {
+		cc, err := cs.computeConsensusChange(tx, ce, &fs.filter)
+		if err != nil {
+			return err
+		}
+		cc.ID = id
+		fs.subscriber.ProcessConsensusChange(cc)
+		return nil
+	}
+	cc, err := cs.computeConsensusChange(tx, ce, nil)
+	if err != nil {
+		return err
+	}
+	cc.ID = id
+	subscriber.ProcessConsensusChange(cc)
+	return nil
+}
+
+// ConsensusSetHeaderSubscribe adds a header subscriber to the list of
+// subscribers, and gives them the headers of every consensus change that has
+// occurred since the change with the provided id.
+func (cs *ConsensusSet) ConsensusSetHeaderSubscribe(subscriber modules.ConsensusSetHeaderSubscriber, start modules.ConsensusChangeID,
+	cancel <-chan struct{}) error {
+
+	hs := &headerSubscriber{subscriber: subscriber}
+	cs.mu.Lock()
+	if _, exists := cs.headerSubscribers[subscriber]; exists {
+		cs.mu.Unlock()
+		build.Critical("refusing to double-subscribe subscriber")
+		return nil
+	}
+	cs.headerSubscribers[subscriber] = hs
+	cs.mu.Unlock()
+
+	err := cs.ConsensusSetSubscribe(hs, start, cancel)
+	if err != nil {
+		cs.mu.Lock()
+		if cs.headerSubscribers[subscriber] == hs {
+			delete(cs.headerSubscribers, subscriber)
+		}
+		cs.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// UnsubscribeHeaders removes a header subscriber from the list of
+// subscribers. If the subscriber is not found, no action is taken.
+func (cs *ConsensusSet) UnsubscribeHeaders(subscriber modules.ConsensusSetHeaderSubscriber) {
+	cs.mu.Lock()
+	hs, exists := cs.headerSubscribers[subscriber]
+	delete(cs.headerSubscribers, subscriber)
+	cs.mu.Unlock()
+	if exists {
+		cs.Unsubscribe(hs)
+	}
+}
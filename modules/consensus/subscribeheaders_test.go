@@ -0,0 +1,111 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// mockHeaderSubscriber receives and holds header changes to the consensus set.
+type mockHeaderSubscriber struct {
+	updates []modules.HeaderConsensusChange
+}
+
+// ProcessHeaderConsensusChange adds a header consensus change to the mock
+// header subscriber.
+func (mhs *mockHeaderSubscriber) ProcessHeaderConsensusChange(hcc modules.HeaderConsensusChange) {
+	mhs.updates = append(mhs.updates, hcc)
+}
+
+// checkHeaders checks that the header changes match the full changes.
+func checkHeaders(t *testing.T, full []modules.ConsensusChange, headers []modules.HeaderConsensusChange) {
+	if len(full) != len(headers) {
+		t.Fatalf("header subscriber has %v updates, expected %v", len(headers), len(full))
+	}
+	for i := range full {
+		if full[i].ID != headers[i].ID {
+			t.Fatal("mismatched change id at index", i)
+		}
+		if len(full[i].AppliedBlocks) != len(headers[i].AppliedBlockHeaders) || len(full[i].RevertedBlocks) != len(headers[i].RevertedBlockHeaders) {
+			t.Fatal("mismatched number of blocks at index", i)
+		}
+		for j, b := range full[i].AppliedBlocks {
+			if b.ID() != headers[i].AppliedBlockHeaders[j].ID() {
+				t.Fatal("mismatched applied header at index", i)
+			}
+		}
+		if full[i].ChildTarget != headers[i].ChildTarget {
+			t.Fatal("mismatched child target at index", i)
+		}
+	}
+}
+
+// TestConsensusSetHeaderSubscribe checks that header subscribers receive the
+// headers of the same changes as regular subscribers, both during catch-up
+// and for new blocks.
+func TestConsensusSetHeaderSubscribe(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.cs.Unsubscribe(&ms)
+	mhs := new(mockHeaderSubscriber)
+	err = cst.cs.ConsensusSetHeaderSubscribe(mhs, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHeaders(t, ms.updates, mhs.updates)
+
+	// Mine a block and check that the header subscriber receives it.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkHeaders(t, ms.updates, mhs.updates)
+
+	// Resume a header subscription from the middle of the changelog.
+	mid := new(mockHeaderSubscriber)
+	err = cst.cs.ConsensusSetHeaderSubscribe(mid, ms.updates[3].ID, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHeaders(t, ms.updates[4:], mid.updates)
+	cst.cs.UnsubscribeHeaders(mid)
+
+	// After unsubscribing, no more updates should arrive.
+	cst.cs.UnsubscribeHeaders(mhs)
+	numUpdates := len(mhs.updates)
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mhs.updates) != numUpdates {
+		t.Fatal("header subscriber received an update after unsubscribing")
+	}
+
+	// Subscribing from the beginning after the changelog has been pruned
+	// should produce one header change per block in the current path.
+	if _, err := cst.cs.PruneChangeLog(2); err != nil {
+		t.Fatal(err)
+	}
+	pruned := new(mockHeaderSubscriber)
+	err = cst.cs.ConsensusSetHeaderSubscribe(pruned, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned.updates) != int(cst.cs.Height())+1 {
+		t.Fatal("wrong number of header updates after pruning:", len(pruned.updates))
+	}
+	if pruned.updates[len(pruned.updates)-1].ID != ms.updates[len(ms.updates)-1].ID {
+		t.Fatal("final header update should have the id of the most recent change")
+	}
+}
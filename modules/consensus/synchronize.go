@@ -11,8 +11,6 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 const (
@@ -90,13 +88,49 @@ func isTimeoutErr(err error) bool {
 	return (err.Error() == "Read timeout" || err.Error() == "Write timeout")
 }
 
+// isInvalidBlockErr is a helper function that returns true if err shows that
+// a block or header is invalid, as opposed to being known, an orphan, or too
+// early to accept.
+func isInvalidBlockErr(err error) bool {
+	switch err {
+	case errDoSBlock, errNonLinearChain, errLargeBlock, errBadMinerPayouts,
+		errEarlyTimestamp, errExtremeFutureTimestamp, modules.ErrBlockUnsolved:
+		return true
+	}
+	return false
+}
+
+// managedPenalizeInvalidBlocks penalizes the peer that sent the blocks if
+// acceptErr shows that one of the blocks is invalid. Blocks that contain
+// invalid transactions are recognized by having been marked as DoS blocks.
+func (cs *ConsensusSet) managedPenalizeInvalidBlocks(addr modules.NetAddress, blocks []types.Block, acceptErr error) {
+	if acceptErr == nil {
+		return
+	}
+	invalid := isInvalidBlockErr(acceptErr)
+	if !invalid {
+		cs.mu.RLock()
+		for _, b := range blocks {
+			if _, exists := cs.dosBlocks[b.ID()]; exists {
+				invalid = true
+				break
+			}
+		}
+		cs.mu.RUnlock()
+	}
+	if invalid {
+		cs.log.Debugf("WARN: peer %v sent an invalid block: %v", addr, acceptErr)
+		cs.gateway.PenalizePeer(addr, modules.PenaltyInvalidBlock)
+	}
+}
+
 // blockHistory returns up to 32 block ids, starting with recent blocks and
 // then proving exponentially increasingly less recent blocks. The genesis
 // block is always included as the last block. This block history can be used
 // to find a common parent that is reasonably recent, usually the most recent
 // common parent is found, but always a common parent within a factor of 2 is
 // found.
-func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
+func blockHistory(tx Tx) (blockIDs [32]types.BlockID) {
 	height := blockHeight(tx)
 	step := types.BlockHeight(1)
 	// The final step is to include the genesis block, which is why the final
@@ -132,6 +166,33 @@ func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
 	return blockIDs
 }
 
+// historyStart finds the most recent block from a block history that is in
+// the current path, and returns the height of its child. found is false if
+// none of the blocks are in the current path, or if the most recent one is the
+// current block.
+func historyStart(tx Tx, knownBlocks [32]types.BlockID) (start types.BlockHeight, found bool) {
+	csHeight := blockHeight(tx)
+	for _, id := range knownBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			continue
+		}
+		pathID, err := getPath(tx, pb.Height)
+		if err != nil {
+			continue
+		}
+		if pathID != pb.Block.ID() {
+			continue
+		}
+		if pb.Height == csHeight {
+			break
+		}
+		// Start from the child of the common block.
+		return pb.Height + 1, true
+	}
+	return 0, false
+}
+
 // managedReceiveBlocks is the calling end of the SendBlocks RPC, without the
 // threadgroup wrapping.
 func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr error) {
@@ -165,7 +226,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 	// Get blockIDs to send.
 	var history [32]types.BlockID
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx Tx) error {
 		history = blockHistory(tx)
 		return nil
 	})
@@ -226,6 +287,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		if extended {
 			chainExtended = true
 		}
+		cs.managedPenalizeInvalidBlocks(conn.RPCAddr(), newBlocks, acceptErr)
 		// ErrNonExtendingBlock must be ignored until headers-first block
 		// sharing is implemented, block already in database should also be
 		// ignored.
@@ -293,32 +355,11 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	}
 
 	// Find the most recent block from knownBlocks in the current path.
-	found := false
+	var found bool
 	var start types.BlockHeight
-	var csHeight types.BlockHeight
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
-		csHeight = blockHeight(tx)
-		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
-			if err != nil {
-				continue
-			}
-			pathID, err := getPath(tx, pb.Height)
-			if err != nil {
-				continue
-			}
-			if pathID != pb.Block.ID() {
-				continue
-			}
-			if pb.Height == csHeight {
-				break
-			}
-			found = true
-			// Start from the child of the common block.
-			start = pb.Height + 1
-			break
-		}
+	err = cs.db.View(func(tx Tx) error {
+		start, found = historyStart(tx, knownBlocks)
 		return nil
 	})
 	cs.mu.RUnlock()
@@ -344,7 +385,7 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 		// Get the set of blocks to send.
 		var blocks []types.Block
 		cs.mu.RLock()
-		err = cs.db.View(func(tx *bolt.Tx) error {
+		err = cs.db.View(func(tx Tx) error {
 			height := blockHeight(tx)
 			for i := start; i <= height && i < start+MaxCatchUpBlocks; i++ {
 				id, err := getPath(tx, i)
@@ -420,12 +461,24 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 	}
 
 	// Start verification inside of a bolt View tx.
+	var parentHeight types.BlockHeight
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx Tx) error {
 		// Do some relatively inexpensive checks to validate the header
-		return cs.validateHeader(boltTxWrapper{tx}, h)
+		err := cs.validateHeader(txWrapper{tx}, h)
+		if err == nil {
+			if parent, perr := getBlockMap(tx, h.ParentID); perr == nil {
+				parentHeight = parent.Height
+			}
+		}
+		return err
 	})
 	cs.mu.RUnlock()
+	if err == nil {
+		cs.mu.Lock()
+		cs.notePeerHeight(parentHeight + 1)
+		cs.mu.Unlock()
+	}
 	// WARN: orphan multithreading logic (dangerous areas, see below)
 	//
 	// If the header is valid and extends the heaviest chain, fetch the
@@ -449,14 +502,29 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 		}()
 		return nil
 	} else if err != nil {
+		if isInvalidBlockErr(err) {
+			cs.gateway.PenalizePeer(conn.RPCAddr(), modules.PenaltyInvalidBlock)
+		}
 		return err
 	}
 
 	// WARN: orphan multithreading logic case #2
+	//
+	// Peers that support compact blocks are asked for a compact block first,
+	// falling back to the full block if it cannot be reconstructed.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err = cs.gateway.RPC(conn.RPCAddr(), "SendBlk", cs.managedReceiveBlock(h.ID()))
+		if cs.gateway.PeerCapable(conn.RPCAddr(), compactBlocksCapability) {
+			err := cs.gateway.RPC(conn.RPCAddr(), "CompactBlk", cs.managedReceiveCompactBlock(h.ID()))
+			if err != errCompactBlockMismatch {
+				if err != nil {
+					cs.log.Debugln("WARN: failed to get header's corresponding compact block:", err)
+				}
+				return
+			}
+		}
+		err := cs.gateway.RPC(conn.RPCAddr(), "SendBlk", cs.managedReceiveBlock(h.ID()))
 		if err != nil {
 			cs.log.Debugln("WARN: failed to get header's corresponding block:", err)
 		}
@@ -494,7 +562,7 @@ func (cs *ConsensusSet) rpcSendBlk(conn modules.PeerConn) error {
 	// Lookup the corresponding block.
 	var b types.Block
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -530,6 +598,7 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 		if chainExtended {
 			cs.managedBroadcastBlock(block)
 		}
+		cs.managedPenalizeInvalidBlocks(conn.RPCAddr(), []types.Block{block}, err)
 		if err != nil {
 			return err
 		}
@@ -537,8 +606,9 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 	}
 }
 
-// threadedInitialBlockchainDownload performs the IBD on outbound peers. Blocks
-// are downloaded from one peer at a time in 5 minute intervals, so as to
+// threadedInitialBlockchainDownload performs the IBD on outbound peers. Peers
+// that support header-first synchronization are used first, and the remaining
+// blocks are downloaded from one peer at a time in 5 minute intervals, so as to
 // prevent any one peer from significantly slowing down IBD.
 //
 // NOTE: IBD will succeed right now when each peer has a different blockchain.
@@ -557,6 +627,30 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 	numOutboundSynced := 0
 	numOutboundNotSynced := 0
 	for {
+		// Download as many blocks as possible with header-first
+		// synchronization from the outbound peers that support it. SendBlocks
+		// is then called on every peer as before, which picks up any blocks
+		// that were not downloaded and determines whether the peers consider
+		// the consensus set to be synced.
+		var headerSyncPeers []modules.NetAddress
+		for _, p := range cs.gateway.Peers() {
+			if !p.Inbound && cs.gateway.PeerCapable(p.NetAddress, headerSyncCapability) {
+				headerSyncPeers = append(headerSyncPeers, p.NetAddress)
+			}
+		}
+		if len(headerSyncPeers) > 0 {
+			err := func() error {
+				if err := cs.tg.Add(); err != nil {
+					return err
+				}
+				defer cs.tg.Done()
+				return cs.managedSyncHeadersFirst(headerSyncPeers)
+			}()
+			if err != nil {
+				return err
+			}
+		}
+
 		numOutboundSynced = 0
 		numOutboundNotSynced = 0
 		for _, p := range cs.gateway.Peers() {
@@ -592,6 +686,9 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 					//
 					// We disconnect so that these peers are removed from gateway.Peers() and
 					// do not prevent us from marking ourselves as fully synced.
+					if err == errSendBlocksStalled {
+						cs.gateway.PenalizePeer(p.NetAddress, modules.PenaltyStall)
+					}
 					err := cs.gateway.Disconnect(p.NetAddress)
 					if err != nil {
 						cs.log.Printf("WARN: disconnecting from peer %v failed: %v", p.NetAddress, err)
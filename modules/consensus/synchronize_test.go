@@ -17,8 +17,6 @@ import (
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 // TestSynchronize tests that the consensus set can successfully synchronize
@@ -131,7 +129,7 @@ func TestBlockHistory(t *testing.T) {
 	}
 
 	var history [32]types.BlockID
-	_ = cst.cs.db.View(func(tx *bolt.Tx) error {
+	_ = cst.cs.db.View(func(tx Tx) error {
 		history = blockHistory(tx)
 		return nil
 	})
@@ -597,7 +595,7 @@ func TestRPCSendBlockSendsOnlyNecessaryBlocks(t *testing.T) {
 		// Get blockIDs to send.
 		var history [32]types.BlockID
 		cs.mu.RLock()
-		err := cs.db.View(func(tx *bolt.Tx) error {
+		err := cs.db.View(func(tx Tx) error {
 			history = blockHistory(tx)
 			return nil
 		})
@@ -1505,3 +1503,92 @@ func TestIntegrationSendBlocksStalls(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// mockGatewayRecordsPenalties is a mock implementation of modules.Gateway that
+// records the penalties given to peers.
+type mockGatewayRecordsPenalties struct {
+	modules.Gateway
+	mu        sync.Mutex
+	penalties map[modules.NetAddress]int
+}
+
+// PenalizePeer records the penalty instead of passing it to the gateway.
+func (g *mockGatewayRecordsPenalties) PenalizePeer(addr modules.NetAddress, points int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.penalties[addr] += points
+}
+
+// penalty returns the number of penalty points given to a peer.
+func (g *mockGatewayRecordsPenalties) penalty(addr modules.NetAddress) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.penalties[addr]
+}
+
+// TestPenalizeInvalidBlocks checks that peers are penalized for relaying
+// invalid headers and blocks, but not for relaying known blocks.
+func TestPenalizeInvalidBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	mg := &mockGatewayRecordsPenalties{
+		Gateway:   cst.cs.gateway,
+		penalties: make(map[modules.NetAddress]int),
+	}
+	cst.cs.gateway = mg
+
+	p1, p2 := net.Pipe()
+	mockP2 := mockPeerConn{p2}
+	addr := mockP2.RPCAddr()
+
+	// Relaying a known header should not be penalized.
+	go encoding.WriteObject(p1, types.GenesisBlock.Header())
+	if err := cst.cs.threadedRPCRelayHeader(mockP2); err != modules.ErrBlockKnown {
+		t.Fatal("expected ErrBlockKnown, got", err)
+	}
+	if mg.penalty(addr) != 0 {
+		t.Fatal("peer was penalized for relaying a known header")
+	}
+
+	// Relaying an unsolved header should be penalized.
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for checkTarget(block, block.ID(), target) {
+		block.Nonce[0]++
+	}
+	go encoding.WriteObject(p1, block.Header())
+	if err := cst.cs.threadedRPCRelayHeader(mockP2); err != modules.ErrBlockUnsolved {
+		t.Fatal("expected ErrBlockUnsolved, got", err)
+	}
+	if mg.penalty(addr) != modules.PenaltyInvalidBlock {
+		t.Fatal("peer was not penalized for relaying an unsolved header:", mg.penalty(addr))
+	}
+
+	// Sending a solved block with invalid miner payouts should be penalized.
+	block, target, err = cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.MinerPayouts = append(block.MinerPayouts, types.SiacoinOutput{Value: types.NewCurrency64(1)})
+	block, _ = cst.miner.SolveBlock(block, target)
+	go func() {
+		var id types.BlockID
+		encoding.ReadObject(p1, &id, crypto.HashSize)
+		encoding.WriteObject(p1, block)
+	}()
+	if err := cst.cs.managedReceiveBlock(block.ID())(mockP2); err != errBadMinerPayouts {
+		t.Fatal("expected errBadMinerPayouts, got", err)
+	}
+	if mg.penalty(addr) != 2*modules.PenaltyInvalidBlock {
+		t.Fatal("peer was not penalized for sending an invalid block:", mg.penalty(addr))
+	}
+}
@@ -0,0 +1,113 @@
+package consensus
+
+// syncprogress.go estimates how far the consensus set has synced with the
+// network. The height of the network is estimated from the heights of the
+// headers that peers announce, which carry proof of work, and, until initial
+// blockchain download has finished, from the time that has passed since the
+// current block was mined. Peers only send a limited number of headers at a
+// time, so the announced heights alone underestimate the network height early
+// in the download. The rate at which blocks are added is measured over a
+// sliding window.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// syncRateWindow is the length of the window over which the rate at
+	// which blocks are added is measured.
+	syncRateWindow = build.Select(build.Var{
+		Standard: 2 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+)
+
+// A syncSample records the height of the current path at a point in time.
+type syncSample struct {
+	timestamp time.Time
+	height    types.BlockHeight
+}
+
+// notePeerHeight records the height of a block that a peer announced. The
+// consensus set must be locked when notePeerHeight is called.
+func (cs *ConsensusSet) notePeerHeight(height types.BlockHeight) {
+	if height > cs.peerHeight {
+		cs.peerHeight = height
+	}
+}
+
+// recordSyncSample records the height of the current path after blocks were
+// added to it, and drops the samples that have left the rate window. The most
+// recent sample outside of the window is kept as the start of the window. The
+// consensus set must be locked when recordSyncSample is called.
+func (cs *ConsensusSet) recordSyncSample(height types.BlockHeight) {
+	now := time.Now()
+	cs.syncSamples = append(cs.syncSamples, syncSample{now, height})
+	i := 0
+	for i < len(cs.syncSamples)-1 && now.Sub(cs.syncSamples[i+1].timestamp) > syncRateWindow {
+		i++
+	}
+	cs.syncSamples = cs.syncSamples[i:]
+}
+
+// blocksPerSecond returns the rate at which blocks were added to the current
+// path during the rate window. The consensus set must be locked when
+// blocksPerSecond is called.
+func (cs *ConsensusSet) blocksPerSecond() float64 {
+	if len(cs.syncSamples) < 2 {
+		return 0
+	}
+	first, last := cs.syncSamples[0], cs.syncSamples[len(cs.syncSamples)-1]
+	elapsed := time.Since(first.timestamp)
+	if elapsed > syncRateWindow {
+		elapsed = syncRateWindow
+	}
+	if last.height <= first.height || elapsed <= 0 {
+		return 0
+	}
+	return float64(last.height-first.height) / elapsed.Seconds()
+}
+
+// SyncProgress reports how far the consensus set has synced with the network.
+func (cs *ConsensusSet) SyncProgress() (sp modules.ConsensusSyncProgress) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var timestamp types.Timestamp
+	_ = cs.db.View(func(tx Tx) error {
+		pb := currentProcessedBlock(tx)
+		sp.Height = pb.Height
+		timestamp = pb.Block.Timestamp
+		return nil
+	})
+	sp.Synced = cs.synced
+	sp.BlocksPerSecond = cs.blocksPerSecond()
+
+	sp.EstimatedHeight = sp.Height
+	if cs.peerHeight > sp.EstimatedHeight {
+		sp.EstimatedHeight = cs.peerHeight
+	}
+	if now := types.CurrentTimestamp(); !cs.synced && now > timestamp {
+		if est := sp.Height + types.BlockHeight(now-timestamp)/types.BlockFrequency; est > sp.EstimatedHeight {
+			sp.EstimatedHeight = est
+		}
+	}
+
+	sp.Progress = 1
+	if sp.EstimatedHeight > 0 {
+		sp.Progress = float64(sp.Height) / float64(sp.EstimatedHeight)
+	}
+	if remaining := sp.EstimatedHeight - sp.Height; remaining > 0 && sp.BlocksPerSecond > 0 {
+		sp.ETA = time.Duration(float64(remaining) / sp.BlocksPerSecond * float64(time.Second))
+	}
+	return sp
+}
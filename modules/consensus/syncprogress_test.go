@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlocksPerSecond checks that the block rate is measured over the rate
+// window.
+func TestBlocksPerSecond(t *testing.T) {
+	cs := new(ConsensusSet)
+	if rate := cs.blocksPerSecond(); rate != 0 {
+		t.Fatal("expected a rate of 0 without samples, got", rate)
+	}
+
+	// Samples that left the window should be dropped, except for the most
+	// recent one.
+	now := time.Now()
+	cs.syncSamples = []syncSample{
+		{now.Add(-3 * syncRateWindow), 0},
+		{now.Add(-2 * syncRateWindow), 10},
+		{now.Add(-syncRateWindow / 2), 50},
+	}
+	cs.recordSyncSample(110)
+	if len(cs.syncSamples) != 3 || cs.syncSamples[0].height != 10 {
+		t.Fatal("wrong samples after pruning:", cs.syncSamples)
+	}
+	want := 100 / syncRateWindow.Seconds()
+	if rate := cs.blocksPerSecond(); rate < want*0.99 || rate > want*1.01 {
+		t.Fatalf("expected a rate of %v, got %v", want, rate)
+	}
+
+	// A reorg to a lower height should not report a negative rate.
+	cs.recordSyncSample(5)
+	if rate := cs.blocksPerSecond(); rate != 0 {
+		t.Fatal("expected a rate of 0 after a reorg, got", rate)
+	}
+}
+
+// TestSyncProgress checks the sync progress reported by the consensus set.
+func TestSyncProgress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The consensus set is marked as synced asynchronously, once the initial
+	// blockchain download finishes.
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		if !cst.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := cst.cs.SyncProgress()
+	if !sp.Synced || sp.Height != cst.cs.Height() || sp.EstimatedHeight != sp.Height || sp.Progress != 1 {
+		t.Fatalf("wrong sync progress for a synced consensus set: %+v", sp)
+	} else if sp.BlocksPerSecond <= 0 {
+		t.Fatal("expected a positive block rate, got", sp.BlocksPerSecond)
+	} else if sp.ETA != 0 {
+		t.Fatal("expected no ETA at the estimated height, got", sp.ETA)
+	}
+
+	// A peer announcing a higher block should raise the estimated height.
+	cst.cs.mu.Lock()
+	cst.cs.notePeerHeight(2 * sp.Height)
+	cst.cs.mu.Unlock()
+	sp = cst.cs.SyncProgress()
+	if sp.EstimatedHeight != 2*sp.Height || sp.Progress != 0.5 || sp.ETA <= 0 {
+		t.Fatalf("wrong sync progress after a peer announced a higher block: %+v", sp)
+	}
+	cst.cs.mu.Lock()
+	cst.cs.notePeerHeight(types.BlockHeight(1))
+	cst.cs.mu.Unlock()
+	if cst.cs.SyncProgress().EstimatedHeight != sp.EstimatedHeight {
+		t.Fatal("a lower announced height decreased the estimated height")
+	}
+}
@@ -0,0 +1,159 @@
+package consensus
+
+// utxo.go provides read-only access to the unspent outputs and open file
+// contracts of the consensus set, so that callers can inspect the current
+// state without subscribing.
+
+import (
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// SiacoinOutput returns the unspent siacoin output with the given id, with a
+// bool to indicate whether the output exists.
+func (cs *ConsensusSet) SiacoinOutput(id types.SiacoinOutputID) (sco types.SiacoinOutput, exists bool) {
+	if cs.tg.Add() != nil {
+		return types.SiacoinOutput{}, false
+	}
+	defer cs.tg.Done()
+	_ = cs.db.View(func(tx Tx) error {
+		var err error
+		sco, err = getSiacoinOutput(tx, id)
+		exists = err == nil
+		return nil
+	})
+	return sco, exists
+}
+
+// SiafundOutput returns the unspent siafund output with the given id, with a
+// bool to indicate whether the output exists.
+func (cs *ConsensusSet) SiafundOutput(id types.SiafundOutputID) (sfo types.SiafundOutput, exists bool) {
+	if cs.tg.Add() != nil {
+		return types.SiafundOutput{}, false
+	}
+	defer cs.tg.Done()
+	_ = cs.db.View(func(tx Tx) error {
+		var err error
+		sfo, err = getSiafundOutput(tx, id)
+		exists = err == nil
+		return nil
+	})
+	return sfo, exists
+}
+
+// DelayedOutputs returns the delayed siacoin outputs that mature at the
+// provided height. Delayed outputs only exist for heights after the current
+// height, so the map is empty for any other height.
+func (cs *ConsensusSet) DelayedOutputs(height types.BlockHeight) (map[types.SiacoinOutputID]types.SiacoinOutput, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+	dscos := make(map[types.SiacoinOutputID]types.SiacoinOutput)
+	err = cs.db.View(func(tx Tx) error {
+		bucketID := append(prefixDSCO, encoding.Marshal(height)...)
+		dscoBucket := tx.Bucket(bucketID)
+		if dscoBucket == nil {
+			return nil
+		}
+		return dscoBucket.ForEach(func(k, v []byte) error {
+			var id types.SiacoinOutputID
+			var sco types.SiacoinOutput
+			copy(id[:], k)
+			if err := encoding.Unmarshal(v, &sco); err != nil {
+				return err
+			}
+			dscos[id] = sco
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dscos, nil
+}
+
+// FileContract returns the open file contract with the given id, with a bool
+// to indicate whether the file contract exists.
+func (cs *ConsensusSet) FileContract(id types.FileContractID) (fc types.FileContract, exists bool) {
+	if cs.tg.Add() != nil {
+		return types.FileContract{}, false
+	}
+	defer cs.tg.Done()
+	_ = cs.db.View(func(tx Tx) error {
+		var err error
+		fc, err = getFileContract(tx, id)
+		exists = err == nil
+		return nil
+	})
+	return fc, exists
+}
+
+// ForEachSiacoinOutput calls fn on every unspent siacoin output. Iteration
+// stops if fn returns an error, and the error is returned. All of the outputs
+// are read from a single snapshot of the consensus set.
+func (cs *ConsensusSet) ForEachSiacoinOutput(fn func(types.SiacoinOutputID, types.SiacoinOutput) error) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	return cs.db.View(func(tx Tx) error {
+		return tx.Bucket(SiacoinOutputs).ForEach(func(k, v []byte) error {
+			var id types.SiacoinOutputID
+			var sco types.SiacoinOutput
+			copy(id[:], k)
+			if err := encoding.Unmarshal(v, &sco); err != nil {
+				return err
+			}
+			return fn(id, sco)
+		})
+	})
+}
+
+// ForEachSiafundOutput calls fn on every unspent siafund output. Iteration
+// stops if fn returns an error, and the error is returned. All of the outputs
+// are read from a single snapshot of the consensus set.
+func (cs *ConsensusSet) ForEachSiafundOutput(fn func(types.SiafundOutputID, types.SiafundOutput) error) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	return cs.db.View(func(tx Tx) error {
+		return tx.Bucket(SiafundOutputs).ForEach(func(k, _ []byte) error {
+			var id types.SiafundOutputID
+			copy(id[:], k)
+			// getSiafundOutput is used so that the siafund outputs match the
+			// outputs returned by SiafundOutput.
+			sfo, err := getSiafundOutput(tx, id)
+			if err != nil {
+				return err
+			}
+			return fn(id, sfo)
+		})
+	})
+}
+
+// ForEachFileContract calls fn on every open file contract. Iteration stops if
+// fn returns an error, and the error is returned. All of the file contracts
+// are read from a single snapshot of the consensus set.
+func (cs *ConsensusSet) ForEachFileContract(fn func(types.FileContractID, types.FileContract) error) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	return cs.db.View(func(tx Tx) error {
+		return tx.Bucket(FileContracts).ForEach(func(k, v []byte) error {
+			var id types.FileContractID
+			var fc types.FileContract
+			copy(id[:], k)
+			if err := encoding.Unmarshal(v, &fc); err != nil {
+				return err
+			}
+			return fn(id, fc)
+		})
+	})
+}
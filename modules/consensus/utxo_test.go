@@ -0,0 +1,119 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestUTXOQueries probes the output lookup and iteration methods of the
+// consensus set.
+func TestUTXOQueries(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Every siacoin output returned by the iterator should also be returned by
+	// the lookup.
+	var numSCOs int
+	err = cst.cs.ForEachSiacoinOutput(func(id types.SiacoinOutputID, sco types.SiacoinOutput) error {
+		numSCOs++
+		lookup, exists := cst.cs.SiacoinOutput(id)
+		if !exists || lookup.Value.Cmp(sco.Value) != 0 || lookup.UnlockHash != sco.UnlockHash {
+			return errors.New("siacoin output lookup does not match iterator")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numSCOs == 0 {
+		t.Fatal("no siacoin outputs were found")
+	}
+
+	// The siafund outputs should add up to the total number of siafunds.
+	total := types.ZeroCurrency
+	err = cst.cs.ForEachSiafundOutput(func(id types.SiafundOutputID, sfo types.SiafundOutput) error {
+		total = total.Add(sfo.Value)
+		if _, exists := cst.cs.SiafundOutput(id); !exists {
+			return errors.New("siafund output lookup failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(types.SiafundCount) != 0 {
+		t.Fatal("siafund outputs do not add up to the siafund count:", total)
+	}
+	if err := cst.cs.ForEachFileContract(func(types.FileContractID, types.FileContract) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lookups of unknown ids should fail.
+	if _, exists := cst.cs.SiacoinOutput(types.SiacoinOutputID{}); exists {
+		t.Error("unknown siacoin output was found")
+	}
+	if _, exists := cst.cs.SiafundOutput(types.SiafundOutputID{}); exists {
+		t.Error("unknown siafund output was found")
+	}
+	if _, exists := cst.cs.FileContract(types.FileContractID{}); exists {
+		t.Error("unknown file contract was found")
+	}
+
+	// Iteration should stop at the first error.
+	errStop := errors.New("stop")
+	var calls int
+	err = cst.cs.ForEachSiacoinOutput(func(types.SiacoinOutputID, types.SiacoinOutput) error {
+		calls++
+		return errStop
+	})
+	if err != errStop || calls != 1 {
+		t.Fatal("iteration did not stop at the first error:", err, calls)
+	}
+}
+
+// TestDelayedOutputs checks that the miner payouts of a block are returned as
+// delayed outputs maturing MaturityDelay blocks later.
+func TestDelayedOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dscos, err := cst.cs.DelayedOutputs(cst.cs.Height() + types.MaturityDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, payout := range b.MinerPayouts {
+		sco, exists := dscos[b.MinerPayoutID(uint64(i))]
+		if !exists || sco.Value.Cmp(payout.Value) != 0 || sco.UnlockHash != payout.UnlockHash {
+			t.Fatal("miner payout is not a delayed output")
+		}
+	}
+
+	// There are no delayed outputs for heights that have passed.
+	dscos, err = cst.cs.DelayedOutputs(cst.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dscos) != 0 {
+		t.Fatal("delayed outputs were returned for the current height:", dscos)
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
-
-	"github.com/coreos/bbolt"
 )
 
 var (
@@ -18,7 +16,6 @@ var (
 	errInvalidStorageProof        = errors.New("provided storage proof is invalid")
 	errLateRevision               = errors.New("file contract revision submitted after deadline")
 	errLowRevisionNumber          = errors.New("transaction has a file contract with an outdated revision number")
-	errMissingSiacoinOutput       = errors.New("transaction spends a nonexisting siacoin output")
 	errMissingSiafundOutput       = errors.New("transaction spends a nonexisting siafund output")
 	errSiacoinInputOutputMismatch = errors.New("siacoin inputs do not equal siacoin outputs for transaction")
 	errSiafundInputOutputMismatch = errors.New("siafund inputs do not equal siafund outputs for transaction")
@@ -29,14 +26,14 @@ var (
 
 // validSiacoins checks that the siacoin inputs and outputs are valid in the
 // context of the current consensus set.
-func validSiacoins(tx *bolt.Tx, t types.Transaction) error {
+func validSiacoins(tx Tx, t types.Transaction) error {
 	scoBucket := tx.Bucket(SiacoinOutputs)
 	var inputSum types.Currency
 	for _, sci := range t.SiacoinInputs {
 		// Check that the input spends an existing output.
 		scoBytes := scoBucket.Get(sci.ParentID[:])
 		if scoBytes == nil {
-			return errMissingSiacoinOutput
+			return modules.ErrMissingSiacoinOutput
 		}
 
 		// Check that the unlock conditions match the required unlock hash.
@@ -59,7 +56,7 @@ func validSiacoins(tx *bolt.Tx, t types.Transaction) error {
 
 // storageProofSegment returns the index of the segment that needs to be proven
 // exists in a file contract.
-func storageProofSegment(tx *bolt.Tx, fcid types.FileContractID) (uint64, error) {
+func storageProofSegment(tx Tx, fcid types.FileContractID) (uint64, error) {
 	// Check that the parent file contract exists.
 	fcBucket := tx.Bucket(FileContracts)
 	fcBytes := fcBucket.Get(fcid[:])
@@ -107,7 +104,7 @@ func storageProofSegment(tx *bolt.Tx, fcid types.FileContractID) (uint64, error)
 // zero. A hardfork was added triggering at block 100,000 to enable an
 // optimization where hosts could submit empty storage proofs for files of size
 // 0, saving space on the blockchain in conditions where the renter is content.
-func validStorageProofs100e3(tx *bolt.Tx, t types.Transaction) error {
+func validStorageProofs100e3(tx Tx, t types.Transaction) error {
 	for _, sp := range t.StorageProofs {
 		// Check that the storage proof itself is valid.
 		segmentIndex, err := storageProofSegment(tx, sp.ParentID)
@@ -141,7 +138,7 @@ func validStorageProofs100e3(tx *bolt.Tx, t types.Transaction) error {
 		// crypto.SegmentSize bytes, because the segmentLen would be set to 0
 		// instead of crypto.SegmentSize, due to an error with the modulus
 		// math. This new error has been fixed with the block 100,000 hardfork.
-		if (build.Release == "standard" && blockHeight(tx) < 21e3) || (build.Release == "testing" && blockHeight(tx) < 10) {
+		if !types.RuleStorageProofFinalSegment.Active(blockHeight(tx)) {
 			segmentLen = uint64(crypto.SegmentSize)
 		}
 
@@ -162,8 +159,8 @@ func validStorageProofs100e3(tx *bolt.Tx, t types.Transaction) error {
 
 // validStorageProofs checks that the storage proofs are valid in the context
 // of the consensus set.
-func validStorageProofs(tx *bolt.Tx, t types.Transaction) error {
-	if (build.Release == "standard" && blockHeight(tx) < 100e3) || (build.Release == "testing" && blockHeight(tx) < 10) {
+func validStorageProofs(tx Tx, t types.Transaction) error {
+	if !types.RuleStorageProofSegmentFix.Active(blockHeight(tx)) {
 		return validStorageProofs100e3(tx, t)
 	}
 
@@ -207,7 +204,7 @@ func validStorageProofs(tx *bolt.Tx, t types.Transaction) error {
 
 // validFileContractRevision checks that each file contract revision is valid
 // in the context of the current consensus set.
-func validFileContractRevisions(tx *bolt.Tx, t types.Transaction) error {
+func validFileContractRevisions(tx Tx, t types.Transaction) error {
 	for _, fcr := range t.FileContractRevisions {
 		fc, err := getFileContract(tx, fcr.ParentID)
 		if err != nil {
@@ -256,7 +253,7 @@ func validFileContractRevisions(tx *bolt.Tx, t types.Transaction) error {
 
 // validSiafunds checks that the siafund portions of the transaction are valid
 // in the context of the consensus set.
-func validSiafunds(tx *bolt.Tx, t types.Transaction) (err error) {
+func validSiafunds(tx Tx, t types.Transaction) (err error) {
 	// Compare the number of input siafunds to the output siafunds.
 	var siafundInputSum types.Currency
 	var siafundOutputSum types.Currency
@@ -284,17 +281,22 @@ func validSiafunds(tx *bolt.Tx, t types.Transaction) (err error) {
 
 // validTransaction checks that all fields are valid within the current
 // consensus state. If not an error is returned.
-func validTransaction(tx *bolt.Tx, t types.Transaction) error {
+func validTransaction(tx Tx, t types.Transaction) error {
 	// StandaloneValid will check things like signatures and properties that
 	// should be inherent to the transaction. (storage proof rules, etc.)
 	err := t.StandaloneValid(blockHeight(tx))
 	if err != nil {
 		return err
 	}
+	return validTransactionState(tx, t)
+}
 
-	// Check that each portion of the transaction is legal given the current
-	// consensus set.
-	err = validSiacoins(tx, t)
+// validTransactionState checks that each portion of the transaction is legal
+// given the current consensus set. Unlike validTransaction, the standalone
+// checks are skipped, so it should only be used for transactions that have
+// already passed StandaloneValid at the current height.
+func validTransactionState(tx Tx, t types.Transaction) error {
+	err := validSiacoins(tx, t)
 	if err != nil {
 		return err
 	}
@@ -331,7 +333,7 @@ func (cs *ConsensusSet) tryTransactionSet(txns []types.Transaction) (modules.Con
 	// manually manage the tx instead of using 'Update', but that has safety
 	// concerns and is more difficult to implement correctly.
 	errSuccess := errors.New("success")
-	err := cs.db.Update(func(tx *bolt.Tx) error {
+	err := cs.db.Update(func(tx Tx) error {
 		diffHolder.Height = blockHeight(tx)
 		for _, txn := range txns {
 			err := validTransaction(tx, txn)
@@ -5,10 +5,9 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
-
-	"github.com/coreos/bbolt"
 )
 
 // TestTryValidTransactionSet submits a valid transaction set to the
@@ -342,9 +341,9 @@ func TestValidSiacoins(t *testing.T) {
 	txn := types.Transaction{
 		SiacoinInputs: []types.SiacoinInput{{}},
 	}
-	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+	err = cst.cs.db.View(func(tx Tx) error {
 		err := validSiacoins(tx, txn)
-		if err != errMissingSiacoinOutput {
+		if err != modules.ErrMissingSiacoinOutput {
 			t.Fatal(err)
 		}
 		return nil
@@ -363,7 +362,7 @@ func TestValidSiacoins(t *testing.T) {
 			ParentID: scoid,
 		}},
 	}
-	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+	err = cst.cs.db.View(func(tx Tx) error {
 		err := validSiacoins(tx, txn)
 		if err != errWrongUnlockConditions {
 			t.Fatal(err)
@@ -380,7 +379,7 @@ func TestValidSiacoins(t *testing.T) {
 			Value: types.NewCurrency64(1),
 		}},
 	}
-	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+	err = cst.cs.db.View(func(tx Tx) error {
 		err := validSiacoins(tx, txn)
 		if err != errSiacoinInputOutputMismatch {
 			t.Fatal(err)
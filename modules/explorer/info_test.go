@@ -75,6 +75,33 @@ func TestBlockFacts(t *testing.T) {
 	}
 }
 
+// TestBlockFactsHashrate checks that the block facts report the hashrate
+// estimated by the consensus set.
+func TestBlockFactsHashrate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for et.cs.Height() <= hashrateEstimationBlocks {
+		b, _ := et.miner.FindBlock()
+		if err := et.cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	facts := et.explorer.LatestBlockFacts()
+	hashrate, err := et.cs.EstimatedHashrateAt(facts.Height, hashrateEstimationBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facts.EstimatedHashrate.IsZero() || !facts.EstimatedHashrate.Equals(hashrate) {
+		t.Fatal("wrong hashrate in block facts:", facts.EstimatedHashrate, hashrate)
+	}
+}
+
 // TestFileContractPayouts checks that file contract outputs are tracked by the explorer
 func TestFileContractPayoutsMissingProof(t *testing.T) {
 	if testing.Short() {
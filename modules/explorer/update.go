@@ -475,25 +475,13 @@ func dbCalculateBlockFacts(tx *bolt.Tx, cs modules.ConsensusSet, block types.Blo
 	}
 	bf.MaturityTimestamp = maturityTimestamp
 
-	// calculate hashrate by averaging last 'hashrateEstimationBlocks' blocks
+	// estimate the hashrate from the last 'hashrateEstimationBlocks' blocks
 	var estimatedHashrate types.Currency
 	if bf.Height > hashrateEstimationBlocks {
-		var totalDifficulty = bf.Target
-		var oldestTimestamp types.Timestamp
-		for i := types.BlockHeight(1); i < hashrateEstimationBlocks; i++ {
-			b, exists := cs.BlockAtHeight(bf.Height - i)
-			if !exists {
-				panic(fmt.Sprint("ConsensusSet is missing block at height", bf.Height-hashrateEstimationBlocks))
-			}
-			target, exists := cs.ChildTarget(b.ParentID)
-			if !exists {
-				panic(fmt.Sprint("ConsensusSet is missing target of known block", b.ParentID))
-			}
-			totalDifficulty = totalDifficulty.AddDifficulties(target)
-			oldestTimestamp = b.Timestamp
+		estimatedHashrate, err = cs.EstimatedHashrateAt(bf.Height, hashrateEstimationBlocks)
+		if err != nil {
+			panic(fmt.Sprint("ConsensusSet is unable to estimate the hashrate at height ", bf.Height, ": ", err))
 		}
-		secondsPassed := bf.Timestamp - oldestTimestamp
-		estimatedHashrate = totalDifficulty.Difficulty().Div64(uint64(secondsPassed))
 	}
 	bf.EstimatedHashrate = estimatedHashrate
 
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"net"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 )
@@ -12,6 +13,38 @@ const (
 	GatewayDir = "gateway"
 )
 
+const (
+	// PenaltyInvalidBlock is the penalty for a peer that relays an invalid
+	// block or block header. A single invalid block gets the peer banned.
+	PenaltyInvalidBlock = 100
+
+	// PenaltyMalformedRPC is the penalty for a peer that sends an RPC that
+	// cannot be understood.
+	PenaltyMalformedRPC = 20
+
+	// PenaltyStall is the penalty for a peer that stops relaying blocks in
+	// the middle of the blockchain download.
+	PenaltyStall = 25
+
+	// PenaltyTransactionFlood is the penalty for a peer that keeps relaying
+	// invalid or low-fee transaction sets after exhausting its allowance in
+	// the transaction pool.
+	PenaltyTransactionFlood = 10
+)
+
+const (
+	// AddressFamilyIPv4 is the family of IPv4 addresses, including
+	// IPv4-mapped IPv6 addresses.
+	AddressFamilyIPv4 AddressFamily = "ipv4"
+
+	// AddressFamilyIPv6 is the family of IPv6 addresses.
+	AddressFamilyIPv6 AddressFamily = "ipv6"
+)
+
+// DefaultSeedPort is the port of the peers returned by a DNS seed that does
+// not specify a port.
+const DefaultSeedPort = "9981"
+
 var (
 	// BootstrapPeers is a list of peers that can be used to find other peers -
 	// when a client first connects to the network, the only options for
@@ -126,6 +159,18 @@ var (
 		Dev:     []NetAddress(nil),
 		Testing: []NetAddress(nil),
 	}).([]NetAddress)
+
+	// DNSSeeds are hostnames that resolve to the addresses of stable peers.
+	// Seeds are resolved when the gateway bootstraps, so that the network
+	// can be joined even if every hardcoded bootstrap peer is offline. A
+	// seed may include a port; otherwise, the peers are assumed to listen on
+	// DefaultSeedPort. No seeds are operated yet, so operators must
+	// configure their own through the gateway settings.
+	DNSSeeds = build.Select(build.Var{
+		Standard: []string(nil),
+		Dev:     []string(nil),
+		Testing: []string(nil),
+	}).([]string)
 )
 
 type (
@@ -135,6 +180,100 @@ type (
 		Local      bool       `json:"local"`
 		NetAddress NetAddress `json:"netaddress"`
 		Version    string     `json:"version"`
+
+		// ProtocolVersion and Capabilities are advertised by the peer during
+		// the connection handshake. Peers that predate capability
+		// negotiation have a ProtocolVersion of 0 and no capabilities.
+		ProtocolVersion uint64   `json:"protocolversion"`
+		Capabilities    []string `json:"capabilities"`
+
+		// Encrypted is true if the connection to the peer is encrypted.
+		Encrypted bool `json:"encrypted"`
+	}
+
+	// A GatewaySubscriber is notified every time the Gateway connects to or
+	// disconnects from a peer.
+	GatewaySubscriber interface {
+		// OnConnect is called after the Gateway connected to a peer.
+		OnConnect(Peer)
+
+		// OnDisconnect is called after the Gateway disconnected from a
+		// peer, for whatever reason.
+		OnDisconnect(Peer)
+	}
+
+	// PeerStats contains the traffic statistics of a connected peer. Bytes
+	// are counted on the wire, including protocol overhead. RPCsCalled is
+	// the number of RPCs that the gateway called on the peer, and
+	// RPCsHandled is the number of RPCs that the peer called on the gateway.
+	// Latency is the round trip time measured during the connection
+	// handshake.
+	PeerStats struct {
+		NetAddress    NetAddress    `json:"netaddress"`
+		Inbound       bool          `json:"inbound"`
+		ConnectedAt   time.Time     `json:"connectedat"`
+		BytesSent     uint64        `json:"bytessent"`
+		BytesReceived uint64        `json:"bytesreceived"`
+		RPCsCalled    uint64        `json:"rpcscalled"`
+		RPCsHandled   uint64        `json:"rpcshandled"`
+		Latency       time.Duration `json:"latency"`
+	}
+
+	// GatewaySettings control the behavior of the Gateway. Bandwidth limits
+	// are in bytes per second, and a limit of zero means that there is no
+	// limit. The peer limits are the number of inbound and outbound slots,
+	// and a limit of zero selects the default.
+	GatewaySettings struct {
+		MaxDownloadSpeed     int64 `json:"maxdownloadspeed"`
+		MaxUploadSpeed       int64 `json:"maxuploadspeed"`
+		PeerMaxDownloadSpeed int64 `json:"peermaxdownloadspeed"`
+		PeerMaxUploadSpeed   int64 `json:"peermaxuploadspeed"`
+
+		MaxInboundPeers  int `json:"maxinboundpeers"`
+		MaxOutboundPeers int `json:"maxoutboundpeers"`
+
+		// PreferredAddressFamily is the address family that the gateway
+		// tries first when connecting to peers and discovering its own
+		// address. If AddressFamilyOnly is set, the gateway only connects
+		// to peers of the preferred family, which allows hosts with only
+		// IPv6 connectivity to avoid dialing IPv4 peers.
+		PreferredAddressFamily AddressFamily `json:"preferredaddressfamily"`
+		AddressFamilyOnly      bool          `json:"addressfamilyonly"`
+
+		// DNSSeeds are resolved in addition to the default DNS seeds when
+		// the gateway bootstraps. If DisableDNSSeeds is set, no seeds are
+		// resolved.
+		DNSSeeds        []string `json:"dnsseeds"`
+		DisableDNSSeeds bool     `json:"disablednsseeds"`
+	}
+
+	// An AddressFamily is an IP address family. The empty AddressFamily
+	// means that there is no preference.
+	AddressFamily string
+
+	// A PeerBan is a host that the gateway refuses to connect to until the
+	// ban expires, after its peers accumulated too many penalty points.
+	PeerBan struct {
+		Host   string    `json:"host"`
+		Expiry time.Time `json:"expiry"`
+	}
+
+	// GatewayPeerLists contains the addresses that the operator has
+	// blacklisted or whitelisted. Hosts are matched by IP address, so listing
+	// an address applies to every port on its host. If WhitelistOnly is set,
+	// the gateway only connects to whitelisted hosts.
+	GatewayPeerLists struct {
+		Blacklist     []NetAddress `json:"blacklist"`
+		Whitelist     []NetAddress `json:"whitelist"`
+		WhitelistOnly bool         `json:"whitelistonly"`
+	}
+
+	// A BootstrapNode is a node that the operator has chosen for the gateway
+	// to rejoin the network through. Pinned bootstrap nodes are reconnected
+	// whenever the connection to them is lost.
+	BootstrapNode struct {
+		NetAddress NetAddress `json:"netaddress"`
+		Pinned     bool       `json:"pinned"`
 	}
 
 	// A PeerConn is the connection type used when communicating with peers during
@@ -153,6 +292,13 @@ type (
 	// keeping the connection open after all necessary I/O has been performed.
 	RPCFunc func(PeerConn) error
 
+	// TopicHandler is the type signature of functions that handle the
+	// messages broadcast on a topic. source is the peer that relayed the
+	// message. The message is only relayed to other peers if the handler
+	// returns nil. Handlers that receive an invalid message should penalize
+	// the source themselves.
+	TopicHandler func(source NetAddress, payload []byte) error
+
 	// A Gateway facilitates the interactions between the local node and remote
 	// nodes (peers). It relays incoming blocks and transactions to local modules,
 	// and broadcasts outgoing blocks and transactions to peers. In a broad sense,
@@ -168,9 +314,22 @@ type (
 		// Address returns the Gateway's address.
 		Address() NetAddress
 
+		// ListenAddresses returns the addresses that the Gateway listens on
+		// for new peers.
+		ListenAddresses() []NetAddress
+
+		// SetListenAddresses changes the addresses that the Gateway listens
+		// on. Existing peers stay connected. The port of the first address
+		// is announced to peers.
+		SetListenAddresses([]string) error
+
 		// Peers returns the addresses that the Gateway is currently connected to.
 		Peers() []Peer
 
+		// PeerStats returns the traffic statistics of the peers that the
+		// Gateway is currently connected to.
+		PeerStats() []PeerStats
+
 		// RegisterRPC registers a function to handle incoming connections that
 		// supply the given RPC ID.
 		RegisterRPC(string, RPCFunc)
@@ -191,6 +350,27 @@ type (
 		// removed with UnregisterRPC. If the RPC does not exist no action is taken.
 		UnregisterConnectCall(string)
 
+		// RegisterCapability adds a capability to the set that the Gateway
+		// advertises to the peers it connects to from then on.
+		RegisterCapability(string)
+
+		// UnregisterCapability stops the Gateway from advertising a
+		// capability to new peers.
+		UnregisterCapability(string)
+
+		// PeerCapable returns true if the Gateway is connected to the peer
+		// and the peer advertised the capability.
+		PeerCapable(NetAddress, string) bool
+
+		// Subscribe adds a subscriber that is notified of every peer that
+		// the Gateway connects to or disconnects from. Events are delivered
+		// in order from a separate thread, after the Gateway was unlocked.
+		Subscribe(GatewaySubscriber)
+
+		// Unsubscribe removes a subscriber. If the subscriber is not found,
+		// no action is taken.
+		Unsubscribe(GatewaySubscriber)
+
 		// RPC calls an RPC on the given address. RPC cannot be called on an
 		// address that the Gateway is not connected to.
 		RPC(NetAddress, string, RPCFunc) error
@@ -199,10 +379,84 @@ type (
 		// given peers in parallel.
 		Broadcast(name string, obj interface{}, peers []Peer)
 
+		// RegisterTopic registers a handler for the messages broadcast on a
+		// topic. The Gateway only relays messages on topics that have a
+		// handler.
+		RegisterTopic(string, TopicHandler)
+
+		// UnregisterTopic removes the handler of a topic.
+		UnregisterTopic(string)
+
+		// BroadcastTopic broadcasts a message on a topic to all peers. Each
+		// peer passes the message to its handler for the topic and relays
+		// it further, at most once per message.
+		BroadcastTopic(topic string, payload []byte) error
+
 		// Online returns true if the gateway is connected to remote hosts
 		Online() bool
 
+		// AddBootstrapNode adds a node to the set of bootstrap nodes, which
+		// are saved with the node list and never pruned.
+		AddBootstrapNode(NetAddress) error
+
+		// BootstrapNodes returns the bootstrap nodes of the gateway.
+		BootstrapNodes() []BootstrapNode
+
+		// PinBootstrapNode sets whether the gateway should stay connected to
+		// a bootstrap node.
+		PinBootstrapNode(NetAddress, bool) error
+
+		// RemoveBootstrapNode removes a bootstrap node from the node list.
+		RemoveBootstrapNode(NetAddress) error
+
+		// Settings returns the Gateway's current settings.
+		Settings() GatewaySettings
+
+		// SetSettings sets the Gateway's settings.
+		SetSettings(GatewaySettings) error
+
+		// PenalizePeer adds penalty points to the host of a misbehaving peer.
+		// Hosts that accumulate too many points are banned for a while.
+		PenalizePeer(NetAddress, int)
+
+		// Bans returns the hosts that are currently banned.
+		Bans() []PeerBan
+
+		// ClearBans lifts all bans and forgets all penalty points.
+		ClearBans() error
+
+		// Blacklist adds an address to the blacklist. The gateway disconnects
+		// from the address's host and refuses to connect to it.
+		Blacklist(NetAddress) error
+
+		// Whitelist adds an address to the whitelist. Whitelisted hosts are
+		// never banned.
+		Whitelist(NetAddress) error
+
+		// RemoveFromBlacklist removes an address from the blacklist.
+		RemoveFromBlacklist(NetAddress) error
+
+		// RemoveFromWhitelist removes an address from the whitelist.
+		RemoveFromWhitelist(NetAddress) error
+
+		// PeerLists returns the Gateway's blacklist and whitelist.
+		PeerLists() GatewayPeerLists
+
+		// SetWhitelistOnly sets whether the Gateway only connects to
+		// whitelisted peers.
+		SetWhitelistOnly(bool) error
+
 		// Close safely stops the Gateway's listener process.
 		Close() error
 	}
 )
+
+// HasCapability returns true if the peer advertised the capability.
+func (p Peer) HasCapability(capability string) bool {
+	for _, c := range p.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package gateway
+
+// addrfamily.go implements the address family support of the gateway. The
+// gateway listens on both IPv4 and IPv6 when it is given an unspecified
+// address, and shares the addresses of both families with its peers. Hosts
+// with only one working address family can make the gateway prefer that
+// family, or restrict it to that family, so that they do not waste outbound
+// connection attempts on peers that they cannot reach.
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errInvalidAddressFamily = errors.New("address family must be empty, \"ipv4\" or \"ipv6\"")
+	errNoAddressFamily      = errors.New("a preferred address family is required to restrict the gateway to it")
+	errZonedAddress         = errors.New("addresses with an IPv6 zone cannot be shared")
+)
+
+// addressFamily returns the address family of a host, or the empty
+// AddressFamily if the host is not an IP address.
+func addressFamily(host string) modules.AddressFamily {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	} else if ip.To4() != nil {
+		return modules.AddressFamilyIPv4
+	}
+	return modules.AddressFamilyIPv6
+}
+
+// canonicalAddress returns the canonical form of an address that was received
+// from a peer, so that the same node is not added twice under different
+// spellings of its IP address. IPv4-mapped IPv6 addresses are converted to
+// IPv4 addresses, and IPv6 addresses are written in their shortest form.
+func canonicalAddress(addr modules.NetAddress) (modules.NetAddress, error) {
+	host, port, err := net.SplitHostPort(string(addr))
+	if err != nil {
+		return "", err
+	} else if strings.Contains(host, "%") {
+		return "", errZonedAddress
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return modules.NetAddress(net.JoinHostPort(host, port)), nil
+}
+
+// checkAddressFamily returns an error if the address family settings are
+// invalid.
+func checkAddressFamily(settings modules.GatewaySettings) error {
+	switch settings.PreferredAddressFamily {
+	case "", modules.AddressFamilyIPv4, modules.AddressFamilyIPv6:
+	default:
+		return errInvalidAddressFamily
+	}
+	if settings.AddressFamilyOnly && settings.PreferredAddressFamily == "" {
+		return errNoAddressFamily
+	}
+	return nil
+}
+
+// preferredFamily returns the preferred address family of the gateway, and
+// whether the gateway is restricted to it.
+func (g *Gateway) preferredFamily() (family modules.AddressFamily, only bool) {
+	settings := g.rl.limits()
+	return settings.PreferredAddressFamily, settings.AddressFamilyOnly
+}
+
+// dialableFamily returns false if the gateway is restricted to an address
+// family that the host does not belong to. Hosts that are not IP addresses,
+// such as onion addresses, are always dialable.
+func (g *Gateway) dialableFamily(host string) bool {
+	family, only := g.preferredFamily()
+	hostFamily := addressFamily(host)
+	return !only || hostFamily == "" || hostFamily == family
+}
@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestCanonicalAddress checks that addresses received from peers are converted
+// to their canonical form.
+func TestCanonicalAddress(t *testing.T) {
+	tests := []struct {
+		addr      modules.NetAddress
+		canonical modules.NetAddress
+		err       bool
+	}{
+		{"1.2.3.4:9981", "1.2.3.4:9981", false},
+		{"[::ffff:1.2.3.4]:9981", "1.2.3.4:9981", false},
+		{"[2001:0db8:0000::1]:9981", "[2001:db8::1]:9981", false},
+		{"[2001:DB8::1]:9981", "[2001:db8::1]:9981", false},
+		{"example.com:9981", "example.com:9981", false},
+		{"[fe80::1%eth0]:9981", "", true},
+		{"2001:db8::1", "", true},
+	}
+	for _, test := range tests {
+		canonical, err := canonicalAddress(test.addr)
+		if (err != nil) != test.err {
+			t.Errorf("canonicalAddress(%v): unexpected error %v", test.addr, err)
+		} else if canonical != test.canonical {
+			t.Errorf("canonicalAddress(%v): expected %v, got %v", test.addr, test.canonical, canonical)
+		}
+	}
+}
+
+// TestCheckAddressFamily checks that invalid address family settings are
+// rejected.
+func TestCheckAddressFamily(t *testing.T) {
+	tests := []struct {
+		settings modules.GatewaySettings
+		err      error
+	}{
+		{modules.GatewaySettings{}, nil},
+		{modules.GatewaySettings{PreferredAddressFamily: modules.AddressFamilyIPv6}, nil},
+		{modules.GatewaySettings{PreferredAddressFamily: modules.AddressFamilyIPv4, AddressFamilyOnly: true}, nil},
+		{modules.GatewaySettings{PreferredAddressFamily: "ipx"}, errInvalidAddressFamily},
+		{modules.GatewaySettings{AddressFamilyOnly: true}, errNoAddressFamily},
+	}
+	for _, test := range tests {
+		if err := checkAddressFamily(test.settings); err != test.err {
+			t.Errorf("checkAddressFamily(%v): expected %v, got %v", test.settings, test.err, err)
+		}
+	}
+}
+
+// TestAddressFamilyNodeList checks that the peer manager tries the nodes of
+// the preferred address family first, and skips the nodes of other families
+// when the gateway is restricted to its preferred family.
+func TestAddressFamilyNodeList(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	g.mu.Lock()
+	for _, addr := range []modules.NetAddress{"111.111.111.111:1", "[2001:db8::1]:1", "112.112.112.112:1", "[2001:db8::2]:1"} {
+		if err := g.addNode(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.mu.Unlock()
+
+	if err := g.SetSettings(modules.GatewaySettings{PreferredAddressFamily: modules.AddressFamilyIPv6}); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.RLock()
+	nodes := g.buildPeerManagerNodeList()
+	g.mu.RUnlock()
+	if len(nodes) != 4 {
+		t.Fatal("expected 4 nodes, got", nodes)
+	}
+	for i, addr := range nodes {
+		if isIPv6 := addressFamily(addr.Host()) == modules.AddressFamilyIPv6; isIPv6 != (i < 2) {
+			t.Fatal("IPv6 nodes are not at the front of the node list:", nodes)
+		}
+	}
+
+	if err := g.SetSettings(modules.GatewaySettings{PreferredAddressFamily: modules.AddressFamilyIPv4, AddressFamilyOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.RLock()
+	nodes = g.buildPeerManagerNodeList()
+	g.mu.RUnlock()
+	if len(nodes) != 2 {
+		t.Fatal("expected 2 nodes, got", nodes)
+	}
+	for _, addr := range nodes {
+		if addressFamily(addr.Host()) != modules.AddressFamilyIPv4 {
+			t.Fatal("node list contains a node of another address family:", nodes)
+		}
+	}
+}
+
+// TestIPv6Peers checks that gateways can connect to each other over IPv6 and
+// share their IPv6 addresses.
+func TestIPv6Peers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1, err := New("[::1]:0", false, build.TempDir("gateway", t.Name()+"1"))
+	if err != nil {
+		t.Skip("IPv6 is not available:", err)
+	}
+	defer g1.Close()
+	g2, err := New("[::1]:0", false, build.TempDir("gateway", t.Name()+"2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g2.Close()
+	if addressFamily(g1.Address().Host()) != modules.AddressFamilyIPv6 {
+		t.Fatal("gateway is not listening on IPv6:", g1.Address())
+	}
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.RPC(g2.Address(), "ShareNodes", g1.requestNodes); err != nil {
+		t.Fatal(err)
+	}
+	g1.mu.RLock()
+	_, exists := g1.nodes[g2.Address()]
+	g1.mu.RUnlock()
+	if !exists {
+		t.Fatal("IPv6 peer was not added to the node list")
+	}
+}
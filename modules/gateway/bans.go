@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// bansFile is the name of the file that contains the banned hosts.
+	bansFile = "bans.json"
+)
+
+var (
+	// bansMetadata contains the header and version strings that identify the
+	// gateway's ban list.
+	bansMetadata = persist.Metadata{
+		Header:  "Sia Gateway Bans",
+		Version: "1.3.3",
+	}
+
+	errPeerBanned = errors.New("peer is banned")
+)
+
+// misbehavior tracks the penalty points that a host has accumulated.
+type misbehavior struct {
+	points      int
+	lastPenalty time.Time
+}
+
+// isBanned returns true if the host is currently banned.
+func (g *Gateway) isBanned(host string) bool {
+	expiry, exists := g.bans[host]
+	return exists && time.Now().Before(expiry)
+}
+
+// pruneMisbehavior removes the expired bans and the penalty points that have
+// fallen out of the penalty window.
+func (g *Gateway) pruneMisbehavior() {
+	for host, expiry := range g.bans {
+		if !time.Now().Before(expiry) {
+			delete(g.bans, host)
+		}
+	}
+	for host, m := range g.misbehavior {
+		if time.Since(m.lastPenalty) > penaltyWindow {
+			delete(g.misbehavior, host)
+		}
+	}
+}
+
+// banHost bans a host until the expiry. The gateway disconnects from all peers
+// on the host, and removes the host's nodes from the node list unless they are
+// bootstrap nodes.
+func (g *Gateway) banHost(host string, expiry time.Time) {
+	delete(g.misbehavior, host)
+	g.bans[host] = expiry
+	for addr, p := range g.peers {
+		if addr.Host() == host {
+			p.sess.Close()
+			g.removePeer(addr)
+		}
+	}
+	for addr, n := range g.nodes {
+		if addr.Host() == host && !n.Bootstrap {
+			g.removeNode(addr)
+		}
+	}
+	g.log.Printf("INFO: banned %v until %v", host, expiry)
+}
+
+// managedPenalizePeer adds penalty points to the host of a peer, and bans the
+// host if it has accumulated banThreshold points within the penalty window.
+func (g *Gateway) managedPenalizePeer(addr modules.NetAddress, points int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Whitelisted hosts are trusted by the operator and are never banned.
+	host := addr.Host()
+	if g.isBanned(host) || listedHost(g.whitelist, host) {
+		return
+	}
+	g.pruneMisbehavior()
+	m, exists := g.misbehavior[host]
+	if !exists {
+		m = new(misbehavior)
+		g.misbehavior[host] = m
+	}
+	m.points += points
+	m.lastPenalty = time.Now()
+	g.log.Debugf("INFO: penalized %v with %v points, %v points in total", addr, points, m.points)
+	if m.points < banThreshold {
+		return
+	}
+
+	g.banHost(host, time.Now().Add(banDuration))
+	if err := g.saveBans(); err != nil {
+		g.log.Println("ERROR: unable to save the ban list:", err)
+	}
+}
+
+// loadBans loads the gateway's ban list from disk.
+func (g *Gateway) loadBans() error {
+	var bans []modules.PeerBan
+	err := persist.LoadJSON(bansMetadata, &bans, filepath.Join(g.persistDir, bansFile))
+	if err != nil {
+		return err
+	}
+	for _, ban := range bans {
+		g.bans[ban.Host] = ban.Expiry
+	}
+	return nil
+}
+
+// saveBans saves the gateway's ban list to disk.
+func (g *Gateway) saveBans() error {
+	return persist.SaveJSON(bansMetadata, g.bansList(), filepath.Join(g.persistDir, bansFile))
+}
+
+// bansList returns the bans that have not expired yet, sorted by host.
+func (g *Gateway) bansList() []modules.PeerBan {
+	bans := []modules.PeerBan{}
+	for host, expiry := range g.bans {
+		if time.Now().Before(expiry) {
+			bans = append(bans, modules.PeerBan{
+				Host:   host,
+				Expiry: expiry,
+			})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Host < bans[j].Host
+	})
+	return bans
+}
+
+// PenalizePeer adds penalty points to the host of a misbehaving peer. If the
+// host accumulates banThreshold points before its points are forgotten, the
+// gateway disconnects from the host and refuses to connect to it until the
+// ban expires.
+func (g *Gateway) PenalizePeer(addr modules.NetAddress, points int) {
+	if g.threads.Add() != nil {
+		return
+	}
+	defer g.threads.Done()
+	g.managedPenalizePeer(addr, points)
+}
+
+// Bans returns the hosts that are currently banned.
+func (g *Gateway) Bans() []modules.PeerBan {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.bansList()
+}
+
+// ClearBans lifts all bans and forgets the penalty points of all hosts.
+func (g *Gateway) ClearBans() error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.bans = make(map[string]time.Time)
+	g.misbehavior = make(map[string]*misbehavior)
+	return g.saveBans()
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestPenalizePeer checks that a peer is banned once its host accumulates
+// enough penalty points, that the ban is enforced in both directions and
+// survives a restart, and that bans can be cleared.
+func TestPenalizePeer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A small penalty should not get the peer banned.
+	g1.PenalizePeer(g2.Address(), modules.PenaltyMalformedRPC)
+	if len(g1.Bans()) != 0 {
+		t.Fatal("peer was banned after a small penalty:", g1.Bans())
+	}
+	if len(g1.Peers()) != 1 {
+		t.Fatal("peer was disconnected after a small penalty")
+	}
+
+	// Reaching the threshold should ban the host and disconnect the peer.
+	g1.PenalizePeer(g2.Address(), banThreshold-modules.PenaltyMalformedRPC)
+	bans := g1.Bans()
+	if len(bans) != 1 || bans[0].Host != g2.Address().Host() {
+		t.Fatal("peer was not banned:", bans)
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("banned peer was not disconnected")
+	}
+	if err := g1.Connect(g2.Address()); err != errPeerBanned {
+		t.Fatal("expected errPeerBanned, got", err)
+	}
+	if err := g2.Connect(g1.Address()); err == nil {
+		t.Fatal("banned peer was able to connect")
+	}
+
+	// The ban should be loaded after a restart.
+	if err := g1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g1, err := New("localhost:0", false, g1.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	if len(g1.Bans()) != 1 {
+		t.Fatal("ban was not loaded:", g1.Bans())
+	}
+
+	// Clearing the bans should allow the peers to connect again.
+	if err := g1.ClearBans(); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.Bans()) != 0 {
+		t.Fatal("bans were not cleared:", g1.Bans())
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMisbehaviorExpiry checks that expired bans and stale penalty points are
+// forgotten.
+func TestMisbehaviorExpiry(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	g.mu.Lock()
+	g.bans["111.111.111.111"] = time.Now().Add(-time.Second)
+	g.misbehavior["222.222.222.222"] = &misbehavior{
+		points:      banThreshold - 1,
+		lastPenalty: time.Now().Add(-penaltyWindow - time.Second),
+	}
+	if g.isBanned("111.111.111.111") {
+		t.Fatal("expired ban is still in effect")
+	}
+	g.mu.Unlock()
+	if len(g.Bans()) != 0 {
+		t.Fatal("expired ban was returned:", g.Bans())
+	}
+
+	// The stale points should not count towards a new ban.
+	g.PenalizePeer("222.222.222.222:2222", modules.PenaltyMalformedRPC)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.isBanned("222.222.222.222") {
+		t.Fatal("stale penalty points led to a ban")
+	}
+	if _, exists := g.bans["111.111.111.111"]; exists {
+		t.Fatal("expired ban was not pruned")
+	}
+	if m := g.misbehavior["222.222.222.222"]; m.points != modules.PenaltyMalformedRPC {
+		t.Fatal("stale penalty points were not forgotten:", m.points)
+	}
+}
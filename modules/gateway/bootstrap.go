@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errNotBootstrapNode = errors.New("node is not a bootstrap node")
+)
+
+// AddBootstrapNode adds a node to the set of bootstrap nodes. Bootstrap nodes
+// are saved with the node list, are never pruned, and are the first nodes
+// that the gateway tries to connect to when it needs more peers.
+func (g *Gateway) AddBootstrapNode(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	err := g.addNode(addr)
+	if err != nil && err != errNodeExists {
+		return err
+	}
+	g.nodes[addr].Bootstrap = true
+	return g.saveSync()
+}
+
+// BootstrapNodes returns the bootstrap nodes of the gateway.
+func (g *Gateway) BootstrapNodes() []modules.BootstrapNode {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var nodes []modules.BootstrapNode
+	for _, n := range g.nodes {
+		if n.Bootstrap {
+			nodes = append(nodes, modules.BootstrapNode{
+				NetAddress: n.NetAddress,
+				Pinned:     n.Pinned,
+			})
+		}
+	}
+	return nodes
+}
+
+// PinBootstrapNode sets whether a bootstrap node is pinned. The gateway
+// reconnects to pinned nodes whenever the connection to them is lost, even if
+// it already has enough peers.
+func (g *Gateway) PinBootstrapNode(addr modules.NetAddress, pinned bool) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, exists := g.nodes[addr]
+	if !exists || !n.Bootstrap {
+		return errNotBootstrapNode
+	}
+	n.Pinned = pinned
+	return g.saveSync()
+}
+
+// RemoveBootstrapNode removes a bootstrap node from the node list. An existing
+// connection to the node is not closed. Nodes from modules.BootstrapPeers are
+// added back to the node list when the gateway is started with bootstrapping
+// enabled.
+func (g *Gateway) RemoveBootstrapNode(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, exists := g.nodes[addr]
+	if !exists || !n.Bootstrap {
+		return errNotBootstrapNode
+	}
+	g.removeNode(addr)
+	return g.saveSync()
+}
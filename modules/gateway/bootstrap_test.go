@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestBootstrapNodes checks that bootstrap nodes can be added, pinned and
+// removed, that they survive a restart, and that they are not pruned.
+func TestBootstrapNodes(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+
+	if err := g.AddBootstrapNode(dummyNode); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddBootstrapNode("foo"); err == nil {
+		t.Fatal("expected an invalid address to be rejected")
+	}
+	if err := g.PinBootstrapNode(dummyNode, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PinBootstrapNode("222.222.222.222:2222", true); err != errNotBootstrapNode {
+		t.Fatal("expected errNotBootstrapNode, got", err)
+	}
+
+	// Fill the node list so that pruning is allowed. The bootstrap node
+	// should not be pruned.
+	g.mu.Lock()
+	for i := 0; len(g.nodes) <= pruneNodeListLen; i++ {
+		g.addNode(modules.NetAddress("111.111.111.111:" + strconv.Itoa(2000+i)))
+	}
+	if g.pruneNode(dummyNode) {
+		t.Fatal("bootstrap node was pruned")
+	}
+	if !g.pruneNode("111.111.111.111:2000") {
+		t.Fatal("regular node was not pruned")
+	}
+	g.recordConnectionAttempt(dummyNode, true)
+	g.mu.Unlock()
+
+	// The bootstrap node should be the first node in the peer manager's node
+	// list.
+	g.mu.RLock()
+	nodes := g.buildPeerManagerNodeList()
+	g.mu.RUnlock()
+	if nodes[0] != dummyNode {
+		t.Fatal("pinned node is not at the front of the node list:", nodes[0])
+	}
+
+	// Restart the gateway. The bootstrap node and its score should be loaded
+	// from disk. Reconnection attempts to the unreachable node can only add
+	// failed connections.
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("localhost:0", false, g.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	bns := g.BootstrapNodes()
+	if len(bns) != 1 || bns[0].NetAddress != dummyNode || !bns[0].Pinned {
+		t.Fatal("bootstrap nodes were not loaded:", bns)
+	}
+	g.mu.RLock()
+	successes := g.nodes[dummyNode].SuccessfulConnections
+	g.mu.RUnlock()
+	if successes != 1 {
+		t.Fatal("node score was not loaded, successful connections:", successes)
+	}
+
+	// Remove the bootstrap node.
+	if err := g.RemoveBootstrapNode(dummyNode); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.BootstrapNodes()) != 0 {
+		t.Fatal("bootstrap node was not removed")
+	}
+	if err := g.RemoveBootstrapNode(dummyNode); err != errNotBootstrapNode {
+		t.Fatal("expected errNotBootstrapNode, got", err)
+	}
+}
+
+// TestNodeScore checks that the node score reflects the connection attempts.
+func TestNodeScore(t *testing.T) {
+	var n node
+	if n.score() != 0.5 {
+		t.Fatal("untried node should have a neutral score, got", n.score())
+	}
+	n.SuccessfulConnections = 3
+	good := n.score()
+	n.FailedConnections = 6
+	if bad := n.score(); bad >= good || bad >= 0.5 {
+		t.Fatal("failed connections should lower the score:", good, bad)
+	}
+}
@@ -0,0 +1,242 @@
+package gateway
+
+// buckets.go implements the address tables of the gateway, which limit how
+// much of the node list a single attacker can occupy. Every node is placed in
+// one bucket of either the new table, which holds nodes that the gateway has
+// heard about but never reached, or the tried table, which holds nodes that
+// the gateway has successfully connected to.
+//
+// The bucket of a node in the new table is chosen by the network group of the
+// node and the network group of the peer that shared it, so that the nodes
+// shared by one peer can only occupy a few buckets. The bucket of a node in
+// the tried table is chosen by the network group of the node, so that the
+// nodes in one network group can only occupy a few buckets. Bucket selection
+// is keyed by a secret, so an attacker cannot predict which addresses
+// collide. When a bucket is full, a random entry is evicted to make room.
+//
+// Feeler connections regularly test a random node from the new table, and
+// move it to the tried table if it is reachable. This keeps the tried table
+// filled with honest nodes even if an attacker floods the new table.
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+var (
+	errBucketFull = errors.New("the node's bucket is full")
+
+	// bucketSize is the number of nodes that fit in a bucket.
+	bucketSize = build.Select(build.Var{
+		Standard: 32,
+		Dev:      16,
+		Testing:  32,
+	}).(int)
+
+	// newBucketCount is the number of buckets in the new table.
+	newBucketCount = build.Select(build.Var{
+		Standard: 256,
+		Dev:      32,
+		Testing:  8,
+	}).(int)
+
+	// newBucketsPerSourceGroup is the number of buckets of the new table that
+	// the nodes shared by a single network group can occupy.
+	newBucketsPerSourceGroup = build.Select(build.Var{
+		Standard: 16,
+		Dev:      8,
+		Testing:  2,
+	}).(int)
+
+	// triedBucketCount is the number of buckets in the tried table.
+	triedBucketCount = build.Select(build.Var{
+		Standard: 64,
+		Dev:      16,
+		Testing:  4,
+	}).(int)
+
+	// triedBucketsPerGroup is the number of buckets of the tried table that
+	// the nodes of a single network group can occupy.
+	triedBucketsPerGroup = build.Select(build.Var{
+		Standard: 4,
+		Dev:      2,
+		Testing:  2,
+	}).(int)
+
+	// feelerInterval is the amount of time between feeler connections.
+	feelerInterval = build.Select(build.Var{
+		Standard: 2 * time.Minute,
+		Dev:      30 * time.Second,
+		Testing:  500 * time.Millisecond,
+	}).(time.Duration)
+)
+
+// addressTables holds the buckets of the new and tried tables.
+type addressTables struct {
+	// key randomizes the bucket of each node. It is generated when the
+	// gateway starts, so the buckets are rebuilt when the node list is
+	// loaded.
+	key   crypto.Hash
+	new   []map[modules.NetAddress]struct{}
+	tried []map[modules.NetAddress]struct{}
+}
+
+// newAddressTables returns empty address tables with a random key.
+func newAddressTables() addressTables {
+	at := addressTables{
+		new:   make([]map[modules.NetAddress]struct{}, newBucketCount),
+		tried: make([]map[modules.NetAddress]struct{}, triedBucketCount),
+	}
+	fastrand.Read(at.key[:])
+	for i := range at.new {
+		at.new[i] = make(map[modules.NetAddress]struct{})
+	}
+	for i := range at.tried {
+		at.tried[i] = make(map[modules.NetAddress]struct{})
+	}
+	return at
+}
+
+// networkGroup returns the network group of an address. Addresses in the same
+// network group are likely to be controlled by the same operator. IPv4
+// addresses are grouped by /16 and IPv6 addresses by /32. Local addresses and
+// onion addresses cannot be grouped, so every address is its own group.
+func networkGroup(addr modules.NetAddress) string {
+	ip := net.ParseIP(addr.Host())
+	switch {
+	case ip == nil:
+		return addr.Host()
+	case addr.IsLocal():
+		return string(addr)
+	case ip.To4() != nil:
+		return ip.To4().Mask(net.CIDRMask(16, 32)).String()
+	default:
+		return ip.Mask(net.CIDRMask(32, 128)).String()
+	}
+}
+
+// bucketIndex hashes the objects with the key of the tables and returns the
+// result modulo n.
+func (at *addressTables) bucketIndex(n int, objs ...interface{}) int {
+	h := crypto.HashAll(append([]interface{}{at.key}, objs...)...)
+	return int(encoding.DecUint64(h[:8]) % uint64(n))
+}
+
+// bucket returns the bucket of the tables that the node belongs in.
+func (at *addressTables) bucket(n *node) map[modules.NetAddress]struct{} {
+	group := networkGroup(n.NetAddress)
+	if n.Tried {
+		i := at.bucketIndex(triedBucketsPerGroup, n.NetAddress)
+		return at.tried[at.bucketIndex(triedBucketCount, group, i)]
+	}
+	source := n.Source
+	if source == "" {
+		source = group
+	}
+	i := at.bucketIndex(newBucketsPerSourceGroup, group, source)
+	return at.new[at.bucketIndex(newBucketCount, source, i)]
+}
+
+// insertNode adds a node to the node list and places it in its bucket. If the
+// bucket is full, a random entry is evicted. Entries evicted from the tried
+// table are moved back to the new table, while entries evicted from the new
+// table are removed from the node list. Bootstrap nodes and current peers are
+// never evicted, and errBucketFull is returned if no entry can be evicted.
+func (g *Gateway) insertNode(n *node) error {
+	bucket := g.addrTables.bucket(n)
+	if len(bucket) >= bucketSize {
+		var candidates []modules.NetAddress
+		for addr := range bucket {
+			if _, isPeer := g.peers[addr]; !isPeer && !g.nodes[addr].Bootstrap {
+				candidates = append(candidates, addr)
+			}
+		}
+		if len(candidates) == 0 {
+			return errBucketFull
+		}
+		evict := g.nodes[candidates[fastrand.Intn(len(candidates))]]
+		delete(bucket, evict.NetAddress)
+		delete(g.nodes, evict.NetAddress)
+		if evict.Tried {
+			evict.Tried = false
+			if err := g.insertNode(evict); err != nil {
+				g.log.Debugf("INFO: dropped node %v that was evicted from the tried table: %v", evict.NetAddress, err)
+			}
+		}
+	}
+	bucket[n.NetAddress] = struct{}{}
+	g.nodes[n.NetAddress] = n
+	return nil
+}
+
+// markTried moves a node that the gateway has successfully connected to from
+// the new table to the tried table.
+func (g *Gateway) markTried(addr modules.NetAddress) {
+	n, exists := g.nodes[addr]
+	if !exists || n.Tried {
+		return
+	}
+	delete(g.addrTables.bucket(n), addr)
+	delete(g.nodes, addr)
+	n.Tried = true
+	if err := g.insertNode(n); err != nil {
+		// The tried bucket only holds bootstrap nodes and peers, so the node
+		// stays in the new table.
+		n.Tried = false
+		g.insertNode(n)
+	}
+}
+
+// randomNewNode returns a random node from the new table that the gateway is
+// not connected to.
+func (g *Gateway) randomNewNode() (modules.NetAddress, error) {
+	var candidates []modules.NetAddress
+	for _, bucket := range g.addrTables.new {
+		for addr := range bucket {
+			if _, isPeer := g.peers[addr]; !isPeer {
+				candidates = append(candidates, addr)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errNoNodes
+	}
+	return candidates[fastrand.Intn(len(candidates))], nil
+}
+
+// permanentFeeler is a thread that runs throughout the lifetime of the
+// gateway, making short-lived connections to random nodes of the new table.
+// Reachable nodes are moved to the tried table, and unreachable nodes are
+// pruned.
+func (g *Gateway) permanentFeeler(closeChan chan struct{}) {
+	defer close(closeChan)
+
+	for {
+		if !g.managedSleep(feelerInterval) {
+			return
+		}
+
+		g.mu.RLock()
+		addr, err := g.randomNewNode()
+		dialable := err == nil && g.dialableFamily(addr.Host()) && g.acceptableHost(addr.Host()) == nil
+		g.mu.RUnlock()
+		if !dialable {
+			continue
+		}
+
+		err = g.staticPingNode(addr)
+		g.mu.Lock()
+		g.recordConnectionAttempt(addr, err == nil)
+		if err != nil && g.pruneNode(addr) {
+			g.log.Debugf("INFO: removing node %q because a feeler connection failed: %v", addr, err)
+		}
+		g.mu.Unlock()
+	}
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestNetworkGroup checks that addresses are grouped by their network.
+func TestNetworkGroup(t *testing.T) {
+	tests := []struct {
+		addr  modules.NetAddress
+		group string
+	}{
+		{"1.2.3.4:9981", "1.2.0.0"},
+		{"1.2.200.100:1", "1.2.0.0"},
+		{"[2001:db8:1234::1]:9981", "2001:db8::"},
+		{"[2001:db8:5678::1]:1", "2001:db8::"},
+		{"[::ffff:1.2.3.4]:9981", "1.2.0.0"},
+		{"127.0.0.1:9981", "127.0.0.1:9981"},
+		{"192.168.1.1:1", "192.168.1.1:1"},
+		{"expyuzz4wqqyqhjn.onion:9981", "expyuzz4wqqyqhjn.onion"},
+	}
+	for _, test := range tests {
+		if group := networkGroup(test.addr); group != test.group {
+			t.Errorf("networkGroup(%v): expected %v, got %v", test.addr, test.group, group)
+		}
+	}
+}
+
+// TestBucketLimits checks that the nodes shared by a single source can only
+// occupy a few buckets of the new table, and that full buckets evict random
+// entries other than bootstrap nodes.
+func TestBucketLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Nodes in the same network group shared by the same source all land in
+	// the same bucket.
+	source := modules.NetAddress("5.5.5.5:9981")
+	if err := g.addNodeFrom("111.111.0.0:1", source); err != nil {
+		t.Fatal(err)
+	}
+	g.nodes["111.111.0.0:1"].Bootstrap = true
+	for i := 1; i < 2*bucketSize; i++ {
+		addr := modules.NetAddress(fmt.Sprintf("111.111.%v.%v:1", i/256, i%256))
+		if err := g.addNodeFrom(addr, source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(g.nodes) != bucketSize {
+		t.Fatalf("expected %v nodes, got %v", bucketSize, len(g.nodes))
+	} else if _, exists := g.nodes["111.111.0.0:1"]; !exists {
+		t.Fatal("bootstrap node was evicted")
+	}
+
+	// Nodes in many network groups shared by the same source can only fill
+	// newBucketsPerSourceGroup buckets.
+	g.nodes = make(map[modules.NetAddress]*node)
+	g.addrTables = newAddressTables()
+	for i := 0; i < 4*newBucketsPerSourceGroup*bucketSize; i++ {
+		addr := modules.NetAddress(fmt.Sprintf("%v.%v.1.1:1", 1+i/256, i%256))
+		if err := g.addNodeFrom(addr, source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	used := 0
+	for _, bucket := range g.addrTables.new {
+		if len(bucket) > 0 {
+			used++
+		}
+	}
+	if used > newBucketsPerSourceGroup || len(g.nodes) > newBucketsPerSourceGroup*bucketSize {
+		t.Fatalf("a single source occupies %v buckets with %v nodes", used, len(g.nodes))
+	}
+}
+
+// TestMarkTried checks that nodes move to the tried table after a successful
+// connection attempt, and that removed nodes leave their bucket.
+func TestMarkTried(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	addr := modules.NetAddress("111.111.111.111:1")
+	if err := g.addNode(addr); err != nil {
+		t.Fatal(err)
+	}
+	n := g.nodes[addr]
+	if _, inNew := g.addrTables.bucket(n)[addr]; !inNew || n.Tried {
+		t.Fatal("node was not added to the new table")
+	}
+	newBucket := g.addrTables.bucket(n)
+
+	g.recordConnectionAttempt(addr, false)
+	if n.Tried {
+		t.Fatal("failed connection attempt moved the node to the tried table")
+	}
+	g.recordConnectionAttempt(addr, true)
+	if _, inTried := g.addrTables.bucket(n)[addr]; !inTried || !n.Tried {
+		t.Fatal("node was not moved to the tried table")
+	} else if _, inNew := newBucket[addr]; inNew {
+		t.Fatal("node is still in the new table")
+	}
+
+	if err := g.removeNode(addr); err != nil {
+		t.Fatal(err)
+	} else if _, inTried := g.addrTables.bucket(n)[addr]; inTried {
+		t.Fatal("removed node is still in the tried table")
+	}
+}
+
+// TestFeelerConnections checks that the feeler moves reachable nodes from the
+// new table to the tried table.
+func TestFeelerConnections(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	g1.mu.Lock()
+	err := g1.addNode(g2.Address())
+	g1.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		g1.mu.RLock()
+		defer g1.mu.RUnlock()
+		if n, exists := g1.nodes[g2.Address()]; !exists || !n.Tried {
+			return errors.New("node was not moved to the tried table")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
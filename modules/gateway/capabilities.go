@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// protocolVersion is the version of the gateway protocol that the
+	// gateway advertises in its session header. It is increased whenever the
+	// set of RPCs that every peer is expected to support changes. Optional
+	// RPCs are advertised as capabilities instead.
+	protocolVersion = 1
+
+	// maxCapabilities is the maximum number of capabilities that are read
+	// from a session header.
+	maxCapabilities = 32
+
+	// maxCapabilityLength is the maximum length of a capability name.
+	maxCapabilityLength = 32
+)
+
+var errTooManyCapabilities = errors.New("session header has too many capabilities")
+
+// MarshalSia implements the encoding.SiaMarshaler interface. The protocol
+// version and capabilities are appended to the fields of the original header,
+// which older peers ignore. They are omitted entirely if the protocol version
+// is 0.
+func (sh sessionHeader) MarshalSia(w io.Writer) error {
+	err := encoding.NewEncoder(w).EncodeAll(sh.GenesisID, sh.UniqueID, sh.NetAddress)
+	if err != nil || sh.ProtocolVersion == 0 {
+		return err
+	}
+	return encoding.NewEncoder(w).EncodeAll(sh.ProtocolVersion, sh.Capabilities)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface. A header
+// that ends after the original fields was sent by a peer that does not
+// negotiate capabilities.
+func (sh *sessionHeader) UnmarshalSia(r io.Reader) error {
+	err := encoding.NewDecoder(r).DecodeAll(&sh.GenesisID, &sh.UniqueID, &sh.NetAddress)
+	if err != nil {
+		return err
+	}
+	var pv [8]byte
+	if _, err := io.ReadFull(r, pv[:]); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	sh.ProtocolVersion = encoding.DecUint64(pv[:])
+
+	var n uint64
+	if err := encoding.NewDecoder(r).Decode(&n); err != nil {
+		return err
+	} else if n > maxCapabilities {
+		return errTooManyCapabilities
+	}
+	sh.Capabilities = make([]string, n)
+	for i := range sh.Capabilities {
+		c, err := encoding.ReadPrefix(r, maxCapabilityLength)
+		if err != nil {
+			return err
+		}
+		sh.Capabilities[i] = string(c)
+	}
+	return nil
+}
+
+// ourSessionHeader returns the session header that the gateway sends to its
+// peers.
+func (g *Gateway) ourSessionHeader() sessionHeader {
+	capabilities := make([]string, 0, len(g.capabilities))
+	for c := range g.capabilities {
+		capabilities = append(capabilities, c)
+	}
+	sort.Strings(capabilities)
+	return sessionHeader{
+		GenesisID:       types.GenesisID,
+		UniqueID:        g.staticId,
+		NetAddress:      g.myAddr,
+		ProtocolVersion: protocolVersion,
+		Capabilities:    capabilities,
+	}
+}
+
+// RegisterCapability adds a capability to the set that the gateway advertises
+// in its session header. Only peers that connect after the capability has
+// been registered learn about it, so modules should register their
+// capabilities before the gateway starts connecting to peers.
+func (g *Gateway) RegisterCapability(capability string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(capability) > maxCapabilityLength {
+		build.Critical("capability name is too long: " + capability)
+		return
+	} else if _, ok := g.capabilities[capability]; ok {
+		build.Critical("capability already registered: " + capability)
+	} else if len(g.capabilities) >= maxCapabilities {
+		build.Critical("too many capabilities registered")
+		return
+	}
+	g.capabilities[capability] = struct{}{}
+}
+
+// UnregisterCapability removes a capability from the set that the gateway
+// advertises.
+func (g *Gateway) UnregisterCapability(capability string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.capabilities[capability]; !ok {
+		build.Critical("capability not registered: " + capability)
+	}
+	delete(g.capabilities, capability)
+}
+
+// PeerCapable returns true if the gateway is connected to the peer and the
+// peer advertised the capability during the handshake.
+func (g *Gateway) PeerCapable(addr modules.NetAddress, capability string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	p, exists := g.peers[addr]
+	return exists && p.HasCapability(capability)
+}
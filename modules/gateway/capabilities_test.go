@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// legacySessionHeader is the session header sent by peers that do not
+// negotiate capabilities.
+type legacySessionHeader struct {
+	GenesisID  types.BlockID
+	UniqueID   gatewayID
+	NetAddress modules.NetAddress
+}
+
+// TestSessionHeaderCompat checks that session headers with capabilities can
+// be read by peers that do not negotiate capabilities, and vice versa.
+func TestSessionHeaderCompat(t *testing.T) {
+	header := sessionHeader{
+		GenesisID:       types.GenesisID,
+		UniqueID:        gatewayID{1, 2, 3},
+		NetAddress:      "111.111.111.111:9981",
+		ProtocolVersion: protocolVersion,
+		Capabilities:    []string{"Bar", "Foo"},
+	}
+	legacy := legacySessionHeader{
+		GenesisID:  header.GenesisID,
+		UniqueID:   header.UniqueID,
+		NetAddress: header.NetAddress,
+	}
+
+	// A legacy peer should read the original fields and ignore the rest.
+	b := encoding.Marshal(header)
+	if len(b) > 40+modules.MaxEncodedNetAddressLength {
+		t.Fatal("header is too large for legacy peers:", len(b))
+	}
+	var decodedLegacy legacySessionHeader
+	if err := encoding.Unmarshal(b, &decodedLegacy); err != nil {
+		t.Fatal(err)
+	} else if decodedLegacy != legacy {
+		t.Fatal("legacy peer decoded the wrong header:", decodedLegacy)
+	}
+	var decoded sessionHeader
+	if err := encoding.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(decoded, header) {
+		t.Fatal("header did not round trip:", decoded)
+	}
+
+	// A legacy header should decode with a protocol version of 0.
+	decoded = sessionHeader{}
+	if err := encoding.Unmarshal(encoding.Marshal(legacy), &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded.ProtocolVersion != 0 || decoded.Capabilities != nil || decoded.NetAddress != legacy.NetAddress {
+		t.Fatal("legacy header decoded incorrectly:", decoded)
+	}
+
+	// Headers with too many capabilities should be rejected.
+	header.Capabilities = make([]string, maxCapabilities+1)
+	if err := encoding.Unmarshal(encoding.Marshal(header), &decoded); err == nil {
+		t.Fatal("expected header with too many capabilities to be rejected")
+	}
+}
+
+// TestCapabilityNegotiation checks that peers learn each other's protocol
+// version and capabilities when they connect.
+func TestCapabilityNegotiation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	g1.RegisterCapability("Foo")
+	g2.RegisterCapability("Bar")
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g2.Peers()) != 1 {
+			return errors.New("g2 has not accepted the connection")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !g1.PeerCapable(g2.Address(), "Bar") || g1.PeerCapable(g2.Address(), "Foo") {
+		t.Fatal("g1 has the wrong capabilities for g2:", g1.Peers())
+	}
+	p := g2.Peers()[0]
+	if !g2.PeerCapable(p.NetAddress, "Foo") || g2.PeerCapable(p.NetAddress, "Bar") {
+		t.Fatal("g2 has the wrong capabilities for g1:", p)
+	}
+	if p.ProtocolVersion != protocolVersion || g1.Peers()[0].ProtocolVersion != protocolVersion {
+		t.Fatal("peers did not learn each other's protocol version")
+	}
+
+	// Capabilities that are unregistered should not be advertised to new
+	// peers.
+	g1.UnregisterCapability("Foo")
+	if err := g1.Disconnect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		for _, p := range g2.Peers() {
+			if !p.HasCapability("Foo") {
+				return nil
+			}
+		}
+		return errors.New("g2 has not accepted the new connection")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
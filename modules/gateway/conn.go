@@ -23,14 +23,32 @@ func (pc peerConn) RPCAddr() modules.NetAddress {
 // handles things like clean shutdown, fast shutdown, and chooses the correct
 // communication protocol.
 func (g *Gateway) staticDial(addr modules.NetAddress) (net.Conn, error) {
+	if g.staticProxy.enabled() {
+		conn, err := g.staticDialProxy(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn.SetDeadline(time.Now().Add(connStdDeadline))
+		return g.newRLConn(conn), nil
+	}
 	dialer := &net.Dialer{
-		Cancel:  g.threads.StopChan(),
-		Timeout: dialTimeout,
+		Cancel:    g.threads.StopChan(),
+		Timeout:   dialTimeout,
+		KeepAlive: tcpKeepAlivePeriod,
 	}
 	conn, err := dialer.Dial("tcp", string(addr))
 	if err != nil {
 		return nil, err
 	}
 	conn.SetDeadline(time.Now().Add(connStdDeadline))
-	return conn, nil
+	return g.newRLConn(conn), nil
+}
+
+// setKeepAlive enables TCP keepalives on an accepted connection, so that a
+// peer that disappears without closing the connection is detected.
+func setKeepAlive(conn net.Conn) {
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetKeepAlive(true)
+		tc.SetKeepAlivePeriod(tcpKeepAlivePeriod)
+	}
 }
@@ -13,8 +13,10 @@ const (
 	handshakeUpgradeVersion = "1.0.0"
 
 	// maxEncodedSessionHeaderSize is the maximum allowed size of an encoded
-	// sessionHeader object.
-	maxEncodedSessionHeaderSize = 40 + modules.MaxEncodedNetAddressLength
+	// sessionHeader object. Peers that predate capability negotiation only
+	// accept 40 + modules.MaxEncodedNetAddressLength bytes, so the gateway
+	// should only advertise a handful of short capabilities.
+	maxEncodedSessionHeaderSize = 40 + modules.MaxEncodedNetAddressLength + 16 + maxCapabilities*(8+maxCapabilityLength)
 
 	// maxLocalOutbound is currently set to 3, meaning the gateway will not
 	// consider a local node to be an outbound peer if the gateway already has
@@ -33,6 +35,10 @@ const (
 	// codebase were made that weren't backwards compatible. This might include
 	// changes to the protocol or hardforks.
 	minimumAcceptablePeerVersion = "1.3.1"
+
+	// banThreshold is the number of penalty points at which a misbehaving
+	// host is banned.
+	banThreshold = 100
 )
 
 var (
@@ -199,12 +205,44 @@ var (
 	}).(time.Duration)
 
 	// rpcStdDeadline defines the standard deadline that should be used for all
-	// incoming RPC calls.
+	// RPC calls. RPCs that take longer must extend the deadline themselves.
 	rpcStdDeadline = build.Select(build.Var{
 		Standard: 5 * time.Minute,
 		Dev:      3 * time.Minute,
 		Testing:  5 * time.Second,
 	}).(time.Duration)
+
+	// tcpKeepAlivePeriod is the interval between TCP keepalive probes on peer
+	// connections.
+	tcpKeepAlivePeriod = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      15 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// sessionKeepAliveInterval is the interval between the keepalive frames
+	// that the gateway sends on an idle peer session, and
+	// sessionKeepAliveTimeout is the amount of time after which a session
+	// that has received nothing is closed.
+	sessionKeepAliveInterval = build.Select(build.Var{
+		Standard: 10 * time.Second,
+		Dev:      10 * time.Second,
+		Testing:  2 * time.Second,
+	}).(time.Duration)
+	sessionKeepAliveTimeout = build.Select(build.Var{
+		Standard: 2 * time.Minute,
+		Dev:      1 * time.Minute,
+		Testing:  30 * time.Second,
+	}).(time.Duration)
+
+	// maxConsecutiveStalls is the number of RPCs in a row that may time out
+	// without receiving any data from a peer before the gateway disconnects
+	// from the peer.
+	maxConsecutiveStalls = build.Select(build.Var{
+		Standard: 3,
+		Dev:      3,
+		Testing:  2,
+	}).(int)
 )
 
 var (
@@ -250,3 +288,21 @@ var (
 		Testing:  100 * time.Millisecond,
 	}).(time.Duration)
 )
+
+var (
+	// banDuration is the amount of time that a host is banned for after it
+	// has accumulated banThreshold penalty points.
+	banDuration = build.Select(build.Var{
+		Standard: 24 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// penaltyWindow is the amount of time after which the penalty points of
+	// a host are forgotten if the host has not misbehaved again.
+	penaltyWindow = build.Select(build.Var{
+		Standard: 6 * time.Hour,
+		Dev:      5 * time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
@@ -0,0 +1,133 @@
+package gateway
+
+// dnsseeds.go implements DNS seeding. A DNS seed is a hostname that resolves
+// to the addresses of stable peers. When the gateway bootstraps, it resolves
+// the default seeds and the seeds configured by the operator, and adds the
+// returned addresses to its node list. Seeds can be updated by their operators
+// as peers come and go, so the gateway does not depend solely on the
+// hardcoded bootstrap peers. The nodes returned by a seed share a source, so a
+// single seed can only occupy a few buckets of the new table.
+//
+// DNS lookups are not sent through the proxy, so a gateway that dials through
+// a proxy does not resolve any seeds.
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errInvalidDNSSeed = errors.New("DNS seed must be a hostname, optionally followed by a port")
+
+	// dnsSeedTimeout is the amount of time after which the lookup of a DNS
+	// seed is abandoned.
+	dnsSeedTimeout = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      10 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
+
+// seedAddress returns the address of a DNS seed, adding the default port if
+// the seed does not specify one.
+func seedAddress(seed string) modules.NetAddress {
+	if _, _, err := net.SplitHostPort(seed); err == nil {
+		return modules.NetAddress(seed)
+	}
+	return modules.NetAddress(net.JoinHostPort(seed, modules.DefaultSeedPort))
+}
+
+// checkDNSSeeds returns an error if any DNS seed of the settings is invalid.
+func checkDNSSeeds(settings modules.GatewaySettings) error {
+	for _, seed := range settings.DNSSeeds {
+		if seedAddress(seed).IsStdValid() != nil {
+			return errInvalidDNSSeed
+		}
+	}
+	return nil
+}
+
+// dnsSeeds returns the DNS seeds that the gateway resolves when it
+// bootstraps.
+func (g *Gateway) dnsSeeds() []string {
+	settings := g.rl.limits()
+	if settings.DisableDNSSeeds {
+		return nil
+	}
+	return append(append([]string(nil), modules.DNSSeeds...), settings.DNSSeeds...)
+}
+
+// managedResolveDNSSeeds resolves the DNS seeds of the gateway and adds the
+// returned addresses to the node list. It returns the number of nodes that
+// were added.
+func (g *Gateway) managedResolveDNSSeeds() int {
+	seeds := g.dnsSeeds()
+	if len(seeds) == 0 {
+		return 0
+	} else if g.staticProxy.enabled() {
+		g.log.Println("INFO: not resolving DNS seeds, as lookups would bypass the proxy")
+		return 0
+	}
+
+	// Abort the lookups when the gateway is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-g.threads.StopChan():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	added := 0
+	for _, seed := range seeds {
+		seedAddr := seedAddress(seed)
+		lookupCtx, lookupCancel := context.WithTimeout(ctx, dnsSeedTimeout)
+		hosts, err := net.DefaultResolver.LookupHost(lookupCtx, seedAddr.Host())
+		lookupCancel()
+		if err != nil {
+			g.log.Printf("WARN: unable to resolve DNS seed %v: %v", seed, err)
+			continue
+		}
+
+		g.mu.Lock()
+		for _, host := range hosts {
+			addr, err := canonicalAddress(modules.NetAddress(net.JoinHostPort(host, seedAddr.Port())))
+			if err == nil {
+				err = g.addNodeFrom(addr, seedAddr)
+			}
+			if err == nil {
+				added++
+			} else if err != errNodeExists {
+				g.log.Debugf("INFO: DNS seed %v returned the unusable address %v: %v", seed, addr, err)
+			}
+		}
+		g.mu.Unlock()
+	}
+	if added > 0 {
+		g.mu.Lock()
+		err := g.saveSync()
+		g.mu.Unlock()
+		if err != nil {
+			g.log.Println("ERROR: unable to save nodes from DNS seeds:", err)
+		}
+	}
+	g.log.Printf("INFO: added %v nodes from %v DNS seeds", added, len(seeds))
+	return added
+}
+
+// threadedResolveDNSSeeds resolves the DNS seeds of the gateway in the
+// background, so that slow lookups do not delay startup.
+func (g *Gateway) threadedResolveDNSSeeds() {
+	if err := g.threads.Add(); err != nil {
+		return
+	}
+	defer g.threads.Done()
+	g.managedResolveDNSSeeds()
+}
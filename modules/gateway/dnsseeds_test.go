@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestCheckDNSSeeds checks that invalid DNS seeds are rejected.
+func TestCheckDNSSeeds(t *testing.T) {
+	tests := []struct {
+		seed  string
+		valid bool
+	}{
+		{"seed.example.com", true},
+		{"seed.example.com:9981", true},
+		{"localhost", true},
+		{"", false},
+		{"seed.example.com:0", false},
+		{"seed.example.com:port", false},
+		{"-seed.example.com", false},
+	}
+	for _, test := range tests {
+		err := checkDNSSeeds(modules.GatewaySettings{DNSSeeds: []string{test.seed}})
+		if (err == nil) != test.valid {
+			t.Errorf("checkDNSSeeds(%q): expected valid to be %v, got %v", test.seed, test.valid, err)
+		}
+	}
+	if seedAddress("seed.example.com") != "seed.example.com:"+modules.DefaultSeedPort {
+		t.Error("default port was not added to seed")
+	}
+}
+
+// TestResolveDNSSeeds checks that the addresses returned by the DNS seeds are
+// added to the node list, and that DNS seeding can be disabled.
+func TestResolveDNSSeeds(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	settings := g.Settings()
+	settings.DNSSeeds = []string{"localhost:9999"}
+	settings.DisableDNSSeeds = true
+	if err := g.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if added := g.managedResolveDNSSeeds(); added != 0 {
+		t.Fatal("nodes were added while DNS seeding was disabled:", added)
+	}
+
+	settings.DisableDNSSeeds = false
+	if err := g.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if added := g.managedResolveDNSSeeds(); added == 0 {
+		t.Fatal("no nodes were added from the DNS seeds")
+	}
+	g.mu.RLock()
+	n, exists := g.nodes["127.0.0.1:9999"]
+	g.mu.RUnlock()
+	if !exists {
+		t.Fatal("address of the DNS seed was not added to the node list")
+	} else if n.Bootstrap || n.Source != networkGroup("localhost:9999") {
+		t.Fatal("node from the DNS seed has the wrong properties:", n)
+	}
+
+	// Invalid seeds should be rejected.
+	settings.DNSSeeds = []string{"localhost:0"}
+	if err := g.SetSettings(settings); err != errInvalidDNSSeed {
+		t.Fatal("expected errInvalidDNSSeed, got", err)
+	}
+}
@@ -0,0 +1,294 @@
+package gateway
+
+// encryption.go implements the encryption of peer connections. Peers that
+// both advertise the Encryption capability perform an X25519 key exchange
+// after the session headers have been exchanged, and wrap the connection in
+// ChaCha20-Poly1305 frames before the stream multiplexer is started. The keys
+// are derived from the shared secret and a hash of both session headers and
+// both ephemeral public keys, so a middlebox that alters the handshake causes
+// the handshake to fail instead of going unnoticed.
+//
+// Every gateway has a persistent ed25519 key. Over the encrypted connection,
+// each peer sends its public key and a signature of the handshake transcript,
+// so the keys that protect the connection are bound to the static key of the
+// remote gateway. The static key of a node is remembered once it has
+// negotiated encryption, and later connections to or from that node must
+// negotiate encryption again and authenticate with the same key. The first
+// connection to a node is not authenticated, so an active attacker that
+// intercepts every connection to a node from the start can still pose as it.
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// encryptionCapability is the capability advertised by peers that
+	// support encrypted connections.
+	encryptionCapability = "Encryption"
+
+	// maxFramePayload is the maximum number of plaintext bytes in a single
+	// encrypted frame.
+	maxFramePayload = 1 << 16
+
+	// frameHeaderSize is the size of the length prefix of a frame.
+	frameHeaderSize = 4
+
+	// keyFile is the name of the file that contains the static key of the
+	// gateway.
+	keyFile = "key.json"
+)
+
+var (
+	// keyMetadata contains the header and version strings that identify the
+	// gateway's static key.
+	keyMetadata = persist.Metadata{
+		Header:  "Sia Gateway Key",
+		Version: "1.3.3",
+	}
+
+	errDecryptionFailed  = errors.New("failed to decrypt frame")
+	errFrameTooLarge     = errors.New("encrypted frame is too large")
+	errHandshakeMismatch = errors.New("peers derived different encryption keys")
+	errHandshakeAuth     = errors.New("peer did not sign the handshake with its key")
+	errPeerDowngraded    = errors.New("peer negotiated encryption before, but not on this connection")
+	errPeerKeyMismatch   = errors.New("peer authenticated with a different key than before")
+)
+
+// encryptedConn is a net.Conn that encrypts all data written to it and
+// decrypts all data read from it. Each direction uses its own key and a
+// counter as the nonce, so nonces are never reused. Any error leaves the
+// connection unusable. remoteKey is the static key that the remote peer
+// authenticated with.
+type encryptedConn struct {
+	net.Conn
+	remoteKey crypto.PublicKey
+
+	sendAEAD  cipher.AEAD
+	sendNonce uint64
+	sendBuf   []byte
+	wmu       sync.Mutex
+
+	recvAEAD  cipher.AEAD
+	recvNonce uint64
+	recvBuf   []byte
+	plaintext []byte
+	rmu       sync.Mutex
+}
+
+// nonce returns the AEAD nonce for the frame with the given counter.
+func nonce(counter uint64) []byte {
+	n := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(n, counter)
+	return n
+}
+
+// newEncryptedConn wraps conn so that data is written with sendKey and read
+// with recvKey.
+func newEncryptedConn(conn net.Conn, sendKey, recvKey crypto.Hash) *encryptedConn {
+	// NOTE: chacha20poly1305.New only returns an error if the key has the
+	// wrong size, and crypto.Hash is always 32 bytes.
+	sendAEAD, _ := chacha20poly1305.New(sendKey[:])
+	recvAEAD, _ := chacha20poly1305.New(recvKey[:])
+	return &encryptedConn{
+		Conn:     conn,
+		sendAEAD: sendAEAD,
+		sendBuf:  make([]byte, frameHeaderSize+maxFramePayload+sendAEAD.Overhead()),
+		recvAEAD: recvAEAD,
+		recvBuf:  make([]byte, maxFramePayload+recvAEAD.Overhead()),
+	}
+}
+
+// Write implements net.Conn. The data is split into frames of at most
+// maxFramePayload bytes, each of which is prefixed with its length.
+func (ec *encryptedConn) Write(b []byte) (int, error) {
+	ec.wmu.Lock()
+	defer ec.wmu.Unlock()
+	n := 0
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxFramePayload {
+			chunk = chunk[:maxFramePayload]
+		}
+		frame := ec.sendAEAD.Seal(ec.sendBuf[frameHeaderSize:frameHeaderSize], nonce(ec.sendNonce), chunk, nil)
+		ec.sendNonce++
+		binary.LittleEndian.PutUint32(ec.sendBuf, uint32(len(frame)))
+		if _, err := ec.Conn.Write(ec.sendBuf[:frameHeaderSize+len(frame)]); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	return n, nil
+}
+
+// Read implements net.Conn. A new frame is only read once the plaintext of
+// the previous frame has been consumed.
+func (ec *encryptedConn) Read(b []byte) (int, error) {
+	ec.rmu.Lock()
+	defer ec.rmu.Unlock()
+	if len(ec.plaintext) == 0 {
+		var prefix [frameHeaderSize]byte
+		if _, err := io.ReadFull(ec.Conn, prefix[:]); err != nil {
+			return 0, err
+		}
+		size := binary.LittleEndian.Uint32(prefix[:])
+		if size > uint32(len(ec.recvBuf)) {
+			return 0, errFrameTooLarge
+		}
+		frame := ec.recvBuf[:size]
+		if _, err := io.ReadFull(ec.Conn, frame); err != nil {
+			return 0, err
+		}
+		plaintext, err := ec.recvAEAD.Open(frame[:0], nonce(ec.recvNonce), frame, nil)
+		if err != nil {
+			return 0, errDecryptionFailed
+		}
+		ec.recvNonce++
+		ec.plaintext = plaintext
+	}
+	n := copy(b, ec.plaintext)
+	ec.plaintext = ec.plaintext[n:]
+	return n, nil
+}
+
+// supportsEncryption returns true if the session header advertises the
+// Encryption capability.
+func supportsEncryption(header sessionHeader) bool {
+	for _, c := range header.Capabilities {
+		if c == encryptionCapability {
+			return true
+		}
+	}
+	return false
+}
+
+// handshakeSigHash returns the hash that the peer with the given role signs
+// to authenticate the handshake with its static key.
+func handshakeSigHash(role string, transcript crypto.Hash, pk crypto.PublicKey) crypto.Hash {
+	return crypto.HashAll(role, transcript, pk)
+}
+
+// encryptionHandshake performs the key exchange on a connection whose session
+// headers have been exchanged, and returns the encrypted connection. Each peer
+// authenticates the handshake with its static key; sk is ours. dialer
+// indicates whether we opened the connection, which decides which key is used
+// in each direction.
+func encryptionHandshake(conn net.Conn, ourHeader, remoteHeader sessionHeader, sk crypto.SecretKey, dialer bool) (net.Conn, error) {
+	// Exchange ephemeral public keys.
+	esk, epk := crypto.GenerateX25519KeyPair()
+	if _, err := conn.Write(epk[:]); err != nil {
+		return nil, fmt.Errorf("failed to write public key: %v", err)
+	}
+	var remoteEPK crypto.X25519PublicKey
+	if _, err := io.ReadFull(conn, remoteEPK[:]); err != nil {
+		return nil, fmt.Errorf("failed to read public key: %v", err)
+	}
+	secret, err := esk.SharedSecret(remoteEPK)
+	if err != nil {
+		return nil, err
+	}
+
+	// Derive a key for each direction, binding them to the whole handshake.
+	dialerHeader, acceptorHeader := ourHeader, remoteHeader
+	dialerPK, acceptorPK := epk, remoteEPK
+	ourRole, remoteRole := "dialer", "acceptor"
+	if !dialer {
+		dialerHeader, acceptorHeader = remoteHeader, ourHeader
+		dialerPK, acceptorPK = remoteEPK, epk
+		ourRole, remoteRole = remoteRole, ourRole
+	}
+	transcript := crypto.HashAll(dialerHeader, acceptorHeader, dialerPK, acceptorPK)
+	dialerKey := crypto.HashAll("dialer", secret, transcript)
+	acceptorKey := crypto.HashAll("acceptor", secret, transcript)
+	var ec *encryptedConn
+	if dialer {
+		ec = newEncryptedConn(conn, dialerKey, acceptorKey)
+	} else {
+		ec = newEncryptedConn(conn, acceptorKey, dialerKey)
+	}
+
+	// Over the encrypted connection, send our static public key and a
+	// signature of the transcript. Successfully decrypting the remote
+	// peer's message confirms that both peers derived the same keys, and the
+	// signature proves that the remote peer holds its static key.
+	pk := sk.PublicKey()
+	sig := crypto.SignHash(handshakeSigHash(ourRole, transcript, pk), sk)
+	msg := make([]byte, 0, crypto.PublicKeySize+crypto.SignatureSize)
+	msg = append(append(msg, pk[:]...), sig[:]...)
+	if _, err := ec.Write(msg); err != nil {
+		return nil, fmt.Errorf("failed to write handshake signature: %v", err)
+	}
+	var remoteSig crypto.Signature
+	if _, err := io.ReadFull(ec, ec.remoteKey[:]); err == errDecryptionFailed {
+		return nil, errHandshakeMismatch
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read handshake signature: %v", err)
+	} else if _, err := io.ReadFull(ec, remoteSig[:]); err != nil {
+		return nil, fmt.Errorf("failed to read handshake signature: %v", err)
+	}
+	if crypto.VerifyHash(handshakeSigHash(remoteRole, transcript, ec.remoteKey), ec.remoteKey, remoteSig) != nil {
+		return nil, errHandshakeAuth
+	}
+	return ec, nil
+}
+
+// checkPeerKey returns an error if the node at addr negotiated encryption
+// before, but the connection to it is not encrypted or it authenticated with
+// a different key. conn is the connection returned by the encryption
+// handshake, if any. Nothing is checked if the gateway does not advertise
+// the Encryption capability itself.
+func (g *Gateway) checkPeerKey(addr modules.NetAddress, conn net.Conn) error {
+	n, exists := g.nodes[addr]
+	if _, ok := g.capabilities[encryptionCapability]; !ok {
+		return nil
+	} else if !exists || n.KeyHash == (crypto.Hash{}) {
+		return nil
+	}
+	ec, encrypted := conn.(*encryptedConn)
+	if !encrypted {
+		return errPeerDowngraded
+	} else if crypto.HashObject(ec.remoteKey) != n.KeyHash {
+		return errPeerKeyMismatch
+	}
+	return nil
+}
+
+// recordPeerKey remembers the key that the node at addr authenticated with,
+// if the connection to it is encrypted.
+func (g *Gateway) recordPeerKey(addr modules.NetAddress, conn net.Conn) {
+	n, exists := g.nodes[addr]
+	ec, encrypted := conn.(*encryptedConn)
+	if exists && encrypted {
+		n.KeyHash = crypto.HashObject(ec.remoteKey)
+	}
+}
+
+// loadKey loads the static key of the gateway, and generates and saves a new
+// key if there is none.
+func (g *Gateway) loadKey() error {
+	var sk crypto.SecretKey
+	err := persist.LoadJSON(keyMetadata, &sk, filepath.Join(g.persistDir, keyFile))
+	if os.IsNotExist(err) {
+		sk, _ = crypto.GenerateKeyPair()
+		err = persist.SaveJSON(keyMetadata, sk, filepath.Join(g.persistDir, keyFile))
+	}
+	if err != nil {
+		return err
+	}
+	g.staticKey = sk
+	return nil
+}
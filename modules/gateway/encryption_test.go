@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// tcpConnPair returns both ends of a TCP connection on the loopback interface.
+func tcpConnPair(t *testing.T) (dialer, acceptor net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	dialer, err = net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptor, err = l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dialer, acceptor
+}
+
+// testHandshake performs the encryption handshake on both ends of a
+// connection, and returns the encrypted connections and the errors of both
+// ends.
+func testHandshake(c1, c2 net.Conn, h1, h2 sessionHeader, sk1, sk2 crypto.SecretKey) (net.Conn, net.Conn, error, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	resultChan := make(chan result)
+	go func() {
+		conn, err := encryptionHandshake(c2, h2, h1, sk2, false)
+		resultChan <- result{conn, err}
+	}()
+	ec1, err1 := encryptionHandshake(c1, h1, h2, sk1, true)
+	r := <-resultChan
+	return ec1, r.conn, err1, r.err
+}
+
+// TestEncryptedConn checks that data written to one end of an encrypted
+// connection can be read from the other end, and that tampered data is
+// rejected.
+func TestEncryptedConn(t *testing.T) {
+	c1, c2 := tcpConnPair(t)
+	defer c1.Close()
+	defer c2.Close()
+	h1 := sessionHeader{GenesisID: types.GenesisID, UniqueID: gatewayID{1}, NetAddress: "1.1.1.1:1", ProtocolVersion: protocolVersion, Capabilities: []string{encryptionCapability}}
+	h2 := sessionHeader{GenesisID: types.GenesisID, UniqueID: gatewayID{2}, NetAddress: "2.2.2.2:2", ProtocolVersion: protocolVersion, Capabilities: []string{encryptionCapability}}
+	sk1, pk1 := crypto.GenerateKeyPair()
+	sk2, pk2 := crypto.GenerateKeyPair()
+	ec1, ec2, err1, err2 := testHandshake(c1, c2, h1, h2, sk1, sk2)
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+
+	// Each peer should have learned the static key of the other.
+	if ec1.(*encryptedConn).remoteKey != pk2 || ec2.(*encryptedConn).remoteKey != pk1 {
+		t.Fatal("peers did not learn each other's static keys")
+	}
+
+	// Send data that spans several frames in both directions.
+	for _, conns := range [][2]net.Conn{{ec1, ec2}, {ec2, ec1}} {
+		data := fastrand.Bytes(3*maxFramePayload + 17)
+		go conns[0].Write(data)
+		received := make([]byte, len(data))
+		if _, err := io.ReadFull(conns[1], received); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(received, data) {
+			t.Fatal("received data does not match sent data")
+		}
+	}
+
+	// Data that is modified in transit should be rejected.
+	go func() {
+		frame := []byte{5, 0, 0, 0, 1, 2, 3, 4, 5}
+		c1.Write(frame)
+	}()
+	if _, err := ec2.Read(make([]byte, 10)); err != errDecryptionFailed {
+		t.Fatal("expected errDecryptionFailed, got", err)
+	}
+}
+
+// TestEncryptionHandshakeMismatch checks that the handshake fails if the peers
+// saw different session headers, as happens when the headers are altered in
+// transit.
+func TestEncryptionHandshakeMismatch(t *testing.T) {
+	c1, c2 := tcpConnPair(t)
+	defer c1.Close()
+	defer c2.Close()
+	h1 := sessionHeader{GenesisID: types.GenesisID, UniqueID: gatewayID{1}, NetAddress: "1.1.1.1:1", ProtocolVersion: protocolVersion, Capabilities: []string{encryptionCapability}}
+	h2 := h1
+	h2.UniqueID = gatewayID{2}
+	altered := h1
+	altered.NetAddress = "3.3.3.3:3"
+	sk1, _ := crypto.GenerateKeyPair()
+	sk2, _ := crypto.GenerateKeyPair()
+
+	c1.SetDeadline(time.Now().Add(5 * time.Second))
+	c2.SetDeadline(time.Now().Add(5 * time.Second))
+	errChan := make(chan error)
+	go func() {
+		// The acceptor received an altered version of the dialer's header.
+		_, err := encryptionHandshake(c2, h2, altered, sk2, false)
+		errChan <- err
+	}()
+	_, err1 := encryptionHandshake(c1, h1, h2, sk1, true)
+	err2 := <-errChan
+	if err1 != errHandshakeMismatch || err2 != errHandshakeMismatch {
+		t.Fatal("expected errHandshakeMismatch, got", err1, err2)
+	}
+}
+
+// TestEncryptedPeers checks that gateways encrypt their connection when both
+// support it, and fall back to an unencrypted connection otherwise.
+func TestEncryptedPeers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+	g1.RegisterRPC("Echo", func(conn modules.PeerConn) error {
+		var s string
+		if err := encoding.ReadObject(conn, &s, 100); err != nil {
+			return err
+		}
+		return encoding.WriteObject(conn, s)
+	})
+
+	// checkConnection connects g to g1, and checks that both peers agree on
+	// whether the connection is encrypted and that RPCs work.
+	checkConnection := func(g *Gateway, encrypted bool) {
+		if err := g.Connect(g1.Address()); err != nil {
+			t.Fatal(err)
+		}
+		err := build.Retry(50, 100*time.Millisecond, func() error {
+			if len(g1.Peers()) != 1 {
+				return errors.New("g1 has not accepted the connection")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g1.Peers()[0].Encrypted != encrypted || g.Peers()[0].Encrypted != encrypted {
+			t.Fatalf("expected encrypted to be %v: %v %v", encrypted, g1.Peers(), g.Peers())
+		}
+		var echo string
+		err = g.RPC(g1.Address(), "Echo", func(conn modules.PeerConn) error {
+			if err := encoding.WriteObject(conn, "foo"); err != nil {
+				return err
+			}
+			return encoding.ReadObject(conn, &echo, 100)
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if echo != "foo" {
+			t.Fatal("wrong echo:", echo)
+		}
+	}
+	checkConnection(g2, true)
+
+	// Both gateways should remember the key of the other. g1 only adds g2 to
+	// its node list once it has pinged it.
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		g1.mu.RLock()
+		defer g1.mu.RUnlock()
+		g2.mu.RLock()
+		defer g2.mu.RUnlock()
+		n1, n2 := g1.nodes[g2.Address()], g2.nodes[g1.Address()]
+		if n1 == nil || n1.KeyHash != crypto.HashObject(g2.staticKey.PublicKey()) {
+			return errors.New("g1 did not remember the key of g2")
+		} else if n2 == nil || n2.KeyHash != crypto.HashObject(g1.staticKey.PublicKey()) {
+			return errors.New("g2 did not remember the key of g1")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once g2 has negotiated encryption, g1 refuses unencrypted connections
+	// to it.
+	if err := g2.Disconnect(g1.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g1.Peers()) != 0 {
+			return errors.New("g1 has not noticed the disconnect")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2.UnregisterCapability(encryptionCapability)
+	if err := g1.Connect(g2.Address()); err != errPeerDowngraded {
+		t.Fatal("expected errPeerDowngraded, got", err)
+	}
+
+	// Peers that have never negotiated encryption and do not advertise the
+	// capability use unencrypted connections.
+	g3 := newNamedTestingGateway(t, "3")
+	defer g3.Close()
+	g3.UnregisterCapability(encryptionCapability)
+	checkConnection(g3, false)
+}
+
+// TestCheckPeerKey checks that nodes that negotiated encryption before must
+// negotiate it again with the same key.
+func TestCheckPeerKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	_, pk := crypto.GenerateKeyPair()
+	_, otherPK := crypto.GenerateKeyPair()
+	plain, _ := net.Pipe()
+	encrypted := &encryptedConn{Conn: plain, remoteKey: pk}
+	other := &encryptedConn{Conn: plain, remoteKey: otherPK}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.addNode(dummyNode); err != nil {
+		t.Fatal(err)
+	}
+	// Nodes that never negotiated encryption may connect either way.
+	if err := g.checkPeerKey(dummyNode, plain); err != nil {
+		t.Fatal(err)
+	}
+	g.recordPeerKey(dummyNode, plain)
+	if g.nodes[dummyNode].KeyHash != (crypto.Hash{}) {
+		t.Fatal("key was recorded for an unencrypted connection")
+	}
+	g.recordPeerKey(dummyNode, encrypted)
+	if err := g.checkPeerKey(dummyNode, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.checkPeerKey(dummyNode, plain); err != errPeerDowngraded {
+		t.Fatal("expected errPeerDowngraded, got", err)
+	}
+	if err := g.checkPeerKey(dummyNode, other); err != errPeerKeyMismatch {
+		t.Fatal("expected errPeerKeyMismatch, got", err)
+	}
+}
+
+// TestLoadKey checks that the static key of the gateway persists across
+// restarts.
+func TestLoadKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	key := g.staticKey
+	if key == (crypto.SecretKey{}) {
+		t.Fatal("gateway has no static key")
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("localhost:0", false, g.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if g.staticKey != key {
+		t.Fatal("gateway did not load its static key")
+	}
+}
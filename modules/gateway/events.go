@@ -0,0 +1,115 @@
+package gateway
+
+// events.go notifies subscribers when the gateway connects to or disconnects
+// from a peer. Peers are added and removed while the gateway is locked, so
+// the events are queued and delivered by a separate thread, in the order in
+// which they happened. Subscribers can therefore call the gateway from within
+// their callbacks, but a slow subscriber delays the events of every other
+// subscriber.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// A peerEvent is a connection or disconnection that has not been delivered to
+// the subscribers yet.
+type peerEvent struct {
+	peer      modules.Peer
+	connected bool
+}
+
+// peerEvents holds the subscribers of the gateway and the events that have
+// not been delivered to them yet.
+type peerEvents struct {
+	subscribers []modules.GatewaySubscriber
+	queue       []peerEvent
+	signal      chan struct{}
+}
+
+// queuePeerEvent queues an event for delivery to the subscribers. The gateway
+// must be locked when queuePeerEvent is called.
+func (g *Gateway) queuePeerEvent(p modules.Peer, connected bool) {
+	if len(g.events.subscribers) == 0 {
+		return
+	}
+	g.events.queue = append(g.events.queue, peerEvent{peer: p, connected: connected})
+	select {
+	case g.events.signal <- struct{}{}:
+	default:
+	}
+}
+
+// removePeer removes a peer from the peer list and queues a disconnect event
+// for it. The session of the peer is not closed. The gateway must be locked
+// when removePeer is called.
+func (g *Gateway) removePeer(addr modules.NetAddress) {
+	p, exists := g.peers[addr]
+	if !exists {
+		return
+	}
+	delete(g.peers, addr)
+	g.queuePeerEvent(p.Peer, false)
+}
+
+// threadedDeliverPeerEvents delivers the queued events to the subscribers
+// until the gateway is stopped.
+func (g *Gateway) threadedDeliverPeerEvents() {
+	for {
+		select {
+		case <-g.events.signal:
+		case <-g.threads.StopChan():
+			return
+		}
+
+		func() {
+			if err := g.threads.Add(); err != nil {
+				return
+			}
+			defer g.threads.Done()
+
+			g.mu.Lock()
+			queue := g.events.queue
+			g.events.queue = nil
+			subscribers := append([]modules.GatewaySubscriber(nil), g.events.subscribers...)
+			g.mu.Unlock()
+			for _, e := range queue {
+				for _, s := range subscribers {
+					if e.connected {
+						s.OnConnect(e.peer)
+					} else {
+						s.OnDisconnect(e.peer)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Subscribe adds a subscriber that is notified of every peer that the gateway
+// connects to or disconnects from after subscribing.
+func (g *Gateway) Subscribe(subscriber modules.GatewaySubscriber) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, s := range g.events.subscribers {
+		if s == subscriber {
+			build.Critical("refusing to double-subscribe subscriber")
+			return
+		}
+	}
+	g.events.subscribers = append(g.events.subscribers, subscriber)
+}
+
+// Unsubscribe removes a subscriber. Events that were queued before the
+// subscriber was removed may still be delivered to it. If the subscriber is
+// not found, no action is taken.
+func (g *Gateway) Unsubscribe(subscriber modules.GatewaySubscriber) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, s := range g.events.subscribers {
+		if s == subscriber {
+			g.events.subscribers = append(g.events.subscribers[:i], g.events.subscribers[i+1:]...)
+			return
+		}
+	}
+}
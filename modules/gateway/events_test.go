@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// mockGatewaySubscriber records the peer events it receives.
+type mockGatewaySubscriber struct {
+	events []string
+	mu     sync.Mutex
+}
+
+// OnConnect implements modules.GatewaySubscriber.
+func (s *mockGatewaySubscriber) OnConnect(p modules.Peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprintf("connect %v %v", p.NetAddress, p.Inbound))
+}
+
+// OnDisconnect implements modules.GatewaySubscriber.
+func (s *mockGatewaySubscriber) OnDisconnect(p modules.Peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprintf("disconnect %v %v", p.NetAddress, p.Inbound))
+}
+
+// waitForEvents waits until the subscriber received exactly the expected
+// events.
+func (s *mockGatewaySubscriber) waitForEvents(expected ...string) error {
+	return build.Retry(50, 100*time.Millisecond, func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if fmt.Sprint(s.events) != fmt.Sprint(expected) {
+			return errors.New(fmt.Sprint("expected events ", expected, ", got ", s.events))
+		}
+		return nil
+	})
+}
+
+// TestPeerEvents checks that subscribers are notified when peers connect and
+// disconnect.
+func TestPeerEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	s1, s2 := new(mockGatewaySubscriber), new(mockGatewaySubscriber)
+	g1.Subscribe(s1)
+	g2.Subscribe(s2)
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.waitForEvents(fmt.Sprintf("connect %v false", g2.Address())); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.waitForEvents(fmt.Sprintf("connect %v true", g1.Address())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g1.Disconnect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err := s1.waitForEvents(
+		fmt.Sprintf("connect %v false", g2.Address()),
+		fmt.Sprintf("disconnect %v false", g2.Address()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s2.waitForEvents(
+		fmt.Sprintf("connect %v true", g1.Address()),
+		fmt.Sprintf("disconnect %v true", g1.Address()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unsubscribed subscriber should not receive further events.
+	g1.Unsubscribe(s1)
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = s2.waitForEvents(
+		fmt.Sprintf("connect %v true", g1.Address()),
+		fmt.Sprintf("disconnect %v true", g1.Address()),
+		fmt.Sprintf("connect %v true", g1.Address()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.waitForEvents(
+		fmt.Sprintf("connect %v false", g2.Address()),
+		fmt.Sprintf("disconnect %v false", g2.Address()),
+	); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -59,9 +59,21 @@ import (
 // time, the attacked node should already have its set of outbound peers,
 // limiting the amount of damage that the attacker can do.
 //
+// Every node is additionally placed in a bucket of the new or tried table,
+// keyed by its network group and the network group of the peer that shared
+// it (see buckets.go). An attacker that controls a few network groups can
+// only fill a few buckets, and full buckets evict a random entry instead of
+// refusing honest nodes. Feeler connections move reachable nodes from the
+// new table to the tried table, which the peer manager prefers.
+//
 // To limit DNS-based tomfoolry, nodes are only added to the nodelist if their
 // connection information takes the form of an IP address.
 //
+// Private addresses such as 192.168.x.x are only reachable from their own
+// network, so they are not gossiped to peers. A gateway started in local
+// network mode, as used for offline test networks and LAN clusters, shares
+// private addresses with its local peers instead.
+//
 // Some research has been done on Bitcoin's flood networks. The more relevant
 // research has been listed below. The papers listed first are more relevant.
 //     Eclipse Attacks on Bitcoin's Peer-to-Peer Network (Heilman, Kendler, Zohar, Goldberg)
@@ -74,8 +86,9 @@ import (
 // peers of the same IP address, it should favor kicking peers of the same ip
 // address range.
 //
-// TODO: There is no public key exchange, so communications cannot be
-// effectively encrypted or authenticated.
+// TODO: Gateway keys are only remembered once a node has negotiated
+// encryption, so the first connection to a node is not authenticated. There
+// is no way to learn the key of a node out of band.
 //
 // TODO: Gateway hostname discovery currently has significant centralization,
 // namely the fallback is a single third-party website that can easily form any
@@ -93,16 +106,17 @@ import (
 // the gateway participates in a flood network, practical attacks have been
 // demonstrated which have been able to confuse nodes by manipulating messages
 // from their peers. Encryption + authentication would have made the attack
-// more difficult.
+// more difficult. Connections to peers that do not advertise the Encryption
+// capability are still unencrypted.
 
 import (
 	"errors"
 	"fmt"
-	"net"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
@@ -116,9 +130,12 @@ var (
 
 // Gateway implements the modules.Gateway interface.
 type Gateway struct {
-	listener net.Listener
-	myAddr   modules.NetAddress
-	port     string
+	// listeners are the listeners of the gateway, and port is the port of
+	// the first listener. listenMu serializes changes to the listeners.
+	listeners []gatewayListener
+	listenMu  sync.Mutex
+	myAddr    modules.NetAddress
+	port      string
 
 	// handlers are the RPCs that the Gateway can handle.
 	//
@@ -126,6 +143,18 @@ type Gateway struct {
 	handlers map[rpcID]modules.RPCFunc
 	initRPCs map[string]modules.RPCFunc
 
+	// capabilities are the optional RPCs and protocol features that the
+	// gateway advertises to its peers in the session header.
+	capabilities map[string]struct{}
+
+	// topics holds the handlers of the topics that the gateway relays, and
+	// the messages that it has recently seen.
+	topics topicRelay
+
+	// events holds the subscribers that are notified when peers connect and
+	// disconnect. See events.go.
+	events peerEvents
+
 	// nodes is the set of all known nodes (i.e. potential peers).
 	//
 	// peers are the nodes that the gateway is currently connected to.
@@ -145,6 +174,38 @@ type Gateway struct {
 	peers  map[modules.NetAddress]*peer
 	peerTG siasync.ThreadGroup
 
+	// addrTables places every node in a bucket of the new or tried table,
+	// which limits the share of the node list that an attacker can occupy.
+	addrTables addressTables
+
+	// rl enforces the bandwidth limits on every connection of the gateway.
+	rl rateLimiter
+
+	// misbehavior holds the penalty points of the hosts that have recently
+	// misbehaved, and bans maps the banned hosts to the expiry of their ban.
+	misbehavior map[string]*misbehavior
+	bans        map[string]time.Time
+
+	// blacklist and whitelist are the addresses whose hosts the operator has
+	// refused or trusted. If whitelistOnly is set, the gateway only connects
+	// to whitelisted hosts.
+	blacklist     map[modules.NetAddress]struct{}
+	whitelist     map[modules.NetAddress]struct{}
+	whitelistOnly bool
+
+	// staticProxy is the SOCKS5 proxy that the gateway dials peers through,
+	// if any.
+	staticProxy ProxyConfig
+
+	// staticLocalNetwork is set if the gateway runs on a local network, such
+	// as an offline test network or a LAN cluster. Such a gateway gossips
+	// private addresses and connects to any number of local peers.
+	staticLocalNetwork bool
+
+	// staticKey is the persistent key that the gateway authenticates
+	// encrypted connections with.
+	staticKey crypto.SecretKey
+
 	// Utilities.
 	log        *persist.Logger
 	mu         sync.RWMutex
@@ -188,6 +249,17 @@ func (g *Gateway) Close() error {
 
 // New returns an initialized Gateway.
 func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
+	return NewCustomGateway(addr, bootstrap, persistDir, ProxyConfig{}, false)
+}
+
+// NewCustomGateway returns an initialized Gateway that dials its peers through
+// the provided proxy. If localNetwork is set, the gateway runs on a local
+// network, and gossips private addresses to its local peers.
+func NewCustomGateway(addr string, bootstrap bool, persistDir string, proxy ProxyConfig, localNetwork bool) (*Gateway, error) {
+	if err := proxy.validate(); err != nil {
+		return nil, err
+	}
+
 	// Create the directory if it doesn't exist.
 	err := os.MkdirAll(persistDir, 0700)
 	if err != nil {
@@ -198,9 +270,34 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 		handlers: make(map[rpcID]modules.RPCFunc),
 		initRPCs: make(map[string]modules.RPCFunc),
 
+		capabilities: map[string]struct{}{
+			encryptionCapability: {},
+			gossipCapability:     {},
+		},
+
+		topics: topicRelay{
+			handlers: make(map[string]modules.TopicHandler),
+			seen:     make(map[crypto.Hash]time.Time),
+		},
+
+		events: peerEvents{
+			signal: make(chan struct{}, 1),
+		},
+
 		nodes: make(map[modules.NetAddress]*node),
 		peers: make(map[modules.NetAddress]*peer),
 
+		addrTables: newAddressTables(),
+
+		misbehavior: make(map[string]*misbehavior),
+		bans:        make(map[string]time.Time),
+
+		blacklist: make(map[modules.NetAddress]struct{}),
+		whitelist: make(map[modules.NetAddress]struct{}),
+
+		staticProxy:        proxy,
+		staticLocalNetwork: localNetwork,
+
 		persistDir: persistDir,
 	}
 
@@ -222,6 +319,11 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	})
 	g.log.Println("INFO: gateway created, started logging")
 
+	// Load the static key, generating one if the gateway is new.
+	if err := g.loadKey(); err != nil {
+		return nil, err
+	}
+
 	// Establish that the peerTG must complete shutdown before the primary
 	// thread group completes shutdown.
 	g.threads.OnStop(func() {
@@ -234,11 +336,13 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	// Register RPCs.
 	g.RegisterRPC("ShareNodes", g.shareNodes)
 	g.RegisterRPC("DiscoverIP", g.discoverPeerIP)
+	g.RegisterRPC("Gossip", g.receiveTopicMessage)
 	g.RegisterConnectCall("ShareNodes", g.requestNodes)
 	// Establish the de-registration of the RPCs.
 	g.threads.OnStop(func() {
 		g.UnregisterRPC("ShareNodes")
 		g.UnregisterRPC("DiscoverIP")
+		g.UnregisterRPC("Gossip")
 		g.UnregisterConnectCall("ShareNodes")
 	})
 
@@ -247,8 +351,24 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	if loadErr := g.load(); loadErr != nil && !os.IsNotExist(loadErr) {
 		return nil, loadErr
 	}
+	// Load the settings. If they don't exist, there are no bandwidth limits.
+	if loadErr := g.loadSettings(); loadErr != nil && !os.IsNotExist(loadErr) {
+		return nil, loadErr
+	}
+	// Load the ban list. If it doesn't exist, no hosts are banned.
+	if loadErr := g.loadBans(); loadErr != nil && !os.IsNotExist(loadErr) {
+		return nil, loadErr
+	}
+	// Load the blacklist and whitelist. If they don't exist, all peers are
+	// accepted.
+	if loadErr := g.loadPeerLists(); loadErr != nil && !os.IsNotExist(loadErr) {
+		return nil, loadErr
+	}
 	// Spawn the thread to periodically save the gateway.
 	go g.threadedSaveLoop()
+	// Spawn the thread that notifies subscribers of connecting and
+	// disconnecting peers.
+	go g.threadedDeliverPeerEvents()
 	// Make sure that the gateway saves after shutdown.
 	g.threads.AfterStop(func() {
 		g.mu.Lock()
@@ -269,39 +389,23 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 		}
 	}
 
-	// Create the listener which will listen for new connections from peers.
-	permanentListenClosedChan := make(chan struct{})
-	g.listener, err = net.Listen("tcp", addr)
-	if err != nil {
+	// Create the listeners which will listen for new connections from peers.
+	// If the host of an address is left empty, as in ":9981", its listener
+	// accepts both IPv4 and IPv6 connections.
+	if err := g.managedListen(splitListenAddresses(addr)); err != nil {
 		return nil, err
 	}
-	// Automatically close the listener when g.threads.Stop() is called.
-	g.threads.OnStop(func() {
-		err := g.listener.Close()
-		if err != nil {
-			g.log.Println("WARN: closing the listener failed:", err)
-		}
-		<-permanentListenClosedChan
-	})
-	// Set the address and port of the gateway.
-	host, port, err := net.SplitHostPort(g.listener.Addr().String())
-	g.port = port
-	if err != nil {
-		return nil, err
-	}
-
-	if ip := net.ParseIP(host); ip.IsUnspecified() && ip != nil {
-		// if host is unspecified, set a dummy one for now.
-		host = "localhost"
+	// Automatically close the listeners when g.threads.Stop() is called.
+	g.threads.OnStop(g.managedCloseListeners)
+
+	// Set myAddr equal to the address returned by the first listener. It will
+	// be overwritten by threadedLearnHostname later on. A gateway that runs
+	// as a hidden service announces the onion address instead.
+	g.myAddr = listenerAddress(g.listeners[0])
+	if proxy.HiddenService != "" {
+		g.myAddr = proxy.HiddenService
 	}
 
-	// Set myAddr equal to the address returned by the listener. It will be
-	// overwritten by threadedLearnHostname later on.
-	g.myAddr = modules.NetAddress(net.JoinHostPort(host, port))
-
-	// Spawn the peer connection listener.
-	go g.permanentListen(permanentListenClosedChan)
-
 	// Spawn the peer manager and provide tools for ensuring clean shutdown.
 	peerManagerClosedChan := make(chan struct{})
 	g.threads.OnStop(func() {
@@ -316,6 +420,11 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	})
 	go g.permanentNodeManager(nodeManagerClosedChan)
 
+	// Add the nodes of the DNS seeds to the node list in the background.
+	if bootstrap {
+		go g.threadedResolveDNSSeeds()
+	}
+
 	// Spawn the node purger and provide tools for ensuring clean shutdown.
 	nodePurgerClosedChan := make(chan struct{})
 	g.threads.OnStop(func() {
@@ -323,9 +432,20 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	})
 	go g.permanentNodePurger(nodePurgerClosedChan)
 
+	// Spawn the feeler and provide tools for ensuring clean shutdown.
+	feelerClosedChan := make(chan struct{})
+	g.threads.OnStop(func() {
+		<-feelerClosedChan
+	})
+	go g.permanentFeeler(feelerClosedChan)
+
 	// Spawn threads to take care of port forwarding and hostname discovery.
-	go g.threadedForwardPort(g.port)
-	go g.threadedLearnHostname()
+	// Both are skipped when using a proxy, as they would reveal the node's IP
+	// address.
+	if !proxy.enabled() {
+		go g.threadedForwardPort(g.port)
+		go g.threadedLearnHostname()
+	}
 
 	return g, nil
 }
@@ -75,10 +75,10 @@ func TestAddress(t *testing.T) {
 	if g.Address() != g.myAddr {
 		t.Fatal("Address does not return g.myAddr")
 	}
-	if g.Address() != modules.NetAddress(g.listener.Addr().String()) {
-		t.Fatalf("wrong address: expected %v, got %v", g.listener.Addr(), g.Address())
+	if g.Address() != modules.NetAddress(g.listeners[0].Addr().String()) {
+		t.Fatalf("wrong address: expected %v, got %v", g.listeners[0].Addr(), g.Address())
 	}
-	host := modules.NetAddress(g.listener.Addr().String()).Host()
+	host := modules.NetAddress(g.listeners[0].Addr().String()).Host()
 	ip := net.ParseIP(host)
 	if ip == nil {
 		t.Fatal("address is not an IP address")
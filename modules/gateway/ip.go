@@ -36,8 +36,19 @@ func (g *Gateway) managedIPFromPeers() (string, error) {
 			return "", errors.New("failed to discover ip in time")
 		default:
 		}
-		// Get peers
+		// Get peers. If the gateway prefers an address family, only the
+		// peers of that family are asked, as the others would report an
+		// address of another family.
 		peers := g.Peers()
+		if family, _ := g.preferredFamily(); family != "" {
+			familyPeers := peers[:0]
+			for _, p := range peers {
+				if addressFamily(p.NetAddress.Host()) == family {
+					familyPeers = append(familyPeers, p)
+				}
+			}
+			peers = familyPeers
+		}
 		// Check if there are enough peers. Otherwise wait.
 		if len(peers) < minPeersForIPDiscovery {
 			g.managedSleep(peerDiscoveryRetryInterval)
@@ -0,0 +1,198 @@
+package gateway
+
+// listen.go implements the listeners of the gateway. The gateway can listen on
+// several addresses at once, for example on an IPv4 and an IPv6 address, or on
+// specific interfaces of a host. The listen addresses can be changed at
+// runtime; existing peer connections are not affected by a change, as they no
+// longer depend on the listener that accepted them. The port of the first
+// listener is the port that the gateway announces to its peers.
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errNoListenAddresses = errors.New("at least one listen address is required")
+)
+
+// A gatewayListener is a listener of the gateway, together with the address
+// that it was opened on.
+type gatewayListener struct {
+	net.Listener
+
+	// addr is the address that was requested for the listener, such as
+	// ":9981". It differs from the address of the listener if the port was
+	// chosen by the operating system.
+	addr string
+
+	// closed is closed when the thread that accepts the connections of the
+	// listener has returned.
+	closed chan struct{}
+}
+
+// splitListenAddresses splits a comma-separated list of listen addresses.
+func splitListenAddresses(addrs string) []string {
+	var split []string
+	for _, addr := range strings.Split(addrs, ",") {
+		split = append(split, strings.TrimSpace(addr))
+	}
+	return split
+}
+
+// listenerAddress returns the address of a listener, replacing an unspecified
+// host with localhost.
+func listenerAddress(l net.Listener) modules.NetAddress {
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return modules.NetAddress(net.JoinHostPort(host, port))
+}
+
+// managedListen makes the gateway listen on the given addresses. Listeners on
+// addresses that the gateway already listens on are kept, other listeners are
+// closed. If any address cannot be listened on, the previous listeners are
+// kept and an error is returned. If the port of the first listener changes,
+// the address of the gateway changes accordingly.
+func (g *Gateway) managedListen(addrs []string) error {
+	if len(addrs) == 0 {
+		return errNoListenAddresses
+	}
+	g.listenMu.Lock()
+	defer g.listenMu.Unlock()
+
+	// Open the listeners for the new addresses.
+	g.mu.RLock()
+	existing := make(map[string]gatewayListener)
+	for _, l := range g.listeners {
+		existing[l.addr] = l
+	}
+	g.mu.RUnlock()
+	var listeners, opened []gatewayListener
+	for _, addr := range addrs {
+		if l, ok := existing[addr]; ok {
+			listeners = append(listeners, l)
+			delete(existing, addr)
+			continue
+		}
+		nl, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range opened {
+				l.Close()
+			}
+			return err
+		}
+		l := gatewayListener{
+			Listener: nl,
+			addr:     addr,
+			closed:   make(chan struct{}),
+		}
+		listeners = append(listeners, l)
+		opened = append(opened, l)
+	}
+
+	// Replace the listeners and update the address of the gateway.
+	g.mu.Lock()
+	if len(g.listeners) > 0 {
+		oldAddr := listenerAddress(g.listeners[0])
+		newAddr := listenerAddress(listeners[0])
+		if g.myAddr == oldAddr {
+			g.myAddr = newAddr
+		} else if g.staticProxy.HiddenService == "" {
+			g.myAddr = modules.NetAddress(net.JoinHostPort(g.myAddr.Host(), newAddr.Port()))
+		}
+	}
+	oldPort := g.port
+	g.port = listenerAddress(listeners[0]).Port()
+	g.listeners = listeners
+	g.mu.Unlock()
+	for _, l := range opened {
+		go g.threadedListen(l)
+	}
+	for _, l := range existing {
+		if err := l.Close(); err != nil {
+			g.log.Println("WARN: closing the listener failed:", err)
+		}
+		<-l.closed
+	}
+	if oldPort != "" && oldPort != g.port && !g.staticProxy.enabled() {
+		go g.threadedForwardPort(g.port)
+	}
+	for _, l := range opened {
+		g.log.Println("INFO: listening for peers on", l.Addr())
+	}
+	return nil
+}
+
+// managedCloseListeners closes all listeners of the gateway and waits for
+// their threads to return.
+func (g *Gateway) managedCloseListeners() {
+	g.listenMu.Lock()
+	defer g.listenMu.Unlock()
+	g.mu.Lock()
+	listeners := g.listeners
+	g.listeners = nil
+	g.mu.Unlock()
+	for _, l := range listeners {
+		if err := l.Close(); err != nil {
+			g.log.Println("WARN: closing the listener failed:", err)
+		}
+		<-l.closed
+	}
+}
+
+// threadedListen handles incoming connection requests on a listener until
+// the listener is closed. If a connection is accepted, the peer will be added
+// to the Gateway's peer list.
+func (g *Gateway) threadedListen(l gatewayListener) {
+	// Signal that the thread has completed upon returning.
+	defer close(l.closed)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			g.log.Debugln("[PL] Closing listener:", err)
+			return
+		}
+
+		setKeepAlive(conn)
+		go g.threadedAcceptConn(newStatsConn(g.newRLConn(conn)))
+
+		// Sleep after each accept. This limits the rate at which the Gateway
+		// will accept new connections. The intent here is to prevent new
+		// incoming connections from kicking out old ones before they have a
+		// chance to request additional nodes.
+		select {
+		case <-time.After(acceptInterval):
+		case <-g.threads.StopChan():
+			return
+		}
+	}
+}
+
+// ListenAddresses returns the addresses that the gateway listens on.
+func (g *Gateway) ListenAddresses() []modules.NetAddress {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	addrs := make([]modules.NetAddress, 0, len(g.listeners))
+	for _, l := range g.listeners {
+		addrs = append(addrs, modules.NetAddress(l.Addr().String()))
+	}
+	return addrs
+}
+
+// SetListenAddresses changes the addresses that the gateway listens on,
+// without disconnecting any peers. The port of the first address is announced
+// to peers.
+func (g *Gateway) SetListenAddresses(addrs []string) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	return g.managedListen(addrs)
+}
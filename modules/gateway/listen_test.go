@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestMultipleListeners checks that a gateway accepts peers on every address
+// that it listens on, and announces the port of the first one.
+func TestMultipleListeners(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1, err := New("127.0.0.1:0, 127.0.0.1:0", false, build.TempDir("gateway", t.Name()+"1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	addrs := g1.ListenAddresses()
+	if len(addrs) != 2 || addrs[0] == addrs[1] {
+		t.Fatal("expected two listen addresses, got", addrs)
+	} else if g1.Address() != addrs[0] {
+		t.Fatalf("gateway announces %v instead of its first listen address %v", g1.Address(), addrs[0])
+	}
+
+	// g2 should be able to connect through the second listener.
+	if err := g2.Connect(addrs[1]); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g1.Peers()) != 1 {
+			return errors.New("g1 did not accept the connection")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSetListenAddresses checks that the listen addresses of a gateway can be
+// changed at runtime without disconnecting its peers.
+func TestSetListenAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g2.Connect(g1.Address()); err != nil {
+		t.Fatal(err)
+	}
+	oldAddr := g1.Address()
+
+	// Invalid addresses should leave the current listeners in place.
+	if err := g1.SetListenAddresses(nil); err != errNoListenAddresses {
+		t.Fatal("expected errNoListenAddresses, got", err)
+	}
+	if err := g1.SetListenAddresses([]string{"127.0.0.1:0", "foo"}); err == nil {
+		t.Fatal("expected an invalid listen address to be rejected")
+	}
+	if addrs := g1.ListenAddresses(); len(addrs) != 1 || addrs[0] != oldAddr {
+		t.Fatal("listeners changed after a failed rebind:", addrs)
+	}
+
+	// Rebind to a new port.
+	if err := g1.SetListenAddresses([]string{"127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+	newAddr := g1.Address()
+	if addrs := g1.ListenAddresses(); len(addrs) != 1 || addrs[0] != newAddr || newAddr == oldAddr {
+		t.Fatalf("expected to listen on a new address, got %v and address %v", addrs, newAddr)
+	}
+	if conn, err := net.Dial("tcp", string(oldAddr)); err == nil {
+		conn.Close()
+		t.Fatal("old listener is still open")
+	}
+	if len(g2.Peers()) != 1 {
+		t.Fatal("rebinding disconnected an existing peer")
+	}
+
+	// New peers should be able to connect to the new address.
+	g3 := newNamedTestingGateway(t, "3")
+	defer g3.Close()
+	if err := g3.Connect(newAddr); err != nil {
+		t.Fatal(err)
+	}
+	if peers := g3.Peers(); len(peers) != 1 || peers[0].NetAddress != modules.NetAddress(newAddr) {
+		t.Fatal("g3 did not connect to the new address:", peers)
+	}
+}
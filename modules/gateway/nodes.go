@@ -3,10 +3,10 @@ package gateway
 import (
 	"errors"
 	"fmt"
-	"net"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -24,24 +24,61 @@ var (
 type node struct {
 	NetAddress      modules.NetAddress `json:"netaddress"`
 	WasOutboundPeer bool               `json:"wasoutboundpeer"`
+
+	// Bootstrap nodes were added by the operator and are never pruned from
+	// the node list. Pinned bootstrap nodes are additionally reconnected
+	// whenever the connection to them is lost.
+	Bootstrap bool `json:"bootstrap"`
+	Pinned    bool `json:"pinned"`
+
+	// SuccessfulConnections and FailedConnections count the outcomes of the
+	// attempts to reach the node, and are used to score its quality.
+	SuccessfulConnections uint64 `json:"successfulconnections"`
+	FailedConnections     uint64 `json:"failedconnections"`
+
+	// Tried is true if the node is in the tried table, and Source is the
+	// network group of the peer that shared the node. Together they decide
+	// the bucket of the node.
+	Tried  bool   `json:"tried"`
+	Source string `json:"source"`
+
+	// KeyHash is the hash of the static key that the node authenticated
+	// with when it last negotiated encryption. Nodes that have negotiated
+	// encryption must do so on every later connection.
+	KeyHash crypto.Hash `json:"keyhash"`
+}
+
+// score returns the quality of the node as the fraction of connection
+// attempts that succeeded. Nodes that have not been tried yet get a neutral
+// score of 0.5.
+func (n *node) score() float64 {
+	return float64(n.SuccessfulConnections+1) / float64(n.SuccessfulConnections+n.FailedConnections+2)
 }
 
 // addNode adds an address to the set of nodes on the network.
 func (g *Gateway) addNode(addr modules.NetAddress) error {
+	return g.addNodeFrom(addr, addr)
+}
+
+// addNodeFrom adds an address that was shared by source to the set of nodes
+// on the network.
+func (g *Gateway) addNodeFrom(addr, source modules.NetAddress) error {
 	if addr == g.myAddr {
 		return errOurAddress
 	} else if _, exists := g.nodes[addr]; exists {
 		return errNodeExists
 	} else if addr.IsStdValid() != nil {
 		return errors.New("address is not valid: " + string(addr))
-	} else if net.ParseIP(addr.Host()) == nil {
+	} else if !g.staticDialableHost(addr.Host()) {
 		return errors.New("address must be an IP address: " + string(addr))
+	} else if err := g.acceptableHost(addr.Host()); err != nil && err != errPeerNotWhitelisted {
+		return err
 	}
-	g.nodes[addr] = &node{
+	return g.insertNode(&node{
 		NetAddress:      addr,
 		WasOutboundPeer: false,
-	}
-	return nil
+		Source:          networkGroup(source),
+	})
 }
 
 // staticPingNode verifies that there is a reachable node at the provided address
@@ -92,11 +129,38 @@ func (g *Gateway) staticPingNode(addr modules.NetAddress) error {
 	return nil
 }
 
+// recordConnectionAttempt updates the quality score of a node after an
+// attempt to reach it. Unknown nodes are ignored.
+func (g *Gateway) recordConnectionAttempt(addr modules.NetAddress, success bool) {
+	n, exists := g.nodes[addr]
+	if !exists {
+		return
+	}
+	if success {
+		n.SuccessfulConnections++
+		g.markTried(addr)
+	} else {
+		n.FailedConnections++
+	}
+}
+
+// pruneNode removes an unreachable node from the gateway, but only if there
+// are enough nodes in the node list and the node is not a bootstrap node.
+func (g *Gateway) pruneNode(addr modules.NetAddress) bool {
+	if n, exists := g.nodes[addr]; !exists || n.Bootstrap || len(g.nodes) <= pruneNodeListLen {
+		return false
+	}
+	g.removeNode(addr)
+	return true
+}
+
 // removeNode will remove a node from the gateway.
 func (g *Gateway) removeNode(addr modules.NetAddress) error {
-	if _, exists := g.nodes[addr]; !exists {
+	n, exists := g.nodes[addr]
+	if !exists {
 		return errors.New("no record of that node")
 	}
+	delete(g.addrTables.bucket(n), addr)
 	delete(g.nodes, addr)
 	return nil
 }
@@ -123,6 +187,21 @@ func (g *Gateway) randomNode() (modules.NetAddress, error) {
 	return "", errNoPeers
 }
 
+// gossipableNode returns true if the node may be exchanged with the peer at
+// remoteNA through the ShareNodes RPC. Loopback nodes are only exchanged with
+// loopback peers. Private nodes, such as 192.168.x.x, are not reachable from
+// other networks and would pollute the node lists of remote peers, so they are
+// only exchanged with loopback or private peers, and only if the gateway runs
+// on a local network.
+func (g *Gateway) gossipableNode(node, remoteNA modules.NetAddress) bool {
+	if node.IsLoopback() {
+		return remoteNA.IsLoopback()
+	} else if node.IsLocal() {
+		return g.staticLocalNetwork && remoteNA.IsLocal()
+	}
+	return true
+}
+
 // shareNodes is the receiving end of the ShareNodes RPC. It writes up to 10
 // randomly selected nodes to the caller.
 func (g *Gateway) shareNodes(conn modules.PeerConn) error {
@@ -138,26 +217,35 @@ func (g *Gateway) shareNodes(conn modules.PeerConn) error {
 		// Gather candidates for sharing.
 		gnodes := make([]modules.NetAddress, 0, len(g.nodes))
 		for node := range g.nodes {
-			// Don't share local peers with remote peers. That means that if 'node'
-			// is loopback, it will only be shared if the remote peer is also
-			// loopback. And if 'node' is private, it will only be shared if the
-			// remote peer is either the loopback or is also private.
-			if node.IsLoopback() && !remoteNA.IsLoopback() {
-				continue
-			}
-			if node.IsLocal() && !remoteNA.IsLocal() {
-				continue
+			if g.gossipableNode(node, remoteNA) {
+				gnodes = append(gnodes, node)
 			}
-			gnodes = append(gnodes, node)
 		}
 
 		// Iterate through the random permutation of nodes and select the
-		// desirable ones.
-		for _, i := range fastrand.Perm(len(gnodes)) {
-			nodes = append(nodes, gnodes[i])
+		// desirable ones. Up to half of the nodes are selected from the
+		// address family that the peer connected from, so that peers with
+		// only one working address family learn enough nodes they can
+		// reach, while dual-stack peers still learn about both families.
+		perm := fastrand.Perm(len(gnodes))
+		remoteFamily := addressFamily(remoteNA.Host())
+		selected := make(map[int]bool)
+		for _, i := range perm {
+			if uint64(len(nodes)) == (maxSharedNodes+1)/2 {
+				break
+			}
+			if addressFamily(gnodes[i].Host()) == remoteFamily {
+				nodes = append(nodes, gnodes[i])
+				selected[i] = true
+			}
+		}
+		for _, i := range perm {
 			if uint64(len(nodes)) == maxSharedNodes {
 				break
 			}
+			if !selected[i] {
+				nodes = append(nodes, gnodes[i])
+			}
 		}
 	}()
 	return encoding.WriteObject(conn, nodes)
@@ -172,11 +260,17 @@ func (g *Gateway) requestNodes(conn modules.PeerConn) error {
 		return err
 	}
 
+	remoteNA := modules.NetAddress(conn.RemoteAddr().String())
 	g.mu.Lock()
 	changed := false
 	for _, node := range nodes {
-		err := g.addNode(node)
-		if err != nil && err != errNodeExists && err != errOurAddress {
+		canonical, err := canonicalAddress(node)
+		if err == nil && !g.gossipableNode(canonical, remoteNA) {
+			continue
+		} else if err == nil {
+			err = g.addNodeFrom(canonical, conn.RPCAddr())
+		}
+		if err != nil && err != errNodeExists && err != errOurAddress && err != errPeerBanned && err != errPeerBlacklisted {
 			g.log.Printf("WARN: peer '%v' sent the invalid addr '%v'", conn.RPCAddr(), node)
 		}
 		if err == nil {
@@ -267,15 +361,15 @@ func (g *Gateway) permanentNodePurger(closeChan chan struct{}) {
 		// through, which would cause the node to be pruned even though it may
 		// be a good node. Because nodes are plentiful, this is an acceptable
 		// bug.
-		if err = g.staticPingNode(node); err != nil {
-			g.mu.Lock()
-			if len(g.nodes) > pruneNodeListLen {
-				// Check if the number of nodes is still above the threshold.
-				g.removeNode(node)
-				g.log.Debugf("INFO: removing node %q because it could not be reached during a random scan: %v", node, err)
-			}
-			g.mu.Unlock()
+		err = g.staticPingNode(node)
+		g.mu.Lock()
+		g.recordConnectionAttempt(node, err == nil)
+		if err != nil && g.pruneNode(node) {
+			// pruneNode checks if the number of nodes is still above the
+			// threshold.
+			g.log.Debugf("INFO: removing node %q because it could not be reached during a random scan: %v", node, err)
 		}
+		g.mu.Unlock()
 	}
 }
 
@@ -235,6 +235,34 @@ func TestShareNodes(t *testing.T) {
 	}
 }
 
+// TestGossipableNode checks that private nodes are only shared by gateways on a
+// local network, and only with local peers.
+func TestGossipableNode(t *testing.T) {
+	tests := []struct {
+		node, remote modules.NetAddress
+		normal, lan  bool
+	}{
+		{"111.111.111.111:1", "222.222.222.222:1", true, true},
+		{"111.111.111.111:1", "192.168.1.2:1", true, true},
+		{"127.0.0.1:1", "127.0.0.1:2", true, true},
+		{"127.0.0.1:1", "192.168.1.2:1", false, false},
+		{"192.168.1.1:1", "222.222.222.222:1", false, false},
+		{"192.168.1.1:1", "192.168.1.2:1", false, true},
+		{"10.0.0.1:1", "127.0.0.1:1", false, true},
+		{"[fd00::1]:1", "[fd00::2]:1", false, true},
+	}
+	normal := &Gateway{}
+	lan := &Gateway{staticLocalNetwork: true}
+	for _, test := range tests {
+		if normal.gossipableNode(test.node, test.remote) != test.normal {
+			t.Errorf("normal gateway: expected %v to be shared with %v: %v", test.node, test.remote, test.normal)
+		}
+		if lan.gossipableNode(test.node, test.remote) != test.lan {
+			t.Errorf("local network gateway: expected %v to be shared with %v: %v", test.node, test.remote, test.lan)
+		}
+	}
+}
+
 // TestNodesAreSharedOnConnect tests that nodes that a gateway has never seen
 // before are added to the node list when connecting to another gateway that
 // has seen said nodes.
@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// peerListsFile is the name of the file that contains the gateway's
+	// blacklist and whitelist.
+	peerListsFile = "peerlists.json"
+)
+
+var (
+	// peerListsMetadata contains the header and version strings that identify
+	// the gateway's peer lists.
+	peerListsMetadata = persist.Metadata{
+		Header:  "Sia Gateway Peer Lists",
+		Version: "1.3.3",
+	}
+
+	errNotBlacklisted     = errors.New("address is not blacklisted")
+	errNotWhitelisted     = errors.New("address is not whitelisted")
+	errPeerBlacklisted    = errors.New("peer is blacklisted")
+	errPeerNotWhitelisted = errors.New("peer is not whitelisted and the gateway only connects to whitelisted peers")
+)
+
+// listedHost returns true if the host of one of the addresses in the list
+// matches the host. Hosts are compared instead of addresses because inbound
+// connections come from an arbitrary port.
+func listedHost(list map[modules.NetAddress]struct{}, host string) bool {
+	for addr := range list {
+		if addr.Host() == host {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedList returns the addresses of a peer list in sorted order.
+func sortedList(list map[modules.NetAddress]struct{}) []modules.NetAddress {
+	addrs := []modules.NetAddress{}
+	for addr := range list {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i] < addrs[j]
+	})
+	return addrs
+}
+
+// checkListAddress returns an error if the address cannot be added to a peer
+// list.
+func checkListAddress(addr modules.NetAddress) error {
+	if err := addr.IsStdValid(); err != nil {
+		return errors.New("address is not valid: " + err.Error())
+	} else if net.ParseIP(addr.Host()) == nil {
+		return errors.New("address must be an IP address: " + string(addr))
+	}
+	return nil
+}
+
+// acceptableHost returns an error if the gateway should not be connected to a
+// host. Blacklisted hosts are always refused, and whitelisted hosts are
+// accepted even if they have been banned for misbehaving.
+func (g *Gateway) acceptableHost(host string) error {
+	if listedHost(g.blacklist, host) {
+		return errPeerBlacklisted
+	} else if listedHost(g.whitelist, host) {
+		return nil
+	} else if g.isBanned(host) {
+		return errPeerBanned
+	} else if g.whitelistOnly {
+		return errPeerNotWhitelisted
+	}
+	return nil
+}
+
+// disconnectUnacceptable closes the connections to all peers on hosts that are
+// no longer acceptable.
+func (g *Gateway) disconnectUnacceptable() {
+	for addr, p := range g.peers {
+		if err := g.acceptableHost(addr.Host()); err != nil {
+			p.sess.Close()
+			g.removePeer(addr)
+			g.log.Printf("INFO: disconnected from %v: %v", addr, err)
+		}
+	}
+}
+
+// loadPeerLists loads the gateway's blacklist and whitelist from disk.
+func (g *Gateway) loadPeerLists() error {
+	var lists modules.GatewayPeerLists
+	err := persist.LoadJSON(peerListsMetadata, &lists, filepath.Join(g.persistDir, peerListsFile))
+	if err != nil {
+		return err
+	}
+	for _, addr := range lists.Blacklist {
+		g.blacklist[addr] = struct{}{}
+	}
+	for _, addr := range lists.Whitelist {
+		g.whitelist[addr] = struct{}{}
+	}
+	g.whitelistOnly = lists.WhitelistOnly
+	return nil
+}
+
+// savePeerLists saves the gateway's blacklist and whitelist to disk.
+func (g *Gateway) savePeerLists() error {
+	return persist.SaveJSON(peerListsMetadata, g.peerLists(), filepath.Join(g.persistDir, peerListsFile))
+}
+
+// peerLists returns the gateway's blacklist and whitelist.
+func (g *Gateway) peerLists() modules.GatewayPeerLists {
+	return modules.GatewayPeerLists{
+		Blacklist:     sortedList(g.blacklist),
+		Whitelist:     sortedList(g.whitelist),
+		WhitelistOnly: g.whitelistOnly,
+	}
+}
+
+// Blacklist adds an address to the blacklist and removes it from the
+// whitelist. The gateway disconnects from all peers on the address's host,
+// removes them from the node list, and refuses to connect to the host until it
+// is removed from the blacklist.
+func (g *Gateway) Blacklist(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	if err := checkListAddress(addr); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.whitelist, addr)
+	g.blacklist[addr] = struct{}{}
+	g.disconnectUnacceptable()
+	for naddr := range g.nodes {
+		if naddr.Host() == addr.Host() {
+			g.removeNode(naddr)
+		}
+	}
+	return g.savePeerLists()
+}
+
+// Whitelist adds an address to the whitelist and removes it from the
+// blacklist. Whitelisted hosts are never banned, and the address is added to
+// the node list so that the gateway can connect to it.
+func (g *Gateway) Whitelist(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	if err := checkListAddress(addr); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.blacklist, addr)
+	g.whitelist[addr] = struct{}{}
+	delete(g.bans, addr.Host())
+	delete(g.misbehavior, addr.Host())
+	err := g.addNode(addr)
+	if err != nil && err != errNodeExists && err != errOurAddress {
+		return err
+	}
+	return g.savePeerLists()
+}
+
+// RemoveFromBlacklist removes an address from the blacklist.
+func (g *Gateway) RemoveFromBlacklist(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.blacklist[addr]; !exists {
+		return errNotBlacklisted
+	}
+	delete(g.blacklist, addr)
+	return g.savePeerLists()
+}
+
+// RemoveFromWhitelist removes an address from the whitelist. If the gateway
+// only connects to whitelisted peers, it disconnects from the peers on the
+// address's host unless the host is still whitelisted through another address.
+func (g *Gateway) RemoveFromWhitelist(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.whitelist[addr]; !exists {
+		return errNotWhitelisted
+	}
+	delete(g.whitelist, addr)
+	g.disconnectUnacceptable()
+	return g.savePeerLists()
+}
+
+// PeerLists returns the gateway's blacklist and whitelist, and whether the
+// gateway only connects to whitelisted peers.
+func (g *Gateway) PeerLists() modules.GatewayPeerLists {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.peerLists()
+}
+
+// SetWhitelistOnly sets whether the gateway only connects to whitelisted
+// peers. When the mode is enabled, the gateway disconnects from all peers that
+// are not whitelisted.
+func (g *Gateway) SetWhitelistOnly(whitelistOnly bool) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.whitelistOnly = whitelistOnly
+	g.disconnectUnacceptable()
+	return g.savePeerLists()
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestBlacklist checks that blacklisted peers are disconnected and refused,
+// and that the blacklist survives a restart.
+func TestBlacklist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Blacklist("foo"); err == nil {
+		t.Fatal("expected an invalid address to be rejected")
+	}
+	if err := g1.Blacklist(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("blacklisted peer was not disconnected")
+	}
+	if err := g1.Connect(g2.Address()); err != errPeerBlacklisted {
+		t.Fatal("expected errPeerBlacklisted, got", err)
+	}
+	if err := g2.Connect(g1.Address()); err == nil {
+		t.Fatal("blacklisted peer was able to connect")
+	}
+
+	// The blacklist should be loaded after a restart.
+	if err := g1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g1, err := New("localhost:0", false, g1.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	lists := g1.PeerLists()
+	if len(lists.Blacklist) != 1 || lists.Blacklist[0] != g2.Address() {
+		t.Fatal("blacklist was not loaded:", lists)
+	}
+
+	// Removing the peer from the blacklist should allow it to connect again.
+	if err := g1.RemoveFromBlacklist(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.RemoveFromBlacklist(g2.Address()); err != errNotBlacklisted {
+		t.Fatal("expected errNotBlacklisted, got", err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWhitelistOnly checks that a gateway that only connects to whitelisted
+// peers disconnects from and refuses all other peers.
+func TestWhitelistOnly(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.SetWhitelistOnly(true); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("peer that is not whitelisted was not disconnected")
+	}
+	if err := g1.Connect(g2.Address()); err != errPeerNotWhitelisted {
+		t.Fatal("expected errPeerNotWhitelisted, got", err)
+	}
+	if err := g1.Whitelist(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPeerLists checks how the blacklist and whitelist interact with each
+// other, with bans, and with the peer manager's node list.
+func TestPeerLists(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	whitelisted := modules.NetAddress("111.111.111.111:1111")
+	other := modules.NetAddress("222.222.222.222:2222")
+	if err := g.Whitelist(whitelisted); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetWhitelistOnly(true); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Lock()
+	if err := g.addNode(other); err != nil {
+		t.Fatal(err)
+	}
+	nodes := g.buildPeerManagerNodeList()
+	g.mu.Unlock()
+	if len(nodes) != 1 || nodes[0] != whitelisted {
+		t.Fatal("node list should only contain the whitelisted node:", nodes)
+	}
+
+	// Whitelisted hosts should never be banned.
+	g.PenalizePeer(whitelisted, 2*banThreshold)
+	if len(g.Bans()) != 0 {
+		t.Fatal("whitelisted host was banned:", g.Bans())
+	}
+
+	// Blacklisting the address should remove it from the whitelist and the
+	// node list.
+	if err := g.Blacklist(whitelisted); err != nil {
+		t.Fatal(err)
+	}
+	lists := g.PeerLists()
+	if len(lists.Whitelist) != 0 || len(lists.Blacklist) != 1 || !lists.WhitelistOnly {
+		t.Fatal("wrong peer lists:", lists)
+	}
+	g.mu.RLock()
+	_, exists := g.nodes[whitelisted]
+	hostErr := g.acceptableHost(whitelisted.Host())
+	g.mu.RUnlock()
+	if exists {
+		t.Fatal("blacklisted node was not removed from the node list")
+	}
+	if hostErr != errPeerBlacklisted {
+		t.Fatal("expected errPeerBlacklisted, got", hostErr)
+	}
+	if err := g.RemoveFromWhitelist(whitelisted); err != errNotWhitelisted {
+		t.Fatal("expected errNotWhitelisted, got", err)
+	}
+}
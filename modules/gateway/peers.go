@@ -37,15 +37,35 @@ func (s invalidVersionError) Error() string {
 type peer struct {
 	modules.Peer
 	sess streamSession
+
+	// connectedAt is the time at which the peer was added.
+	connectedAt time.Time
+
+	// topicWindow is the start of the current rate limiting window, and
+	// topicCounts holds the number of messages that the peer sent on each
+	// topic during it.
+	topicWindow time.Time
+	topicCounts map[string]int
+
+	// stats holds the traffic counters of the peer's connection.
+	stats *peerStats
+
+	// stalls is the number of RPCs in a row that the peer let time out
+	// without sending any data.
+	stalls int
 }
 
 // sessionHeader is sent after the initial version exchange. It prevents peers
 // on different blockchains from connecting to each other, and prevents the
-// gateway from connecting to itself.
+// gateway from connecting to itself. Peers also advertise the version of the
+// gateway protocol and the optional RPCs that they support.
 type sessionHeader struct {
 	GenesisID  types.BlockID
 	UniqueID   gatewayID
 	NetAddress modules.NetAddress
+
+	ProtocolVersion uint64
+	Capabilities    []string
 }
 
 func (p *peer) open() (modules.PeerConn, error) {
@@ -67,7 +87,11 @@ func (p *peer) accept() (modules.PeerConn, error) {
 // addPeer adds a peer to the Gateway's peer list, spawns a listener thread to
 // handle its requests and increments the remotePeers accordingly
 func (g *Gateway) addPeer(p *peer) {
+	if p.connectedAt.IsZero() {
+		p.connectedAt = time.Now()
+	}
 	g.peers[p.NetAddress] = p
+	g.queuePeerEvent(p.Peer, true)
 	go g.threadedListenPeer(p)
 }
 
@@ -107,33 +131,6 @@ func (g *Gateway) randomOutboundPeer() (modules.NetAddress, error) {
 	return addrs[fastrand.Intn(len(addrs))], nil
 }
 
-// permanentListen handles incoming connection requests. If the connection is
-// accepted, the peer will be added to the Gateway's peer list.
-func (g *Gateway) permanentListen(closeChan chan struct{}) {
-	// Signal that the permanentListen thread has completed upon returning.
-	defer close(closeChan)
-
-	for {
-		conn, err := g.listener.Accept()
-		if err != nil {
-			g.log.Debugln("[PL] Closing permanentListen:", err)
-			return
-		}
-
-		go g.threadedAcceptConn(conn)
-
-		// Sleep after each accept. This limits the rate at which the Gateway
-		// will accept new connections. The intent here is to prevent new
-		// incoming connections from kicking out old ones before they have a
-		// chance to request additional nodes.
-		select {
-		case <-time.After(acceptInterval):
-		case <-g.threads.StopChan():
-			return
-		}
-	}
-}
-
 // threadedAcceptConn adds a connecting node as a peer.
 func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 	if g.threads.Add() != nil {
@@ -146,6 +143,18 @@ func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 	addr := modules.NetAddress(conn.RemoteAddr().String())
 	g.log.Debugf("INFO: %v wants to connect", addr)
 
+	g.mu.RLock()
+	hostErr := g.acceptableHost(addr.Host())
+	if hostErr == nil && !g.canAcceptPeer(addr) {
+		hostErr = errNoFreeSlots
+	}
+	g.mu.RUnlock()
+	if hostErr != nil {
+		g.log.Debugf("INFO: %v wanted to connect, but was refused: %v", addr, hostErr)
+		conn.Close()
+		return
+	}
+
 	remoteVersion, err := acceptVersionHandshake(conn, build.Version)
 	if err != nil {
 		g.log.Debugf("INFO: %v wanted to connect but version handshake failed: %v", addr, err)
@@ -190,20 +199,24 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	g.log.Debugln("Sending sessionHeader with address", g.myAddr, g.myAddr.IsLocal())
 	// Perform header handshake.
 	g.mu.RLock()
-	ourHeader := sessionHeader{
-		GenesisID:  types.GenesisID,
-		UniqueID:   g.staticId,
-		NetAddress: g.myAddr,
-	}
+	ourHeader := g.ourSessionHeader()
 	g.mu.RUnlock()
 
+	stats := connStats(conn)
 	remoteHeader, err := exchangeRemoteHeader(conn, ourHeader)
 	if err != nil {
 		return err
 	}
-	if err := exchangeOurHeader(conn, ourHeader); err != nil {
+	if err := timedHeaderExchange(conn, ourHeader); err != nil {
 		return err
 	}
+	encrypted := supportsEncryption(ourHeader) && supportsEncryption(remoteHeader)
+	if encrypted {
+		conn, err = encryptionHandshake(conn, ourHeader, remoteHeader, g.staticKey, false)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Get the remote address on which the connecting peer is listening on.
 	// This means we need to combine the incoming connections ip address with
@@ -211,6 +224,12 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	remoteIP := modules.NetAddress(conn.RemoteAddr().String()).Host()
 	remotePort := remoteHeader.NetAddress.Port()
 	remoteAddr := modules.NetAddress(net.JoinHostPort(remoteIP, remotePort))
+	// Peers that connect through a hidden service arrive from the local Tor
+	// client, so their announced onion address is used instead. It is only
+	// added to the node list if it can be pinged.
+	if g.staticProxy.enabled() && isOnionHost(remoteHeader.NetAddress.Host()) {
+		remoteAddr = remoteHeader.NetAddress
+	}
 
 	// Accept the peer.
 	peer := &peer{
@@ -221,14 +240,27 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 			Local: remoteAddr.IsLocal(),
 			// Ignoring claimed IP address (which should be == to the socket address)
 			// by the host but keeping note of the port number so we can call back
-			NetAddress: remoteAddr,
-			Version:    remoteVersion,
+			NetAddress:      remoteAddr,
+			Version:         remoteVersion,
+			ProtocolVersion: remoteHeader.ProtocolVersion,
+			Capabilities:    remoteHeader.Capabilities,
+			Encrypted:       encrypted,
 		},
-		sess: newServerStream(conn, remoteVersion),
+		sess:  newServerStream(conn, remoteVersion),
+		stats: stats,
 	}
 	g.mu.Lock()
-	g.acceptPeer(peer)
+	err = g.checkPeerKey(remoteAddr, conn)
+	if err == nil {
+		err = g.acceptPeer(peer)
+	}
+	if err == nil {
+		g.recordPeerKey(remoteAddr, conn)
+	}
 	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
 
 	// Attempt to ping the supplied address. If successful, we will add
 	// remoteHeader.NetAddress to our node list after accepting the peer. We
@@ -239,6 +271,7 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 		if err == nil {
 			g.mu.Lock()
 			g.addNode(remoteAddr)
+			g.recordPeerKey(remoteAddr, conn)
 			g.mu.Unlock()
 		}
 	}()
@@ -246,44 +279,31 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	return nil
 }
 
-// acceptPeer makes room for the peer if necessary by kicking out existing
-// peers, then adds the peer to the peer list.
-func (g *Gateway) acceptPeer(p *peer) {
-	// If we are not fully connected, add the peer without kicking any out.
-	if len(g.peers) < fullyConnectedThreshold {
+// acceptPeer makes room for the inbound peer if necessary by evicting an
+// existing inbound peer, then adds the peer to the peer list. If all inbound
+// slots are taken and no peer can be evicted, the peer is only added if it is
+// a local peer.
+func (g *Gateway) acceptPeer(p *peer) error {
+	// If there is a free inbound slot, add the peer without evicting any.
+	inbound, _ := g.peerSlots()
+	if g.numInboundPeers() < inbound {
 		g.addPeer(p)
-		return
+		return nil
 	}
 
-	// Select a peer to kick. Outbound peers and local peers are not
-	// available to be kicked.
-	var addrs []modules.NetAddress
-	for addr, peer := range g.peers {
-		// Do not kick outbound peers or local peers.
-		if !peer.Inbound || peer.Local {
-			continue
+	evict, ok := g.evictionCandidate(p.NetAddress.Host())
+	if !ok {
+		if p.Local {
+			g.addPeer(p)
+			return nil
 		}
-
-		// Prefer kicking a peer with the same hostname.
-		if addr.Host() == p.NetAddress.Host() {
-			addrs = []modules.NetAddress{addr}
-			break
-		}
-		addrs = append(addrs, addr)
-	}
-	if len(addrs) == 0 {
-		// There is nobody suitable to kick, therefore do not kick anyone.
-		g.addPeer(p)
-		return
+		return errNoFreeSlots
 	}
-
-	// Of the remaining options, select one at random.
-	kick := addrs[fastrand.Intn(len(addrs))]
-
-	g.peers[kick].sess.Close()
-	delete(g.peers, kick)
-	g.log.Printf("INFO: disconnected from %v to make room for %v\n", kick, p.NetAddress)
+	g.peers[evict].sess.Close()
+	g.removePeer(evict)
+	g.log.Printf("INFO: disconnected from %v to make room for %v\n", evict, p.NetAddress)
 	g.addPeer(p)
+	return nil
 }
 
 // acceptableVersion returns an error if the version is unacceptable.
@@ -380,25 +400,39 @@ func exchangeRemoteHeader(conn net.Conn, ourHeader sessionHeader) (sessionHeader
 	return remoteHeader, nil
 }
 
-// managedConnectPeer connects to peers >= v1.3.1. The peer is added as a
-// node and a peer. The peer is only added if a nil error is returned.
-func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) error {
+// managedConnectPeer connects to peers >= v1.3.1 and returns the peer's session
+// header. If both peers support encryption, the returned connection is
+// encrypted. An error is returned if the peer negotiated encryption before,
+// but does not on this connection or authenticates with a different key. The
+// peer is added as a node and a peer. The peer is only added if a nil error is
+// returned.
+func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) (net.Conn, sessionHeader, error) {
 	g.log.Debugln("Sending sessionHeader with address", g.myAddr, g.myAddr.IsLocal())
 	// Perform header handshake.
 	g.mu.RLock()
-	ourHeader := sessionHeader{
-		GenesisID:  types.GenesisID,
-		UniqueID:   g.staticId,
-		NetAddress: g.myAddr,
-	}
+	ourHeader := g.ourSessionHeader()
 	g.mu.RUnlock()
 
-	if err := exchangeOurHeader(conn, ourHeader); err != nil {
-		return err
-	} else if _, err := exchangeRemoteHeader(conn, ourHeader); err != nil {
-		return err
+	if err := timedHeaderExchange(conn, ourHeader); err != nil {
+		return nil, sessionHeader{}, err
 	}
-	return nil
+	remoteHeader, err := exchangeRemoteHeader(conn, ourHeader)
+	if err != nil {
+		return nil, sessionHeader{}, err
+	}
+	if supportsEncryption(ourHeader) && supportsEncryption(remoteHeader) {
+		conn, err = encryptionHandshake(conn, ourHeader, remoteHeader, g.staticKey, true)
+		if err != nil {
+			return nil, sessionHeader{}, err
+		}
+	}
+	g.mu.RLock()
+	err = g.checkPeerKey(remoteAddr, conn)
+	g.mu.RUnlock()
+	if err != nil {
+		return nil, sessionHeader{}, err
+	}
+	return conn, remoteHeader, nil
 }
 
 // managedConnect establishes a persistent connection to a peer, and adds it to
@@ -414,14 +448,17 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	if err := addr.IsStdValid(); err != nil {
 		return errors.New("can't connect to invalid address")
 	}
-	if net.ParseIP(addr.Host()) == nil {
+	if !g.staticDialableHost(addr.Host()) {
 		return errors.New("address must be an IP address")
 	}
 	g.mu.RLock()
 	_, exists := g.peers[addr]
+	hostErr := g.acceptableHost(addr.Host())
 	g.mu.RUnlock()
 	if exists {
 		return errPeerExists
+	} else if hostErr != nil {
+		return hostErr
 	}
 
 	// Dial the peer and perform peer initialization.
@@ -429,6 +466,7 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	if err != nil {
 		return err
 	}
+	conn = newStatsConn(conn)
 
 	// Perform peer initialization.
 	remoteVersion, err := connectVersionHandshake(conn, build.Version)
@@ -437,8 +475,10 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 		return err
 	}
 
+	var remoteHeader sessionHeader
+	var peerConn net.Conn
 	if build.VersionCmp(remoteVersion, minimumAcceptablePeerVersion) >= 0 {
-		err = g.managedConnectPeer(conn, remoteVersion, addr)
+		peerConn, remoteHeader, err = g.managedConnectPeer(conn, remoteVersion, addr)
 	} else {
 		err = errors.New("version number is below threshold")
 	}
@@ -452,20 +492,34 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	conn.SetDeadline(time.Time{})
 
 	// Add the peer.
+	_, encrypted := peerConn.(*encryptedConn)
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	// The host may have been banned or blacklisted while the connection was
+	// being formed.
+	if err := g.acceptableHost(addr.Host()); err != nil {
+		conn.Close()
+		return err
+	}
+
 	g.addPeer(&peer{
 		Peer: modules.Peer{
-			Inbound:    false,
-			Local:      addr.IsLocal(),
-			NetAddress: addr,
-			Version:    remoteVersion,
+			Inbound:         false,
+			Local:           addr.IsLocal(),
+			NetAddress:      addr,
+			Version:         remoteVersion,
+			ProtocolVersion: remoteHeader.ProtocolVersion,
+			Capabilities:    remoteHeader.Capabilities,
+			Encrypted:       encrypted,
 		},
-		sess: newClientStream(conn, remoteVersion),
+		sess:  newClientStream(peerConn, remoteVersion),
+		stats: connStats(conn),
 	})
 	g.addNode(addr)
 	g.nodes[addr].WasOutboundPeer = true
+	g.recordPeerKey(addr, peerConn)
+	g.recordConnectionAttempt(addr, true)
 
 	if err := g.saveSync(); err != nil {
 		g.log.Println("ERROR: Unable to save new outbound peer to gateway:", err)
@@ -508,8 +562,12 @@ func (g *Gateway) Disconnect(addr modules.NetAddress) error {
 	g.mu.Lock()
 	// Peer is removed from the peer list as well as the node list, to prevent
 	// the node from being re-connected while looking for a replacement peer.
-	delete(g.peers, addr)
-	delete(g.nodes, addr)
+	// Bootstrap nodes stay in the node list until they are removed with
+	// RemoveBootstrapNode.
+	g.removePeer(addr)
+	if n, ok := g.nodes[addr]; ok && !n.Bootstrap {
+		g.removeNode(addr)
+	}
 	g.mu.Unlock()
 
 	g.log.Println("INFO: disconnected from peer", addr)
@@ -850,7 +850,10 @@ func TestPeerManager(t *testing.T) {
 	// g1's node list should only contain g2
 	g1.mu.Lock()
 	g1.nodes = map[modules.NetAddress]*node{}
-	g1.nodes[g2.Address()] = &node{NetAddress: g2.Address()}
+	g1.addrTables = newAddressTables()
+	if err := g1.addNode(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
 	g1.mu.Unlock()
 
 	// when peerManager wakes up, it should connect to g2.
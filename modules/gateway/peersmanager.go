@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"sort"
+
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/fastrand"
@@ -25,6 +27,7 @@ func (g *Gateway) managedPeerManagerConnect(addr modules.NetAddress) {
 		// we can hold off making attacker nodes 'outbound' peers until
 		// our nodelist has had time to fill up naturally.
 		g.mu.Lock()
+		g.recordConnectionAttempt(addr, true)
 		p, exists := g.peers[addr]
 		if exists {
 			// Have to check it exists because we released the lock, a
@@ -42,11 +45,11 @@ func (g *Gateway) managedPeerManagerConnect(addr modules.NetAddress) {
 	} else if err != nil {
 		g.log.Debugf("[PMC] [ERROR] [%v] WARN: removing peer because automatic connect failed: %v\n", addr, err)
 
-		// Remove the node, but only if there are enough nodes in the node list.
+		// Remove the node, but only if there are enough nodes in the node list
+		// and the node is not a bootstrap node.
 		g.mu.Lock()
-		if len(g.nodes) > pruneNodeListLen {
-			g.removeNode(addr)
-		}
+		g.recordConnectionAttempt(addr, false)
+		g.pruneNode(addr)
 		g.mu.Unlock()
 	} else {
 		g.log.Debugf("[PMC] [SUCCESS] [%v] peer successfully added", addr)
@@ -92,12 +95,17 @@ func (g *Gateway) permanentPeerManager(closedChan chan struct{}) {
 		}
 
 		for _, addr := range nodes {
-			// Break as soon as we have enough outbound peers.
+			// Break as soon as all of the outbound slots are taken.
+			// Pinned nodes are connected to even if the gateway already has
+			// enough outbound peers.
 			g.mu.RLock()
 			numOutboundPeers := g.numOutboundPeers()
+			_, outboundSlots := g.peerSlots()
 			isOutboundPeer := g.peers[addr] != nil && !g.peers[addr].Inbound
+			isPinned := g.nodes[addr] != nil && g.nodes[addr].Pinned
+			isWhitelisted := listedHost(g.whitelist, addr.Host())
 			g.mu.RUnlock()
-			if numOutboundPeers >= wellConnectedThreshold {
+			if numOutboundPeers >= outboundSlots && !(isPinned && !isOutboundPeer) {
 				g.log.Debugln("INFO: [PPM] Gateway has enough peers, sleeping.")
 				if !g.managedSleep(wellConnectedDelay) {
 					return
@@ -118,8 +126,9 @@ func (g *Gateway) permanentPeerManager(closedChan chan struct{}) {
 			// we already have reached a certain threshold of outbound peers and
 			// this peer is a local peer, do not consider it for an outbound peer.
 			// Sleep briefly to prevent the gateway from hogging the CPU if all
-			// peers are local.
-			if numOutboundPeers >= maxLocalOutboundPeers && addr.IsLocal() && build.Release != "testing" {
+			// peers are local. Gateways on a local network only have local
+			// peers.
+			if numOutboundPeers >= maxLocalOutboundPeers && addr.IsLocal() && !isPinned && !isWhitelisted && !g.staticLocalNetwork && build.Release != "testing" {
 				g.log.Debugln("[PPM] Ignorning selected peer; this peer is local and we already have multiple outbound peers:", addr)
 				if !g.managedSleep(unwantedLocalPeerDelay) {
 					return
@@ -169,13 +178,41 @@ func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 		perm = perm[1:]
 	}
 
-	// swap the outbound nodes to the front of the list
-	numOutbound := 0
-	for i, node := range nodes {
-		if g.nodes[node].WasOutboundPeer {
-			nodes[numOutbound], nodes[i] = nodes[i], nodes[numOutbound]
-			numOutbound++
+	// skip the nodes that the gateway is not allowed to connect to, such as
+	// nodes that are not whitelisted when the gateway only connects to
+	// whitelisted peers, or nodes of another address family when the
+	// gateway is restricted to its preferred family.
+	acceptable := nodes[:0]
+	for _, addr := range nodes {
+		if g.acceptableHost(addr.Host()) == nil && g.dialableFamily(addr.Host()) {
+			acceptable = append(acceptable, addr)
 		}
 	}
+	nodes = acceptable
+
+	// move the pinned nodes to the front of the list, followed by the other
+	// bootstrap nodes, the nodes of the preferred address family, the
+	// outbound nodes and the nodes of the tried table. Outbound nodes are
+	// ordered by their quality score.
+	family, _ := g.preferredFamily()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		ni, nj := g.nodes[nodes[i]], g.nodes[nodes[j]]
+		pi := family != "" && addressFamily(nodes[i].Host()) == family
+		pj := family != "" && addressFamily(nodes[j].Host()) == family
+		if ni.Pinned != nj.Pinned {
+			return ni.Pinned
+		} else if ni.Bootstrap != nj.Bootstrap {
+			return ni.Bootstrap
+		} else if pi != pj {
+			return pi
+		} else if ni.WasOutboundPeer != nj.WasOutboundPeer {
+			return ni.WasOutboundPeer
+		} else if ni.WasOutboundPeer {
+			return ni.score() > nj.score()
+		} else if ni.Tried != nj.Tried {
+			return ni.Tried
+		}
+		return false
+	})
 	return nodes
 }
@@ -39,8 +39,13 @@ func (g *Gateway) load() error {
 		// COMPATv1.3.0
 		return g.loadv033persist()
 	}
-	for i := range nodes {
-		g.nodes[nodes[i].NetAddress] = nodes[i]
+	for _, n := range nodes {
+		if _, exists := g.nodes[n.NetAddress]; exists {
+			continue
+		}
+		if err := g.insertNode(n); err != nil {
+			g.log.Printf("WARN: dropped node '%v' while loading the node list: %v", n.NetAddress, err)
+		}
 	}
 	return nil
 }
@@ -38,6 +38,7 @@ func TestLoadv033(t *testing.T) {
 	buf.Reset()
 	g := &Gateway{
 		nodes:      make(map[modules.NetAddress]*node),
+		addrTables: newAddressTables(),
 		persistDir: filepath.Join("testdata", t.Name()),
 		log:        log,
 	}
@@ -0,0 +1,229 @@
+package gateway
+
+// proxy.go implements dialing peers through a SOCKS5 proxy (RFC 1928), such as
+// the one provided by a local Tor client. Only the CONNECT command is
+// supported, with either no authentication or username/password
+// authentication (RFC 1929).
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	socks5Version = 5
+
+	socks5AuthNone         = 0
+	socks5AuthPassword     = 2
+	socks5AuthUnacceptable = 0xff
+
+	socks5CmdConnect = 1
+
+	socks5AddrIPv4   = 1
+	socks5AddrDomain = 3
+	socks5AddrIPv6   = 4
+)
+
+var (
+	errHiddenServiceNoProxy = errors.New("a hidden service address requires a proxy")
+	errNotOnionAddress      = errors.New("hidden service address must be an onion address")
+	errProxyAuthFailed      = errors.New("proxy rejected the username and password")
+	errProxyNoAuthMethod    = errors.New("proxy does not support any of the offered authentication methods")
+)
+
+// socks5ReplyErrors are the messages for the failure codes of a SOCKS5
+// CONNECT reply.
+var socks5ReplyErrors = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// ProxyConfig configures the gateway to dial peers through a SOCKS5 proxy.
+// When a proxy is used, the gateway does not forward its port with UPnP and
+// does not look up its external IP address, as both would reveal the node's
+// location.
+type ProxyConfig struct {
+	// Address is the address of the SOCKS5 proxy, e.g. "127.0.0.1:9050" for
+	// a local Tor client. The proxy is not used if Address is empty.
+	Address string
+
+	// Username and Password are sent to the proxy if either is set. Tor uses
+	// them to isolate the streams of different credentials from each other.
+	Username string
+	Password string
+
+	// HiddenService is the onion address of a Tor hidden service that
+	// forwards to the gateway's listen address. If set, it is announced to
+	// peers as the gateway's address.
+	HiddenService modules.NetAddress
+}
+
+// enabled returns true if the gateway should dial through the proxy.
+func (pc ProxyConfig) enabled() bool {
+	return pc.Address != ""
+}
+
+// validate returns an error if the proxy configuration is invalid.
+func (pc ProxyConfig) validate() error {
+	if pc.enabled() {
+		if _, _, err := net.SplitHostPort(pc.Address); err != nil {
+			return fmt.Errorf("invalid proxy address: %v", err)
+		}
+	}
+	if len(pc.Username) > 255 || len(pc.Password) > 255 {
+		return errors.New("proxy username and password cannot be longer than 255 bytes")
+	}
+	if pc.HiddenService != "" {
+		if !pc.enabled() {
+			return errHiddenServiceNoProxy
+		} else if err := pc.HiddenService.IsStdValid(); err != nil {
+			return fmt.Errorf("invalid hidden service address: %v", err)
+		} else if !isOnionHost(pc.HiddenService.Host()) {
+			return errNotOnionAddress
+		}
+	}
+	return nil
+}
+
+// isOnionHost returns true if the host is a Tor onion address.
+func isOnionHost(host string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(host, "."), ".onion")
+}
+
+// staticDialableHost returns true if the gateway is able to dial the host.
+// Peers are identified by their IP address, except for onion addresses, which
+// can be dialed when the gateway uses a proxy.
+func (g *Gateway) staticDialableHost(host string) bool {
+	return net.ParseIP(host) != nil || (g.staticProxy.enabled() && isOnionHost(host))
+}
+
+// staticDialProxy connects to the address through the SOCKS5 proxy.
+func (g *Gateway) staticDialProxy(addr modules.NetAddress) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Cancel:    g.threads.StopChan(),
+		Timeout:   dialTimeout,
+		KeepAlive: tcpKeepAlivePeriod,
+	}
+	conn, err := dialer.Dial("tcp", g.staticProxy.Address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if err := socks5Connect(conn, g.staticProxy, addr); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to connect through proxy: %v", err)
+	}
+	return conn, nil
+}
+
+// socks5Connect performs the SOCKS5 handshake on a connection to the proxy and
+// asks the proxy to connect to addr.
+func socks5Connect(conn net.Conn, pc ProxyConfig, addr modules.NetAddress) error {
+	host, portStr, err := net.SplitHostPort(string(addr))
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port: %v", err)
+	}
+
+	// Negotiate the authentication method.
+	method := byte(socks5AuthNone)
+	if pc.Username != "" || pc.Password != "" {
+		method = socks5AuthPassword
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	} else if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %v", reply[0])
+	} else if reply[1] == socks5AuthUnacceptable || reply[1] != method {
+		return errProxyNoAuthMethod
+	}
+	if method == socks5AuthPassword {
+		req := []byte{1, byte(len(pc.Username))}
+		req = append(req, pc.Username...)
+		req = append(req, byte(len(pc.Password)))
+		req = append(req, pc.Password...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return err
+		} else if reply[1] != 0 {
+			return errProxyAuthFailed
+		}
+	}
+
+	// Send the CONNECT request. Hostnames are passed to the proxy so that they
+	// are resolved remotely, which is required for onion addresses.
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return errors.New("hostname is too long")
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AddrIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+	var portBytes [2]byte
+	binary.BigEndian.PutUint16(portBytes[:], uint16(port))
+	req = append(req, portBytes[:]...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Read the reply. The bound address is of no use to the gateway, but it
+	// has to be read to get to the start of the stream.
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return err
+	} else if header[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %v", header[0])
+	} else if header[1] != 0 {
+		if msg, ok := socks5ReplyErrors[header[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("unknown SOCKS reply %v", header[1])
+	}
+	var addrLen int
+	switch header[3] {
+	case socks5AddrIPv4:
+		addrLen = net.IPv4len
+	case socks5AddrIPv6:
+		addrLen = net.IPv6len
+	case socks5AddrDomain:
+		var l [1]byte
+		if _, err := io.ReadFull(conn, l[:]); err != nil {
+			return err
+		}
+		addrLen = int(l[0])
+	default:
+		return fmt.Errorf("unknown SOCKS address type %v", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, addrLen+2))
+	return err
+}
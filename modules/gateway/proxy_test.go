@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// testSOCKS5Proxy is a minimal SOCKS5 server that supports the CONNECT
+// command and, if a username is set, username/password authentication.
+type testSOCKS5Proxy struct {
+	listener net.Listener
+	username string
+	password string
+	conns    uint64
+}
+
+// newTestSOCKS5Proxy starts a SOCKS5 proxy on a random local port.
+func newTestSOCKS5Proxy(t *testing.T, username, password string) *testSOCKS5Proxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testSOCKS5Proxy{
+		listener: l,
+		username: username,
+		password: password,
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+// serve handles a single connection to the proxy.
+func (p *testSOCKS5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// Read the greeting and pick an authentication method.
+	var buf [255]byte
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	methods := buf[:buf[1]]
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	want := byte(socks5AuthNone)
+	if p.username != "" {
+		want = socks5AuthPassword
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == want
+	}
+	if !offered {
+		conn.Write([]byte{socks5Version, socks5AuthUnacceptable})
+		return
+	}
+	conn.Write([]byte{socks5Version, want})
+	if want == socks5AuthPassword {
+		var creds [2]string
+		io.ReadFull(conn, buf[:1])
+		for i := range creds {
+			io.ReadFull(conn, buf[:1])
+			b := make([]byte, buf[0])
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return
+			}
+			creds[i] = string(b)
+		}
+		if creds[0] != p.username || creds[1] != p.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// Read the CONNECT request and dial the target.
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case socks5AddrIPv4:
+		io.ReadFull(conn, buf[:net.IPv4len])
+		host = net.IP(buf[:net.IPv4len]).String()
+	case socks5AddrIPv6:
+		io.ReadFull(conn, buf[:net.IPv6len])
+		host = net.IP(buf[:net.IPv6len]).String()
+	case socks5AddrDomain:
+		io.ReadFull(conn, buf[:1])
+		b := make([]byte, buf[0])
+		io.ReadFull(conn, b)
+		host = string(b)
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))))
+	if err != nil {
+		conn.Write([]byte{socks5Version, 5, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	atomic.AddUint64(&p.conns, 1)
+	conn.Write([]byte{socks5Version, 0, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+// TestProxyConfigValidate checks that invalid proxy configurations are
+// rejected.
+func TestProxyConfigValidate(t *testing.T) {
+	tests := []struct {
+		pc    ProxyConfig
+		valid bool
+	}{
+		{ProxyConfig{}, true},
+		{ProxyConfig{Address: "127.0.0.1:9050"}, true},
+		{ProxyConfig{Address: "127.0.0.1"}, false},
+		{ProxyConfig{HiddenService: "expyuzz4wqqyqhjn.onion:9981"}, false},
+		{ProxyConfig{Address: "127.0.0.1:9050", HiddenService: "expyuzz4wqqyqhjn.onion:9981"}, true},
+		{ProxyConfig{Address: "127.0.0.1:9050", HiddenService: "111.111.111.111:9981"}, false},
+		{ProxyConfig{Address: "127.0.0.1:9050", HiddenService: "expyuzz4wqqyqhjn.onion"}, false},
+	}
+	for _, test := range tests {
+		if err := test.pc.validate(); (err == nil) != test.valid {
+			t.Errorf("validate(%+v): expected valid = %v, got %v", test.pc, test.valid, err)
+		}
+	}
+}
+
+// TestProxyConnect checks that a gateway with a proxy dials its peers through
+// the proxy, and that onion addresses are only accepted when using a proxy.
+func TestProxyConnect(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	proxy := newTestSOCKS5Proxy(t, "", "")
+	defer proxy.listener.Close()
+
+	g1, err := NewCustomGateway("localhost:0", false, build.TempDir("gateway", t.Name()+"1"), ProxyConfig{
+		Address: proxy.listener.Addr().String(),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadUint64(&proxy.conns) == 0 {
+		t.Fatal("gateway did not connect through the proxy")
+	}
+
+	onion := modules.NetAddress("expyuzz4wqqyqhjn.onion:9981")
+	g1.mu.Lock()
+	err = g1.addNode(onion)
+	g1.mu.Unlock()
+	if err != nil {
+		t.Fatal("proxied gateway should accept onion addresses:", err)
+	}
+	g2.mu.Lock()
+	err = g2.addNode(onion)
+	g2.mu.Unlock()
+	if err == nil {
+		t.Fatal("gateway without a proxy should refuse onion addresses")
+	}
+}
+
+// TestProxyAuth checks that the gateway authenticates with the proxy.
+func TestProxyAuth(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	proxy := newTestSOCKS5Proxy(t, "user", "pass")
+	defer proxy.listener.Close()
+	target := newTestingGateway(t)
+	defer target.Close()
+
+	dial := func(pc ProxyConfig) error {
+		conn, err := net.Dial("tcp", proxy.listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return socks5Connect(conn, pc, target.Address())
+	}
+	if err := dial(ProxyConfig{Username: "user", Password: "pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dial(ProxyConfig{Username: "user", Password: "wrong"}); err != errProxyAuthFailed {
+		t.Fatal("expected errProxyAuthFailed, got", err)
+	}
+	if err := dial(ProxyConfig{}); err != errProxyNoAuthMethod {
+		t.Fatal("expected errProxyNoAuthMethod, got", err)
+	}
+}
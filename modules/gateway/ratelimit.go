@@ -0,0 +1,228 @@
+package gateway
+
+// ratelimit.go implements the bandwidth limits of the gateway. Every
+// connection of the gateway is wrapped in an rlConn, which draws the bytes it
+// reads and writes from two token buckets per direction: one that is shared
+// by all connections, and one that belongs to the connection alone. A limit
+// of zero disables the corresponding bucket.
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// settingsFile is the name of the file that contains the gateway's
+	// settings.
+	settingsFile = "gateway.json"
+
+	// rateLimitChunkSize is the largest number of bytes that an rlConn writes
+	// at once, so that a large write does not leave the upload limit idle and
+	// then exceed it all at once.
+	rateLimitChunkSize = 1 << 14
+)
+
+var (
+	// settingsMetadata contains the header and version strings that identify
+	// the gateway settings file.
+	settingsMetadata = persist.Metadata{
+		Header:  "Sia Gateway Settings",
+		Version: "1.3.3",
+	}
+
+	errNegativeSpeed = errors.New("bandwidth limits cannot be negative")
+)
+
+// A tokenBucket limits the rate at which bytes are transferred. Tokens are
+// added to the bucket at the rate of the limit, up to one second worth of
+// tokens. Taking more tokens than are available puts the bucket into debt,
+// which the caller must wait out.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens from the bucket and returns how long the caller must
+// wait before transferring the n bytes. The rate is passed in on every call so
+// that changes to the limit apply to existing buckets. A rate of zero means
+// that there is no limit.
+func (tb *tokenBucket) reserve(n int, rate int64) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if rate <= 0 {
+		tb.tokens = 0
+		tb.last = time.Time{}
+		return 0
+	}
+	now := time.Now()
+	burst := float64(rate)
+	if tb.last.IsZero() {
+		tb.tokens = burst
+	} else {
+		tb.tokens += now.Sub(tb.last).Seconds() * float64(rate)
+		if tb.tokens > burst {
+			tb.tokens = burst
+		}
+	}
+	tb.last = now
+	tb.tokens -= float64(n)
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / float64(rate) * float64(time.Second))
+}
+
+// A rateLimiter holds the bandwidth limits of the gateway and the buckets
+// that are shared by all connections.
+type rateLimiter struct {
+	mu       sync.RWMutex
+	settings modules.GatewaySettings
+
+	download tokenBucket
+	upload   tokenBucket
+}
+
+// limits returns the current bandwidth limits.
+func (rl *rateLimiter) limits() modules.GatewaySettings {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.settings
+}
+
+// setLimits changes the bandwidth limits. The new limits apply to existing
+// connections as well as new ones.
+func (rl *rateLimiter) setLimits(settings modules.GatewaySettings) {
+	rl.mu.Lock()
+	rl.settings = settings
+	rl.mu.Unlock()
+}
+
+// An rlConn is a connection whose reads and writes are subject to the
+// bandwidth limits of the gateway.
+type rlConn struct {
+	net.Conn
+	rl     *rateLimiter
+	cancel <-chan struct{}
+
+	download tokenBucket
+	upload   tokenBucket
+}
+
+// newRLConn wraps a connection so that it is subject to the bandwidth limits
+// of the gateway.
+func (g *Gateway) newRLConn(conn net.Conn) net.Conn {
+	return &rlConn{
+		Conn:   conn,
+		rl:     &g.rl,
+		cancel: g.threads.StopChan(),
+	}
+}
+
+// wait blocks for the longer of the two durations, returning early if the
+// gateway is shutting down.
+func (c *rlConn) wait(global, peer time.Duration) error {
+	d := global
+	if peer > d {
+		d = peer
+	}
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-c.cancel:
+		return errors.New("gateway is shutting down")
+	}
+}
+
+// Read implements net.Conn. The bytes are read before they are counted
+// against the download limits, as the number of bytes is not known in
+// advance.
+func (c *rlConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		limits := c.rl.limits()
+		global := c.rl.download.reserve(n, limits.MaxDownloadSpeed)
+		peer := c.download.reserve(n, limits.PeerMaxDownloadSpeed)
+		if waitErr := c.wait(global, peer); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Write implements net.Conn. Large writes are split into chunks, and each
+// chunk is counted against the upload limits before it is written.
+func (c *rlConn) Write(b []byte) (written int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > rateLimitChunkSize {
+			chunk = chunk[:rateLimitChunkSize]
+		}
+		limits := c.rl.limits()
+		global := c.rl.upload.reserve(len(chunk), limits.MaxUploadSpeed)
+		peer := c.upload.reserve(len(chunk), limits.PeerMaxUploadSpeed)
+		if err := c.wait(global, peer); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// loadSettings loads the gateway's settings from disk.
+func (g *Gateway) loadSettings() error {
+	var settings modules.GatewaySettings
+	err := persist.LoadJSON(settingsMetadata, &settings, filepath.Join(g.persistDir, settingsFile))
+	if err != nil {
+		return err
+	}
+	g.rl.setLimits(settings)
+	return nil
+}
+
+// Settings returns the gateway's current settings.
+func (g *Gateway) Settings() modules.GatewaySettings {
+	return g.rl.limits()
+}
+
+// SetSettings changes the gateway's settings and saves them to disk. The
+// bandwidth limits apply to existing connections immediately. Lowering the
+// peer limits does not disconnect any peers, but no new peers are added until
+// the number of peers has dropped below the new limits.
+func (g *Gateway) SetSettings(settings modules.GatewaySettings) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	if settings.MaxDownloadSpeed < 0 || settings.MaxUploadSpeed < 0 ||
+		settings.PeerMaxDownloadSpeed < 0 || settings.PeerMaxUploadSpeed < 0 {
+		return errNegativeSpeed
+	} else if settings.MaxInboundPeers < 0 || settings.MaxOutboundPeers < 0 {
+		return errNegativePeerLimit
+	}
+	if err := checkAddressFamily(settings); err != nil {
+		return err
+	}
+	if err := checkDNSSeeds(settings); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rl.setLimits(settings)
+	return persist.SaveJSON(settingsMetadata, settings, filepath.Join(g.persistDir, settingsFile))
+}
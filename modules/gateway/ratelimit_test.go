@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestTokenBucket checks that the token bucket allows a burst of one second
+// worth of tokens and then makes the caller wait.
+func TestTokenBucket(t *testing.T) {
+	var tb tokenBucket
+	if d := tb.reserve(1e6, 0); d != 0 {
+		t.Fatal("unlimited bucket should not wait, got", d)
+	}
+	if d := tb.reserve(1000, 1000); d != 0 {
+		t.Fatal("burst should not wait, got", d)
+	}
+	d := tb.reserve(500, 1000)
+	if d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Fatal("expected to wait about half a second, got", d)
+	}
+}
+
+// TestRateLimitedConn checks that the upload limits are enforced on the
+// connections of the gateway, and that the settings are saved.
+func TestRateLimitedConn(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	if err := g.SetSettings(modules.GatewaySettings{MaxUploadSpeed: -1}); err != errNegativeSpeed {
+		t.Fatal("expected errNegativeSpeed, got", err)
+	}
+	settings := modules.GatewaySettings{
+		MaxUploadSpeed:     1 << 20,
+		PeerMaxUploadSpeed: 1 << 14,
+	}
+	if err := g.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing two seconds worth of data beyond the burst should take about
+	// two seconds, as the per-peer limit is lower than the global limit.
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	go io.Copy(ioutil.Discard, c2)
+	conn := g.newRLConn(c1)
+	defer conn.Close()
+	start := time.Now()
+	if _, err := conn.Write(make([]byte, 3<<14)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 1900*time.Millisecond {
+		t.Fatal("write was not rate limited, took", elapsed)
+	}
+
+	// Removing the limits should apply to the existing connection.
+	if err := g.SetSettings(modules.GatewaySettings{}); err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if _, err := conn.Write(make([]byte, 3<<14)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("write was rate limited after removing the limits, took", elapsed)
+	}
+
+	// The settings should be loaded after a restart.
+	if err := g.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g2, err := New("localhost:0", false, g.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g2.Close()
+	if !reflect.DeepEqual(g2.Settings(), settings) {
+		t.Fatal("settings were not loaded:", g2.Settings())
+	}
+}
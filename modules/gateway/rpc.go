@@ -3,6 +3,7 @@ package gateway
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -50,20 +51,25 @@ func (g *Gateway) managedRPC(addr modules.NetAddress, name string, fn modules.RP
 		g.log.Debugf("Could not initiate RPC with %v; disconnecting", addr)
 		peer.sess.Close()
 		g.mu.Lock()
-		delete(g.peers, addr)
+		g.removePeer(addr)
 		g.mu.Unlock()
 		return err
 	}
 	defer conn.Close()
+	if peer.stats != nil {
+		atomic.AddUint64(&peer.stats.rpcsCalled, 1)
+	}
 
-	// write header
-	conn.SetDeadline(time.Now().Add(rpcStdDeadline))
-	if err := encoding.WriteObject(conn, handlerName(name)); err != nil {
-		return err
+	// Write the header. The deadline also applies to fn, unless fn sets its
+	// own deadline.
+	rc := &rpcConn{PeerConn: conn}
+	rc.SetDeadline(time.Now().Add(rpcStdDeadline))
+	err = encoding.WriteObject(rc, handlerName(name))
+	if err == nil {
+		err = fn(rc)
 	}
-	conn.SetDeadline(time.Time{})
-	// call fn
-	return fn(conn)
+	g.managedRecordRPC(addr, rc.stalled())
+	return err
 }
 
 // RPC calls an RPC on the given address. RPC cannot be called on an address
@@ -158,7 +164,7 @@ func (g *Gateway) threadedListenPeer(p *peer) {
 		// Close the session and remove p from the peer list.
 		p.sess.Close()
 		g.mu.Lock()
-		delete(g.peers, p.NetAddress)
+		g.removePeer(p.NetAddress)
 		g.mu.Unlock()
 	}()
 
@@ -209,9 +215,14 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 	// call registered handler for this ID
 	g.mu.RLock()
 	fn, ok := g.handlers[id]
+	p, connected := g.peers[conn.RPCAddr()]
 	g.mu.RUnlock()
+	if connected && p.stats != nil {
+		atomic.AddUint64(&p.stats.rpcsHandled, 1)
+	}
 	if !ok {
 		g.log.Debugf("WARN: incoming conn %v requested unknown RPC \"%v\"", conn.RPCAddr(), id)
+		g.managedPenalizePeer(conn.RPCAddr(), modules.PenaltyMalformedRPC)
 		return
 	}
 	g.log.Debugf("INFO: incoming conn %v requested RPC \"%v\"", conn.RPCAddr(), id)
@@ -0,0 +1,140 @@
+package gateway
+
+// slots.go implements the connection limits of the gateway. Inbound and
+// outbound peers have separate slots, so that a flood of inbound connections
+// cannot crowd out the outbound peers that the gateway picked itself. When
+// all inbound slots are taken, the lowest-scoring inbound peer is evicted to
+// make room for a new one. Peers that have been connected for a long time
+// without misbehaving are protected from eviction, so that an attacker cannot
+// replace all of the gateway's useful peers by opening many connections.
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errNegativePeerLimit = errors.New("peer limits cannot be negative")
+	errNoFreeSlots       = errors.New("all inbound slots are taken by peers that cannot be evicted")
+
+	// protectedPeerAge is the amount of time that an inbound peer has to be
+	// connected without misbehaving before it is protected from eviction.
+	protectedPeerAge = build.Select(build.Var{
+		Standard: 1 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  3 * time.Second,
+	}).(time.Duration)
+)
+
+// peerSlots returns the number of inbound and outbound slots. A limit of zero
+// in the settings selects the default.
+func (g *Gateway) peerSlots() (inbound, outbound int) {
+	settings := g.rl.limits()
+	inbound, outbound = settings.MaxInboundPeers, settings.MaxOutboundPeers
+	if inbound == 0 {
+		inbound = fullyConnectedThreshold
+	}
+	if outbound == 0 {
+		outbound = wellConnectedThreshold
+	}
+	return inbound, outbound
+}
+
+// numInboundPeers returns the number of inbound peers in the gateway.
+func (g *Gateway) numInboundPeers() int {
+	n := 0
+	for _, p := range g.peers {
+		if p.Inbound {
+			n++
+		}
+	}
+	return n
+}
+
+// canAcceptPeer returns true if an inbound peer connecting from the address
+// would be accepted, either because there is a free inbound slot or because
+// room can be made for it. It is checked before the handshake so that peers
+// that would be refused do not waste resources, but acceptPeer makes the final
+// decision.
+func (g *Gateway) canAcceptPeer(addr modules.NetAddress) bool {
+	inbound, _ := g.peerSlots()
+	if g.numInboundPeers() < inbound || addr.IsLocal() {
+		return true
+	}
+	_, ok := g.evictionCandidate(addr.Host())
+	return ok
+}
+
+// protectedPeers returns the inbound peers that cannot be evicted. These are
+// the whitelisted peers and up to a quarter of the inbound slots worth of the
+// longest connected peers that have been connected for at least
+// protectedPeerAge, and whose host has no penalty points.
+func (g *Gateway) protectedPeers() map[modules.NetAddress]struct{} {
+	protected := make(map[modules.NetAddress]struct{})
+	var longLived []*peer
+	for addr, p := range g.peers {
+		if !p.Inbound {
+			continue
+		}
+		if listedHost(g.whitelist, addr.Host()) {
+			protected[addr] = struct{}{}
+		} else if _, misbehaved := g.misbehavior[addr.Host()]; !misbehaved && time.Since(p.connectedAt) >= protectedPeerAge {
+			longLived = append(longLived, p)
+		}
+	}
+	sort.Slice(longLived, func(i, j int) bool {
+		return longLived[i].connectedAt.Before(longLived[j].connectedAt)
+	})
+	inbound, _ := g.peerSlots()
+	if len(longLived) > inbound/4 {
+		longLived = longLived[:inbound/4]
+	}
+	for _, p := range longLived {
+		protected[p.NetAddress] = struct{}{}
+	}
+	return protected
+}
+
+// evictionCandidate returns the inbound peer that should be evicted to make
+// room for a new peer on the given host. Local and protected peers are never
+// evicted. A peer on the same host as the new peer is evicted first. Otherwise
+// the peer with the lowest score is evicted, which is the peer whose host has
+// the most penalty points, or the most recently connected peer if there is a
+// tie.
+func (g *Gateway) evictionCandidate(host string) (modules.NetAddress, bool) {
+	protected := g.protectedPeers()
+	var candidates []*peer
+	for addr, p := range g.peers {
+		if !p.Inbound || p.Local {
+			continue
+		} else if _, ok := protected[addr]; ok {
+			continue
+		}
+		if addr.Host() == host {
+			return addr, true
+		}
+		candidates = append(candidates, p)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	penalty := func(p *peer) int {
+		if m, ok := g.misbehavior[p.NetAddress.Host()]; ok {
+			return m.points
+		}
+		return 0
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		pi, pj := penalty(candidates[i]), penalty(candidates[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return candidates[i].connectedAt.After(candidates[j].connectedAt)
+	})
+	return candidates[0].NetAddress, true
+}
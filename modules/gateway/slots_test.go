@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestPeerSlots checks that the peer limits can be changed, and that a limit
+// of zero selects the default.
+func TestPeerSlots(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	if inbound, outbound := g.peerSlots(); inbound != fullyConnectedThreshold || outbound != wellConnectedThreshold {
+		t.Fatal("wrong default slots:", inbound, outbound)
+	}
+	if err := g.SetSettings(modules.GatewaySettings{MaxInboundPeers: 5, MaxOutboundPeers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if inbound, outbound := g.peerSlots(); inbound != 5 || outbound != 2 {
+		t.Fatal("wrong slots:", inbound, outbound)
+	}
+	if err := g.SetSettings(modules.GatewaySettings{MaxOutboundPeers: -1}); err != errNegativePeerLimit {
+		t.Fatal("expected errNegativePeerLimit, got", err)
+	}
+}
+
+// TestInboundEviction checks that the lowest-scoring inbound peer is evicted
+// when all inbound slots are taken, and that protected peers are never
+// evicted.
+func TestInboundEviction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	if err := g.SetSettings(modules.GatewaySettings{MaxInboundPeers: 4}); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	newPeer := func(addr modules.NetAddress, age time.Duration) *peer {
+		return &peer{
+			Peer: modules.Peer{
+				NetAddress: addr,
+				Inbound:    true,
+			},
+			sess:        newClientStream(new(dummyConn), build.Version),
+			connectedAt: time.Now().Add(-age),
+		}
+	}
+	// The oldest peer is protected, and the peer with penalty points should
+	// be evicted first.
+	g.addPeer(newPeer("1.1.1.1:1", 3*protectedPeerAge))
+	g.addPeer(newPeer("2.2.2.2:1", 2*protectedPeerAge))
+	g.addPeer(newPeer("3.3.3.3:1", protectedPeerAge/3))
+	g.addPeer(newPeer("4.4.4.4:1", protectedPeerAge/2))
+	g.misbehavior["2.2.2.2"] = &misbehavior{points: 10, lastPenalty: time.Now()}
+
+	if err := g.acceptPeer(newPeer("5.5.5.5:1", 0)); err != nil {
+		t.Fatal(err)
+	} else if _, exists := g.peers["2.2.2.2:1"]; exists {
+		t.Fatal("the peer with penalty points was not evicted")
+	}
+	// Without penalty points, the most recently connected peer is evicted.
+	if err := g.acceptPeer(newPeer("6.6.6.6:1", 0)); err != nil {
+		t.Fatal(err)
+	} else if _, exists := g.peers["5.5.5.5:1"]; exists {
+		t.Fatal("the most recently connected peer was not evicted")
+	}
+	// A peer on the same host as the new peer is evicted first.
+	if err := g.acceptPeer(newPeer("4.4.4.4:2", 0)); err != nil {
+		t.Fatal(err)
+	} else if _, exists := g.peers["4.4.4.4:1"]; exists {
+		t.Fatal("the peer on the same host was not evicted")
+	}
+	if _, exists := g.peers["1.1.1.1:1"]; !exists {
+		t.Fatal("a protected peer was evicted")
+	}
+
+	// Once only protected and whitelisted peers are left, new peers are
+	// refused unless they are local.
+	for addr := range g.peers {
+		if addr != "1.1.1.1:1" {
+			g.whitelist[modules.NetAddress(addr.Host()+":9981")] = struct{}{}
+		}
+	}
+	if err := g.acceptPeer(newPeer("7.7.7.7:1", 0)); err != errNoFreeSlots {
+		t.Fatal("expected errNoFreeSlots, got", err)
+	} else if g.canAcceptPeer("7.7.7.7:1") {
+		t.Fatal("canAcceptPeer should refuse the peer")
+	}
+	local := newPeer("127.0.0.1:1", 0)
+	local.Local = true
+	if err := g.acceptPeer(local); err != nil {
+		t.Fatal(err)
+	} else if len(g.peers) != 5 {
+		t.Fatal("local peer was not accepted:", len(g.peers))
+	}
+}
@@ -0,0 +1,77 @@
+package gateway
+
+// stall.go detects peers that stop responding to RPCs. A peer whose session
+// stays alive but which never answers the RPCs called on it would otherwise
+// hold one of the gateway's slots forever, and every module that syncs from
+// it would wait for the full deadline of each RPC. An RPC stalls if it times
+// out before the peer sent any data; after maxConsecutiveStalls stalled RPCs
+// in a row, the gateway disconnects from the peer, and the peer manager fills
+// the slot with another peer. RPCs that time out after receiving some data
+// are slow rather than stalled, and reset the count.
+
+import (
+	"net"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// An rpcConn is the stream of an outgoing RPC. It records whether the RPC
+// received any data and whether it timed out.
+type rpcConn struct {
+	modules.PeerConn
+	received bool
+	timedOut bool
+}
+
+// isTimeoutErr returns true if err was caused by a deadline.
+func isTimeoutErr(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// Read implements net.Conn.
+func (rc *rpcConn) Read(b []byte) (int, error) {
+	n, err := rc.PeerConn.Read(b)
+	if n > 0 {
+		rc.received = true
+	}
+	if isTimeoutErr(err) {
+		rc.timedOut = true
+	}
+	return n, err
+}
+
+// Write implements net.Conn.
+func (rc *rpcConn) Write(b []byte) (int, error) {
+	n, err := rc.PeerConn.Write(b)
+	if isTimeoutErr(err) {
+		rc.timedOut = true
+	}
+	return n, err
+}
+
+// stalled returns true if the RPC timed out without receiving any data.
+func (rc *rpcConn) stalled() bool {
+	return rc.timedOut && !rc.received
+}
+
+// managedRecordRPC records whether an RPC called on a peer stalled, and
+// disconnects from the peer if too many RPCs in a row have stalled.
+func (g *Gateway) managedRecordRPC(addr modules.NetAddress, stalled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, exists := g.peers[addr]
+	if !exists {
+		return
+	} else if !stalled {
+		p.stalls = 0
+		return
+	}
+	p.stalls++
+	if p.stalls < maxConsecutiveStalls {
+		return
+	}
+	g.log.Printf("INFO: disconnecting from %v, which stalled %v RPCs in a row", addr, p.stalls)
+	p.sess.Close()
+	g.removePeer(addr)
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestStalledPeers checks that the gateway disconnects from peers that let
+// too many RPCs in a row time out without sending any data, and that RPCs
+// that receive data reset the count.
+func TestStalledPeers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	// The Stall RPC never responds, and the Slow RPC responds with a single
+	// byte.
+	g2.RegisterRPC("Stall", func(conn modules.PeerConn) error {
+		_, err := conn.Read(make([]byte, 1))
+		return err
+	})
+	g2.RegisterRPC("Slow", func(conn modules.PeerConn) error {
+		if _, err := conn.Write([]byte{1}); err != nil {
+			return err
+		}
+		_, err := conn.Read(make([]byte, 1))
+		return err
+	})
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	readWithTimeout := func(conn modules.PeerConn) error {
+		conn.SetDeadline(time.Now().Add(200 * time.Millisecond))
+		_, err := conn.Read(make([]byte, 2))
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+		return err
+	}
+	connected := func() bool {
+		g1.mu.RLock()
+		defer g1.mu.RUnlock()
+		_, exists := g1.peers[g2.Address()]
+		return exists
+	}
+
+	// Interleaving stalled and slow RPCs should never disconnect the peer.
+	for i := 0; i < 2*maxConsecutiveStalls; i++ {
+		if err := g1.RPC(g2.Address(), "Stall", readWithTimeout); !isTimeoutErr(err) {
+			t.Fatal("expected the RPC to time out, got", err)
+		}
+		if err := g1.RPC(g2.Address(), "Slow", readWithTimeout); !isTimeoutErr(err) {
+			t.Fatal("expected the RPC to time out, got", err)
+		}
+	}
+	if !connected() {
+		t.Fatal("gateway disconnected from a slow peer")
+	}
+
+	// Stalled RPCs in a row should disconnect the peer. The periodic
+	// ShareNodes RPC resets the count, so more RPCs may be needed.
+	g1.RPC(g2.Address(), "Stall", readWithTimeout)
+	if !connected() {
+		t.Fatal("gateway disconnected from the peer after a single stalled RPC")
+	}
+	for i := 0; i < 10*maxConsecutiveStalls && connected(); i++ {
+		g1.RPC(g2.Address(), "Stall", readWithTimeout)
+	}
+	if connected() {
+		t.Fatal("gateway did not disconnect from a stalled peer")
+	}
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// peerStats holds the traffic counters of a peer connection. The counters are
+// updated atomically, as they are shared by all streams of the connection.
+type peerStats struct {
+	bytesRead    uint64
+	bytesWritten uint64
+	rpcsCalled   uint64
+	rpcsHandled  uint64
+
+	// latency is the round trip time of the session header exchange, in
+	// nanoseconds.
+	latency int64
+}
+
+// A statsConn is a connection that counts the bytes read from and written to
+// it.
+type statsConn struct {
+	net.Conn
+	stats *peerStats
+}
+
+// newStatsConn wraps a connection so that its traffic is counted.
+func newStatsConn(conn net.Conn) net.Conn {
+	return &statsConn{
+		Conn:  conn,
+		stats: new(peerStats),
+	}
+}
+
+// Read implements net.Conn.
+func (c *statsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddUint64(&c.stats.bytesRead, uint64(n))
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *statsConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddUint64(&c.stats.bytesWritten, uint64(n))
+	return n, err
+}
+
+// connStats returns the counters of a connection that was wrapped with
+// newStatsConn, or new counters if it was not.
+func connStats(conn net.Conn) *peerStats {
+	if sc, ok := conn.(*statsConn); ok {
+		return sc.stats
+	}
+	return new(peerStats)
+}
+
+// timedHeaderExchange calls exchangeOurHeader and records the time it took as
+// the latency of the connection. Writing the header and reading the remote's
+// response takes one round trip.
+func timedHeaderExchange(conn net.Conn, ourHeader sessionHeader) error {
+	start := time.Now()
+	if err := exchangeOurHeader(conn, ourHeader); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&connStats(conn).latency, int64(time.Since(start)))
+	return nil
+}
+
+// PeerStats returns the traffic statistics of the gateway's peers, sorted by
+// address.
+func (g *Gateway) PeerStats() []modules.PeerStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stats := make([]modules.PeerStats, 0, len(g.peers))
+	for _, p := range g.peers {
+		ps := modules.PeerStats{
+			NetAddress:  p.NetAddress,
+			Inbound:     p.Inbound,
+			ConnectedAt: p.connectedAt,
+		}
+		if p.stats != nil {
+			ps.BytesReceived = atomic.LoadUint64(&p.stats.bytesRead)
+			ps.BytesSent = atomic.LoadUint64(&p.stats.bytesWritten)
+			ps.RPCsCalled = atomic.LoadUint64(&p.stats.rpcsCalled)
+			ps.RPCsHandled = atomic.LoadUint64(&p.stats.rpcsHandled)
+			ps.Latency = time.Duration(atomic.LoadInt64(&p.stats.latency))
+		}
+		stats = append(stats, ps)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].NetAddress < stats[j].NetAddress
+	})
+	return stats
+}
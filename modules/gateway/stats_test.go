@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestStatsConn checks that a statsConn counts the bytes read and written.
+func TestStatsConn(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	sc := newStatsConn(c1)
+
+	go func() {
+		buf := make([]byte, 10)
+		c2.Read(buf)
+		c2.Write(buf[:3])
+	}()
+	if _, err := sc.Write(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	stats := connStats(sc)
+	if stats.bytesWritten != 10 || stats.bytesRead != 3 {
+		t.Fatalf("expected 10 bytes written and 3 read, got %v and %v", stats.bytesWritten, stats.bytesRead)
+	}
+	if connStats(c1) == stats {
+		t.Fatal("unwrapped connection shares the counters of the wrapped connection")
+	}
+}
+
+// TestPeerStats checks that the gateway counts the traffic and RPCs of its
+// peers.
+func TestPeerStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	g2.RegisterRPC("Foo", func(conn modules.PeerConn) error { return nil })
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := g1.RPC(g2.Address(), "Foo", func(conn modules.PeerConn) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := g1.PeerStats()
+	if len(stats) != 1 {
+		t.Fatal("expected stats of 1 peer, got", len(stats))
+	}
+	ps := stats[0]
+	if ps.NetAddress != g2.Address() || ps.Inbound {
+		t.Fatal("stats have the wrong peer:", ps)
+	} else if ps.RPCsCalled < 3 {
+		t.Fatal("expected at least 3 RPCs called, got", ps.RPCsCalled)
+	} else if ps.BytesSent == 0 || ps.BytesReceived == 0 {
+		t.Fatal("traffic was not counted:", ps)
+	} else if ps.Latency <= 0 || ps.ConnectedAt.IsZero() {
+		t.Fatal("latency or connection time was not recorded:", ps)
+	}
+
+	// g2 should count the RPCs that g1 called on it.
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		stats := g2.PeerStats()
+		if len(stats) != 1 || !stats[0].Inbound {
+			return errors.New("g2 did not accept g1 as an inbound peer")
+		} else if stats[0].RPCsHandled < 3 {
+			return errors.New("g2 did not count the handled RPCs")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -35,18 +35,28 @@ func (s smuxSession) Accept() (net.Conn, error) { return s.sess.AcceptStream() }
 func (s smuxSession) Open() (net.Conn, error)   { return s.sess.OpenStream() }
 func (s smuxSession) Close() error              { return s.sess.Close() }
 
+// smuxConfig returns the smux configuration of peer sessions. Keepalive
+// frames are sent on idle sessions, and sessions that receive nothing for
+// sessionKeepAliveTimeout are closed, which removes the peer.
+func smuxConfig() *smux.Config {
+	config := smux.DefaultConfig()
+	config.KeepAliveInterval = sessionKeepAliveInterval
+	config.KeepAliveTimeout = sessionKeepAliveTimeout
+	return config
+}
+
 func newSmuxServer(conn net.Conn) streamSession {
-	sess, err := smux.Server(conn, nil) // default config means no error is possible
+	sess, err := smux.Server(conn, smuxConfig()) // valid config means no error is possible
 	if err != nil {
-		build.Critical("smux should not fail with default config:", err)
+		build.Critical("smux should not fail with valid config:", err)
 	}
 	return smuxSession{sess}
 }
 
 func newSmuxClient(conn net.Conn) streamSession {
-	sess, err := smux.Client(conn, nil) // default config means no error is possible
+	sess, err := smux.Client(conn, smuxConfig()) // valid config means no error is possible
 	if err != nil {
-		build.Critical("smux should not fail with default config:", err)
+		build.Critical("smux should not fail with valid config:", err)
 	}
 	return smuxSession{sess}
 }
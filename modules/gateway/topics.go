@@ -0,0 +1,217 @@
+package gateway
+
+// topics.go implements the relay of messages that modules broadcast on named
+// topics. Modules register a handler for each topic they understand, and the
+// gateway delivers every message to the handler once before relaying it to
+// its other peers. All topics share the Gossip RPC, so new kinds of messages
+// do not need a new RPC. Messages are deduplicated by their hash, and every
+// peer can only send a limited number of messages on each topic per window.
+// Messages on topics that have no handler are dropped, so a node only relays
+// the messages that it can validate.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// gossipCapability is the capability advertised by peers that support
+	// the Gossip RPC.
+	gossipCapability = "Gossip"
+
+	// maxTopicLength is the maximum length of a topic name.
+	maxTopicLength = 32
+
+	// maxTopicPayloadSize is the maximum size of the payload of a message.
+	maxTopicPayloadSize = 16 << 10
+)
+
+var (
+	errTopicTooLong     = errors.New("topic name is too long")
+	errTopicPayloadSize = errors.New("message payload is too large")
+
+	// seenMessageExpiry is the amount of time that the hash of a message is
+	// remembered, during which the message is not handled or relayed again.
+	seenMessageExpiry = build.Select(build.Var{
+		Standard: 1 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  1 * time.Minute,
+	}).(time.Duration)
+
+	// topicRateWindow is the length of the window in which the messages of
+	// a peer are counted.
+	topicRateWindow = build.Select(build.Var{
+		Standard: 1 * time.Minute,
+		Dev:      1 * time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
+	// maxTopicMessagesPerWindow is the number of messages that a peer can
+	// send on each topic per window. Further messages are dropped.
+	maxTopicMessagesPerWindow = build.Select(build.Var{
+		Standard: 60,
+		Dev:      60,
+		Testing:  5,
+	}).(int)
+)
+
+// topicMessage is the object sent by the Gossip RPC.
+type topicMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// topicRelay holds the topic handlers of the gateway and the messages that
+// the gateway has recently seen.
+type topicRelay struct {
+	handlers  map[string]modules.TopicHandler
+	seen      map[crypto.Hash]time.Time
+	lastPrune time.Time
+}
+
+// checkTopicMessage returns an error if the message is too large to relay.
+func checkTopicMessage(topic string, payload []byte) error {
+	if len(topic) > maxTopicLength {
+		return errTopicTooLong
+	} else if len(payload) > maxTopicPayloadSize {
+		return errTopicPayloadSize
+	}
+	return nil
+}
+
+// markSeen records a message as seen, and returns false if it had already
+// been seen. Expired entries are pruned at most once per expiry period.
+func (tr *topicRelay) markSeen(msg topicMessage) bool {
+	id := crypto.HashAll(msg.Topic, msg.Payload)
+	if expiry, ok := tr.seen[id]; ok && time.Now().Before(expiry) {
+		return false
+	}
+	tr.seen[id] = time.Now().Add(seenMessageExpiry)
+	if time.Since(tr.lastPrune) > seenMessageExpiry {
+		for id, expiry := range tr.seen {
+			if time.Now().After(expiry) {
+				delete(tr.seen, id)
+			}
+		}
+		tr.lastPrune = time.Now()
+	}
+	return true
+}
+
+// allowTopicMessage counts a message that the peer sent on the topic, and
+// returns false if the peer exceeded its limit for the current window.
+func (p *peer) allowTopicMessage(topic string) bool {
+	if time.Since(p.topicWindow) > topicRateWindow || p.topicCounts == nil {
+		p.topicWindow = time.Now()
+		p.topicCounts = make(map[string]int)
+	}
+	p.topicCounts[topic]++
+	return p.topicCounts[topic] <= maxTopicMessagesPerWindow
+}
+
+// managedRelayTopicMessage broadcasts a message to every peer that supports
+// the Gossip RPC, except the peer that the message came from.
+func (g *Gateway) managedRelayTopicMessage(msg topicMessage, source modules.NetAddress) {
+	g.mu.RLock()
+	var peers []modules.Peer
+	for addr, p := range g.peers {
+		if addr != source && p.HasCapability(gossipCapability) {
+			peers = append(peers, p.Peer)
+		}
+	}
+	g.mu.RUnlock()
+	g.Broadcast("Gossip", msg, peers)
+}
+
+// threadedRelayTopicMessage relays a message in a background thread that the
+// gateway waits for when it shuts down.
+func (g *Gateway) threadedRelayTopicMessage(msg topicMessage, source modules.NetAddress) {
+	if g.threads.Add() != nil {
+		return
+	}
+	defer g.threads.Done()
+	g.managedRelayTopicMessage(msg, source)
+}
+
+// receiveTopicMessage is the receiving end of the Gossip RPC. The message is
+// passed to the handler of its topic, and relayed to the other peers if the
+// handler accepts it.
+func (g *Gateway) receiveTopicMessage(conn modules.PeerConn) error {
+	var msg topicMessage
+	if err := encoding.ReadObject(conn, &msg, 8+maxTopicLength+8+maxTopicPayloadSize); err != nil {
+		return err
+	} else if err := checkTopicMessage(msg.Topic, msg.Payload); err != nil {
+		return err
+	}
+	source := conn.RPCAddr()
+
+	g.mu.Lock()
+	p, exists := g.peers[source]
+	allowed := exists && p.allowTopicMessage(msg.Topic)
+	handler, known := g.topics.handlers[msg.Topic]
+	fresh := allowed && known && g.topics.markSeen(msg)
+	g.mu.Unlock()
+	if !allowed {
+		g.log.Debugf("WARN: dropped message on topic %q from %v, which exceeded its rate limit", msg.Topic, source)
+		return nil
+	} else if !fresh {
+		return nil
+	}
+
+	if err := handler(source, msg.Payload); err != nil {
+		g.log.Debugf("INFO: message on topic %q from %v was rejected: %v", msg.Topic, source, err)
+		return nil
+	}
+	go g.threadedRelayTopicMessage(msg, source)
+	return nil
+}
+
+// RegisterTopic registers a handler for the messages broadcast on a topic.
+// The gateway only relays the messages on topics that have a handler, and
+// only if the handler returns nil.
+func (g *Gateway) RegisterTopic(topic string, fn modules.TopicHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(topic) > maxTopicLength {
+		build.Critical("topic name is too long: " + topic)
+		return
+	} else if _, ok := g.topics.handlers[topic]; ok {
+		build.Critical("topic already registered: " + topic)
+	}
+	g.topics.handlers[topic] = fn
+}
+
+// UnregisterTopic removes the handler of a topic. The gateway stops relaying
+// the messages on the topic.
+func (g *Gateway) UnregisterTopic(topic string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.topics.handlers[topic]; !ok {
+		build.Critical("topic not registered: " + topic)
+	}
+	delete(g.topics.handlers, topic)
+}
+
+// BroadcastTopic broadcasts a message on a topic to all peers that support
+// the Gossip RPC. The message is not passed to the local handler of the
+// topic, and is not relayed again if a peer sends it back.
+func (g *Gateway) BroadcastTopic(topic string, payload []byte) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	if err := checkTopicMessage(topic, payload); err != nil {
+		return err
+	}
+	msg := topicMessage{Topic: topic, Payload: payload}
+	g.mu.Lock()
+	g.topics.markSeen(msg)
+	g.mu.Unlock()
+	go g.threadedRelayTopicMessage(msg, "")
+	return nil
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// topicRecorder records the messages that a topic handler receives.
+type topicRecorder struct {
+	payloads [][]byte
+	sources  []modules.NetAddress
+	mu       sync.Mutex
+}
+
+// handle records a message, and rejects messages with the payload "bad".
+func (tr *topicRecorder) handle(source modules.NetAddress, payload []byte) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.payloads = append(tr.payloads, payload)
+	tr.sources = append(tr.sources, source)
+	if bytes.Equal(payload, []byte("bad")) {
+		return errors.New("bad payload")
+	}
+	return nil
+}
+
+// received returns the number of messages with the payload that were
+// received.
+func (tr *topicRecorder) received(payload string) int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	n := 0
+	for _, p := range tr.payloads {
+		if string(p) == payload {
+			n++
+		}
+	}
+	return n
+}
+
+// TestTopicRelay checks that messages broadcast on a topic are relayed across
+// the network, are handled once by every node, and are not relayed further if
+// the handler rejects them.
+func TestTopicRelay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+	g3 := newNamedTestingGateway(t, "3")
+	defer g3.Close()
+
+	// Connect the gateways in a line, g1 - g2 - g3.
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g3.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g2.Peers()) != 2 {
+			return errors.New("g2 has not accepted both connections")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r1, r2, r3 topicRecorder
+	g1.RegisterTopic("Test", r1.handle)
+	g2.RegisterTopic("Test", r2.handle)
+	g3.RegisterTopic("Test", r3.handle)
+
+	// A message should reach g3 through g2, and not be handled by g1 again.
+	if err := g1.BroadcastTopic("Test", []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if r2.received("foo") != 1 || r3.received("foo") != 1 {
+			return errors.New("message was not relayed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r3.mu.Lock()
+	source := r3.sources[0]
+	r3.mu.Unlock()
+	if source != g2.Address() {
+		t.Fatal("wrong source for relayed message:", source)
+	}
+
+	// Broadcasting the same message again and rejected messages should not
+	// reach g3.
+	if err := g1.BroadcastTopic("Test", []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.BroadcastTopic("Test", []byte("bad")); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if r2.received("bad") != 1 {
+			return errors.New("g2 did not receive the bad message")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if r2.received("foo") != 1 || r3.received("foo") != 1 || r3.received("bad") != 0 || r1.received("foo") != 0 {
+		t.Fatal("messages were handled the wrong number of times")
+	}
+
+	// Messages on topics without a handler are dropped.
+	g2.UnregisterTopic("Test")
+	if err := g1.BroadcastTopic("Test", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if r3.received("bar") != 0 {
+		t.Fatal("message was relayed by a gateway without a handler")
+	}
+}
+
+// TestTopicLimits checks that oversized messages are refused, and that peers
+// can only send a limited number of messages per topic.
+func TestTopicLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	if err := g.BroadcastTopic(strings.Repeat("a", maxTopicLength+1), nil); err != errTopicTooLong {
+		t.Fatal("expected errTopicTooLong, got", err)
+	}
+	if err := g.BroadcastTopic("Test", make([]byte, maxTopicPayloadSize+1)); err != errTopicPayloadSize {
+		t.Fatal("expected errTopicPayloadSize, got", err)
+	}
+
+	p := new(peer)
+	for i := 0; i < maxTopicMessagesPerWindow; i++ {
+		if !p.allowTopicMessage("Foo") {
+			t.Fatal("message was refused before the limit was reached")
+		}
+	}
+	if p.allowTopicMessage("Foo") {
+		t.Fatal("message was allowed after the limit was reached")
+	} else if !p.allowTopicMessage("Bar") {
+		t.Fatal("the limit of one topic affected another topic")
+	}
+	p.topicWindow = time.Now().Add(-topicRateWindow - time.Second)
+	if !p.allowTopicMessage("Foo") {
+		t.Fatal("the limit was not reset after the window ended")
+	}
+}
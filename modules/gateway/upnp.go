@@ -66,14 +66,23 @@ func (g *Gateway) threadedLearnHostname() {
 
 	for {
 		// try UPnP first, then fallback to myexternalip.com and peer-to-peer
-		// discovery.
+		// discovery. UPnP and myexternalip.com only report IPv4 addresses,
+		// so gateways that prefer IPv6 only use peer-to-peer discovery.
+		// Gateways on a local network are reached at their local address,
+		// which only their peers can report.
 		var host string
-		d, err := upnp.DiscoverCtx(ctx)
-		if err == nil {
-			host, err = d.ExternalIP()
-		}
-		if !build.DEBUG && err != nil {
-			host, err = myExternalIP()
+		err := errors.New("no IPv4 discovery for a gateway that prefers IPv6")
+		if g.staticLocalNetwork {
+			err = errors.New("no external discovery for a gateway on a local network")
+		} else if family, _ := g.preferredFamily(); family != modules.AddressFamilyIPv6 {
+			var d *upnp.IGD
+			d, err = upnp.DiscoverCtx(ctx)
+			if err == nil {
+				host, err = d.ExternalIP()
+			}
+			if !build.DEBUG && err != nil {
+				host, err = myExternalIP()
+			}
 		}
 		if err != nil {
 			host, err = g.managedIPFromPeers()
@@ -242,6 +242,13 @@ var (
 // init runs a series of sanity checks to verify that the constants have sane
 // values.
 func init() {
+	// Regression test networks mine blocks instantly, so the storage proof
+	// window is kept short to let contracts complete quickly.
+	if build.REGTEST {
+		defaultWindowSize = 5
+		revisionSubmissionBuffer = 4
+	}
+
 	// The revision submission buffer should be greater than the resubmission
 	// timeout, because there should be time to perform resubmission if the
 	// first attempt to submit the revision fails.
@@ -677,8 +677,7 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 			// TODO: If the host or tpool is behind consensus, might be difficult
 			// to have certainty about the issue. If some but not all of the
 			// parents are confirmed, might be some difficulty.
-			_, t := err.(modules.ConsensusConflict)
-			if t {
+			if modules.IsConsensusConflict(err) {
 				h.log.Println("Consensus conflict on the origin transaction set, id", so.id())
 				h.mu.Lock()
 				err = h.removeStorageObligation(so, obligationRejected)
@@ -740,7 +739,8 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 			h.log.Println("Error registering transaction:", err)
 			return
 		}
-		_, feeRecommendation := h.tpool.FeeEstimation()
+		// The revision needs to be confirmed before the contract expires.
+		feeRecommendation := h.tpool.FeeEstimate(so.expiration() - blockHeight)
 		if so.value().Div64(2).Cmp(feeRecommendation) < 0 {
 			// There's no sense submitting the revision if the fee is more than
 			// half of the anticipated revenue - fee market went up
@@ -848,7 +848,9 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 			h.log.Println("Failed to start transaction:", err)
 			return
 		}
-		_, feeRecommendation := h.tpool.FeeEstimation()
+		// The storage proof needs to be confirmed before the proof window
+		// closes.
+		feeRecommendation := h.tpool.FeeEstimate(so.proofDeadline() - blockHeight)
 		if so.value().Cmp(feeRecommendation) < 0 {
 			// There's no sense submitting the storage proof if the fee is more
 			// than the anticipated revenue.
@@ -100,7 +100,7 @@ func (h *Host) initConsensusSubscription() error {
 	// at this time, none of the host external functions are exposed, so it is
 	// save to make the exported call.
 	err := h.cs.ConsensusSetSubscribe(h, h.recentChange, h.tg.StopChan())
-	if err == modules.ErrInvalidConsensusChangeID {
+	if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 		// Perform a rescan of the consensus set if the change id that the host
 		// has is unrecognized by the consensus set. This will typically only
 		// happen if the user has been replacing files inside the Sia folder
@@ -77,6 +77,7 @@ func (m *Miner) HeaderForWork() (types.BlockHeader, types.Target, error) {
 	}
 	defer m.tg.Done()
 
+	m.managedRefreshBlockTransactions()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -27,6 +27,7 @@ func (m *Miner) threadedMine() {
 	// occurring.
 	cycleStart := time.Now()
 	for {
+		m.managedRefreshBlockTransactions()
 		m.mu.Lock()
 
 		// Kill the thread if 'Stop' has been called.
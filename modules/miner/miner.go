@@ -49,17 +49,11 @@ var (
 	}).(time.Duration)
 )
 
-// splitSet defines a transaction set that can be added components-wise to a
-// block. It's split because it doesn't necessarily represent the full set
-// prpovided by the transaction pool. Splits can be sorted so that the largest
-// and most valuable sets can be selected when picking transactions.
-type splitSet struct {
-	averageFee   types.Currency
-	size         uint64
-	transactions []types.Transaction
-}
-
-type splitSetID int
+const (
+	// blockSizeReserve is the space in a block that is left free for the
+	// block header, the miner payouts and the arbitrary data transaction.
+	blockSizeReserve = 5e3
+)
 
 // Miner struct contains all variables the miner needs
 // in order to create and submit blocks.
@@ -87,14 +81,13 @@ type Miner struct {
 	sourceBlockTime time.Time                                      // How long headers have been using the same block (different from 'recent block').
 	memProgress     int                                            // The index of the most recent header used in headerMem.
 
-	// Transaction pool variables.
-	fullSets           map[modules.TransactionSetID][]int
-	blockMapHeap       *mapHeap
-	overflowMapHeap    *mapHeap
-	setCounter         int
-	splitSets          map[splitSetID]*splitSet
-	splitSetIDFromTxID map[types.TransactionID]splitSetID
-	unsolvedBlockIndex map[types.TransactionID]int
+	// Transaction pool variables. The transactions of the unsolved block are
+	// selected by the transaction pool. Sets that leave the pool are removed
+	// from the block right away, and the block is refilled from the pool
+	// before new work is created if the pool has changed.
+	unconfirmedSets     map[modules.TransactionSetID][]types.TransactionID
+	tpoolUpdates        uint64
+	tpoolUpdatesApplied uint64
 
 	// CPUMiner variables.
 	miningOn bool  // indicates if the miner is supposed to be running
@@ -171,20 +164,7 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, w modules.Walle
 		arbDataMem: make(map[types.BlockHeader][crypto.EntropySize]byte),
 		headerMem:  make([]types.BlockHeader, HeaderMemory),
 
-		fullSets:  make(map[modules.TransactionSetID][]int),
-		splitSets: make(map[splitSetID]*splitSet),
-		blockMapHeap: &mapHeap{
-			selectID: make(map[splitSetID]*mapElement),
-			data:     nil,
-			minHeap:  true,
-		},
-		overflowMapHeap: &mapHeap{
-			selectID: make(map[splitSetID]*mapElement),
-			data:     nil,
-			minHeap:  false,
-		},
-		splitSetIDFromTxID: make(map[types.TransactionID]splitSetID),
-		unsolvedBlockIndex: make(map[types.TransactionID]int),
+		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
 
 		persistDir: persistDir,
 	}
@@ -195,7 +175,7 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, w modules.Walle
 	}
 
 	err = m.cs.ConsensusSetSubscribe(m, m.persist.RecentChange, m.tg.StopChan())
-	if err == modules.ErrInvalidConsensusChangeID {
+	if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 		// Perform a rescan of the consensus set if the change id is not found.
 		// The id will only be not found if there has been desynchronization
 		// between the miner and the consensus package.
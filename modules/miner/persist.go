@@ -45,7 +45,15 @@ func (m *Miner) initSettings() error {
 	} else if err != nil {
 		return err
 	}
-	return m.load()
+	err = m.load()
+	if err != nil {
+		return err
+	}
+	// The transactions of the unsolved block may have left the transaction
+	// pool while the miner was offline. The block is refilled from the
+	// transaction pool after the miner subscribes, so drop them.
+	m.persist.UnsolvedBlock.Transactions = nil
+	return nil
 }
 
 // initPersist initializes the persistence of the miner.
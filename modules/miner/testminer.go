@@ -57,6 +57,7 @@ func (m *Miner) BlockForWork() (b types.Block, t types.Target, err error) {
 		err = modules.ErrLockedWallet
 		return
 	}
+	m.managedRefreshBlockTransactions()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	err = m.checkAddress()
@@ -85,6 +86,7 @@ func (m *Miner) AddBlock() (types.Block, error) {
 func (m *Miner) FindBlock() (types.Block, error) {
 	var bfw types.Block
 	var target types.Target
+	m.managedRefreshBlockTransactions()
 	err := func() error {
 		m.mu.Lock()
 		defer m.mu.Unlock()
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // TestIntegrationBlockHeightReorg checks that the miner has the correct block
@@ -96,3 +97,53 @@ func TestIntegrationBlockHeightReorg(t *testing.T) {
 		t.Fatal("mt1 and mt3 should have the same current block")
 	}
 }
+
+// TestIntegrationBlockTransactions checks that the miner fills its blocks with
+// the transactions selected by the transaction pool, and that confirmed
+// transactions are dropped from the unsolved block.
+func TestIntegrationBlockTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = mt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := mt.tpool.BlockTransactions(types.BlockSizeLimit - blockSizeReserve)
+	if len(expected) == 0 {
+		t.Fatal("transaction pool did not select any transactions")
+	}
+	b, _, err := mt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first transaction of the block is the arbitrary data transaction.
+	if len(b.Transactions) != len(expected)+1 {
+		t.Fatalf("expected %v transactions, got %v", len(expected)+1, len(b.Transactions))
+	}
+	for i := range expected {
+		if b.Transactions[i+1].ID() != expected[i].ID() {
+			t.Fatal("block transactions are in the wrong order at index", i)
+		}
+	}
+
+	// Once the transactions are confirmed, the miner drops them from the
+	// unsolved block without querying the transaction pool.
+	_, err = mt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mt.miner.mu.RLock()
+	numTxns := len(mt.miner.persist.UnsolvedBlock.Transactions)
+	mt.miner.mu.RUnlock()
+	if numTxns != 0 {
+		t.Fatal("confirmed transactions were not dropped from the unsolved block")
+	}
+}
@@ -82,6 +82,7 @@ func (na NetAddress) IsLocal() bool {
 		"172.16.0.0/12",
 		"192.168.0.0/16",
 		"fd00::/8",
+		"fe80::/10",
 	}
 	for _, cidr := range localCIDRs {
 		_, ipnet, _ := net.ParseCIDR(cidr)
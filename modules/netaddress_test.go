@@ -284,6 +284,8 @@ func TestIsLocal(t *testing.T) {
 		{"[fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]:1234", true},
 		{"fe00:0000:0000:0000:0000:0000:0000:0000", false},
 		{"[fe00:0000:0000:0000:0000:0000:0000:0000]:1234", false},
+		{"fe80:0000:0000:0000:0000:0000:0000:0001", false},
+		{"[fe80:0000:0000:0000:0000:0000:0000:0001]:1234", true},
 
 		// Unspecified address tests.
 		{"0.0.0.0:1234", false},
@@ -270,7 +270,7 @@ func NewCustomContractor(cs consensusSet, w wallet, tp transactionPool, hdb host
 
 	// Subscribe to the consensus set.
 	err = cs.ConsensusSetSubscribe(c, c.lastChange, c.tg.StopChan())
-	if err == modules.ErrInvalidConsensusChangeID {
+	if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 		// Reset the contractor consensus variables and try rescanning.
 		c.blockHeight = 0
 		c.lastChange = modules.ConsensusChangeBeginning
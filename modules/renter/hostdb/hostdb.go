@@ -156,7 +156,7 @@ func NewCustomHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir stri
 	hdb.mu.Unlock()
 
 	err = cs.ConsensusSetSubscribe(hdb, hdb.lastChange, hdb.tg.StopChan())
-	if err == modules.ErrInvalidConsensusChangeID {
+	if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 		// Subscribe again using the new ID. This will cause a triggered scan
 		// on all of the hosts, but that should be acceptable.
 		hdb.mu.Lock()
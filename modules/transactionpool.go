@@ -1,7 +1,10 @@
 package modules
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -17,6 +20,10 @@ const (
 	// will be accepted by the transaction pool according to the IsStandard
 	// rules.
 	TransactionSizeLimit = 32e3
+
+	// DefaultFeeTarget is the number of blocks within which a transaction
+	// that pays the default fee estimate is expected to be confirmed.
+	DefaultFeeTarget = types.BlockHeight(6)
 )
 
 var (
@@ -24,6 +31,11 @@ var (
 	// duplicate transaction set is given to the transaction pool.
 	ErrDuplicateTransactionSet = errors.New("transaction set contains only duplicate transactions")
 
+	// ErrDustOutput is the error that gets returned if a transaction creates
+	// a siacoin output whose value is below the dust threshold of the
+	// transaction pool's policy.
+	ErrDustOutput = errors.New("transaction creates an output below the dust threshold")
+
 	// ErrInvalidArbPrefix is the error that gets returned if a transaction is
 	// submitted to the transaction pool which contains a prefix that is not
 	// recognized. This helps prevent miners on old versions from mining
@@ -40,6 +52,19 @@ var (
 	// IsStandard rules of the transaction pool.
 	ErrLargeTransactionSet = errors.New("transaction set is too large for this transaction pool")
 
+	// ErrLargeArbitraryData is the error that gets returned if the arbitrary
+	// data of a transaction exceeds the limit of the transaction pool's
+	// policy.
+	ErrLargeArbitraryData = errors.New("transaction contains too much arbitrary data for this transaction pool")
+
+	// ErrNonStandardUnlockConditions is the error that gets returned if a
+	// transaction reveals unlock conditions that use unrecognized key types.
+	ErrNonStandardUnlockConditions = errors.New("transaction contains non-standard unlock conditions")
+
+	// ErrTooManySignatures is the error that gets returned if a transaction
+	// has more signatures than allowed by the transaction pool's policy.
+	ErrTooManySignatures = errors.New("transaction has too many signatures for this transaction pool")
+
 	// PrefixNonSia defines the prefix that should be appended to any
 	// transactions that use the arbitrary data for reasons outside of the
 	// standard Sia protocol. This will prevent these transactions from being
@@ -47,6 +72,14 @@ var (
 	// will never be used within the formal Sia protocol.
 	PrefixNonSia = types.Specifier{'N', 'o', 'n', 'S', 'i', 'a'}
 
+	// ReplaceableSignal is the arbitrary data that marks a transaction as
+	// replaceable. The transaction pool replaces a transaction set that
+	// contains a replaceable transaction with a set that double spends it and
+	// pays a higher fee. The signal uses the 'NonSia' prefix so that nodes
+	// which do not support replacement still consider the transaction
+	// standard.
+	ReplaceableSignal = append(PrefixNonSia[:], "Replaceable"...)
+
 	// TransactionPoolDir is the name of the directory that is used to store
 	// the transaction pool's persistent data.
 	TransactionPoolDir = "transactionpool"
@@ -63,14 +96,235 @@ type (
 	// the ID of an entire transaction set.
 	TransactionSetID crypto.Hash
 
+	// TransactionPoolSettings contains the settings of the transaction pool
+	// that can be changed by the user.
+	TransactionPoolSettings struct {
+		// MaxPoolSize is the maximum size of the transaction pool in bytes.
+		// Once the pool is full, transaction sets are only accepted if they
+		// pay a higher fee rate than the sets they replace.
+		MaxPoolSize uint64 `json:"maxpoolsize"`
+
+		// MaxTransactionAge is the number of blocks after which unconfirmed
+		// transactions are dropped from the transaction pool.
+		MaxTransactionAge types.BlockHeight `json:"maxtransactionage"`
+
+		// Policy contains the standardness rules of the transaction pool.
+		Policy TransactionPoolPolicy `json:"policy"`
+	}
+
+	// TransactionPoolPolicy contains the standardness rules that the
+	// transaction pool enforces on top of the consensus rules. The rules are
+	// local to each node, so they can be changed without a hardfork. Each
+	// rule can be toggled separately. Changing the policy does not affect
+	// the transactions that are already in the pool.
+	TransactionPoolPolicy struct {
+		// CheckArbitraryDataSize limits the total size of the arbitrary data
+		// of a transaction to MaxArbitraryDataSize bytes.
+		CheckArbitraryDataSize bool   `json:"checkarbitrarydatasize"`
+		MaxArbitraryDataSize   uint64 `json:"maxarbitrarydatasize"`
+
+		// CheckDustOutputs rejects transactions that create siacoin outputs
+		// worth less than DustThreshold.
+		CheckDustOutputs bool           `json:"checkdustoutputs"`
+		DustThreshold    types.Currency `json:"dustthreshold"`
+
+		// CheckSignatureCount limits the number of signatures of a
+		// transaction to MaxSignatures.
+		CheckSignatureCount bool   `json:"checksignaturecount"`
+		MaxSignatures       uint64 `json:"maxsignatures"`
+
+		// CheckUnlockConditions rejects transactions that reveal unlock
+		// conditions with unrecognized key types. Unrecognized key types may
+		// be given a meaning by a future soft fork.
+		CheckUnlockConditions bool `json:"checkunlockconditions"`
+	}
+
+	// TransactionPoolStats contains statistics about the contents of the
+	// transaction pool. AcceptedSets and RejectedSets count the transaction
+	// sets that were accepted and rejected since the pool was started.
+	TransactionPoolStats struct {
+		TransactionSets uint64         `json:"transactionsets"`
+		Transactions    uint64         `json:"transactions"`
+		Size            uint64         `json:"size"` // bytes
+		TotalFees       types.Currency `json:"totalfees"`
+		MedianFeeRate   types.Currency `json:"medianfeerate"` // per byte
+		AcceptedSets    uint64         `json:"acceptedsets"`
+		RejectedSets    uint64         `json:"rejectedsets"`
+	}
+
+	// A TransactionPoolMetricType identifies the measurement contained in a
+	// TransactionPoolMetric.
+	TransactionPoolMetricType string
+
+	// A TransactionPoolMetric is a single measurement taken by the
+	// transaction pool.
+	TransactionPoolMetric struct {
+		Type TransactionPoolMetricType `json:"type"`
+
+		// Count, Size, FeeRate and Duration are the measured values. Their
+		// meaning depends on the type of the metric.
+		Count    int            `json:"count"`
+		Size     uint64         `json:"size"`
+		FeeRate  types.Currency `json:"feerate"`
+		Duration time.Duration  `json:"duration"`
+
+		// Reason is the reason of a rejection, and Peer is the peer that the
+		// measurement relates to, if any.
+		Reason TransactionRejectionReason `json:"reason,omitempty"`
+		Peer   NetAddress                 `json:"peer,omitempty"`
+	}
+
+	// A TransactionPoolMetricsSink receives the measurements taken by the
+	// transaction pool, for example to export them to a monitoring system.
+	TransactionPoolMetricsSink interface {
+		// RecordTransactionPoolMetric is called for every measurement. It
+		// may be called while the transaction pool is locked, and therefore
+		// must not block or call back into the transaction pool. It may also
+		// be called from several goroutines at once.
+		RecordTransactionPoolMetric(TransactionPoolMetric)
+	}
+
+	// A LocalTransaction is an unconfirmed transaction that was submitted to
+	// this node through the wallet or the API rather than received from a
+	// peer. FirstSeen is the time at which the transaction was first added
+	// to the transaction pool.
+	LocalTransaction struct {
+		Transaction types.Transaction   `json:"transaction"`
+		ID          types.TransactionID `json:"id"`
+		FirstSeen   time.Time           `json:"firstseen"`
+	}
+
+	// A RejectedTransactionSet is a transaction set that was recently
+	// rejected by the transaction pool. TransactionID is the transaction that
+	// caused the rejection, if the rejection can be attributed to a single
+	// transaction, and Source is the peer that relayed the set, if any.
+	RejectedTransactionSet struct {
+		ID            TransactionSetID           `json:"id"`
+		Reason        TransactionRejectionReason `json:"reason"`
+		Error         string                     `json:"error"`
+		TransactionID types.TransactionID        `json:"transactionid"`
+		Source        NetAddress                 `json:"source"`
+		Time          time.Time                  `json:"time"`
+	}
+
+	// A TransactionSetValidation contains the fees of a transaction set that
+	// was validated without being added to the transaction pool.
+	// RequiredFees is the amount of fees that the set needs to pay to extend
+	// the pool at its current size.
+	TransactionSetValidation struct {
+		Size         uint64         `json:"size"` // bytes
+		Fees         types.Currency `json:"fees"`
+		FeeRate      types.Currency `json:"feerate"` // per byte
+		RequiredFees types.Currency `json:"requiredfees"`
+	}
+
+	// An UnconfirmedOutput is a siacoin or siafund output that is created or
+	// spent by an unconfirmed transaction. Spent indicates whether an output
+	// that is created by an unconfirmed transaction is spent by another
+	// unconfirmed transaction already.
+	UnconfirmedOutput struct {
+		ID            types.OutputID      `json:"id"`
+		FundType      types.Specifier     `json:"fundtype"`
+		Value         types.Currency      `json:"value"`
+		TransactionID types.TransactionID `json:"transactionid"`
+		Spent         bool                `json:"spent"`
+	}
+
+	// A SpendableOutput is an output that is created by an unconfirmed
+	// transaction and not spent by another unconfirmed transaction.
+	// DependencySet holds the transaction that creates the output along with
+	// its unconfirmed ancestors, parents first. A transaction that spends the
+	// output must be broadcast together with its dependency set.
+	SpendableOutput struct {
+		UnconfirmedOutput
+		DependencySet []types.Transaction `json:"dependencyset"`
+	}
+
+	// UnconfirmedOutputs contains the outputs of an unlock hash that are
+	// created (Incoming) or spent (Outgoing) by unconfirmed transactions.
+	// Outgoing outputs may be confirmed outputs or outputs of other
+	// unconfirmed transactions.
+	UnconfirmedOutputs struct {
+		Incoming []UnconfirmedOutput `json:"incoming"`
+		Outgoing []UnconfirmedOutput `json:"outgoing"`
+	}
+
 	// A TransactionPoolDiff indicates the adding or removal of a transaction set to
-	// the transaction pool. The transactions in the pool are not persisted, so at
-	// startup modules should assume an empty transaction pool.
+	// the transaction pool. The transactions in the pool are persisted across
+	// restarts, so modules should not assume an empty transaction pool at
+	// startup. New subscribers receive the current transaction sets when they
+	// subscribe.
+	//
+	// RemovalReasons contains the reason why each of the reverted transaction
+	// sets was removed from the transaction pool. Conflicts contains the
+	// transactions of the reverted sets that were displaced by conflicting
+	// transactions, and will not return to the pool.
 	TransactionPoolDiff struct {
 		AppliedTransactions  []*UnconfirmedTransactionSet
 		RevertedTransactions []TransactionSetID
+		RemovalReasons       map[TransactionSetID]TransactionRemovalReason
+		Conflicts            []TransactionConflict
 	}
 
+	// A TransactionConflict identifies an unconfirmed transaction that was
+	// displaced from the transaction pool by a transaction that double spends
+	// it, or that double spends one of its unconfirmed parents. The displaced
+	// transaction cannot be confirmed unless the conflicting transaction is
+	// reverted.
+	TransactionConflict struct {
+		DisplacedTransaction   types.TransactionID
+		ConflictingTransaction types.TransactionID
+
+		// Confirmed is true if the conflicting transaction was confirmed in
+		// a block, and false if it replaced the displaced transaction in the
+		// pool.
+		Confirmed bool
+	}
+
+	// A TransactionRemovalReason describes why a transaction set was removed
+	// from the transaction pool.
+	TransactionRemovalReason string
+
+	// A TransactionPoolFilter restricts the updates that a subscriber receives
+	// to the transaction sets that contain a matching transaction. A
+	// transaction matches if it matches every non-empty field of the filter.
+	// An empty filter matches every transaction.
+	TransactionPoolFilter struct {
+		// UnlockHashes matches transactions that spend from or send to one of
+		// the unlock hashes.
+		UnlockHashes []types.UnlockHash
+
+		// Types matches transactions of one of the types.
+		Types []TransactionType
+	}
+
+	// A TransactionType identifies a kind of object that a transaction
+	// contains. A transaction can be of multiple types.
+	TransactionType string
+
+	// A TransactionRejection is the error that the transaction pool returns
+	// when it rejects a transaction set. Err is the underlying error, which
+	// is a ConsensusConflict if the set is invalid according to the
+	// consensus rules.
+	TransactionRejection struct {
+		Reason TransactionRejectionReason
+		Err    error
+
+		// TransactionID is the transaction of the set that caused the
+		// rejection, if the rejection can be attributed to a single
+		// transaction.
+		TransactionID types.TransactionID
+
+		// ConflictingTransactionID is the unconfirmed transaction that the
+		// set double spends, if the set was rejected for a double spend of
+		// an unconfirmed transaction.
+		ConflictingTransactionID types.TransactionID
+	}
+
+	// A TransactionRejectionReason describes why a transaction set was
+	// rejected by the transaction pool.
+	TransactionRejectionReason string
+
 	// UnconfirmedTransactionSet defines a new unconfirmed transaction that has
 	// been added to the transaction pool. ID is the ID of the set, IDs contains
 	// an ID for each transaction, eliminating the need to recompute it (because
@@ -85,6 +339,118 @@ type (
 	}
 )
 
+// The measurements taken by the transaction pool.
+const (
+	// TransactionPoolMetricAccepted is recorded for every transaction set
+	// that is accepted into the pool. The count is the number of
+	// transactions in the set, the size and fee rate are those of the set,
+	// and the peer is the peer that relayed the set, if any.
+	TransactionPoolMetricAccepted TransactionPoolMetricType = "accepted"
+
+	// TransactionPoolMetricRejected is recorded for every transaction set
+	// that is rejected by the pool. The count, size, fee rate and peer are
+	// set like for accepted sets, and the reason is the reason of the
+	// rejection.
+	TransactionPoolMetricRejected TransactionPoolMetricType = "rejected"
+
+	// TransactionPoolMetricPoolSize is recorded after every consensus
+	// change. The count is the number of transaction sets in the pool, the
+	// size is the size of the pool, and the fee rate is the median fee rate
+	// of the sets in the pool.
+	TransactionPoolMetricPoolSize TransactionPoolMetricType = "poolsize"
+
+	// TransactionPoolMetricRelay is recorded for every transaction set that
+	// is relayed to peers. The count is the number of peers, the size is the
+	// size of the set, and the duration is the time taken until every peer
+	// received the set or its announcement.
+	TransactionPoolMetricRelay TransactionPoolMetricType = "relay"
+)
+
+// The reasons for which transaction sets are removed from the transaction
+// pool.
+const (
+	// TransactionRemovalConfirmed indicates that at least one transaction of
+	// the set was confirmed in a block. Its unconfirmed transactions are
+	// added back to the pool in new sets if they are still valid.
+	TransactionRemovalConfirmed TransactionRemovalReason = "confirmed"
+
+	// TransactionRemovalReverted indicates that the set was removed by a
+	// change to the consensus set without being confirmed, or that the pool
+	// was purged. Transactions that are still valid are added back to the
+	// pool in new sets.
+	TransactionRemovalReverted TransactionRemovalReason = "reverted"
+
+	// TransactionRemovalEvicted indicates that the set was evicted to make
+	// room for transaction sets that pay higher fees.
+	TransactionRemovalEvicted TransactionRemovalReason = "evicted"
+
+	// TransactionRemovalReplaced indicates that the set was double spent by a
+	// replacement that pays a higher fee.
+	TransactionRemovalReplaced TransactionRemovalReason = "replaced"
+
+	// TransactionRemovalMerged indicates that the set was merged into a new
+	// set along with transactions that depend on it.
+	TransactionRemovalMerged TransactionRemovalReason = "merged"
+)
+
+// The reasons for which the transaction pool rejects transaction sets.
+const (
+	// RejectionConsensusConflict indicates that the set is invalid according
+	// to the consensus rules for a reason not covered by the other reasons.
+	RejectionConsensusConflict TransactionRejectionReason = "consensus conflict"
+
+	// RejectionDoubleSpend indicates that the set spends an output twice, or
+	// spends an output that is spent by an unconfirmed transaction which
+	// cannot be replaced.
+	RejectionDoubleSpend TransactionRejectionReason = "double spend"
+
+	// RejectionEmptySet indicates that the set contains no transactions.
+	RejectionEmptySet TransactionRejectionReason = "empty set"
+
+	// RejectionInsufficientFee indicates that the set does not pay enough
+	// fees to enter the pool or to replace the sets that it double spends.
+	RejectionInsufficientFee TransactionRejectionReason = "insufficient fee"
+
+	// RejectionInvalidSignature indicates that a signature of the set is
+	// invalid, missing, or refers to a nonexistent public key.
+	RejectionInvalidSignature TransactionRejectionReason = "invalid signature"
+
+	// RejectionMissingInputs indicates that the set spends outputs that are
+	// unknown. The outputs may be created by transactions that the pool has
+	// not seen yet, or may have been spent already.
+	RejectionMissingInputs TransactionRejectionReason = "missing inputs"
+
+	// RejectionNonStandard indicates that the set violates the standardness
+	// rules or the policy of the pool.
+	RejectionNonStandard TransactionRejectionReason = "non-standard"
+
+	// RejectionPoolFull indicates that the pool is full and cannot make room
+	// for the set.
+	RejectionPoolFull TransactionRejectionReason = "pool full"
+
+	// RejectionRateLimited indicates that the peer that relayed the set has
+	// relayed too many sets recently.
+	RejectionRateLimited TransactionRejectionReason = "rate limited"
+
+	// RejectionTooLarge indicates that a transaction or the set as a whole
+	// exceeds a size limit.
+	RejectionTooLarge TransactionRejectionReason = "too large"
+
+	// RejectionTooManyReplacements indicates that the set would replace too
+	// many unconfirmed transactions.
+	RejectionTooManyReplacements TransactionRejectionReason = "too many replacements"
+)
+
+// The types of transactions that a TransactionPoolFilter can match.
+const (
+	TransactionTypeSiacoin       TransactionType = "siacoin"
+	TransactionTypeSiafund       TransactionType = "siafund"
+	TransactionTypeFileContract  TransactionType = "filecontract"
+	TransactionTypeRevision      TransactionType = "revision"
+	TransactionTypeStorageProof  TransactionType = "storageproof"
+	TransactionTypeArbitraryData TransactionType = "arbitrarydata"
+)
+
 type (
 	// A TransactionPoolSubscriber receives updates about the confirmed and
 	// unconfirmed set from the transaction pool. Generally, there is no need to
@@ -117,6 +483,33 @@ type (
 		// within 10 blocks.
 		FeeEstimation() (minimumRecommended, maximumRecommended types.Currency)
 
+		// FeeEstimate returns the fee per byte that a transaction needs to
+		// pay to be confirmed within the target number of blocks, based on
+		// the fees of recent blocks and on the congestion of the pool.
+		FeeEstimate(target types.BlockHeight) types.Currency
+
+		// RawTransactions returns the encoded transaction sets in the pool.
+		// Every set can be decoded into a []types.Transaction.
+		RawTransactions() [][]byte
+
+		// LocalTransactions returns the unconfirmed transactions that were
+		// submitted to this node rather than received from peers, sorted by
+		// the time at which they were first seen. Sets with local
+		// transactions are never evicted for paying low fees.
+		LocalTransactions() []LocalTransaction
+
+		// RecentRejections returns the transaction sets that were most
+		// recently rejected by the pool, oldest first.
+		RecentRejections() []RejectedTransactionSet
+
+		// RegisterMetricsSink adds a sink that receives every measurement
+		// taken by the transaction pool after registering.
+		RegisterMetricsSink(TransactionPoolMetricsSink)
+
+		// UnregisterMetricsSink removes a metrics sink. If the sink is not
+		// found, no action is taken.
+		UnregisterMetricsSink(TransactionPoolMetricsSink)
+
 		// PurgeTransactionPool is a temporary function available to the miner. In
 		// the event that a miner mines an unacceptable block, the transaction pool
 		// will be purged to clear out the transaction pool and get rid of the
@@ -124,10 +517,37 @@ type (
 		// that make this condition necessary.
 		PurgeTransactionPool()
 
+		// SetSettings changes the settings of the transaction pool. Lowering
+		// the maximum pool size evicts the transaction sets with the lowest
+		// fee rates until the pool fits.
+		SetSettings(TransactionPoolSettings) error
+
+		// Settings returns the settings of the transaction pool.
+		Settings() TransactionPoolSettings
+
+		// Stats returns the number of transactions and transaction sets in the
+		// pool, along with their combined size and fees.
+		Stats() TransactionPoolStats
+
 		// Transaction returns the transaction and unconfirmed parents
 		// corresponding to the provided transaction id.
 		Transaction(id types.TransactionID) (txn types.Transaction, unconfirmedParents []types.Transaction, exists bool)
 
+		// UnconfirmedOutputs returns the outputs of an unlock hash that are
+		// created or spent by the unconfirmed transactions in the pool.
+		UnconfirmedOutputs(types.UnlockHash) UnconfirmedOutputs
+
+		// SpendableOutputs returns the outputs of an unlock hash that are
+		// created by unconfirmed transactions and not yet spent, along with
+		// the unconfirmed transactions that a transaction spending them
+		// depends on.
+		SpendableOutputs(types.UnlockHash) []SpendableOutput
+
+		// TransactionSource returns the address of the peer that relayed a
+		// transaction to the pool. The source is unknown for transactions
+		// that were submitted locally.
+		TransactionSource(id types.TransactionID) (NetAddress, bool)
+
 		// TransactionConfirmed returns true if the transaction has been seen on the
 		// blockchain. Note, however, that the block containing the transaction may
 		// later be invalidated by a reorg.
@@ -143,13 +563,36 @@ type (
 		// transaction pool changes, and should not subscribe to both.
 		TransactionPoolSubscribe(TransactionPoolSubscriber)
 
+		// TransactionPoolSubscribeFiltered adds a subscriber to the
+		// transaction pool that only receives updates about the transaction
+		// sets that match the filter.
+		TransactionPoolSubscribeFiltered(TransactionPoolSubscriber, TransactionPoolFilter)
+
 		// TransactionSet returns the transaction set the provided object
 		// appears in.
 		TransactionSet(crypto.Hash) []types.Transaction
 
+		// BlockTransactions returns the transactions that a miner should put
+		// into the next block, taking up at most maxSize bytes. Transactions
+		// are ranked by the fee rate of their unconfirmed ancestors combined,
+		// so a child that pays a high fee pulls its low-fee parents into the
+		// block. The same pool always returns the same transactions in the
+		// same order.
+		BlockTransactions(maxSize uint64) []types.Transaction
+
+		// TransactionSetByID returns the transaction set with the provided
+		// id, and a bool indicating if it exists in the pool.
+		TransactionSetByID(TransactionSetID) ([]types.Transaction, bool)
+
 		// Unsubscribe removes a subscriber from the transaction pool.
 		// This is necessary for clean shutdown of the miner.
 		Unsubscribe(TransactionPoolSubscriber)
+
+		// Validate checks whether AcceptTransactionSet would accept a
+		// transaction set, without adding the set to the pool or relaying it.
+		// The fees of the set are returned along with the error that
+		// AcceptTransactionSet would return.
+		Validate([]types.Transaction) (TransactionSetValidation, error)
 	}
 )
 
@@ -165,6 +608,130 @@ func (cc ConsensusConflict) Error() string {
 	return string(cc)
 }
 
+// Error implements the error interface. The message of the underlying error is
+// extended with the transactions involved in the rejection.
+func (tr TransactionRejection) Error() string {
+	msg := tr.Err.Error()
+	if tr.TransactionID != (types.TransactionID{}) {
+		msg += fmt.Sprintf(" (transaction %v)", tr.TransactionID)
+	}
+	if tr.ConflictingTransactionID != (types.TransactionID{}) {
+		msg += fmt.Sprintf(" (conflicts with unconfirmed transaction %v)", tr.ConflictingTransactionID)
+	}
+	return msg
+}
+
+// IsConsensusConflict returns true if err is a ConsensusConflict, or a
+// TransactionRejection caused by one.
+func IsConsensusConflict(err error) bool {
+	if tr, ok := err.(TransactionRejection); ok {
+		err = tr.Err
+	}
+	_, ok := err.(ConsensusConflict)
+	return ok
+}
+
+// IsReplaceable returns true if the transaction signals that it can be
+// replaced by a transaction that double spends it and pays a higher fee.
+func IsReplaceable(txn types.Transaction) bool {
+	for _, arb := range txn.ArbitraryData {
+		if bytes.Equal(arb, ReplaceableSignal) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasType returns true if the transaction contains an object of type t.
+func hasType(txn types.Transaction, t TransactionType) bool {
+	switch t {
+	case TransactionTypeSiacoin:
+		return len(txn.SiacoinInputs) > 0 || len(txn.SiacoinOutputs) > 0
+	case TransactionTypeSiafund:
+		return len(txn.SiafundInputs) > 0 || len(txn.SiafundOutputs) > 0
+	case TransactionTypeFileContract:
+		return len(txn.FileContracts) > 0
+	case TransactionTypeRevision:
+		return len(txn.FileContractRevisions) > 0
+	case TransactionTypeStorageProof:
+		return len(txn.StorageProofs) > 0
+	case TransactionTypeArbitraryData:
+		return len(txn.ArbitraryData) > 0
+	}
+	return false
+}
+
+// unlockHashes returns the unlock hashes that a transaction spends from or
+// sends to.
+func unlockHashes(txn types.Transaction) map[types.UnlockHash]struct{} {
+	uhs := make(map[types.UnlockHash]struct{})
+	for _, sci := range txn.SiacoinInputs {
+		uhs[sci.UnlockConditions.UnlockHash()] = struct{}{}
+	}
+	for _, sco := range txn.SiacoinOutputs {
+		uhs[sco.UnlockHash] = struct{}{}
+	}
+	for _, fc := range txn.FileContracts {
+		for _, sco := range fc.ValidProofOutputs {
+			uhs[sco.UnlockHash] = struct{}{}
+		}
+		for _, sco := range fc.MissedProofOutputs {
+			uhs[sco.UnlockHash] = struct{}{}
+		}
+	}
+	for _, fcr := range txn.FileContractRevisions {
+		for _, sco := range fcr.NewValidProofOutputs {
+			uhs[sco.UnlockHash] = struct{}{}
+		}
+		for _, sco := range fcr.NewMissedProofOutputs {
+			uhs[sco.UnlockHash] = struct{}{}
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		uhs[sfi.UnlockConditions.UnlockHash()] = struct{}{}
+		uhs[sfi.ClaimUnlockHash] = struct{}{}
+	}
+	for _, sfo := range txn.SiafundOutputs {
+		uhs[sfo.UnlockHash] = struct{}{}
+	}
+	return uhs
+}
+
+// MatchesTransaction returns true if the transaction matches the filter.
+func (f TransactionPoolFilter) MatchesTransaction(txn types.Transaction) bool {
+	if len(f.Types) > 0 {
+		matches := false
+		for _, t := range f.Types {
+			matches = matches || hasType(txn, t)
+		}
+		if !matches {
+			return false
+		}
+	}
+	if len(f.UnlockHashes) > 0 {
+		uhs := unlockHashes(txn)
+		matches := false
+		for _, uh := range f.UnlockHashes {
+			_, exists := uhs[uh]
+			matches = matches || exists
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesSet returns true if any transaction of the set matches the filter.
+func (f TransactionPoolFilter) MatchesSet(ts []types.Transaction) bool {
+	for _, txn := range ts {
+		if f.MatchesTransaction(txn) {
+			return true
+		}
+	}
+	return false
+}
+
 // CalculateFee returns the fee-per-byte of a transaction set.
 func CalculateFee(ts []types.Transaction) types.Currency {
 	var sum types.Currency
@@ -176,3 +743,25 @@ func CalculateFee(ts []types.Transaction) types.Currency {
 	size := len(encoding.Marshal(ts))
 	return sum.Div64(uint64(size))
 }
+
+// Balances returns the siacoins and siafunds that the unconfirmed transactions
+// send to the unlock hash, and the siacoins and siafunds that they spend from
+// it. Outputs that are both created and spent by unconfirmed transactions are
+// counted in both directions.
+func (uo UnconfirmedOutputs) Balances() (incomingSiacoins, outgoingSiacoins, incomingSiafunds, outgoingSiafunds types.Currency) {
+	for _, o := range uo.Incoming {
+		if o.FundType == types.SpecifierSiacoinOutput {
+			incomingSiacoins = incomingSiacoins.Add(o.Value)
+		} else {
+			incomingSiafunds = incomingSiafunds.Add(o.Value)
+		}
+	}
+	for _, o := range uo.Outgoing {
+		if o.FundType == types.SpecifierSiacoinOutput {
+			outgoingSiacoins = outgoingSiacoins.Add(o.Value)
+		} else {
+			outgoingSiafunds = outgoingSiafunds.Add(o.Value)
+		}
+	}
+	return
+}
@@ -92,7 +92,7 @@ func (tp *TransactionPool) checkTransactionSetComposition(ts []types.Transaction
 	// fly.
 
 	// Check that all transactions follow 'Standard.md' guidelines.
-	setSize, err := isStandardTransactionSet(ts)
+	setSize, err := isStandardTransactionSet(ts, tp.settings.Policy)
 	if err != nil {
 		return 0, err
 	}
@@ -186,10 +186,17 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		return errLowMinerFees
 	}
 
+	// Check that the pool has room for the transaction set, or that the set
+	// pays enough fees to replace the cheapest sets in the pool.
+	evictions, err := tp.evictionsForSet(superset, supersetMap)
+	if err != nil {
+		return err
+	}
+
 	// Check that the transaction set is valid.
 	cc, err := txnFn(superset)
 	if err != nil {
-		return modules.NewConsensusConflict("provided transaction set has prereqs, but is still invalid: " + err.Error())
+		return tp.consensusRejection(superset, "provided transaction set has prereqs, but is still invalid", err)
 	}
 
 	// Remove the conflicts from the transaction pool.
@@ -198,7 +205,9 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		tp.transactionListSize -= len(encoding.Marshal(conflictSet))
 		delete(tp.transactionSets, conflict)
 		delete(tp.transactionSetDiffs, conflict)
+		tp.removalReasons[conflict] = modules.TransactionRemovalMerged
 	}
+	tp.evictTransactionSets(evictions)
 
 	// Add the transaction set to the pool.
 	setID := TransactionSetID(crypto.HashObject(superset))
@@ -284,12 +293,20 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 		}
 	}
 	if len(conflicts) > 0 {
+		if replaced := tp.doubleSpentSets(ts, conflicts); len(replaced) > 0 {
+			return tp.replaceTransactionSets(ts, conflicts, replaced, txnFn)
+		}
 		return tp.handleConflicts(ts, conflicts, txnFn)
 	}
+	evictions, err := tp.evictionsForSet(ts, nil)
+	if err != nil {
+		return err
+	}
 	cc, err := txnFn(ts)
 	if err != nil {
-		return modules.NewConsensusConflict("provided transaction set is standalone and invalid: " + err.Error())
+		return tp.consensusRejection(ts, "provided transaction set is standalone and invalid", err)
 	}
+	tp.evictTransactionSets(evictions)
 
 	// Add the transaction set to the pool.
 	setID := TransactionSetID(crypto.HashObject(ts))
@@ -323,6 +340,13 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 //
 // TODO: Break into component sets when the set gets accepted.
 func (tp *TransactionPool) AcceptTransactionSet(ts []types.Transaction) error {
+	return tp.managedAcceptTransactionSet(ts, "")
+}
+
+// managedAcceptTransactionSet adds a transaction set that was received from
+// source to the unconfirmed set of transactions, and relays it to the other
+// peers if it is accepted. source is empty for local transaction sets.
+func (tp *TransactionPool) managedAcceptTransactionSet(ts []types.Transaction, source modules.NetAddress) error {
 	// assert on consensus set to get special method
 	cs, ok := tp.consensusSet.(interface {
 		LockedTryTransactionSet(fn func(func(txns []types.Transaction) (modules.ConsensusChange, error)) error) error
@@ -335,12 +359,36 @@ func (tp *TransactionPool) AcceptTransactionSet(ts []types.Transaction) error {
 		tp.log.Debugln("Beginning broadcast of transaction set")
 		tp.mu.Lock()
 		defer tp.mu.Unlock()
-		err := tp.acceptTransactionSet(ts, txnFn)
+		// A rate limited set is not marked as seen, so that it can be
+		// received again once the source is allowed more sets.
+		if err := tp.allowSource(source); err != nil {
+			tp.log.Debugf("Rejecting transaction set from %v: %v\n", source, err)
+			err = rejectTransactionSet(err)
+			tp.recordRejected(ts, source, err)
+			return err
+		}
+		tp.markSeen(TransactionSetID(crypto.HashObject(ts)))
+		orphan, err := tp.tryAcceptTransactionSet(ts, txnFn)
+		if orphan {
+			// The parents of the set may still arrive, so hold on to it.
+			tp.addOrphan(ts, source)
+		}
 		if err != nil {
 			tp.log.Debugln("Transaction set broadcast has failed:", err)
+			err = rejectTransactionSet(err)
+			tp.chargeSource(source, err)
+			tp.recordRejected(ts, source, err)
 			return err
 		}
-		go tp.gateway.Broadcast("RelayTransactionSet", ts, tp.gateway.Peers())
+		if source == "" {
+			tp.markLocal(ts)
+		}
+		tp.markSource(ts, source)
+		tp.recordAccepted(ts, source)
+		go tp.managedRelayTransactionSet(ts, source)
+		for _, orphanSet := range tp.acceptOrphans(ts, txnFn) {
+			go tp.managedRelayTransactionSet(orphanSet, "")
+		}
 		// Notify subscribers of an accepted transaction set
 		tp.updateSubscribersTransactions()
 		tp.log.Debugln("Transaction set broadcast appears to have succeeded")
@@ -377,5 +425,5 @@ func (tp *TransactionPool) relayTransactionSet(conn modules.PeerConn) error {
 		return err
 	}
 
-	return tp.AcceptTransactionSet(ts)
+	return tp.managedAcceptTransactionSet(ts, conn.RPCAddr())
 }
@@ -3,6 +3,7 @@ package transactionpool
 import (
 	"testing"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
@@ -180,7 +181,7 @@ func TestCheckMinerFees(t *testing.T) {
 
 	// Add another transaction, this one should fail for having too few fees.
 	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{{}})
-	if err != errLowMinerFees {
+	if !rejectedWith(err, errLowMinerFees) {
 		t.Error(err)
 	}
 
@@ -227,7 +228,7 @@ func TestCheckMinerFees(t *testing.T) {
 		t.Fatal(err)
 	}
 	err = tpt.tpool.AcceptTransactionSet(lowFeeGraph)
-	if err != errLowMinerFees {
+	if !rejectedWith(err, errLowMinerFees) {
 		t.Fatal(err)
 	}
 }
@@ -361,6 +362,11 @@ func TestTransactionSuperset(t *testing.T) {
 	if err == nil {
 		t.Fatal("transaction set must have dependent transactions")
 	}
+	// The dependent transaction is held as an orphan. Drop it, so that it is
+	// not accepted along with the first transaction.
+	tpt.tpool.mu.Lock()
+	tpt.tpool.removeOrphan(TransactionSetID(crypto.HashObject(txnSet[1:])))
+	tpt.tpool.mu.Unlock()
 
 	// Submit the first transaction in the set to the transaction pool, and
 	// then the superset.
@@ -474,15 +480,19 @@ func TestTransactionChild(t *testing.T) {
 		t.Fatal("transaction set must have dependent transactions")
 	}
 
-	// Submit the first transaction in the set to the transaction pool.
+	// Submit the first transaction in the set to the transaction pool. The
+	// child was held as an orphan, and should be accepted along with it.
 	err = tpt.tpool.AcceptTransactionSet(txnSet[:1])
 	if err != nil {
 		t.Fatal("first transaction in the transaction set was not valid?")
 	}
-	err = tpt.tpool.AcceptTransactionSet(txnSet[1:])
-	if err != nil {
+	if _, _, exists := tpt.tpool.Transaction(txnSet[1].ID()); !exists {
 		t.Fatal("child transaction not seen as valid")
 	}
+	err = tpt.tpool.AcceptTransactionSet(txnSet[1:])
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Fatal("expected a duplicate error for the child, got", err)
+	}
 }
 
 // TestNilAccept tries submitting a nil transaction set and a 0-len
@@ -0,0 +1,208 @@
+package transactionpool
+
+// blockset.go selects the transactions that a miner should put into the next
+// block. Transactions are ranked by the fee rate of their ancestor package,
+// which is the transaction itself along with the unconfirmed transactions that
+// it depends on. A child that pays a high fee therefore pulls its low-fee
+// parents into the block, while the other transactions of the same set do not
+// dilute the rate of the child.
+//
+// The packages are ranked once, and the block is filled with the packages
+// that fit into the requested size, starting with the packages of priority transactions and then the
+// highest fee rate. Ancestors that are already in the block are not added
+// again.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// A blockCandidate is a transaction in the pool along with the indices of its
+// unconfirmed parents.
+type blockCandidate struct {
+	txn     types.Transaction
+	size    uint64
+	fees    types.Currency
+	parents []int
+}
+
+// createdObjectIDs returns the ids of the objects created by a transaction
+// that other transactions can depend on.
+func createdObjectIDs(txn types.Transaction) []ObjectID {
+	var oids []ObjectID
+	for i := range txn.SiacoinOutputs {
+		oids = append(oids, ObjectID(txn.SiacoinOutputID(uint64(i))))
+	}
+	for i := range txn.FileContracts {
+		oids = append(oids, ObjectID(txn.FileContractID(uint64(i))))
+	}
+	for i := range txn.SiafundOutputs {
+		oids = append(oids, ObjectID(txn.SiafundOutputID(uint64(i))))
+	}
+	return oids
+}
+
+// dependedObjectIDs returns the ids of the objects that a transaction depends
+// on.
+func dependedObjectIDs(txn types.Transaction) []ObjectID {
+	oids := spentObjectIDs(txn)
+	for _, fcr := range txn.FileContractRevisions {
+		oids = append(oids, ObjectID(fcr.ParentID))
+	}
+	for _, sp := range txn.StorageProofs {
+		oids = append(oids, ObjectID(sp.ParentID))
+	}
+	return oids
+}
+
+// blockCandidates returns the transactions in the pool in an order that puts
+// parents before their children, along with their dependencies.
+func (tp *TransactionPool) blockCandidates() []blockCandidate {
+	// Sort the sets by id so that the selection is deterministic.
+	ids := make([]TransactionSetID, 0, len(tp.transactionSets))
+	for id := range tp.transactionSets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return string(ids[i][:]) < string(ids[j][:])
+	})
+
+	// Link every transaction to the transactions that create the objects it
+	// depends on. A set may depend on a set that sorts after it.
+	var txns []types.Transaction
+	creators := make(map[ObjectID]int)
+	for _, id := range ids {
+		for _, txn := range tp.transactionSets[id] {
+			for _, oid := range createdObjectIDs(txn) {
+				creators[oid] = len(txns)
+			}
+			txns = append(txns, txn)
+		}
+	}
+	parents := make([][]int, len(txns))
+	for i, txn := range txns {
+		for _, oid := range dependedObjectIDs(txn) {
+			if parent, exists := creators[oid]; exists && parent != i {
+				parents[i] = append(parents[i], parent)
+			}
+		}
+	}
+
+	// Order the transactions so that every transaction follows its parents,
+	// visiting the parents of a transaction before the transaction itself.
+	position := make(map[int]int)
+	var order []int
+	var visit func(int)
+	visit = func(i int) {
+		if _, exists := position[i]; exists {
+			return
+		}
+		position[i] = -1
+		for _, parent := range parents[i] {
+			visit(parent)
+		}
+		position[i] = len(order)
+		order = append(order, i)
+	}
+	for i := range txns {
+		visit(i)
+	}
+
+	candidates := make([]blockCandidate, len(order))
+	for n, i := range order {
+		candidates[n] = blockCandidate{
+			txn:  txns[i],
+			size: uint64(len(encoding.Marshal(txns[i]))),
+			fees: setFees([]types.Transaction{txns[i]}),
+		}
+		for _, parent := range parents[i] {
+			candidates[n].parents = append(candidates[n].parents, position[parent])
+		}
+	}
+	return candidates
+}
+
+// ancestors returns the index of a candidate along with the indices of all of
+// its unconfirmed ancestors.
+func ancestors(candidates []blockCandidate, i int) map[int]struct{} {
+	pkg := map[int]struct{}{i: {}}
+	stack := []int{i}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, parent := range candidates[next].parents {
+			if _, exists := pkg[parent]; !exists {
+				pkg[parent] = struct{}{}
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return pkg
+}
+
+// BlockTransactions returns the transactions that a miner should put into the
+// next block, taking up at most maxSize bytes. Priority transactions come
+// first, and the other transactions are ranked by the fee rate of their
+// ancestor packages. Every transaction follows its unconfirmed parents, and
+// the selection only depends on the contents of the pool.
+func (tp *TransactionPool) BlockTransactions(maxSize uint64) []types.Transaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	// Rank the candidates by the fee rate of their ancestor packages.
+	candidates := tp.blockCandidates()
+	packages := make([]map[int]struct{}, len(candidates))
+	rates := make([]types.Currency, len(candidates))
+	for i := range candidates {
+		packages[i] = ancestors(candidates, i)
+		var size uint64
+		var fees types.Currency
+		for j := range packages[i] {
+			size += candidates[j].size
+			fees = fees.Add(candidates[j].fees)
+		}
+		rates[i] = fees.Div64(size)
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		pi, pj := isPriorityTransaction(candidates[order[i]].txn), isPriorityTransaction(candidates[order[j]].txn)
+		if pi != pj {
+			return pi
+		}
+		return rates[order[i]].Cmp(rates[order[j]]) > 0
+	})
+
+	// Fill the block with the packages that fit, adding the ancestors of a
+	// transaction that are not in the block yet in front of it.
+	var txns []types.Transaction
+	var blockSize uint64
+	selected := make(map[int]struct{})
+	for _, i := range order {
+		if _, exists := selected[i]; exists {
+			continue
+		}
+		var missing []int
+		var size uint64
+		for j := range packages[i] {
+			if _, exists := selected[j]; !exists {
+				missing = append(missing, j)
+				size += candidates[j].size
+			}
+		}
+		if blockSize+size > maxSize {
+			continue
+		}
+		sort.Ints(missing)
+		for _, j := range missing {
+			txns = append(txns, candidates[j].txn)
+			selected[j] = struct{}{}
+		}
+		blockSize += size
+	}
+	return txns
+}
@@ -0,0 +1,153 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlockTransactions checks that a child which pays a high fee pulls
+// its parent into the block ahead of sets that pay a higher fee rate than the
+// set of the child as a whole.
+func TestBlockTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create two outputs that can be spent without signatures.
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoinsMulti([]types.SiacoinOutput{
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output1 := txns[len(txns)-1].SiacoinOutputID(0)
+	output2 := txns[len(txns)-1].SiacoinOutputID(1)
+	if len(tpt.tpool.BlockTransactions(types.BlockSizeLimit)) != 0 {
+		t.Fatal("empty pool returned transactions for a block")
+	}
+
+	// The parent pays no fee and has two children, one that pays a high fee
+	// and a large one that pays no fee.
+	half := value.Div64(2)
+	parent := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: output1}},
+		SiacoinOutputs: []types.SiacoinOutput{
+			{Value: half, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+			{Value: half, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+		},
+	}
+	child := spendTxn(parent.SiacoinOutputID(0), half, types.SiacoinPrecision.Mul64(10), false)
+	sibling := types.Transaction{
+		SiacoinInputs:  []types.SiacoinInput{{ParentID: parent.SiacoinOutputID(1)}},
+		SiacoinOutputs: []types.SiacoinOutput{{Value: half, UnlockHash: types.UnlockConditions{}.UnlockHash()}},
+		ArbitraryData:  [][]byte{append(modules.PrefixNonSia[:], make([]byte, 10e3)...)},
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, child, sibling})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The other set pays a higher fee rate than the set of the parent, but a
+	// lower one than the parent and the child combined.
+	other := spendTxn(output2, value, types.SiacoinPrecision, false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []types.Transaction{parent, child, other, sibling}
+	blockTxns := tpt.tpool.BlockTransactions(types.BlockSizeLimit)
+	if len(blockTxns) != len(expected) {
+		t.Fatalf("expected %v transactions, got %v", len(expected), len(blockTxns))
+	}
+	for i := range expected {
+		if blockTxns[i].ID() != expected[i].ID() {
+			t.Fatal("transactions are in the wrong order at index", i)
+		}
+	}
+
+	// The selection should be a valid block.
+	_, err = tpt.cs.TryTransactionSet(blockTxns)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBlockTransactionsMaxSize checks that BlockTransactions fills the
+// requested size with the packages that pay the highest fee rate, keeping
+// children behind their parents, and that the selection is deterministic.
+func TestBlockTransactionsMaxSize(t *testing.T) {
+	tp := &TransactionPool{
+		transactionSets: make(map[TransactionSetID][]types.Transaction),
+	}
+	addSet := func(txn types.Transaction, fee uint64) types.Transaction {
+		txn.MinerFees = []types.Currency{types.NewCurrency64(fee)}
+		ts := []types.Transaction{txn}
+		tp.transactionSets[TransactionSetID(crypto.HashObject(ts))] = ts
+		return txn
+	}
+	size := func(txns ...types.Transaction) (n uint64) {
+		for _, txn := range txns {
+			n += uint64(len(encoding.Marshal(txn)))
+		}
+		return n
+	}
+
+	// The parent pays no fee, but its child pays enough for both of them.
+	parent := addSet(types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.SiacoinPrecision}},
+	}, 0)
+	child := addSet(types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: parent.SiacoinOutputID(0)}},
+	}, 1e6)
+	high := addSet(types.Transaction{ArbitraryData: [][]byte{[]byte("high")}}, 1e4)
+	low := addSet(types.Transaction{ArbitraryData: [][]byte{[]byte("low")}}, 1e2)
+
+	// Only the low fee set does not fit.
+	maxSize := size(parent, child, high) + size(low) - 1
+	expected := []types.Transaction{parent, child, high}
+	blockTxns := tp.BlockTransactions(maxSize)
+	if len(blockTxns) != len(expected) {
+		t.Fatalf("expected %v transactions, got %v", len(expected), len(blockTxns))
+	}
+	for i := range expected {
+		if blockTxns[i].ID() != expected[i].ID() {
+			t.Fatal("transactions are in the wrong order at index", i)
+		}
+	}
+
+	// If the child does not fit, neither does its parent.
+	blockTxns = tp.BlockTransactions(size(high))
+	if len(blockTxns) != 1 || blockTxns[0].ID() != high.ID() {
+		t.Fatal("expected only the high fee set, got", blockTxns)
+	}
+	if len(tp.BlockTransactions(0)) != 0 {
+		t.Fatal("transactions were selected for an empty block")
+	}
+
+	// The selection does not change between calls.
+	for i := 0; i < 10; i++ {
+		again := tp.BlockTransactions(types.BlockSizeLimit)
+		first := tp.BlockTransactions(types.BlockSizeLimit)
+		for j := range first {
+			if again[j].ID() != first[j].ID() {
+				t.Fatal("selection is not deterministic")
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -16,9 +17,10 @@ const (
 
 // Constants related to the size and ease-of-entry of the transaction pool.
 const (
-	// maxTxnAge determines the maximum age of a transaction (in block height)
-	// allowed before the transaction is pruned from the transaction pool.
-	maxTxnAge = types.BlockHeight(24)
+	// defaultMaxTransactionAge determines the maximum age of a transaction (in
+	// block height) allowed before the transaction is pruned from the
+	// transaction pool, unless the user configures a different age.
+	defaultMaxTransactionAge = types.BlockHeight(24)
 
 	// TransactionPoolFeeExponentiation defines the polynomial rate of growth
 	// required to keep putting transactions into the transaction pool. If the
@@ -37,6 +39,44 @@ const (
 	// TransactionPoolSizeTarget defines the target size of the pool when the
 	// transactions are paying 1 SC / kb in fees.
 	TransactionPoolSizeTarget = 3e6
+
+	// defaultMaxPoolSize is the maximum size of the transaction pool in bytes
+	// unless the user configures a different size.
+	defaultMaxPoolSize = 20e6
+
+	// maxReplacedTransactions is the maximum number of transactions that a
+	// single replacement can remove from the pool.
+	maxReplacedTransactions = 100
+)
+
+// Constants related to orphan transaction sets.
+const (
+	// maxOrphanSets is the maximum number of orphan transaction sets that
+	// the transaction pool holds while waiting for their parents.
+	maxOrphanSets = 100
+
+	// maxOrphanSize is the maximum combined size of the orphan transaction
+	// sets in bytes.
+	maxOrphanSize = 1e6
+
+	// maxOrphanAge is the number of blocks after which an orphan transaction
+	// set is dropped if its parents have not arrived.
+	maxOrphanAge = types.BlockHeight(6)
+)
+
+// Constants related to the metrics of the transaction pool.
+const (
+	// maxRecentRejections is the number of recently rejected transaction sets
+	// that the transaction pool remembers.
+	maxRecentRejections = 100
+)
+
+// Constants related to rate limiting the peers that relay transaction sets.
+const (
+	// rejectedSetCost is the number of sets of its allowance that a host is
+	// charged for a set that is rejected as invalid or for paying too little
+	// fees.
+	rejectedSetCost = 10
 )
 
 // Constants related to fee estimation.
@@ -69,6 +109,25 @@ var (
 	// minEstimation defines a sane minimum fee per byte for transactions.  This
 	// will typically be only suggested as a fee in the absence of congestion.
 	minEstimation = types.SiacoinPrecision.Div64(100).Div64(1e3)
+
+	// replacementFeeIncrement is the fee per byte of the replacing set that a
+	// replacement needs to pay on top of the fees of the sets that it
+	// replaces, so that replacements cannot be relayed for free.
+	replacementFeeIncrement = minEstimation
+)
+
+// Variables related to the standardness policy of the transaction pool.
+var (
+	// defaultPolicy is the policy of the transaction pool unless the user
+	// configures a different one. Only the rule that protects the pool from
+	// soft forks is enabled by default, the limits of the other rules are
+	// used once they get enabled.
+	defaultPolicy = modules.TransactionPoolPolicy{
+		MaxArbitraryDataSize:  1e3,
+		DustThreshold:         types.SiacoinPrecision.Div64(1e3),
+		MaxSignatures:         100,
+		CheckUnlockConditions: true,
+	}
 )
 
 // Variables related to propagating transactions through the network.
@@ -80,4 +139,33 @@ var (
 		Dev:      20 * time.Second,
 		Testing:  3 * time.Second,
 	}).(time.Duration)
+
+	// rebroadcastInterval is the interval at which the transaction sets that
+	// contain local transactions are relayed to peers again. Outside of
+	// testing it is longer than seenSetExpiry, so that peers which saw a set
+	// before request it again.
+	rebroadcastInterval = build.Select(build.Var{
+		Standard: 90 * time.Minute,
+		Dev:      15 * time.Minute,
+		Testing:  2 * time.Second,
+	}).(time.Duration)
+)
+
+// Variables related to rate limiting the peers that relay transaction sets.
+var (
+	// sourceSetBurst is the number of transaction sets that a host can relay
+	// at once before it is rate limited.
+	sourceSetBurst = build.Select(build.Var{
+		Standard: float64(1000),
+		Dev:      float64(500),
+		Testing:  float64(100),
+	}).(float64)
+
+	// sourceSetRate is the number of transaction sets per second that a host
+	// is allowed to relay after exhausting its burst.
+	sourceSetRate = build.Select(build.Var{
+		Standard: float64(10),
+		Dev:      float64(10),
+		Testing:  float64(5),
+	}).(float64)
 )
@@ -2,6 +2,7 @@ package transactionpool
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -30,9 +31,21 @@ var (
 	// median.
 	bucketFeeMedian = []byte("FeeMedian")
 
+	// bucketLocalTransactions holds the ids of the local transactions that
+	// were in the pool when it was last shut down, and the times at which
+	// they were first seen.
+	bucketLocalTransactions = []byte("LocalTransactions")
+
 	// bucketRecentConsensusChange holds the most recent consensus change seen
 	// by the transaction pool.
 	bucketRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// bucketSettings holds the settings of the transaction pool.
+	bucketSettings = []byte("Settings")
+
+	// bucketUnconfirmedSets holds the transaction sets that were in the pool
+	// when it was last shut down, keyed by their ids.
+	bucketUnconfirmedSets = []byte("UnconfirmedSets")
 )
 
 // Explicitly named fields in the database.
@@ -52,6 +65,10 @@ var (
 	// fieldRecentConsensusChange is the field in bucketRecentConsensusChange
 	// that holds the value of the most recent consensus change.
 	fieldRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// fieldSettings is the field in bucketSettings that holds the settings of
+	// the transaction pool.
+	fieldSettings = []byte("Settings")
 )
 
 // Errors relating to the database.
@@ -131,6 +148,58 @@ func (tp *TransactionPool) getRecentConsensusChange(tx *bolt.Tx) (cc modules.Con
 	return cc, nil
 }
 
+// getSettings returns the settings of the transaction pool from the database,
+// or the default settings if none have been stored yet.
+func (tp *TransactionPool) getSettings(tx *bolt.Tx) (modules.TransactionPoolSettings, error) {
+	settings := modules.TransactionPoolSettings{
+		MaxPoolSize:       defaultMaxPoolSize,
+		MaxTransactionAge: defaultMaxTransactionAge,
+		Policy:            defaultPolicy,
+	}
+	settingsBytes := tx.Bucket(bucketSettings).Get(fieldSettings)
+	if settingsBytes == nil {
+		return settings, nil
+	}
+	err := json.Unmarshal(settingsBytes, &settings)
+	if err != nil {
+		return modules.TransactionPoolSettings{}, build.ExtendErr("unable to unmarshal settings:", err)
+	}
+	return settings, nil
+}
+
+// getLocalTransactions returns the local transactions that were persisted
+// when the pool was last shut down, and the times at which they were first
+// seen.
+func (tp *TransactionPool) getLocalTransactions(tx *bolt.Tx) (map[types.TransactionID]time.Time, error) {
+	local := make(map[types.TransactionID]time.Time)
+	err := tx.Bucket(bucketLocalTransactions).ForEach(func(idBytes, timeBytes []byte) error {
+		var id types.TransactionID
+		var firstSeen int64
+		copy(id[:], idBytes)
+		if err := encoding.Unmarshal(timeBytes, &firstSeen); err != nil {
+			return err
+		}
+		local[id] = time.Unix(firstSeen, 0)
+		return nil
+	})
+	return local, err
+}
+
+// getUnconfirmedSets returns the transaction sets that were persisted when the
+// pool was last shut down.
+func (tp *TransactionPool) getUnconfirmedSets(tx *bolt.Tx) ([][]types.Transaction, error) {
+	var sets [][]types.Transaction
+	err := tx.Bucket(bucketUnconfirmedSets).ForEach(func(_, setBytes []byte) error {
+		var ts []types.Transaction
+		if err := encoding.Unmarshal(setBytes, &ts); err != nil {
+			return err
+		}
+		sets = append(sets, ts)
+		return nil
+	})
+	return sets, err
+}
+
 // putBlockHeight updates the transaction pool's block height.
 func (tp *TransactionPool) putBlockHeight(tx *bolt.Tx, height types.BlockHeight) error {
 	tp.blockHeight = height
@@ -158,6 +227,62 @@ func (tp *TransactionPool) putRecentConsensusChange(tx *bolt.Tx, cc modules.Cons
 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, cc[:])
 }
 
+// putSettings stores the settings of the transaction pool in the database.
+func (tp *TransactionPool) putSettings(tx *bolt.Tx, settings modules.TransactionPoolSettings) error {
+	settingsBytes, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketSettings).Put(fieldSettings, settingsBytes)
+}
+
+// putUnconfirmedSets replaces the persisted transaction sets with the sets
+// that are currently in the pool.
+func (tp *TransactionPool) putUnconfirmedSets(tx *bolt.Tx) error {
+	err := tx.DeleteBucket(bucketUnconfirmedSets)
+	if err != nil {
+		return err
+	}
+	bucket, err := tx.CreateBucket(bucketUnconfirmedSets)
+	if err != nil {
+		return err
+	}
+	for id, ts := range tp.transactionSets {
+		err := bucket.Put(id[:], encoding.Marshal(ts))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putLocalTransactions replaces the persisted local transactions with the
+// local transactions that are currently in the pool.
+func (tp *TransactionPool) putLocalTransactions(tx *bolt.Tx) error {
+	err := tx.DeleteBucket(bucketLocalTransactions)
+	if err != nil {
+		return err
+	}
+	bucket, err := tx.CreateBucket(bucketLocalTransactions)
+	if err != nil {
+		return err
+	}
+	for _, ts := range tp.transactionSets {
+		for _, txn := range ts {
+			id := txn.ID()
+			firstSeen, exists := tp.localTransactions[id]
+			if !exists {
+				continue
+			}
+			err := bucket.Put(id[:], encoding.Marshal(firstSeen.Unix()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // putTransaction adds a transaction to the list of confirmed transactions.
 func (tp *TransactionPool) putTransaction(tx *bolt.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Put(id[:], []byte{})
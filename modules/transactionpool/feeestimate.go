@@ -0,0 +1,116 @@
+package transactionpool
+
+// feeestimate.go estimates the fee that a transaction needs to pay to be
+// confirmed within a target number of blocks. Two estimates are combined, and
+// the higher one is used.
+//
+// The first estimate comes from recent blocks. For every block, the update
+// code records the fee rate at which the cheaper quarter of the block space
+// ends. Assuming that the next blocks behave like the recent ones, a fee that
+// clears the recorded rate of a fraction p of the blocks is confirmed within t
+// blocks with probability 1-(1-p)^t, so the estimate picks the smallest
+// recorded rate that is confirmed with a probability of at least
+// feeEstimateConfidence.
+//
+// The second estimate comes from the pool itself. Miners fill blocks with the
+// sets that pay the highest fee rates, so a transaction only fits into the
+// next t blocks if it pays more than the sets that would fill them. If the
+// pool is full, the estimate also clears the minimum fee rate that the pool
+// accepts.
+
+import (
+	"math"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// feeEstimateConfidence is the probability with which a transaction that
+	// pays the estimated fee is confirmed within the target number of blocks.
+	feeEstimateConfidence = 0.95
+)
+
+// setFees returns the sum of the miner fees of a transaction set.
+func setFees(ts []types.Transaction) types.Currency {
+	var fees types.Currency
+	for _, txn := range ts {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	return fees
+}
+
+// feeRate returns the fee per byte paid by a transaction set of the given
+// size.
+func feeRate(ts []types.Transaction, size uint64) types.Currency {
+	if size == 0 {
+		return types.ZeroCurrency
+	}
+	return setFees(ts).Div64(size)
+}
+
+// blockFeeEstimate returns the fee per byte needed to be confirmed within
+// target blocks according to the recent blocks.
+func (tp *TransactionPool) blockFeeEstimate(target types.BlockHeight) types.Currency {
+	if len(tp.recentMedians) == 0 {
+		return types.ZeroCurrency
+	}
+	medians := append([]types.Currency(nil), tp.recentMedians...)
+	sort.Slice(medians, func(i, j int) bool {
+		return medians[i].Cmp(medians[j]) < 0
+	})
+	p := 1 - math.Pow(1-feeEstimateConfidence, 1/float64(target))
+	i := int(math.Ceil(p*float64(len(medians)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(medians) {
+		i = len(medians) - 1
+	}
+	return medians[i]
+}
+
+// poolFeeEstimate returns the fee per byte needed to outbid the transaction
+// sets in the pool that would fill the next target blocks. It is zero if the
+// pool does not fill them.
+func (tp *TransactionPool) poolFeeEstimate(target types.BlockHeight) types.Currency {
+	sets := tp.setsByFeeRate(nil)
+	space := uint64(target) * types.BlockSizeLimit
+	var filled uint64
+	for i := len(sets) - 1; i >= 0; i-- {
+		filled += sets[i].size
+		if filled > space {
+			return sets[i].rate.Add(types.NewCurrency64(1))
+		}
+	}
+	return types.ZeroCurrency
+}
+
+// FeeEstimate returns the fee per byte that a transaction needs to pay to be
+// confirmed within target blocks, based on the fees of the recent blocks and
+// on the congestion of the transaction pool. A target of 0 is treated as 1.
+func (tp *TransactionPool) FeeEstimate(target types.BlockHeight) types.Currency {
+	if err := tp.tg.Add(); err != nil {
+		return minEstimation
+	}
+	defer tp.tg.Done()
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	if target == 0 {
+		target = 1
+	}
+	fee := minEstimation
+	for _, estimate := range []types.Currency{
+		tp.blockFeeEstimate(target),
+		tp.poolFeeEstimate(target),
+		tp.requiredFeesToExtendTpool().MulFloat(minExtendMultiplier),
+		tp.minimumFeeRate().Add(types.NewCurrency64(1)),
+	} {
+		if estimate.Cmp(fee) > 0 {
+			fee = estimate
+		}
+	}
+	return fee
+}
@@ -0,0 +1,117 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlockFeeEstimate checks that longer targets pick lower fees from the
+// recent blocks.
+func TestBlockFeeEstimate(t *testing.T) {
+	tp := new(TransactionPool)
+	if fee := tp.blockFeeEstimate(1); !fee.IsZero() {
+		t.Fatal("expected no estimate without recent blocks, got", fee)
+	}
+	for i := 10; i > 0; i-- {
+		tp.recentMedians = append(tp.recentMedians, types.NewCurrency64(uint64(i)))
+	}
+	tests := []struct {
+		target types.BlockHeight
+		fee    uint64
+	}{
+		{1, 10},
+		{2, 8},
+		{3, 7},
+		{6, 4},
+		{12, 3},
+		{1000, 1},
+	}
+	for _, test := range tests {
+		if fee := tp.blockFeeEstimate(test.target); !fee.Equals64(test.fee) {
+			t.Errorf("expected a fee of %v for a target of %v, got %v", test.fee, test.target, fee)
+		}
+	}
+	if tp.recentMedians[0].Cmp64(10) != 0 {
+		t.Fatal("estimating the fee reordered the recent medians")
+	}
+}
+
+// TestPoolFeeEstimate checks that the pool estimate outbids the sets that
+// fill the target blocks.
+func TestPoolFeeEstimate(t *testing.T) {
+	tp := &TransactionPool{transactionSets: make(map[TransactionSetID][]types.Transaction)}
+	addSet := func(rate uint64, size int) {
+		txn := types.Transaction{ArbitraryData: [][]byte{make([]byte, size)}}
+		txn.MinerFees = []types.Currency{types.NewCurrency64(rate * uint64(size))}
+		ts := []types.Transaction{txn}
+		tp.transactionSets[TransactionSetID(crypto.HashObject(ts))] = ts
+	}
+	setSize := int(types.BlockSizeLimit / 4)
+
+	// Less than a block of transactions does not raise the estimate.
+	for rate := uint64(1); rate <= 3; rate++ {
+		addSet(rate*100, setSize)
+	}
+	if fee := tp.poolFeeEstimate(1); !fee.IsZero() {
+		t.Fatal("expected no estimate for a pool that does not fill a block, got", fee)
+	}
+
+	// Once the pool fills the next block, the estimate should outbid the
+	// most expensive set that does not fit into it.
+	for rate := uint64(4); rate <= 8; rate++ {
+		addSet(rate*100, setSize)
+	}
+	var ts []types.Transaction
+	for _, s := range tp.transactionSets {
+		ts = s
+		break
+	}
+	actualSize := uint64(len(encoding.Marshal(ts)))
+	if actualSize <= uint64(setSize) {
+		t.Fatal("set is smaller than expected")
+	}
+	// The 4 most expensive sets exceed a block, so the 4th one, paying a
+	// rate of 500 on its payload, sets the estimate.
+	fee := tp.poolFeeEstimate(1)
+	expected := feeRate([]types.Transaction{{MinerFees: []types.Currency{types.NewCurrency64(500 * uint64(setSize))}}}, actualSize).Add(types.NewCurrency64(1))
+	if !fee.Equals(expected) {
+		t.Fatalf("expected an estimate of %v, got %v", expected, fee)
+	}
+	if fee := tp.poolFeeEstimate(2); !fee.Equals(types.NewCurrency64(100 * uint64(setSize)).Div64(actualSize).Add(types.NewCurrency64(1))) {
+		t.Fatal("wrong estimate for a target of 2 blocks:", fee)
+	}
+	if fee := tp.poolFeeEstimate(3); !fee.IsZero() {
+		t.Fatal("expected no estimate for a pool that does not fill 3 blocks, got", fee)
+	}
+}
+
+// TestFeeEstimate checks that the fee estimate never drops below the minimum
+// estimation and decreases for longer targets.
+func TestFeeEstimate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	if fee := tpt.tpool.FeeEstimate(1); fee.Cmp(minEstimation) < 0 {
+		t.Fatal("fee estimate is below the minimum estimation:", fee)
+	}
+	tpt.tpool.mu.Lock()
+	for i := range tpt.tpool.recentMedians {
+		tpt.tpool.recentMedians[i] = minEstimation.Mul64(uint64(i + 2))
+	}
+	tpt.tpool.mu.Unlock()
+	fast, slow := tpt.tpool.FeeEstimate(0), tpt.tpool.FeeEstimate(100)
+	if fast.Cmp(slow) <= 0 {
+		t.Fatalf("expected a fast confirmation to cost more than a slow one, got %v and %v", fast, slow)
+	} else if !fast.Equals(tpt.tpool.FeeEstimate(1)) {
+		t.Fatal("a target of 0 should be treated as a target of 1")
+	}
+}
@@ -0,0 +1,65 @@
+package transactionpool
+
+// local.go tracks the local transactions in the pool, which are the
+// transactions that were submitted to this node through the wallet or the API
+// rather than received from peers. Local transaction sets are never evicted
+// to make room for sets that pay a higher fee rate, and they are persisted
+// along with the time at which they were first seen, so that a restart does
+// not turn them into remote transactions.
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// markLocal records the transactions of a set as local. The first time that a
+// transaction was seen is kept if it is already local.
+func (tp *TransactionPool) markLocal(ts []types.Transaction) {
+	now := time.Now()
+	for _, txn := range ts {
+		if _, exists := tp.localTransactions[txn.ID()]; !exists {
+			tp.localTransactions[txn.ID()] = now
+		}
+	}
+}
+
+// isLocalSet returns true if a transaction set contains a local transaction.
+func (tp *TransactionPool) isLocalSet(ts []types.Transaction) bool {
+	for _, txn := range ts {
+		if _, exists := tp.localTransactions[txn.ID()]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalTransactions returns the local transactions in the pool, sorted by the
+// time at which they were first seen.
+func (tp *TransactionPool) LocalTransactions() []modules.LocalTransaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var local []modules.LocalTransaction
+	for _, ts := range tp.transactionSets {
+		for _, txn := range ts {
+			id := txn.ID()
+			if firstSeen, exists := tp.localTransactions[id]; exists {
+				local = append(local, modules.LocalTransaction{
+					Transaction: txn,
+					ID:          id,
+					FirstSeen:   firstSeen,
+				})
+			}
+		}
+	}
+	sort.Slice(local, func(i, j int) bool {
+		if !local[i].FirstSeen.Equal(local[j].FirstSeen) {
+			return local[i].FirstSeen.Before(local[j].FirstSeen)
+		}
+		return string(local[i].ID[:]) < string(local[j].ID[:])
+	})
+	return local
+}
@@ -0,0 +1,125 @@
+package transactionpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestLocalSetsNotEvicted checks that sets with local transactions are never
+// evicted to make room for sets that pay a higher fee rate.
+func TestLocalSetsNotEvicted(t *testing.T) {
+	tp := &TransactionPool{
+		transactionSets:   make(map[TransactionSetID][]types.Transaction),
+		localTransactions: make(map[types.TransactionID]time.Time),
+		settings:          modules.TransactionPoolSettings{MaxPoolSize: modules.TransactionSetSizeLimit},
+	}
+	addSet := func(data string, fee uint64) TransactionSetID {
+		ts := []types.Transaction{{
+			ArbitraryData: [][]byte{[]byte(data)},
+			MinerFees:     []types.Currency{types.NewCurrency64(fee)},
+		}}
+		id := TransactionSetID(crypto.HashObject(ts))
+		tp.transactionSets[id] = ts
+		tp.transactionListSize += len(encoding.Marshal(ts))
+		return id
+	}
+	localID := addSet("local", 1)
+	remoteID := addSet("remote", 1e6)
+	tp.markLocal(tp.transactionSets[localID])
+
+	evictions := tp.cheapestSets(uint64(tp.transactionListSize), nil)
+	if len(evictions) != 1 || evictions[0].id != remoteID {
+		t.Fatal("expected only the remote set to be evictable, got", evictions)
+	}
+	if !tp.minimumFeeRate().Equals(evictions[0].rate) {
+		t.Fatal("the fee rate of the local set was used as the minimum fee rate")
+	}
+}
+
+// TestLocalTransactions checks that the transactions submitted to the pool
+// are reported as local along with the time at which they were first seen,
+// and that they stay local across restarts while remote transactions do not
+// become local.
+func TestLocalTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := spendTxn(output, value, types.SiacoinPrecision, false)
+	err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{remote}, modules.NetAddress("foo.com:1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.LocalTransactions()) != 0 {
+		t.Fatal("remote transaction was reported as local")
+	}
+
+	start := time.Now().Truncate(time.Second)
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLocal := func() {
+		local := tpt.tpool.LocalTransactions()
+		if len(local) != len(txns) {
+			t.Fatalf("expected %v local transactions, got %v", len(txns), len(local))
+		}
+		for i, lt := range local {
+			if lt.ID != lt.Transaction.ID() || lt.FirstSeen.Before(start) {
+				t.Fatal("wrong local transaction:", lt)
+			}
+			if i > 0 && lt.FirstSeen.Before(local[i-1].FirstSeen) {
+				t.Fatal("local transactions are not sorted by the time they were first seen")
+			}
+		}
+		for _, txn := range txns {
+			found := false
+			for _, lt := range local {
+				found = found || lt.ID == txn.ID()
+			}
+			if !found {
+				t.Fatal("wallet transaction was not reported as local")
+			}
+		}
+	}
+	checkLocal()
+
+	// After a restart, the wallet transactions are still local and the
+	// remote transaction is not.
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, tpt.tpool.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(remote.ID()); !exists {
+		t.Fatal("remote transaction was not reloaded")
+	}
+	checkLocal()
+
+	// Confirmed transactions are no longer local.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.LocalTransactions()) != 0 {
+		t.Fatal("confirmed transactions were reported as local")
+	}
+}
@@ -0,0 +1,141 @@
+package transactionpool
+
+// metrics.go implements the metrics sinks of the transaction pool and keeps
+// track of the transaction sets that were recently rejected. A sink is handed
+// every measurement taken by the transaction pool, which allows exporters for
+// monitoring systems to be built outside of the transactionpool package.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// recordMetric sends a measurement to every metrics sink. The transaction pool
+// must be locked (read or write) when recordMetric is called.
+func (tp *TransactionPool) recordMetric(m modules.TransactionPoolMetric) {
+	for _, sink := range tp.metricsSinks {
+		sink.RecordTransactionPoolMetric(m)
+	}
+}
+
+// setMetric returns a measurement of a transaction set.
+func setMetric(metricType modules.TransactionPoolMetricType, ts []types.Transaction, source modules.NetAddress) modules.TransactionPoolMetric {
+	size := uint64(len(encoding.Marshal(ts)))
+	return modules.TransactionPoolMetric{
+		Type:    metricType,
+		Count:   len(ts),
+		Size:    size,
+		FeeRate: feeRate(ts, size),
+		Peer:    source,
+	}
+}
+
+// recordAccepted counts a transaction set that was accepted into the pool.
+func (tp *TransactionPool) recordAccepted(ts []types.Transaction, source modules.NetAddress) {
+	tp.acceptedSets++
+	if len(tp.metricsSinks) > 0 {
+		tp.recordMetric(setMetric(modules.TransactionPoolMetricAccepted, ts, source))
+	}
+}
+
+// recordRejected counts a transaction set that was rejected by the pool, and
+// adds it to the recent rejections. Errors that are not rejections, like
+// duplicate transaction sets, are ignored.
+func (tp *TransactionPool) recordRejected(ts []types.Transaction, source modules.NetAddress, err error) {
+	tr, ok := err.(modules.TransactionRejection)
+	if !ok {
+		return
+	}
+	tp.rejectedSets++
+	if len(tp.recentRejections) == maxRecentRejections {
+		tp.recentRejections = append(tp.recentRejections[:0], tp.recentRejections[1:]...)
+	}
+	tp.recentRejections = append(tp.recentRejections, modules.RejectedTransactionSet{
+		ID:            modules.TransactionSetID(crypto.HashObject(ts)),
+		Reason:        tr.Reason,
+		Error:         tr.Error(),
+		TransactionID: tr.TransactionID,
+		Source:        source,
+		Time:          time.Now(),
+	})
+	if len(tp.metricsSinks) > 0 {
+		m := setMetric(modules.TransactionPoolMetricRejected, ts, source)
+		m.Reason = tr.Reason
+		tp.recordMetric(m)
+	}
+}
+
+// medianFeeRate returns the median fee rate of the transaction sets in the
+// pool.
+func (tp *TransactionPool) medianFeeRate() types.Currency {
+	sets := tp.setsByFeeRate(nil)
+	if len(sets) == 0 {
+		return types.ZeroCurrency
+	}
+	return sets[len(sets)/2].rate
+}
+
+// recordPoolSize records the size and the median fee rate of the pool.
+func (tp *TransactionPool) recordPoolSize() {
+	if len(tp.metricsSinks) == 0 {
+		return
+	}
+	tp.recordMetric(modules.TransactionPoolMetric{
+		Type:    modules.TransactionPoolMetricPoolSize,
+		Count:   len(tp.transactionSets),
+		Size:    uint64(tp.transactionListSize),
+		FeeRate: tp.medianFeeRate(),
+	})
+}
+
+// RecentRejections returns the transaction sets that were most recently
+// rejected by the pool, oldest first.
+func (tp *TransactionPool) RecentRejections() []modules.RejectedTransactionSet {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return append([]modules.RejectedTransactionSet(nil), tp.recentRejections...)
+}
+
+// RegisterMetricsSink adds a sink that receives every measurement taken by the
+// transaction pool after registering. Sinks may be called while the
+// transaction pool is locked.
+func (tp *TransactionPool) RegisterMetricsSink(sink modules.TransactionPoolMetricsSink) {
+	if tp.tg.Add() != nil {
+		return
+	}
+	defer tp.tg.Done()
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	for _, s := range tp.metricsSinks {
+		if s == sink {
+			build.Critical("refusing to register metrics sink twice")
+			return
+		}
+	}
+	tp.metricsSinks = append(tp.metricsSinks, sink)
+}
+
+// UnregisterMetricsSink removes a metrics sink. If the sink is not found, no
+// action is taken.
+func (tp *TransactionPool) UnregisterMetricsSink(sink modules.TransactionPoolMetricsSink) {
+	if tp.tg.Add() != nil {
+		return
+	}
+	defer tp.tg.Done()
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	for i := range tp.metricsSinks {
+		if tp.metricsSinks[i] == sink {
+			tp.metricsSinks[i] = nil
+			tp.metricsSinks = append(tp.metricsSinks[:i], tp.metricsSinks[i+1:]...)
+			break
+		}
+	}
+}
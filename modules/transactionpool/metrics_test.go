@@ -0,0 +1,129 @@
+package transactionpool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mockMetricsSink holds the measurements it receives, grouped by type.
+type mockMetricsSink struct {
+	metrics map[modules.TransactionPoolMetricType][]modules.TransactionPoolMetric
+	mu      sync.Mutex
+}
+
+// RecordTransactionPoolMetric adds a measurement to the mock metrics sink.
+func (mms *mockMetricsSink) RecordTransactionPoolMetric(m modules.TransactionPoolMetric) {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+	mms.metrics[m.Type] = append(mms.metrics[m.Type], m)
+}
+
+// get returns the measurements of a type that the sink received.
+func (mms *mockMetricsSink) get(metricType modules.TransactionPoolMetricType) []modules.TransactionPoolMetric {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+	return append([]modules.TransactionPoolMetric(nil), mms.metrics[metricType]...)
+}
+
+// TestMetricsSink checks that a metrics sink receives measurements for
+// accepted, rejected and relayed transaction sets and for consensus changes,
+// and that the pool counts the sets that it accepts and rejects.
+func TestMetricsSink(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	mms := &mockMetricsSink{metrics: make(map[modules.TransactionPoolMetricType][]modules.TransactionPoolMetric)}
+	tpt.tpool.RegisterMetricsSink(mms)
+
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := mms.get(modules.TransactionPoolMetricAccepted)
+	if len(accepted) != 1 || accepted[0].Count != len(txns) || accepted[0].Size == 0 || accepted[0].Peer != "" {
+		t.Fatal("wrong accepted metrics:", accepted)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if len(mms.get(modules.TransactionPoolMetricRelay)) != 1 {
+			return errors.New("relay was not recorded")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tpt.tpool.AcceptTransactionSet(nil)
+	if !rejectedWith(err, errEmptySet) {
+		t.Fatal("expected an empty set rejection, got", err)
+	}
+	rejected := mms.get(modules.TransactionPoolMetricRejected)
+	if len(rejected) != 1 || rejected[0].Reason != modules.RejectionEmptySet {
+		t.Fatal("wrong rejected metrics:", rejected)
+	}
+	stats := tpt.tpool.Stats()
+	if stats.AcceptedSets != 1 || stats.RejectedSets != 1 {
+		t.Fatal("wrong set counts:", stats.AcceptedSets, stats.RejectedSets)
+	}
+
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := mms.get(modules.TransactionPoolMetricPoolSize)
+	if len(sizes) != 1 || sizes[0].Count != 0 || sizes[0].Size != 0 {
+		t.Fatal("wrong pool size metrics:", sizes)
+	}
+
+	// The sink should not receive measurements after it is unregistered.
+	tpt.tpool.UnregisterMetricsSink(mms)
+	tpt.tpool.AcceptTransactionSet(nil)
+	if len(mms.get(modules.TransactionPoolMetricRejected)) != 1 {
+		t.Fatal("unregistered sink received a measurement")
+	}
+}
+
+// TestRecentRejections checks that the pool remembers the most recently
+// rejected transaction sets.
+func TestRecentRejections(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Reject more sets than the pool remembers.
+	for i := 0; i < maxRecentRejections+10; i++ {
+		tpt.tpool.AcceptTransactionSet(nil)
+	}
+	rejections := tpt.tpool.RecentRejections()
+	if len(rejections) != maxRecentRejections {
+		t.Fatalf("expected %v rejections, got %v", maxRecentRejections, len(rejections))
+	}
+	for i, r := range rejections {
+		if r.Reason != modules.RejectionEmptySet || r.Source != "" || r.Time.IsZero() {
+			t.Fatal("wrong rejection:", r)
+		}
+		if i > 0 && r.Time.Before(rejections[i-1].Time) {
+			t.Fatal("rejections are not ordered oldest first")
+		}
+	}
+	if stats := tpt.tpool.Stats(); stats.RejectedSets != maxRecentRejections+10 {
+		t.Fatal("wrong number of rejected sets:", stats.RejectedSets)
+	}
+}
@@ -0,0 +1,147 @@
+package transactionpool
+
+// orphan.go holds transaction sets that spend the outputs of transactions that
+// the pool has not seen yet. Such orphan sets are common when the sets of a
+// multi-transaction chain, like the sets that form a file contract, are relayed
+// out of order. Instead of dropping them, the pool keeps a bounded number of
+// orphans until their parents are accepted or confirmed, and then tries to
+// accept them again.
+//
+// An orphan may also be a set that spends outputs that were spent already.
+// Such sets never become valid, and are dropped once they are too old or the
+// orphan buffer is full.
+
+import (
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// An orphanSet is a transaction set that is waiting for its parents.
+type orphanSet struct {
+	transactions []types.Transaction
+	source       modules.NetAddress
+	height       types.BlockHeight
+	size         int
+}
+
+// spendsAny returns true if the orphan spends one of the objects in oids.
+func (o orphanSet) spendsAny(oids map[ObjectID]struct{}) bool {
+	for _, txn := range o.transactions {
+		for _, oid := range spentObjectIDs(txn) {
+			if _, exists := oids[oid]; exists {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tryAcceptTransactionSet accepts a transaction set into the pool. If the set
+// is rejected, orphan indicates whether it was rejected because it spends
+// outputs that are unknown to the consensus set.
+func (tp *TransactionPool) tryAcceptTransactionSet(ts []types.Transaction, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) (orphan bool, err error) {
+	err = tp.acceptTransactionSet(ts, func(txns []types.Transaction) (modules.ConsensusChange, error) {
+		cc, err := txnFn(txns)
+		orphan = err == modules.ErrMissingSiacoinOutput
+		return cc, err
+	})
+	return orphan && err != nil, err
+}
+
+// addOrphan adds a transaction set that was relayed by source to the orphans,
+// dropping the oldest orphans if the buffer is full.
+func (tp *TransactionPool) addOrphan(ts []types.Transaction, source modules.NetAddress) {
+	id := TransactionSetID(crypto.HashObject(ts))
+	if _, exists := tp.orphans[id]; exists {
+		return
+	}
+	size := len(encoding.Marshal(ts))
+	if size > maxOrphanSize {
+		return
+	}
+	for len(tp.orphans) >= maxOrphanSets || tp.orphanSize+size > maxOrphanSize {
+		var oldest TransactionSetID
+		oldestHeight := tp.blockHeight + 1
+		for oid, o := range tp.orphans {
+			if o.height < oldestHeight {
+				oldest, oldestHeight = oid, o.height
+			}
+		}
+		tp.removeOrphan(oldest)
+	}
+	tp.orphans[id] = orphanSet{
+		transactions: ts,
+		source:       source,
+		height:       tp.blockHeight,
+		size:         size,
+	}
+	tp.orphanSize += size
+	tp.log.Debugf("holding orphan transaction set %v until its parents arrive\n", id)
+}
+
+// removeOrphan removes a transaction set from the orphans.
+func (tp *TransactionPool) removeOrphan(id TransactionSetID) {
+	tp.orphanSize -= tp.orphans[id].size
+	delete(tp.orphans, id)
+}
+
+// pruneOrphans drops the orphans that have waited for their parents for more
+// than maxOrphanAge blocks.
+func (tp *TransactionPool) pruneOrphans() {
+	for id, o := range tp.orphans {
+		if tp.blockHeight > o.height+maxOrphanAge {
+			tp.removeOrphan(id)
+		}
+	}
+}
+
+// acceptOrphans tries to accept the orphans that spend outputs created by
+// parents, followed by the orphans that spend outputs of the orphans that get
+// accepted. Orphans that are still missing parents are kept, and all other
+// orphans that fail are dropped and charged to their source. The accepted
+// orphan sets are returned.
+func (tp *TransactionPool) acceptOrphans(parents []types.Transaction, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) [][]types.Transaction {
+	var accepted [][]types.Transaction
+	for len(parents) > 0 && len(tp.orphans) > 0 {
+		created := make(map[ObjectID]struct{})
+		for _, txn := range parents {
+			for _, oid := range createdObjectIDs(txn) {
+				created[oid] = struct{}{}
+			}
+		}
+		parents = nil
+
+		var ready []TransactionSetID
+		for id, o := range tp.orphans {
+			if o.spendsAny(created) {
+				ready = append(ready, id)
+			}
+		}
+		for _, id := range ready {
+			o := tp.orphans[id]
+			orphan, err := tp.tryAcceptTransactionSet(o.transactions, txnFn)
+			if orphan {
+				continue
+			}
+			tp.removeOrphan(id)
+			if err != nil {
+				tp.log.Debugln("Dropping orphan transaction set:", err)
+				err = rejectTransactionSet(err)
+				tp.chargeSource(o.source, err)
+				tp.recordRejected(o.transactions, o.source, err)
+				continue
+			}
+			if o.source == "" {
+				tp.markLocal(o.transactions)
+			}
+			tp.markSource(o.transactions, o.source)
+			tp.recordAccepted(o.transactions, o.source)
+			tp.log.Debugf("accepted orphan transaction set %v\n", id)
+			accepted = append(accepted, o.transactions)
+			parents = append(parents, o.transactions...)
+		}
+	}
+	return accepted
+}
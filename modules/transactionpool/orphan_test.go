@@ -0,0 +1,152 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// createSpendableOutput sends value to the empty unlock conditions and
+// confirms the transaction, returning the id of the new output.
+func (tpt *tpoolTester) createSpendableOutput(value types.Currency) (types.SiacoinOutputID, error) {
+	txns, err := tpt.wallet.SendSiacoins(value, types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		return types.SiacoinOutputID{}, err
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		return types.SiacoinOutputID{}, err
+	}
+	return txns[len(txns)-1].SiacoinOutputID(0), nil
+}
+
+// TestOrphanTransactionSets checks that transaction sets which arrive before
+// their parents are accepted once the parents arrive.
+func TestOrphanTransactionSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := spendTxn(output, value, fee, false)
+	child := spendTxn(parent.SiacoinOutputID(0), value.Sub(fee), fee, false)
+	grandchild := spendTxn(child.SiacoinOutputID(0), value.Sub(fee).Sub(fee), fee, false)
+
+	// The descendants are rejected, but held as orphans.
+	for _, txn := range []types.Transaction{grandchild, child} {
+		err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+		if !modules.IsConsensusConflict(err) {
+			t.Fatal("expected a consensus conflict, got", err)
+		}
+	}
+	if len(tpt.tpool.orphans) != 2 || len(tpt.tpool.TransactionList()) != 0 {
+		t.Fatal("descendants were not held as orphans")
+	}
+
+	// Accepting the parent should pull in the whole chain.
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range []types.Transaction{parent, child, grandchild} {
+		if _, _, exists := tpt.tpool.Transaction(txn.ID()); !exists {
+			t.Fatal("transaction of the chain is not in the pool")
+		}
+	}
+	if len(tpt.tpool.orphans) != 0 || tpt.tpool.orphanSize != 0 {
+		t.Fatal("accepted orphans were not removed")
+	}
+}
+
+// TestOrphanConfirmedParent checks that orphans are accepted when their
+// parents are confirmed without passing through the pool.
+func TestOrphanConfirmedParent(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := spendTxn(output, value, fee, false)
+	child := spendTxn(parent.SiacoinOutputID(0), value.Sub(fee), fee, false)
+	tpt.tpool.AcceptTransactionSet([]types.Transaction{child})
+	if len(tpt.tpool.orphans) != 1 {
+		t.Fatal("child was not held as an orphan")
+	}
+
+	// Mine a block that contains the parent.
+	block, target, err := tpt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = append(block.Transactions, parent)
+	block.MinerPayouts[0].Value = block.MinerPayouts[0].Value.Add(fee)
+	block, _ = tpt.miner.SolveBlock(block, target)
+	err = tpt.cs.AcceptBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(child.ID()); !exists {
+		t.Fatal("orphan was not accepted after its parent was confirmed")
+	}
+	if len(tpt.tpool.orphans) != 0 {
+		t.Fatal("accepted orphan was not removed")
+	}
+}
+
+// TestOrphanLimits checks that the number of orphans is bounded and that
+// orphans expire.
+func TestOrphanLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	tpt.tpool.mu.Lock()
+	for i := 0; i < maxOrphanSets+10; i++ {
+		tpt.tpool.addOrphan([]types.Transaction{spendTxn(types.SiacoinOutputID{byte(i), byte(i >> 8)}, types.SiacoinPrecision, types.SiacoinPrecision.Div64(2), false)}, "")
+	}
+	numOrphans := len(tpt.tpool.orphans)
+	tpt.tpool.mu.Unlock()
+	if numOrphans != maxOrphanSets {
+		t.Fatalf("expected %v orphans, got %v", maxOrphanSets, numOrphans)
+	}
+
+	// The orphans should expire after maxOrphanAge blocks.
+	for i := types.BlockHeight(0); i <= maxOrphanAge; i++ {
+		_, err = tpt.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tpt.tpool.mu.Lock()
+	numOrphans, orphanSize := len(tpt.tpool.orphans), tpt.tpool.orphanSize
+	tpt.tpool.mu.Unlock()
+	if numOrphans != 0 || orphanSize != 0 {
+		t.Fatal("orphans did not expire:", numOrphans, orphanSize)
+	}
+}
@@ -0,0 +1,172 @@
+package transactionpool
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// spentOutputs returns the siacoin and siafund outputs that are spent by the
+// transaction sets in the pool, taken from the consensus diffs of the sets.
+func (tp *TransactionPool) spentOutputs() (map[types.SiacoinOutputID]types.SiacoinOutput, map[types.SiafundOutputID]types.SiafundOutput) {
+	siacoinOutputs := make(map[types.SiacoinOutputID]types.SiacoinOutput)
+	siafundOutputs := make(map[types.SiafundOutputID]types.SiafundOutput)
+	for _, cc := range tp.transactionSetDiffs {
+		for _, diff := range cc.SiacoinOutputDiffs {
+			if diff.Direction == modules.DiffRevert {
+				siacoinOutputs[diff.ID] = diff.SiacoinOutput
+			}
+		}
+		for _, diff := range cc.SiafundOutputDiffs {
+			if diff.Direction == modules.DiffRevert {
+				siafundOutputs[diff.ID] = diff.SiafundOutput
+			}
+		}
+	}
+	return siacoinOutputs, siafundOutputs
+}
+
+// UnconfirmedOutputs returns the outputs of an unlock hash that are created or
+// spent by the unconfirmed transactions in the pool. The values of the spent
+// outputs are taken from the consensus diffs of the pool, so they are known
+// for both confirmed and unconfirmed outputs.
+func (tp *TransactionPool) UnconfirmedOutputs(uh types.UnlockHash) modules.UnconfirmedOutputs {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	// Walk the sets in a deterministic order.
+	ids := make([]TransactionSetID, 0, len(tp.transactionSets))
+	for id := range tp.transactionSets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return string(ids[i][:]) < string(ids[j][:])
+	})
+
+	spentSiacoins, spentSiafunds := tp.spentOutputs()
+	spent := make(map[types.OutputID]struct{})
+	var uo modules.UnconfirmedOutputs
+	for _, id := range ids {
+		for _, txn := range tp.transactionSets[id] {
+			for _, sci := range txn.SiacoinInputs {
+				spent[types.OutputID(sci.ParentID)] = struct{}{}
+				if sci.UnlockConditions.UnlockHash() == uh {
+					uo.Outgoing = append(uo.Outgoing, modules.UnconfirmedOutput{
+						ID:            types.OutputID(sci.ParentID),
+						FundType:      types.SpecifierSiacoinOutput,
+						Value:         spentSiacoins[sci.ParentID].Value,
+						TransactionID: txn.ID(),
+					})
+				}
+			}
+			for _, sfi := range txn.SiafundInputs {
+				spent[types.OutputID(sfi.ParentID)] = struct{}{}
+				if sfi.UnlockConditions.UnlockHash() == uh {
+					uo.Outgoing = append(uo.Outgoing, modules.UnconfirmedOutput{
+						ID:            types.OutputID(sfi.ParentID),
+						FundType:      types.SpecifierSiafundOutput,
+						Value:         spentSiafunds[sfi.ParentID].Value,
+						TransactionID: txn.ID(),
+					})
+				}
+			}
+			for i, sco := range txn.SiacoinOutputs {
+				if sco.UnlockHash == uh {
+					uo.Incoming = append(uo.Incoming, modules.UnconfirmedOutput{
+						ID:            types.OutputID(txn.SiacoinOutputID(uint64(i))),
+						FundType:      types.SpecifierSiacoinOutput,
+						Value:         sco.Value,
+						TransactionID: txn.ID(),
+					})
+				}
+			}
+			for i, sfo := range txn.SiafundOutputs {
+				if sfo.UnlockHash == uh {
+					uo.Incoming = append(uo.Incoming, modules.UnconfirmedOutput{
+						ID:            types.OutputID(txn.SiafundOutputID(uint64(i))),
+						FundType:      types.SpecifierSiafundOutput,
+						Value:         sfo.Value,
+						TransactionID: txn.ID(),
+					})
+				}
+			}
+		}
+	}
+	for i := range uo.Incoming {
+		_, uo.Incoming[i].Spent = spent[uo.Incoming[i].ID]
+	}
+	return uo
+}
+
+// SpendableOutputs returns the outputs of an unlock hash that are created by
+// unconfirmed transactions and not spent by other unconfirmed transactions.
+// Each output comes with its dependency set, which is the transaction that
+// creates it along with its unconfirmed ancestors in the order in which they
+// need to be broadcast. The ancestors may belong to different sets in the
+// pool.
+func (tp *TransactionPool) SpendableOutputs(uh types.UnlockHash) []modules.SpendableOutput {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	candidates := tp.blockCandidates()
+	spent := make(map[types.OutputID]struct{})
+	for _, c := range candidates {
+		for _, sci := range c.txn.SiacoinInputs {
+			spent[types.OutputID(sci.ParentID)] = struct{}{}
+		}
+		for _, sfi := range c.txn.SiafundInputs {
+			spent[types.OutputID(sfi.ParentID)] = struct{}{}
+		}
+	}
+
+	var outputs []modules.SpendableOutput
+	for i, c := range candidates {
+		var created []modules.UnconfirmedOutput
+		for j, sco := range c.txn.SiacoinOutputs {
+			id := types.OutputID(c.txn.SiacoinOutputID(uint64(j)))
+			if _, exists := spent[id]; !exists && sco.UnlockHash == uh {
+				created = append(created, modules.UnconfirmedOutput{
+					ID:            id,
+					FundType:      types.SpecifierSiacoinOutput,
+					Value:         sco.Value,
+					TransactionID: c.txn.ID(),
+				})
+			}
+		}
+		for j, sfo := range c.txn.SiafundOutputs {
+			id := types.OutputID(c.txn.SiafundOutputID(uint64(j)))
+			if _, exists := spent[id]; !exists && sfo.UnlockHash == uh {
+				created = append(created, modules.UnconfirmedOutput{
+					ID:            id,
+					FundType:      types.SpecifierSiafundOutput,
+					Value:         sfo.Value,
+					TransactionID: c.txn.ID(),
+				})
+			}
+		}
+		if len(created) == 0 {
+			continue
+		}
+
+		// The candidates are ordered so that parents come first, so sorting
+		// the indices of the ancestors puts them in the order in which they
+		// need to be broadcast.
+		var indices []int
+		for j := range ancestors(candidates, i) {
+			indices = append(indices, j)
+		}
+		sort.Ints(indices)
+		dependencySet := make([]types.Transaction, len(indices))
+		for j, index := range indices {
+			dependencySet[j] = candidates[index].txn
+		}
+		for _, uo := range created {
+			outputs = append(outputs, modules.SpendableOutput{
+				UnconfirmedOutput: uo,
+				DependencySet:     dependencySet,
+			})
+		}
+	}
+	return outputs
+}
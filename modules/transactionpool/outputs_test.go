@@ -0,0 +1,142 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestUnconfirmedOutputs checks that the pool reports the outputs that
+// unconfirmed transactions send to and spend from an unlock hash.
+func TestUnconfirmedOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	uh := types.UnlockConditions{}.UnlockHash()
+	if uo := tpt.tpool.UnconfirmedOutputs(uh); len(uo.Incoming) != 0 || len(uo.Outgoing) != 0 {
+		t.Fatal("empty pool reported unconfirmed outputs:", uo)
+	}
+
+	// Send coins to the unlock hash.
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoins(value, uh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := txns[len(txns)-1]
+	var output types.SiacoinOutputID
+	for i, sco := range parent.SiacoinOutputs {
+		if sco.UnlockHash == uh {
+			output = parent.SiacoinOutputID(uint64(i))
+		}
+	}
+	uo := tpt.tpool.UnconfirmedOutputs(uh)
+	if len(uo.Incoming) != 1 || len(uo.Outgoing) != 0 {
+		t.Fatal("expected a single incoming output, got", uo)
+	}
+	if o := uo.Incoming[0]; o.ID != types.OutputID(output) || !o.Value.Equals(value) || o.TransactionID != parent.ID() || o.Spent {
+		t.Fatal("wrong incoming output:", o)
+	}
+
+	// Spend the unconfirmed output back to the unlock hash.
+	fee := types.SiacoinPrecision
+	child := spendTxn(output, value, fee, false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{child})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uo = tpt.tpool.UnconfirmedOutputs(uh)
+	if len(uo.Incoming) != 2 || len(uo.Outgoing) != 1 {
+		t.Fatal("expected two incoming outputs and one outgoing output, got", uo)
+	}
+	for _, o := range uo.Incoming {
+		if spent := o.ID == types.OutputID(output); o.Spent != spent {
+			t.Fatal("wrong spent flag of incoming output:", o)
+		}
+	}
+	if o := uo.Outgoing[0]; o.ID != types.OutputID(output) || !o.Value.Equals(value) || o.TransactionID != child.ID() {
+		t.Fatal("wrong outgoing output:", o)
+	}
+	incoming, outgoing, _, _ := uo.Balances()
+	if !incoming.Equals(value.Add(value.Sub(fee))) || !outgoing.Equals(value) {
+		t.Fatal("wrong balances:", incoming, outgoing)
+	}
+
+	// Once the transactions are confirmed, there are no unconfirmed outputs.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uo := tpt.tpool.UnconfirmedOutputs(uh); len(uo.Incoming) != 0 || len(uo.Outgoing) != 0 {
+		t.Fatal("confirmed outputs were reported as unconfirmed:", uo)
+	}
+}
+
+// TestSpendableOutputs checks that the pool reports the unspent outputs of
+// unconfirmed transactions along with their dependency sets, and that an
+// output can be spent by broadcasting the spending transaction together with
+// its dependency set.
+func TestSpendableOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	uh := types.UnlockConditions{}.UnlockHash()
+	if len(tpt.tpool.SpendableOutputs(uh)) != 0 {
+		t.Fatal("empty pool reported spendable outputs")
+	}
+
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoins(value, uh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := txns[len(txns)-1]
+	outputs := tpt.tpool.SpendableOutputs(uh)
+	if len(outputs) != 1 {
+		t.Fatal("expected a single spendable output, got", outputs)
+	}
+	so := outputs[0]
+	if so.TransactionID != parent.ID() || !so.Value.Equals(value) || so.FundType != types.SpecifierSiacoinOutput {
+		t.Fatal("wrong spendable output:", so)
+	}
+	if len(so.DependencySet) == 0 || so.DependencySet[len(so.DependencySet)-1].ID() != parent.ID() {
+		t.Fatal("dependency set does not end with the transaction that creates the output")
+	}
+
+	// Spend the output together with its dependency set.
+	fee := types.SiacoinPrecision
+	child := spendTxn(types.SiacoinOutputID(so.ID), value, fee, false)
+	err = tpt.tpool.AcceptTransactionSet(append(so.DependencySet, child))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs = tpt.tpool.SpendableOutputs(uh)
+	if len(outputs) != 1 || outputs[0].TransactionID != child.ID() || !outputs[0].Value.Equals(value.Sub(fee)) {
+		t.Fatal("expected only the output of the child to be spendable, got", outputs)
+	}
+	deps := outputs[0].DependencySet
+	if len(deps) != len(so.DependencySet)+1 || deps[len(deps)-1].ID() != child.ID() || deps[len(deps)-2].ID() != parent.ID() {
+		t.Fatal("wrong dependency set of the child output")
+	}
+
+	// Confirmed outputs are no longer reported.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.SpendableOutputs(uh)) != 0 {
+		t.Fatal("confirmed outputs were reported as spendable")
+	}
+}
@@ -130,6 +130,9 @@ func (tp *TransactionPool) initPersist() error {
 		bucketRecentConsensusChange,
 		bucketConfirmedTransactions,
 		bucketFeeMedian,
+		bucketLocalTransactions,
+		bucketSettings,
+		bucketUnconfirmedSets,
 	}
 	for _, bucket := range buckets {
 		_, err := tp.dbTx.CreateBucketIfNotExists(bucket)
@@ -176,9 +179,16 @@ func (tp *TransactionPool) initPersist() error {
 		tp.recentMedianFee = mp.RecentMedianFee
 	}
 
+	// Load the settings before subscribing, so that the transactions of the
+	// consensus changes are accepted under the configured pool size.
+	tp.settings, err = tp.getSettings(tp.dbTx)
+	if err != nil {
+		return build.ExtendErr("unable to load the tpool settings", err)
+	}
+
 	// Subscribe to the consensus set using the most recent consensus change.
 	err = tp.consensusSet.ConsensusSetSubscribe(tp, cc, tp.tg.StopChan())
-	if err == modules.ErrInvalidConsensusChangeID {
+	if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 		tp.log.Println("Invalid consensus change loaded; resetting. This can take a while.")
 		// Reset and rescan because the consensus set does not recognize the
 		// provided consensus change id.
@@ -204,6 +214,41 @@ func (tp *TransactionPool) initPersist() error {
 	return nil
 }
 
+// managedLoadUnconfirmedSets adds the transaction sets that were in the pool
+// when it was last shut down back to the pool. The sets are validated against
+// the current consensus set, and sets that have been confirmed or invalidated
+// in the meantime are dropped.
+func (tp *TransactionPool) managedLoadUnconfirmedSets() error {
+	tp.mu.Lock()
+	sets, err := tp.getUnconfirmedSets(tp.dbTx)
+	var local map[types.TransactionID]time.Time
+	if err == nil {
+		local, err = tp.getLocalTransactions(tp.dbTx)
+	}
+	tp.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var loaded int
+	for _, ts := range sets {
+		err := tp.managedAcceptTransactionSet(ts, "")
+		if err != nil && err != modules.ErrDuplicateTransactionSet {
+			tp.log.Debugln("Dropping persisted transaction set:", err)
+			continue
+		}
+		loaded++
+	}
+	tp.log.Printf("Loaded %v of %v persisted transaction sets", loaded, len(sets))
+
+	// The sets were added as if they had been submitted locally. Only the
+	// transactions that were local before the shutdown remain local.
+	tp.mu.Lock()
+	tp.localTransactions = local
+	tp.mu.Unlock()
+	return nil
+}
+
 // TransactionConfirmed returns true if the transaction has been seen on the
 // blockchain. Note, however, that the block containing the transaction may
 // later be invalidated by a reorg.
@@ -93,3 +93,60 @@ func TestRescan(t *testing.T) {
 		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
 	}
 }
+
+// TestUnconfirmedSetPersistence checks that the transaction sets in the pool
+// survive a restart, unless they are confirmed while the pool is offline.
+func TestUnconfirmedSetPersistence(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txnID := txns[len(txns)-1].ID()
+	restart := func() {
+		err := tpt.tpool.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tpt.tpool, err = New(tpt.cs, tpt.gateway, tpt.tpool.persistDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restart()
+	if _, _, exists := tpt.tpool.Transaction(txnID); !exists {
+		t.Fatal("transaction was not reloaded after a restart")
+	}
+
+	// Confirm the transaction while the pool is offline. The miner still
+	// knows the transaction from before the restart.
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, tpt.tpool.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(txnID); exists {
+		t.Fatal("confirmed transaction was reloaded")
+	}
+	if confirmed, err := tpt.tpool.TransactionConfirmed(txnID); err != nil {
+		t.Fatal(err)
+	} else if !confirmed {
+		t.Fatal("transaction was not confirmed")
+	}
+}
@@ -0,0 +1,180 @@
+package transactionpool
+
+// poolsize.go limits the size of the transaction pool. Once the pool is full,
+// a new transaction set is only accepted if the pool can make room for it by
+// evicting sets that pay a lower fee rate, so the fee rate of the cheapest set
+// in a full pool is the minimum fee rate that the pool accepts.
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errSmallPoolSize      = errors.New("maximum pool size cannot be smaller than the transaction set size limit")
+	errZeroTransactionAge = errors.New("maximum transaction age must be at least one block")
+)
+
+// A rankedSet is a transaction set in the pool along with its size and fee
+// rate.
+type rankedSet struct {
+	id   TransactionSetID
+	rate types.Currency
+	size uint64
+}
+
+// setsByFeeRate returns the transaction sets in the pool that are not in
+// exclude, sorted by increasing fee rate.
+func (tp *TransactionPool) setsByFeeRate(exclude map[TransactionSetID]struct{}) []rankedSet {
+	sets := make([]rankedSet, 0, len(tp.transactionSets))
+	for id, ts := range tp.transactionSets {
+		if _, ok := exclude[id]; ok {
+			continue
+		}
+		size := uint64(len(encoding.Marshal(ts)))
+		sets = append(sets, rankedSet{id: id, rate: feeRate(ts, size), size: size})
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].rate.Cmp(sets[j].rate) < 0
+	})
+	return sets
+}
+
+// cheapestSets returns the transaction sets with the lowest fee rates, sorted
+// by increasing fee rate, whose combined size is at least needed bytes. The
+// sets in exclude, priority sets and local sets are never returned, so the
+// combined size of the returned sets may be less than needed.
+func (tp *TransactionPool) cheapestSets(needed uint64, exclude map[TransactionSetID]struct{}) []rankedSet {
+	if needed == 0 {
+		return nil
+	}
+	var sets []rankedSet
+	var freed uint64
+	for _, s := range tp.setsByFeeRate(exclude) {
+		if ts := tp.transactionSets[s.id]; isPrioritySet(ts) || tp.isLocalSet(ts) {
+			continue
+		}
+		sets = append(sets, s)
+		freed += s.size
+		if freed >= needed {
+			break
+		}
+	}
+	return sets
+}
+
+// minimumFeeRate returns the fee rate that a transaction set needs to exceed
+// to be accepted by the pool. It is zero unless the pool is too full to fit
+// another set of the maximum size.
+func (tp *TransactionPool) minimumFeeRate() types.Currency {
+	if uint64(tp.transactionListSize)+modules.TransactionSetSizeLimit <= tp.settings.MaxPoolSize {
+		return types.ZeroCurrency
+	}
+	sets := tp.cheapestSets(1, nil)
+	if len(sets) == 0 {
+		return types.ZeroCurrency
+	}
+	return sets[0].rate
+}
+
+// evictionsForSet returns the transaction sets that need to be evicted to make
+// room for a new set. The sets in exclude are about to be replaced by the new
+// set, so they are neither counted towards the size of the pool nor evicted.
+// errLowMinerFees is returned if the new set does not pay a higher fee rate
+// than every set that would need to be evicted, and errFullTransactionPool if
+// the pool cannot make room because it is filled with priority or local sets.
+func (tp *TransactionPool) evictionsForSet(ts []types.Transaction, exclude map[TransactionSetID]struct{}) ([]rankedSet, error) {
+	size := uint64(len(encoding.Marshal(ts)))
+	if size > tp.settings.MaxPoolSize {
+		return nil, errFullTransactionPool
+	}
+	poolSize := uint64(tp.transactionListSize)
+	for id := range exclude {
+		poolSize -= uint64(len(encoding.Marshal(tp.transactionSets[id])))
+	}
+	if poolSize+size <= tp.settings.MaxPoolSize {
+		return nil, nil
+	}
+
+	needed := poolSize + size - tp.settings.MaxPoolSize
+	evictions := tp.cheapestSets(needed, exclude)
+	var freed uint64
+	for _, s := range evictions {
+		freed += s.size
+	}
+	if freed < needed {
+		return nil, errFullTransactionPool
+	}
+	if len(evictions) > 0 && evictions[len(evictions)-1].rate.Cmp(feeRate(ts, size)) >= 0 {
+		return nil, errLowMinerFees
+	}
+	return evictions, nil
+}
+
+// evictTransactionSets removes transaction sets from the pool to make room
+// for sets that pay higher fees.
+func (tp *TransactionPool) evictTransactionSets(evictions []rankedSet) {
+	for _, s := range evictions {
+		tp.removeTransactionSet(s.id, modules.TransactionRemovalEvicted)
+		tp.log.Debugf("evicted transaction set %v with a fee rate of %v to make room in the pool\n", s.id, s.rate)
+	}
+}
+
+// removeTransactionSet removes a transaction set and the objects that it
+// created or spent from the pool, recording the reason for subscribers.
+func (tp *TransactionPool) removeTransactionSet(id TransactionSetID, reason modules.TransactionRemovalReason) {
+	ts := tp.transactionSets[id]
+	for _, oid := range relatedObjectIDs(ts) {
+		if tp.knownObjects[oid] == id {
+			delete(tp.knownObjects, oid)
+		}
+	}
+	for _, txn := range ts {
+		delete(tp.transactionHeights, txn.ID())
+	}
+	delete(tp.transactionSets, id)
+	delete(tp.transactionSetDiffs, id)
+	tp.transactionListSize -= len(encoding.Marshal(ts))
+	tp.removalReasons[id] = reason
+}
+
+// SetSettings changes the settings of the transaction pool. Lowering the
+// maximum pool size evicts the transaction sets with the lowest fee rates
+// until the pool fits, or until only priority and local sets are left.
+func (tp *TransactionPool) SetSettings(settings modules.TransactionPoolSettings) error {
+	if err := tp.tg.Add(); err != nil {
+		return err
+	}
+	defer tp.tg.Done()
+	if settings.MaxPoolSize < modules.TransactionSetSizeLimit {
+		return errSmallPoolSize
+	}
+	if settings.MaxTransactionAge == 0 {
+		return errZeroTransactionAge
+	}
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	err := tp.putSettings(tp.dbTx, settings)
+	if err != nil {
+		return err
+	}
+	tp.settings = settings
+
+	if poolSize := uint64(tp.transactionListSize); poolSize > settings.MaxPoolSize {
+		tp.evictTransactionSets(tp.cheapestSets(poolSize-settings.MaxPoolSize, nil))
+		tp.updateSubscribersTransactions()
+	}
+	return nil
+}
+
+// Settings returns the settings of the transaction pool.
+func (tp *TransactionPool) Settings() modules.TransactionPoolSettings {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.settings
+}
@@ -0,0 +1,161 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestPoolSizeLimit checks that a full transaction pool rejects transaction
+// sets that pay low fees, and evicts the sets with the lowest fee rates to
+// make room for sets that pay higher fees.
+func TestPoolSizeLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	if tpt.tpool.Settings().MaxPoolSize != defaultMaxPoolSize {
+		t.Fatal("pool does not use the default size")
+	}
+	settings := tpt.tpool.Settings()
+	settings.MaxPoolSize = modules.TransactionSetSizeLimit - 1
+	err = tpt.tpool.SetSettings(settings)
+	if err != errSmallPoolSize {
+		t.Fatal("expected errSmallPoolSize, got", err)
+	}
+	maxSize := uint64(300e3)
+	settings.MaxPoolSize = maxSize
+	err = tpt.tpool.SetSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolSize := func() uint64 {
+		tpt.tpool.mu.Lock()
+		defer tpt.tpool.mu.Unlock()
+		return uint64(tpt.tpool.transactionListSize)
+	}
+
+	// Create an output that can pay the fees of a transaction set later on.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fill the pool with transactions from a peer that do not pay any fees.
+	// Local transactions would never be evicted.
+	var filled int
+	for {
+		arbData := make([]byte, 10e3)
+		copy(arbData, modules.PrefixNonSia[:])
+		fastrand.Read(arbData[100:116])
+		err := tpt.tpool.managedAcceptTransactionSet([]types.Transaction{{ArbitraryData: [][]byte{arbData}}}, modules.NetAddress("foo.com:1234"))
+		if rejectedWith(err, errLowMinerFees) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		filled++
+	}
+	if filled < 25 {
+		t.Fatal("pool was full after", filled, "transactions")
+	} else if poolSize() > maxSize {
+		t.Fatal("pool exceeds its maximum size:", poolSize())
+	}
+
+	// A transaction set that pays fees should replace the transactions that
+	// do not.
+	edge := types.TransactionGraphEdge{
+		Dest:   1,
+		Fee:    types.SiacoinPrecision,
+		Source: 0,
+		Value:  types.SiacoinPrecision.Mul64(99),
+	}
+	graph, err := types.TransactionGraph(txns[len(txns)-1].SiacoinOutputID(0), []types.TransactionGraphEdge{edge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet(graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poolSize() > maxSize {
+		t.Fatal("pool exceeds its maximum size:", poolSize())
+	}
+	if _, _, exists := tpt.tpool.Transaction(graph[0].ID()); !exists {
+		t.Fatal("transaction set with fees was not added to the pool")
+	}
+
+	// Shrinking the pool should evict the transactions without fees first.
+	maxSize = modules.TransactionSetSizeLimit
+	settings.MaxPoolSize = maxSize
+	err = tpt.tpool.SetSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poolSize() > maxSize {
+		t.Fatal("pool exceeds its maximum size:", poolSize())
+	}
+	if _, _, exists := tpt.tpool.Transaction(graph[0].ID()); !exists {
+		t.Fatal("transaction set with fees was evicted")
+	}
+	if len(tpt.tpool.TransactionList()) >= filled {
+		t.Fatal("shrinking the pool did not evict any transactions")
+	}
+}
+
+// TestPoolSettingsPersistence checks that the settings of the transaction pool
+// survive a restart.
+func TestPoolSettingsPersistence(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	settings := tpt.tpool.Settings()
+	if settings.MaxTransactionAge != defaultMaxTransactionAge {
+		t.Fatal("pool does not use the default transaction age")
+	}
+	settings.MaxTransactionAge = 0
+	if err := tpt.tpool.SetSettings(settings); err != errZeroTransactionAge {
+		t.Fatal("expected errZeroTransactionAge, got", err)
+	}
+	settings.MaxPoolSize = 5e6
+	settings.MaxTransactionAge = 144
+	settings.Policy.CheckDustOutputs = true
+	settings.Policy.DustThreshold = types.SiacoinPrecision
+	err = tpt.tpool.SetSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, tpt.tpool.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	persisted := tpt.tpool.Settings()
+	if persisted.MaxPoolSize != settings.MaxPoolSize ||
+		persisted.MaxTransactionAge != settings.MaxTransactionAge ||
+		persisted.Policy.CheckDustOutputs != settings.Policy.CheckDustOutputs ||
+		persisted.Policy.DustThreshold.Cmp(settings.Policy.DustThreshold) != 0 ||
+		persisted.Policy.CheckUnlockConditions != settings.Policy.CheckUnlockConditions {
+		t.Fatal("settings were not persisted:", persisted)
+	}
+}
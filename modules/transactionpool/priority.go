@@ -0,0 +1,30 @@
+package transactionpool
+
+// priority.go classifies the transactions that are time-critical for hosts.
+// A host that fails to get a storage proof confirmed within the proof window
+// of a file contract loses its collateral, and a revision has to be confirmed
+// before the proof window starts to count. Transaction sets that contain
+// either are never evicted from the pool to make room for sets that pay a
+// higher fee rate, and miners include them in blocks before other
+// transactions.
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// isPriorityTransaction returns true if a transaction contains file contract
+// revisions or storage proofs.
+func isPriorityTransaction(txn types.Transaction) bool {
+	return len(txn.FileContractRevisions) > 0 || len(txn.StorageProofs) > 0
+}
+
+// isPrioritySet returns true if a transaction set contains a priority
+// transaction.
+func isPrioritySet(ts []types.Transaction) bool {
+	for _, txn := range ts {
+		if isPriorityTransaction(txn) {
+			return true
+		}
+	}
+	return false
+}
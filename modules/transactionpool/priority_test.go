@@ -0,0 +1,63 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPrioritySets checks that sets with file contract revisions or storage
+// proofs are never evicted, and are put into blocks before sets that pay a
+// higher fee rate.
+func TestPrioritySets(t *testing.T) {
+	tp := &TransactionPool{
+		transactionSets: make(map[TransactionSetID][]types.Transaction),
+		settings:        modules.TransactionPoolSettings{MaxPoolSize: modules.TransactionSetSizeLimit},
+	}
+	addSet := func(txn types.Transaction, fee uint64) TransactionSetID {
+		txn.MinerFees = []types.Currency{types.NewCurrency64(fee)}
+		ts := []types.Transaction{txn}
+		id := TransactionSetID(crypto.HashObject(ts))
+		tp.transactionSets[id] = ts
+		tp.transactionListSize += len(encoding.Marshal(ts))
+		return id
+	}
+	revision := types.Transaction{FileContractRevisions: []types.FileContractRevision{{ParentID: types.FileContractID{1}}}}
+	proof := types.Transaction{StorageProofs: []types.StorageProof{{ParentID: types.FileContractID{2}}}}
+	other := types.Transaction{ArbitraryData: [][]byte{[]byte("other")}}
+	revisionID := addSet(revision, 1)
+	proofID := addSet(proof, 2)
+	otherID := addSet(other, 1e6)
+
+	// Only the non-priority set can be evicted.
+	evictions := tp.cheapestSets(uint64(tp.transactionListSize), nil)
+	if len(evictions) != 1 || evictions[0].id != otherID {
+		t.Fatal("expected only the non-priority set to be evictable, got", evictions)
+	}
+
+	// A set that needs more room than the non-priority sets take up cannot be
+	// accepted.
+	large := []types.Transaction{{
+		ArbitraryData: [][]byte{{}},
+		MinerFees:     []types.Currency{types.SiacoinPrecision},
+	}}
+	large[0].ArbitraryData[0] = make([]byte, modules.TransactionSetSizeLimit-len(encoding.Marshal(large)))
+	if _, err := tp.evictionsForSet(large, nil); err != errFullTransactionPool {
+		t.Fatal("expected a full pool, got", err)
+	}
+
+	// The priority transactions come first in a block, even though they pay
+	// a lower fee rate.
+	blockTxns := tp.BlockTransactions(types.BlockSizeLimit)
+	if len(blockTxns) != 3 || blockTxns[2].ID() != tp.transactionSets[otherID][0].ID() {
+		t.Fatal("priority transactions were not put into the block first")
+	}
+	for _, txn := range blockTxns[:2] {
+		if txn.ID() != tp.transactionSets[revisionID][0].ID() && txn.ID() != tp.transactionSets[proofID][0].ID() {
+			t.Fatal("priority transactions were not put into the block first")
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package transactionpool
+
+// rebroadcast.go periodically relays the transaction sets that contain local
+// transactions, which are the transactions that were submitted to this node
+// rather than received from peers. Peers may drop a transaction set that they
+// received earlier, for example because their pool was full or because they
+// restarted. Relaying the sets again gives the transactions of the wallet and
+// the other local modules a chance to be confirmed until they expire from this
+// node's pool.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// localTransactionSets returns the transaction sets in the pool that contain
+// local transactions. Local transactions that are no longer in the pool are
+// forgotten.
+func (tp *TransactionPool) localTransactionSets() [][]types.Transaction {
+	inPool := make(map[types.TransactionID]struct{})
+	var sets [][]types.Transaction
+	for _, ts := range tp.transactionSets {
+		local := false
+		for _, txn := range ts {
+			id := txn.ID()
+			inPool[id] = struct{}{}
+			_, isLocal := tp.localTransactions[id]
+			local = local || isLocal
+		}
+		if local {
+			sets = append(sets, ts)
+		}
+	}
+	for id := range tp.localTransactions {
+		if _, exists := inPool[id]; !exists {
+			delete(tp.localTransactions, id)
+		}
+	}
+	return sets
+}
+
+// threadedRebroadcast relays the transaction sets that contain local
+// transactions to the peers of the pool every rebroadcastInterval.
+func (tp *TransactionPool) threadedRebroadcast() {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+	for {
+		select {
+		case <-tp.tg.StopChan():
+			return
+		case <-time.After(rebroadcastInterval):
+		}
+
+		tp.mu.Lock()
+		sets := tp.localTransactionSets()
+		tp.mu.Unlock()
+		for _, ts := range sets {
+			tp.managedRelayTransactionSet(ts, "")
+		}
+		if len(sets) > 0 {
+			tp.log.Debugf("rebroadcast %v transaction sets with local transactions\n", len(sets))
+		}
+	}
+}
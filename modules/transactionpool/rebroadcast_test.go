@@ -0,0 +1,118 @@
+package transactionpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestLocalTransactionSets checks that only the sets with transactions that
+// were submitted locally are rebroadcast, and that local transactions are
+// forgotten once they leave the pool.
+func TestLocalTransactionSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := spendTxn(output, value, fee, false)
+	err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{remote}, modules.NetAddress("foo.com:1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpt.tpool.mu.Lock()
+	sets := tpt.tpool.localTransactionSets()
+	tpt.tpool.mu.Unlock()
+	if len(sets) != 1 || sets[0][len(sets[0])-1].ID() != local[len(local)-1].ID() {
+		t.Fatal("expected only the local set to be rebroadcast, got", sets)
+	}
+
+	tpt.tpool.PurgeTransactionPool()
+	tpt.tpool.mu.Lock()
+	sets = tpt.tpool.localTransactionSets()
+	numLocal := len(tpt.tpool.localTransactions)
+	tpt.tpool.mu.Unlock()
+	if len(sets) != 0 || numLocal != 0 {
+		t.Fatal("local transactions were not forgotten after leaving the pool")
+	}
+}
+
+// TestTransactionRebroadcast checks that local transactions reach peers that
+// dropped them.
+func TestTransactionRebroadcast(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	tpt2, err := blankTpoolTester(t.Name() + "-tpt2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt2.Close()
+
+	err = tpt2.gateway.Connect(tpt.gateway.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(600, 100*time.Millisecond, func() error {
+		if tpt.cs.CurrentBlock().ID() != tpt2.cs.CurrentBlock().ID() {
+			return errors.New("testers are not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed := func() error {
+		if _, _, exists := tpt2.tpool.Transaction(txns[len(txns)-1].ID()); !exists {
+			return errors.New("transaction is not in the pool of the peer")
+		}
+		return nil
+	}
+	err = build.Retry(50, 100*time.Millisecond, relayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the peer forget the transaction, as if it had restarted. The
+	// transaction should be rebroadcast.
+	tpt2.tpool.mu.Lock()
+	tpt2.tpool.purge()
+	tpt2.tpool.seenSets = make(map[TransactionSetID]time.Time)
+	tpt2.tpool.mu.Unlock()
+	if relayed() == nil {
+		t.Fatal("peer did not drop the transaction")
+	}
+	err = build.Retry(50, 100*time.Millisecond, relayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+package transactionpool
+
+// rejection.go turns the errors that cause a transaction set to be rejected
+// into modules.TransactionRejection errors, which tell the caller why the set
+// was rejected and which transaction is at fault, so that wallets and the API
+// can tell users how to fix the set.
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// rejectionReasons maps the errors of the transaction pool to the reasons
+	// that they are reported with.
+	rejectionReasons = map[error]modules.TransactionRejectionReason{
+		errEmptySet:            modules.RejectionEmptySet,
+		errFullTransactionPool: modules.RejectionPoolFull,
+		errLowMinerFees:        modules.RejectionInsufficientFee,
+		errLowReplacementFees:  modules.RejectionInsufficientFee,
+		errSourceRateLimited:   modules.RejectionRateLimited,
+		errTooManyReplacements: modules.RejectionTooManyReplacements,
+
+		modules.ErrLargeTransactionSet: modules.RejectionTooLarge,
+		types.ErrTransactionTooLarge:   modules.RejectionTooLarge,
+
+		modules.ErrMissingSiacoinOutput: modules.RejectionMissingInputs,
+		types.ErrDoubleSpend:            modules.RejectionDoubleSpend,
+
+		types.ErrEntropyKey:                modules.RejectionInvalidSignature,
+		types.ErrFrivolousSignature:        modules.RejectionInvalidSignature,
+		types.ErrInvalidPubKeyIndex:        modules.RejectionInvalidSignature,
+		types.ErrMissingSignatures:         modules.RejectionInvalidSignature,
+		types.ErrPrematureSignature:        modules.RejectionInvalidSignature,
+		types.ErrPublicKeyOveruse:          modules.RejectionInvalidSignature,
+		types.ErrSortedUniqueViolation:     modules.RejectionInvalidSignature,
+		types.ErrWholeTransactionViolation: modules.RejectionInvalidSignature,
+	}
+)
+
+// rejectionReason returns the reason that err is reported with. Errors that
+// are not known to the pool are reported as consensus conflicts.
+func rejectionReason(err error) modules.TransactionRejectionReason {
+	if reason, exists := rejectionReasons[err]; exists {
+		return reason
+	}
+	return modules.RejectionConsensusConflict
+}
+
+// rejectTransactionSet wraps an error of the transaction pool in a
+// modules.TransactionRejection. Rejections, duplicate transaction sets, and
+// errors that do not reject the set itself are returned unchanged.
+func rejectTransactionSet(err error) error {
+	if err == nil || err == modules.ErrDuplicateTransactionSet {
+		return err
+	}
+	if _, ok := err.(modules.TransactionRejection); ok {
+		return err
+	}
+	reason, exists := rejectionReasons[err]
+	if !exists {
+		return err
+	}
+	return modules.TransactionRejection{
+		Reason: reason,
+		Err:    err,
+	}
+}
+
+// standardRejection returns the rejection of a transaction that violates the
+// IsStandard rules or the policy of the pool.
+func standardRejection(txn types.Transaction, err error) modules.TransactionRejection {
+	reason := modules.RejectionNonStandard
+	if err == modules.ErrLargeTransaction {
+		reason = modules.RejectionTooLarge
+	}
+	return modules.TransactionRejection{
+		Reason:        reason,
+		Err:           err,
+		TransactionID: txn.ID(),
+	}
+}
+
+// consensusRejection returns the rejection of a transaction set that the
+// consensus set considers invalid. The transaction that caused the rejection
+// is identified if it is invalid on its own.
+func (tp *TransactionPool) consensusRejection(ts []types.Transaction, context string, err error) modules.TransactionRejection {
+	rejection := modules.TransactionRejection{
+		Reason: rejectionReason(err),
+		Err:    modules.NewConsensusConflict(context + ": " + err.Error()),
+	}
+	for _, txn := range ts {
+		if txn.StandaloneValid(tp.blockHeight) == err {
+			rejection.TransactionID = txn.ID()
+			break
+		}
+	}
+	return rejection
+}
@@ -0,0 +1,95 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// rejectedWith returns true if err is a rejection caused by cause.
+func rejectedWith(err, cause error) bool {
+	tr, ok := err.(modules.TransactionRejection)
+	return ok && tr.Err == cause
+}
+
+// TestTransactionRejections checks that rejected transaction sets are
+// reported with the right reasons and transactions.
+func TestTransactionRejections(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signature that points to a nonexistent public key.
+	badSig := spendTxn(output, value, fee, false)
+	badSig.TransactionSignatures = []types.TransactionSignature{{
+		ParentID:       crypto.Hash(output),
+		PublicKeyIndex: 5,
+		CoveredFields:  types.CoveredFields{WholeTransaction: true},
+	}}
+	// A transaction that spends an output that does not exist.
+	orphan := spendTxn(types.SiacoinOutputID{1}, value, fee, false)
+	// A transaction with arbitrary data that is not prefixed.
+	nonStandard := types.Transaction{ArbitraryData: [][]byte{[]byte("foo")}}
+
+	tests := []struct {
+		txns   []types.Transaction
+		reason modules.TransactionRejectionReason
+		txnID  types.TransactionID
+	}{
+		{nil, modules.RejectionEmptySet, types.TransactionID{}},
+		{[]types.Transaction{badSig}, modules.RejectionInvalidSignature, badSig.ID()},
+		{[]types.Transaction{orphan}, modules.RejectionMissingInputs, types.TransactionID{}},
+		{[]types.Transaction{nonStandard}, modules.RejectionNonStandard, nonStandard.ID()},
+	}
+	for i, test := range tests {
+		err := tpt.tpool.AcceptTransactionSet(test.txns)
+		tr, ok := err.(modules.TransactionRejection)
+		if !ok {
+			t.Errorf("test %v: expected a rejection, got %v", i, err)
+			continue
+		}
+		if tr.Reason != test.reason || tr.TransactionID != test.txnID {
+			t.Errorf("test %v: expected %v for %v, got %v for %v", i, test.reason, test.txnID, tr.Reason, tr.TransactionID)
+		}
+	}
+
+	// A double spend of a transaction that cannot be replaced should identify
+	// both transactions.
+	original := spendTxn(output, value, fee, false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{original})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doubleSpend := spendTxn(output, value, fee.Mul64(2), false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{doubleSpend})
+	tr, ok := err.(modules.TransactionRejection)
+	if !ok || tr.Reason != modules.RejectionDoubleSpend {
+		t.Fatal("expected a double spend, got", err)
+	}
+	if tr.TransactionID != doubleSpend.ID() || tr.ConflictingTransactionID != original.ID() {
+		t.Fatal("double spend does not identify the conflicting transactions")
+	}
+	if !modules.IsConsensusConflict(err) {
+		t.Fatal("double spend is not a consensus conflict")
+	}
+
+	// Duplicates are not rejections.
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{original})
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Fatal("expected a duplicate transaction set, got", err)
+	}
+}
@@ -0,0 +1,221 @@
+package transactionpool
+
+// relay.go implements inventory-based relay of transaction sets. Instead of
+// sending every accepted transaction set to every peer, the transaction pool
+// announces the IDs of new sets to the peers that advertise the TxInventory
+// capability, and only sends the sets that a peer asks for. A peer asks for
+// the sets that are not in its pool and that it has not seen recently, so a
+// set crosses each connection at most once in the common case. Peers without
+// the capability still receive full sets through the RelayTransactionSet RPC.
+//
+// The IDs of the sets that the pool has seen, whether they were accepted or
+// not, are kept in a rolling cache, so that rejected and confirmed sets are
+// not requested again when other peers announce them.
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// txInventoryCapability is the gateway capability that is advertised by
+	// transaction pools that serve the TxInventory RPC.
+	txInventoryCapability = "TxInventory"
+
+	// maxInventorySize is the maximum number of transaction set IDs that can
+	// be announced in a single TxInventory RPC.
+	maxInventorySize = 1000
+)
+
+var (
+	errInventoryTooLarge = errors.New("inventory announcement contains too many transaction sets")
+	errUnannouncedSet    = errors.New("peer requested a transaction set that was not announced")
+	errWrongSet          = errors.New("peer sent a transaction set that was not requested")
+
+	// seenSetExpiry is the amount of time that the ID of a transaction set is
+	// remembered after it was last seen.
+	seenSetExpiry = build.Select(build.Var{
+		Standard: 1 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  1 * time.Minute,
+	}).(time.Duration)
+
+	// txInventoryTimeout is the timeout for the TxInventory RPC.
+	txInventoryTimeout = build.Select(build.Var{
+		Standard: 3 * time.Minute,
+		Dev:      20 * time.Second,
+		Testing:  3 * time.Second,
+	}).(time.Duration)
+)
+
+// markSeen records a transaction set as seen, and returns false if it had
+// already been seen. Expired entries are pruned at most once per expiry
+// period.
+func (tp *TransactionPool) markSeen(id TransactionSetID) bool {
+	expiry, seen := tp.seenSets[id]
+	seen = seen && time.Now().Before(expiry)
+	tp.seenSets[id] = time.Now().Add(seenSetExpiry)
+	if time.Since(tp.lastSeenPrune) > seenSetExpiry {
+		for id, expiry := range tp.seenSets {
+			if time.Now().After(expiry) {
+				delete(tp.seenSets, id)
+			}
+		}
+		tp.lastSeenPrune = time.Now()
+	}
+	return !seen
+}
+
+// managedRelayTransactionSet sends a transaction set to every peer except the
+// one it came from. Peers that support the TxInventory RPC only receive the
+// ID of the set, and request the set if they need it. The time taken until
+// every peer received the set is recorded in the background.
+func (tp *TransactionPool) managedRelayTransactionSet(ts []types.Transaction, source modules.NetAddress) {
+	id := TransactionSetID(crypto.HashObject(ts))
+	tp.mu.Lock()
+	tp.markSeen(id)
+	tp.mu.Unlock()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var fullPeers []modules.Peer
+	var numPeers int
+	for _, p := range tp.gateway.Peers() {
+		if p.NetAddress == source {
+			continue
+		}
+		numPeers++
+		if !p.HasCapability(txInventoryCapability) {
+			fullPeers = append(fullPeers, p)
+			continue
+		}
+		wg.Add(1)
+		go func(addr modules.NetAddress) {
+			defer wg.Done()
+			err := tp.gateway.RPC(addr, "TxInventory", announceTransactionSets(map[TransactionSetID][]types.Transaction{id: ts}))
+			if err != nil {
+				tp.log.Debugf("WARN: failed to announce transaction set to %v: %v", addr, err)
+			}
+		}(p.NetAddress)
+	}
+	tp.gateway.Broadcast("RelayTransactionSet", ts, fullPeers)
+	go func() {
+		wg.Wait()
+		m := setMetric(modules.TransactionPoolMetricRelay, ts, "")
+		m.Count = numPeers
+		m.Duration = time.Since(start)
+		tp.mu.RLock()
+		tp.recordMetric(m)
+		tp.mu.RUnlock()
+	}()
+}
+
+// announceTransactionSets returns the calling end of the TxInventory RPC. It
+// announces the IDs of the given transaction sets, and sends the sets that
+// the peer requests.
+func announceTransactionSets(sets map[TransactionSetID][]types.Transaction) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		if err := conn.SetDeadline(time.Now().Add(txInventoryTimeout)); err != nil {
+			return err
+		}
+		ids := make([]TransactionSetID, 0, len(sets))
+		for id := range sets {
+			ids = append(ids, id)
+		}
+		if err := encoding.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		var wanted []TransactionSetID
+		if err := encoding.ReadObject(conn, &wanted, 8+uint64(len(ids))*crypto.HashSize); err != nil {
+			return err
+		}
+		for _, id := range wanted {
+			ts, ok := sets[id]
+			if !ok {
+				return errUnannouncedSet
+			}
+			if err := encoding.WriteObject(conn, ts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// rpcTransactionInventory is the receiving end of the TxInventory RPC. It
+// requests the announced transaction sets that the pool has not seen, and
+// accepts them.
+func (tp *TransactionPool) rpcTransactionInventory(conn modules.PeerConn) error {
+	if err := tp.tg.Add(); err != nil {
+		return err
+	}
+	defer tp.tg.Done()
+	err := conn.SetDeadline(time.Now().Add(txInventoryTimeout))
+	if err != nil {
+		return err
+	}
+	// Automatically close the channel when tg.Stop() is called.
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-tp.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+
+	var ids []TransactionSetID
+	err = encoding.ReadObject(conn, &ids, 8+maxInventorySize*crypto.HashSize)
+	if err != nil {
+		return err
+	} else if len(ids) > maxInventorySize {
+		return errInventoryTooLarge
+	}
+
+	// Request the sets that are unknown. They are marked as seen right away,
+	// so that they are not requested from other peers at the same time, and
+	// forgotten again if the peer fails to send them.
+	var wanted []TransactionSetID
+	tp.mu.Lock()
+	for _, id := range ids {
+		if _, exists := tp.transactionSets[id]; !exists && tp.markSeen(id) {
+			wanted = append(wanted, id)
+		}
+	}
+	tp.mu.Unlock()
+	var received int
+	defer func() {
+		tp.mu.Lock()
+		for _, id := range wanted[received:] {
+			delete(tp.seenSets, id)
+		}
+		tp.mu.Unlock()
+	}()
+	if err := encoding.WriteObject(conn, wanted); err != nil {
+		return err
+	}
+
+	for _, id := range wanted {
+		var ts []types.Transaction
+		if err := encoding.ReadObject(conn, &ts, modules.TransactionSetSizeLimit); err != nil {
+			return err
+		} else if TransactionSetID(crypto.HashObject(ts)) != id {
+			tp.gateway.PenalizePeer(conn.RPCAddr(), modules.PenaltyMalformedRPC)
+			return errWrongSet
+		}
+		received++
+		err := tp.managedAcceptTransactionSet(ts, conn.RPCAddr())
+		if err != nil && err != modules.ErrDuplicateTransactionSet {
+			tp.log.Debugf("INFO: transaction set from %v was rejected: %v", conn.RPCAddr(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package transactionpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMarkSeen checks that transaction sets are only reported as unseen once
+// until they expire.
+func TestMarkSeen(t *testing.T) {
+	tp := &TransactionPool{seenSets: make(map[TransactionSetID]time.Time)}
+	id := TransactionSetID{1}
+	if !tp.markSeen(id) {
+		t.Fatal("new transaction set was reported as seen")
+	} else if tp.markSeen(id) {
+		t.Fatal("transaction set was reported as unseen twice")
+	}
+	tp.seenSets[id] = time.Now().Add(-time.Second)
+	if !tp.markSeen(id) {
+		t.Fatal("expired transaction set was reported as seen")
+	}
+}
+
+// TestTransactionInventory checks that transaction sets are relayed through
+// the TxInventory RPC, and that only unknown sets are requested.
+func TestTransactionInventory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	tpt2, err := blankTpoolTester(t.Name() + "-tpt2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt2.Close()
+
+	err = tpt2.gateway.Connect(tpt.gateway.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(600, 100*time.Millisecond, func() error {
+		if tpt.cs.CurrentBlock().ID() != tpt2.cs.CurrentBlock().ID() {
+			return errors.New("testers are not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tpt.gateway.PeerCapable(tpt2.gateway.Address(), txInventoryCapability) {
+		t.Fatal("peer does not advertise the TxInventory capability")
+	}
+
+	// A new transaction set should reach the other pool.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if _, _, exists := tpt2.tpool.Transaction(txns[len(txns)-1].ID()); !exists {
+			return errors.New("transaction was not relayed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Announcing the set again, together with an unknown set, should only
+	// cause the unknown set to be requested.
+	known := TransactionSetID(crypto.HashObject(txns))
+	unknown := TransactionSetID{1}
+	var wanted []TransactionSetID
+	err = tpt.gateway.RPC(tpt2.gateway.Address(), "TxInventory", func(conn modules.PeerConn) error {
+		if err := encoding.WriteObject(conn, []TransactionSetID{known, unknown}); err != nil {
+			return err
+		}
+		return encoding.ReadObject(conn, &wanted, 8+2*crypto.HashSize)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wanted) != 1 || wanted[0] != unknown {
+		t.Fatal("expected only the unknown set to be requested, got", wanted)
+	}
+
+	// The unknown set was not sent, so it should be requested again.
+	err = tpt.gateway.RPC(tpt2.gateway.Address(), "TxInventory", func(conn modules.PeerConn) error {
+		if err := encoding.WriteObject(conn, []TransactionSetID{unknown}); err != nil {
+			return err
+		}
+		return encoding.ReadObject(conn, &wanted, 8+crypto.HashSize)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wanted) != 1 || wanted[0] != unknown {
+		t.Fatal("expected the unknown set to be requested again, got", wanted)
+	}
+}
@@ -0,0 +1,233 @@
+package transactionpool
+
+// replace.go lets a transaction set replace the unconfirmed transaction sets
+// that it double spends, so that stuck transactions can be bumped by paying a
+// higher fee. A set can only be replaced if one of its transactions signals
+// replaceability with modules.ReplaceableSignal, and the replacement has to
+// pay the fees of every set that it replaces, plus replacementFeeIncrement
+// for each of its own bytes, at a higher fee rate than each of them.
+//
+// Conflicting sets that are not double spent are parents of the replacement
+// and stay in the pool. The transactions of the replaced sets that are not
+// part of the replacement are removed from the pool, so a replacement should
+// include the unconfirmed parents of the transactions that it replaces.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errLowReplacementFees  = errors.New("transaction set does not pay enough fees to replace the transaction sets that it double spends")
+	errTooManyReplacements = errors.New("transaction set would replace too many transactions")
+)
+
+// spentObjectIDs returns the ids of the siacoin and siafund outputs spent by a
+// transaction.
+func spentObjectIDs(txn types.Transaction) []ObjectID {
+	var oids []ObjectID
+	for _, sci := range txn.SiacoinInputs {
+		oids = append(oids, ObjectID(sci.ParentID))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		oids = append(oids, ObjectID(sfi.ParentID))
+	}
+	return oids
+}
+
+// doubleSpentSets returns the conflicting sets that contain a transaction
+// which spends an output that is also spent by a different transaction of ts.
+func (tp *TransactionPool) doubleSpentSets(ts []types.Transaction, conflicts []TransactionSetID) map[TransactionSetID]struct{} {
+	txnIDs := make(map[types.TransactionID]struct{})
+	spent := make(map[ObjectID]struct{})
+	for _, txn := range ts {
+		txnIDs[txn.ID()] = struct{}{}
+		for _, oid := range spentObjectIDs(txn) {
+			spent[oid] = struct{}{}
+		}
+	}
+
+	doubleSpent := make(map[TransactionSetID]struct{})
+	for _, conflict := range conflicts {
+		for _, txn := range tp.transactionSets[conflict] {
+			if _, exists := txnIDs[txn.ID()]; exists {
+				continue
+			}
+			for _, oid := range spentObjectIDs(txn) {
+				if _, exists := spent[oid]; exists {
+					doubleSpent[conflict] = struct{}{}
+				}
+			}
+		}
+	}
+	return doubleSpent
+}
+
+// doubleSpend returns the id of a transaction of ts that spends an output
+// which is also spent by a different transaction of set, along with the id of
+// that transaction.
+func doubleSpend(ts, set []types.Transaction) (spender, conflicting types.TransactionID) {
+	spenders := make(map[ObjectID]types.TransactionID)
+	for _, txn := range ts {
+		for _, oid := range spentObjectIDs(txn) {
+			spenders[oid] = txn.ID()
+		}
+	}
+	for _, txn := range set {
+		for _, oid := range spentObjectIDs(txn) {
+			if id, exists := spenders[oid]; exists && id != txn.ID() {
+				return id, txn.ID()
+			}
+		}
+	}
+	return types.TransactionID{}, types.TransactionID{}
+}
+
+// recordReplacementConflicts records the transactions of a replaced set that
+// are not part of the replacement as displaced by the transaction of the
+// replacement that double spends the set.
+func (tp *TransactionPool) recordReplacementConflicts(replacement, replacedSet []types.Transaction) {
+	spender, _ := doubleSpend(replacement, replacedSet)
+	kept := make(map[types.TransactionID]struct{})
+	for _, txn := range replacement {
+		kept[txn.ID()] = struct{}{}
+	}
+	for _, txn := range replacedSet {
+		if _, exists := kept[txn.ID()]; exists {
+			continue
+		}
+		tp.conflicts = append(tp.conflicts, modules.TransactionConflict{
+			DisplacedTransaction:   txn.ID(),
+			ConflictingTransaction: spender,
+		})
+	}
+}
+
+// replaceTransactionSets replaces the transaction sets that ts double spends
+// with ts, keeping the other conflicting sets as its parents.
+func (tp *TransactionPool) replaceTransactionSets(ts []types.Transaction, conflicts []TransactionSetID, replaced map[TransactionSetID]struct{}, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) error {
+	// Check that every replaced set signals replaceability, and that the
+	// replacement does not remove too many transactions from the pool.
+	var replacedTxns int
+	var replacedFees types.Currency
+	for id := range replaced {
+		set := tp.transactionSets[id]
+		replaceable := false
+		for _, txn := range set {
+			replaceable = replaceable || modules.IsReplaceable(txn)
+		}
+		if !replaceable {
+			spender, conflicting := doubleSpend(ts, set)
+			return modules.TransactionRejection{
+				Reason:                   modules.RejectionDoubleSpend,
+				Err:                      modules.NewConsensusConflict("transaction set double spends an unconfirmed transaction set that cannot be replaced"),
+				TransactionID:            spender,
+				ConflictingTransactionID: conflicting,
+			}
+		}
+		replacedTxns += len(set)
+		replacedFees = replacedFees.Add(setFees(set))
+	}
+	if replacedTxns > maxReplacedTransactions {
+		return errTooManyReplacements
+	}
+
+	// Merge the conflicting sets that are not replaced with the new
+	// transactions, which may depend on them.
+	var superset []types.Transaction
+	keptTxns := make(map[types.TransactionID]struct{})
+	removed := make(map[TransactionSetID]struct{})
+	for _, conflict := range conflicts {
+		if _, exists := removed[conflict]; exists {
+			continue
+		}
+		removed[conflict] = struct{}{}
+		if _, exists := replaced[conflict]; exists {
+			continue
+		}
+		for _, txn := range tp.transactionSets[conflict] {
+			keptTxns[txn.ID()] = struct{}{}
+		}
+		superset = append(superset, tp.transactionSets[conflict]...)
+	}
+	var newTxns []types.Transaction
+	for _, txn := range ts {
+		if _, exists := keptTxns[txn.ID()]; !exists {
+			newTxns = append(newTxns, txn)
+		}
+	}
+	superset = append(superset, newTxns...)
+	setSize, err := tp.checkTransactionSetComposition(superset)
+	if err != nil {
+		return err
+	}
+
+	// Check that the new transactions outbid the replaced sets, both in total
+	// fees and in fee rate.
+	newFees := setFees(newTxns)
+	newSize := uint64(len(encoding.Marshal(newTxns)))
+	if newFees.Cmp(replacedFees.Add(replacementFeeIncrement.Mul64(newSize))) < 0 {
+		return errLowReplacementFees
+	}
+	newRate := feeRate(newTxns, newSize)
+	for id := range replaced {
+		set := tp.transactionSets[id]
+		if newRate.Cmp(feeRate(set, uint64(len(encoding.Marshal(set))))) <= 0 {
+			return errLowReplacementFees
+		}
+	}
+	if tp.requiredFeesToExtendTpool().Mul64(setSize).Cmp(setFees(superset)) > 0 {
+		return errLowMinerFees
+	}
+	evictions, err := tp.evictionsForSet(superset, removed)
+	if err != nil {
+		return err
+	}
+
+	// Check that the replacement is valid without the replaced sets.
+	cc, err := txnFn(superset)
+	if err != nil {
+		return tp.consensusRejection(superset, "replacement transaction set is invalid", err)
+	}
+
+	// Remove the replaced and merged sets, keeping the heights of the
+	// transactions that remain in the pool.
+	heights := make(map[types.TransactionID]types.BlockHeight)
+	for _, txn := range superset {
+		if height, exists := tp.transactionHeights[txn.ID()]; exists {
+			heights[txn.ID()] = height
+		}
+	}
+	for id := range removed {
+		if _, exists := replaced[id]; exists {
+			tp.log.Debugf("replaced transaction set %v\n", id)
+			tp.recordReplacementConflicts(superset, tp.transactionSets[id])
+			tp.removeTransactionSet(id, modules.TransactionRemovalReplaced)
+		} else {
+			tp.removeTransactionSet(id, modules.TransactionRemovalMerged)
+		}
+	}
+	tp.evictTransactionSets(evictions)
+
+	// Add the replacement to the pool.
+	setID := TransactionSetID(crypto.HashObject(superset))
+	tp.transactionSets[setID] = superset
+	for _, oid := range relatedObjectIDs(superset) {
+		tp.knownObjects[oid] = setID
+	}
+	tp.transactionSetDiffs[setID] = &cc
+	tp.transactionListSize += len(encoding.Marshal(superset))
+	for _, txn := range superset {
+		if height, exists := heights[txn.ID()]; exists {
+			tp.transactionHeights[txn.ID()] = height
+		} else {
+			tp.transactionHeights[txn.ID()] = tp.blockHeight
+		}
+	}
+	tp.log.Debugf("accepted replacement transaction set %v, tpool size is %vB\n", setID, tp.transactionListSize)
+	return nil
+}
@@ -0,0 +1,141 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// spendTxn returns a transaction that spends an output sent to the empty
+// unlock conditions, paying fee and sending the rest back to the empty unlock
+// conditions.
+func spendTxn(parent types.SiacoinOutputID, value, fee types.Currency, replaceable bool) types.Transaction {
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID: parent,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      value.Sub(fee),
+			UnlockHash: types.UnlockConditions{}.UnlockHash(),
+		}},
+		MinerFees: []types.Currency{fee},
+	}
+	if replaceable {
+		txn.ArbitraryData = [][]byte{modules.ReplaceableSignal}
+	}
+	return txn
+}
+
+// TestReplaceByFee checks that replaceable transaction sets can be replaced by
+// sets that double spend them and pay higher fees.
+func TestReplaceByFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create two outputs that can be spent without signatures.
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoinsMulti([]types.SiacoinOutput{
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output1 := txns[len(txns)-1].SiacoinOutputID(0)
+	output2 := txns[len(txns)-1].SiacoinOutputID(1)
+	inPool := func(txn types.Transaction) bool {
+		_, _, exists := tpt.tpool.Transaction(txn.ID())
+		return exists
+	}
+
+	original := spendTxn(output1, value, types.SiacoinPrecision, true)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{original})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A double spend that pays lower fees should be rejected.
+	cheap := spendTxn(output1, value, types.SiacoinPrecision.Div64(2), true)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{cheap})
+	if !rejectedWith(err, errLowReplacementFees) {
+		t.Fatal("expected errLowReplacementFees, got", err)
+	}
+
+	// A double spend that pays higher fees should replace the original.
+	replacement := spendTxn(output1, value, types.SiacoinPrecision.Mul64(2), false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{replacement})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inPool(original) || !inPool(replacement) {
+		t.Fatal("original transaction was not replaced")
+	}
+
+	// The replacement does not signal replaceability, so it cannot be
+	// replaced itself.
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{spendTxn(output1, value, types.SiacoinPrecision.Mul64(5), true)})
+	if !modules.IsConsensusConflict(err) {
+		t.Fatal("expected a consensus conflict, got", err)
+	}
+	if !inPool(replacement) {
+		t.Fatal("replacement was removed from the pool")
+	}
+
+	// Replace a child of an unconfirmed parent. The parent should stay in the
+	// pool.
+	parent := spendTxn(output2, value, types.SiacoinPrecision, false)
+	child := spendTxn(parent.SiacoinOutputID(0), value.Sub(types.SiacoinPrecision), types.SiacoinPrecision, true)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, child})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bumped := spendTxn(parent.SiacoinOutputID(0), value.Sub(types.SiacoinPrecision), types.SiacoinPrecision.Mul64(3), true)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, bumped})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inPool(parent) || inPool(child) || !inPool(bumped) {
+		t.Fatal("child transaction was not replaced")
+	}
+
+	// The replacements should be confirmed by the next block.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range []types.Transaction{replacement, parent, bumped} {
+		confirmed, err := tpt.tpool.TransactionConfirmed(txn.ID())
+		if err != nil {
+			t.Fatal(err)
+		} else if !confirmed {
+			t.Fatal("replacement was not confirmed")
+		}
+	}
+}
+
+// TestIsReplaceable checks that transactions are only replaceable if they
+// carry the replaceable signal.
+func TestIsReplaceable(t *testing.T) {
+	txn := types.Transaction{ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], "data"...)}}
+	if modules.IsReplaceable(txn) {
+		t.Fatal("transaction without the signal is replaceable")
+	}
+	txn.ArbitraryData = append(txn.ArbitraryData, modules.ReplaceableSignal)
+	if !modules.IsReplaceable(txn) {
+		t.Fatal("transaction with the signal is not replaceable")
+	}
+	if _, err := isStandardTransaction(txn, defaultPolicy); err != nil {
+		t.Fatal("replaceable transaction is not standard:", err)
+	}
+}
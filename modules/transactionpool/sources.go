@@ -0,0 +1,138 @@
+package transactionpool
+
+// sources.go tracks the peers that relay transaction sets to the pool. Every
+// host gets an allowance of transaction sets that refills over time, so a
+// single peer cannot occupy the pool with a flood of sets. Sets that are
+// rejected as invalid or for paying too little fees cost more of the
+// allowance than other sets, and hosts that keep relaying them after their
+// allowance is exhausted are penalized through the gateway, which bans them
+// once they have accumulated enough penalty points.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errSourceRateLimited = errors.New("peer has relayed too many transaction sets recently")
+)
+
+// A sourceAllowance is the number of transaction sets that a host may relay
+// to the pool before it is rate limited.
+type sourceAllowance struct {
+	sets       float64
+	lastRefill time.Time
+}
+
+// refill adds the sets that the host was allowed since the last refill.
+func (sa *sourceAllowance) refill() {
+	sa.sets += time.Since(sa.lastRefill).Seconds() * sourceSetRate
+	if sa.sets > sourceSetBurst {
+		sa.sets = sourceSetBurst
+	}
+	sa.lastRefill = time.Now()
+}
+
+// penalizedRejection returns true if a set that was rejected with err costs
+// the source rejectedSetCost sets of its allowance. Rejections that honest
+// peers cause regularly, like conflicts with sets that they have not seen
+// yet, are not penalized.
+func penalizedRejection(err error) bool {
+	tr, ok := err.(modules.TransactionRejection)
+	if !ok {
+		return false
+	}
+	switch tr.Reason {
+	case modules.RejectionInsufficientFee, modules.RejectionInvalidSignature, modules.RejectionTooLarge, modules.RejectionEmptySet:
+		return true
+	}
+	return false
+}
+
+// allowSource takes one set from the allowance of a source, returning
+// errSourceRateLimited if the allowance is exhausted. Local transaction sets
+// are never rate limited.
+func (tp *TransactionPool) allowSource(source modules.NetAddress) error {
+	if source == "" {
+		return nil
+	}
+	host := source.Host()
+	sa, exists := tp.sourceAllowances[host]
+	if !exists {
+		sa = &sourceAllowance{sets: sourceSetBurst, lastRefill: time.Now()}
+		tp.sourceAllowances[host] = sa
+	}
+	sa.refill()
+	if sa.sets < 1 {
+		return errSourceRateLimited
+	}
+	sa.sets--
+	return nil
+}
+
+// chargeSource charges the source of a rejected transaction set for the
+// rejection. Sources that exhaust their allowance with penalized rejections
+// are penalized by the gateway.
+func (tp *TransactionPool) chargeSource(source modules.NetAddress, err error) {
+	sa, exists := tp.sourceAllowances[source.Host()]
+	if source == "" || !exists || !penalizedRejection(err) {
+		return
+	}
+	sa.sets -= rejectedSetCost - 1
+	if sa.sets < 0 {
+		sa.sets = 0
+		tp.log.Debugf("%v is flooding the transaction pool with rejected transaction sets\n", source)
+		tp.gateway.PenalizePeer(source, modules.PenaltyTransactionFlood)
+	}
+}
+
+// pruneSourceAllowances forgets the hosts whose allowance is full again.
+func (tp *TransactionPool) pruneSourceAllowances() {
+	for host, sa := range tp.sourceAllowances {
+		sa.refill()
+		if sa.sets >= sourceSetBurst {
+			delete(tp.sourceAllowances, host)
+		}
+	}
+}
+
+// markSource records the peer that introduced the transactions of a set.
+// Transactions that are already known keep their original source.
+func (tp *TransactionPool) markSource(ts []types.Transaction, source modules.NetAddress) {
+	if source == "" {
+		return
+	}
+	for _, txn := range ts {
+		if _, exists := tp.transactionSources[txn.ID()]; !exists {
+			tp.transactionSources[txn.ID()] = source
+		}
+	}
+}
+
+// pruneSources forgets the sources of the transactions that are no longer in
+// the pool.
+func (tp *TransactionPool) pruneSources() {
+	inPool := make(map[types.TransactionID]struct{})
+	for _, ts := range tp.transactionSets {
+		for _, txn := range ts {
+			inPool[txn.ID()] = struct{}{}
+		}
+	}
+	for id := range tp.transactionSources {
+		if _, exists := inPool[id]; !exists {
+			delete(tp.transactionSources, id)
+		}
+	}
+}
+
+// TransactionSource returns the address of the peer that relayed a
+// transaction to the pool. The source is unknown for local transactions.
+func (tp *TransactionPool) TransactionSource(id types.TransactionID) (modules.NetAddress, bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	source, exists := tp.transactionSources[id]
+	return source, exists
+}
@@ -0,0 +1,110 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSourceRateLimit checks that hosts which flood the pool with rejected
+// transaction sets are rate limited and eventually banned, without affecting
+// other hosts or local transactions.
+func TestSourceRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Empty sets are rejected, and cost the source rejectedSetCost sets of
+	// its allowance, so the flood is rate limited quickly.
+	flooder := modules.NetAddress("111.111.111.111:1111")
+	var rejected int
+	for i := 0; i < int(sourceSetBurst); i++ {
+		err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{}, flooder)
+		if rejectedWith(err, errSourceRateLimited) {
+			break
+		} else if !rejectedWith(err, errEmptySet) {
+			t.Fatal("expected an empty set rejection, got", err)
+		}
+		rejected++
+	}
+	if rejected > int(sourceSetBurst)/rejectedSetCost+1 {
+		t.Fatal("flooder was not rate limited after", rejected, "rejected sets")
+	}
+
+	// Other hosts and local transactions are not affected.
+	err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{}, "222.222.222.222:2222")
+	if !rejectedWith(err, errEmptySet) {
+		t.Fatal("expected an empty set rejection, got", err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{})
+	if !rejectedWith(err, errEmptySet) {
+		t.Fatal("expected an empty set rejection, got", err)
+	}
+
+	// Every rejected set beyond the allowance penalizes the flooder, until
+	// the gateway bans it.
+	for i := 0; i < 100/modules.PenaltyTransactionFlood; i++ {
+		tpt.tpool.mu.Lock()
+		tpt.tpool.sourceAllowances[flooder.Host()].sets = 1
+		tpt.tpool.mu.Unlock()
+		err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{}, flooder)
+		if !rejectedWith(err, errEmptySet) {
+			t.Fatal("expected an empty set rejection, got", err)
+		}
+	}
+	bans := tpt.gateway.Bans()
+	if len(bans) != 1 || bans[0].Host != flooder.Host() {
+		t.Fatal("flooder was not banned:", bans)
+	}
+}
+
+// TestTransactionSource checks that the pool reports the peer that relayed a
+// transaction, and forgets the source once the transaction is confirmed.
+func TestTransactionSource(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := spendTxn(output, value, fee, false)
+	source := modules.NetAddress("foo.com:1234")
+	err = tpt.tpool.managedAcceptTransactionSet([]types.Transaction{remote}, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if src, exists := tpt.tpool.TransactionSource(remote.ID()); !exists || src != source {
+		t.Fatal("wrong source for the remote transaction:", src, exists)
+	}
+	if _, exists := tpt.tpool.TransactionSource(local[len(local)-1].ID()); exists {
+		t.Fatal("local transaction should not have a source")
+	}
+
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := tpt.tpool.TransactionSource(remote.ID()); exists {
+		t.Fatal("source was not forgotten after the transaction was confirmed")
+	}
+}
@@ -1,8 +1,6 @@
 package transactionpool
 
 import (
-	"errors"
-
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -36,6 +34,27 @@ import (
 // Rule: The transaction set size is limited.
 //		A group of dependent transactions cannot exceed 100kb to limit how
 //		quickly the transaction pool can be filled with new transactions.
+//
+// The following rules are part of the configurable policy of the transaction
+// pool. Each of them can be toggled in the settings of the pool, and all but
+// the first are disabled by default.
+//
+// Rule: Foreign unlock conditions are rejected.
+//		Unlock conditions with unrecognized key types are rejected for the same
+//		reason as foreign signature algorithms.
+//
+// Rule: The size of the arbitrary data is limited.
+//		Arbitrary data is a cheap way to store data in the blockchain. Nodes
+//		that relay transactions can limit how much of it they relay.
+//
+// Rule: Dust outputs are rejected.
+//		Outputs that are worth less than the fees needed to spend them bloat
+//		the set of unspent outputs that every node has to keep.
+//
+// Rule: The number of signatures is limited.
+//		Each signature requires a verifier to hash a part of the transaction.
+//		Limiting the number of signatures limits how much work a transaction
+//		can demand from the nodes that relay it.
 
 // checkUnlockConditions looks at the UnlockConditions and verifies that all
 // public keys are recognized. Unrecognized public keys are automatically
@@ -46,19 +65,45 @@ func checkUnlockConditions(uc types.UnlockConditions) error {
 	for _, pk := range uc.PublicKeys {
 		if pk.Algorithm != types.SignatureEntropy &&
 			pk.Algorithm != types.SignatureEd25519 {
-			return errors.New("unrecognized key type in transaction")
+			return modules.ErrNonStandardUnlockConditions
 		}
 	}
 
 	return nil
 }
 
-// isStandardTransaction enforces extra rules such as a transaction size limit.
-// These rules can be altered without disrupting consensus.
+// checkPolicy enforces the rules of the policy that are not covered by
+// isStandardTransaction.
+func checkPolicy(t types.Transaction, policy modules.TransactionPoolPolicy) error {
+	if policy.CheckArbitraryDataSize {
+		var size uint64
+		for _, arb := range t.ArbitraryData {
+			size += uint64(len(arb))
+		}
+		if size > policy.MaxArbitraryDataSize {
+			return modules.ErrLargeArbitraryData
+		}
+	}
+	if policy.CheckDustOutputs {
+		for _, sco := range t.SiacoinOutputs {
+			if sco.Value.Cmp(policy.DustThreshold) < 0 {
+				return modules.ErrDustOutput
+			}
+		}
+	}
+	if policy.CheckSignatureCount && uint64(len(t.TransactionSignatures)) > policy.MaxSignatures {
+		return modules.ErrTooManySignatures
+	}
+	return nil
+}
+
+// isStandardTransaction enforces extra rules such as a transaction size limit,
+// along with the rules of the policy. These rules can be altered without
+// disrupting consensus.
 //
 // The size of the transaction is returned so that the transaction does not need
 // to be encoded multiple times.
-func isStandardTransaction(t types.Transaction) (uint64, error) {
+func isStandardTransaction(t types.Transaction, policy modules.TransactionPoolPolicy) (uint64, error) {
 	// Check that the size of the transaction does not exceed the standard
 	// established in Standard.md. Larger transactions are a DOS vector,
 	// because someone can fill a large transaction with a bunch of signatures
@@ -76,22 +121,24 @@ func isStandardTransaction(t types.Transaction) (uint64, error) {
 	// of the transaction. Unrecognized types are ignored because a softfork
 	// may make certain unrecognized signatures invalid, and this node cannot
 	// tell which signatures are the invalid ones.
-	for _, sci := range t.SiacoinInputs {
-		err := checkUnlockConditions(sci.UnlockConditions)
-		if err != nil {
-			return 0, err
+	if policy.CheckUnlockConditions {
+		for _, sci := range t.SiacoinInputs {
+			err := checkUnlockConditions(sci.UnlockConditions)
+			if err != nil {
+				return 0, err
+			}
 		}
-	}
-	for _, fcr := range t.FileContractRevisions {
-		err := checkUnlockConditions(fcr.UnlockConditions)
-		if err != nil {
-			return 0, err
+		for _, fcr := range t.FileContractRevisions {
+			err := checkUnlockConditions(fcr.UnlockConditions)
+			if err != nil {
+				return 0, err
+			}
 		}
-	}
-	for _, sfi := range t.SiafundInputs {
-		err := checkUnlockConditions(sfi.UnlockConditions)
-		if err != nil {
-			return 0, err
+		for _, sfi := range t.SiafundInputs {
+			err := checkUnlockConditions(sfi.UnlockConditions)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 
@@ -111,6 +158,10 @@ func isStandardTransaction(t types.Transaction) (uint64, error) {
 
 		return 0, modules.ErrInvalidArbPrefix
 	}
+
+	if err := checkPolicy(t, policy); err != nil {
+		return 0, err
+	}
 	return uint64(tlen), nil
 }
 
@@ -120,14 +171,14 @@ func isStandardTransaction(t types.Transaction) (uint64, error) {
 //
 // The size of the transaction set is returned so that the encoding only needs
 // to happen once.
-func isStandardTransactionSet(ts []types.Transaction) (uint64, error) {
+func isStandardTransactionSet(ts []types.Transaction, policy modules.TransactionPoolPolicy) (uint64, error) {
 	// Check that each transaction is acceptable, while also making sure that
 	// the size of the whole set is legal.
 	var totalSize uint64
 	for i := range ts {
-		tSize, err := isStandardTransaction(ts[i])
+		tSize, err := isStandardTransaction(ts[i], policy)
 		if err != nil {
-			return 0, err
+			return 0, standardRejection(ts[i], err)
 		}
 		totalSize += tSize
 		if totalSize > modules.TransactionSetSizeLimit {
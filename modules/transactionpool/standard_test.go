@@ -27,7 +27,7 @@ func TestIntegrationLargeTransactions(t *testing.T) {
 	fastrand.Read(arbData[100:116]) // prevents collisions with other transacitons in the loop.
 	txn := types.Transaction{ArbitraryData: [][]byte{arbData}}
 	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
-	if err != modules.ErrLargeTransaction {
+	if !rejectedWith(err, modules.ErrLargeTransaction) {
 		t.Fatal(err)
 	}
 
@@ -41,7 +41,64 @@ func TestIntegrationLargeTransactions(t *testing.T) {
 		tset = append(tset, txn)
 	}
 	err = tpt.tpool.AcceptTransactionSet(tset)
-	if err != modules.ErrLargeTransactionSet {
+	if !rejectedWith(err, modules.ErrLargeTransactionSet) {
 		t.Fatal(err)
 	}
 }
+
+// TestStandardPolicy checks that each rule of the policy is only enforced
+// when it is enabled.
+func TestStandardPolicy(t *testing.T) {
+	arbData := append(modules.PrefixNonSia[:], make([]byte, 100)...)
+	tests := []struct {
+		txn    types.Transaction
+		enable func(*modules.TransactionPoolPolicy)
+		err    error
+	}{
+		{
+			txn: types.Transaction{ArbitraryData: [][]byte{arbData}},
+			enable: func(p *modules.TransactionPoolPolicy) {
+				p.CheckArbitraryDataSize = true
+				p.MaxArbitraryDataSize = uint64(len(arbData)) - 1
+			},
+			err: modules.ErrLargeArbitraryData,
+		},
+		{
+			txn: types.Transaction{SiacoinOutputs: []types.SiacoinOutput{{Value: types.NewCurrency64(1)}}},
+			enable: func(p *modules.TransactionPoolPolicy) {
+				p.CheckDustOutputs = true
+				p.DustThreshold = types.NewCurrency64(2)
+			},
+			err: modules.ErrDustOutput,
+		},
+		{
+			txn: types.Transaction{TransactionSignatures: make([]types.TransactionSignature, 3)},
+			enable: func(p *modules.TransactionPoolPolicy) {
+				p.CheckSignatureCount = true
+				p.MaxSignatures = 2
+			},
+			err: modules.ErrTooManySignatures,
+		},
+		{
+			txn: types.Transaction{SiacoinInputs: []types.SiacoinInput{{
+				UnlockConditions: types.UnlockConditions{
+					PublicKeys: []types.SiaPublicKey{{Algorithm: types.Specifier{'f', 'o', 'o'}}},
+				},
+			}}},
+			enable: func(p *modules.TransactionPoolPolicy) {
+				p.CheckUnlockConditions = true
+			},
+			err: modules.ErrNonStandardUnlockConditions,
+		},
+	}
+	for i, test := range tests {
+		var policy modules.TransactionPoolPolicy
+		if _, err := isStandardTransaction(test.txn, policy); err != nil {
+			t.Errorf("test %v: transaction was rejected by an empty policy: %v", i, err)
+		}
+		test.enable(&policy)
+		if _, err := isStandardTransaction(test.txn, policy); err != test.err {
+			t.Errorf("test %v: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
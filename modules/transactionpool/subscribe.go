@@ -7,12 +7,59 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
+// removalReason returns the reason for which a transaction set that is no
+// longer in the pool was removed.
+func (tp *TransactionPool) removalReason(id TransactionSetID, ts []types.Transaction) modules.TransactionRemovalReason {
+	if reason, exists := tp.removalReasons[id]; exists {
+		return reason
+	}
+	for _, txn := range ts {
+		if tp.transactionConfirmed(tp.dbTx, txn.ID()) {
+			return modules.TransactionRemovalConfirmed
+		}
+	}
+	return modules.TransactionRemovalReverted
+}
+
+// filterDiff returns the part of a diff that matches a filter. removed holds
+// the transactions of the reverted sets. Conflicts are included if the
+// displaced transaction was part of a matching reverted set.
+func filterDiff(diff *modules.TransactionPoolDiff, removed map[TransactionSetID][]types.Transaction, filter modules.TransactionPoolFilter) *modules.TransactionPoolDiff {
+	filtered := &modules.TransactionPoolDiff{
+		RemovalReasons: make(map[modules.TransactionSetID]modules.TransactionRemovalReason),
+	}
+	matched := make(map[types.TransactionID]struct{})
+	for _, id := range diff.RevertedTransactions {
+		if filter.MatchesSet(removed[TransactionSetID(id)]) {
+			filtered.RevertedTransactions = append(filtered.RevertedTransactions, id)
+			filtered.RemovalReasons[id] = diff.RemovalReasons[id]
+			for _, txn := range removed[TransactionSetID(id)] {
+				matched[txn.ID()] = struct{}{}
+			}
+		}
+	}
+	for _, conflict := range diff.Conflicts {
+		if _, exists := matched[conflict.DisplacedTransaction]; exists {
+			filtered.Conflicts = append(filtered.Conflicts, conflict)
+		}
+	}
+	for _, ut := range diff.AppliedTransactions {
+		if filter.MatchesSet(ut.Transactions) {
+			filtered.AppliedTransactions = append(filtered.AppliedTransactions, ut)
+		}
+	}
+	return filtered
+}
+
 // updateSubscribersTransactions sends a new transaction pool update to all
 // subscribers.
 func (tp *TransactionPool) updateSubscribersTransactions() {
-	diff := new(modules.TransactionPoolDiff)
+	diff := &modules.TransactionPoolDiff{
+		RemovalReasons: make(map[modules.TransactionSetID]modules.TransactionRemovalReason),
+	}
+	removed := make(map[TransactionSetID][]types.Transaction)
 	// Create all of the diffs for reverted sets.
-	for id := range tp.subscriberSets {
+	for id, ut := range tp.subscriberSets {
 		// The transaction set is still in the transaction pool, no need to
 		// create an update.
 		_, exists := tp.transactionSets[id]
@@ -20,10 +67,15 @@ func (tp *TransactionPool) updateSubscribersTransactions() {
 			continue
 		}
 
-		// Report that this set has been removed. Negative diffs don't have all
-		// fields filled out.
+		// Report that this set has been removed, and why. Negative diffs
+		// don't have all fields filled out.
 		diff.RevertedTransactions = append(diff.RevertedTransactions, modules.TransactionSetID(id))
+		diff.RemovalReasons[modules.TransactionSetID(id)] = tp.removalReason(id, ut.Transactions)
+		removed[id] = ut.Transactions
 	}
+	tp.removalReasons = make(map[TransactionSetID]modules.TransactionRemovalReason)
+	diff.Conflicts = tp.conflicts
+	tp.conflicts = nil
 
 	// Clear the subscriber sets map.
 	for _, revert := range diff.RevertedTransactions {
@@ -60,7 +112,17 @@ func (tp *TransactionPool) updateSubscribersTransactions() {
 	}
 
 	for _, subscriber := range tp.subscribers {
-		subscriber.ReceiveUpdatedUnconfirmedTransactions(diff)
+		filter, filtered := tp.subscriberFilters[subscriber]
+		if !filtered {
+			subscriber.ReceiveUpdatedUnconfirmedTransactions(diff)
+			continue
+		}
+		// Filtered subscribers are only notified about changes to the sets
+		// that they are interested in.
+		fd := filterDiff(diff, removed, filter)
+		if len(fd.AppliedTransactions) > 0 || len(fd.RevertedTransactions) > 0 {
+			subscriber.ReceiveUpdatedUnconfirmedTransactions(fd)
+		}
 	}
 }
 
@@ -70,7 +132,22 @@ func (tp *TransactionPool) updateSubscribersTransactions() {
 func (tp *TransactionPool) TransactionPoolSubscribe(subscriber modules.TransactionPoolSubscriber) {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
+	tp.subscribe(subscriber)
+}
 
+// TransactionPoolSubscribeFiltered adds a subscriber to the transaction pool
+// that only receives updates about the transaction sets that match the
+// filter. Updates that do not contain a matching set are not sent at all.
+func (tp *TransactionPool) TransactionPoolSubscribeFiltered(subscriber modules.TransactionPoolSubscriber, filter modules.TransactionPoolFilter) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.subscriberFilters[subscriber] = filter
+	tp.subscribe(subscriber)
+}
+
+// subscribe adds a subscriber to the transaction pool and sends it the
+// transaction sets that are currently in the pool.
+func (tp *TransactionPool) subscribe(subscriber modules.TransactionPoolSubscriber) {
 	// Check that this subscriber is not already subscribed.
 	for _, s := range tp.subscribers {
 		if s == subscriber {
@@ -82,10 +159,13 @@ func (tp *TransactionPool) TransactionPoolSubscribe(subscriber modules.Transacti
 	tp.subscribers = append(tp.subscribers, subscriber)
 
 	// Send the new subscriber the transaction pool set.
+	filter := tp.subscriberFilters[subscriber]
 	diff := new(modules.TransactionPoolDiff)
 	diff.AppliedTransactions = make([]*modules.UnconfirmedTransactionSet, 0, len(tp.subscriberSets))
 	for _, ut := range tp.subscriberSets {
-		diff.AppliedTransactions = append(diff.AppliedTransactions, ut)
+		if filter.MatchesSet(ut.Transactions) {
+			diff.AppliedTransactions = append(diff.AppliedTransactions, ut)
+		}
 	}
 	subscriber.ReceiveUpdatedUnconfirmedTransactions(diff)
 }
@@ -105,4 +185,5 @@ func (tp *TransactionPool) Unsubscribe(subscriber modules.TransactionPoolSubscri
 			break
 		}
 	}
+	delete(tp.subscriberFilters, subscriber)
 }
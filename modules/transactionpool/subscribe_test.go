@@ -5,13 +5,16 @@ import (
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
 )
 
 // mockSubscriber receives transactions from the transaction pool it is
 // subscribed to, retaining them in the order they were received.
 type mockSubscriber struct {
-	txnMap map[modules.TransactionSetID][]types.Transaction
-	txns   []types.Transaction
+	txnMap    map[modules.TransactionSetID][]types.Transaction
+	txns      []types.Transaction
+	reasons   []modules.TransactionRemovalReason
+	conflicts []modules.TransactionConflict
 }
 
 // ReceiveUpdatedUnconfirmedTransactions receives transactinos from the
@@ -21,10 +24,12 @@ type mockSubscriber struct {
 func (ms *mockSubscriber) ReceiveUpdatedUnconfirmedTransactions(diff *modules.TransactionPoolDiff) {
 	for _, revert := range diff.RevertedTransactions {
 		delete(ms.txnMap, revert)
+		ms.reasons = append(ms.reasons, diff.RemovalReasons[revert])
 	}
 	for _, uts := range diff.AppliedTransactions {
 		ms.txnMap[uts.ID] = uts.Transactions
 	}
+	ms.conflicts = append(ms.conflicts, diff.Conflicts...)
 	ms.txns = nil
 	for _, txnSet := range ms.txnMap {
 		ms.txns = append(ms.txns, txnSet...)
@@ -82,3 +87,267 @@ func TestSubscription(t *testing.T) {
 		t.Error("transaction pool failed to unsubscribe mock subscriber")
 	}
 }
+
+// TestSubscriptionRemovalReasons checks that subscribers learn why transaction
+// sets are removed from the pool.
+func TestSubscriptionRemovalReasons(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoinsMulti([]types.SiacoinOutput{
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+		{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output1 := txns[len(txns)-1].SiacoinOutputID(0)
+	output2 := txns[len(txns)-1].SiacoinOutputID(1)
+
+	ms := &mockSubscriber{
+		txnMap: make(map[modules.TransactionSetID][]types.Transaction),
+	}
+	tpt.tpool.TransactionPoolSubscribe(ms)
+	checkReasons := func(expected ...modules.TransactionRemovalReason) {
+		t.Helper()
+		if len(ms.reasons) != len(expected) {
+			t.Fatalf("expected reasons %v, got %v", expected, ms.reasons)
+		}
+		for i := range expected {
+			if ms.reasons[i] != expected[i] {
+				t.Fatalf("expected reasons %v, got %v", expected, ms.reasons)
+			}
+		}
+		ms.reasons = nil
+	}
+
+	// Adding a child in a separate set merges the parent set.
+	parent := spendTxn(output1, value, types.SiacoinPrecision, false)
+	child := spendTxn(parent.SiacoinOutputID(0), value.Sub(types.SiacoinPrecision), types.SiacoinPrecision, true)
+	if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{parent}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{child}); err != nil {
+		t.Fatal(err)
+	}
+	checkReasons(modules.TransactionRemovalMerged)
+
+	// Replacing the child replaces the merged set.
+	bumped := spendTxn(parent.SiacoinOutputID(0), value.Sub(types.SiacoinPrecision), types.SiacoinPrecision.Mul64(3), false)
+	if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, bumped}); err != nil {
+		t.Fatal(err)
+	}
+	checkReasons(modules.TransactionRemovalReplaced)
+
+	// Confirming the set removes it as confirmed.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkReasons(modules.TransactionRemovalConfirmed)
+
+	// Shrinking the pool evicts the sets that were relayed by peers.
+	for i := 0; i < 30; i++ {
+		arbData := make([]byte, 10e3)
+		copy(arbData, modules.PrefixNonSia[:])
+		fastrand.Read(arbData[100:116])
+		if err := tpt.tpool.managedAcceptTransactionSet([]types.Transaction{{ArbitraryData: [][]byte{arbData}}}, modules.NetAddress("foo.com:1234")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	settings := tpt.tpool.Settings()
+	settings.MaxPoolSize = modules.TransactionSetSizeLimit
+	err = tpt.tpool.SetSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.reasons) == 0 {
+		t.Fatal("no sets were evicted")
+	}
+	for _, reason := range ms.reasons {
+		if reason != modules.TransactionRemovalEvicted {
+			t.Fatal("expected the shrunk sets to be evicted, got", ms.reasons)
+		}
+	}
+	ms.reasons = nil
+	remaining := len(ms.txnMap)
+
+	// Purging the pool reverts the remaining sets.
+	tpt.tpool.PurgeTransactionPool()
+	if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{spendTxn(output2, value, types.SiacoinPrecision, false)}); err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]modules.TransactionRemovalReason, remaining)
+	for i := range expected {
+		expected[i] = modules.TransactionRemovalReverted
+	}
+	checkReasons(expected...)
+}
+
+// TestSubscriptionFilters checks that filtered subscribers only receive
+// updates about matching transaction sets.
+func TestSubscriptionFilters(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := tpt.wallet.SendSiacoins(value, types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uh := types.UnlockHash{1}
+	byHash := &mockSubscriber{txnMap: make(map[modules.TransactionSetID][]types.Transaction)}
+	byType := &mockSubscriber{txnMap: make(map[modules.TransactionSetID][]types.Transaction)}
+	tpt.tpool.TransactionPoolSubscribeFiltered(byHash, modules.TransactionPoolFilter{UnlockHashes: []types.UnlockHash{uh}})
+	tpt.tpool.TransactionPoolSubscribeFiltered(byType, modules.TransactionPoolFilter{Types: []modules.TransactionType{modules.TransactionTypeArbitraryData}})
+	defer tpt.tpool.Unsubscribe(byHash)
+	defer tpt.tpool.Unsubscribe(byType)
+
+	// A transaction with arbitrary data should only be sent to byType.
+	arbData := append(modules.PrefixNonSia[:], "data"...)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{{ArbitraryData: [][]byte{arbData}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byHash.txns) != 0 || len(byType.txns) != 1 {
+		t.Fatal("arbitrary data transaction was sent to the wrong subscribers")
+	}
+
+	// A transaction sending to uh should only be sent to byHash.
+	txn := spendTxn(txns[len(txns)-1].SiacoinOutputID(0), value, types.SiacoinPrecision, false)
+	txn.SiacoinOutputs[0].UnlockHash = uh
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byHash.txns) != 1 || byHash.txns[0].ID() != txn.ID() || len(byType.txns) != 1 {
+		t.Fatal("siacoin transaction was sent to the wrong subscribers")
+	}
+
+	// Both subscribers should learn that their sets were confirmed.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byHash.txns) != 0 || len(byType.txns) != 0 {
+		t.Fatal("subscribers did not learn that their sets were confirmed")
+	}
+	if len(byHash.reasons) != 1 || byHash.reasons[0] != modules.TransactionRemovalConfirmed {
+		t.Fatal("wrong removal reasons:", byHash.reasons)
+	}
+
+	// A late subscriber should only receive the matching sets in the pool.
+	arbData = append(modules.PrefixNonSia[:], "more data"...)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{{ArbitraryData: [][]byte{arbData}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	late := &mockSubscriber{txnMap: make(map[modules.TransactionSetID][]types.Transaction)}
+	tpt.tpool.TransactionPoolSubscribeFiltered(late, modules.TransactionPoolFilter{Types: []modules.TransactionType{modules.TransactionTypeSiacoin}})
+	defer tpt.tpool.Unsubscribe(late)
+	if len(late.txns) != 0 {
+		t.Fatal("late subscriber received a set that does not match its filter")
+	}
+}
+
+// TestSubscriptionConflicts checks that subscribers are told about the
+// transactions that were displaced by conflicting transactions, both in the
+// pool and in blocks.
+func TestSubscriptionConflicts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	ms := mockSubscriber{
+		txnMap: make(map[modules.TransactionSetID][]types.Transaction),
+	}
+	tpt.tpool.TransactionPoolSubscribe(&ms)
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace a replaceable transaction in the pool.
+	original := spendTxn(output, value, fee, true)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{original})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement := spendTxn(output, value, fee.Mul64(3), false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{replacement})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := modules.TransactionConflict{
+		DisplacedTransaction:   original.ID(),
+		ConflictingTransaction: replacement.ID(),
+	}
+	if len(ms.conflicts) != 1 || ms.conflicts[0] != expected {
+		t.Fatal("replacement was not reported as a conflict:", ms.conflicts)
+	}
+
+	// Confirm a transaction that double spends the replacement. The
+	// replacement and its child should both be displaced.
+	child := spendTxn(replacement.SiacoinOutputID(0), value.Sub(fee.Mul64(3)), fee, false)
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{child})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doubleSpend := spendTxn(output, value, fee.Mul64(2), false)
+	block, target, err := tpt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = []types.Transaction{doubleSpend}
+	block.MinerPayouts = []types.SiacoinOutput{{
+		Value:      block.CalculateSubsidy(tpt.cs.Height() + 1),
+		UnlockHash: block.MinerPayouts[0].UnlockHash,
+	}}
+	block, _ = tpt.miner.SolveBlock(block, target)
+	err = tpt.cs.AcceptBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.conflicts = ms.conflicts[1:]
+	if len(ms.conflicts) != 2 {
+		t.Fatal("expected 2 conflicts, got", ms.conflicts)
+	}
+	for i, displaced := range []types.Transaction{replacement, child} {
+		expected := modules.TransactionConflict{
+			DisplacedTransaction:   displaced.ID(),
+			ConflictingTransaction: doubleSpend.ID(),
+			Confirmed:              true,
+		}
+		if ms.conflicts[i] != expected {
+			t.Fatalf("conflict %v is wrong: %v", i, ms.conflicts[i])
+		}
+	}
+}
@@ -2,11 +2,14 @@ package transactionpool
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/demotemutex"
 	"github.com/coreos/bbolt"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/sync"
@@ -52,16 +55,61 @@ type (
 		transactionSetDiffs map[TransactionSetID]*modules.ConsensusChange
 		transactionListSize int
 
+		// orphans holds the transaction sets that spend outputs of
+		// transactions that the pool has not seen yet, until their parents
+		// arrive.
+		orphans    map[TransactionSetID]orphanSet
+		orphanSize int
+
+		// seenSets holds the IDs of the transaction sets that were recently
+		// announced to or by the transaction pool, and when they expire.
+		seenSets      map[TransactionSetID]time.Time
+		lastSeenPrune time.Time
+
 		// Variables related to the blockchain.
 		blockHeight     types.BlockHeight
 		recentMedians   []types.Currency
 		recentMedianFee types.Currency // SC per byte
 
+		// settings holds the settings that can be changed by the user.
+		settings modules.TransactionPoolSettings
+
 		// The consensus change index tracks how many consensus changes have
 		// been sent to the transaction pool. When a new subscriber joins the
 		// transaction pool, all prior consensus changes are sent to the new
 		// subscriber.
-		subscribers []modules.TransactionPoolSubscriber
+		subscribers       []modules.TransactionPoolSubscriber
+		subscriberFilters map[modules.TransactionPoolSubscriber]modules.TransactionPoolFilter
+
+		// removalReasons holds the reasons for which transaction sets were
+		// removed from the pool since subscribers were last updated. Sets
+		// without a reason were removed by a consensus change.
+		removalReasons map[TransactionSetID]modules.TransactionRemovalReason
+
+		// conflicts holds the transactions that were displaced by conflicting
+		// transactions since subscribers were last updated.
+		conflicts []modules.TransactionConflict
+
+		// localTransactions holds the transactions in the pool that were
+		// submitted to this node rather than received from peers, and the
+		// time at which they were first seen. They are rebroadcast
+		// periodically and never evicted for paying low fees.
+		localTransactions map[types.TransactionID]time.Time
+
+		// transactionSources holds the peers that introduced the
+		// transactions in the pool, and sourceAllowances holds the number of
+		// sets that each host may still relay before it is rate limited.
+		transactionSources map[types.TransactionID]modules.NetAddress
+		sourceAllowances   map[string]*sourceAllowance
+
+		// acceptedSets and rejectedSets count the transaction sets that were
+		// accepted and rejected since the pool was started, and
+		// recentRejections holds the most recently rejected sets. The
+		// metrics sinks receive the measurements taken by the pool.
+		acceptedSets     uint64
+		rejectedSets     uint64
+		recentRejections []modules.RejectedTransactionSet
+		metricsSinks     []modules.TransactionPoolMetricsSink
 
 		// Utilities.
 		db         *persist.BoltDatabase
@@ -93,6 +141,13 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 		transactionHeights:  make(map[types.TransactionID]types.BlockHeight),
 		transactionSets:     make(map[TransactionSetID][]types.Transaction),
 		transactionSetDiffs: make(map[TransactionSetID]*modules.ConsensusChange),
+		orphans:             make(map[TransactionSetID]orphanSet),
+		seenSets:            make(map[TransactionSetID]time.Time),
+		subscriberFilters:   make(map[modules.TransactionPoolSubscriber]modules.TransactionPoolFilter),
+		removalReasons:      make(map[TransactionSetID]modules.TransactionRemovalReason),
+		localTransactions:   make(map[types.TransactionID]time.Time),
+		transactionSources:  make(map[types.TransactionID]modules.NetAddress),
+		sourceAllowances:    make(map[string]*sourceAllowance),
 
 		persistDir: persistDir,
 	}
@@ -105,9 +160,40 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 
 	// Register RPCs
 	g.RegisterRPC("RelayTransactionSet", tp.relayTransactionSet)
+	g.RegisterRPC("TxInventory", tp.rpcTransactionInventory)
+	g.RegisterCapability(txInventoryCapability)
 	tp.tg.OnStop(func() {
 		tp.gateway.UnregisterRPC("RelayTransactionSet")
+		tp.gateway.UnregisterRPC("TxInventory")
+		tp.gateway.UnregisterCapability(txInventoryCapability)
+	})
+
+	// Provide the unconfirmed transactions to the consensus set, so that
+	// compact blocks can be reconstructed from them.
+	cs.RegisterTransactionSource(tp)
+	tp.tg.OnStop(func() {
+		tp.consensusSet.UnregisterTransactionSource(tp)
+	})
+
+	// Reload the transaction sets of the previous session, and persist the
+	// current ones on shutdown. The sets are persisted before the global db
+	// transaction is committed.
+	err = tp.managedLoadUnconfirmedSets()
+	if err != nil {
+		return nil, build.ExtendErr("unable to load the persisted transaction sets", err)
+	}
+	tp.tg.AfterStop(func() {
+		tp.mu.Lock()
+		err := tp.putUnconfirmedSets(tp.dbTx)
+		if err == nil {
+			err = tp.putLocalTransactions(tp.dbTx)
+		}
+		tp.mu.Unlock()
+		if err != nil {
+			tp.log.Println("Unable to persist the unconfirmed transaction sets:", err)
+		}
 	})
+	go tp.threadedRebroadcast()
 	return tp, nil
 }
 
@@ -274,8 +360,51 @@ func (tp *TransactionPool) TransactionSet(oid crypto.Hash) []types.Transaction {
 	return parents
 }
 
+// TransactionSetByID returns the transaction set with the provided id, and a
+// bool indicating if it exists in the transaction pool.
+func (tp *TransactionPool) TransactionSetByID(id modules.TransactionSetID) ([]types.Transaction, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	tSet, exists := tp.transactionSets[TransactionSetID(id)]
+	if !exists {
+		return nil, false
+	}
+	return append([]types.Transaction(nil), tSet...), true
+}
+
+// RawTransactions returns the encoded transaction sets in the transaction
+// pool.
+func (tp *TransactionPool) RawTransactions() [][]byte {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	sets := make([][]byte, 0, len(tp.transactionSets))
+	for _, tSet := range tp.transactionSets {
+		sets = append(sets, encoding.Marshal(tSet))
+	}
+	return sets
+}
+
+// Stats returns statistics about the contents of the transaction pool.
+func (tp *TransactionPool) Stats() modules.TransactionPoolStats {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	stats := modules.TransactionPoolStats{
+		TransactionSets: uint64(len(tp.transactionSets)),
+		Size:            uint64(tp.transactionListSize),
+		MedianFeeRate:   tp.medianFeeRate(),
+		AcceptedSets:    tp.acceptedSets,
+		RejectedSets:    tp.rejectedSets,
+	}
+	for _, tSet := range tp.transactionSets {
+		stats.Transactions += uint64(len(tSet))
+		stats.TotalFees = stats.TotalFees.Add(setFees(tSet))
+	}
+	return stats
+}
+
 // Broadcast broadcasts a transaction set to all of the transaction pool's
-// peers.
+// peers. Peers that support inventory relay only receive the set if they do
+// not know it yet.
 func (tp *TransactionPool) Broadcast(ts []types.Transaction) {
-	go tp.gateway.Broadcast("RelayTransactionSet", ts, tp.gateway.Peers())
+	go tp.managedRelayTransactionSet(ts, "")
 }
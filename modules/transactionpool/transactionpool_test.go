@@ -6,6 +6,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/modules/gateway"
@@ -216,6 +217,61 @@ func TestGetTransaction(t *testing.T) {
 	}
 }
 
+// TestTransactionPoolStats checks that the transaction pool's query methods
+// report the contents of the pool.
+func TestTransactionPoolStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	if stats := tpt.tpool.Stats(); stats.TransactionSets != 0 || stats.Transactions != 0 || stats.Size != 0 || !stats.TotalFees.IsZero() {
+		t.Fatal("empty pool has non-empty stats:", stats)
+	}
+	if len(tpt.tpool.RawTransactions()) != 0 {
+		t.Fatal("empty pool returned raw transactions")
+	}
+
+	txnSet, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := tpt.tpool.Stats()
+	if stats.TransactionSets != 1 || stats.Transactions != uint64(len(txnSet)) {
+		t.Fatal("wrong number of transactions:", stats)
+	}
+	if stats.Size != uint64(len(encoding.Marshal(txnSet))) {
+		t.Fatal("wrong pool size:", stats.Size)
+	}
+	if !stats.TotalFees.Equals(setFees(txnSet)) || stats.TotalFees.IsZero() {
+		t.Fatal("wrong total fees:", stats.TotalFees)
+	}
+
+	// The set should be returned by its id and in its raw form.
+	setID := modules.TransactionSetID(crypto.HashObject(txnSet))
+	ts, exists := tpt.tpool.TransactionSetByID(setID)
+	if !exists || len(ts) != len(txnSet) || ts[len(ts)-1].ID() != txnSet[len(txnSet)-1].ID() {
+		t.Fatal("transaction set was not returned by its id")
+	}
+	if _, exists := tpt.tpool.TransactionSetByID(modules.TransactionSetID{}); exists {
+		t.Fatal("unknown transaction set exists")
+	}
+	raw := tpt.tpool.RawTransactions()
+	if len(raw) != 1 {
+		t.Fatal("expected one raw transaction set, got", len(raw))
+	}
+	var decoded []types.Transaction
+	if err := encoding.Unmarshal(raw[0], &decoded); err != nil {
+		t.Fatal(err)
+	} else if crypto.HashObject(decoded) != crypto.Hash(setID) {
+		t.Fatal("raw transaction set does not match")
+	}
+}
+
 // TestBlockFeeEstimation checks that the fee estimation algorithm is reasonably
 // on target when the tpool is relying on blockchain based fee estimation.
 func TestFeeEstimation(t *testing.T) {
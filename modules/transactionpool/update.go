@@ -355,12 +355,12 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// after the consensus change.
 	tp.purge()
 
-	// prune transactions older than maxTxnAge.
+	// prune transactions older than the maximum transaction age.
 	for i, tSet := range unconfirmedSets {
 		var validTxns []types.Transaction
 		for _, txn := range tSet {
 			seenHeight, seen := tp.transactionHeights[txn.ID()]
-			if tp.blockHeight-seenHeight <= maxTxnAge || !seen {
+			if tp.blockHeight-seenHeight <= tp.settings.MaxTransactionAge || !seen {
 				validTxns = append(validTxns, txn)
 			} else {
 				delete(tp.transactionHeights, txn.ID())
@@ -400,6 +400,11 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// Which means that no other modules can require a tpool lock when
 	// processing consensus changes. Overall, the locking is pretty fragile and
 	// more rules need to be put in place.
+	//
+	// Transactions that are invalid because they double spend a transaction
+	// of the applied blocks, or depend on a transaction that does, are
+	// reported to subscribers as displaced.
+	displacers := confirmedSpenders(cc.AppliedBlocks)
 	for _, set := range unconfirmedSets {
 		for _, txn := range set {
 			err := tp.acceptTransactionSet([]types.Transaction{txn}, cc.TryTransactionSet)
@@ -407,16 +412,71 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 				// The transaction is no longer valid, delete it from the
 				// heights map to prevent a memory leak.
 				delete(tp.transactionHeights, txn.ID())
+				tp.recordConfirmedConflict(txn, displacers)
 			}
 		}
 	}
 
+	// Drop the orphans that waited too long for their parents, and accept the
+	// orphans whose parents were confirmed.
+	tp.pruneOrphans()
+	var appliedTxns []types.Transaction
+	for _, block := range cc.AppliedBlocks {
+		appliedTxns = append(appliedTxns, block.Transactions...)
+	}
+	for _, ts := range tp.acceptOrphans(appliedTxns, cc.TryTransactionSet) {
+		go tp.managedRelayTransactionSet(ts, "")
+	}
+
+	// Forget the sources of the transactions that left the pool, and the
+	// hosts that are allowed to relay as many sets as new hosts.
+	tp.pruneSources()
+	tp.pruneSourceAllowances()
+	tp.recordPoolSize()
+
 	// Inform subscribers that an update has executed.
 	tp.mu.Demote()
 	tp.updateSubscribersTransactions()
 	tp.mu.DemotedUnlock()
 }
 
+// confirmedSpenders maps the objects spent by the transactions of the blocks to
+// the transactions that spend them.
+func confirmedSpenders(blocks []types.Block) map[ObjectID]types.TransactionID {
+	spenders := make(map[ObjectID]types.TransactionID)
+	for _, block := range blocks {
+		for _, txn := range block.Transactions {
+			for _, oid := range spentObjectIDs(txn) {
+				spenders[oid] = txn.ID()
+			}
+		}
+	}
+	return spenders
+}
+
+// recordConfirmedConflict records an invalid transaction as displaced if it
+// spends an object in displacers, which maps the objects that can no longer be
+// spent to the confirmed transaction that prevents it. The objects created by
+// a displaced transaction are added to displacers, so that its children are
+// displaced as well.
+func (tp *TransactionPool) recordConfirmedConflict(txn types.Transaction, displacers map[ObjectID]types.TransactionID) {
+	for _, oid := range spentObjectIDs(txn) {
+		conflicting, exists := displacers[oid]
+		if !exists {
+			continue
+		}
+		tp.conflicts = append(tp.conflicts, modules.TransactionConflict{
+			DisplacedTransaction:   txn.ID(),
+			ConflictingTransaction: conflicting,
+			Confirmed:              true,
+		})
+		for _, created := range createdObjectIDs(txn) {
+			displacers[created] = conflicting
+		}
+		return
+	}
+}
+
 // PurgeTransactionPool deletes all transactions from the transaction pool.
 func (tp *TransactionPool) PurgeTransactionPool() {
 	tp.mu.Lock()
@@ -0,0 +1,85 @@
+package transactionpool
+
+// validate.go runs the acceptance checks of the transaction pool on a
+// transaction set without adding the set to the pool or relaying it. The
+// checks run against a copy of the pool, so that replacements, evictions and
+// merges with the sets in the pool are checked exactly as they would be when
+// the set is accepted.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// dryRunPool returns a copy of the transaction pool that transaction sets can
+// be accepted into without changing the pool itself.
+func (tp *TransactionPool) dryRunPool() *TransactionPool {
+	dry := &TransactionPool{
+		knownObjects:        make(map[ObjectID]TransactionSetID, len(tp.knownObjects)),
+		transactionHeights:  make(map[types.TransactionID]types.BlockHeight, len(tp.transactionHeights)),
+		transactionSets:     make(map[TransactionSetID][]types.Transaction, len(tp.transactionSets)),
+		transactionSetDiffs: make(map[TransactionSetID]*modules.ConsensusChange, len(tp.transactionSetDiffs)),
+		transactionListSize: tp.transactionListSize,
+		removalReasons:      make(map[TransactionSetID]modules.TransactionRemovalReason),
+
+		blockHeight: tp.blockHeight,
+		settings:    tp.settings,
+
+		db:   tp.db,
+		dbTx: tp.dbTx,
+		log:  tp.log,
+	}
+	for oid, id := range tp.knownObjects {
+		dry.knownObjects[oid] = id
+	}
+	for txid, height := range tp.transactionHeights {
+		dry.transactionHeights[txid] = height
+	}
+	for id, ts := range tp.transactionSets {
+		dry.transactionSets[id] = ts
+	}
+	for id, diff := range tp.transactionSetDiffs {
+		dry.transactionSetDiffs[id] = diff
+	}
+	return dry
+}
+
+// Validate runs the checks that AcceptTransactionSet runs on a transaction
+// set, without adding the set to the pool or relaying it. The fees of the set
+// are returned along with the error that AcceptTransactionSet would return.
+func (tp *TransactionPool) Validate(ts []types.Transaction) (modules.TransactionSetValidation, error) {
+	if err := tp.tg.Add(); err != nil {
+		return modules.TransactionSetValidation{}, err
+	}
+	defer tp.tg.Done()
+
+	// assert on consensus set to get special method
+	cs, ok := tp.consensusSet.(interface {
+		LockedTryTransactionSet(fn func(func(txns []types.Transaction) (modules.ConsensusChange, error)) error) error
+	})
+	if !ok {
+		return modules.TransactionSetValidation{}, errors.New("consensus set does not support LockedTryTransactionSet method")
+	}
+
+	var validation modules.TransactionSetValidation
+	err := cs.LockedTryTransactionSet(func(txnFn func(txns []types.Transaction) (modules.ConsensusChange, error)) error {
+		tp.mu.RLock()
+		defer tp.mu.RUnlock()
+		size := uint64(len(encoding.Marshal(ts)))
+		validation.Size = size
+		for _, txn := range ts {
+			for _, fee := range txn.MinerFees {
+				validation.Fees = validation.Fees.Add(fee)
+			}
+		}
+		if size > 0 {
+			validation.FeeRate = validation.Fees.Div64(size)
+		}
+		validation.RequiredFees = tp.requiredFeesToExtendTpool().Mul64(size)
+		return rejectTransactionSet(tp.dryRunPool().acceptTransactionSet(ts, txnFn))
+	})
+	return validation, err
+}
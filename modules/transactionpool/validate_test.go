@@ -0,0 +1,78 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestValidate checks that Validate reports the fees of a transaction set and
+// the errors that AcceptTransactionSet would return, without changing the
+// pool.
+func TestValidate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	value := types.SiacoinPrecision.Mul64(100)
+	fee := types.SiacoinPrecision
+	output, err := tpt.createSpendableOutput(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := spendTxn(output, value, fee, true)
+	ts := []types.Transaction{original}
+
+	// A valid set is reported with its fees, but not added to the pool.
+	validation, err := tpt.tpool.Validate(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := uint64(len(encoding.Marshal(ts)))
+	if validation.Size != size || !validation.Fees.Equals(fee) || !validation.FeeRate.Equals(fee.Div64(size)) {
+		t.Fatal("wrong validation of the set:", validation)
+	}
+	if len(tpt.tpool.TransactionList()) != 0 {
+		t.Fatal("validated set was added to the pool")
+	}
+
+	// A set that is already in the pool is a duplicate.
+	err = tpt.tpool.AcceptTransactionSet(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tpt.tpool.Validate(ts); err != modules.ErrDuplicateTransactionSet {
+		t.Fatal("expected a duplicate set, got", err)
+	}
+
+	// A valid replacement does not replace the original set.
+	replacement := spendTxn(output, value, fee.Mul64(2), false)
+	if _, err = tpt.tpool.Validate([]types.Transaction{replacement}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(original.ID()); !exists {
+		t.Fatal("validating a replacement removed the original set")
+	}
+	tpt.tpool.mu.Lock()
+	numRemovals := len(tpt.tpool.removalReasons)
+	tpt.tpool.mu.Unlock()
+	if numRemovals != 0 {
+		t.Fatal("validating a replacement recorded removals")
+	}
+
+	// An invalid set is rejected.
+	if _, err = tpt.tpool.Validate(nil); !rejectedWith(err, errEmptySet) {
+		t.Fatal("expected an empty set rejection, got", err)
+	}
+	lowFee := spendTxn(output, value, fee, false)
+	if _, err = tpt.tpool.Validate([]types.Transaction{lowFee}); !rejectedWith(err, errLowReplacementFees) {
+		t.Fatal("expected an insufficient fee rejection, got", err)
+	}
+}
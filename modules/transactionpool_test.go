@@ -28,6 +28,30 @@ func TestConsensusConflict(t *testing.T) {
 	}
 }
 
+// TestTransactionRejection checks that transaction rejections report the
+// transactions involved and are recognized as consensus conflicts only if
+// they were caused by one.
+func TestTransactionRejection(t *testing.T) {
+	t.Parallel()
+
+	tr := TransactionRejection{
+		Reason:        RejectionDoubleSpend,
+		Err:           NewConsensusConflict("problem"),
+		TransactionID: types.TransactionID{1},
+	}
+	expected := "consensus conflict: problem (transaction " + tr.TransactionID.String() + ")"
+	if tr.Error() != expected {
+		t.Error("wrong error message being reported in a rejection:", tr.Error())
+	}
+	if !IsConsensusConflict(tr) || !IsConsensusConflict(tr.Err) {
+		t.Error("consensus conflict was not recognized")
+	}
+	tr.Err = ErrLargeTransactionSet
+	if IsConsensusConflict(tr) {
+		t.Error("rejection without a consensus conflict was recognized as one")
+	}
+}
+
 // TestCalculateFee checks that the CalculateFee function is correctly tallying
 // the number of fees in a transaction set.
 func TestCalculateFee(t *testing.T) {
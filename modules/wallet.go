@@ -3,6 +3,7 @@ package modules
 import (
 	"bytes"
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/entropy-mnemonics"
 
@@ -11,6 +12,9 @@ import (
 )
 
 const (
+	// MaxMemoSize is the maximum size of a transaction memo in bytes.
+	MaxMemoSize = 128
+
 	// PublicKeysPerSeed define the number of public keys that get pregenerated
 	// for a seed at startup when searching for balances in the blockchain.
 	PublicKeysPerSeed = 2500
@@ -23,6 +27,45 @@ const (
 	WalletDir = "wallet"
 )
 
+const (
+	// TransactionDirectionIncoming is the direction of transactions that
+	// increase the wallet's balance.
+	TransactionDirectionIncoming TransactionDirection = "incoming"
+
+	// TransactionDirectionOutgoing is the direction of transactions that
+	// decrease the wallet's balance.
+	TransactionDirectionOutgoing TransactionDirection = "outgoing"
+
+	// TransactionDirectionInternal is the direction of transactions that do
+	// not change the wallet's balance, such as transactions that only move
+	// value between the wallet's own addresses.
+	TransactionDirectionInternal TransactionDirection = "internal"
+)
+
+const (
+	// FeePolicyEconomical pays the minimum fee recommended by the
+	// transaction pool, which may take longer to be confirmed.
+	FeePolicyEconomical FeePolicy = "economical"
+
+	// FeePolicyNormal pays the maximum fee recommended by the transaction
+	// pool.
+	FeePolicyNormal FeePolicy = "normal"
+
+	// FeePolicyPriority pays twice the maximum fee recommended by the
+	// transaction pool, for transactions that need to be confirmed quickly.
+	FeePolicyPriority FeePolicy = "priority"
+)
+
+const (
+	// ExportFormatCSV exports the wallet's history as comma-separated values
+	// with a header row.
+	ExportFormatCSV ExportFormat = "csv"
+
+	// ExportFormatJSON exports the wallet's history as a JSON array of
+	// WalletExportEntry objects.
+	ExportFormatJSON ExportFormat = "json"
+)
+
 var (
 	// ErrBadEncryptionKey is returned if the incorrect encryption key to a
 	// file is provided.
@@ -45,6 +88,12 @@ var (
 	// ErrWalletShutdown is returned when a method can't continue execution due
 	// to the wallet shutting down.
 	ErrWalletShutdown = errors.New("wallet is shutting down")
+
+	// MemoPrefix is the prefix of the arbitrary data that carries a
+	// transaction memo, such as the deposit ID that an exchange uses to
+	// credit a payment. The memo follows the prefix. The prefix starts with
+	// 'NonSia' so that memos are considered standard by the transaction pool.
+	MemoPrefix = append(PrefixNonSia[:], "Memo"...)
 )
 
 type (
@@ -94,6 +143,10 @@ type (
 	// Because of the block subsidy, a block is considered as a transaction.
 	// Since there is technically no transaction id for the block subsidy, the
 	// block id is used instead.
+	//
+	// ChangeOutputs are the ids of the outputs that return change to the
+	// wallet's change addresses. They are a part of the funds that the
+	// wallet spent, not funds that it received.
 	ProcessedTransaction struct {
 		Transaction           types.Transaction   `json:"transaction"`
 		TransactionID         types.TransactionID `json:"transactionid"`
@@ -102,8 +155,58 @@ type (
 
 		Inputs  []ProcessedInput  `json:"inputs"`
 		Outputs []ProcessedOutput `json:"outputs"`
+
+		ChangeOutputs []types.OutputID `json:"changeoutputs"`
+	}
+
+	// A PendingTransaction is an unconfirmed transaction of the wallet along
+	// with the time at which the wallet first saw it in the transaction pool.
+	// The age of the transaction is the time that has passed since then.
+	PendingTransaction struct {
+		ProcessedTransaction
+		FirstSeen types.Timestamp `json:"firstseen"`
 	}
 
+	// A ProcessedTransactionSummary describes how a processed transaction
+	// affects the wallet. The net change in the wallet's balance is the
+	// difference between the incoming and outgoing values, and its sign is
+	// given by the direction of the transaction. Change counts as neither
+	// incoming nor outgoing.
+	ProcessedTransactionSummary struct {
+		Direction        TransactionDirection `json:"direction"`
+		Confirmations    types.BlockHeight    `json:"confirmations"`
+		RelatedAddresses []types.UnlockHash   `json:"relatedaddresses"`
+		Fees             types.Currency       `json:"fees"`
+		IncomingSiacoins types.Currency       `json:"incomingsiacoins"`
+		OutgoingSiacoins types.Currency       `json:"outgoingsiacoins"`
+		IncomingSiafunds types.Currency       `json:"incomingsiafunds"`
+		OutgoingSiafunds types.Currency       `json:"outgoingsiafunds"`
+	}
+
+	// An AddressLabel is a label that the user has given to an address.
+	// Labels of addresses that do not belong to the wallet form the wallet's
+	// address book of frequent recipients.
+	AddressLabel struct {
+		Address       types.UnlockHash `json:"address"`
+		Label         string           `json:"label"`
+		WalletAddress bool             `json:"walletaddress"`
+	}
+
+	// A WalletOutput is a confirmed siacoin or siafund output that the
+	// wallet holds the keys for. Locked outputs are not used when the wallet
+	// funds transactions.
+	WalletOutput struct {
+		ID         types.OutputID   `json:"id"`
+		FundType   types.Specifier  `json:"fundtype"`
+		UnlockHash types.UnlockHash `json:"unlockhash"`
+		Value      types.Currency   `json:"value"`
+		Locked     bool             `json:"locked"`
+	}
+
+	// TransactionDirection indicates whether a transaction adds value to the
+	// wallet or removes value from it.
+	TransactionDirection string
+
 	// TransactionBuilder is used to construct custom transactions. A transaction
 	// builder is initialized via 'RegisterTransaction' and then can be modified by
 	// adding funds or other fields. The transaction is completed by calling
@@ -124,6 +227,12 @@ type (
 		// transaction failed.
 		FundSiacoins(amount types.Currency) error
 
+		// FundTransactionWithOutputs works like FundSiacoins, but spends
+		// exactly the specified siacoin outputs of the wallet instead of
+		// selecting them automatically. Any value beyond 'amount' is sent
+		// back to the wallet.
+		FundTransactionWithOutputs(amount types.Currency, outputs []types.SiacoinOutputID) error
+
 		// FundSiafunds will add a siafund input of exactly 'amount' to the
 		// transaction. A parent transaction may be needed to achieve an input
 		// with the correct value. The siafund input will not be signed until
@@ -202,6 +311,14 @@ type (
 		// transaction should be dropped.
 		Sign(wholeTransaction bool) ([]types.Transaction, error)
 
+		// SignWithCoveredFields is like Sign, but the signatures cover
+		// exactly the fields of the provided covered fields object. Fields
+		// that are not covered can still be changed after signing, which
+		// allows external modules to sign a transaction that other parties
+		// extend. The same restrictions on multiple calls apply as for
+		// 'Sign'.
+		SignWithCoveredFields(cf types.CoveredFields) ([]types.Transaction, error)
+
 		// UnconfirmedParents returns any unconfirmed parents the transaction set that
 		// is being built by the transaction builder could have.
 		UnconfirmedParents() ([]types.Transaction, error)
@@ -320,6 +437,19 @@ type (
 		// seed.
 		NextAddresses(uint64) ([]types.UnlockConditions, error)
 
+		// UnusedAddress returns the most recently generated address of the
+		// primary seed if it has not been used yet, and a new address
+		// otherwise.
+		UnusedAddress() (types.UnlockConditions, error)
+
+		// Subscribe adds a subscriber that is notified of the wallet's
+		// transactions. Transactions are reported as confirmed once they
+		// have the given number of confirmations.
+		Subscribe(subscriber WalletSubscriber, confirmations types.BlockHeight)
+
+		// Unsubscribe removes a subscriber from the wallet.
+		Unsubscribe(WalletSubscriber)
+
 		// PrimarySeed returns the unencrypted primary seed of the wallet,
 		// along with a uint64 indicating how many addresses may be safely
 		// generated from the seed.
@@ -331,6 +461,11 @@ type (
 		// outputs, minus the fee. If only siafunds were found, the fee is
 		// deducted from the wallet.
 		SweepSeed(seed Seed) (coins, funds types.Currency, err error)
+
+		// SweepKey scans the blockchain for outputs of the standard address
+		// of a secret key and creates a transaction that transfers them to
+		// the wallet. Like SweepSeed, this incurs a transaction fee.
+		SweepKey(sk crypto.SecretKey) (coins, funds types.Currency, err error)
 	}
 
 	// Wallet stores and manages siacoins and siafunds. The wallet file is
@@ -366,6 +501,10 @@ type (
 		// transactions related to a given address.
 		AddressUnconfirmedTransactions(types.UnlockHash) ([]ProcessedTransaction, error)
 
+		// MemoTransactions returns all of the confirmed transactions that
+		// carry the given memo.
+		MemoTransactions(memo string) ([]ProcessedTransaction, error)
+
 		// Transaction returns the transaction with the given id. The bool
 		// indicates whether the transaction is in the wallet database. The
 		// wallet only stores transactions that are related to the wallet.
@@ -376,14 +515,34 @@ type (
 		// included.
 		Transactions(startHeight types.BlockHeight, endHeight types.BlockHeight) ([]ProcessedTransaction, error)
 
+		// Export returns the accounting report of the transactions that
+		// were confirmed at heights [startHeight, endHeight] in the given
+		// format.
+		Export(format ExportFormat, startHeight, endHeight types.BlockHeight) ([]byte, error)
+
 		// UnconfirmedTransactions returns all unconfirmed transactions
 		// relative to the wallet.
 		UnconfirmedTransactions() ([]ProcessedTransaction, error)
 
+		// PendingTransactions returns all unconfirmed transactions relative
+		// to the wallet, along with the time at which the wallet first saw
+		// each of them in the transaction pool.
+		PendingTransactions() ([]PendingTransaction, error)
+
+		// RejectedTransactions returns the unconfirmed transactions of the
+		// wallet that were displaced from the transaction pool by
+		// conflicting transactions, along with the transactions that they
+		// conflict with.
+		RejectedTransactions() ([]TransactionConflict, error)
+
 		// RegisterTransaction takes a transaction and its parents and returns
 		// a TransactionBuilder which can be used to expand the transaction.
 		RegisterTransaction(t types.Transaction, parents []types.Transaction) (TransactionBuilder, error)
 
+		// Rescan clears the outputs and transactions tracked by the wallet
+		// and rescans the blockchain to rediscover them.
+		Rescan() error
+
 		// Rescanning reports whether the wallet is currently rescanning the
 		// blockchain.
 		Rescanning() (bool, error)
@@ -404,9 +563,37 @@ type (
 		// are also returned to the caller.
 		SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
 
+		// SendSiacoinsWithFee sends siacoins to an address like
+		// SendSiacoins, but pays the specified total fee instead of the fee
+		// of the wallet's fee policy.
+		SendSiacoinsWithFee(amount types.Currency, dest types.UnlockHash, fee types.Currency) ([]types.Transaction, error)
+
+		// SendSiacoinsWithMemo sends siacoins to an address like
+		// SendSiacoins, and attaches the memo to the transaction.
+		SendSiacoinsWithMemo(amount types.Currency, dest types.UnlockHash, memo string) ([]types.Transaction, error)
+
 		// SendSiacoinsMulti sends coins to multiple addresses.
 		SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error)
 
+		// SendSiacoinsMultiWithFee sends coins to multiple addresses, paying
+		// the specified total fee.
+		SendSiacoinsMultiWithFee(outputs []types.SiacoinOutput, fee types.Currency) ([]types.Transaction, error)
+
+		// SendSiacoinsMultiConfirmed and SendSiacoinsMultiWithFeeConfirmed
+		// send coins like SendSiacoinsMulti and SendSiacoinsMultiWithFee,
+		// but are not limited by the wallet's spending policy. They are used
+		// to confirm sends that the policy rejected.
+		SendSiacoinsMultiConfirmed(outputs []types.SiacoinOutput) ([]types.Transaction, error)
+		SendSiacoinsMultiWithFeeConfirmed(outputs []types.SiacoinOutput, fee types.Currency) ([]types.Transaction, error)
+
+		// SpendingPolicy returns the policy that limits the siacoins sent by
+		// the wallet.
+		SpendingPolicy() (SpendingPolicy, error)
+
+		// SetSpendingPolicy sets the policy that limits the siacoins sent by
+		// the wallet.
+		SetSpendingPolicy(SpendingPolicy) error
+
 		// SendSiafunds is a tool for sending siafunds from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and
@@ -416,11 +603,187 @@ type (
 		// DustThreshold returns the quantity per byte below which a Currency is
 		// considered to be Dust.
 		DustThreshold() (types.Currency, error)
+
+		// AddWatchAddresses instructs the wallet to track a set of addresses
+		// without holding their keys. Their outputs are counted in the
+		// wallet's balance and history, but cannot be spent. If none of the
+		// addresses have appeared in the blockchain, unused may be set to
+		// true to skip rescanning the blockchain.
+		AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
+
+		// RemoveWatchAddresses instructs the wallet to stop tracking a set of
+		// watched addresses. If none of the addresses have appeared in the
+		// blockchain, unused may be set to true to skip rescanning the
+		// blockchain.
+		RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) error
+
+		// WatchAddresses returns the set of addresses that the wallet is
+		// watching.
+		WatchAddresses() ([]types.UnlockHash, error)
+
+		// AddressLabels returns the labels of the wallet's addresses and the
+		// entries of its address book, sorted by label.
+		AddressLabels() ([]AddressLabel, error)
+
+		// SetAddressLabel sets the label of an address, which may belong to
+		// the wallet or be added to its address book. An empty label removes
+		// the label of the address.
+		SetAddressLabel(addr types.UnlockHash, label string) error
+
+		// SpendableOutputs returns the confirmed outputs that the wallet can
+		// spend, including locked outputs.
+		SpendableOutputs() ([]WalletOutput, error)
+
+		// LockOutputs prevents the wallet from spending a set of outputs when
+		// it funds transactions, until they are unlocked with UnlockOutputs.
+		LockOutputs(ids []types.OutputID) error
+
+		// UnlockOutputs allows the wallet to spend a set of locked outputs
+		// again.
+		UnlockOutputs(ids []types.OutputID) error
+
+		// SendSiacoinsWithOutputs works like SendSiacoins, but funds the
+		// transaction with exactly the specified outputs.
+		SendSiacoinsWithOutputs(amount types.Currency, dest types.UnlockHash, outputs []types.SiacoinOutputID) ([]types.Transaction, error)
+
+		// AddMultisigAddress creates and watches an M-of-N multisig address
+		// from a set of public keys, returning its unlock conditions. If the
+		// address has not appeared in the blockchain, unused may be set to
+		// true to skip rescanning the blockchain.
+		AddMultisigAddress(publicKeys []types.SiaPublicKey, signaturesRequired uint64, unused bool) (types.UnlockConditions, error)
+
+		// AddUnlockConditions stores the unlock conditions of an address that
+		// the wallet does not hold the keys for, so that unsigned transactions
+		// spending from the address can be built.
+		AddUnlockConditions(uc types.UnlockConditions) error
+
+		// UnlockConditions returns the unlock conditions of an address known
+		// to the wallet.
+		UnlockConditions(addr types.UnlockHash) (types.UnlockConditions, error)
+
+		// UnsignedTransaction builds a transaction sending to outputs and
+		// paying fee, funded by the outputs of watched addresses whose unlock
+		// conditions were added. The transaction is returned without
+		// signatures, along with the parent IDs of the signatures that need
+		// to be signed.
+		UnsignedTransaction(outputs []types.SiacoinOutput, fee types.Currency) (txn types.Transaction, toSign []crypto.Hash, err error)
+
+		// SignTransaction signs the TransactionSignatures of txn whose parent
+		// IDs are in toSign, using the keys of the wallet. Signatures that the
+		// wallet does not hold the keys for are left for the other cosigners
+		// of a multisig input.
+		SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error
+
+		// SignMessage signs an off-chain message with the key of an address
+		// of the wallet, proving that the wallet controls the address. The
+		// signature can be checked with types.VerifyMessage.
+		SignMessage(addr types.UnlockHash, msg []byte) (types.MessageSignature, error)
+	}
+
+	// A WalletSubscriber is notified of events that affect the transactions
+	// of the wallet.
+	WalletSubscriber interface {
+		// OnReceive is called when an unconfirmed transaction that adds
+		// siacoins or siafunds to the wallet enters the transaction pool.
+		OnReceive(ProcessedTransaction)
+
+		// OnConfirm is called when a wallet transaction reaches the number
+		// of confirmations that the subscriber subscribed with.
+		OnConfirm(ProcessedTransaction)
+
+		// OnRevert is called when a confirmed wallet transaction is removed
+		// from the blockchain by a reorg.
+		OnRevert(ProcessedTransaction)
 	}
 
 	// WalletSettings control the behavior of the Wallet.
 	WalletSettings struct {
 		NoDefrag bool `json:"noDefrag"`
+
+		// DefragThreshold is the number of outputs the wallet is allowed
+		// before it consolidates them into a single output; a threshold of
+		// zero selects the default. DefragMaxFee is the highest fee per byte
+		// at which the wallet defrags, so that defragging waits for fees to
+		// be low. A fee of zero means no limit.
+		DefragThreshold uint64         `json:"defragthreshold"`
+		DefragMaxFee    types.Currency `json:"defragmaxfee"`
+
+		// AutoLockTimeout is the amount of time after which an unlocked
+		// wallet is locked again. A timeout of zero disables auto-locking.
+		AutoLockTimeout time.Duration `json:"autolocktimeout"`
+
+		// SiafundClaimThreshold is the siacoin claim balance of the wallet's
+		// siafunds at which the wallet sends the siafunds to itself, which
+		// pays the claim balance out to the wallet. A threshold of zero
+		// disables claiming.
+		SiafundClaimThreshold types.Currency `json:"siafundclaimthreshold"`
+
+		// FeePolicy determines the fee per byte that the wallet pays when
+		// sending coins without an explicit fee. The empty FeePolicy selects
+		// FeePolicyNormal.
+		FeePolicy FeePolicy `json:"feepolicy"`
+
+		// NoSpendUnconfirmed prevents the wallet from funding transactions
+		// with the change of its own unconfirmed transactions, so that
+		// consecutive sends don't depend on each other. Outputs that are
+		// selected explicitly can still be unconfirmed.
+		NoSpendUnconfirmed bool `json:"nospendunconfirmed"`
+
+		// DustThreshold is the value below which the wallet does not spend
+		// outputs, and below which change is added to the miner fee instead
+		// of creating a change output. A threshold of zero selects three
+		// times the minimum fee per byte recommended by the transaction pool.
+		// Sends of outputs below the threshold are only rejected if it was
+		// set explicitly.
+		DustThreshold types.Currency `json:"dustthreshold"`
+	}
+
+	// A SpendingPolicy limits the siacoins that the wallet sends with the
+	// SendSiacoins methods, so that the wallet can be used by automation that
+	// is not fully trusted. Sends that violate the policy are rejected unless
+	// they are confirmed. Transactions built with a TransactionBuilder, such
+	// as file contracts, are not limited, but count towards MaxPerDay.
+	SpendingPolicy struct {
+		// MaxPerTransaction is the highest value, including the miner fee,
+		// that a single send may spend. Zero means no limit.
+		MaxPerTransaction types.Currency `json:"maxpertransaction"`
+
+		// MaxPerDay is the highest value that the wallet may spend in any 24
+		// hours, counting the siacoins that left the wallet in its
+		// transactions of the last 24 hours. Zero means no limit.
+		MaxPerDay types.Currency `json:"maxperday"`
+
+		// Whitelist is the set of addresses that the wallet may send to,
+		// besides its own addresses. An empty whitelist allows any address.
+		Whitelist []types.UnlockHash `json:"whitelist"`
+	}
+
+	// A FeePolicy selects a transaction fee from the range of fees
+	// recommended by the transaction pool.
+	FeePolicy string
+
+	// An ExportFormat is a format of the wallet's accounting report.
+	ExportFormat string
+
+	// A WalletExportEntry is a confirmed transaction of the wallet as it
+	// appears in the wallet's accounting report. The amounts are those of the
+	// transaction's summary, so change is not counted. Fees are only reported
+	// for transactions that the wallet paid for. Balance is the wallet's
+	// siacoin balance after the transaction, counting miner payouts from the
+	// block that created them.
+	WalletExportEntry struct {
+		TransactionID    types.TransactionID  `json:"transactionid"`
+		Height           types.BlockHeight    `json:"height"`
+		Timestamp        types.Timestamp      `json:"timestamp"`
+		Direction        TransactionDirection `json:"direction"`
+		IncomingSiacoins types.Currency       `json:"incomingsiacoins"`
+		OutgoingSiacoins types.Currency       `json:"outgoingsiacoins"`
+		Fees             types.Currency       `json:"fees"`
+		IncomingSiafunds types.Currency       `json:"incomingsiafunds"`
+		OutgoingSiafunds types.Currency       `json:"outgoingsiafunds"`
+		Counterparties   []types.UnlockHash   `json:"counterparties"`
+		Balance          types.Currency       `json:"balance"`
+		Memo             string               `json:"memo,omitempty"`
 	}
 )
 
@@ -430,6 +793,90 @@ func CalculateWalletTransactionID(tid types.TransactionID, oid types.OutputID) W
 	return WalletTransactionID(crypto.HashAll(tid, oid))
 }
 
+// Summary returns a summary of how the transaction affects the wallet,
+// counting confirmations relative to the given block height. Unconfirmed
+// transactions have zero confirmations. Siafunds only determine the direction
+// of transactions that do not change the wallet's siacoin balance.
+func (pt ProcessedTransaction) Summary(height types.BlockHeight) ProcessedTransactionSummary {
+	var pts ProcessedTransactionSummary
+	if pt.ConfirmationHeight <= height {
+		pts.Confirmations = height - pt.ConfirmationHeight + 1
+	}
+
+	seen := make(map[types.UnlockHash]struct{})
+	addRelated := func(walletAddress bool, addr types.UnlockHash) {
+		if _, exists := seen[addr]; exists || walletAddress || addr == (types.UnlockHash{}) {
+			return
+		}
+		seen[addr] = struct{}{}
+		pts.RelatedAddresses = append(pts.RelatedAddresses, addr)
+	}
+	for _, input := range pt.Inputs {
+		addRelated(input.WalletAddress, input.RelatedAddress)
+		if !input.WalletAddress {
+			continue
+		}
+		if input.FundType == types.SpecifierSiafundInput {
+			pts.OutgoingSiafunds = pts.OutgoingSiafunds.Add(input.Value)
+		} else {
+			pts.OutgoingSiacoins = pts.OutgoingSiacoins.Add(input.Value)
+		}
+	}
+	change := make(map[types.OutputID]struct{}, len(pt.ChangeOutputs))
+	for _, id := range pt.ChangeOutputs {
+		change[id] = struct{}{}
+	}
+	for _, output := range pt.Outputs {
+		if output.FundType == types.SpecifierMinerFee {
+			pts.Fees = pts.Fees.Add(output.Value)
+			continue
+		}
+		addRelated(output.WalletAddress, output.RelatedAddress)
+		if !output.WalletAddress {
+			continue
+		}
+		if _, isChange := change[output.ID]; isChange {
+			if output.FundType == types.SpecifierSiafundOutput && pts.OutgoingSiafunds.Cmp(output.Value) >= 0 {
+				pts.OutgoingSiafunds = pts.OutgoingSiafunds.Sub(output.Value)
+				continue
+			} else if output.FundType == types.SpecifierSiacoinOutput && pts.OutgoingSiacoins.Cmp(output.Value) >= 0 {
+				pts.OutgoingSiacoins = pts.OutgoingSiacoins.Sub(output.Value)
+				continue
+			}
+		}
+		if output.FundType == types.SpecifierSiafundOutput {
+			pts.IncomingSiafunds = pts.IncomingSiafunds.Add(output.Value)
+		} else {
+			pts.IncomingSiacoins = pts.IncomingSiacoins.Add(output.Value)
+		}
+	}
+
+	direction := pts.IncomingSiacoins.Cmp(pts.OutgoingSiacoins)
+	if direction == 0 {
+		direction = pts.IncomingSiafunds.Cmp(pts.OutgoingSiafunds)
+	}
+	switch direction {
+	case 1:
+		pts.Direction = TransactionDirectionIncoming
+	case -1:
+		pts.Direction = TransactionDirectionOutgoing
+	default:
+		pts.Direction = TransactionDirectionInternal
+	}
+	return pts
+}
+
+// TransactionMemo returns the memo of a transaction. The bool is false if the
+// transaction does not carry a memo.
+func TransactionMemo(txn types.Transaction) (string, bool) {
+	for _, arb := range txn.ArbitraryData {
+		if bytes.HasPrefix(arb, MemoPrefix) {
+			return string(arb[len(MemoPrefix):]), true
+		}
+	}
+	return "", false
+}
+
 // SeedToString converts a wallet seed to a human friendly string.
 func SeedToString(seed Seed, did mnemonics.DictionaryID) (string, error) {
 	fullChecksum := crypto.HashObject(seed)
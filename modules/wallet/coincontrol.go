@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// SpendableOutputs returns the confirmed siacoin and siafund outputs that the
+// wallet holds the keys for and has not spent recently, including outputs
+// that are locked.
+func (w *Wallet) SpendableOutputs() ([]modules.WalletOutput, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []modules.WalletOutput
+	addOutput := func(id types.OutputID, fundType types.Specifier, uh types.UnlockHash, value types.Currency) {
+		if _, exists := w.keys[uh]; !exists {
+			return
+		}
+		spendHeight, err := dbGetSpentOutput(w.dbTx, id)
+		if err == nil && spendHeight+RespendTimeout > consensusHeight {
+			return
+		}
+		_, locked := w.lockedOutputs[id]
+		outputs = append(outputs, modules.WalletOutput{
+			ID:         id,
+			FundType:   fundType,
+			UnlockHash: uh,
+			Value:      value,
+			Locked:     locked,
+		})
+	}
+	err = dbForEachSiacoinOutput(w.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		addOutput(types.OutputID(scoid), types.SpecifierSiacoinOutput, sco.UnlockHash, sco.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = dbForEachSiafundOutput(w.dbTx, func(sfoid types.SiafundOutputID, sfo types.SiafundOutput) {
+		addOutput(types.OutputID(sfoid), types.SpecifierSiafundOutput, sfo.UnlockHash, sfo.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// LockOutputs prevents the wallet from spending a set of outputs when it funds
+// transactions, until they are unlocked with UnlockOutputs.
+func (w *Wallet) LockOutputs(ids []types.OutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		w.lockedOutputs[id] = struct{}{}
+	}
+	return dbPutLockedOutputs(w.dbTx, w.lockedOutputList())
+}
+
+// UnlockOutputs allows the wallet to spend a set of locked outputs again.
+func (w *Wallet) UnlockOutputs(ids []types.OutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		delete(w.lockedOutputs, id)
+	}
+	return dbPutLockedOutputs(w.dbTx, w.lockedOutputList())
+}
+
+// lockedOutputList returns the locked outputs sorted in byte-order.
+func (w *Wallet) lockedOutputList() []types.OutputID {
+	ids := make([]types.OutputID, 0, len(w.lockedOutputs))
+	for id := range w.lockedOutputs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return ids
+}
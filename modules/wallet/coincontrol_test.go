@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestCoinControl checks that locked outputs are not used to fund
+// transactions and that transactions can be funded by specific outputs.
+func TestCoinControl(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Mine a block so that the wallet has multiple matured outputs, and
+	// collect the siacoin outputs of the wallet.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scoids []types.SiacoinOutputID
+	var ids []types.OutputID
+	var total types.Currency
+	for _, o := range outputs {
+		if o.FundType != types.SpecifierSiacoinOutput {
+			continue
+		}
+		if o.Locked {
+			t.Fatal("output should not be locked")
+		}
+		scoids = append(scoids, types.SiacoinOutputID(o.ID))
+		ids = append(ids, o.ID)
+		total = total.Add(o.Value)
+	}
+	if len(scoids) < 2 {
+		t.Fatal("expected at least two siacoin outputs, got", len(scoids))
+	}
+
+	// Lock all but the first output. The wallet should not be able to fund
+	// more than the value of the first output.
+	err = wt.wallet.LockOutputs(ids[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err = wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := 0
+	for _, o := range outputs {
+		if o.Locked {
+			locked++
+		}
+	}
+	if locked != len(ids)-1 {
+		t.Fatalf("expected %v locked outputs, got %v", len(ids)-1, locked)
+	}
+	b, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.FundSiacoins(total.Sub(types.SiacoinPrecision)); err != modules.ErrLowBalance {
+		t.Fatal("expected ErrLowBalance, got", err)
+	}
+	b.Drop()
+
+	// Funding with a locked output should fail.
+	b, err = wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.FundTransactionWithOutputs(types.SiacoinPrecision, scoids[1:2]); err != errLockedOutput {
+		t.Fatal("expected errLockedOutput, got", err)
+	}
+	if err := b.FundTransactionWithOutputs(types.SiacoinPrecision, []types.SiacoinOutputID{{1}}); err != errUnknownOutput {
+		t.Fatal("expected errUnknownOutput, got", err)
+	}
+	if err := b.FundTransactionWithOutputs(types.SiacoinPrecision, []types.SiacoinOutputID{scoids[0], scoids[0]}); err != errDuplicateOutput {
+		t.Fatal("expected errDuplicateOutput, got", err)
+	}
+	b.Drop()
+
+	// Unlock the outputs and send coins funded by the second output.
+	err = wt.wallet.UnlockOutputs(ids[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendSiacoinsWithOutputs(types.SiacoinPrecision, uc.UnlockHash(), scoids[1:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 || len(txns[0].SiacoinInputs) != 1 || txns[0].SiacoinInputs[0].ParentID != scoids[1] {
+		t.Fatal("transaction was not funded by the specified output")
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The locked outputs should persist.
+	err = wt.wallet.LockOutputs(ids[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if _, locked := w.lockedOutputs[ids[0]]; !locked || len(w.lockedOutputs) != 1 {
+		t.Fatal("locked outputs were not persisted")
+	}
+}
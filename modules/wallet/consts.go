@@ -15,6 +15,12 @@ const (
 	// defragThreshold is the number of outputs a wallet is allowed before it is
 	// defragmented.
 	defragThreshold = 50
+
+	// changeIndexOffset is the seed index of the first change address. Change
+	// addresses are derived from the upper half of the seed's index space,
+	// which the addresses handed out by NextAddress never reach, so that the
+	// wallet can tell change apart from the funds it received.
+	changeIndexOffset = uint64(1) << 63
 )
 
 var (
@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"reflect"
@@ -16,6 +17,10 @@ import (
 )
 
 var (
+	// bucketAddressLabels maps an UnlockHash to the label that the user has
+	// given it. Labels of addresses that do not belong to the wallet form its
+	// address book.
+	bucketAddressLabels = []byte("bucketAddressLabels")
 	// bucketProcessedTransactions stores ProcessedTransactions in
 	// chronological order. Only transactions relevant to the wallet are
 	// stored. The key of this bucket is an autoincrementing integer.
@@ -26,6 +31,9 @@ var (
 	// bucketAddrTransactions maps an UnlockHash to the
 	// ProcessedTransactions that it appears in.
 	bucketAddrTransactions = []byte("bucketAddrTransactions")
+	// bucketMemoTransactions maps a memo to the ProcessedTransactions that
+	// carry it.
+	bucketMemoTransactions = []byte("bucketMemoTransactions")
 	// bucketSiacoinOutputs maps a SiacoinOutputID to its SiacoinOutput. Only
 	// outputs that the wallet controls are stored. The wallet uses these
 	// outputs to fund transactions.
@@ -39,32 +47,46 @@ var (
 	// these outputs so that it can reuse them if they are not confirmed on
 	// the blockchain.
 	bucketSpentOutputs = []byte("bucketSpentOutputs")
+	// bucketUnlockConditions maps an UnlockHash to the UnlockConditions that
+	// it was derived from. Only unlock conditions imported for addresses that
+	// the wallet does not hold keys for are stored, so that the wallet can
+	// build unsigned transactions spending from them.
+	bucketUnlockConditions = []byte("bucketUnlockConditions")
 	// bucketWallet contains various fields needed by the wallet, such as its
 	// UID, EncryptionVerification, and PrimarySeedFile.
 	bucketWallet = []byte("bucketWallet")
 
 	dbBuckets = [][]byte{
+		bucketAddressLabels,
 		bucketProcessedTransactions,
 		bucketProcessedTxnIndex,
 		bucketAddrTransactions,
+		bucketMemoTransactions,
 		bucketSiacoinOutputs,
 		bucketSiafundOutputs,
 		bucketSpentOutputs,
+		bucketUnlockConditions,
 		bucketWallet,
 	}
 
 	errNoKey = errors.New("key does not exist")
 
 	// these keys are used in bucketWallet
+	keyAutoLockTimeout        = []byte("keyAutoLockTimeout")
 	keyAuxiliarySeedFiles     = []byte("keyAuxiliarySeedFiles")
+	keyChangeProgress         = []byte("keyChangeProgress")
 	keyConsensusChange        = []byte("keyConsensusChange")
 	keyConsensusHeight        = []byte("keyConsensusHeight")
 	keyEncryptionVerification = []byte("keyEncryptionVerification")
+	keyLockedOutputs          = []byte("keyLockedOutputs")
 	keyPrimarySeedFile        = []byte("keyPrimarySeedFile")
 	keyPrimarySeedProgress    = []byte("keyPrimarySeedProgress")
+	keySchemaVersion          = []byte("keySchemaVersion")
 	keySiafundPool            = []byte("keySiafundPool")
 	keySpendableKeyFiles      = []byte("keySpendableKeyFiles")
+	keySpendingPolicy         = []byte("keySpendingPolicy")
 	keyUID                    = []byte("keyUID")
+	keyWatchedAddrs           = []byte("keyWatchedAddrs")
 )
 
 // threadedDBUpdate commits the active database transaction and starts a new
@@ -141,13 +163,42 @@ func dbReset(tx *bolt.Tx) error {
 	wb.Put(keyConsensusHeight, encoding.Marshal(uint64(0)))
 	wb.Put(keyAuxiliarySeedFiles, encoding.Marshal([]seedFile{}))
 	wb.Put(keySpendableKeyFiles, encoding.Marshal([]spendableKeyFile{}))
+	wb.Put(keyWatchedAddrs, encoding.Marshal([]types.UnlockHash{}))
+	wb.Put(keyLockedOutputs, encoding.Marshal([]types.OutputID{}))
 	dbPutConsensusHeight(tx, 0)
 	dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning)
 	dbPutSiafundPool(tx, types.ZeroCurrency)
+	dbPutSchemaVersion(tx, uint64(len(dbMigrations)))
 
 	return nil
 }
 
+// dbResetTracked wipes the outputs and transactions tracked by the wallet
+// while keeping its seeds, keys, address labels and imported unlock
+// conditions, so that they can be rediscovered by rescanning the blockchain.
+func dbResetTracked(tx *bolt.Tx) error {
+	for _, bucket := range dbBuckets {
+		if bytes.Equal(bucket, bucketWallet) || bytes.Equal(bucket, bucketUnlockConditions) || bytes.Equal(bucket, bucketAddressLabels) {
+			continue
+		}
+		err := tx.DeleteBucket(bucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket(bucket)
+		if err != nil {
+			return err
+		}
+	}
+	if err := dbPutConsensusHeight(tx, 0); err != nil {
+		return err
+	}
+	if err := dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning); err != nil {
+		return err
+	}
+	return dbPutSiafundPool(tx, types.ZeroCurrency)
+}
+
 // dbPut is a helper function for storing a marshalled key/value pair.
 func dbPut(b *bolt.Bucket, key, val interface{}) error {
 	return b.Put(encoding.Marshal(key), encoding.Marshal(val))
@@ -232,6 +283,28 @@ func dbDeleteSpentOutput(tx *bolt.Tx, id types.OutputID) error {
 	return dbDelete(tx.Bucket(bucketSpentOutputs), id)
 }
 
+func dbPutUnlockConditions(tx *bolt.Tx, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketUnlockConditions), uc.UnlockHash(), uc)
+}
+func dbGetUnlockConditions(tx *bolt.Tx, addr types.UnlockHash) (uc types.UnlockConditions, err error) {
+	err = dbGet(tx.Bucket(bucketUnlockConditions), addr, &uc)
+	return
+}
+
+func dbPutAddressLabel(tx *bolt.Tx, addr types.UnlockHash, label string) error {
+	return dbPut(tx.Bucket(bucketAddressLabels), addr, label)
+}
+func dbGetAddressLabel(tx *bolt.Tx, addr types.UnlockHash) (label string, err error) {
+	err = dbGet(tx.Bucket(bucketAddressLabels), addr, &label)
+	return
+}
+func dbDeleteAddressLabel(tx *bolt.Tx, addr types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketAddressLabels), addr)
+}
+func dbForEachAddressLabel(tx *bolt.Tx, fn func(types.UnlockHash, string)) error {
+	return dbForEach(tx.Bucket(bucketAddressLabels), fn)
+}
+
 func dbPutAddrTransactions(tx *bolt.Tx, addr types.UnlockHash, txns []uint64) error {
 	return dbPut(tx.Bucket(bucketAddrTransactions), addr, txns)
 }
@@ -280,6 +353,59 @@ func dbAddProcessedTransactionAddrs(tx *bolt.Tx, pt modules.ProcessedTransaction
 	return nil
 }
 
+func dbPutMemoTransactions(tx *bolt.Tx, memo string, txns []uint64) error {
+	return dbPut(tx.Bucket(bucketMemoTransactions), memo, txns)
+}
+func dbGetMemoTransactions(tx *bolt.Tx, memo string) (txns []uint64, err error) {
+	err = dbGet(tx.Bucket(bucketMemoTransactions), memo, &txns)
+	return
+}
+
+// dbAddProcessedTransactionMemo updates bucketMemoTransactions to associate
+// the memo of pt, if it has one, with txn, which is assumed to be pt's index
+// in bucketProcessedTransactions.
+func dbAddProcessedTransactionMemo(tx *bolt.Tx, pt modules.ProcessedTransaction, txn uint64) error {
+	memo, ok := modules.TransactionMemo(pt.Transaction)
+	if !ok {
+		return nil
+	}
+	txns, err := dbGetMemoTransactions(tx, memo)
+	if err != nil && err != errNoKey {
+		return err
+	}
+	for _, i := range txns {
+		if i == txn {
+			return nil
+		}
+	}
+	return dbPutMemoTransactions(tx, memo, append(txns, txn))
+}
+
+// dbDeleteProcessedTransactionMemo removes txn from the transactions
+// associated with the memo of pt.
+func dbDeleteProcessedTransactionMemo(tx *bolt.Tx, pt modules.ProcessedTransaction, txn uint64) error {
+	memo, ok := modules.TransactionMemo(pt.Transaction)
+	if !ok {
+		return nil
+	}
+	txns, err := dbGetMemoTransactions(tx, memo)
+	if err == errNoKey {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := range txns {
+		if txns[i] == txn {
+			txns = append(txns[:i], txns[i+1:]...)
+			break
+		}
+	}
+	if len(txns) == 0 {
+		return dbDelete(tx.Bucket(bucketMemoTransactions), memo)
+	}
+	return dbPutMemoTransactions(tx, memo, txns)
+}
+
 // bucketProcessedTransactions works a little differently: the key is
 // meaningless, only used to order the transactions chronologically.
 
@@ -287,6 +413,20 @@ func dbAddProcessedTransactionAddrs(tx *bolt.Tx, pt modules.ProcessedTransaction
 func decodeProcessedTransaction(ptBytes []byte, pt *modules.ProcessedTransaction) error {
 	err := encoding.Unmarshal(ptBytes, pt)
 	if err != nil {
+		// COMPATv1.3.3: try decoding into the transaction type without
+		// change outputs
+		var v133pt v133ProcessedTransaction
+		if encoding.Unmarshal(ptBytes, &v133pt) == nil {
+			*pt = modules.ProcessedTransaction{
+				Transaction:           v133pt.Transaction,
+				TransactionID:         v133pt.TransactionID,
+				ConfirmationHeight:    v133pt.ConfirmationHeight,
+				ConfirmationTimestamp: v133pt.ConfirmationTimestamp,
+				Inputs:                v133pt.Inputs,
+				Outputs:               v133pt.Outputs,
+			}
+			return nil
+		}
 		// COMPATv1.2.1: try decoding into old transaction type
 		var oldpt v121ProcessedTransaction
 		err = encoding.Unmarshal(ptBytes, &oldpt)
@@ -345,6 +485,11 @@ func dbAppendProcessedTransaction(tx *bolt.Tx, pt modules.ProcessedTransaction)
 	if err = dbAddProcessedTransactionAddrs(tx, pt, key); err != nil {
 		return errors.AddContext(err, "failed to add processed transaction to addresses in database")
 	}
+
+	// and index its memo
+	if err = dbAddProcessedTransactionMemo(tx, pt, key); err != nil {
+		return errors.AddContext(err, "failed to add processed transaction to memos in database")
+	}
 	return nil
 }
 
@@ -370,6 +515,9 @@ func dbDeleteLastProcessedTransaction(tx *bolt.Tx) error {
 	// Delete the last processed txn and decrement the sequence.
 	b := tx.Bucket(bucketProcessedTransactions)
 	seq := b.Sequence()
+	if err := dbDeleteProcessedTransactionMemo(tx, pt, seq); err != nil {
+		return errors.AddContext(err, "couldn't delete txn memo")
+	}
 	keyBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(keyBytes, seq)
 	return errors.Compose(b.SetSequence(seq-1), b.Delete(keyBytes))
@@ -466,6 +614,23 @@ func dbPutConsensusHeight(tx *bolt.Tx, height types.BlockHeight) error {
 	return tx.Bucket(bucketWallet).Put(keyConsensusHeight, encoding.Marshal(height))
 }
 
+// dbGetSchemaVersion returns the number of migrations that have been applied
+// to the database.
+func dbGetSchemaVersion(tx *bolt.Tx) (version uint64, err error) {
+	versionBytes := tx.Bucket(bucketWallet).Get(keySchemaVersion)
+	if versionBytes == nil {
+		return 0, nil
+	}
+	err = encoding.Unmarshal(versionBytes, &version)
+	return
+}
+
+// dbPutSchemaVersion stores the number of migrations that have been applied to
+// the database.
+func dbPutSchemaVersion(tx *bolt.Tx, version uint64) error {
+	return tx.Bucket(bucketWallet).Put(keySchemaVersion, encoding.Marshal(version))
+}
+
 // dbGetSiafundPool returns the value of the siafund pool.
 func dbGetSiafundPool(tx *bolt.Tx) (pool types.Currency, err error) {
 	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keySiafundPool), &pool)
@@ -477,6 +642,77 @@ func dbPutSiafundPool(tx *bolt.Tx, pool types.Currency) error {
 	return tx.Bucket(bucketWallet).Put(keySiafundPool, encoding.Marshal(pool))
 }
 
+// dbGetWatchedAddresses retrieves the set of watched addresses.
+func dbGetWatchedAddresses(tx *bolt.Tx) (addrs []types.UnlockHash, err error) {
+	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keyWatchedAddrs), &addrs)
+	return
+}
+
+// dbPutWatchedAddresses stores the set of watched addresses.
+func dbPutWatchedAddresses(tx *bolt.Tx, addrs []types.UnlockHash) error {
+	return tx.Bucket(bucketWallet).Put(keyWatchedAddrs, encoding.Marshal(addrs))
+}
+
+// dbGetLockedOutputs retrieves the set of locked outputs.
+func dbGetLockedOutputs(tx *bolt.Tx) (ids []types.OutputID, err error) {
+	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keyLockedOutputs), &ids)
+	return
+}
+
+// dbPutLockedOutputs stores the set of locked outputs.
+func dbPutLockedOutputs(tx *bolt.Tx, ids []types.OutputID) error {
+	return tx.Bucket(bucketWallet).Put(keyLockedOutputs, encoding.Marshal(ids))
+}
+
+// dbGetChangeProgress returns the number of change addresses generated from
+// the primary seed. Wallets that have not generated any change addresses yet
+// have a progress of zero.
+func dbGetChangeProgress(tx *bolt.Tx) (progress uint64, err error) {
+	progressBytes := tx.Bucket(bucketWallet).Get(keyChangeProgress)
+	if progressBytes == nil {
+		return 0, nil
+	}
+	err = encoding.Unmarshal(progressBytes, &progress)
+	return
+}
+
+// dbPutChangeProgress sets the change progress counter.
+func dbPutChangeProgress(tx *bolt.Tx, progress uint64) error {
+	return tx.Bucket(bucketWallet).Put(keyChangeProgress, encoding.Marshal(progress))
+}
+
+// dbGetSpendingPolicy retrieves the spending policy. Wallets without a
+// spending policy have the empty policy, which does not limit sends.
+func dbGetSpendingPolicy(tx *bolt.Tx) (p modules.SpendingPolicy, err error) {
+	policyBytes := tx.Bucket(bucketWallet).Get(keySpendingPolicy)
+	if policyBytes == nil {
+		return modules.SpendingPolicy{}, nil
+	}
+	err = encoding.Unmarshal(policyBytes, &p)
+	return
+}
+
+// dbPutSpendingPolicy stores the spending policy.
+func dbPutSpendingPolicy(tx *bolt.Tx, p modules.SpendingPolicy) error {
+	return tx.Bucket(bucketWallet).Put(keySpendingPolicy, encoding.Marshal(p))
+}
+
+// dbGetAutoLockTimeout retrieves the auto-lock timeout. Wallets without a
+// stored timeout have a timeout of zero, which disables auto-locking.
+func dbGetAutoLockTimeout(tx *bolt.Tx) (timeout time.Duration, err error) {
+	timeoutBytes := tx.Bucket(bucketWallet).Get(keyAutoLockTimeout)
+	if timeoutBytes == nil {
+		return 0, nil
+	}
+	err = encoding.Unmarshal(timeoutBytes, &timeout)
+	return
+}
+
+// dbPutAutoLockTimeout stores the auto-lock timeout.
+func dbPutAutoLockTimeout(tx *bolt.Tx, timeout time.Duration) error {
+	return tx.Bucket(bucketWallet).Put(keyAutoLockTimeout, encoding.Marshal(timeout))
+}
+
 // COMPATv121: these types were stored in the db in v1.2.2 and earlier.
 type (
 	v121ProcessedInput struct {
@@ -504,6 +740,16 @@ type (
 	}
 )
 
+// COMPATv133: this type was stored in the db in v1.3.3 and earlier.
+type v133ProcessedTransaction struct {
+	Transaction           types.Transaction
+	TransactionID         types.TransactionID
+	ConfirmationHeight    types.BlockHeight
+	ConfirmationTimestamp types.Timestamp
+	Inputs                []modules.ProcessedInput
+	Outputs               []modules.ProcessedOutput
+}
+
 func convertProcessedTransaction(oldpt v121ProcessedTransaction) (pt modules.ProcessedTransaction) {
 	pt.Transaction = oldpt.Transaction
 	pt.TransactionID = oldpt.TransactionID
@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/coreos/bbolt"
 )
@@ -39,3 +41,36 @@ func TestDBOpen(t *testing.T) {
 	})
 	w.db.Close()
 }
+
+// TestDecodeProcessedTransaction checks that processed transactions stored
+// before change outputs were recorded can still be decoded.
+func TestDecodeProcessedTransaction(t *testing.T) {
+	pt := modules.ProcessedTransaction{
+		TransactionID:      types.TransactionID{1},
+		ConfirmationHeight: 5,
+		Inputs:             []modules.ProcessedInput{{ParentID: types.OutputID{2}, Value: types.NewCurrency64(3)}},
+		Outputs:            []modules.ProcessedOutput{{ID: types.OutputID{4}, Value: types.NewCurrency64(2)}},
+		ChangeOutputs:      []types.OutputID{{4}},
+	}
+	var decoded modules.ProcessedTransaction
+	if err := decodeProcessedTransaction(encoding.Marshal(pt), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.ChangeOutputs) != 1 || decoded.ChangeOutputs[0] != pt.ChangeOutputs[0] {
+		t.Fatal("change outputs were not decoded:", decoded.ChangeOutputs)
+	}
+
+	old := v133ProcessedTransaction{
+		TransactionID:      pt.TransactionID,
+		ConfirmationHeight: pt.ConfirmationHeight,
+		Inputs:             pt.Inputs,
+		Outputs:            pt.Outputs,
+	}
+	decoded = modules.ProcessedTransaction{}
+	if err := decodeProcessedTransaction(encoding.Marshal(old), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.TransactionID != pt.TransactionID || decoded.ConfirmationHeight != pt.ConfirmationHeight || len(decoded.Inputs) != 1 || len(decoded.Outputs) != 1 || len(decoded.ChangeOutputs) != 0 {
+		t.Fatal("v1.3.3 transaction was not decoded correctly:", decoded)
+	}
+}
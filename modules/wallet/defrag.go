@@ -9,7 +9,8 @@ import (
 )
 
 var (
-	errDefragNotNeeded = errors.New("defragging not needed, wallet is already sufficiently defragged")
+	errDefragNotNeeded  = errors.New("defragging not needed, wallet is already sufficiently defragged")
+	errDefragFeeTooHigh = errors.New("defragging postponed, transaction fees are above the defrag fee limit")
 )
 
 // managedCreateDefragTransaction creates a transaction that spends multiple existing
@@ -25,6 +26,11 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	// Wait for fees to drop below the limit, if there is one.
+	if !w.defragMaxFee.IsZero() && minFee.Cmp(w.defragMaxFee) > 0 {
+		return nil, errDefragFeeTooHigh
+	}
+
 	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
 	if err != nil {
 		return nil, err
@@ -44,7 +50,7 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 	sort.Sort(sort.Reverse(so))
 
 	// Only defrag if there are enough outputs to merit defragging.
-	if len(so.ids) <= defragThreshold {
+	if uint64(len(so.ids)) <= w.defragThreshold {
 		return nil, errDefragNotNeeded
 	}
 
@@ -72,7 +78,7 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 
 	// Create and add the output that will be used to fund the defrag
 	// transaction.
-	parentUnlockConditions, err := w.nextPrimarySeedAddress(w.dbTx)
+	parentUnlockConditions, err := w.nextChangeAddress(w.dbTx)
 	if err != nil {
 		return nil, err
 	}
@@ -84,11 +90,14 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 
 	// Sign all of the inputs to the parent transaction.
 	for _, sci := range parentTxn.SiacoinInputs {
-		addSignatures(&parentTxn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), w.keys[sci.UnlockConditions.UnlockHash()])
+		_, err = addSignatures(&parentTxn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), w.keys[sci.UnlockConditions.UnlockHash()], w.signHash)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create the defrag transaction.
-	refundAddr, err := w.nextPrimarySeedAddress(w.dbTx)
+	refundAddr, err := w.nextChangeAddress(w.dbTx)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +117,10 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 		}},
 		MinerFees: []types.Currency{fee},
 	}
-	addSignatures(&txn, types.FullCoveredFields, parentUnlockConditions, crypto.Hash(parentTxn.SiacoinOutputID(0)), w.keys[parentUnlockConditions.UnlockHash()])
+	_, err = addSignatures(&txn, types.FullCoveredFields, parentUnlockConditions, crypto.Hash(parentTxn.SiacoinOutputID(0)), w.keys[parentUnlockConditions.UnlockHash()], w.signHash)
+	if err != nil {
+		return nil, err
+	}
 
 	// Mark all outputs that were spent as spent.
 	for _, scoid := range spentScoids {
@@ -128,8 +140,8 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 
 // threadedDefragWallet computes the sum of the 15 largest outputs in the wallet and
 // sends that sum to itself, effectively defragmenting the wallet. This defrag
-// operation is only performed if the wallet has greater than defragThreshold
-// outputs.
+// operation is only performed if the wallet has more outputs than its defrag
+// threshold and fees are not above its defrag fee limit.
 func (w *Wallet) threadedDefragWallet() {
 	// Don't defrag if it was disabled
 	if w.defragDisabled {
@@ -165,7 +177,7 @@ func (w *Wallet) threadedDefragWallet() {
 			}
 		}
 	}()
-	if err == errDefragNotNeeded {
+	if err == errDefragNotNeeded || err == errDefragFeeTooHigh {
 		// begin
 		return
 	} else if err != nil {
@@ -285,3 +285,71 @@ func TestDefragInterrupted(t *testing.T) {
 	}
 
 }
+
+// TestDefragSettings checks that the defrag threshold and fee limit of the
+// wallet's settings are respected.
+func TestDefragSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Disable the background defrag so that the outputs accumulate.
+	settings, err := wt.wallet.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.DefragThreshold != defragThreshold {
+		t.Fatal("wrong default defrag threshold:", settings.DefragThreshold)
+	}
+	settings.NoDefrag = true
+	settings.DefragThreshold = defragBatchSize + defragStartIndex
+	if err := wt.wallet.SetSettings(settings); err != errDefragThresholdTooLow {
+		t.Fatal("expected errDefragThresholdTooLow, got", err)
+	}
+	settings.DefragThreshold = defragThreshold + 10
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < defragThreshold+1; i++ {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The wallet has more than defragThreshold outputs, but fewer than its
+	// threshold.
+	_, err = wt.wallet.managedCreateDefragTransaction()
+	if err != errDefragNotNeeded {
+		t.Fatal("expected errDefragNotNeeded, got", err)
+	}
+
+	// Fees above the limit postpone the defrag.
+	settings.DefragThreshold = defragThreshold
+	settings.DefragMaxFee = types.NewCurrency64(1)
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.managedCreateDefragTransaction()
+	if err != errDefragFeeTooHigh {
+		t.Fatal("expected errDefragFeeTooHigh, got", err)
+	}
+
+	// Without a fee limit the defrag goes ahead.
+	settings.DefragMaxFee = types.ZeroCurrency
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	txnSet, err := wt.wallet.managedCreateDefragTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.tpool.AcceptTransactionSet(txnSet); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -107,6 +107,7 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 	var lastChange modules.ConsensusChangeID
 	var primarySeedFile seedFile
 	var primarySeedProgress uint64
+	var changeProgress uint64
 	var auxiliarySeedFiles []seedFile
 	var unseededKeyFiles []spendableKeyFile
 	err := func() error {
@@ -132,6 +133,10 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		if err != nil {
 			return err
 		}
+		changeProgress, err = dbGetChangeProgress(w.dbTx)
+		if err != nil {
+			return err
+		}
 
 		// auxiliarySeedFiles
 		err = encoding.Unmarshal(wb.Get(keyAuxiliarySeedFiles), &auxiliarySeedFiles)
@@ -161,9 +166,10 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		if err != nil {
 			return err
 		}
-		w.integrateSeed(primarySeed, primarySeedProgress)
+		w.integratePrimarySeed(primarySeed, primarySeedProgress, changeProgress)
 		w.primarySeed = primarySeed
 		w.regenerateLookahead(primarySeedProgress)
+		w.regenerateChangeLookahead(changeProgress)
 
 		// auxiliarySeedFiles
 		for _, sf := range auxiliarySeedFiles {
@@ -203,7 +209,7 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		defer close(done)
 
 		err = w.cs.ConsensusSetSubscribe(w, lastChange, w.tg.StopChan())
-		if err == modules.ErrInvalidConsensusChangeID {
+		if err == modules.ErrInvalidConsensusChangeID || err == modules.ErrConsensusChangePruned {
 			// something went wrong; resubscribe from the beginning
 			err = dbPutConsensusChangeID(w.dbTx, modules.ConsensusChangeBeginning)
 			if err != nil {
@@ -224,6 +230,7 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 	w.mu.Lock()
 	w.unlocked = true
 	w.subscribed = true
+	w.resetAutoLock()
 	w.mu.Unlock()
 	return nil
 }
@@ -341,6 +348,11 @@ func (w *Wallet) Reset() error {
 	w.wipeSecrets()
 	w.keys = make(map[types.UnlockHash]spendableKey)
 	w.lookahead = make(map[types.UnlockHash]uint64)
+	w.changeAddrs = make(map[types.UnlockHash]struct{})
+	w.changeLookahead = make(map[types.UnlockHash]uint64)
+	w.primaryKeyIndices = make(map[crypto.PublicKey]uint64)
+	w.watchedAddrs = make(map[types.UnlockHash]struct{})
+	w.lockedOutputs = make(map[types.OutputID]struct{})
 	w.seeds = []modules.Seed{}
 	w.unconfirmedProcessedTransactions = []modules.ProcessedTransaction{}
 	w.unlocked = false
@@ -386,12 +398,17 @@ func (w *Wallet) InitFromSeed(masterKey crypto.TwofishKey, seed modules.Seed) er
 	progress := s.largestIndexSeen + 1
 	progress += progress / 10
 	w.log.Printf("INFO: found key index %v in blockchain. Setting primary seed progress to %v", s.largestIndexSeen, progress)
+	changeProgress := s.largestChangeIndexSeen + 1
+	changeProgress += changeProgress / 10
+	w.log.Printf("INFO: found change index %v in blockchain. Setting change progress to %v", s.largestChangeIndexSeen, changeProgress)
 
 	// initialize the wallet with the appropriate seed progress
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	_, err := w.initEncryption(masterKey, seed, progress)
-	return err
+	if _, err := w.initEncryption(masterKey, seed, progress); err != nil {
+		return err
+	}
+	return dbPutChangeProgress(w.dbTx, changeProgress)
 }
 
 // Unlocked indicates whether the wallet is locked or unlocked.
@@ -590,13 +607,54 @@ func (w *Wallet) managedLock() error {
 		return modules.ErrLockedWallet
 	}
 	w.log.Println("INFO: Locking wallet.")
+	w.lock()
+	return nil
+}
 
+// lock erases all keys from memory and stops the auto-lock timer. The caller
+// must hold w.mu.
+func (w *Wallet) lock() {
 	// Wipe all of the seeds and secret keys. They will be replaced upon
 	// calling 'Unlock' again. Note that since the public keys are not wiped,
 	// we can continue processing blocks.
 	w.wipeSecrets()
 	w.unlocked = false
-	return nil
+	w.resetAutoLock()
+}
+
+// resetAutoLock stops the pending auto-lock timer and, if the wallet is
+// unlocked and has an auto-lock timeout, starts a new one. The caller must
+// hold w.mu.
+func (w *Wallet) resetAutoLock() {
+	if w.autoLockTimer != nil {
+		w.autoLockTimer.Stop()
+		w.autoLockTimer = nil
+	}
+	w.autoLockID++
+	if !w.unlocked || w.autoLockTimeout == 0 {
+		return
+	}
+	id := w.autoLockID
+	w.autoLockTimer = time.AfterFunc(w.autoLockTimeout, func() {
+		w.threadedAutoLock(id)
+	})
+}
+
+// threadedAutoLock locks the wallet once its auto-lock timeout has passed,
+// unless the timer with the given id has been replaced in the meantime.
+func (w *Wallet) threadedAutoLock(id uint64) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if id != w.autoLockID || !w.unlocked {
+		return
+	}
+	w.log.Println("INFO: Locking wallet after the auto-lock timeout.")
+	w.lock()
 }
 
 // managedUnlocked indicates whether the wallet is locked or unlocked.
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -285,6 +286,94 @@ func TestLock(t *testing.T) {
 	}
 }
 
+// TestAutoLock checks that an unlocked wallet is locked again once its
+// auto-lock timeout has passed, and that the timer is restarted when the
+// wallet is unlocked.
+func TestAutoLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	err = wt.wallet.SetSettings(modules.WalletSettings{AutoLockTimeout: -time.Second})
+	if err != errNegativeAutoLockTimeout {
+		t.Fatal("expected errNegativeAutoLockTimeout, got", err)
+	}
+	timeout := 500 * time.Millisecond
+	err = wt.wallet.SetSettings(modules.WalletSettings{AutoLockTimeout: timeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings, _ := wt.wallet.Settings(); settings.AutoLockTimeout != timeout {
+		t.Fatal("auto-lock timeout was not set:", settings.AutoLockTimeout)
+	}
+
+	// The wallet should lock itself after the timeout, and spending should
+	// then fail.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if wt.wallet.managedUnlocked() {
+			return errors.New("wallet was not locked")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+
+	// Unlocking the wallet restarts the timer.
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wt.wallet.managedUnlocked() {
+		t.Fatal("wallet was locked right after unlocking it")
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if wt.wallet.managedUnlocked() {
+			return errors.New("wallet was not locked after the timeout")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The timeout should persist across restarts.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if settings, _ := wt.wallet.Settings(); settings.AutoLockTimeout != timeout {
+		t.Fatal("auto-lock timeout was not persisted:", settings.AutoLockTimeout)
+	}
+
+	// Disabling the timeout keeps the wallet unlocked.
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.SetSettings(modules.WalletSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * timeout)
+	if !wt.wallet.managedUnlocked() {
+		t.Fatal("wallet was locked although auto-locking was disabled")
+	}
+}
+
 // TestInitFromSeedConcurrentUnlock verifies that calling InitFromSeed and
 // then Unlock() concurrently results in the correct balance.
 func TestInitFromSeedConcurrentUnlock(t *testing.T) {
@@ -0,0 +1,181 @@
+package wallet
+
+// events.go notifies subscribers of the transactions of the wallet. Events are
+// found while the wallet is locked, so they are queued and delivered by a
+// separate thread, in the order in which they happened. Subscribers can
+// therefore call the wallet from within their callbacks, but a slow subscriber
+// delays the events of every other subscriber. A rescan of the blockchain
+// reports the confirmations of the wallet's transactions again, so subscribers
+// need to be prepared to see a transaction more than once.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// The kinds of wallet events.
+const (
+	eventReceive = iota
+	eventConfirm
+	eventRevert
+)
+
+// A walletEvent is an event that has not been delivered to the subscribers
+// yet. Confirmations depend on the subscription, so confirm events are only
+// delivered to the subscriber that they were queued for.
+type walletEvent struct {
+	kind       int
+	pt         modules.ProcessedTransaction
+	subscriber modules.WalletSubscriber
+}
+
+// A walletSubscription is a subscriber along with the number of confirmations
+// after which it is notified of a transaction.
+type walletSubscription struct {
+	subscriber    modules.WalletSubscriber
+	confirmations types.BlockHeight
+}
+
+// walletEvents holds the subscriptions of the wallet and the events that have
+// not been delivered to them yet.
+type walletEvents struct {
+	subscriptions []walletSubscription
+	queue         []walletEvent
+	signal        chan struct{}
+}
+
+// queueEvent queues an event for delivery. A nil subscriber delivers the event
+// to every subscriber. The wallet must be locked when queueEvent is called.
+func (w *Wallet) queueEvent(kind int, pt modules.ProcessedTransaction, subscriber modules.WalletSubscriber) {
+	if len(w.events.subscriptions) == 0 {
+		return
+	}
+	w.events.queue = append(w.events.queue, walletEvent{kind: kind, pt: pt, subscriber: subscriber})
+	select {
+	case w.events.signal <- struct{}{}:
+	default:
+	}
+}
+
+// queueConfirmations queues a confirm event for every transaction that
+// reaches the number of confirmations of a subscription at the given height.
+// The wallet must be locked when queueConfirmations is called.
+func (w *Wallet) queueConfirmations(tx *bolt.Tx, height types.BlockHeight) {
+	confirmed := make(map[types.BlockHeight][]modules.ProcessedTransaction)
+	for _, s := range w.events.subscriptions {
+		if height+1 < s.confirmations {
+			continue
+		}
+		target := height + 1 - s.confirmations
+		pts, exists := confirmed[target]
+		if !exists {
+			pts = dbProcessedTransactionsAtHeight(tx, target)
+			confirmed[target] = pts
+		}
+		for _, pt := range pts {
+			w.queueEvent(eventConfirm, pt, s.subscriber)
+		}
+	}
+}
+
+// dbProcessedTransactionsAtHeight returns the processed transactions that were
+// confirmed at the given height. Processed transactions are stored in the
+// order in which they were confirmed, so the bucket is searched from the end.
+func dbProcessedTransactionsAtHeight(tx *bolt.Tx, height types.BlockHeight) []modules.ProcessedTransaction {
+	var pts []modules.ProcessedTransaction
+	c := tx.Bucket(bucketProcessedTransactions).Cursor()
+	for k, v := c.Last(); k != nil; k, v = c.Prev() {
+		var pt modules.ProcessedTransaction
+		if err := decodeProcessedTransaction(v, &pt); err != nil {
+			continue
+		}
+		if pt.ConfirmationHeight < height {
+			break
+		} else if pt.ConfirmationHeight == height {
+			pts = append(pts, pt)
+		}
+	}
+	// Restore the order in which the transactions were confirmed.
+	for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+		pts[i], pts[j] = pts[j], pts[i]
+	}
+	return pts
+}
+
+// threadedDeliverEvents delivers the queued events to the subscribers until
+// the wallet is closed.
+func (w *Wallet) threadedDeliverEvents() {
+	for {
+		select {
+		case <-w.events.signal:
+		case <-w.tg.StopChan():
+			return
+		}
+
+		func() {
+			if err := w.tg.Add(); err != nil {
+				return
+			}
+			defer w.tg.Done()
+
+			w.mu.Lock()
+			queue := w.events.queue
+			w.events.queue = nil
+			subscriptions := append([]walletSubscription(nil), w.events.subscriptions...)
+			w.mu.Unlock()
+			for _, e := range queue {
+				for _, s := range subscriptions {
+					if e.subscriber != nil && e.subscriber != s.subscriber {
+						continue
+					}
+					switch e.kind {
+					case eventReceive:
+						s.subscriber.OnReceive(e.pt)
+					case eventConfirm:
+						s.subscriber.OnConfirm(e.pt)
+					case eventRevert:
+						s.subscriber.OnRevert(e.pt)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Subscribe adds a subscriber that is notified of the wallet's transactions
+// after subscribing. Transactions are reported as confirmed once they have the
+// given number of confirmations; zero is treated as one.
+func (w *Wallet) Subscribe(subscriber modules.WalletSubscriber, confirmations types.BlockHeight) {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.events.subscriptions {
+		if s.subscriber == subscriber {
+			build.Critical("refusing to double-subscribe subscriber")
+			return
+		}
+	}
+	w.events.subscriptions = append(w.events.subscriptions, walletSubscription{
+		subscriber:    subscriber,
+		confirmations: confirmations,
+	})
+}
+
+// Unsubscribe removes a subscriber. Events that were queued before the
+// subscriber was removed may still be delivered to it. If the subscriber is
+// not found, no action is taken.
+func (w *Wallet) Unsubscribe(subscriber modules.WalletSubscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, s := range w.events.subscriptions {
+		if s.subscriber == subscriber {
+			w.events.subscriptions = append(w.events.subscriptions[:i], w.events.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// mockWalletSubscriber records the wallet events it receives.
+type mockWalletSubscriber struct {
+	events []string
+	mu     sync.Mutex
+}
+
+// OnReceive implements modules.WalletSubscriber.
+func (s *mockWalletSubscriber) OnReceive(pt modules.ProcessedTransaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprint("receive ", pt.TransactionID))
+}
+
+// OnConfirm implements modules.WalletSubscriber.
+func (s *mockWalletSubscriber) OnConfirm(pt modules.ProcessedTransaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprint("confirm ", pt.TransactionID))
+}
+
+// OnRevert implements modules.WalletSubscriber.
+func (s *mockWalletSubscriber) OnRevert(pt modules.ProcessedTransaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprint("revert ", pt.TransactionID))
+}
+
+// waitForEvents waits until the subscriber received exactly the expected
+// events.
+func (s *mockWalletSubscriber) waitForEvents(expected ...string) error {
+	return build.Retry(50, 100*time.Millisecond, func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if fmt.Sprint(s.events) != fmt.Sprint(expected) {
+			return errors.New(fmt.Sprint("expected events ", expected, ", got ", s.events))
+		}
+		return nil
+	})
+}
+
+// TestWalletEvents checks that subscribers are notified when the wallet
+// receives funds, when a transaction reaches the requested number of
+// confirmations, and when it is reverted by a reorg.
+func TestWalletEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Create a second wallet on the same consensus set that receives coins
+	// from the wallet tester.
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, "receiver"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var masterKey crypto.TwofishKey
+	fastrand.Read(masterKey[:])
+	if _, err := w.Encrypt(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unlock(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	s := new(mockWalletSubscriber)
+	w.Subscribe(s, 2)
+
+	uc, err := w.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+	if err := s.waitForEvents(fmt.Sprint("receive ", txid)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The transaction is reported as confirmed once it has two
+	// confirmations.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.waitForEvents(fmt.Sprint("receive ", txid)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.waitForEvents(fmt.Sprint("receive ", txid), fmt.Sprint("confirm ", txid)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine a heavier fork on a separate consensus set and give its blocks to
+	// the wallet tester. The transaction does not exist on the fork.
+	wt2, err := createWalletTester(t.Name()+"-fork", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt2.closeWt()
+	for i := 0; i < 4; i++ {
+		if _, err := wt2.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for height := types.BlockHeight(1); height <= wt2.cs.Height(); height++ {
+		b, _ := wt2.cs.BlockAtHeight(height)
+		if err := wt.cs.AcceptBlock(b); err != nil && err != modules.ErrNonExtendingBlock {
+			t.Fatal(err)
+		}
+	}
+	if wt.cs.CurrentBlock().ID() != wt2.cs.CurrentBlock().ID() {
+		t.Fatal("wallet tester did not reorg onto the fork")
+	}
+	if err := s.waitForEvents(fmt.Sprint("receive ", txid), fmt.Sprint("confirm ", txid), fmt.Sprint("revert ", txid)); err != nil {
+		t.Fatal(err)
+	}
+
+	// After unsubscribing, no more events are delivered.
+	w.Unsubscribe(s)
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.waitForEvents(fmt.Sprint("receive ", txid), fmt.Sprint("confirm ", txid), fmt.Sprint("revert ", txid)); err != nil {
+		t.Fatal(err)
+	}
+}
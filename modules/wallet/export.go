@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+var (
+	errUnknownExportFormat = errors.New("unknown export format")
+
+	// exportCSVHeader is the header row of the CSV accounting report.
+	exportCSVHeader = []string{
+		"timestamp",
+		"height",
+		"transactionid",
+		"direction",
+		"incomingsiacoins",
+		"outgoingsiacoins",
+		"fees",
+		"incomingsiafunds",
+		"outgoingsiafunds",
+		"counterparties",
+		"balance",
+		"memo",
+	}
+)
+
+// Export returns the accounting report of the transactions that were
+// confirmed at heights [startHeight, endHeight] in the given format. The
+// running balance of the report includes the transactions that were confirmed
+// before startHeight.
+func (w *Wallet) Export(format modules.ExportFormat, startHeight, endHeight types.BlockHeight) ([]byte, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if format != modules.ExportFormatCSV && format != modules.ExportFormatJSON {
+		return nil, errUnknownExportFormat
+	}
+	entries, err := w.managedExportEntries(startHeight, endHeight)
+	if err != nil {
+		return nil, err
+	}
+	if format == modules.ExportFormatJSON {
+		return json.Marshal(entries)
+	}
+	return exportCSV(entries)
+}
+
+// managedExportEntries returns the entries of the accounting report of the
+// transactions that were confirmed at heights [startHeight, endHeight].
+func (w *Wallet) managedExportEntries(startHeight, endHeight types.BlockHeight) ([]modules.WalletExportEntry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	} else if startHeight > height || startHeight > endHeight {
+		return nil, errOutOfBounds
+	}
+
+	entries := []modules.WalletExportEntry{}
+	var balance types.Currency
+	it := dbProcessedTransactionsIterator(w.dbTx)
+	for it.next() {
+		pt := it.value()
+		if pt.ConfirmationHeight > endHeight {
+			break
+		}
+		pts := pt.Summary(height)
+		balance = balance.Add(pts.IncomingSiacoins)
+		if balance.Cmp(pts.OutgoingSiacoins) >= 0 {
+			balance = balance.Sub(pts.OutgoingSiacoins)
+		} else {
+			balance = types.ZeroCurrency
+		}
+		if pt.ConfirmationHeight < startHeight {
+			continue
+		}
+
+		entry := modules.WalletExportEntry{
+			TransactionID:    pt.TransactionID,
+			Height:           pt.ConfirmationHeight,
+			Timestamp:        pt.ConfirmationTimestamp,
+			Direction:        pts.Direction,
+			IncomingSiacoins: pts.IncomingSiacoins,
+			OutgoingSiacoins: pts.OutgoingSiacoins,
+			IncomingSiafunds: pts.IncomingSiafunds,
+			OutgoingSiafunds: pts.OutgoingSiafunds,
+			Counterparties:   pts.RelatedAddresses,
+			Balance:          balance,
+		}
+		if entry.Counterparties == nil {
+			entry.Counterparties = []types.UnlockHash{}
+		}
+		if paidByWallet(pt) {
+			entry.Fees = pts.Fees
+		}
+		entry.Memo, _ = modules.TransactionMemo(pt.Transaction)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// paidByWallet returns true if the wallet spent siacoins in pt, in which case
+// the wallet paid the transaction's fees.
+func paidByWallet(pt modules.ProcessedTransaction) bool {
+	for _, input := range pt.Inputs {
+		if input.FundType == types.SpecifierSiacoinInput && input.WalletAddress {
+			return true
+		}
+	}
+	return false
+}
+
+// exportCSV encodes the entries of an accounting report as CSV. Timestamps
+// are written in RFC 3339 format and addresses are separated by spaces.
+func exportCSV(entries []modules.WalletExportEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		counterparties := make([]string, len(e.Counterparties))
+		for i, addr := range e.Counterparties {
+			counterparties[i] = addr.String()
+		}
+		err := cw.Write([]string{
+			time.Unix(int64(e.Timestamp), 0).UTC().Format(time.RFC3339),
+			fmt.Sprint(e.Height),
+			e.TransactionID.String(),
+			string(e.Direction),
+			e.IncomingSiacoins.String(),
+			e.OutgoingSiacoins.String(),
+			e.Fees.String(),
+			e.IncomingSiafunds.String(),
+			e.OutgoingSiafunds.String(),
+			strings.Join(counterparties, " "),
+			e.Balance.String(),
+			e.Memo,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestExport checks that the accounting report of the wallet lists its
+// transactions with their amounts, fees, counterparties and running balance.
+func TestExport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	amount := types.SiacoinPrecision.Mul64(100)
+	dest := types.UnlockHash{1}
+	txns, err := wt.wallet.SendSiacoinsWithMemo(amount, dest, "invoice-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	sendID := txns[len(txns)-1].ID()
+
+	data, err := wt.wallet.Export(modules.ExportFormatJSON, 0, wt.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []modules.WalletExportEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	var balance types.Currency
+	var send *modules.WalletExportEntry
+	for i, e := range entries {
+		balance = balance.Add(e.IncomingSiacoins).Sub(e.OutgoingSiacoins)
+		if !e.Balance.Equals(balance) {
+			t.Fatalf("entry %v: expected balance %v, got %v", i, balance, e.Balance)
+		}
+		if e.TransactionID == sendID {
+			send = &entries[i]
+		} else if e.Direction == modules.TransactionDirectionIncoming && !e.Fees.IsZero() {
+			t.Fatal("fees were reported for an incoming transaction")
+		}
+	}
+	if send == nil {
+		t.Fatal("sent transaction is missing from the report")
+	}
+	if send.Direction != modules.TransactionDirectionOutgoing || send.Fees.IsZero() || !send.OutgoingSiacoins.Equals(amount.Add(send.Fees)) {
+		t.Fatal("wrong amounts for the sent transaction:", send)
+	}
+	if len(send.Counterparties) != 1 || send.Counterparties[0] != dest || send.Memo != "invoice-1" {
+		t.Fatal("wrong counterparties or memo for the sent transaction:", send)
+	}
+
+	// Transactions before startHeight are only counted in the balance.
+	data, err = wt.wallet.Export(modules.ExportFormatCSV, send.Height, send.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 2 || len(rows[0]) != len(exportCSVHeader) || rows[0][0] != "timestamp" {
+		t.Fatal("wrong CSV report:", rows)
+	}
+	var found bool
+	for _, row := range rows[1:] {
+		if row[2] != sendID.String() {
+			continue
+		}
+		found = true
+		if row[5] != send.OutgoingSiacoins.String() || row[6] != send.Fees.String() || row[9] != dest.String() || row[10] != send.Balance.String() || row[11] != "invoice-1" {
+			t.Fatal("wrong CSV row for the sent transaction:", row)
+		}
+	}
+	if !found {
+		t.Fatal("sent transaction is missing from the CSV report")
+	}
+
+	if _, err := wt.wallet.Export("xml", 0, wt.cs.Height()); err != errUnknownExportFormat {
+		t.Fatal("expected errUnknownExportFormat, got", err)
+	}
+	if _, err := wt.wallet.Export(modules.ExportFormatCSV, wt.cs.Height()+1, wt.cs.Height()+2); err != errOutOfBounds {
+		t.Fatal("expected errOutOfBounds, got", err)
+	}
+}
@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+const (
+	// maxLabelLength is the maximum length in bytes of an address label.
+	maxLabelLength = 128
+)
+
+var (
+	errLabelTooLong = errors.New("address label is too long")
+)
+
+// AddressLabels returns the labels of the wallet's addresses and the entries
+// of its address book, sorted by label.
+func (w *Wallet) AddressLabels() ([]modules.AddressLabel, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	labels := []modules.AddressLabel{}
+	err := dbForEachAddressLabel(w.dbTx, func(addr types.UnlockHash, label string) {
+		labels = append(labels, modules.AddressLabel{
+			Address:       addr,
+			Label:         label,
+			WalletAddress: w.isWalletAddress(addr),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Label != labels[j].Label {
+			return labels[i].Label < labels[j].Label
+		}
+		return bytes.Compare(labels[i].Address[:], labels[j].Address[:]) < 0
+	})
+	return labels, nil
+}
+
+// SetAddressLabel sets the label of an address. Addresses that do not belong
+// to the wallet are added to its address book. An empty label removes the
+// label of the address.
+func (w *Wallet) SetAddressLabel(addr types.UnlockHash, label string) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if len(label) > maxLabelLength {
+		return errLabelTooLong
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if label == "" {
+		return dbDeleteAddressLabel(w.dbTx, addr)
+	}
+	return dbPutAddressLabel(w.dbTx, addr, label)
+}
@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestAddressLabels checks that labels can be set on the wallet's addresses
+// and on the addresses of its address book, and that they persist.
+func TestAddressLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	own := uc.UnlockHash()
+	contact := types.UnlockHash{1}
+	if err := wt.wallet.SetAddressLabel(own, "savings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(contact, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(contact, strings.Repeat("a", maxLabelLength+1)); err != errLabelTooLong {
+		t.Fatal("expected errLabelTooLong, got", err)
+	}
+
+	// The labels should be sorted by label.
+	labels, err := wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 {
+		t.Fatal("expected 2 labels, got", len(labels))
+	}
+	if labels[0].Address != contact || labels[0].Label != "alice" || labels[0].WalletAddress {
+		t.Fatal("wrong address book entry:", labels[0])
+	}
+	if labels[1].Address != own || labels[1].Label != "savings" || !labels[1].WalletAddress {
+		t.Fatal("wrong address label:", labels[1])
+	}
+
+	// Removing a label and rescanning should leave the other label in place.
+	if err := wt.wallet.SetAddressLabel(own, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Rescan(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	labels, err = wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0].Address != contact {
+		t.Fatal("labels were not persisted:", labels)
+	}
+}
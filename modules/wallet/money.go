@@ -8,6 +8,18 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	errNoOutputs = errors.New("at least one output is required to send siacoins")
+
+	// errDustSend is returned when a send would create an output that is
+	// below the dust threshold.
+	errDustSend = errors.New("cannot send an output below the dust threshold")
+
+	// errMemoTooLarge is returned when a transaction memo is larger than
+	// modules.MaxMemoSize.
+	errMemoTooLarge = errors.New("memo is too large")
+)
+
 // sortedOutputs is a struct containing a slice of siacoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -16,17 +28,57 @@ type sortedOutputs struct {
 }
 
 // DustThreshold returns the quantity per byte below which a Currency is
-// considered to be Dust.
+// considered to be Dust. Unless the threshold was set in the wallet's
+// settings, it is three times the minimum fee recommended by the transaction
+// pool.
 func (w *Wallet) DustThreshold() (types.Currency, error) {
 	if err := w.tg.Add(); err != nil {
 		return types.Currency{}, modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
 
+	w.mu.RLock()
+	threshold := w.dustThreshold
+	w.mu.RUnlock()
+	if !threshold.IsZero() {
+		return threshold, nil
+	}
 	minFee, _ := w.tpool.FeeEstimation()
 	return minFee.Mul64(3), nil
 }
 
+// managedCheckDust returns errDustSend if one of the outputs is below the
+// dust threshold that was set in the wallet's settings. Without a configured
+// threshold, outputs of any value can be sent.
+func (w *Wallet) managedCheckDust(outputs []types.SiacoinOutput) error {
+	w.mu.RLock()
+	dustThreshold := w.dustThreshold
+	w.mu.RUnlock()
+	for _, sco := range outputs {
+		if sco.Value.Cmp(dustThreshold) < 0 {
+			return errDustSend
+		}
+	}
+	return nil
+}
+
+// managedFeePerByte returns the fee per byte that the wallet's fee policy
+// selects from the fees recommended by the transaction pool.
+func (w *Wallet) managedFeePerByte() types.Currency {
+	minFee, maxFee := w.tpool.FeeEstimation()
+	w.mu.RLock()
+	policy := w.feePolicy
+	w.mu.RUnlock()
+	switch policy {
+	case modules.FeePolicyEconomical:
+		return minFee
+	case modules.FeePolicyPriority:
+		return maxFee.Mul64(2)
+	default:
+		return maxFee
+	}
+}
+
 // ConfirmedBalance returns the balance of the wallet according to all of the
 // confirmed transactions.
 func (w *Wallet) ConfirmedBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, siafundClaimBalance types.Currency, err error) {
@@ -114,6 +166,70 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 	}
 	defer w.tg.Done()
 
+	fee := w.managedFeePerByte().Mul64(750) // Estimated transaction size in bytes
+	return w.managedSendSiacoins(amount, dest, fee, "", func(tb modules.TransactionBuilder, fund types.Currency) error {
+		return tb.FundSiacoins(fund)
+	})
+}
+
+// SendSiacoinsWithMemo creates a transaction sending 'amount' to 'dest' that
+// carries 'memo' in its arbitrary data, so that the recipient can tell what
+// the payment is for. The transaction is submitted to the transaction pool and
+// is also returned.
+func (w *Wallet) SendSiacoinsWithMemo(amount types.Currency, dest types.UnlockHash, memo string) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	if len(memo) > modules.MaxMemoSize {
+		return nil, errMemoTooLarge
+	}
+	// Estimated transaction size in bytes, plus the memo.
+	fee := w.managedFeePerByte().Mul64(750 + uint64(len(modules.MemoPrefix)+len(memo)))
+	return w.managedSendSiacoins(amount, dest, fee, memo, func(tb modules.TransactionBuilder, fund types.Currency) error {
+		return tb.FundSiacoins(fund)
+	})
+}
+
+// SendSiacoinsWithFee creates a transaction sending 'amount' to 'dest' that
+// pays 'fee' in miner fees. The transaction is submitted to the transaction
+// pool and is also returned.
+func (w *Wallet) SendSiacoinsWithFee(amount types.Currency, dest types.UnlockHash, fee types.Currency) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	return w.managedSendSiacoins(amount, dest, fee, "", func(tb modules.TransactionBuilder, fund types.Currency) error {
+		return tb.FundSiacoins(fund)
+	})
+}
+
+// SendSiacoinsWithOutputs creates a transaction sending 'amount' to 'dest',
+// funded by exactly the specified outputs of the wallet. The transaction is
+// submitted to the transaction pool and is also returned.
+func (w *Wallet) SendSiacoinsWithOutputs(amount types.Currency, dest types.UnlockHash, outputs []types.SiacoinOutputID) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	fee := w.managedFeePerByte().Mul64(750) // Estimated transaction size in bytes
+	return w.managedSendSiacoins(amount, dest, fee, "", func(tb modules.TransactionBuilder, fund types.Currency) error {
+		return tb.FundTransactionWithOutputs(fund, outputs)
+	})
+}
+
+// managedSendSiacoins creates a transaction sending 'amount' to 'dest' that
+// pays 'tpoolFee' in miner fees, using fundFn to add the amount plus the fees
+// to the transaction, and submits it to the transaction pool. If 'memo' is not
+// empty, it is attached to the transaction. The send has to comply with the
+// spending policy.
+func (w *Wallet) managedSendSiacoins(amount types.Currency, dest types.UnlockHash, tpoolFee types.Currency, memo string, fundFn func(modules.TransactionBuilder, types.Currency) error) (txns []types.Transaction, err error) {
 	w.mu.RLock()
 	unlocked := w.unlocked
 	w.mu.RUnlock()
@@ -122,12 +238,18 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 		return nil, modules.ErrLockedWallet
 	}
 
-	_, tpoolFee := w.tpool.FeeEstimation()
-	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
 	output := types.SiacoinOutput{
 		Value:      amount,
 		UnlockHash: dest,
 	}
+	if err := w.managedCheckDust([]types.SiacoinOutput{output}); err != nil {
+		return nil, err
+	}
+	w.policyMu.Lock()
+	defer w.policyMu.Unlock()
+	if err := w.managedCheckSpendingPolicy([]types.SiacoinOutput{output}, tpoolFee); err != nil {
+		return nil, err
+	}
 
 	txnBuilder, err := w.StartTransaction()
 	if err != nil {
@@ -138,13 +260,16 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 			txnBuilder.Drop()
 		}
 	}()
-	err = txnBuilder.FundSiacoins(amount.Add(tpoolFee))
+	err = fundFn(txnBuilder, amount.Add(tpoolFee))
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to fund transaction:", err)
 		return nil, build.ExtendErr("unable to fund transaction", err)
 	}
 	txnBuilder.AddMinerFee(tpoolFee)
 	txnBuilder.AddSiacoinOutput(output)
+	if memo != "" {
+		txnBuilder.AddArbitraryData(append(append([]byte(nil), modules.MemoPrefix...), memo...))
+	}
 	txnSet, err := txnBuilder.Sign(true)
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to sign transaction:", err)
@@ -175,6 +300,63 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.
 		return nil, err
 	}
 	defer w.tg.Done()
+
+	return w.managedSendSiacoinsMulti(outputs, w.managedMultiSendFee(len(outputs)), false)
+}
+
+// SendSiacoinsMultiWithFee creates a transaction that includes the specified
+// outputs and pays 'fee' in miner fees. The transaction is submitted to the
+// transaction pool and is also returned.
+func (w *Wallet) SendSiacoinsMultiWithFee(outputs []types.SiacoinOutput, fee types.Currency) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	return w.managedSendSiacoinsMulti(outputs, fee, false)
+}
+
+// SendSiacoinsMultiConfirmed works like SendSiacoinsMulti, but is not limited
+// by the spending policy.
+func (w *Wallet) SendSiacoinsMultiConfirmed(outputs []types.SiacoinOutput) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	return w.managedSendSiacoinsMulti(outputs, w.managedMultiSendFee(len(outputs)), true)
+}
+
+// SendSiacoinsMultiWithFeeConfirmed works like SendSiacoinsMultiWithFee, but
+// is not limited by the spending policy.
+func (w *Wallet) SendSiacoinsMultiWithFeeConfirmed(outputs []types.SiacoinOutput, fee types.Currency) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	return w.managedSendSiacoinsMulti(outputs, fee, true)
+}
+
+// managedMultiSendFee estimates the fee of a transaction sending to n
+// outputs.
+func (w *Wallet) managedMultiSendFee(n int) types.Currency {
+	tpoolFee := w.managedFeePerByte()
+	tpoolFee = tpoolFee.Mul64(2)               // We don't want send-to-many transactions to fail.
+	return tpoolFee.Mul64(1000 + 60*uint64(n)) // Estimated transaction size in bytes
+}
+
+// managedSendSiacoinsMulti creates a transaction that includes the specified
+// outputs and pays 'tpoolFee' in miner fees, and submits it to the
+// transaction pool. Unless the send is confirmed, it has to comply with the
+// spending policy.
+func (w *Wallet) managedSendSiacoinsMulti(outputs []types.SiacoinOutput, tpoolFee types.Currency, confirmed bool) (txns []types.Transaction, err error) {
+	if len(outputs) == 0 {
+		return nil, errNoOutputs
+	}
 	w.mu.RLock()
 	unlocked := w.unlocked
 	w.mu.RUnlock()
@@ -182,6 +364,16 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
 		return nil, modules.ErrLockedWallet
 	}
+	if err := w.managedCheckDust(outputs); err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		w.policyMu.Lock()
+		defer w.policyMu.Unlock()
+		if err := w.managedCheckSpendingPolicy(outputs, tpoolFee); err != nil {
+			return nil, err
+		}
+	}
 
 	txnBuilder, err := w.StartTransaction()
 	if err != nil {
@@ -193,10 +385,6 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.
 		}
 	}()
 
-	// Add estimated transaction fee.
-	_, tpoolFee := w.tpool.FeeEstimation()
-	tpoolFee = tpoolFee.Mul64(2)                              // We don't want send-to-many transactions to fail.
-	tpoolFee = tpoolFee.Mul64(1000 + 60*uint64(len(outputs))) // Estimated transaction size in bytes
 	txnBuilder.AddMinerFee(tpoolFee)
 
 	// Calculate total cost to wallet.
@@ -256,7 +444,7 @@ func (w *Wallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) (txn
 		return nil, modules.ErrLockedWallet
 	}
 
-	_, tpoolFee := w.tpool.FeeEstimation()
+	tpoolFee := w.managedFeePerByte()
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
 	tpoolFee = tpoolFee.Mul64(5)   // use large fee to ensure siafund transactions are selected by miners
 	output := types.SiafundOutput{
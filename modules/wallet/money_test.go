@@ -169,6 +169,28 @@ func TestIntegrationSpendUnconfirmed(t *testing.T) {
 	if err != nil {
 		t.Error("wallet appears to be struggling to spend unconfirmed outputs")
 	}
+
+	// Once spending unconfirmed outputs is disabled, the remaining change
+	// can't be spent until it is confirmed.
+	settings, err := wt.wallet.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.NoSpendUnconfirmed = true
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{2})
+	if err == nil {
+		t.Fatal("wallet spent an unconfirmed output although it was told not to")
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{2})
+	if err != nil {
+		t.Fatal("wallet can't spend confirmed change:", err)
+	}
 }
 
 // TestIntegrationSortedOutputsSorting checks that the outputs are being correctly sorted
@@ -275,3 +297,242 @@ func TestSendSiacoinsAcceptTxnSetFailed(t *testing.T) {
 		t.Fatalf("SendSiacoins failed: %v", err)
 	}
 }
+
+// TestFeePolicy checks that sends pay the fee of the wallet's fee policy, or
+// the fee that was passed explicitly.
+func TestFeePolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// minerFee returns the sum of the miner fees of a transaction set.
+	minerFee := func(txns []types.Transaction) (fee types.Currency) {
+		for _, txn := range txns {
+			for _, mf := range txn.MinerFees {
+				fee = fee.Add(mf)
+			}
+		}
+		return fee
+	}
+
+	if err := wt.wallet.SetSettings(modules.WalletSettings{FeePolicy: "cheap"}); err != errInvalidFeePolicy {
+		t.Fatal("expected errInvalidFeePolicy, got", err)
+	}
+	settings, err := wt.wallet.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.FeePolicy != modules.FeePolicyNormal {
+		t.Fatal("wrong default fee policy:", settings.FeePolicy)
+	}
+
+	minFee, maxFee := wt.tpool.FeeEstimation()
+	policies := []struct {
+		policy modules.FeePolicy
+		fee    types.Currency
+	}{
+		{modules.FeePolicyEconomical, minFee},
+		{modules.FeePolicyNormal, maxFee},
+		{modules.FeePolicyPriority, maxFee.Mul64(2)},
+	}
+	for _, p := range policies {
+		settings.FeePolicy = p.policy
+		if err := wt.wallet.SetSettings(settings); err != nil {
+			t.Fatal(err)
+		}
+		txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee := minerFee(txns); !fee.Equals(p.fee.Mul64(750)) {
+			t.Errorf("%v: expected fee %v, got %v", p.policy, p.fee.Mul64(750), fee)
+		}
+	}
+
+	// An explicit fee overrides the fee policy.
+	fee := types.SiacoinPrecision.Div64(10)
+	txns, err := wt.wallet.SendSiacoinsWithFee(types.SiacoinPrecision, types.UnlockHash{}, fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !minerFee(txns).Equals(fee) {
+		t.Fatal("expected fee", fee, "got", minerFee(txns))
+	}
+	outputs := []types.SiacoinOutput{
+		{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{1}},
+		{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{2}},
+	}
+	txns, err = wt.wallet.SendSiacoinsMultiWithFee(outputs, fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !minerFee(txns).Equals(fee) {
+		t.Fatal("expected fee", fee, "got", minerFee(txns))
+	}
+}
+
+// TestSendSiacoinsMulti checks that SendSiacoinsMulti pays all recipients in
+// a single transaction.
+func TestSendSiacoinsMulti(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.SendSiacoinsMulti(nil); err != errNoOutputs {
+		t.Fatal("expected errNoOutputs, got", err)
+	}
+
+	var outputs []types.SiacoinOutput
+	for i := 0; i < 10; i++ {
+		outputs = append(outputs, types.SiacoinOutput{
+			Value:      types.SiacoinPrecision.Mul64(uint64(i + 1)),
+			UnlockHash: types.UnlockHash{byte(i + 1)},
+		})
+	}
+	txns, err := wt.wallet.SendSiacoinsMulti(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last transaction pays every recipient, plus at most one refund
+	// output.
+	txn := txns[len(txns)-1]
+	if len(txn.SiacoinOutputs) != len(outputs) && len(txn.SiacoinOutputs) != len(outputs)+1 {
+		t.Fatal("wrong number of outputs:", len(txn.SiacoinOutputs))
+	}
+	paid := make(map[types.UnlockHash]types.Currency)
+	for _, sco := range txn.SiacoinOutputs {
+		paid[sco.UnlockHash] = paid[sco.UnlockHash].Add(sco.Value)
+	}
+	for _, sco := range outputs {
+		if !paid[sco.UnlockHash].Equals(sco.Value) {
+			t.Fatal("recipient was not paid:", sco.UnlockHash)
+		}
+	}
+}
+
+// TestSendSiacoinsWithMemo checks that memos are attached to sent
+// transactions and that the wallet indexes the transactions by their memo.
+func TestSendSiacoinsWithMemo(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	largeMemo := string(make([]byte, modules.MaxMemoSize+1))
+	if _, err := wt.wallet.SendSiacoinsWithMemo(types.SiacoinPrecision, uc.UnlockHash(), largeMemo); err != errMemoTooLarge {
+		t.Fatal("expected errMemoTooLarge, got", err)
+	}
+	txns, err := wt.wallet.SendSiacoinsWithMemo(types.SiacoinPrecision, uc.UnlockHash(), "deposit-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+	if memo, ok := modules.TransactionMemo(txn); !ok || memo != "deposit-1234" {
+		t.Fatalf("transaction carries the wrong memo: %q %v", memo, ok)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	pts, err := wt.wallet.MemoTransactions("deposit-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 || pts[0].TransactionID != txn.ID() {
+		t.Fatal("transaction was not indexed by its memo:", pts)
+	}
+	pts, err = wt.wallet.MemoTransactions("deposit-5678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 0 {
+		t.Fatal("expected no transactions for an unknown memo, got", len(pts))
+	}
+}
+
+// TestDustThreshold checks that the wallet does not create outputs below the
+// dust threshold, and adds change below the threshold to the miner fee.
+func TestDustThreshold(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	settings, err := wt.wallet.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.DustThreshold = types.SiacoinPrecision
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if dt, err := wt.wallet.DustThreshold(); err != nil || !dt.Equals(types.SiacoinPrecision) {
+		t.Fatal("dust threshold was not set:", dt, err)
+	}
+
+	// Sends below the threshold are rejected.
+	dust := types.SiacoinPrecision.Sub(types.NewCurrency64(1))
+	if _, err := wt.wallet.SendSiacoins(dust, types.UnlockHash{}); err != errDustSend {
+		t.Fatal("expected errDustSend, got", err)
+	}
+	outputs := []types.SiacoinOutput{
+		{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{1}},
+		{Value: dust, UnlockHash: types.UnlockHash{2}},
+	}
+	if _, err := wt.wallet.SendSiacoinsMulti(outputs); err != errDustSend {
+		t.Fatal("expected errDustSend, got", err)
+	}
+
+	// Fund a transaction so that the change of the largest output is below
+	// the threshold. The change should be paid as a fee.
+	wos, err := wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var largest types.Currency
+	for _, wo := range wos {
+		if wo.FundType == types.SpecifierSiacoinOutput && wo.Value.Cmp(largest) > 0 {
+			largest = wo.Value
+		}
+	}
+	change := types.SiacoinPrecision.Div64(2)
+	b, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Drop()
+	if err := b.FundSiacoins(largest.Sub(change)); err != nil {
+		t.Fatal(err)
+	}
+	_, parents := b.View()
+	parent := parents[len(parents)-1]
+	if len(parent.SiacoinOutputs) != 1 {
+		t.Fatal("expected no change output, got", len(parent.SiacoinOutputs), "outputs")
+	}
+	if len(parent.MinerFees) != 1 || !parent.MinerFees[0].Equals(change) {
+		t.Fatal("change was not added to the miner fee:", parent.MinerFees)
+	}
+}
@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+var (
+	errMultisigNoPublicKeys       = errors.New("a multisig address needs at least one public key")
+	errMultisigSignaturesRequired = errors.New("signatures required must be between one and the number of public keys")
+)
+
+// AddMultisigAddress creates the unlock conditions of an M-of-N multisig
+// address from a set of public keys, which usually include a key of the
+// wallet, and stores them. The address is watched, so that its outputs are
+// tracked by the wallet and can fund transactions built by
+// UnsignedTransaction, which are then signed by the cosigners using
+// SignTransaction. If the address has not appeared in the blockchain, unused
+// may be set to true to skip rescanning the blockchain.
+func (w *Wallet) AddMultisigAddress(publicKeys []types.SiaPublicKey, signaturesRequired uint64, unused bool) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if len(publicKeys) == 0 {
+		return types.UnlockConditions{}, errMultisigNoPublicKeys
+	}
+	if signaturesRequired == 0 || signaturesRequired > uint64(len(publicKeys)) {
+		return types.UnlockConditions{}, errMultisigSignaturesRequired
+	}
+	uc := types.UnlockConditions{
+		PublicKeys:         publicKeys,
+		SignaturesRequired: signaturesRequired,
+	}
+
+	err := w.managedUpdateWatchAddresses(unused, func() error {
+		if err := dbPutUnlockConditions(w.dbTx, uc); err != nil {
+			return err
+		}
+		w.watchedAddrs[uc.UnlockHash()] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return uc, nil
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMultisig checks that the outputs of a 2-of-2 multisig address can be
+// spent once both cosigners have signed.
+func TestMultisig(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	cosigner, err := createWalletTester(t.Name()+"-cosigner", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cosigner.closeWt()
+
+	// Create the multisig address from a key of each wallet.
+	ucA, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ucB, err := cosigner.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeys := []types.SiaPublicKey{ucA.PublicKeys[0], ucB.PublicKeys[0]}
+	if _, err := wt.wallet.AddMultisigAddress(publicKeys, 3, true); err != errMultisigSignaturesRequired {
+		t.Fatal("expected errMultisigSignaturesRequired, got", err)
+	}
+	if _, err := wt.wallet.AddMultisigAddress(nil, 1, true); err != errMultisigNoPublicKeys {
+		t.Fatal("expected errMultisigNoPublicKeys, got", err)
+	}
+	uc, err := wt.wallet.AddMultisigAddress(publicKeys, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fund the address.
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.AddressTransactions(uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1 {
+		t.Fatal("expected one transaction for the multisig address, got", len(txns))
+	}
+
+	// Spend from the address.
+	outputs := []types.SiacoinOutput{{
+		Value:      types.SiacoinPrecision.Mul64(50),
+		UnlockHash: types.UnlockHash{},
+	}}
+	txn, toSign, err := wt.wallet.UnsignedTransaction(outputs, types.SiacoinPrecision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != 2 {
+		t.Fatal("expected two signatures, got", len(txn.TransactionSignatures))
+	}
+	err = wt.wallet.SignTransaction(&txn, toSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures[0].Signature) == 0 || len(txn.TransactionSignatures[1].Signature) != 0 {
+		t.Fatal("the wallet should only sign with its own key")
+	}
+	if err := wt.wallet.SignTransaction(&txn, toSign); err != errMissingSigningKey {
+		t.Fatal("expected errMissingSigningKey, got", err)
+	}
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err == nil {
+		t.Fatal("transaction with one of two signatures was accepted")
+	}
+	err = cosigner.wallet.SignTransaction(&txn, toSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -28,8 +29,49 @@ var (
 		Header:  "Wallet Database",
 		Version: "1.1.0",
 	}
+
+	errInvalidBackup  = errors.New("file is not a wallet backup")
+	errUnknownVersion = errors.New("wallet database was created by a newer version of Sia")
+	errWalletExists   = errors.New("cannot restore a backup over an existing wallet")
+
+	// dbMigrations upgrade the layout of the wallet database. The schema
+	// version that is stored in the database is the number of migrations that
+	// have been applied to it; databases that predate the schema version are
+	// at version 0. Migrations must only ever be appended.
+	dbMigrations = []func(*bolt.Tx) error{
+		// version 1: index the processed transactions by their memo
+		func(tx *bolt.Tx) error {
+			it := dbProcessedTransactionsIterator(tx)
+			for it.next() {
+				index, pt := it.key(), it.value()
+				if err := dbAddProcessedTransactionMemo(tx, pt, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
 )
 
+// dbMigrate applies the migrations that the database is missing. It is called
+// within the transaction that opens the database, so either all of the
+// migrations are applied or, if the wallet crashes, none of them are.
+func dbMigrate(tx *bolt.Tx) error {
+	version, err := dbGetSchemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if version > uint64(len(dbMigrations)) {
+		return errUnknownVersion
+	}
+	for ; version < uint64(len(dbMigrations)); version++ {
+		if err := dbMigrations[version](tx); err != nil {
+			return errors.AddContext(err, fmt.Sprintf("failed to migrate wallet database to version %v", version+1))
+		}
+	}
+	return dbPutSchemaVersion(tx, version)
+}
+
 // spendableKeyFile stores an encrypted spendable key on disk.
 type spendableKeyFile struct {
 	UID                    uniqueID
@@ -74,6 +116,12 @@ func (w *Wallet) openDB(filename string) (err error) {
 		if wb.Get(keySiafundPool) == nil {
 			wb.Put(keySiafundPool, encoding.Marshal(types.ZeroCurrency))
 		}
+		if wb.Get(keyWatchedAddrs) == nil {
+			wb.Put(keyWatchedAddrs, encoding.Marshal([]types.UnlockHash{}))
+		}
+		if wb.Get(keyLockedOutputs) == nil {
+			wb.Put(keyLockedOutputs, encoding.Marshal([]types.OutputID{}))
+		}
 
 		// build the bucketAddrTransactions bucket if necessary
 		if buildAddrTxns {
@@ -86,6 +134,11 @@ func (w *Wallet) openDB(filename string) (err error) {
 			}
 		}
 
+		// bring the layout of the database up to date
+		if err := dbMigrate(tx); err != nil {
+			return err
+		}
+
 		// check whether wallet is encrypted
 		w.encrypted = tx.Bucket(bucketWallet).Get(keyEncryptionVerification) != nil
 		return nil
@@ -153,7 +206,10 @@ func (w *Wallet) createBackup(dst io.Writer) error {
 	return err
 }
 
-// CreateBackup creates a backup file at the desired filepath.
+// CreateBackup creates a backup file at the desired filepath. The backup is a
+// copy of the wallet database, including its version, and is written
+// atomically so that an interrupted backup does not leave a partial file at
+// the filepath.
 func (w *Wallet) CreateBackup(backupFilepath string) error {
 	if err := w.tg.Add(); err != nil {
 		return err
@@ -161,12 +217,89 @@ func (w *Wallet) CreateBackup(backupFilepath string) error {
 	defer w.tg.Done()
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	f, err := os.Create(backupFilepath)
+	f, err := persist.NewSafeFile(backupFilepath)
+	if err != nil {
+		return err
+	}
+	if err := w.createBackup(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return f.CommitSync()
+}
+
+// checkBackup returns an error if the file at backupFilepath is not a wallet
+// database of the current version.
+func checkBackup(backupFilepath string) error {
+	db, err := bolt.Open(backupFilepath, 0600, &bolt.Options{ReadOnly: true, Timeout: 3 * time.Second})
+	if err != nil {
+		return errors.Compose(errInvalidBackup, err)
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		md := tx.Bucket([]byte("Metadata"))
+		if md == nil || string(md.Get([]byte("Header"))) != dbMetadata.Header {
+			return persist.ErrBadHeader
+		}
+		if string(md.Get([]byte("Version"))) != dbMetadata.Version {
+			return persist.ErrBadVersion
+		}
+		if tx.Bucket(bucketWallet) == nil {
+			return errInvalidBackup
+		}
+		return nil
+	})
+}
+
+// restoreBackup copies the backup at backupFilepath into persistDir, which
+// must not contain a wallet yet.
+func restoreBackup(backupFilepath, persistDir string) error {
+	dbFilename := filepath.Join(persistDir, dbFile)
+	for _, filename := range []string{dbFilename, filepath.Join(persistDir, compatFile)} {
+		if _, err := os.Stat(filename); err == nil {
+			return errWalletExists
+		}
+	}
+	if err := checkBackup(backupFilepath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		return err
+	}
+
+	src, err := os.Open(backupFilepath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := persist.NewSafeFile(dbFilename)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	return w.createBackup(f)
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	return dst.CommitSync()
+}
+
+// NewFromBackup restores a backup that was created by CreateBackup into
+// persistDir and returns the restored wallet, which is unlocked with the
+// encryption key of the wallet that was backed up. persistDir must not already
+// contain a wallet.
+func NewFromBackup(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir, backupFilepath string) (*Wallet, error) {
+	if cs == nil {
+		return nil, errNilConsensusSet
+	}
+	if tpool == nil {
+		return nil, errNilTpool
+	}
+	if err := restoreBackup(backupFilepath, persistDir); err != nil {
+		return nil, errors.AddContext(err, "unable to restore wallet backup")
+	}
+	return New(cs, tpool, persistDir)
 }
 
 // compat112Persist is the structure of the wallet.json file used in v1.1.2
@@ -186,7 +319,10 @@ var compat112Meta = persist.Metadata{
 }
 
 // convertPersistFrom112To120 converts an old (pre-v1.2.0) wallet.json file to
-// a wallet.db database.
+// a wallet.db database. The database is built in a temporary file that is only
+// moved into place once it is complete, so that an interrupted conversion is
+// started over instead of leaving behind a database without the wallet's
+// seeds.
 func (w *Wallet) convertPersistFrom112To120(dbFilename, compatFilename string) error {
 	var data compat112Persist
 	err := persist.LoadJSON(compat112Meta, &data, compatFilename)
@@ -194,12 +330,16 @@ func (w *Wallet) convertPersistFrom112To120(dbFilename, compatFilename string) e
 		return err
 	}
 
-	w.db, err = persist.OpenDatabase(dbMetadata, dbFilename)
+	tmpFilename := dbFilename + "_temp"
+	if err := os.Remove(tmpFilename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := persist.OpenDatabase(dbMetadata, tmpFilename)
 	if err != nil {
 		return err
 	}
 	// initialize the database
-	err = w.db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx *bolt.Tx) error {
 		for _, b := range dbBuckets {
 			_, err := tx.CreateBucket(b)
 			if err != nil {
@@ -212,6 +352,8 @@ func (w *Wallet) convertPersistFrom112To120(dbFilename, compatFilename string) e
 		tx.Bucket(bucketWallet).Put(keyPrimarySeedFile, encoding.Marshal(data.PrimarySeedFile))
 		tx.Bucket(bucketWallet).Put(keyAuxiliarySeedFiles, encoding.Marshal(data.AuxiliarySeedFiles))
 		tx.Bucket(bucketWallet).Put(keySpendableKeyFiles, encoding.Marshal(data.UnseededKeys))
+		tx.Bucket(bucketWallet).Put(keyWatchedAddrs, encoding.Marshal([]types.UnlockHash{}))
+		tx.Bucket(bucketWallet).Put(keyLockedOutputs, encoding.Marshal([]types.OutputID{}))
 		// old wallets had a "preload depth" of 25
 		dbPutPrimarySeedProgress(tx, data.PrimarySeedProgress+25)
 
@@ -221,8 +363,13 @@ func (w *Wallet) convertPersistFrom112To120(dbFilename, compatFilename string) e
 		dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning)
 		return nil
 	})
-	w.encrypted = true
-	return err
+	if err = errors.Compose(err, db.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFilename, dbFilename); err != nil {
+		return err
+	}
+	return w.openDB(dbFilename)
 }
 
 /*
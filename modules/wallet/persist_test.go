@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestBackupRestore checks that a wallet backup can be restored into a new
+// wallet, and that backups are not restored over existing wallets.
+func TestBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the backup. No temporary file should be left behind.
+	backup := filepath.Join(wt.persistDir, "wallet.backup")
+	if err := wt.wallet.CreateBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backup + "_temp"); !os.IsNotExist(err) {
+		t.Fatal("temporary backup file was not removed:", err)
+	}
+
+	// Backups are not restored over an existing wallet, and files that are
+	// not backups are rejected.
+	_, err = NewFromBackup(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir), backup)
+	if !errors.Contains(err, errWalletExists) {
+		t.Fatal("expected errWalletExists, got", err)
+	}
+	notBackup := filepath.Join(wt.persistDir, "notabackup")
+	if err := ioutil.WriteFile(notBackup, []byte("not a backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewFromBackup(wt.cs, wt.tpool, filepath.Join(wt.persistDir, "invalid"), notBackup)
+	if !errors.Contains(err, errInvalidBackup) {
+		t.Fatal("expected errInvalidBackup, got", err)
+	}
+
+	// Restore the backup into a new wallet, which should have the same seed
+	// and balance once it is unlocked.
+	w, err := NewFromBackup(wt.cs, wt.tpool, filepath.Join(wt.persistDir, "restored"), backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	restoredSeed, _, err := w.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredSeed != seed {
+		t.Fatal("restored wallet has a different seed")
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		restoredBalance, _, _, err := w.ConfirmedBalance()
+		if err != nil {
+			return err
+		}
+		if !restoredBalance.Equals(balance) {
+			return errors.New("restored wallet has a different balance")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDBMigrate checks that the migrations that a database is missing are
+// applied when the wallet is opened, and that databases of a newer version are
+// rejected.
+func TestDBMigrate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoinsWithMemo(types.SiacoinPrecision, uc.UnlockHash(), "migrate"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Turn the database into one that predates the memo index.
+	wt.wallet.mu.Lock()
+	if err := wt.wallet.dbTx.DeleteBucket(bucketMemoTransactions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.dbTx.CreateBucket(bucketMemoTransactions); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.dbTx.Bucket(bucketWallet).Delete(keySchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Unlock()
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening the wallet rebuilds the memo index.
+	dir := filepath.Join(wt.persistDir, modules.WalletDir)
+	wt.wallet, err = New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts, err := wt.wallet.MemoTransactions("migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 {
+		t.Fatal("memo index was not rebuilt, found transactions:", len(pts))
+	}
+	wt.wallet.mu.Lock()
+	version, err := dbGetSchemaVersion(wt.wallet.dbTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != uint64(len(dbMigrations)) {
+		t.Fatal("wrong schema version:", version)
+	}
+
+	// A database of a newer version cannot be opened.
+	if err := dbPutSchemaVersion(wt.wallet.dbTx, version+1); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Unlock()
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(wt.cs, wt.tpool, dir); !errors.Contains(err, errUnknownVersion) {
+		t.Fatal("expected errUnknownVersion, got", err)
+	}
+}
+
+// TestConvertPersist112 checks that a v1.1.2 wallet.json file is converted to
+// a database, even if an earlier conversion was interrupted.
+func TestConvertPersist112(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createBlankWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Write a v1.1.2 wallet file.
+	masterKey := crypto.GenerateTwofishKey()
+	var seed modules.Seed
+	fastrand.Read(seed[:])
+	var data compat112Persist
+	fastrand.Read(data.UID[:])
+	data.EncryptionVerification = uidEncryptionKey(masterKey, data.UID).EncryptBytes(verificationPlaintext)
+	data.PrimarySeedFile = createSeedFile(masterKey, seed)
+	data.AuxiliarySeedFiles = []seedFile{}
+	data.UnseededKeys = []spendableKeyFile{}
+	dir := filepath.Join(wt.persistDir, "compat")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := persist.SaveJSON(compat112Meta, data, filepath.Join(dir, compatFile)); err != nil {
+		t.Fatal(err)
+	}
+	// Leave behind the database of an interrupted conversion.
+	if err := ioutil.WriteFile(filepath.Join(dir, dbFile+"_temp"), []byte("interrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := os.Stat(filepath.Join(dir, dbFile+"_temp")); !os.IsNotExist(err) {
+		t.Fatal("temporary database was not moved into place:", err)
+	}
+	if err := w.Unlock(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	primarySeed, _, err := w.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primarySeed != seed {
+		t.Fatal("converted wallet has a different seed")
+	}
+}
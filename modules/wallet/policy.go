@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+// spendingPolicyPeriod is the period over which the MaxPerDay limit of the
+// spending policy applies.
+const spendingPolicyPeriod = 24 * time.Hour
+
+var (
+	errPolicyMaxPerDay         = errors.New("send exceeds the daily limit of the spending policy and has to be confirmed")
+	errPolicyMaxPerTransaction = errors.New("send exceeds the per-transaction limit of the spending policy and has to be confirmed")
+	errPolicyWhitelist         = errors.New("destination is not on the whitelist of the spending policy and has to be confirmed")
+)
+
+// SpendingPolicy returns the policy that limits the siacoins sent by the
+// wallet.
+func (w *Wallet) SpendingPolicy() (modules.SpendingPolicy, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.SpendingPolicy{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return dbGetSpendingPolicy(w.dbTx)
+}
+
+// SetSpendingPolicy sets the policy that limits the siacoins sent by the
+// wallet. The policy is stored in the wallet's database.
+func (w *Wallet) SetSpendingPolicy(p modules.SpendingPolicy) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutSpendingPolicy(w.dbTx, p); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
+
+// managedCheckSpendingPolicy returns an error if sending outputs and paying
+// fee violates the spending policy.
+func (w *Wallet) managedCheckSpendingPolicy(outputs []types.SiacoinOutput, fee types.Currency) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p, err := dbGetSpendingPolicy(w.dbTx)
+	if err != nil {
+		return err
+	}
+
+	if len(p.Whitelist) > 0 {
+		whitelist := make(map[types.UnlockHash]struct{}, len(p.Whitelist))
+		for _, addr := range p.Whitelist {
+			whitelist[addr] = struct{}{}
+		}
+		for _, sco := range outputs {
+			_, whitelisted := whitelist[sco.UnlockHash]
+			_, ours := w.keys[sco.UnlockHash]
+			if !whitelisted && !ours {
+				return errPolicyWhitelist
+			}
+		}
+	}
+
+	value := fee
+	for _, sco := range outputs {
+		value = value.Add(sco.Value)
+	}
+	if !p.MaxPerTransaction.IsZero() && value.Cmp(p.MaxPerTransaction) > 0 {
+		return errPolicyMaxPerTransaction
+	}
+	if !p.MaxPerDay.IsZero() {
+		spent, err := w.spentSince(time.Now().Add(-spendingPolicyPeriod))
+		if err != nil {
+			return err
+		}
+		if spent.Add(value).Cmp(p.MaxPerDay) > 0 {
+			return errPolicyMaxPerDay
+		}
+	}
+	return nil
+}
+
+// spentSince returns the siacoins that left the wallet in its unconfirmed
+// transactions and the transactions confirmed since t.
+func (w *Wallet) spentSince(t time.Time) (spent types.Currency, err error) {
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		spent = spent.Add(siacoinsSpent(pt))
+	}
+	cutoff := types.Timestamp(t.Unix())
+	for i := w.dbTx.Bucket(bucketProcessedTransactions).Sequence(); i > 0; i-- {
+		pt, err := dbGetProcessedTransaction(w.dbTx, i)
+		if err != nil {
+			return types.ZeroCurrency, err
+		}
+		if pt.ConfirmationTimestamp < cutoff {
+			break
+		}
+		spent = spent.Add(siacoinsSpent(pt))
+	}
+	return spent, nil
+}
+
+// siacoinsSpent returns the siacoins that left the wallet in pt, which are
+// the siacoins spent from the wallet's addresses minus those sent back to
+// them.
+func siacoinsSpent(pt modules.ProcessedTransaction) types.Currency {
+	var in, out types.Currency
+	for _, input := range pt.Inputs {
+		if input.FundType == types.SpecifierSiacoinInput && input.WalletAddress {
+			in = in.Add(input.Value)
+		}
+	}
+	for _, output := range pt.Outputs {
+		if output.FundType == types.SpecifierSiacoinOutput && output.WalletAddress {
+			out = out.Add(output.Value)
+		}
+	}
+	if in.Cmp(out) <= 0 {
+		return types.ZeroCurrency
+	}
+	return in.Sub(out)
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSpendingPolicy checks that sends that violate the spending policy are
+// rejected unless they are confirmed.
+func TestSpendingPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	sc := types.SiacoinPrecision
+	allowed := types.UnlockHash{1}
+	policy := modules.SpendingPolicy{
+		MaxPerTransaction: sc.Mul64(150),
+		MaxPerDay:         sc.Mul64(250),
+		Whitelist:         []types.UnlockHash{allowed},
+	}
+	if err := wt.wallet.SetSpendingPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := wt.wallet.SpendingPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.MaxPerTransaction.Cmp(policy.MaxPerTransaction) != 0 || stored.MaxPerDay.Cmp(policy.MaxPerDay) != 0 || len(stored.Whitelist) != 1 || stored.Whitelist[0] != allowed {
+		t.Fatal("spending policy was not stored:", stored)
+	}
+
+	// Only whitelisted addresses and the wallet's own addresses can be sent
+	// to.
+	if _, err := wt.wallet.SendSiacoins(sc, types.UnlockHash{2}); err != errPolicyWhitelist {
+		t.Fatal("expected errPolicyWhitelist, got", err)
+	}
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(sc, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sends above the per-transaction limit are rejected.
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(200), allowed); err != errPolicyMaxPerTransaction {
+		t.Fatal("expected errPolicyMaxPerTransaction, got", err)
+	}
+
+	// The second send of 100 SC exceeds the daily limit once the fees of
+	// both sends are added.
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(100), allowed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(100), allowed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(50), allowed); err != errPolicyMaxPerDay {
+		t.Fatal("expected errPolicyMaxPerDay, got", err)
+	}
+	outputs := []types.SiacoinOutput{{Value: sc.Mul64(50), UnlockHash: allowed}}
+	if _, err := wt.wallet.SendSiacoinsMulti(outputs); err != errPolicyMaxPerDay {
+		t.Fatal("expected errPolicyMaxPerDay, got", err)
+	}
+
+	// Confirming the sends overrides the policy, and confirmed sends still
+	// count towards the daily limit once they are mined.
+	outputs = []types.SiacoinOutput{{Value: sc.Mul64(500), UnlockHash: types.UnlockHash{2}}}
+	if _, err := wt.wallet.SendSiacoinsMultiConfirmed(outputs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetSpendingPolicy(modules.SpendingPolicy{MaxPerDay: sc.Mul64(1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(300), types.UnlockHash{2}); err != errPolicyMaxPerDay {
+		t.Fatal("expected errPolicyMaxPerDay, got", err)
+	}
+
+	// The empty policy does not limit sends.
+	if err := wt.wallet.SetSpendingPolicy(modules.SpendingPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(sc.Mul64(300), types.UnlockHash{2}); err != nil {
+		t.Fatal(err)
+	}
+}
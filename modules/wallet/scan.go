@@ -44,22 +44,27 @@ var numInitialKeys = func() uint64 {
 }()
 
 // A scannedOutput is an output found in the blockchain that was generated
-// from a given seed.
+// from a given seed. The seedIndex of a change output is its change index.
 type scannedOutput struct {
 	id        types.OutputID
 	value     types.Currency
 	seedIndex uint64
+	change    bool
 }
 
 // A seedScanner scans the blockchain for addresses that belong to a given
-// seed.
+// seed, or to a fixed set of keys. The change addresses of a seed are scanned
+// for alongside its other addresses.
 type seedScanner struct {
-	dustThreshold    types.Currency              // minimum value of outputs to be included
-	keys             map[types.UnlockHash]uint64 // map address to seed index
-	largestIndexSeen uint64                      // largest index that has appeared in the blockchain
-	seed             modules.Seed
-	siacoinOutputs   map[types.SiacoinOutputID]scannedOutput
-	siafundOutputs   map[types.SiafundOutputID]scannedOutput
+	dustThreshold          types.Currency              // minimum value of outputs to be included
+	fixedKeys              []spendableKey              // keys to scan for instead of the seed's keys
+	keys                   map[types.UnlockHash]uint64 // map address to seed index
+	changeKeys             map[types.UnlockHash]uint64 // map change address to change index
+	largestIndexSeen       uint64                      // largest index that has appeared in the blockchain
+	largestChangeIndexSeen uint64                      // largest change index that has appeared in the blockchain
+	seed                   modules.Seed
+	siacoinOutputs         map[types.SiacoinOutputID]scannedOutput
+	siafundOutputs         map[types.SiafundOutputID]scannedOutput
 
 	log *persist.Logger
 }
@@ -68,12 +73,53 @@ func (s *seedScanner) numKeys() uint64 {
 	return uint64(len(s.keys))
 }
 
-// generateKeys generates n additional keys from the seedScanner's seed.
+// spendableKey returns the key of a scanned output, whose seedIndex is either
+// the seed index or change index of the key, or its index in s.fixedKeys.
+func (s *seedScanner) spendableKey(output scannedOutput) spendableKey {
+	if s.fixedKeys != nil {
+		return s.fixedKeys[output.seedIndex]
+	}
+	if output.change {
+		return generateSpendableKey(s.seed, changeIndexOffset+output.seedIndex)
+	}
+	return generateSpendableKey(s.seed, output.seedIndex)
+}
+
+// generateKeys generates n additional keys and n additional change keys from
+// the seedScanner's seed.
 func (s *seedScanner) generateKeys(n uint64) {
 	initialProgress := s.numKeys()
 	for i, k := range generateKeys(s.seed, initialProgress, n) {
 		s.keys[k.UnlockConditions.UnlockHash()] = initialProgress + uint64(i)
 	}
+	for i, k := range generateKeys(s.seed, changeIndexOffset+initialProgress, n) {
+		s.changeKeys[k.UnlockConditions.UnlockHash()] = initialProgress + uint64(i)
+	}
+}
+
+// lookup returns the index of the key of an address, and whether it is a
+// change index.
+func (s *seedScanner) lookup(uh types.UnlockHash) (index uint64, change bool, exists bool) {
+	if index, exists = s.keys[uh]; exists {
+		return index, false, true
+	}
+	index, exists = s.changeKeys[uh]
+	return index, exists, exists
+}
+
+// markSeen updates the largest index seen for the key at index.
+func (s *seedScanner) markSeen(index uint64, change bool) {
+	if change {
+		s.log.Debugln("Seed scanner found a change key used at index", index)
+		if index > s.largestChangeIndexSeen {
+			s.largestChangeIndexSeen = index
+		}
+		return
+	}
+	s.log.Debugln("Seed scanner found a key used at index", index)
+	if index > s.largestIndexSeen {
+		s.largestIndexSeen = index
+	}
 }
 
 // ProcessConsensusChange scans the blockchain for information relevant to the
@@ -82,17 +128,18 @@ func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// update outputs
 	for _, diff := range cc.SiacoinOutputDiffs {
 		if diff.Direction == modules.DiffApply {
-			if index, exists := s.keys[diff.SiacoinOutput.UnlockHash]; exists && diff.SiacoinOutput.Value.Cmp(s.dustThreshold) > 0 {
+			if index, change, exists := s.lookup(diff.SiacoinOutput.UnlockHash); exists && diff.SiacoinOutput.Value.Cmp(s.dustThreshold) > 0 {
 				s.siacoinOutputs[diff.ID] = scannedOutput{
 					id:        types.OutputID(diff.ID),
 					value:     diff.SiacoinOutput.Value,
 					seedIndex: index,
+					change:    change,
 				}
 			}
 		} else if diff.Direction == modules.DiffRevert {
 			// NOTE: DiffRevert means the output was either spent or was in a
 			// block that was reverted.
-			if _, exists := s.keys[diff.SiacoinOutput.UnlockHash]; exists {
+			if _, _, exists := s.lookup(diff.SiacoinOutput.UnlockHash); exists {
 				delete(s.siacoinOutputs, diff.ID)
 			}
 		}
@@ -101,39 +148,32 @@ func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
 		if diff.Direction == modules.DiffApply {
 			// do not compare against dustThreshold here; we always want to
 			// sweep every siafund found
-			if index, exists := s.keys[diff.SiafundOutput.UnlockHash]; exists {
+			if index, change, exists := s.lookup(diff.SiafundOutput.UnlockHash); exists {
 				s.siafundOutputs[diff.ID] = scannedOutput{
 					id:        types.OutputID(diff.ID),
 					value:     diff.SiafundOutput.Value,
 					seedIndex: index,
+					change:    change,
 				}
 			}
 		} else if diff.Direction == modules.DiffRevert {
 			// NOTE: DiffRevert means the output was either spent or was in a
 			// block that was reverted.
-			if _, exists := s.keys[diff.SiafundOutput.UnlockHash]; exists {
+			if _, _, exists := s.lookup(diff.SiafundOutput.UnlockHash); exists {
 				delete(s.siafundOutputs, diff.ID)
 			}
 		}
 	}
 
-	// update s.largestIndexSeen
+	// update s.largestIndexSeen and s.largestChangeIndexSeen
 	for _, diff := range cc.SiacoinOutputDiffs {
-		index, exists := s.keys[diff.SiacoinOutput.UnlockHash]
-		if exists {
-			s.log.Debugln("Seed scanner found a key used at index", index)
-			if index > s.largestIndexSeen {
-				s.largestIndexSeen = index
-			}
+		if index, change, exists := s.lookup(diff.SiacoinOutput.UnlockHash); exists {
+			s.markSeen(index, change)
 		}
 	}
 	for _, diff := range cc.SiafundOutputDiffs {
-		index, exists := s.keys[diff.SiafundOutput.UnlockHash]
-		if exists {
-			s.log.Debugln("Seed scanner found a key used at index", index)
-			if index > s.largestIndexSeen {
-				s.largestIndexSeen = index
-			}
+		if index, change, exists := s.lookup(diff.SiafundOutput.UnlockHash); exists {
+			s.markSeen(index, change)
 		}
 	}
 }
@@ -149,6 +189,15 @@ func (s *seedScanner) scan(cs modules.ConsensusSet, cancel <-chan struct{}) erro
 	//
 	// NOTE: since scanning is very slow, we aim to only scan once, which
 	// means generating many keys.
+	//
+	// A fixed set of keys only needs to be scanned for once.
+	if s.fixedKeys != nil {
+		if err := cs.ConsensusSetSubscribe(s, modules.ConsensusChangeBeginning, cancel); err != nil {
+			return err
+		}
+		cs.Unsubscribe(s)
+		return nil
+	}
 	var numKeys uint64 = numInitialKeys
 	for s.numKeys() < maxScanKeys {
 		s.generateKeys(numKeys)
@@ -156,7 +205,7 @@ func (s *seedScanner) scan(cs modules.ConsensusSet, cancel <-chan struct{}) erro
 			return err
 		}
 		cs.Unsubscribe(s)
-		if s.largestIndexSeen < s.numKeys()/2 {
+		if s.largestIndexSeen < s.numKeys()/2 && s.largestChangeIndexSeen < s.numKeys()/2 {
 			return nil
 		}
 		// increase number of keys generated each iteration, capping so that
@@ -174,9 +223,27 @@ func newSeedScanner(seed modules.Seed, log *persist.Logger) *seedScanner {
 	return &seedScanner{
 		seed:           seed,
 		keys:           make(map[types.UnlockHash]uint64, numInitialKeys),
+		changeKeys:     make(map[types.UnlockHash]uint64, numInitialKeys),
+		siacoinOutputs: make(map[types.SiacoinOutputID]scannedOutput),
+		siafundOutputs: make(map[types.SiafundOutputID]scannedOutput),
+
+		log: log,
+	}
+}
+
+// newKeyScanner returns a seedScanner that scans the blockchain for the
+// addresses of a fixed set of keys.
+func newKeyScanner(keys []spendableKey, log *persist.Logger) *seedScanner {
+	s := &seedScanner{
+		fixedKeys:      keys,
+		keys:           make(map[types.UnlockHash]uint64, len(keys)),
 		siacoinOutputs: make(map[types.SiacoinOutputID]scannedOutput),
 		siafundOutputs: make(map[types.SiafundOutputID]scannedOutput),
 
 		log: log,
 	}
+	for i, k := range keys {
+		s.keys[k.UnlockConditions.UnlockHash()] = uint64(i)
+	}
+	return s
 }
@@ -33,9 +33,11 @@ func TestScanLargeIndex(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// set the wallet's seed progress to a high number and then mine some coins.
+	// set the wallet's seed and change progress to a high number and then
+	// mine some coins.
 	wt.wallet.mu.Lock()
 	dbPutPrimarySeedProgress(wt.wallet.dbTx, numInitialKeys+1)
+	dbPutChangeProgress(wt.wallet.dbTx, numInitialKeys+1)
 	wt.wallet.mu.Unlock()
 	if err != nil {
 		t.Fatal(err)
@@ -81,6 +83,9 @@ func TestScanLargeIndex(t *testing.T) {
 	if ss.largestIndexSeen != 0 {
 		t.Error("expected no index to be seen, got", ss.largestIndexSeen)
 	}
+	if ss.largestChangeIndexSeen != 0 {
+		t.Error("expected no change index to be seen, got", ss.largestChangeIndexSeen)
+	}
 }
 
 // TestScanLoop tests that the scan loop will continue to run as long as it
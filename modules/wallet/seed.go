@@ -65,6 +65,15 @@ func generateKeys(seed modules.Seed, start, n uint64) []spendableKey {
 	return keys
 }
 
+// GenerateKey derives the unlock conditions and secret key of the address at
+// the given index of a seed. It does not need a wallet, so it can be used
+// offline to create cold storage addresses. A wallet that loads the seed will
+// find the address, and the secret key alone can be swept with SweepKey.
+func GenerateKey(seed modules.Seed, index uint64) (types.UnlockConditions, crypto.SecretKey) {
+	sk := generateSpendableKey(seed, index)
+	return sk.UnlockConditions, sk.SecretKeys[0]
+}
+
 // createSeedFile creates and encrypts a seedFile.
 func createSeedFile(masterKey crypto.TwofishKey, seed modules.Seed) seedFile {
 	var sf seedFile
@@ -104,12 +113,49 @@ func (w *Wallet) regenerateLookahead(start uint64) {
 	}
 }
 
-// integrateSeed generates n spendableKeys from the seed and loads them into
-// the wallet.
+// regenerateChangeLookahead creates future change keys up to a maximum of
+// maxKeys keys.
+func (w *Wallet) regenerateChangeLookahead(start uint64) {
+	maxKeys := maxLookahead(start)
+	existingKeys := uint64(len(w.changeLookahead))
+
+	for i, k := range generateKeys(w.primarySeed, changeIndexOffset+start+existingKeys, maxKeys-existingKeys) {
+		w.changeLookahead[k.UnlockConditions.UnlockHash()] = start + existingKeys + uint64(i)
+	}
+}
+
+// integrateSeed generates n spendableKeys and n change keys from the seed and
+// loads them into the wallet.
 func (w *Wallet) integrateSeed(seed modules.Seed, n uint64) {
 	for _, sk := range generateKeys(seed, 0, n) {
 		w.keys[sk.UnlockConditions.UnlockHash()] = sk
 	}
+	for _, sk := range generateKeys(seed, changeIndexOffset, n) {
+		w.keys[sk.UnlockConditions.UnlockHash()] = sk
+		w.changeAddrs[sk.UnlockConditions.UnlockHash()] = struct{}{}
+	}
+}
+
+// integratePrimarySeed loads the first n keys and the first changeN change
+// keys of the primary seed into the wallet, and records their indices for the
+// wallet's Signer.
+func (w *Wallet) integratePrimarySeed(seed modules.Seed, n, changeN uint64) {
+	for i, sk := range generateKeys(seed, 0, n) {
+		w.keys[sk.UnlockConditions.UnlockHash()] = sk
+		w.primaryKeyIndices[sk.SecretKeys[0].PublicKey()] = uint64(i)
+	}
+	for i, sk := range generateKeys(seed, changeIndexOffset, changeN) {
+		w.integrateChangeKey(sk, uint64(i))
+	}
+}
+
+// integrateChangeKey loads the change key of the primary seed with the given
+// change index into the wallet.
+func (w *Wallet) integrateChangeKey(sk spendableKey, index uint64) {
+	w.keys[sk.UnlockConditions.UnlockHash()] = sk
+	w.changeAddrs[sk.UnlockConditions.UnlockHash()] = struct{}{}
+	w.primaryKeyIndices[sk.SecretKeys[0].PublicKey()] = changeIndexOffset + index
+	delete(w.changeLookahead, sk.UnlockConditions.UnlockHash())
 }
 
 // nextPrimarySeedAddress fetches the next n addresses from the primary seed.
@@ -132,8 +178,9 @@ func (w *Wallet) nextPrimarySeedAddresses(tx *bolt.Tx, n uint64) ([]types.Unlock
 	// according to new progress
 	spendableKeys := generateKeys(w.primarySeed, progress, n)
 	ucs := make([]types.UnlockConditions, 0, len(spendableKeys))
-	for _, spendableKey := range spendableKeys {
+	for i, spendableKey := range spendableKeys {
 		w.keys[spendableKey.UnlockConditions.UnlockHash()] = spendableKey
+		w.primaryKeyIndices[spendableKey.SecretKeys[0].PublicKey()] = progress + uint64(i)
 		delete(w.lookahead, spendableKey.UnlockConditions.UnlockHash())
 		ucs = append(ucs, spendableKey.UnlockConditions)
 	}
@@ -151,6 +198,29 @@ func (w *Wallet) nextPrimarySeedAddress(tx *bolt.Tx) (types.UnlockConditions, er
 	return ucs[0], nil
 }
 
+// nextChangeAddress fetches the next change address from the primary seed.
+// Change addresses are only used by the wallet itself, for the outputs that
+// return the excess value of a transaction's inputs to the wallet.
+func (w *Wallet) nextChangeAddress(tx *bolt.Tx) (types.UnlockConditions, error) {
+	// Check that the wallet has been unlocked.
+	if !w.unlocked {
+		return types.UnlockConditions{}, modules.ErrLockedWallet
+	}
+
+	// Fetch and increment the change progress.
+	progress, err := dbGetChangeProgress(tx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	if err = dbPutChangeProgress(tx, progress+1); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	sk := generateSpendableKey(w.primarySeed, changeIndexOffset+progress)
+	w.integrateChangeKey(sk, progress)
+	w.regenerateChangeLookahead(progress + 1)
+	return sk.UnlockConditions, nil
+}
+
 // AllSeeds returns a list of all seeds known to and used by the wallet.
 func (w *Wallet) AllSeeds() ([]modules.Seed, error) {
 	w.mu.Lock()
@@ -219,6 +289,61 @@ func (w *Wallet) NextAddress() (types.UnlockConditions, error) {
 	return ucs[0], nil
 }
 
+// addressUsed returns true if the address appears in a confirmed or
+// unconfirmed wallet transaction.
+func (w *Wallet) addressUsed(uh types.UnlockHash) bool {
+	if txns, err := dbGetAddrTransactions(w.dbTx, uh); err == nil && len(txns) > 0 {
+		return true
+	}
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		for _, input := range pt.Inputs {
+			if input.RelatedAddress == uh {
+				return true
+			}
+		}
+		for _, output := range pt.Outputs {
+			if output.RelatedAddress == uh {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnusedAddress returns an unlock hash that is ready to receive siacoins or
+// siafunds and has not been used yet. The most recently generated primary
+// seed address is returned until it appears in a transaction, after which a
+// new address is generated. Unlike NextAddress, repeated calls do not grow the
+// gap between used addresses, so the lookahead and the seed scanner are able
+// to find every address when the wallet is recovered from its seed.
+func (w *Wallet) UnusedAddress() (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return types.UnlockConditions{}, modules.ErrLockedWallet
+	}
+	progress, err := dbGetPrimarySeedProgress(w.dbTx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	if progress > 0 {
+		sk := generateSpendableKey(w.primarySeed, progress-1)
+		if !w.addressUsed(sk.UnlockConditions.UnlockHash()) {
+			return sk.UnlockConditions, nil
+		}
+	}
+	uc, err := w.nextPrimarySeedAddress(w.dbTx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return uc, w.syncDB()
+}
+
 // LoadSeed will track all of the addresses generated by the input seed,
 // reclaiming any funds that were lost due to a deleted file or lost encryption
 // key. An error will be returned if the seed has already been integrated with
@@ -260,10 +385,15 @@ func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error
 		return err
 	}
 	// Add 4% as a buffer because the seed may have addresses in the wild
-	// that have not appeared in the blockchain yet.
-	seedProgress := s.largestIndexSeen + 500
+	// that have not appeared in the blockchain yet. The same number of
+	// change keys is generated.
+	largestIndexSeen := s.largestIndexSeen
+	if s.largestChangeIndexSeen > largestIndexSeen {
+		largestIndexSeen = s.largestChangeIndexSeen
+	}
+	seedProgress := largestIndexSeen + 500
 	seedProgress += seedProgress / 25
-	w.log.Printf("INFO: found key index %v in blockchain. Setting auxiliary seed progress to %v", s.largestIndexSeen, seedProgress)
+	w.log.Printf("INFO: found key index %v in blockchain. Setting auxiliary seed progress to %v", largestIndexSeen, seedProgress)
 
 	err := func() error {
 		w.mu.Lock()
@@ -351,6 +481,45 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 		return types.Currency{}, types.Currency{}, errors.New("cannot sweep primary seed")
 	}
 
+	return w.managedSweep(newSeedScanner(seed, w.log))
+}
+
+// SweepKey scans the blockchain for outputs of the standard address of a
+// secret key, such as the key of a paper wallet, and creates a transaction
+// that transfers them to the wallet. Like SweepSeed, this incurs a
+// transaction fee.
+func (w *Wallet) SweepKey(sk crypto.SecretKey) (coins, funds types.Currency, err error) {
+	if err = w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	if !w.scanLock.TryLock() {
+		return types.Currency{}, types.Currency{}, errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	key := spendableKey{
+		UnlockConditions: types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{types.Ed25519PublicKey(sk.PublicKey())},
+			SignaturesRequired: 1,
+		},
+		SecretKeys: []crypto.SecretKey{sk},
+	}
+	w.mu.RLock()
+	_, match := w.keys[key.UnlockConditions.UnlockHash()]
+	w.mu.RUnlock()
+	if match {
+		return types.Currency{}, types.Currency{}, errors.New("cannot sweep a key of the wallet")
+	}
+
+	return w.managedSweep(newKeyScanner([]spendableKey{key}, w.log))
+}
+
+// managedSweep scans the blockchain for the outputs of the keys of s and
+// creates transactions that transfer them to the wallet. The caller must hold
+// the scanLock.
+func (w *Wallet) managedSweep(s *seedScanner) (coins, funds types.Currency, err error) {
 	if !w.cs.Synced() {
 		return types.Currency{}, types.Currency{}, errors.New("cannot sweep until blockchain is synced")
 	}
@@ -365,7 +534,6 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 
 	// scan blockchain for outputs, filtering out 'dust' (outputs that cost
 	// more in fees than they are worth)
-	s := newSeedScanner(seed, w.log)
 	_, maxFee := w.tpool.FeeEstimation()
 	const outputSize = 350 // approx. size in bytes of an output and accompanying signature
 	const maxOutputs = 50  // approx. number of outputs that a transaction can handle
@@ -417,7 +585,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 		var sweptCoins, sweptFunds types.Currency // total values of swept outputs
 		for _, output := range txnSiacoinOutputs {
 			// construct a siacoin input that spends the output
-			sk := generateSpendableKey(seed, output.seedIndex)
+			sk := s.spendableKey(output)
 			tb.AddSiacoinInput(types.SiacoinInput{
 				ParentID:         types.SiacoinOutputID(output.id),
 				UnlockConditions: sk.UnlockConditions,
@@ -427,7 +595,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 		}
 		for _, output := range txnSiafundOutputs {
 			// construct a siafund input that spends the output
-			sk := generateSpendableKey(seed, output.seedIndex)
+			sk := s.spendableKey(output)
 			tb.AddSiafundInput(types.SiafundInput{
 				ParentID:         types.SiafundOutputID(output.id),
 				UnlockConditions: sk.UnlockConditions,
@@ -495,12 +663,18 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 		// access to the signing keys)
 		txn, parents := tb.View()
 		for _, output := range txnSiacoinOutputs {
-			sk := generateSpendableKey(seed, output.seedIndex)
-			addSignatures(&txn, types.FullCoveredFields, sk.UnlockConditions, crypto.Hash(output.id), sk)
+			sk := s.spendableKey(output)
+			_, err = addSignatures(&txn, types.FullCoveredFields, sk.UnlockConditions, crypto.Hash(output.id), sk, signInMemory)
+			if err != nil {
+				return types.ZeroCurrency, types.ZeroCurrency, err
+			}
 		}
 		for _, sfo := range txnSiafundOutputs {
-			sk := generateSpendableKey(seed, sfo.seedIndex)
-			addSignatures(&txn, types.FullCoveredFields, sk.UnlockConditions, crypto.Hash(sfo.id), sk)
+			sk := s.spendableKey(sfo)
+			_, err = addSignatures(&txn, types.FullCoveredFields, sk.UnlockConditions, crypto.Hash(sfo.id), sk, signInMemory)
+			if err != nil {
+				return types.ZeroCurrency, types.ZeroCurrency, err
+			}
 		}
 		// Usually, all the inputs will come from swept outputs. However, there is
 		// an edge case in which inputs will be added from the wallet. To cover
@@ -509,10 +683,16 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 		w.mu.RLock()
 		for _, input := range txn.SiacoinInputs {
 			if key, ok := w.keys[input.UnlockConditions.UnlockHash()]; ok {
-				addSignatures(&txn, types.FullCoveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key)
+				_, err = addSignatures(&txn, types.FullCoveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key, w.signHash)
+				if err != nil {
+					break
+				}
 			}
 		}
 		w.mu.RUnlock()
+		if err != nil {
+			return types.ZeroCurrency, types.ZeroCurrency, err
+		}
 
 		// Append transaction to txnSet
 		txnSet := append(parents, txn)
@@ -523,7 +703,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 			return types.ZeroCurrency, types.ZeroCurrency, err
 		}
 
-		w.log.Println("Creating a transaction set to sweep a seed, IDs:")
+		w.log.Println("Creating a transaction set to sweep outputs, IDs:")
 		for _, txn := range txnSet {
 			w.log.Println("\t", txn.ID())
 		}
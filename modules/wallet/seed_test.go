@@ -2,8 +2,10 @@ package wallet
 
 import (
 	"bytes"
+	"errors"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -84,6 +86,66 @@ func TestPrimarySeed(t *testing.T) {
 	}
 }
 
+// TestUnusedAddress checks that UnusedAddress keeps returning the same address
+// until it has been used.
+func TestUnusedAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc1, err := wt.wallet.UnusedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc2, err := wt.wallet.UnusedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc1.UnlockHash() != uc2.UnlockHash() {
+		t.Fatal("UnusedAddress returned a new address for an unused one")
+	}
+
+	// Send coins to the address. An unconfirmed transaction is enough to
+	// mark it as used.
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, uc1.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc3, err := wt.wallet.UnusedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc3.UnlockHash() == uc1.UnlockHash() {
+		t.Fatal("UnusedAddress returned an address that was already used")
+	}
+
+	// The address should stay used once the transaction is confirmed.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	uc4, err := wt.wallet.UnusedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc4.UnlockHash() == uc1.UnlockHash() {
+		t.Fatal("UnusedAddress returned an address that was already used")
+	}
+
+	// A locked wallet can't hand out addresses.
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.UnusedAddress(); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+}
+
 // TestLoadSeed checks that a seed can be successfully recovered from a wallet,
 // and then remain available on subsequent loads of the wallet.
 func TestLoadSeed(t *testing.T) {
@@ -507,6 +569,82 @@ func TestSweepSeedCoinsAndFunds(t *testing.T) {
 	}
 }
 
+// TestSweepKey tests that sweeping a secret key results in the transfer of
+// the siacoin outputs of its address to the wallet.
+func TestSweepKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// send money to the address of a paper wallet key
+	sk, pk := crypto.GenerateKeyPair()
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{types.Ed25519PublicKey(pk)},
+		SignaturesRequired: 1,
+	}
+	sent := types.SiacoinPrecision.Mul64(100)
+	_, err = wt.wallet.SendSiacoins(sent, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sweep the key into the wallet once the consensus set is synced
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if !wt.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sweptCoins, sweptFunds, err := wt.wallet.SweepKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sweptCoins.IsZero() || sweptCoins.Cmp(sent) >= 0 || !sweptFunds.IsZero() {
+		t.Fatalf("wrong amount swept: %v coins, %v funds", sweptCoins, sweptFunds)
+	}
+	_, incoming, err := wt.wallet.UnconfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incoming.Cmp(sweptCoins) != 0 {
+		t.Fatalf("wallet should have correct balance after sweeping key: wanted %v, got %v", sweptCoins, incoming)
+	}
+
+	// once the sweep is confirmed, there is nothing left to sweep
+	_, err = wt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := wt.wallet.SweepKey(sk); err == nil {
+		t.Fatal("expected an error when sweeping an empty key")
+	}
+
+	// the keys of the wallet cannot be swept
+	walletUC, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	walletKey := wt.wallet.keys[walletUC.UnlockHash()].SecretKeys[0]
+	wt.wallet.mu.RUnlock()
+	if _, _, err := wt.wallet.SweepKey(walletKey); err == nil {
+		t.Fatal("expected an error when sweeping a key of the wallet")
+	}
+}
+
 // TestGenerateKeys tests that the generateKeys function correctly generates a
 // key for every index specified.
 func TestGenerateKeys(t *testing.T) {
@@ -516,3 +654,114 @@ func TestGenerateKeys(t *testing.T) {
 		}
 	}
 }
+
+// TestGenerateKey checks that GenerateKey derives the same addresses as a
+// wallet that uses the seed.
+func TestGenerateKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createBlankWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	seed, err := wt.wallet.Encrypt(crypto.TwofishKey{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Unlock(crypto.TwofishKey(crypto.HashObject(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint64(0); i < 3; i++ {
+		walletUC, err := wt.wallet.NextAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uc, sk := GenerateKey(seed, i)
+		if uc.UnlockHash() != walletUC.UnlockHash() {
+			t.Errorf("index %v: GenerateKey returned a different address than the wallet", i)
+		}
+		pk := sk.PublicKey()
+		if !bytes.Equal(uc.PublicKeys[0].Key, pk[:]) {
+			t.Errorf("index %v: secret key does not belong to the address", i)
+		}
+	}
+}
+
+// TestChangeAddresses checks that the wallet sends change to its change
+// addresses, which do not use up addresses of the primary seed, and that
+// change is not counted as incoming siacoins.
+func TestChangeAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	seed, remaining, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.SiacoinPrecision.Mul64(100)
+	txns, err := wt.wallet.SendSiacoins(amount, types.UnlockHash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, remainingAfter, err := wt.wallet.PrimarySeed(); err != nil {
+		t.Fatal(err)
+	} else if remainingAfter != remaining {
+		t.Fatal("sending siacoins used up addresses of the primary seed")
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first change address of the seed received change.
+	changeAddr := generateSpendableKey(seed, changeIndexOffset).UnlockConditions.UnlockHash()
+	var changeSeen bool
+	for _, txn := range txns {
+		pt, found, err := wt.wallet.Transaction(txn.ID())
+		if err != nil {
+			t.Fatal(err)
+		} else if !found {
+			t.Fatal("transaction not found in the wallet's history")
+		}
+		change := make(map[types.OutputID]struct{})
+		for _, id := range pt.ChangeOutputs {
+			change[id] = struct{}{}
+		}
+		for _, po := range pt.Outputs {
+			if po.FundType != types.SpecifierSiacoinOutput || !po.WalletAddress {
+				continue
+			}
+			if _, isChange := change[po.ID]; !isChange {
+				t.Fatal("output to the wallet was not marked as change")
+			}
+			changeSeen = changeSeen || po.RelatedAddress == changeAddr
+		}
+		if pts := pt.Summary(wt.cs.Height()); !pts.IncomingSiacoins.IsZero() {
+			t.Fatal("change was counted as incoming siacoins:", pts.IncomingSiacoins)
+		}
+	}
+	if !changeSeen {
+		t.Fatal("first change address of the seed did not receive change")
+	}
+
+	// The transaction that pays the recipient only spends the amount and
+	// the fee.
+	pt, _, err := wt.wallet.Transaction(txns[len(txns)-1].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts := pt.Summary(wt.cs.Height())
+	if !pts.OutgoingSiacoins.Equals(amount.Add(pts.Fees)) {
+		t.Fatalf("expected outgoing siacoins of %v, got %v", amount.Add(pts.Fees), pts.OutgoingSiacoins)
+	}
+}
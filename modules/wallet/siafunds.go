@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// managedClaimableSiafunds returns the siafunds of the confirmed siafund
+// outputs that the wallet can spend, and the siacoin claim balance that has
+// accrued to them.
+func (w *Wallet) managedClaimableSiafunds() (funds, claim types.Currency, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, err
+	}
+	siafundPool, err := dbGetSiafundPool(w.dbTx)
+	if err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, err
+	}
+	err = dbForEachSiafundOutput(w.dbTx, func(sfoid types.SiafundOutputID, sfo types.SiafundOutput) {
+		// Skip outputs that FundSiafunds would not spend.
+		if _, exists := w.keys[sfo.UnlockHash]; !exists {
+			return
+		}
+		if _, locked := w.lockedOutputs[types.OutputID(sfoid)]; locked {
+			return
+		}
+		spendHeight, err := dbGetSpentOutput(w.dbTx, types.OutputID(sfoid))
+		if err == nil && spendHeight+RespendTimeout > consensusHeight {
+			return
+		}
+		funds = funds.Add(sfo.Value)
+		if sfo.ClaimStart.Cmp(siafundPool) < 0 {
+			claim = claim.Add(siafundPool.Sub(sfo.ClaimStart).Mul(sfo.Value).Div(types.SiafundCount))
+		}
+	})
+	return funds, claim, err
+}
+
+// threadedClaimSiafunds sends the wallet's siafunds to itself once the siacoin
+// claim balance that has accrued to them reaches the claim threshold of the
+// wallet's settings. Spending a siafund output pays out its claim balance, so
+// this moves the claim balance into the wallet's siacoin balance once the
+// claim output matures.
+func (w *Wallet) threadedClaimSiafunds() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	threshold := w.claimThreshold
+	unlocked := w.unlocked
+	w.mu.RUnlock()
+	if threshold.IsZero() || !unlocked {
+		return
+	}
+
+	funds, claim, err := w.managedClaimableSiafunds()
+	if err != nil {
+		w.log.Println("WARN: couldn't compute siafund claim balance:", err)
+		return
+	}
+	if funds.IsZero() || claim.Cmp(threshold) < 0 {
+		return
+	}
+
+	uc, err := w.NextAddress()
+	if err != nil {
+		w.log.Println("WARN: couldn't get an address to claim siafunds:", err)
+		return
+	}
+	txns, err := w.SendSiafunds(funds, uc.UnlockHash())
+	if err != nil {
+		w.log.Println("WARN: couldn't claim siafund claim balance:", err)
+		return
+	}
+	w.log.Printf("Claiming siafund claim balance of %v, IDs:", claim.HumanString())
+	for _, txn := range txns {
+		w.log.Println("Siafund claim: \t", txn.ID())
+	}
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestClaimSiafunds checks that the wallet claims the siacoin claim balance of
+// its siafunds once it reaches the claim threshold.
+func TestClaimSiafunds(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Load the siafunds into the wallet.
+	err = wt.wallet.LoadSiagKeys(wt.walletMasterKey, []string{"../../types/siag0of1of1.siakey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// need to reset the miner as well, since it depends on the wallet
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Form a file contract, which adds its tax to the siafund pool.
+	builder, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payout := types.SiacoinPrecision.Mul64(1000)
+	err = builder.FundSiacoins(payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder.AddFileContract(types.FileContract{
+		WindowStart:        wt.cs.Height() + 10,
+		WindowEnd:          wt.cs.Height() + 20,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: types.PostTax(wt.cs.Height(), payout)}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: types.PostTax(wt.cs.Height(), payout)}},
+		UnlockHash:         types.UnlockConditions{}.UnlockHash(),
+	})
+	txnSet, err := builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a threshold, the claim balance is not claimed.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	_, siafundBal, claimBal, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimBal.IsZero() {
+		t.Fatal("expected a siacoin claim balance")
+	}
+
+	// Set a threshold below the claim balance; the siafunds should be sent
+	// back to the wallet, which claims the balance.
+	settings, err := wt.wallet.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.SiafundClaimThreshold = claimBal.Div64(2)
+	if err := wt.wallet.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			return err
+		}
+		_, newSiafundBal, newClaimBal, err := wt.wallet.ConfirmedBalance()
+		if err != nil {
+			return err
+		}
+		if !newClaimBal.IsZero() {
+			return errors.New("claim balance was not claimed")
+		}
+		if newSiafundBal.Cmp(siafundBal) != 0 {
+			return errors.New("siafunds were not sent to the wallet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
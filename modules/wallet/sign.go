@@ -0,0 +1,309 @@
+package wallet
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+var (
+	errEmptyToSign             = errors.New("no signatures were specified to sign")
+	errMissingSigningKey       = errors.New("wallet does not hold the key for a requested signature")
+	errMissingSignatureInput   = errors.New("transaction has no input for a requested signature")
+	errUnknownUnlockConditions = errors.New("unlock conditions of the address are not known to the wallet")
+)
+
+// UnlockConditions returns the unlock conditions of an address, if the wallet
+// holds its keys or its unlock conditions were added with
+// AddUnlockConditions.
+func (w *Wallet) UnlockConditions(addr types.UnlockHash) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	uc, exists := w.unlockConditions(addr)
+	if !exists {
+		return types.UnlockConditions{}, errUnknownUnlockConditions
+	}
+	return uc, nil
+}
+
+// AddUnlockConditions stores the unlock conditions of an address that the
+// wallet does not hold the keys for, so that UnsignedTransaction can spend
+// the outputs of the address while it is being watched.
+func (w *Wallet) AddUnlockConditions(uc types.UnlockConditions) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return dbPutUnlockConditions(w.dbTx, uc)
+}
+
+// UnsignedTransaction builds a transaction sending to outputs and paying fee,
+// funded by the outputs of watched addresses whose unlock conditions were
+// added with AddUnlockConditions. The transaction contains
+// an unsigned TransactionSignature for each signature required by its inputs,
+// and toSign holds their parent IDs, so that the transaction can be signed on
+// another machine. The signatures of a multisig input are for its first M
+// public keys; the PublicKeyIndex of an unsigned signature can be changed to
+// let a different cosigner sign. The outputs funding the transaction are not
+// marked as spent.
+func (w *Wallet) UnsignedTransaction(outputs []types.SiacoinOutput, fee types.Currency) (txn types.Transaction, toSign []crypto.Hash, err error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedCheckDust(outputs); err != nil {
+		return types.Transaction{}, nil, err
+	}
+	dustThreshold, err := w.DustThreshold()
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+
+	amount := fee
+	for _, sco := range outputs {
+		amount = amount.Add(sco.Value)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+
+	// Collect the spendable outputs, largest first.
+	var so sortedOutputs
+	ucs := make(map[types.UnlockHash]types.UnlockConditions)
+	err = dbForEachSiacoinOutput(w.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		if sco.Value.Cmp(dustThreshold) < 0 {
+			return
+		}
+		spendHeight, err := dbGetSpentOutput(w.dbTx, types.OutputID(scoid))
+		if err == nil && spendHeight+RespendTimeout > consensusHeight {
+			return
+		}
+		// Only the outputs of watched addresses are used, the wallet can
+		// spend the outputs of its own keys directly.
+		if _, exists := w.watchedAddrs[sco.UnlockHash]; !exists {
+			return
+		}
+		uc, err := dbGetUnlockConditions(w.dbTx, sco.UnlockHash)
+		if err != nil || consensusHeight < uc.Timelock {
+			return
+		}
+		so.ids = append(so.ids, scoid)
+		so.outputs = append(so.outputs, sco)
+		ucs[sco.UnlockHash] = uc
+	})
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+	sort.Sort(sort.Reverse(so))
+
+	// Add inputs until the outputs and fee are covered.
+	var fund types.Currency
+	for i := range so.ids {
+		if fund.Cmp(amount) >= 0 {
+			break
+		}
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         so.ids[i],
+			UnlockConditions: ucs[so.outputs[i].UnlockHash],
+		})
+		fund = fund.Add(so.outputs[i].Value)
+	}
+	if fund.Cmp(amount) < 0 {
+		return types.Transaction{}, nil, modules.ErrLowBalance
+	}
+
+	// Send the change back to the address of the largest input. Change below
+	// the dust threshold is added to the fee instead.
+	txn.SiacoinOutputs = append(txn.SiacoinOutputs, outputs...)
+	if change := fund.Sub(amount); change.Cmp(dustThreshold) < 0 {
+		fee = fee.Add(change)
+	} else {
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:      change,
+			UnlockHash: txn.SiacoinInputs[0].UnlockConditions.UnlockHash(),
+		})
+	}
+	if !fee.IsZero() {
+		txn.MinerFees = append(txn.MinerFees, fee)
+	}
+
+	// Add an unsigned signature for each signature required by the inputs.
+	for _, sci := range txn.SiacoinInputs {
+		for i := uint64(0); i < sci.UnlockConditions.SignaturesRequired; i++ {
+			txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+				ParentID:       crypto.Hash(sci.ParentID),
+				CoveredFields:  types.CoveredFields{WholeTransaction: true},
+				PublicKeyIndex: i,
+			})
+		}
+		toSign = append(toSign, crypto.Hash(sci.ParentID))
+	}
+	return txn, toSign, nil
+}
+
+// SignTransaction signs the TransactionSignatures of txn whose parent IDs are
+// in toSign, using the keys of the wallet. Signatures that the wallet does not
+// hold the keys for are left for the other cosigners of a multisig input. The
+// wallet must be unlocked, but does not need to be synced, so that
+// transactions can be signed on a machine that is offline.
+func (w *Wallet) SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+	return signTransaction(txn, w.keys, toSign, w.signHash)
+}
+
+// SignMessage signs msg with the key of addr, so that the owner of the wallet
+// can prove off-chain that they control the address. The signature can be
+// checked with types.VerifyMessage. Only addresses that require a single
+// signature can sign messages.
+func (w *Wallet) SignMessage(addr types.UnlockHash, msg []byte) (types.MessageSignature, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.MessageSignature{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.unlocked {
+		return types.MessageSignature{}, modules.ErrLockedWallet
+	}
+	key, exists := w.keys[addr]
+	if !exists {
+		return types.MessageSignature{}, errMissingSigningKey
+	}
+	uc := key.UnlockConditions
+	if uc.SignaturesRequired != 1 {
+		return types.MessageSignature{}, types.ErrUnsupportedMessageSignature
+	}
+
+	// Sign with the first public key of the address that the wallet holds
+	// the secret key for.
+	secretKeys := make(map[crypto.PublicKey]crypto.SecretKey)
+	for _, sk := range key.SecretKeys {
+		secretKeys[sk.PublicKey()] = sk
+	}
+	for i, pk := range uc.PublicKeys {
+		if pk.Algorithm != types.SignatureEd25519 || len(pk.Key) != crypto.PublicKeySize {
+			continue
+		}
+		var cpk crypto.PublicKey
+		copy(cpk[:], pk.Key)
+		sk, exists := secretKeys[cpk]
+		if !exists {
+			continue
+		}
+		sig, err := w.signHash(crypto.HashMessage(msg), sk)
+		if err != nil {
+			return types.MessageSignature{}, err
+		}
+		return types.MessageSignature{
+			UnlockConditions: uc,
+			PublicKeyIndex:   uint64(i),
+			Signature:        sig[:],
+		}, nil
+	}
+	return types.MessageSignature{}, errMissingSigningKey
+}
+
+// unlockConditions returns the unlock conditions of an address from the
+// wallet's keys or the imported unlock conditions.
+func (w *Wallet) unlockConditions(addr types.UnlockHash) (types.UnlockConditions, bool) {
+	if key, exists := w.keys[addr]; exists {
+		return key.UnlockConditions, true
+	}
+	uc, err := dbGetUnlockConditions(w.dbTx, addr)
+	return uc, err == nil
+}
+
+// signTransaction signs the unsigned TransactionSignatures of txn whose parent
+// IDs are in toSign with sign, using keys. Signatures for public keys that are
+// not among keys are left unsigned, so that the other cosigners of a multisig
+// input can sign them. An error is returned if an input still needs signatures but none
+// of them can be signed.
+func signTransaction(txn *types.Transaction, keys map[types.UnlockHash]spendableKey, toSign []crypto.Hash, sign signFunc) error {
+	if len(toSign) == 0 {
+		return errEmptyToSign
+	}
+	// Index the secret keys by their public key, since the unlock conditions
+	// of a multisig input do not belong to any single key of the wallet.
+	secretKeys := make(map[crypto.PublicKey]crypto.SecretKey)
+	for _, key := range keys {
+		for _, sk := range key.SecretKeys {
+			secretKeys[sk.PublicKey()] = sk
+		}
+	}
+
+	for _, id := range toSign {
+		// Find the unlock conditions of the input being signed.
+		var uc types.UnlockConditions
+		found := false
+		for _, sci := range txn.SiacoinInputs {
+			if crypto.Hash(sci.ParentID) == id {
+				uc, found = sci.UnlockConditions, true
+			}
+		}
+		for _, sfi := range txn.SiafundInputs {
+			if crypto.Hash(sfi.ParentID) == id {
+				uc, found = sfi.UnlockConditions, true
+			}
+		}
+		if !found {
+			return errMissingSignatureInput
+		}
+
+		// Sign each unsigned signature of the input that the keys can sign.
+		var unsigned, signed int
+		for i, sig := range txn.TransactionSignatures {
+			if sig.ParentID != id || len(sig.Signature) != 0 {
+				continue
+			}
+			unsigned++
+			if sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+				continue
+			}
+			pk := uc.PublicKeys[sig.PublicKeyIndex]
+			if pk.Algorithm != types.SignatureEd25519 || len(pk.Key) != crypto.PublicKeySize {
+				continue
+			}
+			var cpk crypto.PublicKey
+			copy(cpk[:], pk.Key)
+			sk, exists := secretKeys[cpk]
+			if !exists {
+				continue
+			}
+			encodedSig, err := sign(txn.SigHash(i), sk)
+			if err != nil {
+				return err
+			}
+			txn.TransactionSignatures[i].Signature = encodedSig[:]
+			signed++
+		}
+		if unsigned > 0 && signed == 0 {
+			return errMissingSigningKey
+		}
+	}
+	return nil
+}
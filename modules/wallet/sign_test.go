@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOfflineSigning checks that a transaction spending from a watched address
+// can be built by one wallet, signed by a wallet holding the keys of the
+// address, and accepted by the transaction pool.
+func TestOfflineSigning(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	cold, err := createWalletTester(t.Name()+"-cold", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cold.closeWt()
+
+	// Send coins to an address of the cold wallet and watch it.
+	uc, err := cold.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.AddWatchAddresses([]types.UnlockHash{addr}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the unlock conditions, the output cannot be spent.
+	outputs := []types.SiacoinOutput{{
+		Value:      types.SiacoinPrecision.Mul64(50),
+		UnlockHash: types.UnlockHash{},
+	}}
+	fee := types.SiacoinPrecision
+	_, _, err = wt.wallet.UnsignedTransaction(outputs, fee)
+	if err != modules.ErrLowBalance {
+		t.Fatal("expected ErrLowBalance, got", err)
+	}
+	exported, err := cold.wallet.UnlockConditions(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.AddUnlockConditions(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, toSign, err := wt.wallet.UnsignedTransaction(outputs, fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.SiacoinInputs) != 1 || len(toSign) != 1 || len(txn.TransactionSignatures) != 1 {
+		t.Fatal("wrong number of inputs or signatures:", len(txn.SiacoinInputs), len(toSign), len(txn.TransactionSignatures))
+	}
+	if len(txn.SiacoinOutputs) != 2 || txn.SiacoinOutputs[1].UnlockHash != addr {
+		t.Fatal("change was not sent back to the watched address")
+	}
+
+	// The online wallet cannot sign the transaction.
+	unsigned := txn
+	err = wt.wallet.SignTransaction(&unsigned, toSign)
+	if err != errMissingSigningKey {
+		t.Fatal("expected errMissingSigningKey, got", err)
+	}
+	err = cold.wallet.SignTransaction(&txn, []crypto.Hash{{1}})
+	if err != errMissingSignatureInput {
+		t.Fatal("expected errMissingSignatureInput, got", err)
+	}
+
+	// Sign the transaction with the cold wallet and broadcast it.
+	err = cold.wallet.SignTransaction(&txn, toSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSignMessage checks that the wallet signs messages with the keys of its
+// addresses, and that the signatures verify with types.VerifyMessage.
+func TestSignMessage(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	msg := []byte("I operate this host")
+	ms, err := wt.wallet.SignMessage(addr, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := types.VerifyMessage(addr, msg, ms); err != nil {
+		t.Fatal(err)
+	}
+	if err := types.VerifyMessage(addr, []byte("I operate another host"), ms); err != types.ErrInvalidMessageSignature {
+		t.Fatal("expected ErrInvalidMessageSignature, got", err)
+	}
+
+	// Signing with a Signer produces the same signature.
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetSigner(NewSeedSigner(seed)); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := wt.wallet.SignMessage(addr, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signed.Signature, ms.Signature) {
+		t.Fatal("Signer produced a different signature")
+	}
+
+	// The wallet cannot sign for addresses it does not hold the keys of, or
+	// while it is locked.
+	if _, err := wt.wallet.SignMessage(types.UnlockHash{1}, msg); err != errMissingSigningKey {
+		t.Fatal("expected errMissingSigningKey, got", err)
+	}
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SignMessage(addr, msg); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+}
@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+type (
+	// A Signer signs sighashes with the keys of the wallet's primary seed,
+	// identified by their index in the seed. The keys of change addresses
+	// have indices of 2^63 and above. By default the wallet signs with the
+	// keys it derives from the seed in memory; SetSigner replaces this with
+	// another Signer, such as a hardware wallet or a remote signer, for all
+	// transactions that the wallet signs with the keys of its primary seed.
+	Signer interface {
+		Sign(sigHash crypto.Hash, keyIndex uint64) (crypto.Signature, error)
+	}
+
+	// SeedSigner is the software Signer, which derives the keys from a seed.
+	// It signs exactly like the wallet does when no Signer is set.
+	SeedSigner struct {
+		seed modules.Seed
+	}
+
+	// signFunc signs a sighash with the key of the wallet that belongs to the
+	// secret key.
+	signFunc func(sigHash crypto.Hash, sk crypto.SecretKey) (crypto.Signature, error)
+)
+
+// NewSeedSigner returns a SeedSigner for seed.
+func NewSeedSigner(seed modules.Seed) *SeedSigner {
+	return &SeedSigner{seed: seed}
+}
+
+// Sign implements Signer.
+func (s *SeedSigner) Sign(sigHash crypto.Hash, keyIndex uint64) (crypto.Signature, error) {
+	sk := generateSpendableKey(s.seed, keyIndex).SecretKeys[0]
+	defer crypto.SecureWipe(sk[:])
+	return crypto.SignHash(sigHash, sk), nil
+}
+
+// SetSigner sets the Signer that signs with the keys of the primary seed. A
+// nil Signer restores the default of signing in memory.
+func (w *Wallet) SetSigner(s Signer) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.signer = s
+	return nil
+}
+
+// signInMemory signs sigHash with sk. It is used for keys that do not belong
+// to the wallet, such as those of a seed that is being swept.
+func signInMemory(sigHash crypto.Hash, sk crypto.SecretKey) (crypto.Signature, error) {
+	return crypto.SignHash(sigHash, sk), nil
+}
+
+// signHash signs sigHash with sk. Keys of the primary seed are signed by the
+// wallet's Signer if one is set. The caller must hold w.mu.
+func (w *Wallet) signHash(sigHash crypto.Hash, sk crypto.SecretKey) (crypto.Signature, error) {
+	if w.signer != nil {
+		if index, exists := w.primaryKeyIndices[sk.PublicKey()]; exists {
+			return w.signer.Sign(sigHash, index)
+		}
+	}
+	return crypto.SignHash(sigHash, sk), nil
+}
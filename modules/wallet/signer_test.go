@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var errSignerUnavailable = errors.New("signer is unavailable")
+
+// recordingSigner is a Signer that signs with a SeedSigner and records the
+// indices of the keys it signed with.
+type recordingSigner struct {
+	*SeedSigner
+	indices []uint64
+}
+
+func (rs *recordingSigner) Sign(sigHash crypto.Hash, keyIndex uint64) (crypto.Signature, error) {
+	rs.indices = append(rs.indices, keyIndex)
+	return rs.SeedSigner.Sign(sigHash, keyIndex)
+}
+
+// failingSigner is a Signer that cannot sign.
+type failingSigner struct{}
+
+func (failingSigner) Sign(crypto.Hash, uint64) (crypto.Signature, error) {
+	return crypto.Signature{}, errSignerUnavailable
+}
+
+// TestSeedSigner checks that a SeedSigner signs with the keys of the seed.
+func TestSeedSigner(t *testing.T) {
+	var seed modules.Seed
+	copy(seed[:], "seed signer test seed")
+	signer := NewSeedSigner(seed)
+	sigHash := crypto.HashObject("sighash")
+	for _, index := range []uint64{0, 1, 500} {
+		sig, err := signer.Sign(sigHash, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pk := generateSpendableKey(seed, index).SecretKeys[0].PublicKey()
+		if err := crypto.VerifyHash(sigHash, pk, sig); err != nil {
+			t.Fatal("signature does not verify for index", index, err)
+		}
+	}
+}
+
+// TestSetSigner checks that the wallet signs its transactions with the Signer
+// that was set, and fails to send when the Signer cannot sign.
+func TestSetSigner(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &recordingSigner{SeedSigner: NewSeedSigner(seed)}
+	if err := wt.wallet.SetSigner(rs); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.indices) == 0 {
+		t.Fatal("transaction was not signed by the signer")
+	}
+
+	// A signer that cannot sign prevents the wallet from sending.
+	if err := wt.wallet.SetSigner(failingSigner{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockHash{})
+	if err == nil || !strings.Contains(err.Error(), errSignerUnavailable.Error()) {
+		t.Fatal("expected errSignerUnavailable, got", err)
+	}
+
+	// Removing the signer restores signing in memory.
+	if err := wt.wallet.SetSigner(nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -28,6 +28,22 @@ var (
 	// errSpendHeightTooHigh indicates an output's spend height is greater than
 	// the allowed height.
 	errSpendHeightTooHigh = errors.New("output spend height exceeds the allowed height")
+
+	// errWatchOnlyOutput indicates an output is not spendable because it
+	// belongs to a watched address whose keys the wallet does not hold.
+	errWatchOnlyOutput = errors.New("output belongs to a watch-only address")
+
+	// errLockedOutput indicates an output is not spendable because it has been
+	// locked by the user.
+	errLockedOutput = errors.New("output is locked")
+
+	// errUnknownOutput indicates that an output passed to
+	// FundTransactionWithOutputs is not tracked by the wallet.
+	errUnknownOutput = errors.New("output is not tracked by the wallet")
+
+	// errDuplicateOutput indicates that an output was passed to
+	// FundTransactionWithOutputs more than once.
+	errDuplicateOutput = errors.New("output was specified more than once")
 )
 
 // transactionBuilder allows transactions to be manually constructed, including
@@ -49,8 +65,9 @@ type transactionBuilder struct {
 
 // addSignatures will sign a transaction using a spendable key, with support
 // for multisig spendable keys. Because of the restricted input, the function
-// is compatible with both siacoin inputs and siafund inputs.
-func addSignatures(txn *types.Transaction, cf types.CoveredFields, uc types.UnlockConditions, parentID crypto.Hash, spendKey spendableKey) (newSigIndices []int) {
+// is compatible with both siacoin inputs and siafund inputs. The signatures
+// are made by sign.
+func addSignatures(txn *types.Transaction, cf types.CoveredFields, uc types.UnlockConditions, parentID crypto.Hash, spendKey spendableKey, sign signFunc) (newSigIndices []int, err error) {
 	// Try to find the matching secret key for each public key - some public
 	// keys may not have a match. Some secret keys may be used multiple times,
 	// which is why public keys are used as the outer loop.
@@ -73,7 +90,10 @@ func addSignatures(txn *types.Transaction, cf types.CoveredFields, uc types.Unlo
 			txn.TransactionSignatures = append(txn.TransactionSignatures, sig)
 			sigIndex := len(txn.TransactionSignatures) - 1
 			sigHash := txn.SigHash(sigIndex)
-			encodedSig := crypto.SignHash(sigHash, spendKey.SecretKeys[j])
+			encodedSig, err := sign(sigHash, spendKey.SecretKeys[j])
+			if err != nil {
+				return nil, err
+			}
 			txn.TransactionSignatures[sigIndex].Signature = encodedSig[:]
 
 			// Count that the signature has been added, and break out of the
@@ -88,7 +108,7 @@ func addSignatures(txn *types.Transaction, cf types.CoveredFields, uc types.Unlo
 			break
 		}
 	}
-	return newSigIndices
+	return newSigIndices, nil
 }
 
 // checkOutput is a helper function used to determine if an output is usable.
@@ -97,6 +117,15 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 	if output.Value.Cmp(dustThreshold) < 0 {
 		return errDustOutput
 	}
+	// Check that the wallet holds the keys for this output.
+	key, exists := w.keys[output.UnlockHash]
+	if !exists {
+		return errWatchOnlyOutput
+	}
+	// Check that the output has not been locked.
+	if _, locked := w.lockedOutputs[types.OutputID(id)]; locked {
+		return errLockedOutput
+	}
 	// Check that this output has not recently been spent by the wallet.
 	spendHeight, err := dbGetSpentOutput(tx, types.OutputID(id))
 	if err == nil {
@@ -104,8 +133,7 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 			return errSpendHeightTooHigh
 		}
 	}
-	outputUnlockConditions := w.keys[output.UnlockHash].UnlockConditions
-	if currentHeight < outputUnlockConditions.Timelock {
+	if currentHeight < key.UnlockConditions.Timelock {
 		return errOutputTimelock
 	}
 
@@ -140,16 +168,20 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	if err != nil {
 		return err
 	}
-	// Add all of the unconfirmed outputs as well.
-	for _, upt := range tb.wallet.unconfirmedProcessedTransactions {
-		for i, sco := range upt.Transaction.SiacoinOutputs {
-			// Determine if the output belongs to the wallet.
-			_, exists := tb.wallet.keys[sco.UnlockHash]
-			if !exists {
-				continue
+	// Add all of the unconfirmed outputs as well, unless the wallet was told
+	// not to spend them. Their transactions are already in the transaction
+	// pool, which merges them with the set that spends them.
+	if !tb.wallet.spendUnconfirmedDisabled {
+		for _, upt := range tb.wallet.unconfirmedProcessedTransactions {
+			for i, sco := range upt.Transaction.SiacoinOutputs {
+				// Determine if the output belongs to the wallet.
+				_, exists := tb.wallet.keys[sco.UnlockHash]
+				if !exists {
+					continue
+				}
+				so.ids = append(so.ids, upt.Transaction.SiacoinOutputID(uint64(i)))
+				so.outputs = append(so.outputs, sco)
 			}
-			so.ids = append(so.ids, upt.Transaction.SiacoinOutputID(uint64(i)))
-			so.outputs = append(so.outputs, sco)
 		}
 	}
 	sort.Sort(sort.Reverse(so))
@@ -196,10 +228,82 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	if fund.Cmp(amount) < 0 {
 		return modules.ErrLowBalance
 	}
+	return tb.addFundingParent(parentTxn, spentScoids, amount, fund, dustThreshold, consensusHeight)
+}
+
+// FundTransactionWithOutputs will add a siacoin input of exactly 'amount' to
+// the transaction, funded by exactly the specified siacoin outputs of the
+// wallet. Any value beyond 'amount' is sent back to the wallet. The siacoin
+// input will not be signed until 'Sign' is called on the transaction builder.
+func (tb *transactionBuilder) FundTransactionWithOutputs(amount types.Currency, outputs []types.SiacoinOutputID) error {
+	// dustThreshold has to be obtained separate from the lock
+	dustThreshold, err := tb.wallet.DustThreshold()
+	if err != nil {
+		return err
+	}
+
+	tb.wallet.mu.Lock()
+	defer tb.wallet.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
+	if err != nil {
+		return err
+	}
+
+	// Spend each of the specified outputs, which may be unconfirmed outputs
+	// of the wallet.
+	var fund types.Currency
+	parentTxn := types.Transaction{}
+	spent := make(map[types.SiacoinOutputID]struct{})
+	for _, scoid := range outputs {
+		if _, exists := spent[scoid]; exists {
+			return errDuplicateOutput
+		}
+		sco, err := dbGetSiacoinOutput(tb.wallet.dbTx, scoid)
+		if err != nil {
+			sco, err = tb.wallet.unconfirmedSiacoinOutput(scoid)
+		}
+		if err != nil {
+			return errUnknownOutput
+		}
+		err = tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, sco, dustThreshold)
+		if err != nil {
+			return err
+		}
+
+		parentTxn.SiacoinInputs = append(parentTxn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         scoid,
+			UnlockConditions: tb.wallet.keys[sco.UnlockHash].UnlockConditions,
+		})
+		spent[scoid] = struct{}{}
+		fund = fund.Add(sco.Value)
+	}
+	if fund.Cmp(amount) < 0 {
+		return modules.ErrLowBalance
+	}
+	return tb.addFundingParent(parentTxn, outputs, amount, fund, dustThreshold, consensusHeight)
+}
+
+// unconfirmedSiacoinOutput returns an unconfirmed siacoin output of the wallet.
+func (w *Wallet) unconfirmedSiacoinOutput(id types.SiacoinOutputID) (types.SiacoinOutput, error) {
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for i, sco := range upt.Transaction.SiacoinOutputs {
+			if upt.Transaction.SiacoinOutputID(uint64(i)) == id && w.isWalletAddress(sco.UnlockHash) {
+				return sco, nil
+			}
+		}
+	}
+	return types.SiacoinOutput{}, errUnknownOutput
+}
 
+// addFundingParent completes parentTxn, which spends the outputs in
+// spentScoids worth 'fund' in total, with an output of exactly 'amount' and a
+// refund output, signs it, and adds an input spending the exact output to the
+// transaction. The caller must hold the wallet's lock.
+func (tb *transactionBuilder) addFundingParent(parentTxn types.Transaction, spentScoids []types.SiacoinOutputID, amount, fund, dustThreshold types.Currency, consensusHeight types.BlockHeight) error {
 	// Create and add the output that will be used to fund the standard
 	// transaction.
-	parentUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	parentUnlockConditions, err := tb.wallet.nextChangeAddress(tb.wallet.dbTx)
 	if err != nil {
 		return err
 	}
@@ -210,14 +314,20 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	}
 	parentTxn.SiacoinOutputs = append(parentTxn.SiacoinOutputs, exactOutput)
 
-	// Create a refund output if needed.
-	if !amount.Equals(fund) {
-		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	// Create a refund output if needed. Change below the dust threshold would
+	// cost more to spend than it is worth, so it is paid as a miner fee
+	// instead.
+	if change := fund.Sub(amount); change.Cmp(dustThreshold) < 0 {
+		if !change.IsZero() {
+			parentTxn.MinerFees = append(parentTxn.MinerFees, change)
+		}
+	} else {
+		refundUnlockConditions, err := tb.wallet.nextChangeAddress(tb.wallet.dbTx)
 		if err != nil {
 			return err
 		}
 		refundOutput := types.SiacoinOutput{
-			Value:      fund.Sub(amount),
+			Value:      change,
 			UnlockHash: refundUnlockConditions.UnlockHash(),
 		}
 		parentTxn.SiacoinOutputs = append(parentTxn.SiacoinOutputs, refundOutput)
@@ -225,7 +335,10 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 
 	// Sign all of the inputs to the parent transaction.
 	for _, sci := range parentTxn.SiacoinInputs {
-		addSignatures(&parentTxn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), tb.wallet.keys[sci.UnlockConditions.UnlockHash()])
+		_, err = addSignatures(&parentTxn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), tb.wallet.keys[sci.UnlockConditions.UnlockHash()], tb.wallet.signHash)
+		if err != nil {
+			return err
+		}
 	}
 	// Mark the parent output as spent. Must be done after the transaction is
 	// finished because otherwise the txid and output id will change.
@@ -283,6 +396,16 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 			return err
 		}
 
+		// Skip outputs of watched addresses that the wallet cannot spend, and
+		// outputs that have been locked.
+		key, exists := tb.wallet.keys[sfo.UnlockHash]
+		if !exists {
+			continue
+		}
+		if _, locked := tb.wallet.lockedOutputs[types.OutputID(sfoid)]; locked {
+			continue
+		}
+
 		// Check that this output has not recently been spent by the wallet.
 		spendHeight, err := dbGetSpentOutput(tb.wallet.dbTx, types.OutputID(sfoid))
 		if err != nil {
@@ -298,8 +421,7 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 			potentialFund = potentialFund.Add(sfo.Value)
 			continue
 		}
-		outputUnlockConditions := tb.wallet.keys[sfo.UnlockHash].UnlockConditions
-		if consensusHeight < outputUnlockConditions.Timelock {
+		if consensusHeight < key.UnlockConditions.Timelock {
 			continue
 		}
 
@@ -310,7 +432,7 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 		}
 		sfi := types.SiafundInput{
 			ParentID:         sfoid,
-			UnlockConditions: outputUnlockConditions,
+			UnlockConditions: key.UnlockConditions,
 			ClaimUnlockHash:  parentClaimUnlockConditions.UnlockHash(),
 		}
 		parentTxn.SiafundInputs = append(parentTxn.SiafundInputs, sfi)
@@ -332,7 +454,7 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 
 	// Create and add the output that will be used to fund the standard
 	// transaction.
-	parentUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	parentUnlockConditions, err := tb.wallet.nextChangeAddress(tb.wallet.dbTx)
 	if err != nil {
 		return err
 	}
@@ -344,7 +466,7 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 
 	// Create a refund output if needed.
 	if !amount.Equals(fund) {
-		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+		refundUnlockConditions, err := tb.wallet.nextChangeAddress(tb.wallet.dbTx)
 		if err != nil {
 			return err
 		}
@@ -357,7 +479,10 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 
 	// Sign all of the inputs to the parent transaction.
 	for _, sfi := range parentTxn.SiafundInputs {
-		addSignatures(&parentTxn, types.FullCoveredFields, sfi.UnlockConditions, crypto.Hash(sfi.ParentID), tb.wallet.keys[sfi.UnlockConditions.UnlockHash()])
+		_, err = addSignatures(&parentTxn, types.FullCoveredFields, sfi.UnlockConditions, crypto.Hash(sfi.ParentID), tb.wallet.keys[sfi.UnlockConditions.UnlockHash()], tb.wallet.signHash)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Add the exact output.
@@ -584,7 +709,26 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 	for i := range tb.transaction.TransactionSignatures {
 		coveredFields.TransactionSignatures = append(coveredFields.TransactionSignatures, uint64(i))
 	}
+	return tb.sign(coveredFields)
+}
+
+// SignWithCoveredFields will sign any inputs added by 'FundSiacoins' or
+// 'FundSiafunds' with signatures that cover exactly the provided fields, and
+// return a transaction set that contains all parents prepended to the
+// transaction. Fields that are not covered may still be changed after
+// signing.
+//
+// Like Sign, SignWithCoveredFields should not be called more than once.
+func (tb *transactionBuilder) SignWithCoveredFields(cf types.CoveredFields) ([]types.Transaction, error) {
+	if tb.signed {
+		return nil, errBuilderAlreadySigned
+	}
+	return tb.sign(cf)
+}
 
+// sign adds signatures with the provided covered fields for all of the inputs
+// that were added by the builder, and returns the transaction set.
+func (tb *transactionBuilder) sign(coveredFields types.CoveredFields) ([]types.Transaction, error) {
 	// For each siacoin input in the transaction that we added, provide a
 	// signature.
 	tb.wallet.mu.RLock()
@@ -595,7 +739,10 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 		if !ok {
 			return nil, errors.New("transaction builder added an input that it cannot sign")
 		}
-		newSigIndices := addSignatures(&tb.transaction, coveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key)
+		newSigIndices, err := addSignatures(&tb.transaction, coveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key, tb.wallet.signHash)
+		if err != nil {
+			return nil, err
+		}
 		tb.transactionSignatures = append(tb.transactionSignatures, newSigIndices...)
 		tb.signed = true // Signed is set to true after one successful signature to indicate that future signings can cause issues.
 	}
@@ -605,7 +752,10 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 		if !ok {
 			return nil, errors.New("transaction builder added an input that it cannot sign")
 		}
-		newSigIndices := addSignatures(&tb.transaction, coveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key)
+		newSigIndices, err := addSignatures(&tb.transaction, coveredFields, input.UnlockConditions, crypto.Hash(input.ParentID), key, tb.wallet.signHash)
+		if err != nil {
+			return nil, err
+		}
 		tb.transactionSignatures = append(tb.transactionSignatures, newSigIndices...)
 		tb.signed = true // Signed is set to true after one successful signature to indicate that future signings can cause issues.
 	}
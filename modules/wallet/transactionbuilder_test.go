@@ -177,6 +177,61 @@ func TestDoubleSignError(t *testing.T) {
 	}
 }
 
+// TestSignWithCoveredFields checks that a transaction signed with explicit
+// covered fields is valid, and that fields that are not covered can be added
+// after signing.
+func TestSignWithCoveredFields(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	b, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := types.NewCurrency64(100e9)
+	if err := b.FundSiacoins(fee); err != nil {
+		t.Fatal(err)
+	}
+	b.AddMinerFee(fee)
+
+	// Cover the inputs, outputs and fees, but not the arbitrary data.
+	txn, _ := b.View()
+	var cf types.CoveredFields
+	for i := range txn.SiacoinInputs {
+		cf.SiacoinInputs = append(cf.SiacoinInputs, uint64(i))
+	}
+	for i := range txn.SiacoinOutputs {
+		cf.SiacoinOutputs = append(cf.SiacoinOutputs, uint64(i))
+	}
+	cf.MinerFees = []uint64{0}
+	txnSet, err := b.SignWithCoveredFields(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.SignWithCoveredFields(cf); err != errBuilderAlreadySigned {
+		t.Fatal("expected errBuilderAlreadySigned, got", err)
+	}
+	signed := &txnSet[len(txnSet)-1]
+	for _, sig := range signed.TransactionSignatures {
+		if sig.CoveredFields.WholeTransaction || len(sig.CoveredFields.MinerFees) != 1 {
+			t.Fatal("signature does not cover the requested fields:", sig.CoveredFields)
+		}
+	}
+
+	// Arbitrary data added after signing should not invalidate the
+	// signatures.
+	signed.ArbitraryData = append(signed.ArbitraryData, append(modules.PrefixNonSia[:], "data"...))
+	if err := wt.tpool.AcceptTransactionSet(txnSet); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestConcurrentBuilders checks that multiple transaction builders can safely
 // be opened at the same time, and that they will make valid transactions when
 // building concurrently.
@@ -7,7 +7,6 @@ import (
 	"sort"
 
 	"github.com/NebulousLabs/Sia/build"
-	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -78,6 +77,36 @@ func (w *Wallet) AddressUnconfirmedTransactions(uh types.UnlockHash) (pts []modu
 	return pts, err
 }
 
+// MemoTransactions returns all of the confirmed wallet transactions that carry
+// the given memo.
+func (w *Wallet) MemoTransactions(memo string) (pts []modules.ProcessedTransaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		return []modules.ProcessedTransaction{}, err
+	}
+	defer w.tg.Done()
+	// ensure durability of reported transactions
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err = w.syncDB(); err != nil {
+		return
+	}
+
+	txnIndices, _ := dbGetMemoTransactions(w.dbTx, memo)
+	for _, i := range txnIndices {
+		pt, err := dbGetProcessedTransaction(w.dbTx, i)
+		if err != nil {
+			continue
+		}
+		// A rescan rebuilds the processed transactions, so an index may
+		// refer to a different transaction than when it was stored.
+		if m, ok := modules.TransactionMemo(pt.Transaction); !ok || m != memo {
+			continue
+		}
+		pts = append(pts, pt)
+	}
+	return pts, nil
+}
+
 // Transaction returns the transaction with the given id. 'False' is returned
 // if the transaction does not exist.
 func (w *Wallet) Transaction(txid types.TransactionID) (pt modules.ProcessedTransaction, found bool, err error) {
@@ -99,7 +128,7 @@ func (w *Wallet) Transaction(txid types.TransactionID) (pt modules.ProcessedTran
 	}
 
 	// Retrieve the transaction
-	found = encoding.Unmarshal(w.dbTx.Bucket(bucketProcessedTransactions).Get(keyBytes), &pt) == nil
+	found = decodeProcessedTransaction(w.dbTx.Bucket(bucketProcessedTransactions).Get(keyBytes), &pt) == nil
 	return
 }
 
@@ -207,6 +236,22 @@ func (w *Wallet) Transactions(startHeight, endHeight types.BlockHeight) (pts []m
 	return
 }
 
+// RejectedTransactions returns the conflicts that displaced unconfirmed
+// transactions of the wallet from the transaction pool.
+func (w *Wallet) RejectedTransactions() ([]modules.TransactionConflict, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	conflicts := make([]modules.TransactionConflict, 0, len(w.rejectedTransactions))
+	for _, conflict := range w.rejectedTransactions {
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
 // UnconfirmedTransactions returns the set of unconfirmed transactions that are
 // relevant to the wallet.
 func (w *Wallet) UnconfirmedTransactions() ([]modules.ProcessedTransaction, error) {
@@ -218,3 +263,26 @@ func (w *Wallet) UnconfirmedTransactions() ([]modules.ProcessedTransaction, erro
 	defer w.mu.RUnlock()
 	return w.unconfirmedProcessedTransactions, nil
 }
+
+// PendingTransactions returns the unconfirmed transactions of the wallet along
+// with the time at which the wallet first saw each of them in the transaction
+// pool.
+func (w *Wallet) PendingTransactions() ([]modules.PendingTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.unconfirmedProcessedTransactions == nil {
+		return nil, nil
+	}
+	pts := make([]modules.PendingTransaction, len(w.unconfirmedProcessedTransactions))
+	for i, pt := range w.unconfirmedProcessedTransactions {
+		pts[i] = modules.PendingTransaction{
+			ProcessedTransaction: pt,
+			FirstSeen:            w.unconfirmedFirstSeen[pt.TransactionID],
+		}
+	}
+	return pts, nil
+}
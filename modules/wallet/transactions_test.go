@@ -81,6 +81,55 @@ func TestIntegrationTransactions(t *testing.T) {
 	}
 }
 
+// TestPendingTransactions checks that the wallet reports when it first saw
+// its unconfirmed transactions, and forgets them once they are confirmed.
+func TestPendingTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	before := types.CurrentTimestamp()
+	txns, err := wt.wallet.SendSiacoins(types.NewCurrency64(5000), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts, err := wt.wallet.PendingTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != len(txns) {
+		t.Fatalf("expected %v pending transactions, got %v", len(txns), len(pts))
+	}
+	for _, pt := range pts {
+		if pt.FirstSeen < before || pt.FirstSeen > types.CurrentTimestamp() {
+			t.Fatal("wrong first seen time:", pt.FirstSeen)
+		}
+	}
+
+	// Confirming the transactions should remove them.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	pts, err = wt.wallet.PendingTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 0 {
+		t.Fatal("confirmed transactions are still pending:", len(pts))
+	}
+	wt.wallet.mu.RLock()
+	seen := len(wt.wallet.unconfirmedFirstSeen)
+	wt.wallet.mu.RUnlock()
+	if seen != 0 {
+		t.Fatal("first seen times of confirmed transactions were not removed")
+	}
+}
+
 // TestTransactionsSingleTxn checks if it is possible to find a txn that was
 // appended to the processed transactions and is also the only txn for a
 // certain block height.
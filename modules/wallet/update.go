@@ -70,10 +70,40 @@ func (w *Wallet) advanceSeedLookahead(index uint64) (bool, error) {
 	return false, nil
 }
 
+// advanceChangeLookahead generates all change keys from the current change
+// progress up to index and adds them to the set of spendable keys, like
+// advanceSeedLookahead does for the primary seed's addresses. Returns true if
+// a blockchain rescan is required
+func (w *Wallet) advanceChangeLookahead(index uint64) (bool, error) {
+	progress, err := dbGetChangeProgress(w.dbTx)
+	if err != nil {
+		return false, err
+	}
+	newProgress := index + 1
+	if newProgress <= progress {
+		return false, nil
+	}
+
+	// Add spendable keys and remove them from lookahead
+	spendableKeys := generateKeys(w.primarySeed, changeIndexOffset+progress, newProgress-progress)
+	for i, key := range spendableKeys {
+		w.integrateChangeKey(key, progress+uint64(i))
+	}
+	if err := dbPutChangeProgress(w.dbTx, newProgress); err != nil {
+		return false, err
+	}
+	w.regenerateChangeLookahead(newProgress)
+	return uint64(len(spendableKeys)) > lookaheadRescanThreshold, nil
+}
+
 // isWalletAddress is a helper function that checks if an UnlockHash is
-// derived from one of the wallet's spendable keys or future keys.
+// derived from one of the wallet's spendable keys or future keys, or is one of
+// the wallet's watched addresses.
 func (w *Wallet) isWalletAddress(uh types.UnlockHash) bool {
-	_, exists := w.keys[uh]
+	if _, exists := w.keys[uh]; exists {
+		return true
+	}
+	_, exists := w.watchedAddrs[uh]
 	return exists
 }
 
@@ -95,11 +125,36 @@ func (w *Wallet) updateLookahead(tx *bolt.Tx, cc modules.ConsensusChange) (bool,
 			}
 		}
 	}
+
+	// Change addresses are tracked separately, as they have their own
+	// progress.
+	var largestChangeIndex uint64
+	changeSeen := false
+	for _, diff := range cc.SiacoinOutputDiffs {
+		if index, ok := w.changeLookahead[diff.SiacoinOutput.UnlockHash]; ok && (!changeSeen || index > largestChangeIndex) {
+			largestChangeIndex, changeSeen = index, true
+		}
+	}
+	for _, diff := range cc.SiafundOutputDiffs {
+		if index, ok := w.changeLookahead[diff.SiafundOutput.UnlockHash]; ok && (!changeSeen || index > largestChangeIndex) {
+			largestChangeIndex, changeSeen = index, true
+		}
+	}
+	var changeRescan bool
+	if changeSeen {
+		var err error
+		changeRescan, err = w.advanceChangeLookahead(largestChangeIndex)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	if largestIndex > 0 {
-		return w.advanceSeedLookahead(largestIndex)
+		rescan, err := w.advanceSeedLookahead(largestIndex)
+		return rescan || changeRescan, err
 	}
 
-	return false, nil
+	return changeRescan, nil
 }
 
 // updateConfirmedSet uses a consensus change to update the confirmed set of
@@ -173,6 +228,7 @@ func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 			}
 			if txid == pt.TransactionID {
 				w.log.Println("A wallet transaction has been reverted due to a reorg:", txid)
+				w.queueEvent(eventRevert, pt, nil)
 				if err := dbDeleteLastProcessedTransaction(tx); err != nil {
 					w.log.Severe("Could not revert transaction:", err)
 					return err
@@ -190,6 +246,7 @@ func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 			}
 			if types.TransactionID(block.ID()) == pt.TransactionID {
 				w.log.Println("Miner payout has been reverted due to a reorg:", block.MinerPayoutID(uint64(i)), "::", mp.Value.HumanString())
+				w.queueEvent(eventRevert, pt, nil)
 				if err := dbDeleteLastProcessedTransaction(tx); err != nil {
 					w.log.Severe("Could not revert transaction:", err)
 					return err
@@ -394,11 +451,27 @@ func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.
 				Value:          fee,
 			})
 		}
+		pt.ChangeOutputs = w.changeOutputs(pt)
 		pts = append(pts, pt)
 	}
 	return pts
 }
 
+// changeOutputs returns the ids of the siacoin and siafund outputs of pt that
+// are sent to the wallet's change addresses.
+func (w *Wallet) changeOutputs(pt modules.ProcessedTransaction) []types.OutputID {
+	var ids []types.OutputID
+	for _, po := range pt.Outputs {
+		if po.FundType != types.SpecifierSiacoinOutput && po.FundType != types.SpecifierSiafundOutput {
+			continue
+		}
+		if _, isChange := w.changeAddrs[po.RelatedAddress]; isChange {
+			ids = append(ids, po.ID)
+		}
+	}
+	return ids
+}
+
 // applyHistory applies any transaction history that the applied blocks
 // introduced.
 func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
@@ -426,6 +499,9 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 				return errors.AddContext(err, "could not put processed transaction")
 			}
 		}
+		if len(w.events.subscriptions) > 0 {
+			w.queueConfirmations(tx, consensusHeight)
+		}
 	}
 
 	return nil
@@ -467,6 +543,7 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 
 	if cc.Synced {
 		go w.threadedDefragWallet()
+		go w.threadedClaimSiafunds()
 	}
 }
 
@@ -481,6 +558,21 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	// Mark the transactions of the wallet that were displaced by conflicting
+	// transactions as rejected, before they are pruned.
+	if len(diff.Conflicts) > 0 {
+		walletTxns := make(map[types.TransactionID]struct{})
+		for _, pt := range w.unconfirmedProcessedTransactions {
+			walletTxns[pt.TransactionID] = struct{}{}
+		}
+		for _, conflict := range diff.Conflicts {
+			if _, exists := walletTxns[conflict.DisplacedTransaction]; exists {
+				w.log.Printf("Transaction %v was rejected, it conflicts with transaction %v\n", conflict.DisplacedTransaction, conflict.ConflictingTransaction)
+				w.rejectedTransactions[conflict.DisplacedTransaction] = conflict
+			}
+		}
+	}
+
 	// Do the pruning first. If there are any pruned transactions, we will need
 	// to re-allocate the whole processed transactions array.
 	droppedTransactions := make(map[types.TransactionID]struct{})
@@ -522,6 +614,12 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 		// to the wallet, but overhead should be low.
 		w.unconfirmedSets[unconfirmedTxnSet.ID] = unconfirmedTxnSet.IDs
 
+		// A rejected transaction can return to the pool if the transaction
+		// that it conflicts with is reverted.
+		for _, txid := range unconfirmedTxnSet.IDs {
+			delete(w.rejectedTransactions, txid)
+		}
+
 		// Get the values for the spent outputs.
 		spentSiacoinOutputs := make(map[types.SiacoinOutputID]types.SiacoinOutput)
 		for _, scod := range unconfirmedTxnSet.Change.SiacoinOutputDiffs {
@@ -579,7 +677,21 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 					Value:    fee,
 				})
 			}
+			pt.ChangeOutputs = w.changeOutputs(pt)
 			w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
+			delete(droppedTransactions, pt.TransactionID)
+			if _, exists := w.unconfirmedFirstSeen[pt.TransactionID]; !exists {
+				w.unconfirmedFirstSeen[pt.TransactionID] = types.CurrentTimestamp()
+				if pt.Summary(0).Direction == modules.TransactionDirectionIncoming {
+					w.queueEvent(eventReceive, pt, nil)
+				}
+			}
 		}
 	}
+
+	// Transactions that were dropped and did not return to the pool as part
+	// of a new set are no longer pending.
+	for txid := range droppedTransactions {
+		delete(w.unconfirmedFirstSeen, txid)
+	}
 }
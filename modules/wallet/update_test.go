@@ -83,3 +83,81 @@ func TestUpdate(t *testing.T) {
 		t.Fatal("transaction was not removed")
 	}
 }
+
+// TestRejectedTransactions checks that the wallet marks its unconfirmed
+// transactions as rejected when they are displaced by a conflicting
+// transaction.
+func TestRejectedTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txnSet, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := txnSet[len(txnSet)-1]
+	var setID modules.TransactionSetID
+	var setIDs []types.TransactionID
+	wt.wallet.mu.RLock()
+	for id, txids := range wt.wallet.unconfirmedSets {
+		for _, txid := range txids {
+			if txid == sent.ID() {
+				setID, setIDs = id, txids
+			}
+		}
+	}
+	wt.wallet.mu.RUnlock()
+	if len(setIDs) == 0 {
+		t.Fatal("sent transaction is not unconfirmed")
+	}
+
+	// Displace the transaction.
+	conflict := modules.TransactionConflict{
+		DisplacedTransaction:   sent.ID(),
+		ConflictingTransaction: types.TransactionID{1},
+		Confirmed:              true,
+	}
+	wt.wallet.ReceiveUpdatedUnconfirmedTransactions(&modules.TransactionPoolDiff{
+		RevertedTransactions: []modules.TransactionSetID{setID},
+		Conflicts:            []modules.TransactionConflict{conflict},
+	})
+	rejected, err := wt.wallet.RejectedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 || rejected[0] != conflict {
+		t.Fatal("transaction was not marked as rejected:", rejected)
+	}
+	unconfirmed, err := wt.wallet.UnconfirmedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pt := range unconfirmed {
+		if pt.TransactionID == sent.ID() {
+			t.Fatal("rejected transaction is still unconfirmed")
+		}
+	}
+
+	// The transaction is no longer rejected once it returns to the pool.
+	wt.wallet.ReceiveUpdatedUnconfirmedTransactions(&modules.TransactionPoolDiff{
+		AppliedTransactions: []*modules.UnconfirmedTransactionSet{{
+			Change:       new(modules.ConsensusChange),
+			ID:           setID,
+			IDs:          setIDs,
+			Transactions: txnSet,
+		}},
+	})
+	rejected, err = wt.wallet.RejectedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 0 {
+		t.Fatal("returned transaction is still rejected:", rejected)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/coreos/bbolt"
 
@@ -33,6 +34,10 @@ const (
 var (
 	errNilConsensusSet = errors.New("wallet cannot initialize with a nil consensus set")
 	errNilTpool        = errors.New("wallet cannot initialize with a nil transaction pool")
+
+	errNegativeAutoLockTimeout = errors.New("auto-lock timeout cannot be negative")
+	errDefragThresholdTooLow   = errors.New("defrag threshold must be larger than the number of outputs combined by a defrag")
+	errInvalidFeePolicy        = errors.New("fee policy must be \"economical\", \"normal\" or \"priority\"")
 )
 
 // spendableKey is a set of secret keys plus the corresponding unlock
@@ -75,6 +80,27 @@ type Wallet struct {
 	keys      map[types.UnlockHash]spendableKey
 	lookahead map[types.UnlockHash]uint64
 
+	// changeAddrs are the addresses of the keys in keys that were derived as
+	// change addresses. changeLookahead holds the future change addresses of
+	// the primary seed, mapped to their change index.
+	changeAddrs     map[types.UnlockHash]struct{}
+	changeLookahead map[types.UnlockHash]uint64
+
+	// primaryKeyIndices maps the public keys of the primary seed's keys to
+	// their index in the seed. signer, if set, signs with these keys instead
+	// of the wallet. See signer.go.
+	primaryKeyIndices map[crypto.PublicKey]uint64
+	signer            Signer
+
+	// watchedAddrs are addresses that the wallet tracks on the blockchain
+	// without holding their keys. Outputs sent to them are counted in the
+	// wallet's balance and history, but cannot be spent by the wallet.
+	watchedAddrs map[types.UnlockHash]struct{}
+
+	// lockedOutputs are outputs of the wallet that it does not use when it
+	// funds transactions, unless they are unlocked again.
+	lockedOutputs map[types.OutputID]struct{}
+
 	// unconfirmedProcessedTransactions tracks unconfirmed transactions.
 	//
 	// TODO: Replace this field with a linked list. Currently when a new
@@ -84,6 +110,15 @@ type Wallet struct {
 	unconfirmedSets                  map[modules.TransactionSetID][]types.TransactionID
 	unconfirmedProcessedTransactions []modules.ProcessedTransaction
 
+	// unconfirmedFirstSeen tracks when the wallet first saw each of its
+	// unconfirmed transactions in the transaction pool.
+	unconfirmedFirstSeen map[types.TransactionID]types.Timestamp
+
+	// rejectedTransactions tracks the unconfirmed transactions of the wallet
+	// that were displaced from the transaction pool by conflicting
+	// transactions, and will not be confirmed.
+	rejectedTransactions map[types.TransactionID]modules.TransactionConflict
+
 	// The wallet's database tracks its seeds, keys, outputs, and
 	// transactions. A global db transaction is maintained in memory to avoid
 	// excessive disk writes. Any operations involving dbTx must hold an
@@ -111,6 +146,47 @@ type Wallet struct {
 	// defragDisabled determines if the wallet is set to defrag outputs once it
 	// reaches a certain threshold
 	defragDisabled bool
+
+	// defragThreshold is the number of outputs the wallet is allowed before
+	// it is defragmented. defragMaxFee is the highest fee per byte at which
+	// the wallet defrags; a fee of zero means no limit.
+	defragThreshold uint64
+	defragMaxFee    types.Currency
+
+	// claimThreshold is the siacoin claim balance of the wallet's siafunds at
+	// which the wallet sends the siafunds to itself to claim it. A threshold
+	// of zero disables claiming.
+	claimThreshold types.Currency
+
+	// feePolicy determines the fee per byte of transactions that are sent
+	// without an explicit fee.
+	feePolicy modules.FeePolicy
+
+	// dustThreshold is the value below which outputs are considered dust. A
+	// threshold of zero selects a threshold based on the current fees.
+	dustThreshold types.Currency
+
+	// spendUnconfirmedDisabled prevents the wallet from funding transactions
+	// with outputs that are created by its own unconfirmed transactions.
+	spendUnconfirmedDisabled bool
+
+	// autoLockTimeout is the amount of time after which an unlocked wallet
+	// is locked again, and is stored in the database. autoLockTimer is the
+	// pending timer, if any, and autoLockID identifies it so that a timer
+	// that fires after the wallet was locked and unlocked again does not
+	// lock the wallet early.
+	autoLockTimeout time.Duration
+	autoLockTimer   *time.Timer
+	autoLockID      uint64
+
+	// policyMu serializes the sends that are limited by the spending
+	// policy, so that concurrent sends cannot exceed its daily limit
+	// together. See policy.go.
+	policyMu sync.Mutex
+
+	// events holds the subscribers that are notified of the wallet's
+	// transactions. See events.go.
+	events walletEvents
 }
 
 // Height return the internal processed consensus height of the wallet
@@ -159,10 +235,27 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 		keys:      make(map[types.UnlockHash]spendableKey),
 		lookahead: make(map[types.UnlockHash]uint64),
 
-		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
+		changeAddrs:     make(map[types.UnlockHash]struct{}),
+		changeLookahead: make(map[types.UnlockHash]uint64),
+
+		primaryKeyIndices: make(map[crypto.PublicKey]uint64),
+
+		watchedAddrs:  make(map[types.UnlockHash]struct{}),
+		lockedOutputs: make(map[types.OutputID]struct{}),
+
+		unconfirmedSets:      make(map[modules.TransactionSetID][]types.TransactionID),
+		unconfirmedFirstSeen: make(map[types.TransactionID]types.Timestamp),
+		rejectedTransactions: make(map[types.TransactionID]modules.TransactionConflict),
 
 		persistDir: persistDir,
 
+		defragThreshold: defragThreshold,
+		feePolicy:       modules.FeePolicyNormal,
+
+		events: walletEvents{
+			signal: make(chan struct{}, 1),
+		},
+
 		deps: deps,
 	}
 	err := w.initPersist()
@@ -176,6 +269,30 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 		w.log.Critical("ERROR: failed to start database update:", err)
 	}
 
+	// Load the watched addresses.
+	watched, err := dbGetWatchedAddresses(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range watched {
+		w.watchedAddrs[addr] = struct{}{}
+	}
+
+	// Load the locked outputs.
+	locked, err := dbGetLockedOutputs(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range locked {
+		w.lockedOutputs[id] = struct{}{}
+	}
+
+	// Load the auto-lock timeout.
+	w.autoLockTimeout, err = dbGetAutoLockTimeout(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+
 	// COMPATv131 we need to create the bucketProcessedTxnIndex if it doesn't exist
 	if w.dbTx.Bucket(bucketProcessedTransactions).Stats().KeyN > 0 &&
 		w.dbTx.Bucket(bucketProcessedTxnIndex).Stats().KeyN == 0 {
@@ -188,6 +305,7 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 			return nil, err
 		}
 	}
+	go w.threadedDeliverEvents()
 	return w, nil
 }
 
@@ -253,14 +371,82 @@ func (w *Wallet) Rescanning() (bool, error) {
 	return rescanning, nil
 }
 
+// Rescan clears the outputs and transactions tracked by the wallet and
+// rescans the blockchain to rediscover them for all of the wallet's keys.
+// Rescan blocks until the wallet has caught up with the consensus set.
+func (w *Wallet) Rescan() error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.mu.RLock()
+	unlocked := w.unlocked
+	w.mu.RUnlock()
+	if !unlocked {
+		return modules.ErrLockedWallet
+	}
+	return w.managedRescan()
+}
+
+// managedRescan wipes the outputs and transactions tracked by the wallet and,
+// if the wallet is subscribed, resubscribes to the consensus set from the
+// beginning. If the wallet is not subscribed yet, the rescan happens when it
+// is first unlocked. The caller must hold the scanLock.
+func (w *Wallet) managedRescan() error {
+	w.mu.Lock()
+	w.log.Println("INFO: Rescanning the blockchain.")
+	// Wipe the tracked outputs and transactions. The unconfirmed
+	// transactions are sent again when the wallet resubscribes to the
+	// transaction pool.
+	err := dbResetTracked(w.dbTx)
+	if err == nil {
+		w.unconfirmedSets = make(map[modules.TransactionSetID][]types.TransactionID)
+		w.unconfirmedProcessedTransactions = nil
+	}
+	subscribed := w.subscribed
+	w.mu.Unlock()
+	if err != nil || !subscribed {
+		return err
+	}
+
+	w.cs.Unsubscribe(w)
+	w.tpool.Unsubscribe(w)
+
+	done := make(chan struct{})
+	go w.rescanMessage(done)
+	defer close(done)
+
+	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	if err != nil {
+		return err
+	}
+	w.tpool.TransactionPoolSubscribe(w)
+	return nil
+}
+
 // Settings returns the wallet's current settings
 func (w *Wallet) Settings() (modules.WalletSettings, error) {
 	if err := w.tg.Add(); err != nil {
 		return modules.WalletSettings{}, modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return modules.WalletSettings{
-		NoDefrag: w.defragDisabled,
+		NoDefrag:              w.defragDisabled,
+		DefragThreshold:       w.defragThreshold,
+		DefragMaxFee:          w.defragMaxFee,
+		AutoLockTimeout:       w.autoLockTimeout,
+		SiafundClaimThreshold: w.claimThreshold,
+		FeePolicy:             w.feePolicy,
+		NoSpendUnconfirmed:    w.spendUnconfirmedDisabled,
+		DustThreshold:         w.dustThreshold,
 	}, nil
 }
 
@@ -270,9 +456,42 @@ func (w *Wallet) SetSettings(s modules.WalletSettings) error {
 		return modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
+	if s.AutoLockTimeout < 0 {
+		return errNegativeAutoLockTimeout
+	}
+	if s.DefragThreshold == 0 {
+		s.DefragThreshold = defragThreshold
+	} else if s.DefragThreshold <= defragBatchSize+defragStartIndex {
+		return errDefragThresholdTooLow
+	}
+	switch s.FeePolicy {
+	case "":
+		s.FeePolicy = modules.FeePolicyNormal
+	case modules.FeePolicyEconomical, modules.FeePolicyNormal, modules.FeePolicyPriority:
+	default:
+		return errInvalidFeePolicy
+	}
 
 	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The auto-lock timeout is stored in the database so that it survives a
+	// restart. Changing it restarts the timer of an unlocked wallet.
+	if s.AutoLockTimeout != w.autoLockTimeout {
+		if err := dbPutAutoLockTimeout(w.dbTx, s.AutoLockTimeout); err != nil {
+			return err
+		}
+		if err := w.syncDB(); err != nil {
+			return err
+		}
+		w.autoLockTimeout = s.AutoLockTimeout
+		w.resetAutoLock()
+	}
 	w.defragDisabled = s.NoDefrag
-	w.mu.Unlock()
+	w.defragThreshold = s.DefragThreshold
+	w.defragMaxFee = s.DefragMaxFee
+	w.claimThreshold = s.SiafundClaimThreshold
+	w.feePolicy = s.FeePolicy
+	w.spendUnconfirmedDisabled = s.NoSpendUnconfirmed
+	w.dustThreshold = s.DustThreshold
 	return nil
 }
@@ -290,6 +290,91 @@ func TestRescanning(t *testing.T) {
 	}
 }
 
+// TestRescan checks that Rescan rediscovers the outputs and transactions of
+// the wallet after they were wiped from its database.
+func TestRescan(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Send some coins so that the wallet has an unconfirmed transaction.
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	height, err := wt.wallet.Height()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.Transactions(0, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err := wt.wallet.UnconfirmedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wipe the tracked outputs and transactions.
+	wt.wallet.mu.Lock()
+	err = dbResetTracked(wt.wallet.dbTx)
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _, _, _ := wt.wallet.ConfirmedBalance(); !b.IsZero() {
+		t.Fatal("balance was not wiped:", b)
+	}
+
+	err = wt.wallet.Rescan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance2, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance2.Equals(balance) {
+		t.Fatalf("balance after rescan is %v, expected %v", balance2, balance)
+	}
+	height2, err := wt.wallet.Height()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns2, err := wt.wallet.Transactions(0, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height2 != height || len(txns2) != len(txns) {
+		t.Fatalf("rescan found %v transactions up to height %v, expected %v up to %v", len(txns2), height2, len(txns), height)
+	}
+	unconfirmed2, err := wt.wallet.UnconfirmedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed2) != len(unconfirmed) {
+		t.Fatalf("rescan found %v unconfirmed transactions, expected %v", len(unconfirmed2), len(unconfirmed))
+	}
+
+	// A locked wallet cannot be rescanned.
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wt.wallet.Rescan(); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+}
+
 // TestFutureAddressGeneration checks if the right amount of future addresses
 // is generated after calling NextAddress() or locking + unlocking the wallet.
 func TestLookaheadGeneration(t *testing.T) {
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+)
+
+var (
+	errNotWatchedAddress = errors.New("address is not being watched")
+)
+
+// WatchAddresses returns the addresses that the wallet is watching, sorted in
+// byte-order.
+func (w *Wallet) WatchAddresses() ([]types.UnlockHash, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.watchedAddrList(), nil
+}
+
+// AddWatchAddresses instructs the wallet to track a set of addresses in
+// addition to the ones it holds keys for. Outputs sent to watched addresses
+// are counted in the wallet's balance and history but cannot be spent by the
+// wallet. If none of the addresses have appeared in the blockchain, unused
+// may be set to true to skip rescanning the blockchain for them.
+func (w *Wallet) AddWatchAddresses(addrs []types.UnlockHash, unused bool) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	return w.managedUpdateWatchAddresses(unused, func() error {
+		for _, addr := range addrs {
+			w.watchedAddrs[addr] = struct{}{}
+		}
+		return nil
+	})
+}
+
+// RemoveWatchAddresses instructs the wallet to stop tracking a set of
+// addresses. If none of the addresses have appeared in the blockchain, unused
+// may be set to true to skip rescanning the blockchain without them.
+func (w *Wallet) RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	return w.managedUpdateWatchAddresses(unused, func() error {
+		for _, addr := range addrs {
+			if _, exists := w.watchedAddrs[addr]; !exists {
+				return errNotWatchedAddress
+			}
+		}
+		for _, addr := range addrs {
+			delete(w.watchedAddrs, addr)
+		}
+		return nil
+	})
+}
+
+// managedUpdateWatchAddresses applies fn to the set of watched addresses,
+// persists the result, and rescans the blockchain unless unused is set.
+func (w *Wallet) managedUpdateWatchAddresses(unused bool, fn func() error) error {
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.mu.Lock()
+	err := fn()
+	if err == nil {
+		err = dbPutWatchedAddresses(w.dbTx, w.watchedAddrList())
+	}
+	if err == nil {
+		err = w.syncDB()
+	}
+	w.mu.Unlock()
+	if err != nil || unused {
+		return err
+	}
+	return w.managedRescan()
+}
+
+// watchedAddrList returns the watched addresses sorted in byte-order.
+func (w *Wallet) watchedAddrList() []types.UnlockHash {
+	addrs := make([]types.UnlockHash, 0, len(w.watchedAddrs))
+	for addr := range w.watchedAddrs {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
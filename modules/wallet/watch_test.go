@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestWatchAddresses checks that the outputs of watched addresses are tracked
+// by the wallet, but cannot be spent.
+func TestWatchAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Send coins to an address that the wallet does not hold the keys for.
+	addr := types.UnlockHash{1}
+	amount := types.SiacoinPrecision.Mul64(100)
+	_, err = wt.wallet.SendSiacoins(amount, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	balance, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Watch the address. The wallet should rescan the blockchain and find
+	// the output.
+	err = wt.wallet.AddWatchAddresses([]types.UnlockHash{addr}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := wt.wallet.WatchAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0] != addr {
+		t.Fatal("wrong watched addresses:", addrs)
+	}
+	watched, err := dbGetWatchedAddresses(wt.wallet.dbTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(watched) != 1 || watched[0] != addr {
+		t.Fatal("watched addresses were not persisted:", watched)
+	}
+	balance2, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance2.Equals(balance.Add(amount)) {
+		t.Fatalf("balance is %v, expected %v", balance2, balance.Add(amount))
+	}
+	txns, err := wt.wallet.AddressTransactions(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1 {
+		t.Fatal("expected one transaction for the watched address, got", len(txns))
+	}
+
+	// The wallet should not be able to spend the watched output.
+	tb, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.FundSiacoins(balance2)
+	tb.Drop()
+	if err == nil {
+		t.Fatal("wallet was able to spend the output of a watched address")
+	}
+
+	// Stop watching the address.
+	err = wt.wallet.RemoveWatchAddresses([]types.UnlockHash{addr}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance3, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance3.Equals(balance) {
+		t.Fatalf("balance is %v, expected %v", balance3, balance)
+	}
+	err = wt.wallet.RemoveWatchAddresses([]types.UnlockHash{addr}, true)
+	if err != errNotWatchedAddress {
+		t.Fatal("expected errNotWatchedAddress, got", err)
+	}
+}
@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/entropy-mnemonics"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestSeedStringRoundTrip checks that a seed survives conversion to and from
+// its phrase, and that a phrase with a changed word fails the checksum.
+func TestSeedStringRoundTrip(t *testing.T) {
+	var seed Seed
+	fastrand.Read(seed[:])
+	for _, did := range []mnemonics.DictionaryID{mnemonics.English, mnemonics.German, mnemonics.Japanese} {
+		phrase, err := SeedToString(seed, did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := StringToSeed(phrase, did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != seed {
+			t.Fatal("seed changed after round trip through dictionary", did)
+		}
+	}
+
+	// Replace the first word of the phrase with a different word from the
+	// same phrase.
+	phrase, err := SeedToString(seed, mnemonics.English)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields(phrase)
+	for _, w := range words[1:] {
+		if w != words[0] {
+			words[0] = w
+			break
+		}
+	}
+	_, err = StringToSeed(strings.Join(words, " "), mnemonics.English)
+	if err == nil {
+		t.Fatal("phrase with a changed word passed the checksum")
+	}
+}
+
+// TestProcessedTransactionSummary checks that the summary of a processed
+// transaction reports the value that it moves in and out of the wallet.
+func TestProcessedTransactionSummary(t *testing.T) {
+	own := types.UnlockHash{1}
+	other := types.UnlockHash{2}
+	pt := ProcessedTransaction{
+		ConfirmationHeight: 10,
+		Inputs: []ProcessedInput{
+			{FundType: types.SpecifierSiacoinInput, WalletAddress: true, RelatedAddress: own, Value: types.NewCurrency64(100)},
+			{FundType: types.SpecifierSiafundInput, WalletAddress: true, RelatedAddress: own, Value: types.NewCurrency64(5)},
+		},
+		Outputs: []ProcessedOutput{
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: false, RelatedAddress: other, Value: types.NewCurrency64(60)},
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: true, RelatedAddress: own, Value: types.NewCurrency64(30)},
+			{FundType: types.SpecifierSiafundOutput, WalletAddress: false, RelatedAddress: other, Value: types.NewCurrency64(5)},
+			{FundType: types.SpecifierMinerFee, Value: types.NewCurrency64(10)},
+		},
+	}
+	pts := pt.Summary(12)
+	if pts.Direction != TransactionDirectionOutgoing || pts.Confirmations != 3 {
+		t.Fatal("wrong direction or confirmations:", pts.Direction, pts.Confirmations)
+	}
+	if !pts.IncomingSiacoins.Equals64(30) || !pts.OutgoingSiacoins.Equals64(100) || !pts.Fees.Equals64(10) {
+		t.Fatal("wrong siacoin values:", pts.IncomingSiacoins, pts.OutgoingSiacoins, pts.Fees)
+	}
+	if !pts.IncomingSiafunds.IsZero() || !pts.OutgoingSiafunds.Equals64(5) {
+		t.Fatal("wrong siafund values:", pts.IncomingSiafunds, pts.OutgoingSiafunds)
+	}
+	if len(pts.RelatedAddresses) != 1 || pts.RelatedAddresses[0] != other {
+		t.Fatal("wrong related addresses:", pts.RelatedAddresses)
+	}
+
+	// Change is neither incoming nor outgoing.
+	pt.Outputs[1].ID = types.OutputID{1}
+	pt.ChangeOutputs = []types.OutputID{{1}}
+	pts = pt.Summary(12)
+	if pts.Direction != TransactionDirectionOutgoing || !pts.IncomingSiacoins.IsZero() || !pts.OutgoingSiacoins.Equals64(70) {
+		t.Fatal("wrong summary of transaction with change:", pts)
+	}
+
+	// An unconfirmed transaction that only moves siafunds into the wallet is
+	// incoming.
+	pt = ProcessedTransaction{
+		ConfirmationHeight: types.BlockHeight(1<<64 - 1),
+		Outputs: []ProcessedOutput{
+			{FundType: types.SpecifierSiafundOutput, WalletAddress: true, RelatedAddress: own, Value: types.NewCurrency64(5)},
+		},
+	}
+	pts = pt.Summary(12)
+	if pts.Direction != TransactionDirectionIncoming || pts.Confirmations != 0 || len(pts.RelatedAddresses) != 0 {
+		t.Fatal("wrong summary of unconfirmed siafund transaction:", pts)
+	}
+
+	// A transaction without any value is internal.
+	if d := (ProcessedTransaction{}).Summary(12).Direction; d != TransactionDirectionInternal {
+		t.Fatal("expected an internal transaction, got", d)
+	}
+}
@@ -24,3 +24,16 @@ func (c *Client) ConsensusBlocksHeightGet(height types.BlockHeight) (cbg api.Con
 	err = c.get("/consensus/blocks?height="+fmt.Sprint(height), &cbg)
 	return
 }
+
+// ConsensusCompactPost uses the /consensus/compact endpoint to compact the
+// consensus database.
+func (c *Client) ConsensusCompactPost() (ccp api.ConsensusCompactPOST, err error) {
+	err = c.post("/consensus/compact", "", &ccp)
+	return
+}
+
+// ConsensusSyncGet requests the /consensus/sync api resource
+func (c *Client) ConsensusSyncGet() (csg api.ConsensusSyncGET, err error) {
+	err = c.get("/consensus/sync", &csg)
+	return
+}
@@ -1,6 +1,11 @@
 package client
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/errors"
@@ -35,3 +40,141 @@ func (c *Client) GatewayGet() (gwg api.GatewayGET, err error) {
 	err = c.get("/gateway", &gwg)
 	return
 }
+
+// GatewayListenPost uses the /gateway/listen endpoint to change the addresses
+// that the gateway listens on.
+func (c *Client) GatewayListenPost(addrs []string) (err error) {
+	values := url.Values{}
+	values.Set("addresses", strings.Join(addrs, ","))
+	err = c.post("/gateway/listen", values.Encode(), nil)
+	return
+}
+
+// GatewayBootstrapGet requests the /gateway/bootstrap api resource
+func (c *Client) GatewayBootstrapGet() (gbg api.GatewayBootstrapGET, err error) {
+	err = c.get("/gateway/bootstrap", &gbg)
+	return
+}
+
+// GatewayBootstrapAddPost uses the /gateway/bootstrap/add/:address endpoint to
+// add a bootstrap node to the gateway.
+func (c *Client) GatewayBootstrapAddPost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/bootstrap/add/"+string(address), "", nil)
+	return
+}
+
+// GatewayBootstrapRemovePost uses the /gateway/bootstrap/remove/:address
+// endpoint to remove a bootstrap node from the gateway.
+func (c *Client) GatewayBootstrapRemovePost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/bootstrap/remove/"+string(address), "", nil)
+	return
+}
+
+// GatewayBootstrapPinPost uses the /gateway/bootstrap/pin/:address endpoint
+// to pin or unpin a bootstrap node.
+func (c *Client) GatewayBootstrapPinPost(address modules.NetAddress, pinned bool) (err error) {
+	err = c.post("/gateway/bootstrap/pin/"+string(address), fmt.Sprintf("pinned=%t", pinned), nil)
+	return
+}
+
+// GatewayBansGet requests the /gateway/bans api resource
+func (c *Client) GatewayBansGet() (gbg api.GatewayBansGET, err error) {
+	err = c.get("/gateway/bans", &gbg)
+	return
+}
+
+// GatewayBansClearPost uses the /gateway/bans/clear endpoint to lift all bans.
+func (c *Client) GatewayBansClearPost() (err error) {
+	err = c.post("/gateway/bans/clear", "", nil)
+	return
+}
+
+// GatewayStatsGet requests the /gateway/stats api resource
+func (c *Client) GatewayStatsGet() (gsg api.GatewayStatsGET, err error) {
+	err = c.get("/gateway/stats", &gsg)
+	return
+}
+
+// GatewayPeerListsGet requests the /gateway/peerlists api resource
+func (c *Client) GatewayPeerListsGet() (gplg api.GatewayPeerListsGET, err error) {
+	err = c.get("/gateway/peerlists", &gplg)
+	return
+}
+
+// GatewayWhitelistOnlyPost uses the /gateway/peerlists endpoint to set
+// whether the gateway only connects to whitelisted peers.
+func (c *Client) GatewayWhitelistOnlyPost(whitelistOnly bool) (err error) {
+	err = c.post("/gateway/peerlists", fmt.Sprintf("whitelistonly=%t", whitelistOnly), nil)
+	return
+}
+
+// GatewayBlacklistAddPost uses the /gateway/blacklist/add/:address endpoint to
+// blacklist an address.
+func (c *Client) GatewayBlacklistAddPost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/blacklist/add/"+string(address), "", nil)
+	return
+}
+
+// GatewayBlacklistRemovePost uses the /gateway/blacklist/remove/:address
+// endpoint to remove an address from the blacklist.
+func (c *Client) GatewayBlacklistRemovePost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/blacklist/remove/"+string(address), "", nil)
+	return
+}
+
+// GatewayWhitelistAddPost uses the /gateway/whitelist/add/:address endpoint to
+// whitelist an address.
+func (c *Client) GatewayWhitelistAddPost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/whitelist/add/"+string(address), "", nil)
+	return
+}
+
+// GatewayWhitelistRemovePost uses the /gateway/whitelist/remove/:address
+// endpoint to remove an address from the whitelist.
+func (c *Client) GatewayWhitelistRemovePost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/whitelist/remove/"+string(address), "", nil)
+	return
+}
+
+// GatewayRateLimitPost uses the /gateway endpoint to change the gateway's
+// bandwidth limits.
+func (c *Client) GatewayRateLimitPost(downloadSpeed, uploadSpeed, peerDownloadSpeed, peerUploadSpeed int64) (err error) {
+	values := url.Values{}
+	values.Set("maxdownloadspeed", strconv.FormatInt(downloadSpeed, 10))
+	values.Set("maxuploadspeed", strconv.FormatInt(uploadSpeed, 10))
+	values.Set("peermaxdownloadspeed", strconv.FormatInt(peerDownloadSpeed, 10))
+	values.Set("peermaxuploadspeed", strconv.FormatInt(peerUploadSpeed, 10))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
+
+// GatewayPeerLimitsPost uses the /gateway endpoint to change the number of
+// inbound and outbound slots of the gateway.
+func (c *Client) GatewayPeerLimitsPost(maxInbound, maxOutbound int) (err error) {
+	values := url.Values{}
+	values.Set("maxinboundpeers", strconv.Itoa(maxInbound))
+	values.Set("maxoutboundpeers", strconv.Itoa(maxOutbound))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
+
+// GatewayAddressFamilyPost uses the /gateway endpoint to change the preferred
+// address family of the gateway, and whether it only connects to peers of that
+// family.
+func (c *Client) GatewayAddressFamilyPost(family modules.AddressFamily, only bool) (err error) {
+	values := url.Values{}
+	values.Set("preferredaddressfamily", string(family))
+	values.Set("addressfamilyonly", strconv.FormatBool(only))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
+
+// GatewayDNSSeedsPost uses the /gateway endpoint to change the extra DNS seeds
+// of the gateway, and whether DNS seeding is disabled.
+func (c *Client) GatewayDNSSeedsPost(seeds []string, disable bool) (err error) {
+	values := url.Values{}
+	values.Set("dnsseeds", strings.Join(seeds, ","))
+	values.Set("disablednsseeds", strconv.FormatBool(disable))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
@@ -1,9 +1,12 @@
 package client
 
 import (
+	"fmt"
 	"net/url"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -14,6 +17,36 @@ func (c *Client) TransactionPoolFeeGet() (tfg api.TpoolFeeGET, err error) {
 	return
 }
 
+// TransactionPoolFeeTargetGet uses the /tpool/fee endpoint to get a fee
+// estimation for confirmation within target blocks.
+func (c *Client) TransactionPoolFeeTargetGet(target types.BlockHeight) (tfg api.TpoolFeeGET, err error) {
+	err = c.get(fmt.Sprintf("/tpool/fee?target=%v", target), &tfg)
+	return
+}
+
+// TransactionPoolRawSetsGet uses the /tpool/raw endpoint to get the raw
+// encoded transaction sets in the transaction pool.
+func (c *Client) TransactionPoolRawSetsGet() (trg api.TpoolRawSetsGET, err error) {
+	err = c.get("/tpool/raw", &trg)
+	return
+}
+
+// TransactionPoolOutputsGet uses the /tpool/outputs/:unlockhash endpoint to
+// get the outputs of an unlock hash that are created or spent by unconfirmed
+// transactions.
+func (c *Client) TransactionPoolOutputsGet(uh types.UnlockHash) (tog api.TpoolOutputsGET, err error) {
+	err = c.get("/tpool/outputs/"+uh.String(), &tog)
+	return
+}
+
+// TransactionPoolSpendableGet uses the /tpool/spendable/:unlockhash endpoint to
+// get the outputs of an unlock hash that are created by unconfirmed
+// transactions and not yet spent, along with their dependency sets.
+func (c *Client) TransactionPoolSpendableGet(uh types.UnlockHash) (tsg api.TpoolSpendableGET, err error) {
+	err = c.get("/tpool/spendable/"+uh.String(), &tsg)
+	return
+}
+
 // TransactionPoolRawPost uses the /tpool/raw endpoint to send a raw
 // transaction to the transaction pool.
 func (c *Client) TransactionPoolRawPost(txn types.Transaction, parents types.Transaction) (err error) {
@@ -23,3 +56,89 @@ func (c *Client) TransactionPoolRawPost(txn types.Transaction, parents types.Tra
 	err = c.post("/tpool/raw", values.Encode(), nil)
 	return
 }
+
+// TransactionPoolValidatePost uses the /tpool/validate endpoint to check
+// whether the transaction pool would accept a raw transaction set.
+func (c *Client) TransactionPoolValidatePost(txn types.Transaction, parents []types.Transaction) (tvp api.TpoolValidatePOST, err error) {
+	values := url.Values{}
+	values.Set("transaction", string(encoding.Marshal(txn)))
+	values.Set("parents", string(encoding.Marshal(parents)))
+	err = c.post("/tpool/validate", values.Encode(), &tvp)
+	return
+}
+
+// TransactionPoolLocalGet uses the /tpool/local endpoint to get the unconfirmed
+// transactions that were submitted to the node.
+func (c *Client) TransactionPoolLocalGet() (tlg api.TpoolLocalGET, err error) {
+	err = c.get("/tpool/local", &tlg)
+	return
+}
+
+// TransactionPoolRejectionsGet uses the /tpool/rejections endpoint to get the
+// transaction sets that were recently rejected by the transaction pool.
+func (c *Client) TransactionPoolRejectionsGet() (trg api.TpoolRejectionsGET, err error) {
+	err = c.get("/tpool/rejections", &trg)
+	return
+}
+
+// TransactionPoolSettingsGet uses the /tpool/settings endpoint to get the
+// settings of the transaction pool.
+func (c *Client) TransactionPoolSettingsGet() (tsg api.TpoolSettingsGET, err error) {
+	err = c.get("/tpool/settings", &tsg)
+	return
+}
+
+// TransactionPoolMaxPoolSizePost uses the /tpool/settings endpoint to change
+// the maximum size of the transaction pool.
+func (c *Client) TransactionPoolMaxPoolSizePost(size uint64) (err error) {
+	values := url.Values{}
+	values.Set("maxpoolsize", fmt.Sprint(size))
+	err = c.post("/tpool/settings", values.Encode(), nil)
+	return
+}
+
+// TransactionPoolMaxTransactionAgePost uses the /tpool/settings endpoint to
+// change the number of blocks after which unconfirmed transactions are
+// dropped from the transaction pool.
+func (c *Client) TransactionPoolMaxTransactionAgePost(age types.BlockHeight) (err error) {
+	values := url.Values{}
+	values.Set("maxtransactionage", fmt.Sprint(age))
+	err = c.post("/tpool/settings", values.Encode(), nil)
+	return
+}
+
+// TransactionPoolPolicyPost uses the /tpool/settings endpoint to change the
+// standardness policy of the transaction pool.
+func (c *Client) TransactionPoolPolicyPost(policy modules.TransactionPoolPolicy) (err error) {
+	values := url.Values{}
+	values.Set("checkarbitrarydatasize", fmt.Sprint(policy.CheckArbitraryDataSize))
+	values.Set("maxarbitrarydatasize", fmt.Sprint(policy.MaxArbitraryDataSize))
+	values.Set("checkdustoutputs", fmt.Sprint(policy.CheckDustOutputs))
+	values.Set("dustthreshold", policy.DustThreshold.String())
+	values.Set("checksignaturecount", fmt.Sprint(policy.CheckSignatureCount))
+	values.Set("maxsignatures", fmt.Sprint(policy.MaxSignatures))
+	values.Set("checkunlockconditions", fmt.Sprint(policy.CheckUnlockConditions))
+	err = c.post("/tpool/settings", values.Encode(), nil)
+	return
+}
+
+// TransactionPoolStatsGet uses the /tpool/stats endpoint to get statistics
+// about the contents of the transaction pool.
+func (c *Client) TransactionPoolStatsGet() (tsg api.TpoolStatsGET, err error) {
+	err = c.get("/tpool/stats", &tsg)
+	return
+}
+
+// TransactionPoolTransactionGet uses the /tpool/transactions/:id endpoint to
+// get a transaction and its unconfirmed parents from the transaction pool.
+func (c *Client) TransactionPoolTransactionGet(id types.TransactionID) (ttg api.TpoolTransactionGET, err error) {
+	err = c.get("/tpool/transactions/"+id.String(), &ttg)
+	return
+}
+
+// TransactionPoolTransactionSetGet uses the /tpool/transactionsets/:id
+// endpoint to get a transaction set from the transaction pool.
+func (c *Client) TransactionPoolTransactionSetGet(id modules.TransactionSetID) (ttg api.TpoolTransactionSetGET, err error) {
+	err = c.get("/tpool/transactionsets/"+crypto.Hash(id).String(), &ttg)
+	return
+}
@@ -1,11 +1,16 @@
 package client
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -16,6 +21,13 @@ func (c *Client) WalletAddressGet() (wag api.WalletAddressGET, err error) {
 	return
 }
 
+// WalletUnusedAddressGet requests an address that has not been used yet from
+// the /wallet/address endpoint.
+func (c *Client) WalletUnusedAddressGet() (wag api.WalletAddressGET, err error) {
+	err = c.get("/wallet/address?unused=true", &wag)
+	return
+}
+
 // WalletAddressesGet requests the wallets known addresses from the
 // /wallet/addresses endpoint.
 func (c *Client) WalletAddressesGet() (wag api.WalletAddressesGET, err error) {
@@ -66,6 +78,66 @@ func (c *Client) WalletLockPost() (err error) {
 	return
 }
 
+// WalletMultisigPost uses the /wallet/multisig endpoint to create and watch
+// a multisig address.
+func (c *Client) WalletMultisigPost(publicKeys []types.SiaPublicKey, signaturesRequired uint64, unused bool) (wmp api.WalletMultisigPOST, err error) {
+	marshaledKeys, err := json.Marshal(publicKeys)
+	if err != nil {
+		return api.WalletMultisigPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("publickeys", string(marshaledKeys))
+	values.Set("signaturesrequired", strconv.FormatUint(signaturesRequired, 10))
+	values.Set("unused", strconv.FormatBool(unused))
+	err = c.post("/wallet/multisig", values.Encode(), &wmp)
+	return
+}
+
+// WalletLabelsGet requests the /wallet/labels endpoint to get the labels of
+// the wallet's addresses and the entries of its address book.
+func (c *Client) WalletLabelsGet() (wlg api.WalletLabelsGET, err error) {
+	err = c.get("/wallet/labels", &wlg)
+	return
+}
+
+// WalletLabelsPost uses the /wallet/labels endpoint to set the label of an
+// address. An empty label removes the label of the address.
+func (c *Client) WalletLabelsPost(addr types.UnlockHash, label string) (err error) {
+	values := url.Values{}
+	values.Set("address", addr.String())
+	values.Set("label", label)
+	err = c.post("/wallet/labels", values.Encode(), nil)
+	return
+}
+
+// WalletOutputsGet requests the /wallet/outputs endpoint to get the outputs
+// that the wallet can spend.
+func (c *Client) WalletOutputsGet() (wog api.WalletOutputsGET, err error) {
+	err = c.get("/wallet/outputs", &wog)
+	return
+}
+
+// WalletOutputsPost uses the /wallet/outputs endpoint to lock or unlock a set
+// of outputs of the wallet.
+func (c *Client) WalletOutputsPost(ids []types.OutputID, locked bool) (err error) {
+	idStrs := make([]string, len(ids))
+	for i, id := range ids {
+		idStrs[i] = id.String()
+	}
+	values := url.Values{}
+	values.Set("ids", strings.Join(idStrs, ","))
+	values.Set("locked", strconv.FormatBool(locked))
+	err = c.post("/wallet/outputs", values.Encode(), nil)
+	return
+}
+
+// WalletRescanPost uses the /wallet/rescan endpoint to rescan the blockchain
+// for the wallet's outputs and transactions.
+func (c *Client) WalletRescanPost() (err error) {
+	err = c.post("/wallet/rescan", "", nil)
+	return
+}
+
 // WalletSeedPost uses the /wallet/seed endpoint to add a seed to the wallet's list
 // of seeds.
 func (c *Client) WalletSeedPost(seed, password string) (err error) {
@@ -83,6 +155,80 @@ func (c *Client) WalletSeedsGet() (wsg api.WalletSeedsGET, err error) {
 	return
 }
 
+// WalletSettingsGet uses the /wallet/settings endpoint to get the settings
+// of the wallet.
+func (c *Client) WalletSettingsGet() (wsg api.WalletSettingsGET, err error) {
+	err = c.get("/wallet/settings", &wsg)
+	return
+}
+
+// WalletDefragThresholdPost uses the /wallet/settings endpoint to change the
+// number of outputs the wallet is allowed before it is defragmented.
+func (c *Client) WalletDefragThresholdPost(threshold uint64) (err error) {
+	values := url.Values{}
+	values.Set("defragthreshold", fmt.Sprint(threshold))
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletDefragMaxFeePost uses the /wallet/settings endpoint to change the
+// highest fee per byte at which the wallet is defragmented. A fee of zero
+// means no limit.
+func (c *Client) WalletDefragMaxFeePost(maxFee types.Currency) (err error) {
+	values := url.Values{}
+	values.Set("defragmaxfee", maxFee.String())
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletFeePolicyPost uses the /wallet/settings endpoint to change the fee
+// policy of the wallet.
+func (c *Client) WalletFeePolicyPost(policy modules.FeePolicy) (err error) {
+	values := url.Values{}
+	values.Set("feepolicy", string(policy))
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletDustThresholdPost uses the /wallet/settings endpoint to change the
+// value below which outputs are considered dust. A threshold of zero selects
+// a threshold based on the current fees.
+func (c *Client) WalletDustThresholdPost(threshold types.Currency) (err error) {
+	values := url.Values{}
+	values.Set("dustthreshold", threshold.String())
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletNoSpendUnconfirmedPost uses the /wallet/settings endpoint to change
+// whether the wallet funds transactions with the outputs of its own
+// unconfirmed transactions.
+func (c *Client) WalletNoSpendUnconfirmedPost(noSpendUnconfirmed bool) (err error) {
+	values := url.Values{}
+	values.Set("nospendunconfirmed", fmt.Sprint(noSpendUnconfirmed))
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletAutoLockTimeoutPost uses the /wallet/settings endpoint to change the
+// amount of time after which the unlocked wallet is locked again.
+func (c *Client) WalletAutoLockTimeoutPost(timeout time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("autolocktimeout", fmt.Sprint(int64(timeout)))
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletSiafundClaimThresholdPost uses the /wallet/settings endpoint to change
+// the siacoin claim balance at which the wallet claims the balance of its
+// siafunds. A threshold of zero disables claiming.
+func (c *Client) WalletSiafundClaimThresholdPost(threshold types.Currency) (err error) {
+	values := url.Values{}
+	values.Set("siafundclaimthreshold", threshold.String())
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
 // WalletSiacoinsMultiPost uses the /wallet/siacoin api endpoint to send money
 // to multiple addresses at once
 func (c *Client) WalletSiacoinsMultiPost(outputs []types.SiacoinOutput) (wsp api.WalletSiacoinsPOST, err error) {
@@ -96,6 +242,20 @@ func (c *Client) WalletSiacoinsMultiPost(outputs []types.SiacoinOutput) (wsp api
 	return
 }
 
+// WalletSiacoinsMultiWithFeePost uses the /wallet/siacoins api endpoint to
+// send money to multiple addresses at once, paying the specified total fee.
+func (c *Client) WalletSiacoinsMultiWithFeePost(outputs []types.SiacoinOutput, fee types.Currency) (wsp api.WalletSiacoinsPOST, err error) {
+	values := url.Values{}
+	marshaledOutputs, err := json.Marshal(outputs)
+	if err != nil {
+		return api.WalletSiacoinsPOST{}, err
+	}
+	values.Set("outputs", string(marshaledOutputs))
+	values.Set("fee", fee.String())
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
 // WalletSiacoinsPost uses the /wallet/siacoins api endpoint to send money to a
 // single address
 func (c *Client) WalletSiacoinsPost(amount types.Currency, destination types.UnlockHash) (wsp api.WalletSiacoinsPOST, err error) {
@@ -106,6 +266,56 @@ func (c *Client) WalletSiacoinsPost(amount types.Currency, destination types.Unl
 	return
 }
 
+// WalletSiacoinsWithFeePost uses the /wallet/siacoins api endpoint to send
+// money to a single address, paying the specified total fee.
+func (c *Client) WalletSiacoinsWithFeePost(amount types.Currency, destination types.UnlockHash, fee types.Currency) (wsp api.WalletSiacoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("fee", fee.String())
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletSiacoinsConfirmedPost uses the /wallet/siacoins api endpoint to send
+// money to a single address, confirming the send if it violates the wallet's
+// spending policy.
+func (c *Client) WalletSiacoinsConfirmedPost(amount types.Currency, destination types.UnlockHash) (wsp api.WalletSiacoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("confirm", "true")
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletSiacoinsWithMemoPost uses the /wallet/siacoins api endpoint to send
+// money to a single address, attaching a memo to the transaction.
+func (c *Client) WalletSiacoinsWithMemoPost(amount types.Currency, destination types.UnlockHash, memo string) (wsp api.WalletSiacoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("memo", memo)
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletSiacoinsWithInputsPost uses the /wallet/siacoins api endpoint to send
+// money to a single address, funded by exactly the specified outputs of the
+// wallet.
+func (c *Client) WalletSiacoinsWithInputsPost(amount types.Currency, destination types.UnlockHash, inputs []types.SiacoinOutputID) (wsp api.WalletSiacoinsPOST, err error) {
+	inputStrs := make([]string, len(inputs))
+	for i, id := range inputs {
+		inputStrs[i] = id.String()
+	}
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("inputs", strings.Join(inputStrs, ","))
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
 // WalletSiafundsPost uses the /wallet/siafunds api endpoint to send siafunds
 // to a single address.
 func (c *Client) WalletSiafundsPost(amount types.Currency, destination types.UnlockHash) (wsp api.WalletSiafundsPOST, err error) {
@@ -126,6 +336,15 @@ func (c *Client) WalletSiagKeyPost(keyfiles, password string) (err error) {
 	return
 }
 
+// WalletSweepKeyPost uses the /wallet/sweep/key endpoint to sweep the outputs
+// of a secret key into the current wallet.
+func (c *Client) WalletSweepKeyPost(sk crypto.SecretKey) (wsp api.WalletSweepPOST, err error) {
+	values := url.Values{}
+	values.Set("key", hex.EncodeToString(sk[:]))
+	err = c.post("/wallet/sweep/key", values.Encode(), &wsp)
+	return
+}
+
 // WalletSweepPost uses the /wallet/sweep/seed endpoint to sweep a seed into
 // the current wallet.
 func (c *Client) WalletSweepPost(seed string) (wsp api.WalletSweepPOST, err error) {
@@ -135,6 +354,13 @@ func (c *Client) WalletSweepPost(seed string) (wsp api.WalletSweepPOST, err erro
 	return
 }
 
+// WalletExportGet requests the /wallet/export endpoint to get the accounting
+// report of the wallet's transactions in the given format.
+func (c *Client) WalletExportGet(format modules.ExportFormat, startHeight, endHeight types.BlockHeight) ([]byte, error) {
+	return c.getRawResponse(fmt.Sprintf("/wallet/export?format=%v&startheight=%v&endheight=%v",
+		format, startHeight, endHeight))
+}
+
 // WalletTransactionsGet requests the/wallet/transactions api resource for a
 // certain startheight and endheight
 func (c *Client) WalletTransactionsGet(startHeight types.BlockHeight, endHeight types.BlockHeight) (wtg api.WalletTransactionsGET, err error) {
@@ -143,6 +369,13 @@ func (c *Client) WalletTransactionsGet(startHeight types.BlockHeight, endHeight
 	return
 }
 
+// WalletTransactionsMemoGet requests the /wallet/transactions api resource for
+// the transactions that carry a memo.
+func (c *Client) WalletTransactionsMemoGet(memo string) (wtg api.WalletTransactionsGET, err error) {
+	err = c.get("/wallet/transactions?memo="+url.QueryEscape(memo), &wtg)
+	return
+}
+
 // WalletTransactionGet requests the /wallet/transaction/:id api resource for a
 // certain TransactionID.
 func (c *Client) WalletTransactionGet(id types.TransactionID) (wtg api.WalletTransactionGETid, err error) {
@@ -159,6 +392,127 @@ func (c *Client) WalletUnlockPost(password string) (err error) {
 	return
 }
 
+// WalletSignPost uses the /wallet/sign endpoint to sign the signatures of a
+// transaction whose parent IDs are in toSign.
+func (c *Client) WalletSignPost(txn types.Transaction, toSign []crypto.Hash) (wsp api.WalletSignPOST, err error) {
+	marshaledTxn, err := json.Marshal(txn)
+	if err != nil {
+		return api.WalletSignPOST{}, err
+	}
+	marshaledToSign, err := json.Marshal(toSign)
+	if err != nil {
+		return api.WalletSignPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("transaction", string(marshaledTxn))
+	values.Set("tosign", string(marshaledToSign))
+	err = c.post("/wallet/sign", values.Encode(), &wsp)
+	return
+}
+
+// WalletSignMessagePost uses the /wallet/signmessage endpoint to sign msg with
+// the key of addr.
+func (c *Client) WalletSignMessagePost(addr types.UnlockHash, msg []byte) (wsmp api.WalletSignMessagePOST, err error) {
+	values := url.Values{}
+	values.Set("address", addr.String())
+	values.Set("message", string(msg))
+	err = c.post("/wallet/signmessage", values.Encode(), &wsmp)
+	return
+}
+
+// WalletVerifyMessageGet uses the /wallet/verify/message endpoint to check
+// that msg was signed by addr.
+func (c *Client) WalletVerifyMessageGet(addr types.UnlockHash, msg []byte, ms types.MessageSignature) (wvmg api.WalletVerifyMessageGET, err error) {
+	marshaledSig, err := json.Marshal(ms)
+	if err != nil {
+		return api.WalletVerifyMessageGET{}, err
+	}
+	values := url.Values{}
+	values.Set("address", addr.String())
+	values.Set("message", string(msg))
+	values.Set("signature", string(marshaledSig))
+	err = c.get("/wallet/verify/message?"+values.Encode(), &wvmg)
+	return
+}
+
+// WalletUnlockConditionsGet requests the /wallet/unlockconditions/:addr
+// endpoint to get the unlock conditions of an address.
+func (c *Client) WalletUnlockConditionsGet(addr types.UnlockHash) (wucg api.WalletUnlockConditionsGET, err error) {
+	err = c.get("/wallet/unlockconditions/"+addr.String(), &wucg)
+	return
+}
+
+// WalletUnlockConditionsPost uses the /wallet/unlockconditions endpoint to
+// add the unlock conditions of an address to the wallet.
+func (c *Client) WalletUnlockConditionsPost(uc types.UnlockConditions) (err error) {
+	marshaledUC, err := json.Marshal(uc)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("unlockconditions", string(marshaledUC))
+	err = c.post("/wallet/unlockconditions", values.Encode(), nil)
+	return
+}
+
+// WalletUnsignedPost uses the /wallet/unsigned endpoint to build a funded
+// transaction sending to outputs that still needs to be signed.
+func (c *Client) WalletUnsignedPost(outputs []types.SiacoinOutput, fee types.Currency) (wup api.WalletUnsignedPOST, err error) {
+	marshaledOutputs, err := json.Marshal(outputs)
+	if err != nil {
+		return api.WalletUnsignedPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("outputs", string(marshaledOutputs))
+	values.Set("fee", fee.String())
+	err = c.post("/wallet/unsigned", values.Encode(), &wup)
+	return
+}
+
+// WalletSpendingPolicyGet requests the /wallet/spendingpolicy endpoint to get
+// the policy that limits the siacoins sent by the wallet.
+func (c *Client) WalletSpendingPolicyGet() (wspg api.WalletSpendingPolicyGET, err error) {
+	err = c.get("/wallet/spendingpolicy", &wspg)
+	return
+}
+
+// WalletSpendingPolicyPost uses the /wallet/spendingpolicy endpoint to set the
+// policy that limits the siacoins sent by the wallet.
+func (c *Client) WalletSpendingPolicyPost(policy modules.SpendingPolicy) (err error) {
+	addrStrs := make([]string, len(policy.Whitelist))
+	for i, addr := range policy.Whitelist {
+		addrStrs[i] = addr.String()
+	}
+	values := url.Values{}
+	values.Set("maxpertransaction", policy.MaxPerTransaction.String())
+	values.Set("maxperday", policy.MaxPerDay.String())
+	values.Set("whitelist", strings.Join(addrStrs, ","))
+	err = c.post("/wallet/spendingpolicy", values.Encode(), nil)
+	return
+}
+
+// WalletWatchGet requests the /wallet/watch endpoint to get the addresses
+// that the wallet is watching.
+func (c *Client) WalletWatchGet() (wwg api.WalletWatchGET, err error) {
+	err = c.get("/wallet/watch", &wwg)
+	return
+}
+
+// WalletWatchPost uses the /wallet/watch endpoint to add addresses to, or
+// remove them from, the set of addresses that the wallet is watching.
+func (c *Client) WalletWatchPost(addrs []types.UnlockHash, remove, unused bool) (err error) {
+	addrStrs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		addrStrs[i] = addr.String()
+	}
+	values := url.Values{}
+	values.Set("addresses", strings.Join(addrStrs, ","))
+	values.Set("remove", strconv.FormatBool(remove))
+	values.Set("unused", strconv.FormatBool(unused))
+	err = c.post("/wallet/watch", values.Encode(), nil)
+	return
+}
+
 // Wallet033xPost uses the /wallet/033x endpoint to load a v0.3.3.x wallet into
 // the current wallet.
 func (c *Client) Wallet033xPost(path, password string) (err error) {
@@ -6,19 +6,36 @@ import (
 	"net/http"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// hashrateEstimationBlocks is the number of blocks that the hashrate
+// returned by /consensus is estimated from.
+const hashrateEstimationBlocks = 200
+
 // ConsensusGET contains general information about the consensus set, with tags
 // to support idiomatic json encodings.
 type ConsensusGET struct {
-	Synced       bool              `json:"synced"`
-	Height       types.BlockHeight `json:"height"`
-	CurrentBlock types.BlockID     `json:"currentblock"`
-	Target       types.Target      `json:"target"`
-	Difficulty   types.Currency    `json:"difficulty"`
+	Synced            bool              `json:"synced"`
+	Height            types.BlockHeight `json:"height"`
+	CurrentBlock      types.BlockID     `json:"currentblock"`
+	Target            types.Target      `json:"target"`
+	Difficulty        types.Currency    `json:"difficulty"`
+	EstimatedHashrate types.Currency    `json:"estimatedhashrate"`
+}
+
+// ConsensusCompactPOST contains the result of compacting the consensus
+// database.
+type ConsensusCompactPOST struct {
+	modules.ConsensusCompactionStats
+}
+
+// ConsensusSyncGET contains the sync progress of the consensus set.
+type ConsensusSyncGET struct {
+	modules.ConsensusSyncProgress
 }
 
 // ConsensusHeadersGET contains information from a blocks header.
@@ -170,14 +187,31 @@ func consensusBlocksGetFromBlock(b types.Block, h types.BlockHeight) ConsensusBl
 
 // consensusHandler handles the API calls to /consensus.
 func (api *API) consensusHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	cbid := api.cs.CurrentBlock().ID()
-	currentTarget, _ := api.cs.ChildTarget(cbid)
+	height := api.cs.Height()
+	currentTarget := api.cs.CurrentTarget()
+
+	// Estimate the hashrate from the most recent blocks, or from every block
+	// if there are fewer.
+	var hashrate types.Currency
+	if height > 0 {
+		window := types.BlockHeight(hashrateEstimationBlocks)
+		if height < window {
+			window = height
+		}
+		var err error
+		hashrate, err = api.cs.EstimatedHashrate(window)
+		if err != nil {
+			WriteError(w, Error{"unable to estimate hashrate: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
 	WriteJSON(w, ConsensusGET{
-		Synced:       api.cs.Synced(),
-		Height:       api.cs.Height(),
-		CurrentBlock: cbid,
-		Target:       currentTarget,
-		Difficulty:   currentTarget.Difficulty(),
+		Synced:            api.cs.Synced(),
+		Height:            height,
+		CurrentBlock:      api.cs.CurrentBlock().ID(),
+		Target:            currentTarget,
+		Difficulty:        currentTarget.Difficulty(),
+		EstimatedHashrate: hashrate,
 	})
 }
 
@@ -239,3 +273,18 @@ func (api *API) consensusValidateTransactionsetHandler(w http.ResponseWriter, re
 	}
 	WriteSuccess(w)
 }
+
+// consensusCompactHandler handles the API calls to /consensus/compact.
+func (api *API) consensusCompactHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	stats, err := api.cs.Compact()
+	if err != nil {
+		WriteError(w, Error{"failed to compact the consensus database: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, ConsensusCompactPOST{stats})
+}
+
+// consensusSyncHandler handles the API calls to /consensus/sync.
+func (api *API) consensusSyncHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, ConsensusSyncGET{api.cs.SyncProgress()})
+}
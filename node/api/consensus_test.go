@@ -34,6 +34,13 @@ func TestIntegrationConsensusGET(t *testing.T) {
 	if cg.Target != expectedTarget {
 		t.Error("wrong target returned in consensus GET call")
 	}
+	hashrate, err := st.server.api.cs.EstimatedHashrate(cg.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cg.EstimatedHashrate.IsZero() || !cg.EstimatedHashrate.Equals(hashrate) {
+		t.Error("wrong hashrate returned in consensus GET call:", cg.EstimatedHashrate)
+	}
 }
 
 // TestConsensusValidateTransactionSet probes the POST call to
@@ -83,3 +90,60 @@ func TestConsensusValidateTransactionSet(t *testing.T) {
 		t.Fatal("expected validation error")
 	}
 }
+
+// TestConsensusCompactPOST probes the POST call to /consensus/compact.
+func TestConsensusCompactPOST(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var ccp ConsensusCompactPOST
+	err = st.postAPI("/consensus/compact", nil, &ccp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ccp.OldSize == 0 || ccp.NewSize == 0 {
+		t.Error("database sizes were not reported:", ccp.OldSize, ccp.NewSize)
+	}
+
+	// The consensus set should still be usable.
+	var cg ConsensusGET
+	err = st.getAPI("/consensus", &cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cg.CurrentBlock != st.server.api.cs.CurrentBlock().ID() {
+		t.Error("wrong block returned in consensus GET call after compaction")
+	}
+}
+
+// TestConsensusSyncGET probes the GET call to /consensus/sync.
+func TestConsensusSyncGET(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var csg ConsensusSyncGET
+	err = st.getAPI("/consensus/sync", &csg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !csg.Synced || csg.Height != st.server.api.cs.Height() {
+		t.Errorf("wrong sync status returned in consensus sync GET call: %+v", csg)
+	}
+	if csg.EstimatedHeight != csg.Height || csg.Progress != 1 {
+		t.Errorf("wrong progress returned in consensus sync GET call: %+v", csg)
+	}
+}
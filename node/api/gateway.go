@@ -1,7 +1,10 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/NebulousLabs/Sia/modules"
 
@@ -10,8 +13,49 @@ import (
 
 // GatewayGET contains the fields returned by a GET call to "/gateway".
 type GatewayGET struct {
-	NetAddress modules.NetAddress `json:"netaddress"`
-	Peers      []modules.Peer     `json:"peers"`
+	NetAddress      modules.NetAddress   `json:"netaddress"`
+	ListenAddresses []modules.NetAddress `json:"listenaddresses"`
+	Peers           []modules.Peer       `json:"peers"`
+
+	MaxDownloadSpeed     int64 `json:"maxdownloadspeed"`
+	MaxUploadSpeed       int64 `json:"maxuploadspeed"`
+	PeerMaxDownloadSpeed int64 `json:"peermaxdownloadspeed"`
+	PeerMaxUploadSpeed   int64 `json:"peermaxuploadspeed"`
+
+	MaxInboundPeers  int `json:"maxinboundpeers"`
+	MaxOutboundPeers int `json:"maxoutboundpeers"`
+
+	PreferredAddressFamily modules.AddressFamily `json:"preferredaddressfamily"`
+	AddressFamilyOnly      bool                  `json:"addressfamilyonly"`
+
+	DNSSeeds        []string `json:"dnsseeds"`
+	DisableDNSSeeds bool     `json:"disablednsseeds"`
+}
+
+// GatewayBootstrapGET contains the fields returned by a GET call to
+// "/gateway/bootstrap".
+type GatewayBootstrapGET struct {
+	Nodes []modules.BootstrapNode `json:"nodes"`
+}
+
+// GatewayPeerListsGET contains the fields returned by a GET call to
+// "/gateway/peerlists".
+type GatewayPeerListsGET struct {
+	Blacklist     []modules.NetAddress `json:"blacklist"`
+	Whitelist     []modules.NetAddress `json:"whitelist"`
+	WhitelistOnly bool                 `json:"whitelistonly"`
+}
+
+// GatewayBansGET contains the fields returned by a GET call to
+// "/gateway/bans".
+type GatewayBansGET struct {
+	Bans []modules.PeerBan `json:"bans"`
+}
+
+// GatewayStatsGET contains the fields returned by a GET call to
+// "/gateway/stats".
+type GatewayStatsGET struct {
+	Peers []modules.PeerStats `json:"peers"`
 }
 
 // gatewayHandler handles the API call asking for the gatway status.
@@ -23,7 +67,116 @@ func (api *API) gatewayHandler(w http.ResponseWriter, req *http.Request, _ httpr
 	if peers == nil {
 		peers = make([]modules.Peer, 0)
 	}
-	WriteJSON(w, GatewayGET{api.gateway.Address(), peers})
+	settings := api.gateway.Settings()
+	WriteJSON(w, GatewayGET{
+		NetAddress:      api.gateway.Address(),
+		ListenAddresses: api.gateway.ListenAddresses(),
+		Peers:           peers,
+
+		MaxDownloadSpeed:     settings.MaxDownloadSpeed,
+		MaxUploadSpeed:       settings.MaxUploadSpeed,
+		PeerMaxDownloadSpeed: settings.PeerMaxDownloadSpeed,
+		PeerMaxUploadSpeed:   settings.PeerMaxUploadSpeed,
+
+		MaxInboundPeers:  settings.MaxInboundPeers,
+		MaxOutboundPeers: settings.MaxOutboundPeers,
+
+		PreferredAddressFamily: settings.PreferredAddressFamily,
+		AddressFamilyOnly:      settings.AddressFamilyOnly,
+
+		DNSSeeds:        settings.DNSSeeds,
+		DisableDNSSeeds: settings.DisableDNSSeeds,
+	})
+}
+
+// gatewayHandlerPOST handles the API call changing the gateway's settings.
+func (api *API) gatewayHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.gateway.Settings()
+	speeds := []struct {
+		name  string
+		speed *int64
+	}{
+		{"maxdownloadspeed", &settings.MaxDownloadSpeed},
+		{"maxuploadspeed", &settings.MaxUploadSpeed},
+		{"peermaxdownloadspeed", &settings.PeerMaxDownloadSpeed},
+		{"peermaxuploadspeed", &settings.PeerMaxUploadSpeed},
+	}
+	// Scan the speed limits. (optional parameters)
+	for _, s := range speeds {
+		if v := req.FormValue(s.name); v != "" {
+			if _, err := fmt.Sscan(v, s.speed); err != nil {
+				WriteError(w, Error{"unable to parse " + s.name + ": " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	// Scan the peer limits. (optional parameters)
+	limits := []struct {
+		name  string
+		limit *int
+	}{
+		{"maxinboundpeers", &settings.MaxInboundPeers},
+		{"maxoutboundpeers", &settings.MaxOutboundPeers},
+	}
+	for _, l := range limits {
+		if v := req.FormValue(l.name); v != "" {
+			if _, err := fmt.Sscan(v, l.limit); err != nil {
+				WriteError(w, Error{"unable to parse " + l.name + ": " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	// Scan the address family preference. (optional parameters)
+	if v, ok := req.Form["preferredaddressfamily"]; ok && len(v) > 0 {
+		settings.PreferredAddressFamily = modules.AddressFamily(v[0])
+	}
+	if v := req.FormValue("addressfamilyonly"); v != "" {
+		only, err := strconv.ParseBool(v)
+		if err != nil {
+			WriteError(w, Error{"unable to parse addressfamilyonly: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.AddressFamilyOnly = only
+	}
+	// Scan the DNS seeds. (optional parameters)
+	if v, ok := req.Form["dnsseeds"]; ok && len(v) > 0 {
+		settings.DNSSeeds = nil
+		for _, seed := range strings.Split(v[0], ",") {
+			if seed = strings.TrimSpace(seed); seed != "" {
+				settings.DNSSeeds = append(settings.DNSSeeds, seed)
+			}
+		}
+	}
+	if v := req.FormValue("disablednsseeds"); v != "" {
+		disable, err := strconv.ParseBool(v)
+		if err != nil {
+			WriteError(w, Error{"unable to parse disablednsseeds: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.DisableDNSSeeds = disable
+	}
+	err := api.gateway.SetSettings(settings)
+	if err != nil {
+		WriteError(w, Error{"unable to set gateway settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// gatewayListenHandlerPOST handles the API call to change the addresses that
+// the gateway listens on.
+func (api *API) gatewayListenHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var addrs []string
+	for _, addr := range strings.Split(req.FormValue("addresses"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if err := api.gateway.SetListenAddresses(addrs); err != nil {
+		WriteError(w, Error{"unable to change listen addresses: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
 }
 
 // gatewayConnectHandler handles the API call to add a peer to the gateway.
@@ -49,3 +202,172 @@ func (api *API) gatewayDisconnectHandler(w http.ResponseWriter, req *http.Reques
 
 	WriteSuccess(w)
 }
+
+// gatewayBootstrapHandler handles the API call asking for the bootstrap nodes
+// of the gateway.
+func (api *API) gatewayBootstrapHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	nodes := api.gateway.BootstrapNodes()
+	if nodes == nil {
+		nodes = make([]modules.BootstrapNode, 0)
+	}
+	WriteJSON(w, GatewayBootstrapGET{nodes})
+}
+
+// gatewayBootstrapAddHandler handles the API call to add a bootstrap node to
+// the gateway.
+func (api *API) gatewayBootstrapAddHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.AddBootstrapNode(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayBootstrapRemoveHandler handles the API call to remove a bootstrap
+// node from the gateway.
+func (api *API) gatewayBootstrapRemoveHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.RemoveBootstrapNode(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayBootstrapPinHandler handles the API call to pin or unpin a bootstrap
+// node.
+func (api *API) gatewayBootstrapPinHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	pinned := true
+	if p := req.FormValue("pinned"); p != "" {
+		var err error
+		pinned, err = strconv.ParseBool(p)
+		if err != nil {
+			WriteError(w, Error{"unable to parse pinned: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	err := api.gateway.PinBootstrapNode(addr, pinned)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayBansHandler handles the API call asking for the hosts that are
+// banned by the gateway.
+func (api *API) gatewayBansHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	bans := api.gateway.Bans()
+	if bans == nil {
+		bans = make([]modules.PeerBan, 0)
+	}
+	WriteJSON(w, GatewayBansGET{bans})
+}
+
+// gatewayStatsHandler handles the API call asking for the traffic statistics
+// of the gateway's peers.
+func (api *API) gatewayStatsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	stats := api.gateway.PeerStats()
+	if stats == nil {
+		stats = make([]modules.PeerStats, 0)
+	}
+	WriteJSON(w, GatewayStatsGET{stats})
+}
+
+// gatewayBansClearHandler handles the API call to lift all bans.
+func (api *API) gatewayBansClearHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.gateway.ClearBans()
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayPeerListsHandler handles the API call asking for the blacklist and
+// whitelist of the gateway.
+func (api *API) gatewayPeerListsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	lists := api.gateway.PeerLists()
+	WriteJSON(w, GatewayPeerListsGET{
+		Blacklist:     lists.Blacklist,
+		Whitelist:     lists.Whitelist,
+		WhitelistOnly: lists.WhitelistOnly,
+	})
+}
+
+// gatewayPeerListsHandlerPOST handles the API call to set whether the gateway
+// only connects to whitelisted peers.
+func (api *API) gatewayPeerListsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	whitelistOnly, err := strconv.ParseBool(req.FormValue("whitelistonly"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse whitelistonly: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.gateway.SetWhitelistOnly(whitelistOnly)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayBlacklistAddHandler handles the API call to add an address to the
+// blacklist.
+func (api *API) gatewayBlacklistAddHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.Blacklist(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayBlacklistRemoveHandler handles the API call to remove an address
+// from the blacklist.
+func (api *API) gatewayBlacklistRemoveHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.RemoveFromBlacklist(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayWhitelistAddHandler handles the API call to add an address to the
+// whitelist.
+func (api *API) gatewayWhitelistAddHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.Whitelist(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// gatewayWhitelistRemoveHandler handles the API call to remove an address
+// from the whitelist.
+func (api *API) gatewayWhitelistRemoveHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.RemoveFromWhitelist(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/gateway"
 )
 
@@ -111,3 +113,397 @@ func TestGatewayPeerDisconnect(t *testing.T) {
 		t.Fatal("/gateway/disconnect did not disconnect from peer", peer.Address())
 	}
 }
+
+// TestGatewayBootstrap checks that bootstrap nodes can be managed through the
+// /gateway/bootstrap calls.
+func TestGatewayBootstrap(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	addr := "111.111.111.111:1111"
+	err = st.stdPostAPI("/gateway/bootstrap/add/"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("pinned", "true")
+	err = st.stdPostAPI("/gateway/bootstrap/pin/"+addr, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gbg GatewayBootstrapGET
+	err = st.getAPI("/gateway/bootstrap", &gbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Nodes) != 1 || string(gbg.Nodes[0].NetAddress) != addr || !gbg.Nodes[0].Pinned {
+		t.Fatal("/gateway/bootstrap returned the wrong nodes:", gbg.Nodes)
+	}
+
+	err = st.stdPostAPI("/gateway/bootstrap/remove/"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/gateway/bootstrap", &gbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Nodes) != 0 {
+		t.Fatal("/gateway/bootstrap/remove did not remove the node:", gbg.Nodes)
+	}
+	if err := st.stdPostAPI("/gateway/bootstrap/remove/"+addr, nil); err == nil {
+		t.Fatal("expected an error when removing an unknown bootstrap node")
+	}
+}
+
+// TestGatewayRateLimits checks that the bandwidth limits of the gateway can be
+// changed with a POST call to /gateway.
+func TestGatewayRateLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("maxdownloadspeed", "1000")
+	values.Set("peermaxuploadspeed", "200")
+	err = st.stdPostAPI("/gateway", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info GatewayGET
+	err = st.getAPI("/gateway", &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MaxDownloadSpeed != 1000 || info.PeerMaxUploadSpeed != 200 || info.MaxUploadSpeed != 0 || info.PeerMaxDownloadSpeed != 0 {
+		t.Fatal("/gateway returned the wrong limits:", info)
+	}
+
+	values = url.Values{}
+	values.Set("maxuploadspeed", "-1")
+	if err := st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected a negative limit to be rejected")
+	}
+}
+
+// TestGatewayPeerLimits checks that the number of inbound and outbound slots
+// of the gateway can be changed with a POST call to /gateway.
+func TestGatewayPeerLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("maxinboundpeers", "20")
+	values.Set("maxoutboundpeers", "3")
+	err = st.stdPostAPI("/gateway", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info GatewayGET
+	err = st.getAPI("/gateway", &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MaxInboundPeers != 20 || info.MaxOutboundPeers != 3 {
+		t.Fatal("/gateway returned the wrong peer limits:", info)
+	}
+
+	values = url.Values{}
+	values.Set("maxinboundpeers", "-1")
+	if err := st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected a negative peer limit to be rejected")
+	}
+}
+
+// TestGatewayAddressFamily checks that the address family preference of the
+// gateway can be changed through the API.
+func TestGatewayAddressFamily(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("preferredaddressfamily", "ipv6")
+	values.Set("addressfamilyonly", "true")
+	err = st.stdPostAPI("/gateway", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info GatewayGET
+	err = st.getAPI("/gateway", &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PreferredAddressFamily != modules.AddressFamilyIPv6 || !info.AddressFamilyOnly {
+		t.Fatal("/gateway returned the wrong address family settings:", info)
+	}
+
+	values = url.Values{}
+	values.Set("preferredaddressfamily", "")
+	if err := st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected an address family restriction without a preferred family to be rejected")
+	}
+	values = url.Values{}
+	values.Set("preferredaddressfamily", "ipx")
+	values.Set("addressfamilyonly", "false")
+	if err := st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected an invalid address family to be rejected")
+	}
+}
+
+// TestGatewayStats checks that /gateway/stats lists the traffic statistics of
+// the gateway's peers.
+func TestGatewayStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var stats GatewayStatsGET
+	if err := st.getAPI("/gateway/stats", &stats); err != nil {
+		t.Fatal(err)
+	} else if stats.Peers == nil || len(stats.Peers) != 0 {
+		t.Fatal("expected an empty list of peer stats, got", stats.Peers)
+	}
+
+	peer, err := gateway.New("localhost:0", false, build.TempDir("api", t.Name()+"2", "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := peer.Close()
+		if err != nil {
+			panic(err)
+		}
+	}()
+	err = st.stdPostAPI("/gateway/connect/"+string(peer.Address()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/gateway/stats", &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Peers) != 1 || stats.Peers[0].NetAddress != peer.Address() {
+		t.Fatal("/gateway/stats did not list the peer:", stats.Peers)
+	} else if stats.Peers[0].Inbound || stats.Peers[0].BytesSent == 0 || stats.Peers[0].BytesReceived == 0 || stats.Peers[0].ConnectedAt.IsZero() {
+		t.Fatal("/gateway/stats returned the wrong stats:", stats.Peers[0])
+	}
+}
+
+// TestGatewayDNSSeeds checks that the DNS seed settings can be changed through
+// the /gateway endpoint.
+func TestGatewayDNSSeeds(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("dnsseeds", "seed.example.com, seed.example.org:9981")
+	values.Set("disablednsseeds", "true")
+	if err := st.stdPostAPI("/gateway", values); err != nil {
+		t.Fatal(err)
+	}
+	var info GatewayGET
+	if err := st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	}
+	if len(info.DNSSeeds) != 2 || info.DNSSeeds[0] != "seed.example.com" || info.DNSSeeds[1] != "seed.example.org:9981" || !info.DisableDNSSeeds {
+		t.Fatal("/gateway returned the wrong DNS seed settings:", info.DNSSeeds, info.DisableDNSSeeds)
+	}
+
+	// An empty list removes the seeds, and invalid seeds are rejected.
+	values = url.Values{}
+	values.Set("dnsseeds", "")
+	if err := st.stdPostAPI("/gateway", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	} else if len(info.DNSSeeds) != 0 {
+		t.Fatal("DNS seeds were not removed:", info.DNSSeeds)
+	}
+	values.Set("dnsseeds", "seed.example.com:0")
+	if err := st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected an invalid DNS seed to be rejected")
+	}
+}
+
+// TestGatewayListen checks that /gateway/listen changes the addresses that the
+// gateway listens on.
+func TestGatewayListen(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var info GatewayGET
+	if err := st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	} else if len(info.ListenAddresses) != 1 {
+		t.Fatal("expected one listen address, got", info.ListenAddresses)
+	}
+	oldAddr := info.ListenAddresses[0]
+
+	values := url.Values{}
+	values.Set("addresses", "127.0.0.1:0,127.0.0.1:0")
+	if err := st.stdPostAPI("/gateway/listen", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	} else if len(info.ListenAddresses) != 2 || info.ListenAddresses[0] == oldAddr {
+		t.Fatal("/gateway/listen did not change the listen addresses:", info.ListenAddresses)
+	}
+
+	values.Set("addresses", "")
+	if err := st.stdPostAPI("/gateway/listen", values); err == nil {
+		t.Fatal("expected an empty list of addresses to be rejected")
+	}
+}
+
+// TestGatewayBans checks that the hosts banned by the gateway are listed by
+// /gateway/bans and can be cleared.
+func TestGatewayBans(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var gbg GatewayBansGET
+	err = st.getAPI("/gateway/bans", &gbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gbg.Bans == nil || len(gbg.Bans) != 0 {
+		t.Fatal("expected an empty list of bans:", gbg.Bans)
+	}
+
+	st.gateway.PenalizePeer("111.111.111.111:1111", modules.PenaltyInvalidBlock)
+	err = st.getAPI("/gateway/bans", &gbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Bans) != 1 || gbg.Bans[0].Host != "111.111.111.111" {
+		t.Fatal("/gateway/bans returned the wrong bans:", gbg.Bans)
+	}
+
+	err = st.stdPostAPI("/gateway/bans/clear", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/gateway/bans", &gbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Bans) != 0 {
+		t.Fatal("/gateway/bans/clear did not clear the bans:", gbg.Bans)
+	}
+}
+
+// TestGatewayPeerLists checks that addresses can be blacklisted and
+// whitelisted through the API, and that the whitelist-only mode can be set.
+func TestGatewayPeerLists(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	blacklisted := "111.111.111.111:1111"
+	whitelisted := "222.222.222.222:2222"
+	if err := st.stdPostAPI("/gateway/blacklist/add/"+blacklisted, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/gateway/whitelist/add/"+whitelisted, nil); err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("whitelistonly", "true")
+	if err := st.stdPostAPI("/gateway/peerlists", values); err != nil {
+		t.Fatal(err)
+	}
+	var gplg GatewayPeerListsGET
+	if err := st.getAPI("/gateway/peerlists", &gplg); err != nil {
+		t.Fatal(err)
+	}
+	if len(gplg.Blacklist) != 1 || string(gplg.Blacklist[0]) != blacklisted {
+		t.Fatal("wrong blacklist:", gplg.Blacklist)
+	}
+	if len(gplg.Whitelist) != 1 || string(gplg.Whitelist[0]) != whitelisted {
+		t.Fatal("wrong whitelist:", gplg.Whitelist)
+	}
+	if !gplg.WhitelistOnly {
+		t.Fatal("whitelist-only mode was not enabled")
+	}
+
+	if err := st.stdPostAPI("/gateway/blacklist/remove/"+blacklisted, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/gateway/whitelist/remove/"+whitelisted, nil); err != nil {
+		t.Fatal(err)
+	}
+	values.Set("whitelistonly", "false")
+	if err := st.stdPostAPI("/gateway/peerlists", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/gateway/peerlists", &gplg); err != nil {
+		t.Fatal(err)
+	}
+	if len(gplg.Blacklist) != 0 || len(gplg.Whitelist) != 0 || gplg.WhitelistOnly {
+		t.Fatal("peer lists were not cleared:", gplg)
+	}
+	if err := st.stdPostAPI("/gateway/whitelist/remove/"+whitelisted, nil); err == nil {
+		t.Fatal("expected an error when removing an address that is not whitelisted")
+	}
+}
@@ -22,6 +22,8 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 	if api.cs != nil {
 		router.GET("/consensus", api.consensusHandler)
 		router.GET("/consensus/blocks", api.consensusBlocksHandler)
+		router.POST("/consensus/compact", RequirePassword(api.consensusCompactHandler, requiredPassword))
+		router.GET("/consensus/sync", api.consensusSyncHandler)
 		router.POST("/consensus/validate/transactionset", api.consensusValidateTransactionsetHandler)
 	}
 
@@ -35,8 +37,23 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 	// Gateway API Calls
 	if api.gateway != nil {
 		router.GET("/gateway", api.gatewayHandler)
+		router.POST("/gateway", RequirePassword(api.gatewayHandlerPOST, requiredPassword))
+		router.POST("/gateway/listen", RequirePassword(api.gatewayListenHandlerPOST, requiredPassword))
 		router.POST("/gateway/connect/:netaddress", RequirePassword(api.gatewayConnectHandler, requiredPassword))
 		router.POST("/gateway/disconnect/:netaddress", RequirePassword(api.gatewayDisconnectHandler, requiredPassword))
+		router.GET("/gateway/bootstrap", api.gatewayBootstrapHandler)
+		router.POST("/gateway/bootstrap/add/:netaddress", RequirePassword(api.gatewayBootstrapAddHandler, requiredPassword))
+		router.POST("/gateway/bootstrap/remove/:netaddress", RequirePassword(api.gatewayBootstrapRemoveHandler, requiredPassword))
+		router.POST("/gateway/bootstrap/pin/:netaddress", RequirePassword(api.gatewayBootstrapPinHandler, requiredPassword))
+		router.GET("/gateway/bans", api.gatewayBansHandler)
+		router.GET("/gateway/stats", api.gatewayStatsHandler)
+		router.POST("/gateway/bans/clear", RequirePassword(api.gatewayBansClearHandler, requiredPassword))
+		router.GET("/gateway/peerlists", api.gatewayPeerListsHandler)
+		router.POST("/gateway/peerlists", RequirePassword(api.gatewayPeerListsHandlerPOST, requiredPassword))
+		router.POST("/gateway/blacklist/add/:netaddress", RequirePassword(api.gatewayBlacklistAddHandler, requiredPassword))
+		router.POST("/gateway/blacklist/remove/:netaddress", RequirePassword(api.gatewayBlacklistRemoveHandler, requiredPassword))
+		router.POST("/gateway/whitelist/add/:netaddress", RequirePassword(api.gatewayWhitelistAddHandler, requiredPassword))
+		router.POST("/gateway/whitelist/remove/:netaddress", RequirePassword(api.gatewayWhitelistRemoveHandler, requiredPassword))
 	}
 
 	// Host API Calls
@@ -99,9 +116,20 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 	// Transaction pool API Calls
 	if api.tpool != nil {
 		router.GET("/tpool/fee", api.tpoolFeeHandlerGET)
+		router.GET("/tpool/local", api.tpoolLocalHandlerGET)
+		router.GET("/tpool/outputs/:unlockhash", api.tpoolOutputsHandlerGET)
+		router.GET("/tpool/raw", api.tpoolRawSetsHandlerGET)
 		router.GET("/tpool/raw/:id", api.tpoolRawHandlerGET)
+		router.GET("/tpool/rejections", api.tpoolRejectionsHandlerGET)
 		router.POST("/tpool/raw", api.tpoolRawHandlerPOST)
 		router.GET("/tpool/confirmed/:id", api.tpoolConfirmedGET)
+		router.GET("/tpool/settings", api.tpoolSettingsHandlerGET)
+		router.GET("/tpool/spendable/:unlockhash", api.tpoolSpendableHandlerGET)
+		router.POST("/tpool/settings", RequirePassword(api.tpoolSettingsHandlerPOST, requiredPassword))
+		router.GET("/tpool/stats", api.tpoolStatsHandlerGET)
+		router.GET("/tpool/transactions/:id", api.tpoolTransactionHandlerGET)
+		router.GET("/tpool/transactionsets/:id", api.tpoolTransactionSetHandlerGET)
+		router.POST("/tpool/validate", api.tpoolValidateHandlerPOST)
 
 		// TODO: re-enable this route once the transaction pool API has been finalized
 		//router.GET("/transactionpool/transactions", api.transactionpoolTransactionsHandler)
@@ -114,20 +142,40 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 		router.GET("/wallet/address", RequirePassword(api.walletAddressHandler, requiredPassword))
 		router.GET("/wallet/addresses", api.walletAddressesHandler)
 		router.GET("/wallet/backup", RequirePassword(api.walletBackupHandler, requiredPassword))
+		router.GET("/wallet/export", api.walletExportHandler)
 		router.POST("/wallet/init", RequirePassword(api.walletInitHandler, requiredPassword))
 		router.POST("/wallet/init/seed", RequirePassword(api.walletInitSeedHandler, requiredPassword))
 		router.POST("/wallet/lock", RequirePassword(api.walletLockHandler, requiredPassword))
+		router.POST("/wallet/multisig", RequirePassword(api.walletMultisigHandler, requiredPassword))
+		router.GET("/wallet/labels", api.walletLabelsHandlerGET)
+		router.POST("/wallet/labels", RequirePassword(api.walletLabelsHandlerPOST, requiredPassword))
+		router.GET("/wallet/outputs", api.walletOutputsHandlerGET)
+		router.POST("/wallet/outputs", RequirePassword(api.walletOutputsHandlerPOST, requiredPassword))
+		router.POST("/wallet/rescan", RequirePassword(api.walletRescanHandler, requiredPassword))
 		router.POST("/wallet/seed", RequirePassword(api.walletSeedHandler, requiredPassword))
 		router.GET("/wallet/seeds", RequirePassword(api.walletSeedsHandler, requiredPassword))
+		router.GET("/wallet/settings", api.walletSettingsHandlerGET)
+		router.POST("/wallet/settings", RequirePassword(api.walletSettingsHandlerPOST, requiredPassword))
+		router.GET("/wallet/spendingpolicy", api.walletSpendingPolicyHandlerGET)
+		router.POST("/wallet/spendingpolicy", RequirePassword(api.walletSpendingPolicyHandlerPOST, requiredPassword))
+		router.POST("/wallet/sign", RequirePassword(api.walletSignHandler, requiredPassword))
+		router.POST("/wallet/signmessage", RequirePassword(api.walletSignMessageHandler, requiredPassword))
 		router.POST("/wallet/siacoins", RequirePassword(api.walletSiacoinsHandler, requiredPassword))
 		router.POST("/wallet/siafunds", RequirePassword(api.walletSiafundsHandler, requiredPassword))
 		router.POST("/wallet/siagkey", RequirePassword(api.walletSiagkeyHandler, requiredPassword))
+		router.POST("/wallet/sweep/key", RequirePassword(api.walletSweepKeyHandler, requiredPassword))
 		router.POST("/wallet/sweep/seed", RequirePassword(api.walletSweepSeedHandler, requiredPassword))
 		router.GET("/wallet/transaction/:id", api.walletTransactionHandler)
 		router.GET("/wallet/transactions", api.walletTransactionsHandler)
 		router.GET("/wallet/transactions/:addr", api.walletTransactionsAddrHandler)
+		router.GET("/wallet/unlockconditions/:addr", api.walletUnlockConditionsHandlerGET)
+		router.POST("/wallet/unlockconditions", RequirePassword(api.walletUnlockConditionsHandlerPOST, requiredPassword))
+		router.POST("/wallet/unsigned", RequirePassword(api.walletUnsignedHandler, requiredPassword))
 		router.GET("/wallet/verify/address/:addr", api.walletVerifyAddressHandler)
+		router.GET("/wallet/verify/message", api.walletVerifyMessageHandler)
 		router.POST("/wallet/unlock", RequirePassword(api.walletUnlockHandler, requiredPassword))
+		router.GET("/wallet/watch", api.walletWatchHandlerGET)
+		router.POST("/wallet/watch", RequirePassword(api.walletWatchHandlerPOST, requiredPassword))
 		router.POST("/wallet/changepassword", RequirePassword(api.walletChangePasswordHandler, requiredPassword))
 	}
 
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"math"
 	"math/big"
+	"strconv"
 
 	"errors"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -47,3 +49,25 @@ func scanBool(param string) (bool, error) {
 	}
 	return false, errors.New("could not decode boolean: value was not true or false")
 }
+
+// scanHeightRange parses the startheight and endheight parameters of a
+// request. An endheight of -1 selects all heights from startheight onwards.
+func scanHeightRange(startheightStr, endheightStr string) (types.BlockHeight, types.BlockHeight, error) {
+	// Get the start and end blocks.
+	start, err := strconv.ParseUint(startheightStr, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("parsing integer value for parameter `startheight` failed: " + err.Error())
+	}
+	// Check if endheightStr is set to -1. If it is, we use MaxUint64 as the
+	// end. Otherwise we parse the argument as an unsigned integer.
+	var end uint64
+	if endheightStr == "-1" {
+		end = math.MaxUint64
+	} else {
+		end, err = strconv.ParseUint(endheightStr, 10, 64)
+	}
+	if err != nil {
+		return 0, 0, errors.New("parsing integer value for parameter `endheight` failed: " + err.Error())
+	}
+	return types.BlockHeight(start), types.BlockHeight(end), nil
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -17,6 +19,11 @@ type (
 	TpoolFeeGET struct {
 		Minimum types.Currency `json:"minimum"`
 		Maximum types.Currency `json:"maximum"`
+
+		// Estimate is the fee per byte needed to be confirmed within Target
+		// blocks.
+		Target   types.BlockHeight `json:"target"`
+		Estimate types.Currency    `json:"estimate"`
 	}
 
 	// TpoolRawGET contains the requested transaction encoded to the raw
@@ -27,6 +34,74 @@ type (
 		Transaction []byte              `json:"transaction"`
 	}
 
+	// TpoolRawSetsGET contains the raw encoded transaction sets in the
+	// transaction pool.
+	TpoolRawSetsGET struct {
+		TransactionSets [][]byte `json:"transactionsets"`
+	}
+
+	// TpoolSettingsGET contains the settings of the transaction pool.
+	TpoolSettingsGET struct {
+		MaxPoolSize       uint64                        `json:"maxpoolsize"`
+		MaxTransactionAge types.BlockHeight             `json:"maxtransactionage"`
+		Policy            modules.TransactionPoolPolicy `json:"policy"`
+	}
+
+	// TpoolStatsGET contains statistics about the contents of the
+	// transaction pool.
+	TpoolStatsGET struct {
+		modules.TransactionPoolStats
+	}
+
+	// TpoolTransactionGET contains the requested transaction, its
+	// unconfirmed parents, and the peer that relayed it.
+	TpoolTransactionGET struct {
+		Transaction types.Transaction   `json:"transaction"`
+		Parents     []types.Transaction `json:"parents"`
+		Source      modules.NetAddress  `json:"source"`
+	}
+
+	// TpoolTransactionSetGET contains the requested transaction set.
+	TpoolTransactionSetGET struct {
+		ID           crypto.Hash         `json:"id"`
+		Transactions []types.Transaction `json:"transactions"`
+	}
+
+	// TpoolOutputsGET contains the outputs of an unlock hash that are
+	// created or spent by unconfirmed transactions, along with their totals.
+	TpoolOutputsGET struct {
+		modules.UnconfirmedOutputs
+		IncomingSiacoins types.Currency `json:"incomingsiacoins"`
+		OutgoingSiacoins types.Currency `json:"outgoingsiacoins"`
+		IncomingSiafunds types.Currency `json:"incomingsiafunds"`
+		OutgoingSiafunds types.Currency `json:"outgoingsiafunds"`
+	}
+
+	// TpoolSpendableGET contains the outputs of an unlock hash that are
+	// created by unconfirmed transactions and not yet spent, along with
+	// their dependency sets.
+	TpoolSpendableGET struct {
+		Outputs []modules.SpendableOutput `json:"outputs"`
+	}
+
+	// TpoolLocalGET contains the unconfirmed transactions that were
+	// submitted to this node.
+	TpoolLocalGET struct {
+		Transactions []modules.LocalTransaction `json:"transactions"`
+	}
+
+	// TpoolRejectionsGET contains the transaction sets that were recently
+	// rejected by the transaction pool.
+	TpoolRejectionsGET struct {
+		Rejections []modules.RejectedTransactionSet `json:"rejections"`
+	}
+
+	// TpoolValidatePOST contains the fees of a transaction set that was
+	// validated by the transaction pool.
+	TpoolValidatePOST struct {
+		modules.TransactionSetValidation
+	}
+
 	// TpoolConfirmedGET contains information about whether or not
 	// the transaction has been seen on the blockhain
 	TpoolConfirmedGET struct {
@@ -34,6 +109,32 @@ type (
 	}
 )
 
+// decodeRawTransactionSet decodes the raw transaction and parents of a request
+// into a transaction set. The transactions are accepted both as base64 and as
+// clean values.
+func decodeRawTransactionSet(req *http.Request) ([]types.Transaction, error) {
+	rawParents, err := base64.StdEncoding.DecodeString(req.FormValue("parents"))
+	if err != nil {
+		rawParents = []byte(req.FormValue("parents"))
+	}
+	rawTransaction, err := base64.StdEncoding.DecodeString(req.FormValue("transaction"))
+	if err != nil {
+		rawTransaction = []byte(req.FormValue("transaction"))
+	}
+
+	var parents []types.Transaction
+	var txn types.Transaction
+	err = encoding.Unmarshal(rawParents, &parents)
+	if err != nil {
+		return nil, errors.New("error decoding parents:" + err.Error())
+	}
+	err = encoding.Unmarshal(rawTransaction, &txn)
+	if err != nil {
+		return nil, errors.New("error decoding transaction:" + err.Error())
+	}
+	return append(parents, txn), nil
+}
+
 // decodeTransactionID will decode a transaction id from a string.
 func decodeTransactionID(txidStr string) (types.TransactionID, error) {
 	txid := new(crypto.Hash)
@@ -47,10 +148,20 @@ func decodeTransactionID(txidStr string) (types.TransactionID, error) {
 // tpoolFeeHandlerGET returns the current estimated fee. Transactions with
 // fees are lower than the estimated fee may take longer to confirm.
 func (api *API) tpoolFeeHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	target := modules.DefaultFeeTarget
+	if t := req.FormValue("target"); t != "" {
+		_, err := fmt.Sscan(t, &target)
+		if err != nil || target == 0 {
+			WriteError(w, Error{"target must be a positive number of blocks"}, http.StatusBadRequest)
+			return
+		}
+	}
 	min, max := api.tpool.FeeEstimation()
 	WriteJSON(w, TpoolFeeGET{
-		Minimum: min,
-		Maximum: max,
+		Minimum:  min,
+		Maximum:  max,
+		Target:   target,
+		Estimate: api.tpool.FeeEstimate(target),
 	})
 }
 
@@ -75,47 +186,116 @@ func (api *API) tpoolRawHandlerGET(w http.ResponseWriter, req *http.Request, ps
 	})
 }
 
+// tpoolRawSetsHandlerGET returns the raw byte representation of every
+// transaction set in the transaction pool.
+func (api *API) tpoolRawSetsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, TpoolRawSetsGET{
+		TransactionSets: api.tpool.RawTransactions(),
+	})
+}
+
 // tpoolRawHandlerPOST takes a raw encoded transaction set and posts
 // it to the transaction pool, relaying it to the transaction pool's peers
 // regardless of if the set is accepted.
 func (api *API) tpoolRawHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	// Try accepting the transactions both as base64 and as clean values.
-	rawParents, err := base64.StdEncoding.DecodeString(req.FormValue("parents"))
-	if err != nil {
-		rawParents = []byte(req.FormValue("parents"))
-	}
-	rawTransaction, err := base64.StdEncoding.DecodeString(req.FormValue("transaction"))
+	txnSet, err := decodeRawTransactionSet(req)
 	if err != nil {
-		rawTransaction = []byte(req.FormValue("transaction"))
-	}
-
-	// Decode the transaction and parents into a transaction set that can be
-	// given to the transaction pool.
-	var parents []types.Transaction
-	var txn types.Transaction
-	err = encoding.Unmarshal(rawParents, &parents)
-	if err != nil {
-		WriteError(w, Error{"error decoding parents:" + err.Error()}, http.StatusBadRequest)
-		return
-	}
-	err = encoding.Unmarshal(rawTransaction, &txn)
-	if err != nil {
-		WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
 	}
-	txnSet := append(parents, txn)
 
 	// Re-broadcast the transactions, so that they are passed to any peers that
 	// may have rejected them earlier.
 	api.tpool.Broadcast(txnSet)
 	err = api.tpool.AcceptTransactionSet(txnSet)
-	if err != nil && err != modules.ErrDuplicateTransactionSet {
+	if tr, ok := err.(modules.TransactionRejection); ok {
+		WriteError(w, Error{fmt.Sprintf("transaction set was rejected (%v): %v", tr.Reason, tr)}, http.StatusBadRequest)
+		return
+	} else if err != nil && err != modules.ErrDuplicateTransactionSet {
 		WriteError(w, Error{"error accepting transaction set:" + err.Error()}, http.StatusBadRequest)
 		return
 	}
 	WriteSuccess(w)
 }
 
+// tpoolSettingsHandlerGET returns the settings of the transaction pool.
+func (api *API) tpoolSettingsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.tpool.Settings()
+	WriteJSON(w, TpoolSettingsGET{
+		MaxPoolSize:       settings.MaxPoolSize,
+		MaxTransactionAge: settings.MaxTransactionAge,
+		Policy:            settings.Policy,
+	})
+}
+
+// tpoolSettingsHandlerPOST changes the settings of the transaction pool.
+func (api *API) tpoolSettingsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.tpool.Settings()
+	// Scan the maximum pool size. (optional parameter)
+	if s := req.FormValue("maxpoolsize"); s != "" {
+		_, err := fmt.Sscan(s, &settings.MaxPoolSize)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxpoolsize: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Scan the maximum transaction age. (optional parameter)
+	if s := req.FormValue("maxtransactionage"); s != "" {
+		_, err := fmt.Sscan(s, &settings.MaxTransactionAge)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxtransactionage: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the toggles of the policy rules. (optional parameters)
+	toggles := map[string]*bool{
+		"checkarbitrarydatasize": &settings.Policy.CheckArbitraryDataSize,
+		"checkdustoutputs":       &settings.Policy.CheckDustOutputs,
+		"checksignaturecount":    &settings.Policy.CheckSignatureCount,
+		"checkunlockconditions":  &settings.Policy.CheckUnlockConditions,
+	}
+	for param, toggle := range toggles {
+		if s := req.FormValue(param); s != "" {
+			enabled, err := scanBool(s)
+			if err != nil {
+				WriteError(w, Error{"unable to parse " + param + ": " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			*toggle = enabled
+		}
+	}
+	// Scan the limits of the policy rules. (optional parameters)
+	limits := map[string]*uint64{
+		"maxarbitrarydatasize": &settings.Policy.MaxArbitraryDataSize,
+		"maxsignatures":        &settings.Policy.MaxSignatures,
+	}
+	for param, limit := range limits {
+		if s := req.FormValue(param); s != "" {
+			_, err := fmt.Sscan(s, limit)
+			if err != nil {
+				WriteError(w, Error{"unable to parse " + param + ": " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if s := req.FormValue("dustthreshold"); s != "" {
+		threshold, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse dustthreshold"}, http.StatusBadRequest)
+			return
+		}
+		settings.Policy.DustThreshold = threshold
+	}
+
+	err := api.tpool.SetSettings(settings)
+	if err != nil {
+		WriteError(w, Error{"unable to set transaction pool settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // tpoolConfirmedGET returns whether the specified transaction has
 // been seen on the blockchain.
 func (api *API) tpoolConfirmedGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -133,3 +313,115 @@ func (api *API) tpoolConfirmedGET(w http.ResponseWriter, req *http.Request, ps h
 		Confirmed: confirmed,
 	})
 }
+
+// tpoolLocalHandlerGET returns the unconfirmed transactions that were
+// submitted to this node.
+func (api *API) tpoolLocalHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, TpoolLocalGET{
+		Transactions: api.tpool.LocalTransactions(),
+	})
+}
+
+// tpoolRejectionsHandlerGET returns the transaction sets that were recently
+// rejected by the transaction pool.
+func (api *API) tpoolRejectionsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, TpoolRejectionsGET{
+		Rejections: api.tpool.RecentRejections(),
+	})
+}
+
+// tpoolStatsHandlerGET returns statistics about the contents of the
+// transaction pool.
+func (api *API) tpoolStatsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, TpoolStatsGET{
+		TransactionPoolStats: api.tpool.Stats(),
+	})
+}
+
+// tpoolTransactionHandlerGET returns the transaction that matches the input id
+// along with its unconfirmed parents and its source.
+func (api *API) tpoolTransactionHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	txid, err := decodeTransactionID(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"error decoding transaction id:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	txn, parents, exists := api.tpool.Transaction(txid)
+	if !exists {
+		WriteError(w, Error{"transaction not found in transaction pool"}, http.StatusBadRequest)
+		return
+	}
+	source, _ := api.tpool.TransactionSource(txid)
+	WriteJSON(w, TpoolTransactionGET{
+		Transaction: txn,
+		Parents:     parents,
+		Source:      source,
+	})
+}
+
+// tpoolTransactionSetHandlerGET returns the transaction set that matches the
+// input id.
+func (api *API) tpoolTransactionSetHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var id crypto.Hash
+	err := id.LoadString(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"error decoding transaction set id:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	ts, exists := api.tpool.TransactionSetByID(modules.TransactionSetID(id))
+	if !exists {
+		WriteError(w, Error{"transaction set not found in transaction pool"}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, TpoolTransactionSetGET{
+		ID:           id,
+		Transactions: ts,
+	})
+}
+
+// tpoolValidateHandlerPOST validates a raw transaction set without adding it
+// to the transaction pool or relaying it.
+func (api *API) tpoolValidateHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txnSet, err := decodeRawTransactionSet(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	validation, err := api.tpool.Validate(txnSet)
+	if tr, ok := err.(modules.TransactionRejection); ok {
+		WriteError(w, Error{fmt.Sprintf("transaction set would be rejected (%v): %v", tr.Reason, tr)}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		WriteError(w, Error{"error validating transaction set: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, TpoolValidatePOST{validation})
+}
+
+// tpoolOutputsHandlerGET returns the outputs of an unlock hash that are created
+// or spent by the unconfirmed transactions in the transaction pool.
+func (api *API) tpoolOutputsHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	uh, err := scanAddress(ps.ByName("unlockhash"))
+	if err != nil {
+		WriteError(w, Error{"error decoding unlock hash: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	uo := api.tpool.UnconfirmedOutputs(uh)
+	tog := TpoolOutputsGET{UnconfirmedOutputs: uo}
+	tog.IncomingSiacoins, tog.OutgoingSiacoins, tog.IncomingSiafunds, tog.OutgoingSiafunds = uo.Balances()
+	WriteJSON(w, tog)
+}
+
+// tpoolSpendableHandlerGET returns the outputs of an unlock hash that are
+// created by unconfirmed transactions and not yet spent, along with the
+// transactions that need to be broadcast with a transaction that spends them.
+func (api *API) tpoolSpendableHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	uh, err := scanAddress(ps.ByName("unlockhash"))
+	if err != nil {
+		WriteError(w, Error{"error decoding unlock hash: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, TpoolSpendableGET{
+		Outputs: api.tpool.SpendableOutputs(uh),
+	})
+}
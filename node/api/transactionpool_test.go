@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -180,6 +182,16 @@ func TestTransactionPoolRawHandlerPOST(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// A rejected transaction should report the reason of the rejection.
+	invalidTxn := types.Transaction{ArbitraryData: [][]byte{[]byte("foo")}}
+	postValues = url.Values{}
+	postValues.Set("parents", base64.StdEncoding.EncodeToString(encoding.Marshal([]types.Transaction{})))
+	postValues.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(invalidTxn)))
+	err = st4.stdPostAPI("/tpool/raw", postValues)
+	if err == nil || !strings.Contains(err.Error(), string(modules.RejectionNonStandard)) || !strings.Contains(err.Error(), invalidTxn.ID().String()) {
+		t.Fatal("expected a non-standard rejection, got", err)
+	}
 }
 
 // TestTransactionPoolFee tests the /tpool/fee endpoint.
@@ -203,6 +215,95 @@ func TestTransactionPoolFee(t *testing.T) {
 	if !min.Equals(fees.Minimum) || !max.Equals(fees.Maximum) {
 		t.Fatal("fee mismatch")
 	}
+	if fees.Target != modules.DefaultFeeTarget || !fees.Estimate.Equals(st.tpool.FeeEstimate(modules.DefaultFeeTarget)) {
+		t.Fatal("estimate mismatch")
+	}
+
+	err = st.getAPI("/tpool/fee?target=20", &fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.Target != 20 || !fees.Estimate.Equals(st.tpool.FeeEstimate(20)) {
+		t.Fatal("estimate mismatch")
+	}
+	if err := st.getAPI("/tpool/fee?target=0", &fees); err == nil {
+		t.Fatal("expected an error for a target of 0")
+	}
+}
+
+// TestTransactionPoolSettings tests the /tpool/settings endpoint.
+func TestTransactionPoolSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("maxpoolsize", "5000000")
+	values.Set("maxtransactionage", "144")
+	err = st.stdPostAPI("/tpool/settings", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tsg TpoolSettingsGET
+	err = st.getAPI("/tpool/settings", &tsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tsg.MaxPoolSize != 5e6 {
+		t.Fatal("maximum pool size was not changed:", tsg.MaxPoolSize)
+	}
+	if tsg.MaxTransactionAge != 144 {
+		t.Fatal("maximum transaction age was not changed:", tsg.MaxTransactionAge)
+	}
+
+	// A pool that cannot fit a single transaction set should be rejected.
+	values.Set("maxpoolsize", "1000")
+	if err := st.stdPostAPI("/tpool/settings", values); err == nil {
+		t.Fatal("expected an error for a tiny pool size")
+	}
+	values.Set("maxpoolsize", "many")
+	if err := st.stdPostAPI("/tpool/settings", values); err == nil {
+		t.Fatal("expected an error for an invalid pool size")
+	}
+	values = url.Values{}
+	values.Set("maxtransactionage", "0")
+	if err := st.stdPostAPI("/tpool/settings", values); err == nil {
+		t.Fatal("expected an error for a zero transaction age")
+	}
+
+	// Change the policy, leaving the rules that are not mentioned alone.
+	values = url.Values{}
+	values.Set("checkdustoutputs", "true")
+	values.Set("dustthreshold", "1000")
+	values.Set("maxsignatures", "10")
+	err = st.stdPostAPI("/tpool/settings", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/tpool/settings", &tsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := tsg.Policy
+	if !policy.CheckDustOutputs || policy.DustThreshold.Cmp64(1000) != 0 {
+		t.Fatal("dust rule was not changed:", policy.CheckDustOutputs, policy.DustThreshold)
+	}
+	if policy.CheckSignatureCount || policy.MaxSignatures != 10 {
+		t.Fatal("signature rule was not changed correctly:", policy.CheckSignatureCount, policy.MaxSignatures)
+	}
+	if !policy.CheckUnlockConditions || policy.CheckArbitraryDataSize {
+		t.Fatal("unmentioned rules were changed")
+	}
+	values.Set("checkdustoutputs", "maybe")
+	if err := st.stdPostAPI("/tpool/settings", values); err == nil {
+		t.Fatal("expected an error for an invalid toggle")
+	}
 }
 
 // TestTransactionPoolConfirmed tests the /tpool/confirmed endpoint.
@@ -256,3 +357,276 @@ func TestTransactionPoolConfirmed(t *testing.T) {
 		t.Fatal("transaction should not be confirmed")
 	}
 }
+
+// TestTransactionPoolContents checks that the contents of the transaction pool
+// can be queried through the API.
+func TestTransactionPoolContents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	txns, err := st.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(1000), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+
+	var tsg TpoolStatsGET
+	err = st.getAPI("/tpool/stats", &tsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tsg.TransactionSets != 1 || tsg.Transactions != uint64(len(txns)) || tsg.Size == 0 || tsg.TotalFees.IsZero() {
+		t.Fatal("wrong transaction pool stats:", tsg)
+	}
+
+	var ttg TpoolTransactionGET
+	err = st.getAPI("/tpool/transactions/"+txn.ID().String(), &ttg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttg.Transaction.ID() != txn.ID() {
+		t.Fatal("wrong transaction returned")
+	}
+	if err := st.getAPI("/tpool/transactions/"+strings.Repeat("0", 64), &ttg); err == nil {
+		t.Fatal("expected an error for an unknown transaction")
+	}
+
+	// Decode the raw sets and look up the set by its id.
+	var trg TpoolRawSetsGET
+	err = st.getAPI("/tpool/raw", &trg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trg.TransactionSets) != 1 {
+		t.Fatal("expected one raw transaction set, got", len(trg.TransactionSets))
+	}
+	var set []types.Transaction
+	err = encoding.Unmarshal(trg.TransactionSets[0], &set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tsetg TpoolTransactionSetGET
+	err = st.getAPI("/tpool/transactionsets/"+crypto.HashObject(set).String(), &tsetg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tsetg.Transactions) != len(txns) || tsetg.Transactions[len(txns)-1].ID() != txn.ID() {
+		t.Fatal("wrong transaction set returned")
+	}
+}
+
+// TestTransactionPoolValidate tests the /tpool/validate endpoint.
+func TestTransactionPoolValidate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Build a signed transaction set without submitting it.
+	b, err := st.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := types.SiacoinPrecision
+	err = b.FundSiacoins(types.SiacoinPrecision.Mul64(100).Add(fee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddMinerFee(fee)
+	b.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision.Mul64(100)})
+	txnSet, err := b.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, parents := txnSet[len(txnSet)-1], txnSet[:len(txnSet)-1]
+
+	values := url.Values{}
+	values.Set("parents", base64.StdEncoding.EncodeToString(encoding.Marshal(parents)))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+	var tvp TpoolValidatePOST
+	err = st.postAPI("/tpool/validate", values, &tvp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tvp.Size != uint64(len(encoding.Marshal(txnSet))) || !tvp.Fees.Equals(fee) {
+		t.Fatal("wrong validation of the set:", tvp)
+	}
+	if _, _, exists := st.tpool.Transaction(txn.ID()); exists {
+		t.Fatal("validated transaction was added to the pool")
+	}
+
+	// An invalid transaction should report the reason of the rejection.
+	invalidTxn := types.Transaction{ArbitraryData: [][]byte{[]byte("foo")}}
+	values.Set("parents", base64.StdEncoding.EncodeToString(encoding.Marshal([]types.Transaction{})))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(invalidTxn)))
+	err = st.postAPI("/tpool/validate", values, &tvp)
+	if err == nil || !strings.Contains(err.Error(), string(modules.RejectionNonStandard)) {
+		t.Fatal("expected a non-standard rejection, got", err)
+	}
+}
+
+// TestTransactionPoolOutputs tests the /tpool/outputs/:unlockhash endpoint.
+func TestTransactionPoolOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	uh := types.UnlockHash{1}
+	value := types.SiacoinPrecision.Mul64(100)
+	_, err = st.wallet.SendSiacoins(value, uh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tog TpoolOutputsGET
+	err = st.getAPI("/tpool/outputs/"+uh.String(), &tog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tog.Incoming) != 1 || len(tog.Outgoing) != 0 || !tog.IncomingSiacoins.Equals(value) || !tog.OutgoingSiacoins.IsZero() {
+		t.Fatal("wrong unconfirmed outputs:", tog)
+	}
+	if err := st.getAPI("/tpool/outputs/foo", &tog); err == nil {
+		t.Fatal("expected an error for an invalid unlock hash")
+	}
+}
+
+// TestTransactionPoolRejections checks that the /tpool/rejections endpoint
+// reports rejected transaction sets, and that they are counted by the
+// /tpool/stats endpoint.
+func TestTransactionPoolRejections(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var trg TpoolRejectionsGET
+	err = st.getAPI("/tpool/rejections", &trg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trg.Rejections) != 0 {
+		t.Fatal("expected no rejections, got", trg.Rejections)
+	}
+
+	if err := st.tpool.AcceptTransactionSet(nil); err == nil {
+		t.Fatal("expected the empty set to be rejected")
+	}
+	_, err = st.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/tpool/rejections", &trg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trg.Rejections) != 1 || trg.Rejections[0].Reason != modules.RejectionEmptySet {
+		t.Fatal("wrong rejections:", trg.Rejections)
+	}
+	var tsg TpoolStatsGET
+	err = st.getAPI("/tpool/stats", &tsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tsg.AcceptedSets != 1 || tsg.RejectedSets != 1 || tsg.MedianFeeRate.IsZero() {
+		t.Fatal("wrong stats:", tsg)
+	}
+}
+
+// TestTransactionPoolLocal checks that the /tpool/local endpoint reports the
+// transactions that were submitted through the wallet.
+func TestTransactionPoolLocal(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var tlg TpoolLocalGET
+	err = st.getAPI("/tpool/local", &tlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tlg.Transactions) != 0 {
+		t.Fatal("expected no local transactions, got", tlg.Transactions)
+	}
+
+	txns, err := st.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/tpool/local", &tlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tlg.Transactions) != len(txns) {
+		t.Fatalf("expected %v local transactions, got %v", len(txns), len(tlg.Transactions))
+	}
+	for _, lt := range tlg.Transactions {
+		if lt.ID != lt.Transaction.ID() || lt.FirstSeen.IsZero() {
+			t.Fatal("wrong local transaction:", lt)
+		}
+	}
+}
+
+// TestTransactionPoolSpendable checks that the /tpool/spendable endpoint
+// reports unconfirmed outputs along with their dependency sets.
+func TestTransactionPoolSpendable(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	uh := types.UnlockHash{1}
+	value := types.SiacoinPrecision.Mul64(100)
+	txns, err := st.wallet.SendSiacoins(value, uh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tsg TpoolSpendableGET
+	err = st.getAPI("/tpool/spendable/"+uh.String(), &tsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tsg.Outputs) != 1 || !tsg.Outputs[0].Value.Equals(value) {
+		t.Fatal("wrong spendable outputs:", tsg.Outputs)
+	}
+	deps := tsg.Outputs[0].DependencySet
+	if len(deps) == 0 || deps[len(deps)-1].ID() != txns[len(txns)-1].ID() {
+		t.Fatal("wrong dependency set:", deps)
+	}
+	if err := st.getAPI("/tpool/spendable/foo", &tsg); err == nil {
+		t.Fatal("expected an error for an invalid unlock hash")
+	}
+}
@@ -1,13 +1,13 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
@@ -57,12 +57,14 @@ type (
 	// /wallet/siacoins.
 	WalletSiacoinsPOST struct {
 		TransactionIDs []types.TransactionID `json:"transactionids"`
+		Fee            types.Currency        `json:"fee"`
 	}
 
 	// WalletSiafundsPOST contains the transaction sent in the POST call to
 	// /wallet/siafunds.
 	WalletSiafundsPOST struct {
 		TransactionIDs []types.TransactionID `json:"transactionids"`
+		Fee            types.Currency        `json:"fee"`
 	}
 
 	// WalletSeedsGET contains the seeds used by the wallet.
@@ -72,6 +74,18 @@ type (
 		AllSeeds           []string `json:"allseeds"`
 	}
 
+	// WalletSettingsGET contains the settings of the wallet.
+	WalletSettingsGET struct {
+		NoDefrag              bool              `json:"nodefrag"`
+		DefragThreshold       uint64            `json:"defragthreshold"`
+		DefragMaxFee          types.Currency    `json:"defragmaxfee"`
+		AutoLockTimeout       time.Duration     `json:"autolocktimeout"`
+		SiafundClaimThreshold types.Currency    `json:"siafundclaimthreshold"`
+		FeePolicy             modules.FeePolicy `json:"feepolicy"`
+		NoSpendUnconfirmed    bool              `json:"nospendunconfirmed"`
+		DustThreshold         types.Currency    `json:"dustthreshold"`
+	}
+
 	// WalletSweepPOST contains the coins and funds returned by a call to
 	// /wallet/sweep.
 	WalletSweepPOST struct {
@@ -79,25 +93,98 @@ type (
 		Funds types.Currency `json:"funds"`
 	}
 
+	// WalletTransaction is a processed transaction along with a summary of
+	// how it affects the wallet and the labels of the addresses that appear
+	// in it.
+	WalletTransaction struct {
+		modules.ProcessedTransaction
+		modules.ProcessedTransactionSummary
+		Labels []modules.AddressLabel `json:"labels"`
+
+		// Memo is the memo that the transaction carries, if any.
+		Memo string `json:"memo,omitempty"`
+
+		// FirstSeen is the time at which the wallet first saw an unconfirmed
+		// transaction in the transaction pool. It is only set for
+		// unconfirmed transactions.
+		FirstSeen types.Timestamp `json:"firstseen,omitempty"`
+	}
+
 	// WalletTransactionGETid contains the transaction returned by a call to
 	// /wallet/transaction/:id
 	WalletTransactionGETid struct {
-		Transaction modules.ProcessedTransaction `json:"transaction"`
+		Transaction WalletTransaction `json:"transaction"`
 	}
 
 	// WalletTransactionsGET contains the specified set of confirmed and
 	// unconfirmed transactions.
 	WalletTransactionsGET struct {
-		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
-		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		ConfirmedTransactions   []WalletTransaction `json:"confirmedtransactions"`
+		UnconfirmedTransactions []WalletTransaction `json:"unconfirmedtransactions"`
 	}
 
 	// WalletTransactionsGETaddr contains the set of wallet transactions
 	// relevant to the input address provided in the call to
 	// /wallet/transaction/:addr
 	WalletTransactionsGETaddr struct {
-		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
-		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		ConfirmedTransactions   []WalletTransaction `json:"confirmedtransactions"`
+		UnconfirmedTransactions []WalletTransaction `json:"unconfirmedtransactions"`
+	}
+
+	// WalletMultisigPOST contains the address and unlock conditions of a
+	// multisig address created by /wallet/multisig.
+	WalletMultisigPOST struct {
+		Address          types.UnlockHash       `json:"address"`
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+	}
+
+	// WalletLabelsGET contains the labels of the wallet's addresses and the
+	// entries of its address book.
+	WalletLabelsGET struct {
+		Labels []modules.AddressLabel `json:"labels"`
+	}
+
+	// WalletOutputsGET contains the outputs that the wallet can spend.
+	WalletOutputsGET struct {
+		Outputs []modules.WalletOutput `json:"outputs"`
+	}
+
+	// WalletSignPOST contains the transaction signed by /wallet/sign.
+	WalletSignPOST struct {
+		Transaction types.Transaction `json:"transaction"`
+	}
+
+	// WalletSignMessagePOST contains the signature of a message made by
+	// /wallet/signmessage.
+	WalletSignMessagePOST struct {
+		Signature types.MessageSignature `json:"signature"`
+	}
+
+	// WalletUnlockConditionsGET contains the unlock conditions of an address
+	// known to the wallet.
+	WalletUnlockConditionsGET struct {
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+	}
+
+	// WalletUnsignedPOST contains a funded transaction without signatures and
+	// the parent IDs of the signatures that need to be signed.
+	WalletUnsignedPOST struct {
+		Transaction types.Transaction `json:"transaction"`
+		ToSign      []crypto.Hash     `json:"tosign"`
+	}
+
+	// WalletSpendingPolicyGET contains the policy that limits the siacoins
+	// sent by the wallet.
+	WalletSpendingPolicyGET struct {
+		MaxPerTransaction types.Currency     `json:"maxpertransaction"`
+		MaxPerDay         types.Currency     `json:"maxperday"`
+		Whitelist         []types.UnlockHash `json:"whitelist"`
+	}
+
+	// WalletWatchGET contains the set of addresses that the wallet is
+	// watching.
+	WalletWatchGET struct {
+		Addresses []types.UnlockHash `json:"addresses"`
 	}
 
 	// WalletVerifyAddressGET contains a bool indicating if the address passed to
@@ -105,8 +192,66 @@ type (
 	WalletVerifyAddressGET struct {
 		Valid bool `json:"valid"`
 	}
+
+	// WalletVerifyMessageGET contains a bool indicating if the message passed
+	// to /wallet/verify/message was signed by the address.
+	WalletVerifyMessageGET struct {
+		Valid bool `json:"valid"`
+	}
 )
 
+// walletTransaction adds a summary of how it affects the wallet to a
+// processed transaction, counting confirmations relative to height, along
+// with the labels of the addresses that appear in it.
+func walletTransaction(pt modules.ProcessedTransaction, height types.BlockHeight, labels []modules.AddressLabel) WalletTransaction {
+	addrs := make(map[types.UnlockHash]struct{})
+	for _, input := range pt.Inputs {
+		addrs[input.RelatedAddress] = struct{}{}
+	}
+	for _, output := range pt.Outputs {
+		addrs[output.RelatedAddress] = struct{}{}
+	}
+	wt := WalletTransaction{
+		ProcessedTransaction:        pt,
+		ProcessedTransactionSummary: pt.Summary(height),
+		Labels:                      []modules.AddressLabel{},
+	}
+	wt.Memo, _ = modules.TransactionMemo(pt.Transaction)
+	for _, al := range labels {
+		if _, exists := addrs[al.Address]; exists {
+			wt.Labels = append(wt.Labels, al)
+		}
+	}
+	return wt
+}
+
+// walletTransactions calls walletTransaction on a set of processed
+// transactions.
+func walletTransactions(pts []modules.ProcessedTransaction, height types.BlockHeight, labels []modules.AddressLabel) []WalletTransaction {
+	if pts == nil {
+		return nil
+	}
+	wts := make([]WalletTransaction, len(pts))
+	for i, pt := range pts {
+		wts[i] = walletTransaction(pt, height, labels)
+	}
+	return wts
+}
+
+// pendingWalletTransactions calls walletTransaction on a set of pending
+// transactions, and sets the time at which they were first seen.
+func pendingWalletTransactions(pts []modules.PendingTransaction, height types.BlockHeight, labels []modules.AddressLabel) []WalletTransaction {
+	if pts == nil {
+		return nil
+	}
+	wts := make([]WalletTransaction, len(pts))
+	for i, pt := range pts {
+		wts[i] = walletTransaction(pt.ProcessedTransaction, height, labels)
+		wts[i].FirstSeen = pt.FirstSeen
+	}
+	return wts
+}
+
 // encryptionKeys enumerates the possible encryption keys that can be derived
 // from an input string.
 func encryptionKeys(seedStr string) (validKeys []crypto.TwofishKey) {
@@ -201,7 +346,13 @@ func (api *API) wallet033xHandler(w http.ResponseWriter, req *http.Request, _ ht
 
 // walletAddressHandler handles API calls to /wallet/address.
 func (api *API) walletAddressHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	unlockConditions, err := api.wallet.NextAddress()
+	var unlockConditions types.UnlockConditions
+	var err error
+	if req.FormValue("unused") == "true" {
+		unlockConditions, err = api.wallet.UnusedAddress()
+	} else {
+		unlockConditions, err = api.wallet.NextAddress()
+	}
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/addresses: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -371,6 +522,16 @@ func (api *API) walletLockHandler(w http.ResponseWriter, req *http.Request, _ ht
 	WriteSuccess(w)
 }
 
+// walletRescanHandler handles API calls to /wallet/rescan.
+func (api *API) walletRescanHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.wallet.Rescan()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/rescan: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // walletSeedsHandler handles API calls to /wallet/seeds.
 func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	dictionary := mnemonics.DictionaryID(req.FormValue("dictionary"))
@@ -414,6 +575,26 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 
 // walletSiacoinsHandler handles API calls to /wallet/siacoins.
 func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Scan the fee that overrides the wallet's fee policy. (optional
+	// parameter)
+	var fee types.Currency
+	feeSet := req.FormValue("fee") != ""
+	if feeSet {
+		var ok bool
+		fee, ok = scanAmount(req.FormValue("fee"))
+		if !ok {
+			WriteError(w, Error{"could not read fee from POST call to /wallet/siacoins"}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Scan the flag that confirms a send that violates the spending policy.
+	// (optional parameter)
+	confirm, err := scanBool(req.FormValue("confirm"))
+	if err != nil {
+		WriteError(w, Error{"could not read confirm from POST call to /wallet/siacoins: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
 	var txns []types.Transaction
 	if req.FormValue("outputs") != "" {
 		// multiple amounts + destinations
@@ -423,12 +604,12 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 		}
 
 		var outputs []types.SiacoinOutput
-		err := json.Unmarshal([]byte(req.FormValue("outputs")), &outputs)
+		err = json.Unmarshal([]byte(req.FormValue("outputs")), &outputs)
 		if err != nil {
 			WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
-		txns, err = api.wallet.SendSiacoinsMulti(outputs)
+		txns, err = api.sendSiacoinsMulti(outputs, fee, feeSet, confirm)
 		if err != nil {
 			WriteError(w, Error{"error when calling /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -446,7 +627,45 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 			return
 		}
 
-		txns, err = api.wallet.SendSiacoins(amount, dest)
+		// Scan the outputs that should fund the transaction. (optional
+		// parameter)
+		var inputs []types.SiacoinOutputID
+		for _, idStr := range strings.Split(req.FormValue("inputs"), ",") {
+			if idStr == "" {
+				continue
+			}
+			id, err := scanHash(idStr)
+			if err != nil {
+				WriteError(w, Error{"could not read inputs from POST call to /wallet/siacoins: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			inputs = append(inputs, types.SiacoinOutputID(id))
+		}
+
+		// Scan the memo that is attached to the transaction. (optional
+		// parameter)
+		memo := req.FormValue("memo")
+
+		if len(inputs) > 0 && feeSet {
+			WriteError(w, Error{"cannot supply both 'inputs' and 'fee'"}, http.StatusBadRequest)
+			return
+		} else if memo != "" && (len(inputs) > 0 || feeSet) {
+			WriteError(w, Error{"cannot supply 'memo' together with 'inputs' or 'fee'"}, http.StatusBadRequest)
+			return
+		} else if confirm && (memo != "" || len(inputs) > 0) {
+			WriteError(w, Error{"cannot supply 'confirm' together with 'memo' or 'inputs'"}, http.StatusBadRequest)
+			return
+		} else if confirm {
+			txns, err = api.sendSiacoinsMulti([]types.SiacoinOutput{{Value: amount, UnlockHash: dest}}, fee, feeSet, confirm)
+		} else if memo != "" {
+			txns, err = api.wallet.SendSiacoinsWithMemo(amount, dest, memo)
+		} else if len(inputs) > 0 {
+			txns, err = api.wallet.SendSiacoinsWithOutputs(amount, dest, inputs)
+		} else if feeSet {
+			txns, err = api.wallet.SendSiacoinsWithFee(amount, dest, fee)
+		} else {
+			txns, err = api.wallet.SendSiacoins(amount, dest)
+		}
 		if err != nil {
 			WriteError(w, Error{"error when calling /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -460,9 +679,25 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 	}
 	WriteJSON(w, WalletSiacoinsPOST{
 		TransactionIDs: txids,
+		Fee:            transactionSetFee(txns),
 	})
 }
 
+// sendSiacoinsMulti sends outputs with the wallet, paying fee if feeSet is
+// true. Confirmed sends are not limited by the wallet's spending policy.
+func (api *API) sendSiacoinsMulti(outputs []types.SiacoinOutput, fee types.Currency, feeSet, confirm bool) ([]types.Transaction, error) {
+	switch {
+	case feeSet && confirm:
+		return api.wallet.SendSiacoinsMultiWithFeeConfirmed(outputs, fee)
+	case feeSet:
+		return api.wallet.SendSiacoinsMultiWithFee(outputs, fee)
+	case confirm:
+		return api.wallet.SendSiacoinsMultiConfirmed(outputs)
+	default:
+		return api.wallet.SendSiacoinsMulti(outputs)
+	}
+}
+
 // walletSiafundsHandler handles API calls to /wallet/siafunds.
 func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	amount, ok := scanAmount(req.FormValue("amount"))
@@ -487,9 +722,20 @@ func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request,
 	}
 	WriteJSON(w, WalletSiafundsPOST{
 		TransactionIDs: txids,
+		Fee:            transactionSetFee(txns),
 	})
 }
 
+// transactionSetFee returns the sum of the miner fees of a transaction set.
+func transactionSetFee(txns []types.Transaction) (fee types.Currency) {
+	for _, txn := range txns {
+		for _, minerFee := range txn.MinerFees {
+			fee = fee.Add(minerFee)
+		}
+	}
+	return fee
+}
+
 // walletSweepSeedHandler handles API calls to /wallet/sweep/seed.
 func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Get the seed using the ditionary + phrase
@@ -514,6 +760,31 @@ func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletSweepKeyHandler handles API calls to /wallet/sweep/key.
+func (api *API) walletSweepKeyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	keyBytes, err := hex.DecodeString(req.FormValue("key"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse key: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var sk crypto.SecretKey
+	if len(keyBytes) != len(sk) {
+		WriteError(w, Error{fmt.Sprintf("unable to parse key: key must be %v bytes", len(sk))}, http.StatusBadRequest)
+		return
+	}
+	copy(sk[:], keyBytes)
+
+	coins, funds, err := api.wallet.SweepKey(sk)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/sweep/key: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSweepPOST{
+		Coins: coins,
+		Funds: funds,
+	})
+}
+
 // walletTransactionHandler handles API calls to /wallet/transaction/:id.
 func (api *API) walletTransactionHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	// Parse the id from the url.
@@ -534,50 +805,97 @@ func (api *API) walletTransactionHandler(w http.ResponseWriter, req *http.Reques
 		WriteError(w, Error{"error when calling /wallet/transaction/:id  :  transaction not found"}, http.StatusBadRequest)
 		return
 	}
+	height, err := api.wallet.Height()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transaction/id:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	labels, err := api.wallet.AddressLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transaction/id:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
 	WriteJSON(w, WalletTransactionGETid{
-		Transaction: txn,
+		Transaction: walletTransaction(txn, height, labels),
 	})
 }
 
 // walletTransactionsHandler handles API calls to /wallet/transactions.
 func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if memo := req.FormValue("memo"); memo != "" {
+		api.walletTransactionsMemoHandler(w, memo)
+		return
+	}
 	startheightStr, endheightStr := req.FormValue("startheight"), req.FormValue("endheight")
 	if startheightStr == "" || endheightStr == "" {
 		WriteError(w, Error{"startheight and endheight must be provided to a /wallet/transactions call."}, http.StatusBadRequest)
 		return
 	}
-	// Get the start and end blocks.
-	start, err := strconv.ParseUint(startheightStr, 10, 64)
+	start, end, err := scanHeightRange(startheightStr, endheightStr)
 	if err != nil {
-		WriteError(w, Error{"parsing integer value for parameter `startheight` failed: " + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
 	}
-	// Check if endheightStr is set to -1. If it is, we use MaxUint64 as the
-	// end. Otherwise we parse the argument as an unsigned integer.
-	var end uint64
-	if endheightStr == "-1" {
-		end = math.MaxUint64
-	} else {
-		end, err = strconv.ParseUint(endheightStr, 10, 64)
+	confirmedTxns, err := api.wallet.Transactions(start, end)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
 	}
+	pendingTxns, err := api.wallet.PendingTransactions()
 	if err != nil {
-		WriteError(w, Error{"parsing integer value for parameter `endheight` failed: " + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
-	confirmedTxns, err := api.wallet.Transactions(types.BlockHeight(start), types.BlockHeight(end))
+
+	height, err := api.wallet.Height()
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
-	unconfirmedTxns, err := api.wallet.UnconfirmedTransactions()
+	labels, err := api.wallet.AddressLabels()
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 
 	WriteJSON(w, WalletTransactionsGET{
-		ConfirmedTransactions:   confirmedTxns,
-		UnconfirmedTransactions: unconfirmedTxns,
+		ConfirmedTransactions:   walletTransactions(confirmedTxns, height, labels),
+		UnconfirmedTransactions: pendingWalletTransactions(pendingTxns, height, labels),
+	})
+}
+
+// walletTransactionsMemoHandler handles API calls to /wallet/transactions that
+// request the transactions carrying a memo.
+func (api *API) walletTransactionsMemoHandler(w http.ResponseWriter, memo string) {
+	confirmedTxns, err := api.wallet.MemoTransactions(memo)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	pendingTxns, err := api.wallet.PendingTransactions()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var memoTxns []modules.PendingTransaction
+	for _, pt := range pendingTxns {
+		if m, ok := modules.TransactionMemo(pt.Transaction); ok && m == memo {
+			memoTxns = append(memoTxns, pt)
+		}
+	}
+	height, err := api.wallet.Height()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	labels, err := api.wallet.AddressLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletTransactionsGET{
+		ConfirmedTransactions:   walletTransactions(confirmedTxns, height, labels),
+		UnconfirmedTransactions: pendingWalletTransactions(memoTxns, height, labels),
 	})
 }
 
@@ -603,12 +921,473 @@ func (api *API) walletTransactionsAddrHandler(w http.ResponseWriter, req *http.R
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	height, err := api.wallet.Height()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	labels, err := api.wallet.AddressLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
 	WriteJSON(w, WalletTransactionsGETaddr{
-		ConfirmedTransactions:   confirmedATs,
-		UnconfirmedTransactions: unconfirmedATs,
+		ConfirmedTransactions:   walletTransactions(confirmedATs, height, labels),
+		UnconfirmedTransactions: walletTransactions(unconfirmedATs, height, labels),
+	})
+}
+
+// walletSettingsHandlerGET handles GET calls to /wallet/settings.
+func (api *API) walletSettingsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings, err := api.wallet.Settings()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSettingsGET{
+		NoDefrag:              settings.NoDefrag,
+		DefragThreshold:       settings.DefragThreshold,
+		DefragMaxFee:          settings.DefragMaxFee,
+		AutoLockTimeout:       settings.AutoLockTimeout,
+		SiafundClaimThreshold: settings.SiafundClaimThreshold,
+		FeePolicy:             settings.FeePolicy,
+		NoSpendUnconfirmed:    settings.NoSpendUnconfirmed,
+		DustThreshold:         settings.DustThreshold,
 	})
 }
 
+// walletSettingsHandlerPOST handles POST calls to /wallet/settings.
+func (api *API) walletSettingsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings, err := api.wallet.Settings()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Scan the defrag toggle. (optional parameter)
+	if s := req.FormValue("nodefrag"); s != "" {
+		settings.NoDefrag, err = scanBool(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse nodefrag: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Scan the defrag threshold. (optional parameter)
+	if s := req.FormValue("defragthreshold"); s != "" {
+		_, err = fmt.Sscan(s, &settings.DefragThreshold)
+		if err != nil {
+			WriteError(w, Error{"unable to parse defragthreshold: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Scan the defrag fee limit. (optional parameter)
+	if s := req.FormValue("defragmaxfee"); s != "" {
+		maxFee, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse defragmaxfee"}, http.StatusBadRequest)
+			return
+		}
+		settings.DefragMaxFee = maxFee
+	}
+	// Scan the auto-lock timeout. (optional parameter)
+	if s := req.FormValue("autolocktimeout"); s != "" {
+		_, err = fmt.Sscan(s, &settings.AutoLockTimeout)
+		if err != nil {
+			WriteError(w, Error{"unable to parse autolocktimeout: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Scan the siafund claim threshold. (optional parameter)
+	if s := req.FormValue("siafundclaimthreshold"); s != "" {
+		threshold, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse siafundclaimthreshold"}, http.StatusBadRequest)
+			return
+		}
+		settings.SiafundClaimThreshold = threshold
+	}
+	// Scan the fee policy. (optional parameter)
+	if s := req.FormValue("feepolicy"); s != "" {
+		settings.FeePolicy = modules.FeePolicy(s)
+	}
+	// Scan the dust threshold. (optional parameter)
+	if s := req.FormValue("dustthreshold"); s != "" {
+		threshold, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse dustthreshold"}, http.StatusBadRequest)
+			return
+		}
+		settings.DustThreshold = threshold
+	}
+	// Scan the unconfirmed spending toggle. (optional parameter)
+	if s := req.FormValue("nospendunconfirmed"); s != "" {
+		settings.NoSpendUnconfirmed, err = scanBool(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse nospendunconfirmed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	err = api.wallet.SetSettings(settings)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletSpendingPolicyHandlerGET handles GET calls to /wallet/spendingpolicy.
+func (api *API) walletSpendingPolicyHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	policy, err := api.wallet.SpendingPolicy()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/spendingpolicy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSpendingPolicyGET{
+		MaxPerTransaction: policy.MaxPerTransaction,
+		MaxPerDay:         policy.MaxPerDay,
+		Whitelist:         policy.Whitelist,
+	})
+}
+
+// walletExportHandler handles API calls to /wallet/export.
+func (api *API) walletExportHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	startheightStr, endheightStr := req.FormValue("startheight"), req.FormValue("endheight")
+	if startheightStr == "" || endheightStr == "" {
+		WriteError(w, Error{"startheight and endheight must be provided to a /wallet/export call."}, http.StatusBadRequest)
+		return
+	}
+	start, end, err := scanHeightRange(startheightStr, endheightStr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	format := modules.ExportFormat(req.FormValue("format"))
+	if format == "" {
+		format = modules.ExportFormatJSON
+	}
+	report, err := api.wallet.Export(format, start, end)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/export: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if format == modules.ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.Write(report)
+}
+
+// walletSpendingPolicyHandlerPOST handles POST calls to
+// /wallet/spendingpolicy.
+func (api *API) walletSpendingPolicyHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	policy, err := api.wallet.SpendingPolicy()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/spendingpolicy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Scan the limits. (optional parameters)
+	if s := req.FormValue("maxpertransaction"); s != "" {
+		limit, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse maxpertransaction"}, http.StatusBadRequest)
+			return
+		}
+		policy.MaxPerTransaction = limit
+	}
+	if s := req.FormValue("maxperday"); s != "" {
+		limit, ok := scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"unable to parse maxperday"}, http.StatusBadRequest)
+			return
+		}
+		policy.MaxPerDay = limit
+	}
+	// Scan the whitelist, which is cleared by an empty value. (optional
+	// parameter)
+	if v, ok := req.Form["whitelist"]; ok && len(v) > 0 {
+		policy.Whitelist = nil
+		for _, addrStr := range strings.Split(v[0], ",") {
+			if addrStr == "" {
+				continue
+			}
+			addr, err := scanAddress(addrStr)
+			if err != nil {
+				WriteError(w, Error{"unable to parse whitelist: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			policy.Whitelist = append(policy.Whitelist, addr)
+		}
+	}
+	err = api.wallet.SetSpendingPolicy(policy)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/spendingpolicy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletMultisigHandler handles API calls to /wallet/multisig.
+func (api *API) walletMultisigHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var publicKeys []types.SiaPublicKey
+	err := json.Unmarshal([]byte(req.FormValue("publickeys")), &publicKeys)
+	if err != nil {
+		WriteError(w, Error{"unable to parse publickeys: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var signaturesRequired uint64
+	_, err = fmt.Sscan(req.FormValue("signaturesrequired"), &signaturesRequired)
+	if err != nil {
+		WriteError(w, Error{"unable to parse signaturesrequired: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	unused, err := scanBool(req.FormValue("unused"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse unused: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	uc, err := api.wallet.AddMultisigAddress(publicKeys, signaturesRequired, unused)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletMultisigPOST{
+		Address:          uc.UnlockHash(),
+		UnlockConditions: uc,
+	})
+}
+
+// walletLabelsHandlerGET handles GET calls to /wallet/labels.
+func (api *API) walletLabelsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	labels, err := api.wallet.AddressLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletLabelsGET{
+		Labels: labels,
+	})
+}
+
+// walletLabelsHandlerPOST handles POST calls to /wallet/labels.
+func (api *API) walletLabelsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.SetAddressLabel(addr, req.FormValue("label"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletOutputsHandlerGET handles GET calls to /wallet/outputs.
+func (api *API) walletOutputsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	outputs, err := api.wallet.SpendableOutputs()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/outputs: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletOutputsGET{
+		Outputs: outputs,
+	})
+}
+
+// walletOutputsHandlerPOST handles POST calls to /wallet/outputs.
+func (api *API) walletOutputsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var ids []types.OutputID
+	for _, idStr := range strings.Split(req.FormValue("ids"), ",") {
+		if idStr == "" {
+			continue
+		}
+		id, err := scanHash(idStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse ids: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, types.OutputID(id))
+	}
+	if len(ids) == 0 {
+		WriteError(w, Error{"at least one output must be provided to ids"}, http.StatusBadRequest)
+		return
+	}
+	locked, err := scanBool(req.FormValue("locked"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse locked: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if locked {
+		err = api.wallet.LockOutputs(ids)
+	} else {
+		err = api.wallet.UnlockOutputs(ids)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/outputs: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletSignHandler handles API calls to /wallet/sign.
+func (api *API) walletSignHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var txn types.Transaction
+	err := json.Unmarshal([]byte(req.FormValue("transaction")), &txn)
+	if err != nil {
+		WriteError(w, Error{"unable to parse transaction: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var toSign []crypto.Hash
+	err = json.Unmarshal([]byte(req.FormValue("tosign")), &toSign)
+	if err != nil {
+		WriteError(w, Error{"unable to parse tosign: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.SignTransaction(&txn, toSign)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/sign: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSignPOST{
+		Transaction: txn,
+	})
+}
+
+// walletSignMessageHandler handles API calls to /wallet/signmessage.
+func (api *API) walletSignMessageHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	ms, err := api.wallet.SignMessage(addr, []byte(req.FormValue("message")))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/signmessage: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSignMessagePOST{
+		Signature: ms,
+	})
+}
+
+// walletUnlockConditionsHandlerGET handles GET calls to
+// /wallet/unlockconditions/:addr.
+func (api *API) walletUnlockConditionsHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr, err := scanAddress(ps.ByName("addr"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	uc, err := api.wallet.UnlockConditions(addr)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unlockconditions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletUnlockConditionsGET{
+		UnlockConditions: uc,
+	})
+}
+
+// walletUnlockConditionsHandlerPOST handles POST calls to
+// /wallet/unlockconditions.
+func (api *API) walletUnlockConditionsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var uc types.UnlockConditions
+	err := json.Unmarshal([]byte(req.FormValue("unlockconditions")), &uc)
+	if err != nil {
+		WriteError(w, Error{"unable to parse unlockconditions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.AddUnlockConditions(uc)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unlockconditions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletUnsignedHandler handles API calls to /wallet/unsigned.
+func (api *API) walletUnsignedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var outputs []types.SiacoinOutput
+	err := json.Unmarshal([]byte(req.FormValue("outputs")), &outputs)
+	if err != nil {
+		WriteError(w, Error{"unable to parse outputs: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var fee types.Currency
+	if s := req.FormValue("fee"); s != "" {
+		var ok bool
+		fee, ok = scanAmount(s)
+		if !ok {
+			WriteError(w, Error{"could not read fee from POST call to /wallet/unsigned"}, http.StatusBadRequest)
+			return
+		}
+	}
+	txn, toSign, err := api.wallet.UnsignedTransaction(outputs, fee)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unsigned: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletUnsignedPOST{
+		Transaction: txn,
+		ToSign:      toSign,
+	})
+}
+
+// walletWatchHandlerGET handles GET calls to /wallet/watch.
+func (api *API) walletWatchHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addrs, err := api.wallet.WatchAddresses()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletWatchGET{
+		Addresses: addrs,
+	})
+}
+
+// walletWatchHandlerPOST handles POST calls to /wallet/watch.
+func (api *API) walletWatchHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var addrs []types.UnlockHash
+	for _, addrStr := range strings.Split(req.FormValue("addresses"), ",") {
+		if addrStr == "" {
+			continue
+		}
+		addr, err := scanAddress(addrStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse addresses: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		WriteError(w, Error{"at least one address must be provided to addresses"}, http.StatusBadRequest)
+		return
+	}
+	remove, err := scanBool(req.FormValue("remove"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse remove: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	unused, err := scanBool(req.FormValue("unused"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse unused: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if remove {
+		err = api.wallet.RemoveWatchAddresses(addrs, unused)
+	} else {
+		err = api.wallet.AddWatchAddresses(addrs, unused)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // walletUnlockHandler handles API calls to /wallet/unlock.
 func (api *API) walletUnlockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
@@ -658,3 +1437,20 @@ func (api *API) walletVerifyAddressHandler(w http.ResponseWriter, req *http.Requ
 	err := new(types.UnlockHash).LoadString(addrString)
 	WriteJSON(w, WalletVerifyAddressGET{Valid: err == nil})
 }
+
+// walletVerifyMessageHandler handles API calls to /wallet/verify/message.
+func (api *API) walletVerifyMessageHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var ms types.MessageSignature
+	err = json.Unmarshal([]byte(req.FormValue("signature")), &ms)
+	if err != nil {
+		WriteError(w, Error{"unable to parse signature: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = types.VerifyMessage(addr, []byte(req.FormValue("message")), ms)
+	WriteJSON(w, WalletVerifyMessageGET{Valid: err == nil})
+}
@@ -1,16 +1,19 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/modules/gateway"
@@ -482,6 +485,58 @@ func TestIntegrationWalletSweepSeedPOST(t *testing.T) {
 	}
 }
 
+// TestIntegrationWalletSweepKeyPOST probes the POST call to /wallet/sweep/key.
+func TestIntegrationWalletSweepKeyPOST(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// send coins to the address of a key, then sweep them back
+	sk, pk := crypto.GenerateKeyPair()
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{types.Ed25519PublicKey(pk)},
+		SignaturesRequired: 1,
+	}
+	_, err = st.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wsp WalletSweepPOST
+	qs := url.Values{}
+	qs.Set("key", hex.EncodeToString(sk[:]))
+	err = st.postAPI("/wallet/sweep/key", qs, &wsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Should have swept more than 80 SC
+	if wsp.Coins.Cmp(types.SiacoinPrecision.Mul64(80)) <= 0 {
+		t.Fatalf("swept fewer coins (%v SC) than expected %v+", wsp.Coins.Div(types.SiacoinPrecision), 80)
+	}
+
+	// Add a block so that the sweep transaction is processed
+	_, err = st.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Call /wallet/sweep/key with an invalid key
+	qs.Set("key", "abcd")
+	err = st.postAPI("/wallet/sweep/key", qs, &wsp)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 // TestIntegrationWalletLoadSeedPOST probes the POST call to
 // /wallet/seed.
 func TestIntegrationWalletLoadSeedPOST(t *testing.T) {
@@ -613,6 +668,12 @@ func TestWalletTransactionGETid(t *testing.T) {
 	if wtgid.Transaction.Outputs[0].Value.IsZero() {
 		t.Error("output should have a nonzero value")
 	}
+	if wtgid.Transaction.Direction != modules.TransactionDirectionIncoming || wtgid.Transaction.Confirmations == 0 {
+		t.Error("miner payout should be a confirmed incoming transaction")
+	}
+	if !wtgid.Transaction.IncomingSiacoins.Equals(wtgid.Transaction.Outputs[0].Value) {
+		t.Error("incoming siacoins should equal the miner payout")
+	}
 
 	// Query the details of a transaction where siacoins were sent.
 	//
@@ -643,6 +704,11 @@ func TestWalletTransactionGETid(t *testing.T) {
 	} else if exp := txn.Inputs[0].Value.Sub(sentValue); !txn.Outputs[1].Value.Equals(exp) {
 		t.Errorf("expected first output to equal %v, got %v", exp, txn.Outputs[1].Value)
 	}
+	if txn.Direction != modules.TransactionDirectionOutgoing || txn.Confirmations != 1 {
+		t.Error("expected an outgoing transaction with 1 confirmation, got", txn.Direction, txn.Confirmations)
+	} else if exp := sentValue.Add(txn.Fees); txn.Fees.IsZero() || !txn.OutgoingSiacoins.Sub(txn.IncomingSiacoins).Equals(exp) {
+		t.Errorf("expected a net change of -%v, got -%v", exp, txn.OutgoingSiacoins.Sub(txn.IncomingSiacoins))
+	}
 
 	// Create a second wallet and send money to that wallet.
 	st2, err := blankServerTester(t.Name() + "w2")
@@ -677,6 +743,11 @@ func TestWalletTransactionGETid(t *testing.T) {
 	if len(wtg.UnconfirmedTransactions) != 2 {
 		t.Fatal("expecting two unconfirmed transactions in sender wallet")
 	}
+	for _, txn := range wtg.UnconfirmedTransactions {
+		if txn.FirstSeen == 0 || txn.FirstSeen > types.CurrentTimestamp() {
+			t.Fatal("unconfirmed transaction has the wrong first seen time:", txn.FirstSeen)
+		}
+	}
 	// Check that undocumented API behaviour used in Sia-UI still works with
 	// current API.
 	err = st.getAPI("/wallet/transactions?startheight=0&endheight=-1", &wtg)
@@ -1262,6 +1333,539 @@ func TestWalletVerifyAddress(t *testing.T) {
 	}
 }
 
+// TestWalletSettings checks that the settings of the wallet can be changed
+// through the /wallet/settings endpoint, and that the wallet locks itself
+// once the auto-lock timeout has passed.
+func TestWalletSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wsg WalletSettingsGET
+	err = st.getAPI("/wallet/settings", &wsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wsg.FeePolicy != modules.FeePolicyNormal {
+		t.Fatal("wrong default fee policy:", wsg.FeePolicy)
+	}
+	if wsg.NoDefrag || wsg.NoSpendUnconfirmed || !wsg.DustThreshold.IsZero() || wsg.DefragThreshold == 0 || !wsg.DefragMaxFee.IsZero() || wsg.AutoLockTimeout != 0 || !wsg.SiafundClaimThreshold.IsZero() {
+		t.Fatal("wrong default settings:", wsg)
+	}
+
+	values := url.Values{}
+	values.Set("defragthreshold", "1")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected a defrag threshold below the batch size to be rejected")
+	}
+	values.Set("defragthreshold", "100")
+	values.Set("defragmaxfee", "foo")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid defrag fee limit to be rejected")
+	}
+	values.Set("defragmaxfee", "10")
+	values.Set("feepolicy", "cheap")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid fee policy to be rejected")
+	}
+	values.Set("feepolicy", string(modules.FeePolicyPriority))
+	values.Set("autolocktimeout", "-1")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected a negative auto-lock timeout to be rejected")
+	}
+	values.Set("autolocktimeout", "foo")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid auto-lock timeout to be rejected")
+	}
+	values.Set("autolocktimeout", "0")
+	values.Set("siafundclaimthreshold", "foo")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid siafund claim threshold to be rejected")
+	}
+	values.Set("siafundclaimthreshold", types.SiacoinPrecision.String())
+	values.Set("nospendunconfirmed", "foo")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid nospendunconfirmed to be rejected")
+	}
+	values.Set("nospendunconfirmed", "true")
+	values.Set("dustthreshold", "foo")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an invalid dust threshold to be rejected")
+	}
+	values.Set("dustthreshold", "1000")
+	values.Set("nodefrag", "true")
+	values.Set("autolocktimeout", fmt.Sprint(int64(500*time.Millisecond)))
+	err = st.stdPostAPI("/wallet/settings", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/wallet/settings", &wsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wsg.FeePolicy != modules.FeePolicyPriority {
+		t.Fatal("fee policy was not changed:", wsg.FeePolicy)
+	}
+	if !wsg.NoDefrag || !wsg.NoSpendUnconfirmed || !wsg.DustThreshold.Equals64(1000) || wsg.DefragThreshold != 100 || !wsg.DefragMaxFee.Equals64(10) || wsg.AutoLockTimeout != 500*time.Millisecond || !wsg.SiafundClaimThreshold.Equals(types.SiacoinPrecision) {
+		t.Fatal("settings were not changed:", wsg)
+	}
+
+	// The wallet should lock itself, after which spending fails.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		var wg WalletGET
+		if err := st.getAPI("/wallet", &wg); err != nil {
+			return err
+		}
+		if wg.Unlocked {
+			return errors.New("wallet was not locked")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("amount", types.SiacoinPrecision.String())
+	values.Set("destination", types.UnlockHash{}.String())
+	err = st.stdPostAPI("/wallet/siacoins", values)
+	if err == nil || !strings.Contains(err.Error(), modules.ErrLockedWallet.Error()) {
+		t.Fatal("expected a locked wallet error, got", err)
+	}
+}
+
+// TestWalletRescan checks that rescanning the blockchain through the
+// /wallet/rescan endpoint keeps the balance of the wallet.
+func TestWalletRescan(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wg WalletGET
+	err = st.getAPI("/wallet", &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.stdPostAPI("/wallet/rescan", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg2 WalletGET
+	err = st.getAPI("/wallet", &wg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wg2.ConfirmedSiacoinBalance.Equals(wg.ConfirmedSiacoinBalance) || wg2.Rescanning {
+		t.Fatal("wrong wallet state after rescan:", wg2)
+	}
+
+	// A locked wallet cannot be rescanned.
+	err = st.stdPostAPI("/wallet/lock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.stdPostAPI("/wallet/rescan", nil)
+	if err == nil || !strings.Contains(err.Error(), modules.ErrLockedWallet.Error()) {
+		t.Fatal("expected a locked wallet error, got", err)
+	}
+}
+
+// TestWalletWatch checks that addresses can be added to and removed from the
+// set of addresses watched by the wallet using the /wallet/watch endpoint.
+func TestWalletWatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	addr := types.UnlockHash{1}
+	qs := url.Values{}
+	qs.Set("addresses", addr.String())
+	qs.Set("unused", "true")
+	err = st.stdPostAPI("/wallet/watch", qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wwg WalletWatchGET
+	err = st.getAPI("/wallet/watch", &wwg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 1 || wwg.Addresses[0] != addr {
+		t.Fatal("wrong watched addresses:", wwg.Addresses)
+	}
+
+	qs.Set("remove", "true")
+	err = st.stdPostAPI("/wallet/watch", qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/wallet/watch", &wwg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 0 {
+		t.Fatal("address was not removed:", wwg.Addresses)
+	}
+
+	// Calls without addresses should fail.
+	err = st.stdPostAPI("/wallet/watch", url.Values{})
+	if err == nil {
+		t.Fatal("expected an error when no addresses are provided")
+	}
+}
+
+// TestWalletOfflineSigning checks that a transaction spending from a watched
+// address can be built with /wallet/unsigned, signed with /wallet/sign and
+// broadcast with /tpool/raw.
+func TestWalletOfflineSigning(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Fund a new address of the wallet and watch it using its unlock
+	// conditions, as if the wallet did not hold its keys.
+	var wag WalletAddressGET
+	err = st.getAPI("/wallet/address", &wag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(100).String())
+	values.Set("destination", wag.Address.String())
+	err = st.stdPostAPI("/wallet/siacoins", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var wucg WalletUnlockConditionsGET
+	err = st.getAPI("/wallet/unlockconditions/"+wag.Address.String(), &wucg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wucg.UnlockConditions.UnlockHash() != wag.Address {
+		t.Fatal("wrong unlock conditions returned for address")
+	}
+	ucBytes, err := json.Marshal(wucg.UnlockConditions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("unlockconditions", string(ucBytes))
+	err = st.stdPostAPI("/wallet/unlockconditions", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("addresses", wag.Address.String())
+	err = st.stdPostAPI("/wallet/watch", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build the unsigned transaction.
+	outputs, err := json.Marshal([]types.SiacoinOutput{{
+		Value:      types.SiacoinPrecision.Mul64(10),
+		UnlockHash: types.UnlockHash{},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("outputs", string(outputs))
+	values.Set("fee", types.SiacoinPrecision.String())
+	var wup WalletUnsignedPOST
+	err = st.postAPI("/wallet/unsigned", values, &wup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wup.ToSign) != 1 || len(wup.Transaction.SiacoinInputs) != 1 {
+		t.Fatal("wrong unsigned transaction:", wup)
+	}
+	if wup.Transaction.SiacoinInputs[0].UnlockConditions.UnlockHash() != wag.Address {
+		t.Fatal("unsigned transaction is not funded by the watched address")
+	}
+
+	// Sign and broadcast it.
+	txnBytes, err := json.Marshal(wup.Transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toSignBytes, err := json.Marshal(wup.ToSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("transaction", string(txnBytes))
+	values.Set("tosign", string(toSignBytes))
+	var wsp WalletSignPOST
+	err = st.postAPI("/wallet/sign", values, &wsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("parents", string(encoding.Marshal([]types.Transaction{})))
+	values.Set("transaction", string(encoding.Marshal(wsp.Transaction)))
+	err = st.stdPostAPI("/tpool/raw", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWalletMultisig checks that the /wallet/multisig endpoint creates and
+// watches a multisig address.
+func TestWalletMultisig(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Collect the public keys of two addresses of the wallet.
+	var publicKeys []types.SiaPublicKey
+	for i := 0; i < 2; i++ {
+		var wag WalletAddressGET
+		err = st.getAPI("/wallet/address", &wag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wucg WalletUnlockConditionsGET
+		err = st.getAPI("/wallet/unlockconditions/"+wag.Address.String(), &wucg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKeys = append(publicKeys, wucg.UnlockConditions.PublicKeys...)
+	}
+	pkBytes, err := json.Marshal(publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := url.Values{}
+	values.Set("publickeys", string(pkBytes))
+	values.Set("signaturesrequired", "3")
+	values.Set("unused", "true")
+	if err = st.stdPostAPI("/wallet/multisig", values); err == nil {
+		t.Fatal("expected an error when more signatures are required than there are keys")
+	}
+	values.Set("signaturesrequired", "1")
+	var wmp WalletMultisigPOST
+	err = st.postAPI("/wallet/multisig", values, &wmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wmp.UnlockConditions.UnlockHash() != wmp.Address || wmp.UnlockConditions.SignaturesRequired != 1 {
+		t.Fatal("wrong multisig address:", wmp)
+	}
+	var wwg WalletWatchGET
+	err = st.getAPI("/wallet/watch", &wwg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 1 || wwg.Addresses[0] != wmp.Address {
+		t.Fatal("multisig address is not watched:", wwg.Addresses)
+	}
+}
+
+// TestWalletOutputs tests locking and unlocking outputs through
+// /wallet/outputs and spending specific outputs through /wallet/siacoins.
+func TestWalletOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wog WalletOutputsGET
+	err = st.getAPI("/wallet/outputs", &wog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sco modules.WalletOutput
+	for _, o := range wog.Outputs {
+		if o.FundType == types.SpecifierSiacoinOutput {
+			sco = o
+			break
+		}
+	}
+	if sco.Value.IsZero() {
+		t.Fatal("wallet has no siacoin outputs")
+	}
+
+	// Lock the output.
+	values := url.Values{}
+	values.Set("ids", sco.ID.String())
+	values.Set("locked", "true")
+	err = st.stdPostAPI("/wallet/outputs", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/wallet/outputs", &wog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range wog.Outputs {
+		if o.ID == sco.ID && !o.Locked {
+			t.Fatal("output was not locked")
+		}
+	}
+
+	// Spending the locked output should fail.
+	var wag WalletAddressGET
+	err = st.getAPI("/wallet/address", &wag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendValues := url.Values{}
+	sendValues.Set("amount", types.SiacoinPrecision.String())
+	sendValues.Set("destination", wag.Address.String())
+	sendValues.Set("inputs", sco.ID.String())
+	if err = st.stdPostAPI("/wallet/siacoins", sendValues); err == nil {
+		t.Fatal("expected an error when spending a locked output")
+	}
+
+	// Unlock the output and spend it.
+	values.Set("locked", "false")
+	err = st.stdPostAPI("/wallet/outputs", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wsp WalletSiacoinsPOST
+	err = st.postAPI("/wallet/siacoins", sendValues, &wsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wsp.TransactionIDs) == 0 {
+		t.Fatal("no transactions were returned")
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var wtg WalletTransactionGETid
+	err = st.getAPI("/wallet/transaction/"+wsp.TransactionIDs[0].String(), &wtg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := wtg.Transaction.Transaction
+	if len(txn.SiacoinInputs) != 1 || types.OutputID(txn.SiacoinInputs[0].ParentID) != sco.ID {
+		t.Fatal("transaction was not funded by the specified output")
+	}
+}
+
+// TestWalletLabels tests setting address labels through /wallet/labels and
+// checks that they appear in the transaction history.
+func TestWalletLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Label an address of the wallet and send coins to it.
+	var wag WalletAddressGET
+	err = st.getAPI("/wallet/address", &wag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("address", wag.Address.String())
+	values.Set("label", "savings")
+	err = st.stdPostAPI("/wallet/labels", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wlg WalletLabelsGET
+	err = st.getAPI("/wallet/labels", &wlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wlg.Labels) != 1 || wlg.Labels[0].Address != wag.Address || wlg.Labels[0].Label != "savings" || !wlg.Labels[0].WalletAddress {
+		t.Fatal("wrong labels:", wlg.Labels)
+	}
+	sendValues := url.Values{}
+	sendValues.Set("amount", types.SiacoinPrecision.String())
+	sendValues.Set("destination", wag.Address.String())
+	err = st.stdPostAPI("/wallet/siacoins", sendValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The label should appear in the history of the address.
+	var wtga WalletTransactionsGETaddr
+	err = st.getAPI("/wallet/transactions/"+wag.Address.String(), &wtga)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wtga.ConfirmedTransactions) == 0 {
+		t.Fatal("expected a transaction for the labelled address")
+	}
+	for _, txn := range wtga.ConfirmedTransactions {
+		if len(txn.Labels) != 1 || txn.Labels[0].Label != "savings" {
+			t.Fatal("label is missing from the transaction:", txn.Labels)
+		}
+	}
+
+	// Remove the label.
+	values.Set("label", "")
+	err = st.stdPostAPI("/wallet/labels", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/wallet/labels", &wlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wlg.Labels) != 0 {
+		t.Fatal("label was not removed:", wlg.Labels)
+	}
+}
+
 // TestWalletChangePassword verifies that the /wallet/changepassword endpoint
 // works correctly and changes a wallet password.
 func TestWalletChangePassword(t *testing.T) {
@@ -1762,3 +2366,45 @@ func TestWalletTransactionsGetAddr(t *testing.T) {
 		t.Errorf("There should be exactly 0 unconfirmed and 1 confirmed related txns")
 	}
 }
+
+// TestWalletSiacoinsFee checks that /wallet/siacoins pays the fee that is
+// passed to it and reports the fee that was paid.
+func TestWalletSiacoinsFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Without a fee, the fee of the wallet's fee policy is paid.
+	_, maxFee := st.tpool.FeeEstimation()
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.String())
+	values.Set("destination", types.UnlockHash{}.String())
+	var wsp WalletSiacoinsPOST
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	if !wsp.Fee.Equals(maxFee.Mul64(750)) {
+		t.Fatal("expected the fee of the normal fee policy, got", wsp.Fee)
+	}
+
+	// An explicit fee is paid instead.
+	fee := types.SiacoinPrecision.Div64(10)
+	values.Set("fee", fee.String())
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	if !wsp.Fee.Equals(fee) {
+		t.Fatal("expected fee", fee, "got", wsp.Fee)
+	}
+	values.Set("inputs", crypto.Hash{}.String())
+	if err = st.stdPostAPI("/wallet/siacoins", values); err == nil {
+		t.Fatal("expected a fee combined with inputs to be rejected")
+	}
+}
@@ -131,6 +131,31 @@ var (
 		Standard: BlockHeight(21e3),
 		Testing:  BlockHeight(10),
 	}).(BlockHeight)
+
+	// DevAddressHardforkHeight is the first height at which the siafunds of
+	// the last genesis siafund output are paid to the developer address.
+	DevAddressHardforkHeight = build.Select(build.Var{
+		Dev:      BlockHeight(10e3 + 1),
+		Standard: BlockHeight(10e3 + 1),
+		Testing:  BlockHeight(10e3 + 1),
+	}).(BlockHeight)
+
+	// StorageProofHardforkHeight is the height at which storage proofs on a
+	// final segment of exactly crypto.SegmentSize bytes were fixed.
+	StorageProofHardforkHeight = build.Select(build.Var{
+		Dev:      BlockHeight(10),
+		Standard: BlockHeight(100e3),
+		Testing:  BlockHeight(10),
+	}).(BlockHeight)
+
+	// MedianTimePastHardforkHeight is the height at which blocks are required
+	// to have a timestamp strictly greater than the median-time-past. The
+	// hardfork has not been scheduled.
+	MedianTimePastHardforkHeight = build.Select(build.Var{
+		Dev:      unscheduledHardforkHeight,
+		Standard: unscheduledHardforkHeight,
+		Testing:  unscheduledHardforkHeight,
+	}).(BlockHeight)
 )
 
 // init checks which build constant is in place and initializes the variables
@@ -177,6 +202,28 @@ func init() {
 				UnlockHash: UnlockConditions{}.UnlockHash(),
 			},
 		}
+
+		// 'regtest' settings are for regression test networks, which are dev
+		// networks where blocks can be mined instantly by integration tests
+		// and third-party developers. The genesis timestamp differs from the
+		// dev network so that the two networks cannot be mixed up.
+		if build.REGTEST {
+			BlockFrequency = 1
+			MaturityDelay = 1
+			GenesisTimestamp = Timestamp(1424139001)
+			RootTarget = Target{255} // Almost every hash is valid.
+
+			// The difficulty can only change by 0.01% per block, so that it
+			// stays near the root target even though blocks will be mined far
+			// faster than the block frequency.
+			TargetWindow = 200
+			MaxTargetAdjustmentUp = big.NewRat(10001, 10000)
+			MaxTargetAdjustmentDown = big.NewRat(9999, 10000)
+			OakDecayNum = 9999
+			OakDecayDenom = 10e3
+			OakMaxRise = big.NewRat(10001, 10e3)
+			OakMaxDrop = big.NewRat(10e3, 10001)
+		}
 	} else if build.Release == "testing" {
 		// 'testing' settings are for automatic testing, and create much faster
 		// environments than a human can interact with.
@@ -122,7 +122,7 @@ func PostTax(height BlockHeight, payout Currency) Currency {
 func Tax(height BlockHeight, payout Currency) Currency {
 	// COMPATv0.4.0 - until the first 20,000 blocks have been archived, they
 	// will need to be handled in a special way.
-	if height < TaxHardforkHeight {
+	if !RuleTaxFix.Active(height) {
 		return payout.MulFloat(0.039).RoundDown(SiafundCount)
 	}
 	return payout.MulTax().RoundDown(SiafundCount)
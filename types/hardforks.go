@@ -0,0 +1,167 @@
+package types
+
+// hardforks.go describes the consensus rules that have been introduced by
+// hardforks. Rather than comparing heights against hardfork constants, code
+// that depends on a hardfork asks whether the corresponding rule is active:
+//
+//	if RuleOakDifficulty.Active(height) {
+//		...
+//	}
+//
+// A hardfork is a named set of rules that all activate at the same height. A
+// future hardfork is added by declaring its rules below, giving it an
+// activation height in constants.go, and adding it to Hardforks.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// unscheduledHardforkHeight is the activation height of a hardfork that has
+// not been scheduled yet.
+const unscheduledHardforkHeight = BlockHeight(math.MaxUint64)
+
+// A ConsensusRule identifies a consensus rule that was introduced by a
+// hardfork.
+type ConsensusRule int
+
+const (
+	// RuleTaxFix taxes file contract payouts using exact integer arithmetic
+	// instead of floating point arithmetic.
+	RuleTaxFix ConsensusRule = iota
+
+	// RuleSiafundDevAddress redirects the siafunds of the last genesis
+	// siafund output to the developer address.
+	RuleSiafundDevAddress
+
+	// RuleStorageProofFinalSegment allows storage proofs on a final segment
+	// of a file that is shorter than crypto.SegmentSize.
+	RuleStorageProofFinalSegment
+
+	// RuleStorageProofSegmentFix fixes storage proofs on a final segment of
+	// a file that is exactly crypto.SegmentSize bytes.
+	RuleStorageProofSegmentFix
+
+	// RuleOakDifficulty switches the difficulty adjustment to the oak
+	// algorithm.
+	RuleOakDifficulty
+
+	// RuleTxnSizeLimit limits the size of a transaction to
+	// OakHardforkTxnSizeLimit.
+	RuleTxnSizeLimit
+
+	// RuleOakDifficultyFix fixes the oak algorithm to compare the total time
+	// against the time expected since the genesis block.
+	RuleOakDifficultyFix
+
+	// RuleMedianTimePast requires the timestamp of a block to be strictly
+	// greater than the median timestamp of the previous
+	// MedianTimestampWindow blocks, instead of greater than or equal to it.
+	RuleMedianTimePast
+
+	// numConsensusRules is the number of declared consensus rules.
+	numConsensusRules
+)
+
+// A Hardfork is a named set of consensus rules that activate at the same
+// height.
+type Hardfork struct {
+	Name   string          `json:"name"`
+	Height BlockHeight     `json:"height"`
+	Rules  []ConsensusRule `json:"rules"`
+}
+
+// ruleNames are the names of the consensus rules.
+var ruleNames = [numConsensusRules]string{
+	RuleTaxFix:                   "taxfix",
+	RuleSiafundDevAddress:        "siafunddevaddress",
+	RuleStorageProofFinalSegment: "storageprooffinalsegment",
+	RuleStorageProofSegmentFix:   "storageproofsegmentfix",
+	RuleOakDifficulty:            "oakdifficulty",
+	RuleTxnSizeLimit:             "txnsizelimit",
+	RuleOakDifficultyFix:         "oakdifficultyfix",
+	RuleMedianTimePast:           "mediantimepast",
+}
+
+// activationHeight returns a pointer to the constant holding the activation
+// height of the rule.
+func (r ConsensusRule) activationHeight() *BlockHeight {
+	switch r {
+	case RuleTaxFix, RuleStorageProofFinalSegment:
+		return &TaxHardforkHeight
+	case RuleSiafundDevAddress:
+		return &DevAddressHardforkHeight
+	case RuleStorageProofSegmentFix:
+		return &StorageProofHardforkHeight
+	case RuleOakDifficulty, RuleTxnSizeLimit:
+		return &OakHardforkBlock
+	case RuleOakDifficultyFix:
+		return &OakHardforkFixBlock
+	case RuleMedianTimePast:
+		return &MedianTimePastHardforkHeight
+	}
+	panic("unknown consensus rule " + r.String())
+}
+
+// ActivationHeight returns the height of the first block that is subject to
+// the rule.
+func (r ConsensusRule) ActivationHeight() BlockHeight {
+	return *r.activationHeight()
+}
+
+// Active returns whether the rule applies to a block at the provided height.
+func (r ConsensusRule) Active(height BlockHeight) bool {
+	return height >= r.ActivationHeight()
+}
+
+// String implements fmt.Stringer.
+func (r ConsensusRule) String() string {
+	if r < 0 || r >= numConsensusRules {
+		return fmt.Sprintf("ConsensusRule(%d)", int(r))
+	}
+	return ruleNames[r]
+}
+
+// Hardforks returns every hardfork in order of activation.
+func Hardforks() []Hardfork {
+	hardforks := []Hardfork{
+		{Name: "devaddress", Height: DevAddressHardforkHeight, Rules: []ConsensusRule{RuleSiafundDevAddress}},
+		{Name: "tax", Height: TaxHardforkHeight, Rules: []ConsensusRule{RuleTaxFix, RuleStorageProofFinalSegment}},
+		{Name: "storageproof", Height: StorageProofHardforkHeight, Rules: []ConsensusRule{RuleStorageProofSegmentFix}},
+		{Name: "oak", Height: OakHardforkBlock, Rules: []ConsensusRule{RuleOakDifficulty, RuleTxnSizeLimit}},
+		{Name: "oakfix", Height: OakHardforkFixBlock, Rules: []ConsensusRule{RuleOakDifficultyFix}},
+		{Name: "mtp", Height: MedianTimePastHardforkHeight, Rules: []ConsensusRule{RuleMedianTimePast}},
+	}
+	// The order of activation depends on the build constants.
+	sort.SliceStable(hardforks, func(i, j int) bool {
+		return hardforks[i].Height < hardforks[j].Height
+	})
+	return hardforks
+}
+
+// ActiveRules returns the consensus rules that apply to a block at the
+// provided height.
+func ActiveRules(height BlockHeight) []ConsensusRule {
+	var rules []ConsensusRule
+	for r := ConsensusRule(0); r < numConsensusRules; r++ {
+		if r.Active(height) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// SimulateActivation changes the activation height of a rule, and of every
+// other rule introduced by the same hardfork, and returns a function that
+// restores the original height. It is intended for tests that need to
+// exercise a hardfork, and must not be used while a consensus set or any
+// test running in parallel depends on the rule.
+func SimulateActivation(r ConsensusRule, height BlockHeight) (restore func()) {
+	h := r.activationHeight()
+	old := *h
+	*h = height
+	return func() {
+		*h = old
+	}
+}
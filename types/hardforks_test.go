@@ -0,0 +1,67 @@
+package types
+
+import (
+	"testing"
+)
+
+// TestConsensusRules probes the activation of the consensus rules.
+func TestConsensusRules(t *testing.T) {
+	// Every rule should belong to exactly one hardfork, which activates at
+	// the activation height of the rule.
+	seen := make(map[ConsensusRule]bool)
+	hardforks := Hardforks()
+	for i, hf := range hardforks {
+		if i > 0 && hf.Height < hardforks[i-1].Height {
+			t.Fatal("hardforks are not in order of activation")
+		}
+		for _, r := range hf.Rules {
+			if seen[r] {
+				t.Fatal("rule is part of multiple hardforks:", r)
+			}
+			seen[r] = true
+			if r.ActivationHeight() != hf.Height {
+				t.Fatalf("%v activates at %v, but %v activates at %v", r, r.ActivationHeight(), hf.Name, hf.Height)
+			}
+			if r.Active(hf.Height-1) || !r.Active(hf.Height) {
+				t.Fatal("rule is not activated at the hardfork height:", r)
+			}
+		}
+	}
+	if len(seen) != int(numConsensusRules) {
+		t.Fatal("not every rule is part of a hardfork")
+	}
+	if len(ActiveRules(0)) != 0 {
+		t.Fatal("rules are active at the genesis block:", ActiveRules(0))
+	}
+	last := hardforks[len(hardforks)-1].Height
+	if len(ActiveRules(last)) != int(numConsensusRules) {
+		t.Fatal("not every rule is active after the last hardfork:", ActiveRules(last))
+	}
+	if ConsensusRule(-1).String() != "ConsensusRule(-1)" {
+		t.Fatal("unexpected name for unknown rule:", ConsensusRule(-1))
+	}
+
+	// The rules that replaced hardcoded height checks should activate at the
+	// same heights as those checks.
+	if RuleStorageProofFinalSegment.Active(9) || !RuleStorageProofFinalSegment.Active(10) {
+		t.Fatal("final segment storage proofs activate at the wrong height")
+	}
+	if RuleStorageProofSegmentFix.Active(9) || !RuleStorageProofSegmentFix.Active(10) {
+		t.Fatal("storage proof segment fix activates at the wrong height")
+	}
+	if RuleSiafundDevAddress.Active(10e3) || !RuleSiafundDevAddress.Active(10e3+1) {
+		t.Fatal("siafund dev address activates at the wrong height")
+	}
+
+	// Simulating an activation should move every rule of the hardfork, and
+	// be undone by the restore function.
+	oak := OakHardforkBlock
+	restore := SimulateActivation(RuleTxnSizeLimit, 1)
+	if !RuleTxnSizeLimit.Active(1) || !RuleOakDifficulty.Active(1) || OakHardforkBlock != 1 {
+		t.Fatal("activation was not simulated")
+	}
+	restore()
+	if RuleTxnSizeLimit.Active(1) || OakHardforkBlock != oak {
+		t.Fatal("activation was not restored")
+	}
+}
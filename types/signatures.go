@@ -24,6 +24,9 @@ var (
 	// ErrInvalidPubKeyIndex is the error when a transaction contains a signature
 	// that points to a nonexistent public key
 	ErrInvalidPubKeyIndex = errors.New("transaction contains a signature that points to a nonexistent public key")
+	// ErrInvalidMessageSignature is the error when a message signature was not
+	// made by the address that it is verified for
+	ErrInvalidMessageSignature = errors.New("message was not signed by the address")
 	// ErrInvalidUnlockHashChecksum is the error when the provided unlock hash has
 	// an invalid checksum
 	ErrInvalidUnlockHashChecksum = errors.New("provided unlock hash has an invalid checksum")
@@ -36,6 +39,9 @@ var (
 	// ErrPublicKeyOveruse is the error when public key was used multiple times while
 	// signing transaction
 	ErrPublicKeyOveruse = errors.New("public key was used multiple times while signing transaction")
+	// ErrUnsupportedMessageSignature is the error when a message signature is
+	// verified for an address that requires more than one signature
+	ErrUnsupportedMessageSignature = errors.New("messages can only be signed by addresses that require one signature")
 	// ErrSortedUniqueViolation is the error when a sorted unique violation occurs
 	ErrSortedUniqueViolation = errors.New("sorted unique violation")
 	// ErrUnlockHashWrongLen is the error when a marshalled unlock hash is the wrong
@@ -135,6 +141,16 @@ type (
 		SignaturesRequired uint64         `json:"signaturesrequired"`
 	}
 
+	// A MessageSignature proves that the owner of an address signed an
+	// off-chain message. The UnlockConditions are those of the address, and
+	// PublicKeyIndex indicates which of their public keys signed the message.
+	// Only addresses that require a single signature can sign messages.
+	MessageSignature struct {
+		UnlockConditions UnlockConditions `json:"unlockconditions"`
+		PublicKeyIndex   uint64           `json:"publickeyindex"`
+		Signature        []byte           `json:"signature"`
+	}
+
 	// Each input has a list of public keys and a required number of signatures.
 	// inputSignatures keeps track of which public keys have been used and how many
 	// more signatures are needed.
@@ -418,3 +434,45 @@ func (t *Transaction) validSignatures(currentHeight BlockHeight) error {
 
 	return nil
 }
+
+// VerifyMessage checks that msg was signed by the owner of addr. The timelock
+// of the unlock conditions is ignored, since it only restricts when the
+// outputs of the address can be spent.
+func VerifyMessage(addr UnlockHash, msg []byte, ms MessageSignature) error {
+	if ms.UnlockConditions.UnlockHash() != addr {
+		return ErrInvalidMessageSignature
+	}
+	if ms.UnlockConditions.SignaturesRequired != 1 {
+		return ErrUnsupportedMessageSignature
+	}
+	if ms.PublicKeyIndex >= uint64(len(ms.UnlockConditions.PublicKeys)) {
+		return ErrInvalidPubKeyIndex
+	}
+
+	publicKey := ms.UnlockConditions.PublicKeys[ms.PublicKeyIndex]
+	switch publicKey.Algorithm {
+	case SignatureEntropy:
+		return ErrEntropyKey
+
+	case SignatureEd25519:
+		var edPK crypto.PublicKey
+		if len(publicKey.Key) != len(edPK) {
+			return ErrInvalidMessageSignature
+		}
+		copy(edPK[:], publicKey.Key)
+		var edSig crypto.Signature
+		if len(ms.Signature) != len(edSig) {
+			return ErrInvalidMessageSignature
+		}
+		copy(edSig[:], ms.Signature)
+		if crypto.VerifyHash(crypto.HashMessage(msg), edPK, edSig) != nil {
+			return ErrInvalidMessageSignature
+		}
+		return nil
+
+	default:
+		// Unlike transaction signatures, signatures of unknown algorithms
+		// cannot be treated as valid, since anyone could produce them.
+		return ErrUnsupportedMessageSignature
+	}
+}
@@ -354,3 +354,75 @@ func TestTransactionValidSignatures(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestVerifyMessage checks that VerifyMessage only accepts signatures of the
+// message made by a key of the address.
+func TestVerifyMessage(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	uc := UnlockConditions{
+		PublicKeys:         []SiaPublicKey{Ed25519PublicKey(pk)},
+		SignaturesRequired: 1,
+	}
+	addr := uc.UnlockHash()
+	msg := []byte("I operate this host")
+	sig := crypto.SignHash(crypto.HashMessage(msg), sk)
+	ms := MessageSignature{
+		UnlockConditions: uc,
+		Signature:        sig[:],
+	}
+	if err := VerifyMessage(addr, msg, ms); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature must not verify for another message or address.
+	if err := VerifyMessage(addr, []byte("I operate another host"), ms); err != ErrInvalidMessageSignature {
+		t.Fatal("expected ErrInvalidMessageSignature, got", err)
+	}
+	if err := VerifyMessage(UnlockHash{1}, msg, ms); err != ErrInvalidMessageSignature {
+		t.Fatal("expected ErrInvalidMessageSignature, got", err)
+	}
+	// A signature of the message hash is not a signature of the message
+	// itself, so that transaction signatures cannot be passed off as
+	// messages.
+	rawSig := crypto.SignHash(crypto.HashBytes(msg), sk)
+	ms.Signature = rawSig[:]
+	if err := VerifyMessage(addr, msg, ms); err != ErrInvalidMessageSignature {
+		t.Fatal("expected ErrInvalidMessageSignature, got", err)
+	}
+	ms.Signature = sig[:10]
+	if err := VerifyMessage(addr, msg, ms); err != ErrInvalidMessageSignature {
+		t.Fatal("expected ErrInvalidMessageSignature, got", err)
+	}
+	ms.Signature = sig[:]
+	ms.PublicKeyIndex = 1
+	if err := VerifyMessage(addr, msg, ms); err != ErrInvalidPubKeyIndex {
+		t.Fatal("expected ErrInvalidPubKeyIndex, got", err)
+	}
+
+	// Addresses that require several signatures cannot sign messages.
+	_, pk2 := crypto.GenerateKeyPair()
+	multisig := UnlockConditions{
+		PublicKeys:         []SiaPublicKey{Ed25519PublicKey(pk), Ed25519PublicKey(pk2)},
+		SignaturesRequired: 2,
+	}
+	ms = MessageSignature{
+		UnlockConditions: multisig,
+		Signature:        sig[:],
+	}
+	if err := VerifyMessage(multisig.UnlockHash(), msg, ms); err != ErrUnsupportedMessageSignature {
+		t.Fatal("expected ErrUnsupportedMessageSignature, got", err)
+	}
+
+	// Keys of unknown algorithms cannot sign messages.
+	unknown := UnlockConditions{
+		PublicKeys:         []SiaPublicKey{{Algorithm: Specifier{'f', 'o', 'o'}, Key: pk[:]}},
+		SignaturesRequired: 1,
+	}
+	ms = MessageSignature{
+		UnlockConditions: unknown,
+		Signature:        sig[:],
+	}
+	if err := VerifyMessage(unknown.UnlockHash(), msg, ms); err != ErrUnsupportedMessageSignature {
+		t.Fatal("expected ErrUnsupportedMessageSignature, got", err)
+	}
+}
@@ -128,8 +128,8 @@ func (t Transaction) correctFileContractRevisions(currentHeight BlockHeight) err
 	return nil
 }
 
-// fitsInABlock checks if the transaction is likely to fit in a block. After
-// OakHardforkHeight, transactions must be smaller than 64 KiB.
+// fitsInABlock checks if the transaction is likely to fit in a block. Once
+// RuleTxnSizeLimit is active, transactions must be smaller than 64 KiB.
 func (t Transaction) fitsInABlock(currentHeight BlockHeight) error {
 	// Check that the transaction will fit inside of a block, leaving 5kb for
 	// overhead.
@@ -137,7 +137,7 @@ func (t Transaction) fitsInABlock(currentHeight BlockHeight) error {
 	if size > BlockSizeLimit-5e3 {
 		return ErrTransactionTooLarge
 	}
-	if currentHeight >= OakHardforkBlock {
+	if RuleTxnSizeLimit.Active(currentHeight) {
 		if size > OakHardforkTxnSizeLimit {
 			return ErrTransactionTooLarge
 		}